@@ -114,8 +114,9 @@ func main() {
 		}
 	}
 	if embedder == nil {
-		embedder = embedding.NewMockClient(768)
-		fmt.Printf("%s⚠ Using mock embeddings (set OPENROUTER_API_KEY for real embeddings)%s\n", colorYellow, colorReset)
+		// Left unfitted so vectors stored by earlier runs stay comparable
+		embedder = embedding.NewLocalEmbedder(embedding.LocalConfig{Dimension: 768})
+		fmt.Printf("%s⚠ Using local embeddings (set OPENROUTER_API_KEY for real embeddings)%s\n", colorYellow, colorReset)
 	}
 
 	// Initialize vector adapter - dimension will be set dynamically when first embedding is generated
@@ -10,6 +10,7 @@ import (
 	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/comparison"
 	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/monitoring"
 	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/observability"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/retrieval"
 )
 
 // ComparisonHandler handles product comparison requests.
@@ -35,11 +36,20 @@ type ComparisonRequestDTO struct {
 	SecondaryProductID string   `json:"secondaryProductId"`
 	Dimensions         []string `json:"dimensions,omitempty"`
 	MaxRows            int      `json:"maxRows,omitempty"`
+	// IncludeBenchmarks allows the secondary product to be another tenant's public benchmark product.
+	IncludeBenchmarks bool `json:"includeBenchmarks,omitempty"`
+	// AsOf compares the products as they stood at this RFC 3339 time.
+	AsOf string `json:"asOf,omitempty"`
+	// CampaignVersion compares this version of the primary product's campaigns,
+	// taking precedence over asOf.
+	CampaignVersion *int `json:"campaignVersion,omitempty"`
 }
 
 // ComparisonResponseDTO represents the API response for comparison.
 type ComparisonResponseDTO struct {
 	Comparisons []ComparisonRowDTO `json:"comparisons"`
+	// Snapshot names the campaign versions compared, set when asOf or campaignVersion was requested.
+	Snapshot *SnapshotDTO `json:"snapshot,omitempty"`
 }
 
 // ComparisonRowDTO represents a comparison row.
@@ -52,6 +62,8 @@ type ComparisonRowDTO struct {
 	Verdict            string `json:"verdict"`
 	Narrative          string `json:"narrative,omitempty"`
 	Shareability       string `json:"shareability"`
+	// Benchmark is set when the secondary product is another tenant's benchmark product.
+	Benchmark *BenchmarkDTO `json:"benchmark,omitempty"`
 }
 
 // Query handles POST /comparisons/query.
@@ -104,6 +116,12 @@ func (h *ComparisonHandler) Query(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	asOf, err := parseAsOf(reqDTO.AsOf)
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid asOf", err.Error())
+		return
+	}
+
 	h.logger.Info().
 		Str("tenant_id", tenantIDStr).
 		Str("primary_product", reqDTO.PrimaryProductID).
@@ -117,6 +135,9 @@ func (h *ComparisonHandler) Query(w http.ResponseWriter, r *http.Request) {
 		SecondaryProductID: secondaryID,
 		Dimensions:         reqDTO.Dimensions,
 		MaxRows:            reqDTO.MaxRows,
+		IncludeBenchmarks:  reqDTO.IncludeBenchmarks,
+		AsOf:               asOf,
+		CampaignVersion:    reqDTO.CampaignVersion,
 	})
 	if err != nil {
 		if err == comparison.ErrProductNotAccessible {
@@ -124,7 +145,7 @@ func (h *ComparisonHandler) Query(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		h.logger.Error().Err(err).Msg("Comparison failed")
-		h.writeError(w, http.StatusInternalServerError, "comparison failed", err.Error())
+		h.writeError(w, queryErrorStatus(err), "comparison failed", err.Error())
 		return
 	}
 
@@ -141,6 +162,10 @@ func (h *ComparisonHandler) Query(w http.ResponseWriter, r *http.Request) {
 	}
 
 	for _, comp := range result.Comparisons {
+		var benchmark *BenchmarkDTO
+		if comp.BenchmarkProductName != "" {
+			benchmark = &BenchmarkDTO{ProductID: comp.SecondaryProductID.String(), ProductName: comp.BenchmarkProductName}
+		}
 		resp.Comparisons = append(resp.Comparisons, ComparisonRowDTO{
 			Dimension:          comp.Dimension,
 			PrimaryProductID:   comp.PrimaryProductID.String(),
@@ -150,9 +175,25 @@ func (h *ComparisonHandler) Query(w http.ResponseWriter, r *http.Request) {
 			Verdict:            string(comp.Verdict),
 			Narrative:          comp.Narrative,
 			Shareability:       string(comp.Shareability),
+			Benchmark:          benchmark,
 		})
 	}
 
+	if result.Versions != nil {
+		versions := make([]retrieval.CampaignVersion, 0, len(result.Versions))
+		for _, c := range result.Versions {
+			versions = append(versions, retrieval.CampaignVersion{
+				CampaignVariantID: c.ID,
+				ProductID:         c.ProductID,
+				Version:           c.Version,
+				Status:            c.Status,
+				EffectiveFrom:     c.EffectiveFrom,
+				EffectiveThrough:  c.EffectiveThrough,
+			})
+		}
+		resp.Snapshot = toSnapshotDTO(asOf, versions)
+	}
+
 	// Write response
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(resp)
@@ -146,9 +146,11 @@ type PublishRequestDTO struct {
 	ReleaseNotes string `json:"releaseNotes,omitempty"`
 }
 
-// CampaignVersionDTO represents the API response for publish.
+// CampaignVersionDTO represents the API response for publish, and a campaign
+// version a point-in-time response was answered from.
 type CampaignVersionDTO struct {
 	CampaignID       string  `json:"campaignId"`
+	ProductID        string  `json:"productId,omitempty"`
 	Version          int     `json:"version"`
 	Status           string  `json:"status"`
 	EffectiveFrom    string  `json:"effectiveFrom,omitempty"`
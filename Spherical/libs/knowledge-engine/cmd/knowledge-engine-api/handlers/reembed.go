@@ -0,0 +1,264 @@
+// Package handlers provides HTTP handlers for the Knowledge Engine API.
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/observability"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/retrieval"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/storage"
+)
+
+// ReembedHandler handles the admin requests that start, resume, cancel and
+// roll back embedding model upgrades.
+type ReembedHandler struct {
+	logger     *observability.Logger
+	reembedder *retrieval.Reembedder
+	open       retrieval.EmbeddingIndexOpener
+}
+
+// NewReembedHandler creates a new re-embedding handler. open supplies the
+// embedder and shadow index of a model and version.
+func NewReembedHandler(logger *observability.Logger, reembedder *retrieval.Reembedder, open retrieval.EmbeddingIndexOpener) *ReembedHandler {
+	return &ReembedHandler{
+		logger:     logger,
+		reembedder: reembedder,
+		open:       open,
+	}
+}
+
+// ReembedRequestDTO represents the API request to start a re-embedding job.
+type ReembedRequestDTO struct {
+	Model   string `json:"model"`
+	Version string `json:"version"`
+	// CampaignID restricts the job to one campaign; empty re-embeds the whole tenant.
+	CampaignID string `json:"campaignId,omitempty"`
+}
+
+// ReembedJobDTO represents a re-embedding job and its progress.
+type ReembedJobDTO struct {
+	ID              string  `json:"id"`
+	TenantID        string  `json:"tenantId"`
+	CampaignID      string  `json:"campaignId,omitempty"`
+	Model           string  `json:"model"`
+	Version         string  `json:"version"`
+	PreviousModel   string  `json:"previousModel,omitempty"`
+	PreviousVersion string  `json:"previousVersion,omitempty"`
+	Status          string  `json:"status"`
+	TotalChunks     int64   `json:"totalChunks"`
+	ProcessedChunks int64   `json:"processedChunks"`
+	Progress        float64 `json:"progress"`
+	CancelRequested bool    `json:"cancelRequested,omitempty"`
+	Error           string  `json:"error,omitempty"`
+	StartedAt       string  `json:"startedAt"`
+	CompletedAt     string  `json:"completedAt,omitempty"`
+}
+
+// Start handles POST /tenants/{tenantId}/reembed.
+func (h *ReembedHandler) Start(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	tenantID, err := uuid.Parse(chi.URLParam(r, "tenantId"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid tenantId", err.Error())
+		return
+	}
+
+	var reqDTO ReembedRequestDTO
+	if err := json.NewDecoder(r.Body).Decode(&reqDTO); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid request body", err.Error())
+		return
+	}
+	if reqDTO.Model == "" || reqDTO.Version == "" {
+		h.writeError(w, http.StatusBadRequest, "model and version are required", "")
+		return
+	}
+	var campaignID *uuid.UUID
+	if reqDTO.CampaignID != "" {
+		id, err := uuid.Parse(reqDTO.CampaignID)
+		if err != nil {
+			h.writeError(w, http.StatusBadRequest, "invalid campaignId", err.Error())
+			return
+		}
+		campaignID = &id
+	}
+
+	idx, err := h.open(ctx, reqDTO.Model, reqDTO.Version)
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "cannot open embedding index", err.Error())
+		return
+	}
+
+	h.logger.Info().
+		Str("tenant_id", tenantID.String()).
+		Str("model", reqDTO.Model).
+		Str("embedding_version", reqDTO.Version).
+		Msg("Starting re-embedding")
+
+	job, err := h.reembedder.Start(ctx, retrieval.ReembedRequest{
+		TenantID:   tenantID,
+		CampaignID: campaignID,
+		Version:    reqDTO.Version,
+		Embedder:   idx.Embedder,
+		Index:      idx.Adapter,
+	})
+	if err != nil {
+		h.writeError(w, http.StatusInternalServerError, "re-embedding failed to start", err.Error())
+		return
+	}
+	h.writeJob(w, http.StatusAccepted, job)
+}
+
+// Get handles GET /tenants/{tenantId}/reembed/{jobId}.
+func (h *ReembedHandler) Get(w http.ResponseWriter, r *http.Request) {
+	job, ok := h.loadJob(w, r)
+	if !ok {
+		return
+	}
+	h.writeJob(w, http.StatusOK, job)
+}
+
+// Resume handles POST /tenants/{tenantId}/reembed/{jobId}/resume.
+func (h *ReembedHandler) Resume(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	job, ok := h.loadJob(w, r)
+	if !ok {
+		return
+	}
+
+	idx, err := h.open(ctx, job.EmbeddingModel, job.EmbeddingVersion)
+	if err != nil {
+		h.writeError(w, http.StatusConflict, "cannot open embedding index", err.Error())
+		return
+	}
+	resumed, err := h.reembedder.Resume(ctx, job.ID, idx.Embedder, idx.Adapter)
+	if err != nil {
+		h.writeError(w, h.status(err), "re-embedding failed to resume", err.Error())
+		return
+	}
+	h.writeJob(w, http.StatusAccepted, resumed)
+}
+
+// Cancel handles POST /tenants/{tenantId}/reembed/{jobId}/cancel. The job
+// pauses at its next batch, on whichever instance runs it.
+func (h *ReembedHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	job, ok := h.loadJob(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.reembedder.RequestCancel(ctx, job.ID); err != nil {
+		h.writeError(w, h.status(err), "re-embedding failed to cancel", err.Error())
+		return
+	}
+	job, err := h.reembedder.Job(ctx, job.ID)
+	if err != nil {
+		h.writeError(w, http.StatusInternalServerError, "failed to load re-embedding job", err.Error())
+		return
+	}
+	h.writeJob(w, http.StatusAccepted, job)
+}
+
+// Rollback handles POST /tenants/{tenantId}/reembed/{jobId}/rollback.
+func (h *ReembedHandler) Rollback(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	job, ok := h.loadJob(w, r)
+	if !ok {
+		return
+	}
+
+	rolledBack, err := h.reembedder.Rollback(ctx, job.ID)
+	if err != nil {
+		h.writeError(w, h.status(err), "re-embedding failed to roll back", err.Error())
+		return
+	}
+	h.writeJob(w, http.StatusOK, rolledBack)
+}
+
+// loadJob loads the job named in the path, writing an error response unless
+// it belongs to the tenant in the path.
+func (h *ReembedHandler) loadJob(w http.ResponseWriter, r *http.Request) (*storage.ReembedJob, bool) {
+	tenantID, err := uuid.Parse(chi.URLParam(r, "tenantId"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid tenantId", err.Error())
+		return nil, false
+	}
+	jobID, err := uuid.Parse(chi.URLParam(r, "jobId"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid jobId", err.Error())
+		return nil, false
+	}
+
+	job, err := h.reembedder.Job(r.Context(), jobID)
+	if errors.Is(err, storage.ErrNotFound) || (err == nil && job.TenantID != tenantID) {
+		h.writeError(w, http.StatusNotFound, "re-embedding job not found", "")
+		return nil, false
+	}
+	if err != nil {
+		h.writeError(w, http.StatusInternalServerError, "failed to load re-embedding job", err.Error())
+		return nil, false
+	}
+	return job, true
+}
+
+// status maps a re-embedder error to a response status. Jobs in the wrong
+// state for the request are conflicts.
+func (h *ReembedHandler) status(err error) int {
+	if errors.Is(err, storage.ErrNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusConflict
+}
+
+func (h *ReembedHandler) writeJob(w http.ResponseWriter, status int, job *storage.ReembedJob) {
+	resp := ReembedJobDTO{
+		ID:              job.ID.String(),
+		TenantID:        job.TenantID.String(),
+		Model:           job.EmbeddingModel,
+		Version:         job.EmbeddingVersion,
+		Status:          string(job.Status),
+		TotalChunks:     job.TotalChunks,
+		ProcessedChunks: job.ProcessedChunks,
+		Progress:        job.Progress(),
+		CancelRequested: job.CancelRequested,
+		StartedAt:       job.StartedAt.Format(time.RFC3339),
+	}
+	if job.CampaignVariantID != nil {
+		resp.CampaignID = job.CampaignVariantID.String()
+	}
+	if job.PreviousEmbeddingModel != nil {
+		resp.PreviousModel = *job.PreviousEmbeddingModel
+	}
+	if job.PreviousEmbeddingVersion != nil {
+		resp.PreviousVersion = *job.PreviousEmbeddingVersion
+	}
+	if job.Error != nil {
+		resp.Error = *job.Error
+	}
+	if job.CompletedAt != nil {
+		resp.CompletedAt = job.CompletedAt.Format(time.RFC3339)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (h *ReembedHandler) writeError(w http.ResponseWriter, status int, message, detail string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	resp := map[string]string{
+		"error":   message,
+		"message": message,
+	}
+	if detail != "" {
+		resp["detail"] = detail
+	}
+	json.NewEncoder(w).Encode(resp)
+}
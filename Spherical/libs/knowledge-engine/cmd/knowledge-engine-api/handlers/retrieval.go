@@ -2,11 +2,15 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/spherical-ai/spherical/libs/knowledge-engine/cmd/knowledge-engine-api/middleware"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/comparison"
 	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/monitoring"
 	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/observability"
 	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/retrieval"
@@ -40,6 +44,14 @@ type RetrievalRequestDTO struct {
 	Filters             *RetrievalFiltersDTO   `json:"filters,omitempty"`
 	MaxChunks           int                    `json:"maxChunks,omitempty"`
 	IncludeLineage      bool                   `json:"includeLineage,omitempty"`
+	// Explain returns a trace of how the query was answered.
+	Explain bool `json:"explain,omitempty"`
+	// IncludeBenchmarks adds other tenants' public benchmark products, labelled in the response.
+	IncludeBenchmarks bool `json:"includeBenchmarks,omitempty"`
+	// AsOf answers from the campaign versions live at this RFC 3339 time.
+	AsOf string `json:"asOf,omitempty"`
+	// CampaignVersion answers from this version of the campaign, taking precedence over asOf.
+	CampaignVersion *int `json:"campaignVersion,omitempty"`
 }
 
 // ConversationMessage represents a conversation turn.
@@ -62,6 +74,94 @@ type RetrievalResponseDTO struct {
 	SemanticChunks  []SemanticChunkDTO `json:"semanticChunks"`
 	Comparisons     []ComparisonDTO  `json:"comparisons,omitempty"`
 	Lineage         []LineageDTO     `json:"lineage,omitempty"`
+	Evidence        []EvidenceDTO    `json:"evidence,omitempty"`
+	// RewrittenQuestion is set when a follow-up was resolved against the conversation.
+	RewrittenQuestion string `json:"rewrittenQuestion,omitempty"`
+	// Links are the products and trims recognized in the question.
+	Links []EntityLinkDTO `json:"links,omitempty"`
+	// Predicates are the numeric constraints evaluated against spec values.
+	Predicates []PredicateDTO `json:"predicates,omitempty"`
+	// Availability tabulates attribute values per trim for availability questions.
+	Availability []AvailabilityDTO `json:"availability,omitempty"`
+	// Trace explains how the query was answered, set when explain was requested.
+	Trace *TraceDTO `json:"trace,omitempty"`
+	// Snapshot names the campaign versions used, set when asOf or campaignVersion was requested.
+	Snapshot *SnapshotDTO `json:"snapshot,omitempty"`
+}
+
+// SnapshotDTO names the campaign versions a point-in-time response was answered from.
+type SnapshotDTO struct {
+	AsOf     string               `json:"asOf,omitempty"`
+	Versions []CampaignVersionDTO `json:"versions"`
+}
+
+// TraceDTO represents the explain trace of a retrieval query.
+type TraceDTO struct {
+	Question          string              `json:"question"`
+	Intent            string              `json:"intent"`
+	ClassifiedIntent  string              `json:"classifiedIntent"`
+	IntentConfidence  float64             `json:"intentConfidence"`
+	IntentHinted      bool                `json:"intentHinted"`
+	Keywords          []string            `json:"keywords"`
+	Expansions        []ExpansionDTO      `json:"expansions"`
+	Path              string              `json:"path"`
+	KeywordConfidence float64             `json:"keywordConfidence"`
+	ConfidenceFactors map[string]float64  `json:"confidenceFactors,omitempty"`
+	Cache             string              `json:"cache"`
+	Candidates        []TraceCandidateDTO `json:"candidates"`
+	Filters           []FilterDecisionDTO `json:"filters"`
+	Stages            []StageTimingDTO    `json:"stages"`
+}
+
+// ExpansionDTO represents a weighted term searched alongside the question.
+type ExpansionDTO struct {
+	Term     string  `json:"term"`
+	Original string  `json:"original"`
+	Source   string  `json:"source"`
+	Weight   float64 `json:"weight"`
+}
+
+// TraceCandidateDTO represents a scored retrieval candidate.
+type TraceCandidateDTO struct {
+	Kind       string             `json:"kind"`
+	ID         string             `json:"id"`
+	Label      string             `json:"label,omitempty"`
+	Source     string             `json:"source"`
+	Score      float64            `json:"score"`
+	Components map[string]float64 `json:"components,omitempty"`
+}
+
+// FilterDecisionDTO represents why a candidate was kept or dropped.
+type FilterDecisionDTO struct {
+	Stage   string `json:"stage"`
+	Subject string `json:"subject"`
+	Kept    bool   `json:"kept"`
+	Reason  string `json:"reason"`
+}
+
+// StageTimingDTO represents the duration of a query stage.
+type StageTimingDTO struct {
+	Stage      string  `json:"stage"`
+	DurationMs float64 `json:"durationMs"`
+}
+
+// AnswerResponseDTO represents a composed answer with its citations.
+type AnswerResponseDTO struct {
+	Question  string        `json:"question"`
+	Answer    string        `json:"answer"`
+	Outcome   string        `json:"outcome"`
+	Generator string        `json:"generator"`
+	Citations []CitationDTO `json:"citations"`
+	// Retrieval is the evidence the answer was composed from.
+	Retrieval RetrievalResponseDTO `json:"retrieval"`
+}
+
+// CitationDTO represents evidence cited in an answer as [index].
+type CitationDTO struct {
+	Index  int       `json:"index"`
+	Kind   string    `json:"kind"`
+	Text   string    `json:"text"`
+	Source SourceDTO `json:"source"`
 }
 
 // SpecFactDTO represents a structured fact.
@@ -73,7 +173,16 @@ type SpecFactDTO struct {
 	Unit              string    `json:"unit,omitempty"`
 	Confidence        float64   `json:"confidence"`
 	CampaignVariantID string    `json:"campaignVariantId"`
+	Origin            string    `json:"origin,omitempty"`
 	Source            SourceDTO `json:"source"`
+	// Benchmark is set when the fact comes from another tenant's benchmark product.
+	Benchmark *BenchmarkDTO `json:"benchmark,omitempty"`
+}
+
+// BenchmarkDTO labels a result sourced from another tenant's public benchmark product.
+type BenchmarkDTO struct {
+	ProductID   string `json:"productId"`
+	ProductName string `json:"productName"`
 }
 
 // SemanticChunkDTO represents a semantic chunk.
@@ -84,6 +193,64 @@ type SemanticChunkDTO struct {
 	Distance  float32                `json:"distance"`
 	Metadata  map[string]interface{} `json:"metadata,omitempty"`
 	Source    SourceDTO              `json:"source"`
+	// Benchmark is set when the chunk comes from another tenant's benchmark product.
+	Benchmark *BenchmarkDTO `json:"benchmark,omitempty"`
+}
+
+// EvidenceDTO represents an item of the fused evidence ranking.
+type EvidenceDTO struct {
+	Kind        string            `json:"kind"`
+	Score       float64           `json:"score"`
+	LexicalRank int               `json:"lexicalRank,omitempty"`
+	VectorRank  int               `json:"vectorRank,omitempty"`
+	Fact        *SpecFactDTO      `json:"fact,omitempty"`
+	Chunk       *SemanticChunkDTO `json:"chunk,omitempty"`
+}
+
+// EntityLinkDTO represents a product or trim mention linked to the catalogue.
+type EntityLinkDTO struct {
+	Kind       string  `json:"kind"`
+	ID         string  `json:"id"`
+	ProductID  string  `json:"productId"`
+	Name       string  `json:"name"`
+	Mention    string  `json:"mention"`
+	ModelYear  int     `json:"modelYear,omitempty"`
+	Method     string  `json:"method"`
+	Confidence float64 `json:"confidence"`
+	Applied    bool    `json:"applied"`
+}
+
+// PredicateDTO represents a numeric predicate parsed from the question.
+type PredicateDTO struct {
+	Op        string  `json:"op"`
+	Value     float64 `json:"value,omitempty"`
+	Upper     float64 `json:"upper,omitempty"`
+	Unit      string  `json:"unit,omitempty"`
+	Dimension string  `json:"dimension,omitempty"`
+	Attribute string  `json:"attribute,omitempty"`
+	Text      string  `json:"text"`
+}
+
+// AvailabilityDTO represents the values of one attribute offered per trim.
+type AvailabilityDTO struct {
+	Category string               `json:"category"`
+	Name     string               `json:"name"`
+	Trims    []AvailabilityTrimDTO `json:"trims"`
+	Rows     []AvailabilityRowDTO  `json:"rows"`
+}
+
+// AvailabilityTrimDTO represents an availability matrix column.
+type AvailabilityTrimDTO struct {
+	CampaignVariantID string `json:"campaignVariantId"`
+	ProductID         string `json:"productId"`
+	ProductName       string `json:"productName,omitempty"`
+	Trim              string `json:"trim,omitempty"`
+}
+
+// AvailabilityRowDTO represents an availability matrix row, one cell per trim.
+type AvailabilityRowDTO struct {
+	Value     string `json:"value"`
+	Available []bool `json:"available"`
 }
 
 // ComparisonDTO represents a comparison result.
@@ -95,15 +262,19 @@ type ComparisonDTO struct {
 	SecondaryValue     string `json:"secondaryValue,omitempty"`
 	Verdict            string `json:"verdict"`
 	Narrative          string `json:"narrative,omitempty"`
+	// Benchmark is set when the secondary product is another tenant's benchmark product.
+	Benchmark *BenchmarkDTO `json:"benchmark,omitempty"`
 }
 
 // LineageDTO represents a lineage event.
 type LineageDTO struct {
-	ResourceType     string `json:"resourceType"`
-	ResourceID       string `json:"resourceId"`
-	Action           string `json:"action"`
-	DocumentSourceID string `json:"documentSourceId,omitempty"`
-	OccurredAt       string `json:"occurredAt"`
+	ResourceType      string `json:"resourceType"`
+	ResourceID        string `json:"resourceId"`
+	Action            string `json:"action"`
+	DocumentSourceID  string `json:"documentSourceId,omitempty"`
+	OccurredAt        string `json:"occurredAt"`
+	CampaignVariantID string `json:"campaignVariantId,omitempty"`
+	Origin            string `json:"origin,omitempty"`
 }
 
 // SourceDTO represents a source reference.
@@ -117,17 +288,130 @@ type SourceDTO struct {
 func (h *RetrievalHandler) Query(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
+	req, ok := h.parseRequest(w, r)
+	if !ok {
+		return
+	}
+
+	// Execute query
+	resp, err := h.router.Query(ctx, req)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("Query failed")
+		h.writeError(w, queryErrorStatus(err), "query failed", err.Error())
+		return
+	}
+
+	h.recordRetrieval(ctx, req, resp)
+
+	// Convert to DTO
+	respDTO := h.toResponseDTO(resp)
+
+	// Write response
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(respDTO); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to encode response")
+	}
+}
+
+// Answer handles POST /retrieval/answer.
+func (h *RetrievalHandler) Answer(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	req, ok := h.parseRequest(w, r)
+	if !ok {
+		return
+	}
+
+	answer, err := h.router.Answer(ctx, req)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("Answer failed")
+		h.writeError(w, queryErrorStatus(err), "answer failed", err.Error())
+		return
+	}
+
+	h.recordRetrieval(ctx, req, answer.Retrieval)
+
+	respDTO := AnswerResponseDTO{
+		Question:  answer.Question,
+		Answer:    answer.Text,
+		Outcome:   string(answer.Outcome),
+		Generator: answer.Generator,
+		Citations: make([]CitationDTO, 0, len(answer.Citations)),
+		Retrieval: h.toResponseDTO(answer.Retrieval),
+	}
+	for _, c := range answer.Citations {
+		respDTO.Citations = append(respDTO.Citations, CitationDTO{
+			Index:  c.Index,
+			Kind:   string(c.Kind),
+			Text:   c.Text,
+			Source: h.toSourceDTO(c.Source),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(respDTO); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to encode response")
+	}
+}
+
+// CacheStatsDTO represents the API response for semantic cache statistics.
+type CacheStatsDTO struct {
+	Enabled bool    `json:"enabled"`
+	Hits    int64   `json:"hits"`
+	Misses  int64   `json:"misses"`
+	HitRate float64 `json:"hitRate"`
+	Entries int64   `json:"entries"`
+	// Embeddings reports reuse of cached question embeddings, when enabled.
+	Embeddings *EmbeddingCacheStatsDTO `json:"embeddings,omitempty"`
+}
+
+// EmbeddingCacheStatsDTO represents embedding cache statistics.
+type EmbeddingCacheStatsDTO struct {
+	Hits    int64   `json:"hits"`
+	Misses  int64   `json:"misses"`
+	HitRate float64 `json:"hitRate"`
+}
+
+// CacheStats handles GET /retrieval/cache/stats.
+func (h *RetrievalHandler) CacheStats(w http.ResponseWriter, r *http.Request) {
+	stats := h.router.CacheStats()
+	respDTO := CacheStatsDTO{
+		Enabled: stats.Enabled,
+		Hits:    stats.Hits,
+		Misses:  stats.Misses,
+		HitRate: stats.HitRate,
+		Entries: stats.KeyCount,
+	}
+	if embeddings := h.router.EmbeddingCacheStats(); embeddings != nil {
+		respDTO.Embeddings = &EmbeddingCacheStatsDTO{
+			Hits:    embeddings.Hits,
+			Misses:  embeddings.Misses,
+			HitRate: embeddings.HitRate,
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(respDTO); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to encode response")
+	}
+}
+
+// parseRequest decodes and validates a retrieval request body, writing an
+// error response and returning false when it is invalid.
+func (h *RetrievalHandler) parseRequest(w http.ResponseWriter, r *http.Request) (retrieval.RetrievalRequest, bool) {
+	ctx := r.Context()
+
 	// Parse request body
 	var reqDTO RetrievalRequestDTO
 	if err := json.NewDecoder(r.Body).Decode(&reqDTO); err != nil {
 		h.writeError(w, http.StatusBadRequest, "invalid request body", err.Error())
-		return
+		return retrieval.RetrievalRequest{}, false
 	}
 
 	// Validate required fields
 	if reqDTO.Question == "" {
 		h.writeError(w, http.StatusBadRequest, "question is required", "")
-		return
+		return retrieval.RetrievalRequest{}, false
 	}
 
 	// Get tenant from context or request
@@ -137,13 +421,13 @@ func (h *RetrievalHandler) Query(w http.ResponseWriter, r *http.Request) {
 	}
 	if tenantIDStr == "" {
 		h.writeError(w, http.StatusBadRequest, "tenantId is required", "")
-		return
+		return retrieval.RetrievalRequest{}, false
 	}
 
 	tenantID, err := uuid.Parse(tenantIDStr)
 	if err != nil {
 		h.writeError(w, http.StatusBadRequest, "invalid tenantId", err.Error())
-		return
+		return retrieval.RetrievalRequest{}, false
 	}
 
 	// Parse product IDs
@@ -162,6 +446,13 @@ func (h *RetrievalHandler) Query(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Parse the point in time to answer from
+	asOf, err := parseAsOf(reqDTO.AsOf)
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid asOf", err.Error())
+		return retrieval.RetrievalRequest{}, false
+	}
+
 	// Parse intent hint
 	var intentHint *retrieval.Intent
 	if reqDTO.IntentHint != "" {
@@ -178,41 +469,91 @@ func (h *RetrievalHandler) Query(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Parse conversation context
+	var conversation []retrieval.ConversationMessage
+	for _, msg := range reqDTO.ConversationContext {
+		conversation = append(conversation, retrieval.ConversationMessage{Role: msg.Role, Content: msg.Content})
+	}
+
 	// Build request
 	req := retrieval.RetrievalRequest{
-		TenantID:          tenantID,
-		ProductIDs:        productIDs,
-		CampaignVariantID: campaignVariantID,
-		Question:          reqDTO.Question,
-		IntentHint:        intentHint,
-		Filters:           filters,
-		MaxChunks:         reqDTO.MaxChunks,
-		IncludeLineage:    reqDTO.IncludeLineage,
+		TenantID:            tenantID,
+		ProductIDs:          productIDs,
+		CampaignVariantID:   campaignVariantID,
+		Question:            reqDTO.Question,
+		IntentHint:          intentHint,
+		ConversationContext: conversation,
+		Filters:             filters,
+		MaxChunks:           reqDTO.MaxChunks,
+		IncludeLineage:      reqDTO.IncludeLineage,
+		Explain:             reqDTO.Explain,
+		IncludeBenchmarks:   reqDTO.IncludeBenchmarks,
+		AsOf:                asOf,
+		CampaignVersion:     reqDTO.CampaignVersion,
 	}
 
-	// Execute query
-	resp, err := h.router.Query(ctx, req)
+	return req, true
+}
+
+// parseAsOf parses an optional RFC 3339 time.
+func parseAsOf(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
 	if err != nil {
-		h.logger.Error().Err(err).Msg("Query failed")
-		h.writeError(w, http.StatusInternalServerError, "query failed", err.Error())
-		return
+		return nil, err
 	}
+	return &t, nil
+}
 
-	// Record audit event for retrieval request (T038)
-	if h.lineageWriter != nil {
-		resultCount := len(resp.StructuredFacts) + len(resp.SemanticChunks)
-		if err := h.lineageWriter.RecordRetrievalRequest(ctx, tenantID, productIDs, req.Question, string(resp.Intent), resultCount); err != nil {
-			h.logger.Warn().Err(err).Msg("Failed to record retrieval audit event")
-		}
+// queryErrorStatus maps a retrieval error to an HTTP status.
+func queryErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, retrieval.ErrBenchmarksUnavailable):
+		return http.StatusBadRequest
+	case errors.Is(err, storage.ErrNoCampaignVersion), errors.Is(err, storage.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, retrieval.ErrSnapshotUnavailable), errors.Is(err, comparison.ErrSnapshotUnavailable):
+		return http.StatusNotImplemented
+	default:
+		return http.StatusInternalServerError
 	}
+}
 
-	// Convert to DTO
-	respDTO := h.toResponseDTO(resp)
+// toSnapshotDTO describes the campaign versions a point-in-time response used.
+func toSnapshotDTO(asOf *time.Time, versions []retrieval.CampaignVersion) *SnapshotDTO {
+	dto := &SnapshotDTO{Versions: make([]CampaignVersionDTO, 0, len(versions))}
+	if asOf != nil {
+		dto.AsOf = asOf.Format(time.RFC3339)
+	}
+	for _, v := range versions {
+		item := CampaignVersionDTO{
+			CampaignID: v.CampaignVariantID.String(),
+			ProductID:  v.ProductID.String(),
+			Version:    v.Version,
+			Status:     string(v.Status),
+		}
+		if v.EffectiveFrom != nil {
+			item.EffectiveFrom = v.EffectiveFrom.Format(time.RFC3339)
+		}
+		if v.EffectiveThrough != nil {
+			through := v.EffectiveThrough.Format(time.RFC3339)
+			item.EffectiveThrough = &through
+		}
+		dto.Versions = append(dto.Versions, item)
+	}
+	return dto
+}
 
-	// Write response
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(respDTO); err != nil {
-		h.logger.Error().Err(err).Msg("Failed to encode response")
+// recordRetrieval records an audit event for a retrieval request (T038).
+func (h *RetrievalHandler) recordRetrieval(ctx context.Context, req retrieval.RetrievalRequest, resp *retrieval.RetrievalResponse) {
+	if h.lineageWriter == nil {
+		return
+	}
+	resultCount := len(resp.StructuredFacts) + len(resp.SemanticChunks)
+	if err := h.lineageWriter.RecordRetrievalRequest(ctx, req.TenantID, req.ProductIDs, req.Question, string(resp.Intent), resultCount); err != nil {
+		h.logger.Warn().Err(err).Msg("Failed to record retrieval audit event")
 	}
 }
 
@@ -223,31 +564,94 @@ func (h *RetrievalHandler) toResponseDTO(resp *retrieval.RetrievalResponse) Retr
 		StructuredFacts: make([]SpecFactDTO, 0, len(resp.StructuredFacts)),
 		SemanticChunks:  make([]SemanticChunkDTO, 0, len(resp.SemanticChunks)),
 	}
+	if resp.Rewrite != nil && resp.Rewrite.Rewritten {
+		dto.RewrittenQuestion = resp.Rewrite.Question
+	}
 
 	for _, fact := range resp.StructuredFacts {
-		dto.StructuredFacts = append(dto.StructuredFacts, SpecFactDTO{
-			SpecItemID:        fact.SpecItemID.String(),
-			Category:          fact.Category,
-			Name:              fact.Name,
-			Value:             fact.Value,
-			Unit:              fact.Unit,
-			Confidence:        fact.Confidence,
-			CampaignVariantID: fact.CampaignVariantID.String(),
-			Source:            h.toSourceDTO(fact.Source),
-		})
+		dto.StructuredFacts = append(dto.StructuredFacts, h.toSpecFactDTO(fact))
 	}
 
 	for _, chunk := range resp.SemanticChunks {
-		dto.SemanticChunks = append(dto.SemanticChunks, SemanticChunkDTO{
-			ChunkID:   chunk.ChunkID.String(),
-			ChunkType: string(chunk.ChunkType),
-			Text:      chunk.Text,
-			Distance:  chunk.Distance,
-			Metadata:  chunk.Metadata,
-			Source:    h.toSourceDTO(chunk.Source),
+		dto.SemanticChunks = append(dto.SemanticChunks, h.toSemanticChunkDTO(chunk))
+	}
+
+	for _, e := range resp.Evidence {
+		item := EvidenceDTO{
+			Kind:        string(e.Kind),
+			Score:       e.Score,
+			LexicalRank: e.LexicalRank,
+			VectorRank:  e.VectorRank,
+		}
+		if e.Fact != nil {
+			fact := h.toSpecFactDTO(*e.Fact)
+			item.Fact = &fact
+		}
+		if e.Chunk != nil {
+			chunk := h.toSemanticChunkDTO(*e.Chunk)
+			item.Chunk = &chunk
+		}
+		dto.Evidence = append(dto.Evidence, item)
+	}
+
+	for _, link := range resp.Links {
+		item := EntityLinkDTO{
+			Kind:       string(link.Kind),
+			ID:         link.ID.String(),
+			ProductID:  link.ProductID.String(),
+			Name:       link.Name,
+			Mention:    link.Mention,
+			Method:     string(link.Method),
+			Confidence: link.Confidence,
+			Applied:    link.Applied,
+		}
+		if link.ModelYear != nil {
+			item.ModelYear = int(*link.ModelYear)
+		}
+		dto.Links = append(dto.Links, item)
+	}
+
+	for _, p := range resp.Predicates {
+		dto.Predicates = append(dto.Predicates, PredicateDTO{
+			Op:        string(p.Op),
+			Value:     p.Value,
+			Upper:     p.Upper,
+			Unit:      p.Unit,
+			Dimension: string(p.Dimension),
+			Attribute: p.Attribute,
+			Text:      p.Text,
 		})
 	}
 
+	for _, matrix := range resp.Availability {
+		item := AvailabilityDTO{
+			Category: matrix.Category,
+			Name:     matrix.Name,
+			Trims:    make([]AvailabilityTrimDTO, 0, len(matrix.Trims)),
+			Rows:     make([]AvailabilityRowDTO, 0, len(matrix.Rows)),
+		}
+		for _, trim := range matrix.Trims {
+			item.Trims = append(item.Trims, AvailabilityTrimDTO{
+				CampaignVariantID: trim.CampaignVariantID.String(),
+				ProductID:         trim.ProductID.String(),
+				ProductName:       trim.ProductName,
+				Trim:              trim.Trim,
+			})
+		}
+		for _, row := range matrix.Rows {
+			item.Rows = append(item.Rows, AvailabilityRowDTO{Value: row.Value, Available: row.Available})
+		}
+		dto.Availability = append(dto.Availability, item)
+	}
+
+	if resp.Trace != nil {
+		dto.Trace = h.toTraceDTO(resp.Trace)
+	}
+
+	if resp.Snapshot != nil {
+		dto.Snapshot = toSnapshotDTO(resp.Snapshot.AsOf, resp.Snapshot.Versions)
+	}
+
 	for _, comp := range resp.Comparisons {
 		dto.Comparisons = append(dto.Comparisons, ComparisonDTO{
 			Dimension:          comp.Dimension,
@@ -257,6 +661,7 @@ func (h *RetrievalHandler) toResponseDTO(resp *retrieval.RetrievalResponse) Retr
 			SecondaryValue:     comp.SecondaryValue,
 			Verdict:            string(comp.Verdict),
 			Narrative:          comp.Narrative,
+			Benchmark:          toBenchmarkDTO(comp.Benchmark),
 		})
 	}
 
@@ -265,18 +670,102 @@ func (h *RetrievalHandler) toResponseDTO(resp *retrieval.RetrievalResponse) Retr
 		if lineage.DocumentSourceID != nil {
 			docID = lineage.DocumentSourceID.String()
 		}
+		campaignID := ""
+		if lineage.CampaignVariantID != nil {
+			campaignID = lineage.CampaignVariantID.String()
+		}
 		dto.Lineage = append(dto.Lineage, LineageDTO{
-			ResourceType:     lineage.ResourceType,
-			ResourceID:       lineage.ResourceID.String(),
-			Action:           string(lineage.Action),
-			DocumentSourceID: docID,
-			OccurredAt:       lineage.OccurredAt.Format("2006-01-02T15:04:05Z07:00"),
+			ResourceType:      lineage.ResourceType,
+			ResourceID:        lineage.ResourceID.String(),
+			Action:            string(lineage.Action),
+			DocumentSourceID:  docID,
+			OccurredAt:        lineage.OccurredAt.Format("2006-01-02T15:04:05Z07:00"),
+			CampaignVariantID: campaignID,
+			Origin:            string(lineage.Origin),
 		})
 	}
 
 	return dto
 }
 
+func (h *RetrievalHandler) toTraceDTO(trace *retrieval.Trace) *TraceDTO {
+	dto := &TraceDTO{
+		Question:          trace.Question,
+		Intent:            string(trace.Intent),
+		ClassifiedIntent:  string(trace.ClassifiedIntent),
+		IntentConfidence:  trace.IntentConfidence,
+		IntentHinted:      trace.IntentHinted,
+		Keywords:          trace.Keywords,
+		Expansions:        make([]ExpansionDTO, 0, len(trace.Expansions)),
+		Path:              string(trace.Path),
+		KeywordConfidence: trace.KeywordConfidence,
+		ConfidenceFactors: trace.ConfidenceFactors,
+		Cache:             trace.Cache,
+		Candidates:        make([]TraceCandidateDTO, 0, len(trace.Candidates)),
+		Filters:           make([]FilterDecisionDTO, 0, len(trace.Filters)),
+		Stages:            make([]StageTimingDTO, 0, len(trace.Stages)),
+	}
+	for _, e := range trace.Expansions {
+		dto.Expansions = append(dto.Expansions, ExpansionDTO{Term: e.Term, Original: e.Original, Source: string(e.Source), Weight: e.Weight})
+	}
+	for _, c := range trace.Candidates {
+		dto.Candidates = append(dto.Candidates, TraceCandidateDTO{
+			Kind:       string(c.Kind),
+			ID:         c.ID.String(),
+			Label:      c.Label,
+			Source:     c.Source,
+			Score:      c.Score,
+			Components: c.Components,
+		})
+	}
+	for _, f := range trace.Filters {
+		dto.Filters = append(dto.Filters, FilterDecisionDTO{Stage: f.Stage, Subject: f.Subject, Kept: f.Kept, Reason: f.Reason})
+	}
+	for _, st := range trace.Stages {
+		dto.Stages = append(dto.Stages, StageTimingDTO{Stage: st.Stage, DurationMs: st.DurationMs})
+	}
+	return dto
+}
+
+func (h *RetrievalHandler) toSpecFactDTO(fact retrieval.SpecFact) SpecFactDTO {
+	dto := SpecFactDTO{
+		SpecItemID:        fact.SpecItemID.String(),
+		Category:          fact.Category,
+		Name:              fact.Name,
+		Value:             fact.Value,
+		Unit:              fact.Unit,
+		Confidence:        fact.Confidence,
+		CampaignVariantID: fact.CampaignVariantID.String(),
+		Origin:            string(fact.Origin),
+		Source:            h.toSourceDTO(fact.Source),
+		Benchmark:         toBenchmarkDTO(fact.Benchmark),
+	}
+	if fact.Benchmark != nil {
+		// Benchmark facts carry no campaign of the requesting tenant
+		dto.CampaignVariantID = ""
+	}
+	return dto
+}
+
+func (h *RetrievalHandler) toSemanticChunkDTO(chunk retrieval.SemanticChunk) SemanticChunkDTO {
+	return SemanticChunkDTO{
+		ChunkID:   chunk.ChunkID.String(),
+		ChunkType: string(chunk.ChunkType),
+		Text:      chunk.Text,
+		Distance:  chunk.Distance,
+		Metadata:  chunk.Metadata,
+		Source:    h.toSourceDTO(chunk.Source),
+		Benchmark: toBenchmarkDTO(chunk.Benchmark),
+	}
+}
+
+func toBenchmarkDTO(ref *retrieval.BenchmarkRef) *BenchmarkDTO {
+	if ref == nil {
+		return nil
+	}
+	return &BenchmarkDTO{ProductID: ref.ProductID.String(), ProductName: ref.ProductName}
+}
+
 func (h *RetrievalHandler) toSourceDTO(src retrieval.SourceRef) SourceDTO {
 	dto := SourceDTO{}
 	if src.DocumentSourceID != nil {
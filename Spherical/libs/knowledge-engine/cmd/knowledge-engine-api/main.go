@@ -3,14 +3,23 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/cache"
 	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/config"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/embedding"
 	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/observability"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/retrieval"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/storage"
 )
 
 func main() {
@@ -33,6 +42,16 @@ func main() {
 		ServiceName: cfg.Observability.OTEL.ServiceName,
 	})
 
+	shutdownTracing, err := observability.InitTracing(observability.TracingConfig{
+		Enabled:     cfg.Observability.OTEL.Enabled,
+		Endpoint:    cfg.Observability.OTEL.Endpoint,
+		ServiceName: cfg.Observability.OTEL.ServiceName,
+		SampleRatio: cfg.Observability.OTEL.SampleRatio,
+	})
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Failed to initialize tracing")
+	}
+
 	logger.Info().
 		Str("host", cfg.Server.Host).
 		Int("port", cfg.Server.Port).
@@ -45,11 +64,122 @@ func main() {
 		RequestTimeout:     cfg.Server.ReadTimeout,
 		CacheSize:          cfg.Cache.MaxEntries,
 		CacheTTL:           cfg.Cache.TTL,
+		CacheRevalidate:    cfg.Retrieval.CacheRevalidateWindow,
 		MaxChunks:          cfg.Retrieval.MaxChunks,
 		MaxConcurrentJobs:  cfg.Ingestion.MaxConcurrentJobs,
 		EmbeddingDimension: cfg.Embedding.Dimension,
+		EmbeddingModel:     cfg.Embedding.Model,
 		DriftCheckInterval: cfg.Drift.CheckInterval,
 		StalenessWindow:    cfg.Drift.FreshnessThreshold,
+		Hybrid:             toHybridConfig(cfg.Retrieval.Hybrid),
+		TenantHybrid:       make(map[uuid.UUID]retrieval.HybridConfig),
+	}
+	for tenant, hybrid := range cfg.Retrieval.TenantHybrid {
+		tenantID, err := uuid.Parse(tenant)
+		if err != nil {
+			logger.Warn().Str("tenant", tenant).Msg("Ignoring hybrid override for invalid tenant ID")
+			continue
+		}
+		appCfg.TenantHybrid[tenantID] = toHybridConfig(hybrid)
+	}
+	appCfg.Expansion = retrieval.ExpansionConfig{
+		Disabled:              cfg.Retrieval.Expansion.Disabled,
+		Domain:                cfg.Retrieval.Expansion.Domain,
+		Synonyms:              cfg.Retrieval.Expansion.Synonyms,
+		TenantSynonyms:        make(map[uuid.UUID]map[string][]string),
+		MinSpellingSimilarity: cfg.Retrieval.Expansion.MinSpellingSimilarity,
+	}
+	for tenant, synonyms := range cfg.Retrieval.Expansion.TenantSynonyms {
+		tenantID, err := uuid.Parse(tenant)
+		if err != nil {
+			logger.Warn().Str("tenant", tenant).Msg("Ignoring synonyms for invalid tenant ID")
+			continue
+		}
+		appCfg.Expansion.TenantSynonyms[tenantID] = synonyms
+	}
+	appCfg.BenchmarksEnabled = cfg.Retrieval.Benchmarks.Enabled
+	appCfg.Benchmarks = retrieval.BenchmarkConfig{
+		MaxFacts:        cfg.Retrieval.Benchmarks.MaxFacts,
+		MaxChunks:       cfg.Retrieval.Benchmarks.MaxChunks,
+		RefreshInterval: cfg.Retrieval.Benchmarks.RefreshInterval,
+	}
+	appCfg.SemanticCache = retrieval.SemanticCacheConfig{
+		Enabled:             cfg.Retrieval.SemanticCache.Enabled,
+		SimilarityThreshold: cfg.Retrieval.SemanticCache.SimilarityThreshold,
+		TTL:                 cfg.Retrieval.SemanticCache.TTL,
+		MaxEntries:          cfg.Retrieval.SemanticCache.MaxEntries,
+		MaxScopes:           cfg.Retrieval.SemanticCache.MaxScopes,
+	}
+	appCfg.VectorQuantization = retrieval.QuantizationConfig{
+		Mode:             cfg.Vector.FAISS.Quantization.Mode,
+		CompressionRatio: cfg.Vector.FAISS.Quantization.CompressionRatio,
+		TrainSize:        cfg.Vector.FAISS.Quantization.TrainSize,
+		RerankFactor:     cfg.Vector.FAISS.Quantization.RerankFactor,
+		RetrainGrowth:    cfg.Vector.FAISS.Quantization.RetrainGrowth,
+	}
+	appCfg.AllowCrossTenant = cfg.Comparison.AllowCrossTenant
+	if cfg.Retrieval.IntentModelDir != "" {
+		shared, tenants, err := retrieval.LoadIntentModels(cfg.Retrieval.IntentModelDir)
+		if err != nil {
+			logger.Warn().Err(err).Msg("Failed to load intent models, using pattern classifier")
+		} else {
+			appCfg.IntentModel = shared
+			appCfg.TenantIntentModels = tenants
+			logger.Info().
+				Bool("shared", shared != nil).
+				Int("tenants", len(tenants)).
+				Msg("Intent models loaded")
+		}
+	}
+
+	// Replicas sharing Redis evict each other's caches; a single node uses an in-memory bus
+	appCfg.InvalidationBus = cache.NewMemoryInvalidationBus()
+	if cfg.Cache.Driver == "redis" {
+		redisClient, err := cache.NewRedisClient(cache.RedisConfig{
+			Addr:     cfg.Cache.Redis.Addr,
+			Password: cfg.Cache.Redis.Password,
+			DB:       cfg.Cache.Redis.DB,
+			PoolSize: cfg.Cache.Redis.PoolSize,
+		})
+		if err != nil {
+			logger.Warn().Err(err).Msg("Failed to connect to Redis, cache invalidation stays local")
+		} else {
+			defer redisClient.Close()
+			appCfg.InvalidationBus = cache.NewRedisInvalidationBus(redisClient)
+		}
+	}
+	if cfg.Retrieval.Answer.Generator == "openai" {
+		generator := retrieval.NewOpenAIGenerator(retrieval.OpenAIGeneratorConfig{
+			APIKey:  cfg.Retrieval.Answer.APIKey,
+			BaseURL: cfg.Retrieval.Answer.BaseURL,
+			Model:   cfg.Retrieval.Answer.Model,
+			Timeout: cfg.Retrieval.Answer.Timeout,
+		})
+		appCfg.AnswerGenerator = generator
+		if cfg.Retrieval.Answer.Rewriter == "llm" {
+			appCfg.QueryRewriter = retrieval.NewLLMRewriter(generator)
+		}
+	}
+
+	// Catalogue-backed features such as entity linking need the database;
+	// without it the API still serves from its in-memory indexes
+	db, err := openDatabase(cfg)
+	if err != nil && appCfg.VectorQuantization.Mode == retrieval.QuantizationPQ {
+		// PQ codes alone lose too much recall; their re-ranking reads the database
+		logger.Fatal().Err(err).Msg("Failed to open database, which pq vector quantization needs")
+	} else if err != nil {
+		logger.Warn().Err(err).Msg("Failed to open database, serving without catalogue")
+	} else {
+		defer db.Close()
+		appCfg.DB = db
+		appCfg.DatabaseDriver = cfg.Database.Driver
+		appCfg.EmbeddingIndexes = newEmbeddingIndexOpener(cfg, logger, db, appCfg.VectorQuantization)
+	}
+
+	// Questions are embedded with the live model for vector search and the semantic cache
+	if appCfg.Embedder, err = newModelEmbedder(context.Background(), cfg, logger, appCfg.DB, cfg.Embedding.Model); err != nil {
+		logger.Warn().Err(err).Str("model", cfg.Embedding.Model).
+			Msg("Failed to create embedder, serving without vector search or the semantic cache")
 	}
 
 	// Initialize router with all handlers
@@ -94,5 +224,144 @@ func main() {
 		}
 	}
 
+	if err := shutdownTracing(ctx); err != nil {
+		logger.Error().Err(err).Msg("Failed to flush traces")
+	}
+
 	logger.Info().Msg("Server stopped")
 }
+
+// openDatabase opens the configured SQLite or Postgres database and checks the connection.
+func openDatabase(cfg *config.Config) (*sql.DB, error) {
+	var driver string
+	switch cfg.Database.Driver {
+	case "sqlite":
+		driver = "sqlite3"
+	case "postgres":
+		driver = "postgres"
+	default:
+		return nil, fmt.Errorf("unsupported database driver: %s", cfg.Database.Driver)
+	}
+
+	db, err := sql.Open(driver, cfg.DatabaseDSN())
+	if err != nil {
+		return nil, fmt.Errorf("open database: %w", err)
+	}
+	switch cfg.Database.Driver {
+	case "sqlite":
+		db.SetMaxOpenConns(cfg.Database.SQLite.MaxOpenConns)
+	case "postgres":
+		db.SetMaxOpenConns(cfg.Database.Postgres.MaxOpenConns)
+		db.SetMaxIdleConns(cfg.Database.Postgres.MaxIdleConns)
+		db.SetConnMaxLifetime(cfg.Database.Postgres.ConnMaxLifetime)
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("connect to database: %w", err)
+	}
+	return db, nil
+}
+
+// newEmbeddingIndexOpener opens the indexes embedding model upgrades switch
+// to: an embedder for the model and an in-memory vector index per version,
+// shared by the re-embedding job filling it and the queries served from it.
+// An index is filled with the vectors the job stored for its version, so it
+// serves queries again after a restart. Fitted local models are read from the
+// fits the CLI saved in a SQLite db.
+func newEmbeddingIndexOpener(cfg *config.Config, logger *observability.Logger, db *sql.DB, quantization retrieval.QuantizationConfig) retrieval.EmbeddingIndexOpener {
+	var mu sync.Mutex
+	indexes := make(map[string]*retrieval.EmbeddingIndex)
+	return func(ctx context.Context, model, version string) (*retrieval.EmbeddingIndex, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if idx, ok := indexes[version]; ok {
+			if idx.Embedder.Model() != model {
+				return nil, fmt.Errorf("embedding version %s was built with %s, not %s", version, idx.Embedder.Model(), model)
+			}
+			return idx, nil
+		}
+
+		embedder, err := newModelEmbedder(ctx, cfg, logger, db, model)
+		if err != nil {
+			return nil, err
+		}
+
+		// The stored chunk embeddings are the live model's until the switch
+		// relabels them, so a shadow index has no RerankSource and is not
+		// quantized to PQ codes, which are too lossy without re-ranking.
+		shadow := quantization
+		if shadow.Mode == retrieval.QuantizationPQ {
+			shadow.Mode = retrieval.QuantizationInt8
+		}
+		adapter, err := retrieval.NewFAISSAdapter(retrieval.FAISSConfig{
+			Dimension:    cfg.Embedding.Dimension,
+			Quantization: shadow,
+		})
+		if err != nil {
+			return nil, err
+		}
+		idx := &retrieval.EmbeddingIndex{Version: version, Embedder: embedder, Adapter: adapter}
+		filled, err := retrieval.FillEmbeddingIndex(ctx, storage.NewChunkEmbeddingRepository(db), idx)
+		if err != nil {
+			return nil, err
+		}
+		logger.Info().Str("embedding_version", version).Int("vectors", filled).Msg("Embedding index opened")
+		indexes[version] = idx
+		return idx, nil
+	}
+}
+
+// newModelEmbedder creates the embedder of a model: the offline embedder, a
+// fitted local model read from the fits the CLI saved in a SQLite db, or the
+// provider client with retries, rate limiting and a circuit breaker.
+func newModelEmbedder(ctx context.Context, cfg *config.Config, logger *observability.Logger, db *sql.DB, model string) (embedding.Embedder, error) {
+	switch {
+	case model == embedding.LocalModel:
+		return embedding.NewLocalEmbedder(embedding.LocalConfig{Dimension: cfg.Embedding.Dimension}), nil
+	case embedding.IsLocalModel(model):
+		if db == nil || cfg.Database.Driver != "sqlite" {
+			return nil, fmt.Errorf("local embedder fits are only stored in sqlite")
+		}
+		fits, err := embedding.NewLocalFitStore(ctx, db)
+		if err != nil {
+			return nil, err
+		}
+		embedder, err := fits.Model(ctx, model)
+		if err != nil {
+			return nil, fmt.Errorf("open local embedder %s: %w", model, err)
+		}
+		return embedder, nil
+	}
+
+	client, err := embedding.NewClient(embedding.Config{
+		APIKey:    os.Getenv("OPENROUTER_API_KEY"),
+		Model:     model,
+		BaseURL:   "https://openrouter.ai/api/v1",
+		Dimension: cfg.Embedding.Dimension,
+	})
+	if err != nil {
+		return nil, err
+	}
+	retry := cfg.Embedding.Retry
+	return embedding.NewResilientEmbedder(logger, client, embedding.ResilientConfig{
+		MaxRetries:        retry.MaxRetries,
+		InitialBackoff:    retry.InitialBackoff,
+		MaxBackoff:        retry.MaxBackoff,
+		RequestsPerSecond: retry.RequestsPerSecond,
+		Burst:             retry.Burst,
+		MaxBatchSize:      cfg.Embedding.BatchSize,
+		BreakerThreshold:  retry.BreakerThreshold,
+		BreakerCooldown:   retry.BreakerCooldown,
+	}), nil
+}
+
+// toHybridConfig converts file settings into retrieval hybrid configuration.
+func toHybridConfig(cfg config.HybridConfig) retrieval.HybridConfig {
+	return retrieval.HybridConfig{
+		Fusion:        retrieval.FusionMode(cfg.Fusion),
+		RRFK:          cfg.RRFK,
+		LexicalWeight: cfg.LexicalWeight,
+		VectorWeight:  cfg.VectorWeight,
+		MaxEvidence:   cfg.MaxEvidence,
+	}
+}
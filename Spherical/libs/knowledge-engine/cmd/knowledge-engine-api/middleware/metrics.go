@@ -0,0 +1,35 @@
+// Package middleware provides HTTP request metrics for the Knowledge Engine API.
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/observability"
+)
+
+// Metrics records the latency and status of each request by its route
+// pattern, so requests for different IDs share a series.
+func Metrics(metrics *observability.Metrics) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			ww := chimiddleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r)
+
+			route := "unmatched"
+			if rctx := chi.RouteContext(r.Context()); rctx != nil {
+				if pattern := rctx.RoutePattern(); pattern != "" {
+					route = pattern
+				}
+			}
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			metrics.HTTPRequest(r.Method, route, status, time.Since(start))
+		})
+	}
+}
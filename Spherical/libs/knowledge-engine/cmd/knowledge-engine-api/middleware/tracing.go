@@ -0,0 +1,45 @@
+// Package middleware provides HTTP request tracing for the Knowledge Engine API.
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/observability"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// Tracing serves each request in a server span, continuing the caller's
+// trace when the request carries a W3C traceparent header. The span is
+// renamed to the matched route pattern once routing completes.
+func Tracing(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := observability.StartServerSpan(r.Context(), r.Method, r.Header,
+			attribute.String("http.method", r.Method),
+			attribute.String("http.target", r.URL.Path),
+		)
+		defer span.End()
+		if requestID := chimiddleware.GetReqID(ctx); requestID != "" {
+			span.SetAttributes(attribute.String("request_id", requestID))
+		}
+
+		ww := chimiddleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		r = r.WithContext(ctx)
+		next.ServeHTTP(ww, r)
+
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			span.SetName(r.Method + " " + rctx.RoutePattern())
+			span.SetAttributes(attribute.String("http.route", rctx.RoutePattern()))
+		}
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		span.SetAttributes(attribute.Int("http.status_code", status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	})
+}
@@ -2,29 +2,37 @@
 package main
 
 import (
+	"context"
+	"database/sql"
 	"net/http"
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
 	chimiddleware "github.com/go-chi/chi/v5/middleware"
 	"github.com/spherical-ai/spherical/libs/knowledge-engine/cmd/knowledge-engine-api/handlers"
 	"github.com/spherical-ai/spherical/libs/knowledge-engine/cmd/knowledge-engine-api/middleware"
 	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/cache"
 	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/comparison"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/embedding"
 	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/ingest"
 	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/monitoring"
 	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/observability"
 	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/retrieval"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/storage"
 )
 
 // NewRouter creates the main API router with all routes configured.
 func NewRouter(logger *observability.Logger, cfg *AppConfig) http.Handler {
 	r := chi.NewRouter()
+	metrics := observability.NewMetrics()
 
 	// Global middleware
 	r.Use(chimiddleware.RequestID)
 	r.Use(chimiddleware.RealIP)
+	r.Use(middleware.Tracing)
 	r.Use(chimiddleware.Logger) // Use chi's built-in logger
+	r.Use(middleware.Metrics(metrics))
 	r.Use(chimiddleware.Recoverer)
 	r.Use(middleware.CORS([]string{"*"}))
 	r.Use(chimiddleware.Timeout(cfg.RequestTimeout))
@@ -41,20 +49,42 @@ func NewRouter(logger *observability.Logger, cfg *AppConfig) http.Handler {
 		w.Write([]byte(`{"status":"ready"}`))
 	})
 
+	// Prometheus scrape endpoint (unauthenticated)
+	r.Handle("/metrics", metrics.Handler())
+
 	// Create service dependencies
 	memCache := cache.NewMemoryClient(cfg.CacheSize)
 
 	vectorAdapter, err := retrieval.NewFAISSAdapter(retrieval.FAISSConfig{
-		Dimension: cfg.EmbeddingDimension,
+		Dimension:    cfg.EmbeddingDimension,
+		Quantization: cfg.VectorQuantization,
 	})
 	if err != nil {
 		logger.Error().Err(err).Msg("Failed to create vector adapter")
 		vectorAdapter = nil
+	} else {
+		metrics.GaugeFunc("knowledge_engine_vector_index_size", "Vectors held in the vector index.", func() float64 {
+			count, _ := vectorAdapter.Count(context.Background(), retrieval.VectorFilters{})
+			return float64(count)
+		})
+	}
+
+	// Spec lookups and computed comparisons read the spec view of the database;
+	// asOf and campaignVersion requests read campaign version history, and
+	// product ownership and benchmark data come from the benchmark repository
+	var db storage.DB
+	var specViewRepo *storage.SpecViewRepository
+	var snapshotRepo *storage.SnapshotRepository
+	var benchmarkRepo *storage.BenchmarkRepository
+	if cfg.DB != nil {
+		db = storage.NewTracedDB(cfg.DB, cfg.DatabaseDriver)
+		specViewRepo = storage.NewSpecViewRepository(db)
+		snapshotRepo = storage.NewSnapshotRepository(db)
+		benchmarkRepo = storage.NewBenchmarkRepository(db)
 	}
 
 	// Initialize services
-	// TODO: Create specViewRepo from database connection when available
-	router := retrieval.NewRouter(logger, memCache, vectorAdapter, nil, retrieval.RouterConfig{
+	router := retrieval.NewRouter(logger, memCache, vectorAdapter, cfg.Embedder, specViewRepo, retrieval.RouterConfig{
 		MaxChunks:                 cfg.MaxChunks,
 		StructuredFirst:           true,
 		SemanticFallback:          true,
@@ -62,21 +92,88 @@ func NewRouter(logger *observability.Logger, cfg *AppConfig) http.Handler {
 		KeywordConfidenceThreshold: 0.8,
 		CacheResults:              true,
 		CacheTTL:                  cfg.CacheTTL,
+		CacheRevalidateWindow:     cfg.CacheRevalidate,
+		Hybrid:                    cfg.Hybrid,
+		TenantHybrid:              cfg.TenantHybrid,
+		Expansion:                 cfg.Expansion,
+		Benchmarks:                cfg.Benchmarks,
+		SemanticCache:             cfg.SemanticCache,
 	})
+	router.SetMetrics(metrics)
+	if cfg.AnswerGenerator != nil {
+		router.SetAnswerGenerator(cfg.AnswerGenerator)
+	}
+	router.SetQueryRewriter(cfg.QueryRewriter)
+	if db != nil {
+		// Quantized candidates are re-ranked with the stored chunk embeddings
+		if vectorAdapter != nil && cfg.VectorQuantization.Mode != retrieval.QuantizationNone {
+			vectorAdapter.SetRerankSource(storage.NewChunkVectorSource(db, cfg.EmbeddingModel))
+		}
+		router.SetSnapshotSource(snapshotRepo)
+		if cfg.BenchmarksEnabled {
+			router.SetBenchmarkSource(benchmarkRepo)
+		}
+		router.SetEntityLinker(retrieval.NewEntityLinker(
+			retrieval.NewRepositoryCatalog(storage.NewProductRepository(db), storage.NewCampaignRepository(db)),
+			retrieval.DefaultLinkerConfig(),
+		))
+	}
+	router.IntentClassifier().SetModel(cfg.IntentModel)
+	for tenantID, model := range cfg.TenantIntentModels {
+		router.IntentClassifier().SetTenantModel(tenantID, model)
+	}
 
-	pipeline := ingest.NewPipeline(logger, ingest.PipelineConfig{
+	// Ingest stages lexical documents that publish makes searchable in the router
+	pipeline := ingest.NewPipeline(logger, router.LexicalIndex(), ingest.PipelineConfig{
 		ChunkSize:         512,
 		ChunkOverlap:      64,
 		MaxConcurrentJobs: cfg.MaxConcurrentJobs,
 		DedupeThreshold:   0.95,
 	})
+	pipeline.SetMetrics(metrics)
 
-	publisher := ingest.NewPublisher(logger)
-
+	// Comparisons missing from the store are computed from spec values and saved
 	compCache := comparison.NewMemoryComparisonCache()
-	materializer := comparison.NewMaterializer(logger, compCache, nil, comparison.Config{
-		CacheTTL: cfg.CacheTTL,
+	var compStore comparison.ComparisonStore
+	if db != nil {
+		compStore = storage.NewComparisonRepository(db)
+	}
+	materializer := comparison.NewMaterializer(logger, compCache, compStore, comparison.Config{
+		CacheTTL:         cfg.CacheTTL,
+		AllowCrossTenant: cfg.AllowCrossTenant,
 	})
+	materializer.SetMetrics(metrics)
+	if db != nil {
+		materializer.SetSpecSource(specViewRepo)
+		materializer.SetSnapshotSource(snapshotRepo)
+		materializer.SetProductAccess(benchmarkRepo)
+	}
+
+	// Publishes evict this instance's caches and, over the bus, every other instance's
+	invalidation := retrieval.NewInvalidationTrigger(cfg.InvalidationBus, logger)
+	invalidation.AddInvalidator(router)
+	invalidation.AddInvalidator(materializer)
+	if err := invalidation.Start(context.Background()); err != nil {
+		logger.Error().Err(err).Msg("Failed to subscribe to cache invalidations")
+	}
+
+	// Embedding model upgrades are persisted, so restarts and other replicas
+	// serve the index a re-embedding job switched to
+	var reembedHandler *handlers.ReembedHandler
+	if db != nil && cfg.EmbeddingIndexes != nil {
+		router.SetEmbeddingIndexStore(storage.NewEmbeddingIndexRepository(db), cfg.EmbeddingIndexes)
+		router.SetIndexSwitchPublisher(invalidation)
+		if err := router.LoadEmbeddingIndexes(context.Background()); err != nil {
+			logger.Error().Err(err).Msg("Failed to load embedding indexes")
+		}
+		reembedder := retrieval.NewReembedder(logger, router,
+			storage.NewKnowledgeChunkRepository(db), storage.NewReembedJobRepository(db), cfg.Reembed)
+		reembedder.SetEmbeddingStore(storage.NewChunkEmbeddingRepository(db))
+		reembedHandler = handlers.NewReembedHandler(logger, reembedder, cfg.EmbeddingIndexes)
+	}
+
+	publisher := ingest.NewPublisher(logger, router.LexicalIndex())
+	publisher.AddCacheInvalidator(invalidation)
 
 	auditLogger := monitoring.NewAuditLogger(logger, nil)
 	driftRunner := monitoring.NewDriftRunner(logger, nil, monitoring.DriftConfig{
@@ -100,6 +197,8 @@ func NewRouter(logger *observability.Logger, cfg *AppConfig) http.Handler {
 		// Retrieval routes
 		r.Route("/retrieval", func(r chi.Router) {
 			r.Post("/query", retrievalHandler.Query)
+			r.Post("/answer", retrievalHandler.Answer)
+			r.Get("/cache/stats", retrievalHandler.CacheStats)
 		})
 
 		// Ingestion routes
@@ -111,6 +210,18 @@ func NewRouter(logger *observability.Logger, cfg *AppConfig) http.Handler {
 			r.Route("/campaigns/{campaignId}", func(r chi.Router) {
 				r.Post("/publish", ingestionHandler.Publish)
 			})
+
+			// Embedding model upgrade admin routes
+			if reembedHandler != nil {
+				r.Route("/reembed", func(r chi.Router) {
+					r.Use(middleware.RequireRoles(middleware.RoleAdmin))
+					r.Post("/", reembedHandler.Start)
+					r.Get("/{jobId}", reembedHandler.Get)
+					r.Post("/{jobId}/resume", reembedHandler.Resume)
+					r.Post("/{jobId}/cancel", reembedHandler.Cancel)
+					r.Post("/{jobId}/rollback", reembedHandler.Rollback)
+				})
+			}
 		})
 
 		// Comparison routes
@@ -138,12 +249,50 @@ type AppConfig struct {
 	RequestTimeout     time.Duration
 	CacheSize          int
 	CacheTTL           time.Duration
+	CacheRevalidate    time.Duration
 	MaxChunks          int
 	MaxConcurrentJobs  int
 	EmbeddingDimension int
+	// EmbeddingModel is the model of the stored chunk embeddings the vector
+	// index is re-ranked with.
+	EmbeddingModel string
+	// VectorQuantization compresses the vectors held by the FAISS adapter.
+	VectorQuantization retrieval.QuantizationConfig
 	DriftCheckInterval time.Duration
 	StalenessWindow    time.Duration
 	AuthConfig         middleware.AuthConfig
+	Hybrid             retrieval.HybridConfig
+	TenantHybrid       map[uuid.UUID]retrieval.HybridConfig
+	Expansion          retrieval.ExpansionConfig
+	// IntentModel and TenantIntentModels replace the pattern intent classifier when set.
+	IntentModel        *retrieval.IntentModel
+	TenantIntentModels map[uuid.UUID]*retrieval.IntentModel
+	// AnswerGenerator composes /retrieval/answer responses; nil uses the template generator.
+	AnswerGenerator retrieval.AnswerGenerator
+	// QueryRewriter rewrites follow-up questions; nil uses the rule rewriter.
+	QueryRewriter retrieval.QueryRewriter
+	// BenchmarksEnabled lets retrieval requests include other tenants' public
+	// benchmark products; otherwise includeBenchmarks is rejected.
+	BenchmarksEnabled bool
+	// Benchmarks bounds cross-tenant benchmark retrieval.
+	Benchmarks retrieval.BenchmarkConfig
+	// Embedder embeds questions with the live model; nil serves without vector
+	// search and the semantic cache.
+	Embedder embedding.Embedder
+	// SemanticCache reuses responses to questions with similar embeddings.
+	SemanticCache retrieval.SemanticCacheConfig
+	// InvalidationBus carries cache invalidations between instances; nil keeps them local.
+	InvalidationBus cache.InvalidationBus
+	// AllowCrossTenant permits comparisons against other tenants' public benchmark products.
+	AllowCrossTenant bool
+	// DB is the catalogue database, or nil when it is unavailable.
+	DB             *sql.DB
+	DatabaseDriver string
+	// EmbeddingIndexes opens the embedder and vector index of an embedding
+	// model and version; with DB it enables the re-embedding admin routes.
+	EmbeddingIndexes retrieval.EmbeddingIndexOpener
+	// Reembed paces re-embedding jobs.
+	Reembed retrieval.ReembedConfig
 }
 
 // DefaultAppConfig returns default configuration values.
@@ -0,0 +1,293 @@
+// Package main provides the campaign clone command.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/ingest"
+)
+
+// newCloneCmd creates the clone subcommand.
+func newCloneCmd() *cobra.Command {
+	var (
+		tenant   string
+		campaign string
+		mode     string
+		locale   string
+		trim     string
+		market   string
+		operator string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "clone",
+		Short: "Clone a campaign into a new market, locale or trim variant",
+		Long: `Clone creates a new draft campaign variant from an existing one.
+With --mode=inherit (default) the new variant inherits spec values and feature
+blocks from the source and only stores overrides. With --mode=copy the source's
+effective content is copied into an independent variant.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+			defer cancel()
+
+			tenantID, err := resolveID(tenant)
+			if err != nil {
+				return fmt.Errorf("invalid tenant: %w", err)
+			}
+
+			campaignID, err := resolveID(campaign)
+			if err != nil {
+				return fmt.Errorf("invalid campaign: %w", err)
+			}
+
+			db, err := openDatabase(cfg)
+			if err != nil {
+				return fmt.Errorf("open database: %w", err)
+			}
+			defer db.Close()
+
+			req := ingest.CloneRequest{
+				TenantID:         tenantID,
+				SourceCampaignID: campaignID,
+				Mode:             ingest.CloneMode(mode),
+				Locale:           locale,
+				Operator:         cloneOperator(operator),
+			}
+			if cmd.Flags().Changed("trim") {
+				req.Trim = &trim
+			}
+			if cmd.Flags().Changed("market") {
+				req.Market = &market
+			}
+
+			cloner := ingest.NewCloner(logger, db)
+			result, err := cloner.Clone(ctx, req)
+			if err != nil {
+				return fmt.Errorf("clone failed: %w", err)
+			}
+
+			if outputJSON {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				return enc.Encode(map[string]interface{}{
+					"campaign":       result.CampaignID.String(),
+					"source":         result.SourceID.String(),
+					"mode":           string(result.Mode),
+					"specsCopied":    result.SpecsCopied,
+					"featuresCopied": result.FeaturesCopied,
+				})
+			}
+
+			fmt.Printf("✓ Created campaign %s from %s (%s)\n", result.CampaignID, result.SourceID, result.Mode)
+			if result.Mode == ingest.CloneModeCopy {
+				fmt.Printf("  Specs copied: %d\n", result.SpecsCopied)
+				fmt.Printf("  Features copied: %d\n", result.FeaturesCopied)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&tenant, "tenant", "", "tenant ID or name (required)")
+	cmd.Flags().StringVar(&campaign, "campaign", "", "source campaign variant ID (required)")
+	cmd.Flags().StringVar(&mode, "mode", string(ingest.CloneModeInherit), "clone mode (inherit, copy)")
+	cmd.Flags().StringVar(&locale, "locale", "", "locale for the new variant (default: source locale)")
+	cmd.Flags().StringVar(&trim, "trim", "", "trim for the new variant")
+	cmd.Flags().StringVar(&market, "market", "", "market for the new variant")
+	cmd.Flags().StringVar(&operator, "operator", "", "operator name for audit trail")
+
+	_ = cmd.MarkFlagRequired("tenant")
+	_ = cmd.MarkFlagRequired("campaign")
+
+	cmd.AddCommand(newOverrideSpecCmd())
+	cmd.AddCommand(newOverrideFeatureCmd())
+
+	return cmd
+}
+
+// newOverrideSpecCmd creates the clone override-spec subcommand.
+func newOverrideSpecCmd() *cobra.Command {
+	var (
+		tenant   string
+		campaign string
+		specItem string
+		value    string
+		numeric  float64
+		unit     string
+		operator string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "override-spec",
+		Short: "Replace an inherited spec value in a derived campaign",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+
+			tenantID, err := resolveID(tenant)
+			if err != nil {
+				return fmt.Errorf("invalid tenant: %w", err)
+			}
+			campaignID, err := resolveID(campaign)
+			if err != nil {
+				return fmt.Errorf("invalid campaign: %w", err)
+			}
+			specItemID, err := resolveID(specItem)
+			if err != nil {
+				return fmt.Errorf("invalid spec item: %w", err)
+			}
+
+			req := ingest.OverrideSpecRequest{
+				TenantID:   tenantID,
+				CampaignID: campaignID,
+				SpecItemID: specItemID,
+				ValueText:  &value,
+				Operator:   cloneOperator(operator),
+			}
+			if cmd.Flags().Changed("numeric") {
+				req.ValueNumeric = &numeric
+			}
+			if cmd.Flags().Changed("unit") {
+				req.Unit = &unit
+			}
+
+			db, err := openDatabase(cfg)
+			if err != nil {
+				return fmt.Errorf("open database: %w", err)
+			}
+			defer db.Close()
+
+			spec, err := ingest.NewCloner(logger, db).OverrideSpecValue(ctx, req)
+			if err != nil {
+				return fmt.Errorf("override failed: %w", err)
+			}
+
+			if outputJSON {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				return enc.Encode(map[string]interface{}{
+					"specValue": spec.ID.String(),
+					"campaign":  spec.CampaignVariantID.String(),
+					"specItem":  spec.SpecItemID.String(),
+				})
+			}
+
+			fmt.Printf("✓ Overrode spec item %s in campaign %s (value %s)\n", spec.SpecItemID, spec.CampaignVariantID, spec.ID)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&tenant, "tenant", "", "tenant ID or name (required)")
+	cmd.Flags().StringVar(&campaign, "campaign", "", "derived campaign variant ID (required)")
+	cmd.Flags().StringVar(&specItem, "spec-item", "", "spec item ID of the inherited value (required)")
+	cmd.Flags().StringVar(&value, "value", "", "overriding value text (required)")
+	cmd.Flags().Float64Var(&numeric, "numeric", 0, "overriding numeric value")
+	cmd.Flags().StringVar(&unit, "unit", "", "unit of the value (default: the inherited unit)")
+	cmd.Flags().StringVar(&operator, "operator", "", "operator name for audit trail")
+
+	_ = cmd.MarkFlagRequired("tenant")
+	_ = cmd.MarkFlagRequired("campaign")
+	_ = cmd.MarkFlagRequired("spec-item")
+	_ = cmd.MarkFlagRequired("value")
+
+	return cmd
+}
+
+// newOverrideFeatureCmd creates the clone override-feature subcommand.
+func newOverrideFeatureCmd() *cobra.Command {
+	var (
+		tenant   string
+		campaign string
+		block    string
+		body     string
+		tags     []string
+		operator string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "override-feature",
+		Short: "Replace an inherited feature block in a derived campaign",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+
+			tenantID, err := resolveID(tenant)
+			if err != nil {
+				return fmt.Errorf("invalid tenant: %w", err)
+			}
+			campaignID, err := resolveID(campaign)
+			if err != nil {
+				return fmt.Errorf("invalid campaign: %w", err)
+			}
+			blockID, err := resolveID(block)
+			if err != nil {
+				return fmt.Errorf("invalid block: %w", err)
+			}
+
+			req := ingest.OverrideFeatureRequest{
+				TenantID:    tenantID,
+				CampaignID:  campaignID,
+				BaseBlockID: blockID,
+				Body:        body,
+				Operator:    cloneOperator(operator),
+			}
+			if cmd.Flags().Changed("tags") {
+				req.Tags = tags
+			}
+
+			db, err := openDatabase(cfg)
+			if err != nil {
+				return fmt.Errorf("open database: %w", err)
+			}
+			defer db.Close()
+
+			fb, err := ingest.NewCloner(logger, db).OverrideFeatureBlock(ctx, req)
+			if err != nil {
+				return fmt.Errorf("override failed: %w", err)
+			}
+
+			if outputJSON {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				return enc.Encode(map[string]interface{}{
+					"featureBlock": fb.ID.String(),
+					"campaign":     fb.CampaignVariantID.String(),
+					"overrides":    blockID.String(),
+				})
+			}
+
+			fmt.Printf("✓ Overrode feature block %s in campaign %s (block %s)\n", blockID, fb.CampaignVariantID, fb.ID)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&tenant, "tenant", "", "tenant ID or name (required)")
+	cmd.Flags().StringVar(&campaign, "campaign", "", "derived campaign variant ID (required)")
+	cmd.Flags().StringVar(&block, "block", "", "ID of the inherited feature block (required)")
+	cmd.Flags().StringVar(&body, "body", "", "overriding block body (required)")
+	cmd.Flags().StringSliceVar(&tags, "tags", nil, "tags of the block (default: the inherited tags)")
+	cmd.Flags().StringVar(&operator, "operator", "", "operator name for audit trail")
+
+	_ = cmd.MarkFlagRequired("tenant")
+	_ = cmd.MarkFlagRequired("campaign")
+	_ = cmd.MarkFlagRequired("block")
+	_ = cmd.MarkFlagRequired("body")
+
+	return cmd
+}
+
+// cloneOperator returns the operator for the audit trail, defaulting to $USER.
+func cloneOperator(operator string) string {
+	if operator != "" {
+		return operator
+	}
+	if user := os.Getenv("USER"); user != "" {
+		return user
+	}
+	return "cli"
+}
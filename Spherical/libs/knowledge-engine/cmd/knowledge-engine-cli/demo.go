@@ -23,6 +23,7 @@ func newDemoCmd() *cobra.Command {
 	var brochurePath string
 	var dbPath string
 	var useRealEmbeddings bool
+	var embeddingCachePath string
 
 	cmd := &cobra.Command{
 		Use:   "demo",
@@ -38,20 +39,33 @@ This demo:
 Example:
   knowledge-engine-cli demo
   knowledge-engine-cli demo --brochure /path/to/brochure.md
-  knowledge-engine-cli demo --real-embeddings`,
+  knowledge-engine-cli demo --real-embeddings
+
+Real embeddings are cached in --embedding-cache, so re-running the demo on
+the same brochure does not call the embedding API again.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runDemo(brochurePath, dbPath, useRealEmbeddings)
+			return runDemo(brochurePath, dbPath, useRealEmbeddings, embeddingCachePath)
 		},
 	}
 
 	cmd.Flags().StringVarP(&brochurePath, "brochure", "b", "", "Path to brochure markdown file")
 	cmd.Flags().StringVarP(&dbPath, "db", "d", "", "Path to SQLite database (default: temp file)")
 	cmd.Flags().BoolVar(&useRealEmbeddings, "real-embeddings", false, "Use OpenRouter API for embeddings")
+	cmd.Flags().StringVar(&embeddingCachePath, "embedding-cache", defaultEmbeddingCachePath(), "Path to SQLite embedding cache (empty disables it)")
 
 	return cmd
 }
 
-func runDemo(brochurePath, dbPath string, useRealEmbeddings bool) error {
+// defaultEmbeddingCachePath keeps the demo's embedding cache in the user cache directory.
+func defaultEmbeddingCachePath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "knowledge-engine", "embeddings.db")
+}
+
+func runDemo(brochurePath, dbPath string, useRealEmbeddings bool, embeddingCachePath string) error {
 	ctx := context.Background()
 	logger := observability.NewLogger(observability.LogConfig{
 		Level:       "info",
@@ -135,8 +149,8 @@ func runDemo(brochurePath, dbPath string, useRealEmbeddings bool) error {
 	if useRealEmbeddings {
 		apiKey := os.Getenv("OPENROUTER_API_KEY")
 		if apiKey == "" {
-			fmt.Println("⚠️  OPENROUTER_API_KEY not set, using mock embeddings")
-			embClient = embedding.NewMockClient(768)
+			fmt.Println("⚠️  OPENROUTER_API_KEY not set, using local embeddings")
+			embClient = newDemoLocalEmbedder(parsed)
 		} else {
 			client, err := embedding.NewClient(embedding.Config{
 				APIKey:  apiKey,
@@ -144,16 +158,22 @@ func runDemo(brochurePath, dbPath string, useRealEmbeddings bool) error {
 				BaseURL: "https://openrouter.ai/api/v1",
 			})
 			if err != nil {
-				logger.Warn().Err(err).Msg("Failed to create embedding client")
-				embClient = embedding.NewMockClient(768)
+				logger.Warn().Err(err).Msg("Failed to create embedding client, using local embeddings")
+				embClient = newDemoLocalEmbedder(parsed)
 			} else {
 				embClient = client
 				fmt.Println("   Using OpenRouter embeddings (google/gemini-embedding-001)")
+				if cached, err := openDemoEmbeddingCache(ctx, logger, client, embeddingCachePath); err != nil {
+					logger.Warn().Err(err).Msg("Failed to open embedding cache")
+				} else if cached != nil {
+					embClient = cached
+					fmt.Printf("   Caching embeddings in %s\n", embeddingCachePath)
+				}
 			}
 		}
 	} else {
-		embClient = embedding.NewMockClient(768)
-		fmt.Println("   Using mock embeddings (add --real-embeddings for OpenRouter)")
+		embClient = newDemoLocalEmbedder(parsed)
+		fmt.Println("   Using local embeddings (add --real-embeddings for OpenRouter)")
 	}
 
 	// Store data
@@ -165,7 +185,12 @@ func runDemo(brochurePath, dbPath string, useRealEmbeddings bool) error {
 		return fmt.Errorf("failed to store data: %w", err)
 	}
 	fmt.Printf("   ✓ Stored in %v\n", storeTime)
-	fmt.Printf("   ✓ Specs: %d, Chunks: %d\n\n", specCount, chunkCount)
+	fmt.Printf("   ✓ Specs: %d, Chunks: %d\n", specCount, chunkCount)
+	if cached, ok := embClient.(*embedding.CachedEmbedder); ok {
+		stats := cached.Stats()
+		fmt.Printf("   ✓ Embedding cache: %d hits, %d misses (%.0f%%)\n", stats.Hits, stats.Misses, stats.HitRate*100)
+	}
+	fmt.Println()
 
 	// Interactive query loop
 	fmt.Println("═══════════════════════════════════════════════════════════════════")
@@ -347,12 +372,49 @@ func createDemoProduct(db *sql.DB, tenantID, productID uuid.UUID) error {
 	return err
 }
 
+// openDemoEmbeddingCache wraps client with a SQLite embedding cache at path,
+// or returns nil when path is empty. The cache stays open until the demo exits.
+func openDemoEmbeddingCache(ctx context.Context, logger *observability.Logger, client embedding.Embedder, path string) (*embedding.CachedEmbedder, error) {
+	if path == "" {
+		return nil, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, err
+	}
+	backend, err := embedding.NewSQLCacheBackend(ctx, db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return embedding.NewCachedEmbedder(logger, client, backend), nil
+}
+
 func createDemoCampaign(db *sql.DB, tenantID, productID, campaignID uuid.UUID) error {
 	_, err := db.Exec("INSERT INTO campaign_variants (id, product_id, tenant_id) VALUES (?, ?, ?)",
 		campaignID.String(), productID.String(), tenantID.String())
 	return err
 }
 
+// newDemoLocalEmbedder creates an offline embedder fitted on the brochure's
+// feature and USP text, the chunks the demo embeds.
+func newDemoLocalEmbedder(parsed *ingest.ParsedBrochure) *embedding.LocalEmbedder {
+	var corpus []string
+	for _, feature := range parsed.Features {
+		corpus = append(corpus, feature.Body)
+	}
+	for _, usp := range parsed.USPs {
+		corpus = append(corpus, usp.Body)
+	}
+
+	local := embedding.NewLocalEmbedder(embedding.LocalConfig{Dimension: 768})
+	local.Fit(corpus)
+	return local
+}
+
 func storeDemoData(ctx context.Context, db *sql.DB, tenantID, productID, campaignID uuid.UUID,
 	parsed *ingest.ParsedBrochure, embClient embedding.Embedder) (int, int, error) {
 
@@ -0,0 +1,94 @@
+// Package main provides the local embedder fitting command.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/embedding"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/storage"
+)
+
+// newEmbeddingCmd creates the embedding subcommand.
+func newEmbeddingCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "embedding",
+		Short: "Manage the local embedder",
+		Long: `Embedding commands manage the offline "local" embedder selected with
+embedding.model: local.
+
+The local embedder weighs features by how common they are in a tenant's chunks.
+That fit is saved in the database and used by every later ingest and query of
+the tenant, so their vectors stay comparable. Vectors are labelled with the
+fit's model name; after refitting, re-embed the tenant's chunks.`,
+	}
+
+	cmd.AddCommand(newEmbeddingFitCmd())
+	return cmd
+}
+
+// newEmbeddingFitCmd creates the embedding fit subcommand.
+func newEmbeddingFitCmd() *cobra.Command {
+	var tenant string
+
+	cmd := &cobra.Command{
+		Use:   "fit",
+		Short: "Fit the local embedder on a tenant's chunks",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+			defer cancel()
+
+			tenantID, err := resolveID(tenant)
+			if err != nil {
+				return fmt.Errorf("invalid tenant: %w", err)
+			}
+
+			db, err := openDatabase(cfg)
+			if err != nil {
+				return fmt.Errorf("open database: %w", err)
+			}
+			defer db.Close()
+
+			texts, err := storage.NewKnowledgeChunkRepository(db).ListTexts(ctx, tenantID)
+			if err != nil {
+				return fmt.Errorf("load chunks to fit local embedder: %w", err)
+			}
+			if len(texts) == 0 {
+				return fmt.Errorf("tenant %s has no chunks to fit on", tenant)
+			}
+
+			local := embedding.NewLocalEmbedder(embedding.LocalConfig{Dimension: cfg.Embedding.Dimension})
+			local.Fit(texts)
+			fits, err := embedding.NewLocalFitStore(ctx, db)
+			if err != nil {
+				return err
+			}
+			if err := fits.Save(ctx, tenantID, local); err != nil {
+				return err
+			}
+
+			if outputJSON {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				return enc.Encode(map[string]interface{}{
+					"tenant": tenantID.String(),
+					"model":  local.Model(),
+					"chunks": len(texts),
+				})
+			}
+
+			fmt.Printf("✓ Fitted local embedder on %d chunks\n", len(texts))
+			fmt.Printf("  Model: %s\n", local.Model())
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&tenant, "tenant", "", "tenant ID or name (required)")
+	_ = cmd.MarkFlagRequired("tenant")
+	return cmd
+}
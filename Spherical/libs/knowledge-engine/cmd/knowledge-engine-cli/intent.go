@@ -0,0 +1,295 @@
+// Package main provides the intent classifier training and evaluation commands.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/retrieval"
+)
+
+// newIntentCmd creates the intent subcommand.
+func newIntentCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "intent",
+		Short: "Train and evaluate the intent classifier",
+		Long: `Intent commands train the learned intent classifier from labelled examples
+and report its accuracy as a confusion matrix.
+
+Examples are JSON lines files of {"text": ..., "intent": ...} objects. The data
+directory holds default.jsonl, shared by every tenant, and optional
+<tenant-id>.jsonl files with a tenant's own examples. Models are written as
+default.json or <tenant-id>.json; point retrieval.intent_model_dir at the model
+directory to load them. Without a model the pattern classifier is used.`,
+	}
+
+	cmd.AddCommand(newIntentTrainCmd())
+	cmd.AddCommand(newIntentEvalCmd())
+	return cmd
+}
+
+// newIntentTrainCmd creates the intent train subcommand.
+func newIntentTrainCmd() *cobra.Command {
+	var (
+		dataDir string
+		tenant  string
+		outDir  string
+		holdout float64
+		epochs  int
+		seed    int64
+	)
+
+	cmd := &cobra.Command{
+		Use:   "train",
+		Short: "Train an intent model from labelled examples",
+		Long: `Train fits an intent model. A share of each intent's examples is held out of
+training to report accuracy; the model records the split so eval can score it on
+the same held-out examples. Use --holdout 0 to fit on every example.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			tenantID, err := intentTenant(tenant)
+			if err != nil {
+				return err
+			}
+			examples, err := retrieval.LoadTenantIntentExamples(dataDir, tenantID)
+			if err != nil {
+				return err
+			}
+			trainCfg := retrieval.IntentTrainConfig{Epochs: epochs, Seed: seed}
+
+			train, test := examples, []retrieval.IntentExample(nil)
+			if holdout > 0 {
+				train, test = retrieval.SplitIntentExamples(examples, holdout, seed)
+			}
+			model, err := retrieval.TrainIntentModel(train, trainCfg)
+			if err != nil {
+				return fmt.Errorf("train: %w", err)
+			}
+
+			var eval *retrieval.IntentEvaluation
+			if holdout > 0 {
+				model.Holdout, model.Seed = holdout, seed
+				result := retrieval.EvaluateIntents(model.Predict, test)
+				eval = &result
+			}
+
+			if err := os.MkdirAll(outDir, 0o755); err != nil {
+				return fmt.Errorf("create model directory: %w", err)
+			}
+			path := filepath.Join(outDir, intentModelName(tenantID)+".json")
+			if err := retrieval.SaveIntentModel(path, model); err != nil {
+				return err
+			}
+
+			if outputJSON {
+				out := map[string]interface{}{
+					"model":    path,
+					"examples": model.Examples,
+					"intents":  model.Intents,
+					"features": len(model.Features),
+				}
+				if eval != nil {
+					out["evaluation"] = intentEvaluationJSON(*eval)
+				}
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				return enc.Encode(out)
+			}
+
+			fmt.Printf("✓ Trained intent model on %d examples (%d intents, %d features)\n",
+				model.Examples, len(model.Intents), len(model.Features))
+			fmt.Printf("  Saved to %s\n", path)
+			if eval != nil {
+				fmt.Printf("\nHeld-out evaluation (%.0f%% of examples):\n", holdout*100)
+				printIntentEvaluation(*eval)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&dataDir, "data", "configs/intents", "directory of labelled example files")
+	cmd.Flags().StringVar(&tenant, "tenant", "", "tenant ID or name; empty trains the shared model")
+	cmd.Flags().StringVar(&outDir, "out", "models/intents", "directory to write the model to")
+	cmd.Flags().Float64Var(&holdout, "holdout", 0.2, "share of examples held out for evaluation (0 to skip)")
+	cmd.Flags().IntVar(&epochs, "epochs", 200, "training passes over the examples")
+	cmd.Flags().Int64Var(&seed, "seed", 1, "random seed for shuffling and the holdout split")
+
+	return cmd
+}
+
+// newIntentEvalCmd creates the intent eval subcommand.
+func newIntentEvalCmd() *cobra.Command {
+	var (
+		dataDir  string
+		tenant   string
+		modelDir string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "eval",
+		Short: "Evaluate the intent classifier against labelled examples",
+		Long: `Eval classifies every example and prints a confusion matrix with per-intent
+precision and recall. With --model the tenant's model (or the shared model) is
+evaluated with the pattern classifier as fallback, as in retrieval, on the
+examples held out when it was trained; a tenant evaluated with the shared model
+also uses all of its own examples. Without --model only the pattern classifier
+is evaluated, on every example.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			tenantID, err := intentTenant(tenant)
+			if err != nil {
+				return err
+			}
+			examples, err := retrieval.LoadTenantIntentExamples(dataDir, tenantID)
+			if err != nil {
+				return err
+			}
+
+			classifier := retrieval.NewIntentClassifier()
+			source := "patterns"
+			if modelDir != "" {
+				shared, tenants, err := retrieval.LoadIntentModels(modelDir)
+				if err != nil {
+					return err
+				}
+				classifier.SetModel(shared)
+				for id, model := range tenants {
+					classifier.SetTenantModel(id, model)
+				}
+				switch {
+				case tenants[tenantID] != nil:
+					source = "tenant model"
+					if examples, err = intentHeldOut(tenants[tenantID], examples, nil); err != nil {
+						return err
+					}
+				case shared != nil && tenantID == uuid.Nil:
+					source = "shared model"
+					if examples, err = intentHeldOut(shared, examples, nil); err != nil {
+						return err
+					}
+				case shared != nil:
+					// The shared model never saw the tenant's own examples
+					source = "shared model"
+					sharedExamples, err := retrieval.LoadTenantIntentExamples(dataDir, uuid.Nil)
+					if err != nil {
+						return err
+					}
+					if examples, err = intentHeldOut(shared, sharedExamples, examples[len(sharedExamples):]); err != nil {
+						return err
+					}
+				}
+			}
+			eval := retrieval.EvaluateIntents(func(q string) (retrieval.Intent, float64) {
+				return classifier.ClassifyTenant(tenantID, q)
+			}, examples)
+
+			if outputJSON {
+				out := intentEvaluationJSON(eval)
+				out["classifier"] = source
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				return enc.Encode(out)
+			}
+
+			fmt.Printf("Evaluated %s on %d examples:\n", source, eval.Total)
+			printIntentEvaluation(eval)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&dataDir, "data", "configs/intents", "directory of labelled example files")
+	cmd.Flags().StringVar(&tenant, "tenant", "", "tenant ID or name; empty uses the shared examples")
+	cmd.Flags().StringVar(&modelDir, "model", "", "directory of trained models (default: pattern classifier)")
+
+	return cmd
+}
+
+// intentTenant resolves an optional tenant flag.
+func intentTenant(tenant string) (uuid.UUID, error) {
+	if tenant == "" {
+		return uuid.Nil, nil
+	}
+	tenantID, err := resolveID(tenant)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("invalid tenant: %w", err)
+	}
+	return tenantID, nil
+}
+
+// intentHeldOut returns the examples a model was not trained on: its held-out
+// share of trained plus unseen.
+func intentHeldOut(model *retrieval.IntentModel, trained, unseen []retrieval.IntentExample) ([]retrieval.IntentExample, error) {
+	if model.Holdout <= 0 {
+		return nil, fmt.Errorf("the model was trained on every example; retrain it with --holdout to evaluate it")
+	}
+	return append(model.HeldOutExamples(trained), unseen...), nil
+}
+
+// intentModelName returns the model file name for a tenant.
+func intentModelName(tenantID uuid.UUID) string {
+	if tenantID == uuid.Nil {
+		return retrieval.DefaultIntentExamples
+	}
+	return tenantID.String()
+}
+
+// printIntentEvaluation prints accuracy, a confusion matrix and per-intent scores.
+func printIntentEvaluation(eval retrieval.IntentEvaluation) {
+	fmt.Printf("  Accuracy: %.1f%% (%d/%d)\n\n", eval.Accuracy*100, eval.Correct, eval.Total)
+
+	width := len("expected \\ predicted")
+	for _, label := range eval.Labels {
+		if len(label) > width {
+			width = len(label)
+		}
+	}
+	fmt.Printf("  %-*s", width, "expected \\ predicted")
+	for _, label := range eval.Labels {
+		fmt.Printf("  %*s", len(label), label)
+	}
+	fmt.Println()
+	for _, expected := range eval.Labels {
+		fmt.Printf("  %-*s", width, expected)
+		for _, predicted := range eval.Labels {
+			fmt.Printf("  %*d", len(predicted), eval.Confusion[expected][predicted])
+		}
+		fmt.Println()
+	}
+
+	fmt.Printf("\n  %-*s  %9s  %6s\n", width, "intent", "precision", "recall")
+	for _, label := range eval.Labels {
+		fmt.Printf("  %-*s  %9.2f  %6.2f\n", width, label, eval.Precision(label), eval.Recall(label))
+	}
+}
+
+// intentEvaluationJSON converts an evaluation for --json output.
+func intentEvaluationJSON(eval retrieval.IntentEvaluation) map[string]interface{} {
+	confusion := make(map[string]map[string]int, len(eval.Confusion))
+	perIntent := make(map[string]map[string]float64, len(eval.Labels))
+	for _, expected := range eval.Labels {
+		row := make(map[string]int)
+		for predicted, n := range eval.Confusion[expected] {
+			row[string(predicted)] = n
+		}
+		confusion[string(expected)] = row
+		perIntent[string(expected)] = map[string]float64{
+			"precision": eval.Precision(expected),
+			"recall":    eval.Recall(expected),
+		}
+	}
+	labels := make([]string, len(eval.Labels))
+	for i, l := range eval.Labels {
+		labels[i] = string(l)
+	}
+	return map[string]interface{}{
+		"total":     eval.Total,
+		"correct":   eval.Correct,
+		"accuracy":  eval.Accuracy,
+		"labels":    labels,
+		"confusion": confusion,
+		"perIntent": perIntent,
+	}
+}
@@ -6,10 +6,13 @@ import (
 	"database/sql"
 	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -17,6 +20,7 @@ import (
 	_ "github.com/mattn/go-sqlite3"
 
 	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/cache"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/comparison"
 	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/config"
 	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/embedding"
 	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/ingest"
@@ -82,8 +86,11 @@ func init() {
 	// Add subcommands
 	rootCmd.AddCommand(newIngestCmd())
 	rootCmd.AddCommand(newPublishCmd())
+	rootCmd.AddCommand(newCloneCmd())
 	rootCmd.AddCommand(newQueryCmd())
 	rootCmd.AddCommand(newCompareCmd())
+	rootCmd.AddCommand(newIntentCmd())
+	rootCmd.AddCommand(newEmbeddingCmd())
 	rootCmd.AddCommand(newDriftCmd())
 	rootCmd.AddCommand(newExportCmd())
 	rootCmd.AddCommand(newImportCmd())
@@ -193,13 +200,28 @@ the pdf-extractor binary to generate Markdown first.`,
 				Msg("Starting ingestion")
 
 			// Create pipeline
-			pipeline := ingest.NewPipeline(logger, ingest.PipelineConfig{
+			pipeline := ingest.NewPipeline(logger, nil, ingest.PipelineConfig{
 				ChunkSize:         512,
 				ChunkOverlap:      64,
 				MaxConcurrentJobs: 4,
 				DedupeThreshold:   0.95,
 			})
 
+			// Embed chunks when an embedding API key is set or the model is local;
+			// vectors are cached per embedding.cache
+			if apiKey := os.Getenv("OPENROUTER_API_KEY"); apiKey != "" || embedding.IsLocalModel(cfg.Embedding.Model) {
+				db, err := openDatabase(cfg)
+				if err != nil {
+					return fmt.Errorf("open database: %w", err)
+				}
+				defer db.Close()
+				embedder, err := newEmbedder(ctx, cfg, apiKey, db, tenantID)
+				if err != nil {
+					return fmt.Errorf("create embedder: %w", err)
+				}
+				pipeline.SetEmbedder(embedder)
+			}
+
 			// Run ingestion
 			result, err := pipeline.Ingest(ctx, ingest.IngestionRequest{
 				TenantID:     tenantID,
@@ -219,20 +241,21 @@ the pdf-extractor binary to generate Markdown first.`,
 				enc := json.NewEncoder(os.Stdout)
 				enc.SetIndent("", "  ")
 				return enc.Encode(map[string]interface{}{
-					"jobId":           result.JobID.String(),
-					"status":          "completed",
-					"specsCreated":    result.SpecsCreated,
-					"featuresCreated": result.FeaturesCreated,
-					"uspsCreated":     result.USPsCreated,
-					"chunksCreated":   result.ChunksCreated,
-					"duration":        result.Duration.String(),
+					"jobId":              result.JobID.String(),
+					"status":             "completed",
+					"specsCreated":       result.SpecsCreated,
+					"featuresCreated":    result.FeaturesCreated,
+					"uspsCreated":        result.USPsCreated,
+					"accessoriesCreated": result.AccessoriesCreated,
+					"chunksCreated":      result.ChunksCreated,
+					"duration":           result.Duration.String(),
 				})
 			}
 
 			fmt.Printf("✓ Ingestion completed successfully\n")
 			fmt.Printf("  Job ID: %s\n", result.JobID)
-			fmt.Printf("  Specs: %d | Features: %d | USPs: %d | Chunks: %d\n",
-				result.SpecsCreated, result.FeaturesCreated, result.USPsCreated, result.ChunksCreated)
+			fmt.Printf("  Specs: %d | Features: %d | USPs: %d | Accessories: %d | Chunks: %d\n",
+				result.SpecsCreated, result.FeaturesCreated, result.USPsCreated, result.AccessoriesCreated, result.ChunksCreated)
 			fmt.Printf("  Duration: %s\n", result.Duration)
 
 			return nil
@@ -293,7 +316,7 @@ for retrieval. Use --rollback to revert to a previous version.`,
 				}
 			}
 
-			publisher := ingest.NewPublisher(logger)
+			publisher := ingest.NewPublisher(logger, nil)
 
 			if rollback {
 				logger.Info().
@@ -382,13 +405,21 @@ func newQueryCmd() *cobra.Command {
 		question  string
 		intent    string
 		maxChunks int
+		benchmark bool
+		asOf      string
+		version   int
+		lineage   bool
 	)
 
 	cmd := &cobra.Command{
 		Use:   "query",
 		Short: "Query structured specs and semantic chunks",
 		Long: `Query retrieves structured facts and semantic chunks for a question.
-Results include citations and lineage information.`,
+Results include citations and lineage information. With --include-benchmarks
+public benchmark products of other tenants are searched as well and labelled
+as benchmarks; this requires retrieval.benchmarks.enabled. With --as-of or
+--campaign-version the campaign versions live at that time, or with that
+version number, are searched instead of the published ones.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 			defer cancel()
@@ -397,6 +428,17 @@ Results include citations and lineage information.`,
 			if err != nil {
 				return fmt.Errorf("invalid tenant: %w", err)
 			}
+			if benchmark && !cfg.Retrieval.Benchmarks.Enabled {
+				return fmt.Errorf("benchmark retrieval is disabled; set retrieval.benchmarks.enabled")
+			}
+			var pointInTime *time.Time
+			if asOf != "" {
+				t, err := time.Parse(time.RFC3339, asOf)
+				if err != nil {
+					return fmt.Errorf("invalid --as-of: %w", err)
+				}
+				pointInTime = &t
+			}
 
 			var productIDs []uuid.UUID
 			for _, p := range products {
@@ -420,18 +462,15 @@ Results include citations and lineage information.`,
 			}
 			defer db.Close()
 
-			// Create spec view repository
-			specViewRepo := storage.NewSpecViewRepository(db)
+			// Create spec view repository; queries are traced when tracing is enabled
+			tracedDB := storage.NewTracedDB(db, cfg.Database.Driver)
+			specViewRepo := storage.NewSpecViewRepository(tracedDB)
 
 			// Create embedder (use mock for now, can be enhanced to use real embeddings)
 			var embClient embedding.Embedder
 			apiKey := os.Getenv("OPENROUTER_API_KEY")
-			if apiKey != "" {
-				client, err := embedding.NewClient(embedding.Config{
-					APIKey:  apiKey,
-					Model:   cfg.Embedding.Model,
-					BaseURL: "https://openrouter.ai/api/v1",
-				})
+			if apiKey != "" || embedding.IsLocalModel(cfg.Embedding.Model) {
+				client, err := newEmbedder(ctx, cfg, apiKey, db, tenantID)
 				if err == nil {
 					embClient = client
 				} else {
@@ -462,15 +501,44 @@ Results include citations and lineage information.`,
 					StructuredFirst:           true,
 					SemanticFallback:          true,
 					IntentConfidenceThreshold: 0.7,
+					Benchmarks: retrieval.BenchmarkConfig{
+						MaxFacts:        cfg.Retrieval.Benchmarks.MaxFacts,
+						MaxChunks:       cfg.Retrieval.Benchmarks.MaxChunks,
+						RefreshInterval: cfg.Retrieval.Benchmarks.RefreshInterval,
+					},
 				},
 			)
+			if cfg.Retrieval.Benchmarks.Enabled {
+				router.SetBenchmarkSource(storage.NewBenchmarkRepository(tracedDB))
+			}
+			router.SetSnapshotSource(storage.NewSnapshotRepository(tracedDB))
+			router.SetLineageSource(storage.NewLineageRepository(tracedDB))
+			router.SetChunkSource(storage.NewKnowledgeChunkRepository(tracedDB))
+			router.SetEntityLinker(retrieval.NewEntityLinker(
+				retrieval.NewRepositoryCatalog(storage.NewProductRepository(tracedDB), storage.NewCampaignRepository(tracedDB)),
+				retrieval.DefaultLinkerConfig(),
+			))
+			if cfg.Retrieval.IntentModelDir != "" {
+				shared, tenants, err := retrieval.LoadIntentModels(cfg.Retrieval.IntentModelDir)
+				if err != nil {
+					return fmt.Errorf("load intent models: %w", err)
+				}
+				router.IntentClassifier().SetModel(shared)
+				router.IntentClassifier().SetTenantModel(tenantID, tenants[tenantID])
+			}
 
 			// Build request
 			req := retrieval.RetrievalRequest{
-				TenantID:   tenantID,
-				ProductIDs: productIDs,
-				Question:   question,
-				MaxChunks:  maxChunks,
+				TenantID:          tenantID,
+				ProductIDs:        productIDs,
+				Question:          question,
+				MaxChunks:         maxChunks,
+				IncludeBenchmarks: benchmark,
+				IncludeLineage:    lineage,
+				AsOf:              pointInTime,
+			}
+			if cmd.Flags().Changed("campaign-version") {
+				req.CampaignVersion = &version
 			}
 
 			if intent != "" {
@@ -483,6 +551,12 @@ Results include citations and lineage information.`,
 			if err != nil {
 				return fmt.Errorf("query failed: %w", err)
 			}
+			if stats := router.EmbeddingCacheStats(); stats != nil {
+				logger.Debug().
+					Int64("hits", stats.Hits).
+					Int64("misses", stats.Misses).
+					Msg("Embedding cache")
+			}
 
 			// Output result
 			if outputJSON {
@@ -493,11 +567,46 @@ Results include citations and lineage information.`,
 					"latencyMs":       resp.LatencyMs,
 					"structuredFacts": resp.StructuredFacts,
 					"semanticChunks":  resp.SemanticChunks,
+					"comparisons":     resp.Comparisons,
+					"availability":    resp.Availability,
+					"snapshot":        resp.Snapshot,
+					"lineage":         resp.Lineage,
+					"links":           resp.Links,
 				})
 			}
 
 			fmt.Printf("Intent: %s (latency: %dms)\n\n", resp.Intent, resp.LatencyMs)
 
+			if len(resp.Links) > 0 {
+				fmt.Printf("Linked Entities:\n")
+				for _, link := range resp.Links {
+					fmt.Printf("  • %s %q -> %s (%s, conf: %.2f)\n", link.Kind, link.Mention, link.Name, link.Method, link.Confidence)
+				}
+				fmt.Println()
+			}
+
+			if resp.Snapshot != nil {
+				fmt.Printf("Campaign Versions:\n")
+				for _, v := range resp.Snapshot.Versions {
+					fmt.Printf("  • %s v%d (%s)\n", v.CampaignVariantID, v.Version, v.Status)
+				}
+				fmt.Println()
+			}
+
+			for _, matrix := range resp.Availability {
+				fmt.Printf("Availability: %s / %s\n", matrix.Category, matrix.Name)
+				for _, row := range matrix.Rows {
+					var trims []string
+					for i, ok := range row.Available {
+						if ok {
+							trims = append(trims, availabilityTrimLabel(matrix.Trims[i]))
+						}
+					}
+					fmt.Printf("  • %s: %s\n", row.Value, strings.Join(trims, ", "))
+				}
+				fmt.Println()
+			}
+
 			if len(resp.StructuredFacts) > 0 {
 				fmt.Printf("Structured Facts:\n")
 				for _, fact := range resp.StructuredFacts {
@@ -505,7 +614,11 @@ Results include citations and lineage information.`,
 					if fact.Unit != "" {
 						fmt.Printf(" %s", fact.Unit)
 					}
-					fmt.Printf(" (conf: %.2f)\n", fact.Confidence)
+					fmt.Printf(" (conf: %.2f)", fact.Confidence)
+					if fact.Benchmark != nil {
+						fmt.Printf(" [benchmark: %s]", fact.Benchmark.ProductName)
+					}
+					fmt.Println()
 				}
 				fmt.Println()
 			}
@@ -517,7 +630,36 @@ Results include citations and lineage information.`,
 					if len(text) > 100 {
 						text = text[:100] + "..."
 					}
-					fmt.Printf("  %d. [%s] %s (dist: %.3f)\n", i+1, chunk.ChunkType, text, chunk.Distance)
+					fmt.Printf("  %d. [%s] %s (dist: %.3f)", i+1, chunk.ChunkType, text, chunk.Distance)
+					if chunk.Benchmark != nil {
+						fmt.Printf(" [benchmark: %s]", chunk.Benchmark.ProductName)
+					}
+					fmt.Println()
+				}
+			}
+
+			if len(resp.Comparisons) > 0 {
+				fmt.Printf("\nComparisons:\n")
+				for _, comp := range resp.Comparisons {
+					fmt.Printf("  • %s: %s vs %s (%s)", comp.Dimension, comp.PrimaryValue, comp.SecondaryValue, comp.Verdict)
+					if comp.Benchmark != nil {
+						fmt.Printf(" [benchmark: %s]", comp.Benchmark.ProductName)
+					}
+					fmt.Println()
+				}
+			}
+
+			if len(resp.Lineage) > 0 {
+				fmt.Printf("\nLineage:\n")
+				for _, l := range resp.Lineage {
+					fmt.Printf("  • %s %s", l.ResourceType, l.ResourceID)
+					if l.Action != "" {
+						fmt.Printf(": %s %s", l.Action, l.OccurredAt.Format(time.RFC3339))
+					}
+					if l.Origin != "" {
+						fmt.Printf(" (%s)", l.Origin)
+					}
+					fmt.Println()
 				}
 			}
 
@@ -528,8 +670,12 @@ Results include citations and lineage information.`,
 	cmd.Flags().StringVar(&tenant, "tenant", "", "tenant ID or name (required)")
 	cmd.Flags().StringSliceVar(&products, "products", nil, "product IDs to query")
 	cmd.Flags().StringVar(&question, "question", "", "question to answer (required)")
-	cmd.Flags().StringVar(&intent, "intent", "", "intent hint (spec_lookup, usp_lookup, comparison)")
+	cmd.Flags().StringVar(&intent, "intent", "", "intent hint (spec_lookup, usp_lookup, comparison, faq, pricing, availability, warranty, accessory)")
 	cmd.Flags().IntVar(&maxChunks, "max-chunks", 6, "maximum chunks to return")
+	cmd.Flags().BoolVar(&benchmark, "include-benchmarks", false, "include other tenants' public benchmark products")
+	cmd.Flags().StringVar(&asOf, "as-of", "", "answer from the campaign versions live at this RFC3339 time")
+	cmd.Flags().IntVar(&version, "campaign-version", 0, "answer from this campaign version number")
+	cmd.Flags().BoolVar(&lineage, "include-lineage", false, "report the recorded lineage of returned facts and chunks")
 
 	_ = cmd.MarkFlagRequired("tenant")
 	_ = cmd.MarkFlagRequired("question")
@@ -537,6 +683,14 @@ Results include citations and lineage information.`,
 	return cmd
 }
 
+// availabilityTrimLabel names an availability matrix column.
+func availabilityTrimLabel(trim retrieval.AvailabilityTrim) string {
+	if trim.Trim != "" {
+		return trim.Trim
+	}
+	return trim.CampaignVariantID.String()
+}
+
 // newCompareCmd creates the compare subcommand.
 func newCompareCmd() *cobra.Command {
 	var (
@@ -545,12 +699,21 @@ func newCompareCmd() *cobra.Command {
 		secondary string
 		dims      []string
 		maxRows   int
+		benchmark bool
+		asOf      string
+		version   int
 	)
 
 	cmd := &cobra.Command{
 		Use:   "compare",
 		Short: "Compare two products",
-		Long:  `Compare retrieves pre-computed comparisons between two products.`,
+		Long: `Compare retrieves the stored comparison between two products, or computes
+it from their published spec values and stores it. With --include-benchmarks
+the secondary product may be another tenant's public benchmark product; this
+requires comparison.allow_cross_tenant. With --as-of the products
+are compared as they stood at that time; with --campaign-version that version
+of the primary product is compared against the secondary product as it stood
+when the version went live.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 			defer cancel()
@@ -570,19 +733,45 @@ func newCompareCmd() *cobra.Command {
 				return fmt.Errorf("invalid secondary product: %w", err)
 			}
 
+			if benchmark && !cfg.Comparison.AllowCrossTenant {
+				return fmt.Errorf("benchmark comparisons are disabled; set comparison.allow_cross_tenant")
+			}
+
+			req := comparison.ComparisonRequest{
+				TenantID:           tenantID,
+				PrimaryProductID:   primaryID,
+				SecondaryProductID: secondaryID,
+				Dimensions:         dims,
+				MaxRows:            maxRows,
+				IncludeBenchmarks:  benchmark,
+			}
+			if asOf != "" {
+				t, err := time.Parse(time.RFC3339, asOf)
+				if err != nil {
+					return fmt.Errorf("invalid --as-of: %w", err)
+				}
+				req.AsOf = &t
+			}
+			if cmd.Flags().Changed("campaign-version") {
+				req.CampaignVersion = &version
+			}
+
 			logger.Info().
 				Str("tenant", tenant).
 				Str("primary", primary).
 				Str("secondary", secondary).
 				Msg("Comparing products")
 
-			// TODO: Wire up comparison materializer when store is available
-			_ = ctx
-			_ = tenantID
-			_ = primaryID
-			_ = secondaryID
-			_ = dims
-			_ = maxRows
+			db, err := openDatabase(cfg)
+			if err != nil {
+				return fmt.Errorf("open database: %w", err)
+			}
+			defer db.Close()
+
+			resp, err := compareProducts(ctx, logger, storage.NewTracedDB(db, cfg.Database.Driver), cfg.Comparison, req)
+			if err != nil {
+				return fmt.Errorf("compare failed: %w", err)
+			}
 
 			if outputJSON {
 				enc := json.NewEncoder(os.Stdout)
@@ -590,11 +779,35 @@ func newCompareCmd() *cobra.Command {
 				return enc.Encode(map[string]interface{}{
 					"primary":     primaryID.String(),
 					"secondary":   secondaryID.String(),
-					"comparisons": []interface{}{},
+					"comparisons": resp.Comparisons,
+					"hash":        resp.Hash,
+					"versions":    resp.Versions,
 				})
 			}
 
-			fmt.Printf("No pre-computed comparisons found for this product pair.\n")
+			if len(resp.Versions) > 0 {
+				fmt.Printf("Campaign Versions:\n")
+				for _, v := range resp.Versions {
+					fmt.Printf("  • %s v%d (%s)\n", v.ID, v.Version, v.Status)
+				}
+				fmt.Println()
+			}
+
+			if len(resp.Comparisons) == 0 {
+				fmt.Printf("No comparable spec values found for this product pair.\n")
+				return nil
+			}
+			fmt.Printf("Comparisons:\n")
+			for _, row := range resp.Comparisons {
+				fmt.Printf("  • %s: %s vs %s (%s)", row.Dimension, row.PrimaryValue, row.SecondaryValue, row.Verdict)
+				if row.BenchmarkProductName != "" {
+					fmt.Printf(" [benchmark: %s]", row.BenchmarkProductName)
+				}
+				fmt.Println()
+				if row.Narrative != "" {
+					fmt.Printf("    %s\n", row.Narrative)
+				}
+			}
 			return nil
 		},
 	}
@@ -604,6 +817,9 @@ func newCompareCmd() *cobra.Command {
 	cmd.Flags().StringVar(&secondary, "secondary", "", "secondary product ID (required)")
 	cmd.Flags().StringSliceVar(&dims, "dimensions", nil, "dimensions to compare")
 	cmd.Flags().IntVar(&maxRows, "max-rows", 20, "maximum rows to return")
+	cmd.Flags().BoolVar(&benchmark, "include-benchmarks", false, "allow another tenant's public benchmark product as secondary")
+	cmd.Flags().StringVar(&asOf, "as-of", "", "compare the products as they stood at this RFC3339 time")
+	cmd.Flags().IntVar(&version, "campaign-version", 0, "compare this campaign version of the primary product")
 
 	_ = cmd.MarkFlagRequired("tenant")
 	_ = cmd.MarkFlagRequired("primary")
@@ -612,6 +828,19 @@ func newCompareCmd() *cobra.Command {
 	return cmd
 }
 
+// compareProducts serves a comparison from the comparison rows stored in db,
+// computing missing ones from the products' spec values and storing them.
+// Point-in-time requests are compared from campaign version history.
+func compareProducts(ctx context.Context, logger *observability.Logger, db storage.DB, compCfg config.ComparisonConfig, req comparison.ComparisonRequest) (*comparison.ComparisonResponse, error) {
+	materializer := comparison.NewMaterializer(logger, nil, storage.NewComparisonRepository(db), comparison.Config{
+		AllowCrossTenant: compCfg.AllowCrossTenant,
+	})
+	materializer.SetSpecSource(storage.NewSpecViewRepository(db))
+	materializer.SetSnapshotSource(storage.NewSnapshotRepository(db))
+	materializer.SetProductAccess(storage.NewBenchmarkRepository(db))
+	return materializer.Compare(ctx, req)
+}
+
 // newDriftCmd creates the drift subcommand.
 func newDriftCmd() *cobra.Command {
 	var (
@@ -918,31 +1147,41 @@ Use --down to rollback migrations.`,
 			}
 			defer db.Close()
 
-			// Determine migration file
-			var migrationFile string
+			// Determine migration files (relative to knowledge-engine directory)
+			pattern := "db/migrations/*.sql"
 			if target == "sqlite" {
-				migrationFile = "db/migrations/0001_init_sqlite.sql"
-			} else {
-				migrationFile = "db/migrations/0001_init.sql"
+				pattern = "db/migrations/*_sqlite.sql"
 			}
+			migrationFiles, err := filepath.Glob(pattern)
+			if err != nil {
+				return fmt.Errorf("list migration files: %w", err)
+			}
+			sort.Strings(migrationFiles)
 
-			// Read migration file (relative to knowledge-engine directory)
-			migrationPath := migrationFile
+			for _, migrationPath := range migrationFiles {
+				if target != "sqlite" && strings.HasSuffix(migrationPath, "_sqlite.sql") {
+					continue
+				}
 
-			logger.Info().
-				Str("target", target).
-				Str("file", migrationPath).
-				Msg("Running migrations")
+				logger.Info().
+					Str("target", target).
+					Str("file", migrationPath).
+					Msg("Running migrations")
 
-			migrationSQL, err := os.ReadFile(migrationPath)
-			if err != nil {
-				return fmt.Errorf("read migration file: %w", err)
-			}
+				migrationSQL, err := os.ReadFile(migrationPath)
+				if err != nil {
+					return fmt.Errorf("read migration file: %w", err)
+				}
 
-			// Execute migration
-			_, err = db.Exec(string(migrationSQL))
-			if err != nil {
-				return fmt.Errorf("execute migration: %w", err)
+				// Execute migration
+				_, err = db.Exec(string(migrationSQL))
+				if err != nil {
+					// Column additions are not idempotent in SQLite; treat re-runs as applied
+					if strings.Contains(err.Error(), "duplicate column name") {
+						continue
+					}
+					return fmt.Errorf("execute migration %s: %w", migrationPath, err)
+				}
 			}
 
 			fmt.Printf("✓ Migrations applied on %s\n", target)
@@ -1021,3 +1260,104 @@ func openDatabase(cfg *config.Config) (*sql.DB, error) {
 
 	return db, nil
 }
+
+// newEmbedder creates an OpenRouter embedding client that retries transient
+// failures and, when configured, falls back to a secondary provider and
+// caches vectors. The sqlite cache defaults to db. The "local" model needs no
+// API key and uses the tenant's fit saved in db by "embedding fit", so ingest
+// and query embed alike; without one it is unfitted.
+func newEmbedder(ctx context.Context, cfg *config.Config, apiKey string, db *sql.DB, tenantID uuid.UUID) (embedding.Embedder, error) {
+	if embedding.IsLocalModel(cfg.Embedding.Model) {
+		fits, err := embedding.NewLocalFitStore(ctx, db)
+		if err != nil {
+			return nil, err
+		}
+		local, err := fits.Current(ctx, tenantID)
+		if errors.Is(err, embedding.ErrNoLocalFit) {
+			return embedding.NewLocalEmbedder(embedding.LocalConfig{Dimension: cfg.Embedding.Dimension}), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return local, nil
+	}
+
+	client, err := embedding.NewClient(embedding.Config{
+		APIKey:    apiKey,
+		Model:     cfg.Embedding.Model,
+		BaseURL:   "https://openrouter.ai/api/v1",
+		Dimension: cfg.Embedding.Dimension,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	retry := cfg.Embedding.Retry
+	resilient := embedding.NewResilientEmbedder(logger, client, embedding.ResilientConfig{
+		MaxRetries:        retry.MaxRetries,
+		InitialBackoff:    retry.InitialBackoff,
+		MaxBackoff:        retry.MaxBackoff,
+		RequestsPerSecond: retry.RequestsPerSecond,
+		Burst:             retry.Burst,
+		MaxBatchSize:      cfg.Embedding.BatchSize,
+		BreakerThreshold:  retry.BreakerThreshold,
+		BreakerCooldown:   retry.BreakerCooldown,
+	})
+
+	if fb := cfg.Embedding.Fallback; fb.BaseURL != "" {
+		model := fb.Model
+		if model == "" {
+			model = cfg.Embedding.Model
+		}
+		fallback, err := embedding.NewClient(embedding.Config{
+			APIKey:    fb.APIKey,
+			Model:     model,
+			BaseURL:   fb.BaseURL,
+			Dimension: cfg.Embedding.Dimension,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("fallback embedding client: %w", err)
+		}
+		resilient.SetFallback(fallback)
+	}
+
+	var backend embedding.CacheBackend
+	switch cfg.Embedding.Cache.Backend {
+	case "cache":
+		backend = embedding.NewClientCacheBackend(newCacheClient(cfg), cfg.Embedding.Cache.TTL)
+	case "sqlite":
+		if path := cfg.Embedding.Cache.Path; path != "" {
+			// Stays open for the life of the command
+			cacheDB, err := sql.Open("sqlite3", path)
+			if err != nil {
+				return nil, fmt.Errorf("open embedding cache: %w", err)
+			}
+			db = cacheDB
+		}
+		backend, err = embedding.NewSQLCacheBackend(ctx, db)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return resilient, nil
+	}
+	return embedding.NewCachedEmbedder(logger, resilient, backend), nil
+}
+
+// newCacheClient connects to Redis when it is the configured cache driver,
+// falling back to an in-memory cache.
+func newCacheClient(cfg *config.Config) cache.Client {
+	if cfg.Cache.Driver == "redis" {
+		client, err := cache.NewRedisClient(cache.RedisConfig{
+			Addr:     cfg.Cache.Redis.Addr,
+			Password: cfg.Cache.Redis.Password,
+			DB:       cfg.Cache.Redis.DB,
+			PoolSize: cfg.Cache.Redis.PoolSize,
+		})
+		if err == nil {
+			return client
+		}
+		logger.Warn().Err(err).Msg("Failed to connect to Redis, using in-memory cache")
+	}
+	return cache.NewMemoryClient(cfg.Cache.MaxEntries)
+}
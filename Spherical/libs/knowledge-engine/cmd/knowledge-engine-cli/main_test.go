@@ -0,0 +1,238 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/comparison"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/config"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/embedding"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/observability"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/storage"
+)
+
+func TestNewEmbedder_LocalFitSharedByIngestAndQuery(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "knowledge.db"))
+	require.NoError(t, err)
+	defer db.Close()
+	localCfg := &config.Config{Embedding: config.EmbeddingConfig{Model: embedding.LocalModel, Dimension: 256}}
+	tenant := uuid.New()
+
+	unfitted, err := newEmbedder(ctx, localCfg, "", db, tenant)
+	require.NoError(t, err)
+	assert.Equal(t, embedding.LocalModel, unfitted.Model(), "without a saved fit the embedder is unfitted")
+
+	fitted := embedding.NewLocalEmbedder(embedding.LocalConfig{Dimension: 256})
+	fitted.Fit([]string{"towing capacity 3500 kg", "heated seats", "panoramic roof"})
+	fits, err := embedding.NewLocalFitStore(ctx, db)
+	require.NoError(t, err)
+	require.NoError(t, fits.Save(ctx, tenant, fitted))
+
+	ingest, err := newEmbedder(ctx, localCfg, "", db, tenant)
+	require.NoError(t, err)
+	stored, err := ingest.EmbedSingle(ctx, "heated seats")
+	require.NoError(t, err)
+
+	query, err := newEmbedder(ctx, localCfg, "", db, tenant)
+	require.NoError(t, err)
+	assert.Equal(t, fitted.Model(), ingest.Model())
+	assert.Equal(t, ingest.Model(), query.Model(), "ingest and query embed with the same fit")
+	asked, err := query.EmbedSingle(ctx, "heated seats")
+	require.NoError(t, err)
+	assert.Equal(t, stored, asked)
+}
+
+// migratedDB opens a SQLite database with every SQLite migration applied.
+func migratedDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "knowledge.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	migrations, err := filepath.Glob("../../db/migrations/*_sqlite.sql")
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+	sort.Strings(migrations)
+	for _, path := range migrations {
+		script, err := os.ReadFile(path)
+		require.NoError(t, err)
+		_, err = db.Exec(string(script))
+		require.NoError(t, err, path)
+	}
+	return db
+}
+
+func TestCompareProducts_ComputesAndStores(t *testing.T) {
+	ctx := context.Background()
+	db := migratedDB(t)
+	logger := observability.NewLogger(observability.LogConfig{Level: "error"})
+
+	tenant, camry, corolla := uuid.New(), uuid.New(), uuid.New()
+	category, power, price := uuid.New(), uuid.New(), uuid.New()
+	exec := func(query string, args ...interface{}) {
+		_, err := db.Exec(query, args...)
+		require.NoError(t, err)
+	}
+	exec(`INSERT INTO tenants (id, name) VALUES ($1, $2)`, tenant, "Toyota")
+	exec(`INSERT INTO spec_categories (id, name) VALUES ($1, $2)`, category, "Engine")
+	exec(`INSERT INTO spec_items (id, category_id, display_name) VALUES ($1, $2, $3)`, power, category, "Max Power")
+	exec(`INSERT INTO spec_items (id, category_id, display_name) VALUES ($1, $2, $3)`, price, category, "Price")
+	values := map[uuid.UUID][2]string{camry: {"225 hp", "$28,400"}, corolla: {"169 hp", "$22,050"}}
+	for product, name := range map[uuid.UUID]string{camry: "Camry", corolla: "Corolla"} {
+		campaign := uuid.New()
+		exec(`INSERT INTO products (id, tenant_id, name) VALUES ($1, $2, $3)`, product, tenant, name)
+		exec(`INSERT INTO campaign_variants (id, product_id, tenant_id, status) VALUES ($1, $2, $3, 'published')`, campaign, product, tenant)
+		for i, item := range []uuid.UUID{power, price} {
+			exec(`INSERT INTO spec_values (id, tenant_id, product_id, campaign_variant_id, spec_item_id, value_text)
+				VALUES ($1, $2, $3, $4, $5, $6)`, uuid.New(), tenant, product, campaign, item, values[product][i])
+		}
+	}
+
+	req := comparison.ComparisonRequest{TenantID: tenant, PrimaryProductID: camry, SecondaryProductID: corolla}
+	resp, err := compareProducts(ctx, logger, db, config.ComparisonConfig{}, req)
+	require.NoError(t, err)
+	verdicts := make(map[string]storage.Verdict)
+	for _, row := range resp.Comparisons {
+		verdicts[row.Dimension] = row.Verdict
+	}
+	assert.Equal(t, map[string]storage.Verdict{
+		"Max Power": storage.VerdictPrimaryBetter,
+		"Price":     storage.VerdictSecondaryBetter,
+	}, verdicts)
+
+	stored, err := storage.NewComparisonRepository(db).GetComparison(ctx, tenant, corolla, camry)
+	require.NoError(t, err)
+	assert.Len(t, stored, 2, "computed rows are stored")
+
+	// A later run is served from the stored rows, in its own orientation
+	exec(`DELETE FROM spec_values`)
+	resp, err = compareProducts(ctx, logger, db, config.ComparisonConfig{}, comparison.ComparisonRequest{
+		TenantID: tenant, PrimaryProductID: corolla, SecondaryProductID: camry, Dimensions: []string{"Price"},
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Comparisons, 1)
+	assert.Equal(t, corolla, resp.Comparisons[0].PrimaryProductID)
+	assert.Equal(t, "$22,050", resp.Comparisons[0].PrimaryValue)
+	assert.Equal(t, storage.VerdictPrimaryBetter, resp.Comparisons[0].Verdict)
+}
+
+func TestCompareProducts_AsOfAndCampaignVersion(t *testing.T) {
+	ctx := context.Background()
+	db := migratedDB(t)
+	logger := observability.NewLogger(observability.LogConfig{Level: "error"})
+
+	tenant, camry, corolla := uuid.New(), uuid.New(), uuid.New()
+	category, power := uuid.New(), uuid.New()
+	jan := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	apr := time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)
+	exec := func(query string, args ...interface{}) {
+		_, err := db.Exec(query, args...)
+		require.NoError(t, err)
+	}
+	exec(`INSERT INTO tenants (id, name) VALUES ($1, $2)`, tenant, "Toyota")
+	exec(`INSERT INTO spec_categories (id, name) VALUES ($1, $2)`, category, "Engine")
+	exec(`INSERT INTO spec_items (id, category_id, display_name) VALUES ($1, $2, $3)`, power, category, "Max Power")
+	exec(`INSERT INTO products (id, tenant_id, name) VALUES ($1, $2, $3)`, camry, tenant, "Camry")
+	exec(`INSERT INTO products (id, tenant_id, name) VALUES ($1, $2, $3)`, corolla, tenant, "Corolla")
+	version := func(product uuid.UUID, number int, status string, from time.Time, through *time.Time, value string) {
+		campaign := uuid.New()
+		exec(`INSERT INTO campaign_variants (id, product_id, tenant_id, status, version, effective_from, effective_through, is_draft)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, 0)`, campaign, product, tenant, status, number, from, through)
+		exec(`INSERT INTO spec_values (id, tenant_id, product_id, campaign_variant_id, spec_item_id, value_text)
+			VALUES ($1, $2, $3, $4, $5, $6)`, uuid.New(), tenant, product, campaign, power, value)
+	}
+	version(camry, 1, "archived", jan, &apr, "150 hp")
+	version(camry, 2, "published", apr, nil, "225 hp")
+	version(corolla, 1, "published", jan, nil, "169 hp")
+
+	feb := jan.AddDate(0, 1, 0)
+	resp, err := compareProducts(ctx, logger, db, config.ComparisonConfig{}, comparison.ComparisonRequest{
+		TenantID: tenant, PrimaryProductID: camry, SecondaryProductID: corolla, AsOf: &feb,
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Comparisons, 1)
+	assert.Equal(t, "150 hp", resp.Comparisons[0].PrimaryValue, "the archived version was live in February")
+	assert.Equal(t, "169 hp", resp.Comparisons[0].SecondaryValue)
+	assert.Equal(t, storage.VerdictSecondaryBetter, resp.Comparisons[0].Verdict)
+	require.Len(t, resp.Versions, 2)
+	assert.Equal(t, 1, resp.Versions[0].Version)
+
+	number := 2
+	resp, err = compareProducts(ctx, logger, db, config.ComparisonConfig{}, comparison.ComparisonRequest{
+		TenantID: tenant, PrimaryProductID: camry, SecondaryProductID: corolla, CampaignVersion: &number,
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Comparisons, 1)
+	assert.Equal(t, "225 hp", resp.Comparisons[0].PrimaryValue)
+	assert.Equal(t, storage.VerdictPrimaryBetter, resp.Comparisons[0].Verdict)
+
+	stored, err := storage.NewComparisonRepository(db).GetComparison(ctx, tenant, camry, corolla)
+	require.NoError(t, err)
+	assert.Empty(t, stored, "point-in-time comparisons are not materialized")
+
+	before := jan.AddDate(-1, 0, 0)
+	_, err = compareProducts(ctx, logger, db, config.ComparisonConfig{}, comparison.ComparisonRequest{
+		TenantID: tenant, PrimaryProductID: camry, SecondaryProductID: corolla, AsOf: &before,
+	})
+	assert.ErrorIs(t, err, storage.ErrNoCampaignVersion)
+}
+
+func TestCompareProducts_BenchmarkAccess(t *testing.T) {
+	ctx := context.Background()
+	db := migratedDB(t)
+	logger := observability.NewLogger(observability.LogConfig{Level: "error"})
+
+	toyota, honda := uuid.New(), uuid.New()
+	camry, accord, pilot := uuid.New(), uuid.New(), uuid.New()
+	category, power := uuid.New(), uuid.New()
+	exec := func(query string, args ...interface{}) {
+		_, err := db.Exec(query, args...)
+		require.NoError(t, err)
+	}
+	exec(`INSERT INTO tenants (id, name) VALUES ($1, $2)`, toyota, "Toyota")
+	exec(`INSERT INTO tenants (id, name) VALUES ($1, $2)`, honda, "Honda")
+	exec(`INSERT INTO spec_categories (id, name) VALUES ($1, $2)`, category, "Engine")
+	exec(`INSERT INTO spec_items (id, category_id, display_name) VALUES ($1, $2, $3)`, power, category, "Max Power")
+	product := func(id, tenant uuid.UUID, name string, benchmark bool, value string) {
+		campaign := uuid.New()
+		exec(`INSERT INTO products (id, tenant_id, name, is_public_benchmark) VALUES ($1, $2, $3, $4)`, id, tenant, name, benchmark)
+		exec(`INSERT INTO campaign_variants (id, product_id, tenant_id, status) VALUES ($1, $2, $3, 'published')`, campaign, id, tenant)
+		exec(`INSERT INTO spec_values (id, tenant_id, product_id, campaign_variant_id, spec_item_id, value_text)
+			VALUES ($1, $2, $3, $4, $5, $6)`, uuid.New(), tenant, id, campaign, power, value)
+	}
+	product(camry, toyota, "Camry", false, "225 hp")
+	product(accord, honda, "Accord", true, "192 hp")
+	product(pilot, honda, "Pilot", false, "285 hp")
+
+	crossTenant := config.ComparisonConfig{AllowCrossTenant: true}
+	compare := func(compCfg config.ComparisonConfig, primary, secondary uuid.UUID, benchmarks bool) (*comparison.ComparisonResponse, error) {
+		return compareProducts(ctx, logger, db, compCfg, comparison.ComparisonRequest{
+			TenantID: toyota, PrimaryProductID: primary, SecondaryProductID: secondary, IncludeBenchmarks: benchmarks,
+		})
+	}
+
+	_, err := compare(crossTenant, camry, accord, false)
+	assert.ErrorIs(t, err, comparison.ErrProductNotAccessible, "benchmarks must be requested")
+	_, err = compare(config.ComparisonConfig{}, camry, accord, true)
+	assert.ErrorIs(t, err, comparison.ErrProductNotAccessible, "cross-tenant comparisons must be allowed")
+	_, err = compare(crossTenant, camry, pilot, true)
+	assert.ErrorIs(t, err, comparison.ErrProductNotAccessible, "only public benchmark products are shared")
+	_, err = compare(crossTenant, accord, camry, true)
+	assert.ErrorIs(t, err, comparison.ErrProductNotAccessible, "the primary product must be the caller's")
+
+	resp, err := compare(crossTenant, camry, accord, true)
+	require.NoError(t, err)
+	require.Len(t, resp.Comparisons, 1)
+	assert.Equal(t, "192 hp", resp.Comparisons[0].SecondaryValue)
+	assert.Equal(t, "Accord", resp.Comparisons[0].BenchmarkProductName)
+}
@@ -0,0 +1,14 @@
+-- Campaign inheritance
+-- A campaign variant may inherit spec values and feature blocks from a base
+-- variant of the same product and override only what differs.
+
+ALTER TABLE campaign_variants
+    ADD COLUMN base_campaign_variant_id UUID REFERENCES campaign_variants(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_campaigns_base ON campaign_variants(base_campaign_variant_id);
+
+-- A feature block in a derived variant may replace a block inherited from its base.
+ALTER TABLE feature_blocks
+    ADD COLUMN overrides_block_id UUID REFERENCES feature_blocks(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_featureblocks_overrides ON feature_blocks(overrides_block_id);
@@ -0,0 +1,10 @@
+-- Shared knowledge chunks for cross-tenant queries (SQLite)
+-- Mirrors the knowledge_chunks_shared view from the Postgres schema so
+-- benchmark retrieval reads shared chunks through the same view.
+
+DROP VIEW IF EXISTS knowledge_chunks_shared;
+
+CREATE VIEW knowledge_chunks_shared AS
+SELECT *
+FROM knowledge_chunks
+WHERE visibility IN ('shared', 'benchmark');
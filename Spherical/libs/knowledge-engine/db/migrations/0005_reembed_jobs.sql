@@ -0,0 +1,23 @@
+-- Re-embedding jobs
+-- Track migrations of a tenant's or campaign's chunks to a new embedding
+-- model, so an interrupted job resumes after the last re-embedded chunk.
+
+CREATE TABLE IF NOT EXISTS reembed_jobs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    campaign_variant_id UUID REFERENCES campaign_variants(id) ON DELETE CASCADE,
+    embedding_model TEXT NOT NULL,
+    embedding_version TEXT NOT NULL,
+    previous_embedding_model TEXT,
+    previous_embedding_version TEXT,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'running', 'paused', 'failed', 'completed', 'rolled_back')),
+    total_chunks INTEGER NOT NULL DEFAULT 0,
+    processed_chunks INTEGER NOT NULL DEFAULT 0,
+    last_chunk_id UUID,
+    error TEXT,
+    started_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_reembed_jobs_tenant ON reembed_jobs(tenant_id);
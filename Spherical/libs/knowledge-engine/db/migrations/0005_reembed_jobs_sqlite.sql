@@ -0,0 +1,23 @@
+-- Re-embedding jobs (SQLite)
+-- Track migrations of a tenant's or campaign's chunks to a new embedding
+-- model, so an interrupted job resumes after the last re-embedded chunk.
+
+CREATE TABLE IF NOT EXISTS reembed_jobs (
+    id TEXT PRIMARY KEY DEFAULT (lower(hex(randomblob(4))) || '-' || lower(hex(randomblob(2))) || '-4' || substr(lower(hex(randomblob(2))),2) || '-' || substr('89ab',abs(random()) % 4 + 1, 1) || substr(lower(hex(randomblob(2))),2) || '-' || lower(hex(randomblob(6)))),
+    tenant_id TEXT NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    campaign_variant_id TEXT REFERENCES campaign_variants(id) ON DELETE CASCADE,
+    embedding_model TEXT NOT NULL,
+    embedding_version TEXT NOT NULL,
+    previous_embedding_model TEXT,
+    previous_embedding_version TEXT,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'running', 'paused', 'failed', 'completed', 'rolled_back')),
+    total_chunks INTEGER NOT NULL DEFAULT 0,
+    processed_chunks INTEGER NOT NULL DEFAULT 0,
+    last_chunk_id TEXT,
+    error TEXT,
+    started_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    completed_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_reembed_jobs_tenant ON reembed_jobs(tenant_id);
@@ -0,0 +1,18 @@
+-- Embedding index activations
+-- Record which embedding model and version each tenant's, or campaign's,
+-- vector search uses, so restarted instances and other replicas serve the
+-- same index. Re-embedding jobs can be cancelled from another process.
+
+CREATE TABLE IF NOT EXISTS embedding_index_activations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    campaign_variant_id UUID REFERENCES campaign_variants(id) ON DELETE CASCADE,
+    embedding_model TEXT NOT NULL,
+    embedding_version TEXT NOT NULL,
+    activated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_embedding_index_activations_scope
+    ON embedding_index_activations(tenant_id, campaign_variant_id, activated_at);
+
+ALTER TABLE reembed_jobs ADD COLUMN IF NOT EXISTS cancel_requested BOOLEAN NOT NULL DEFAULT FALSE;
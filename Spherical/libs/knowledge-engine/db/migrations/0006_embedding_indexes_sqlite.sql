@@ -0,0 +1,18 @@
+-- Embedding index activations (SQLite)
+-- Record which embedding model and version each tenant's, or campaign's,
+-- vector search uses, so restarted instances and other replicas serve the
+-- same index. Re-embedding jobs can be cancelled from another process.
+
+CREATE TABLE IF NOT EXISTS embedding_index_activations (
+    id TEXT PRIMARY KEY DEFAULT (lower(hex(randomblob(4))) || '-' || lower(hex(randomblob(2))) || '-4' || substr(lower(hex(randomblob(2))),2) || '-' || substr('89ab',abs(random()) % 4 + 1, 1) || substr(lower(hex(randomblob(2))),2) || '-' || lower(hex(randomblob(6)))),
+    tenant_id TEXT NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    campaign_variant_id TEXT REFERENCES campaign_variants(id) ON DELETE CASCADE,
+    embedding_model TEXT NOT NULL,
+    embedding_version TEXT NOT NULL,
+    activated_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_embedding_index_activations_scope
+    ON embedding_index_activations(tenant_id, campaign_variant_id, activated_at);
+
+ALTER TABLE reembed_jobs ADD COLUMN cancel_requested INTEGER NOT NULL DEFAULT 0;
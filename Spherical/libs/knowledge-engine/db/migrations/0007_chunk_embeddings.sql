@@ -0,0 +1,13 @@
+-- Chunk embeddings per embedding version
+-- Keep the vectors a re-embedding job writes to a shadow index, so an index
+-- reopened after a restart is refilled from the database instead of empty.
+-- Versions may use models of other dimensions, so the column is unsized.
+
+CREATE TABLE IF NOT EXISTS chunk_embeddings (
+    chunk_id UUID NOT NULL REFERENCES knowledge_chunks(id) ON DELETE CASCADE,
+    embedding_version TEXT NOT NULL,
+    embedding_model TEXT NOT NULL,
+    embedding_vector vector NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (embedding_version, chunk_id)
+);
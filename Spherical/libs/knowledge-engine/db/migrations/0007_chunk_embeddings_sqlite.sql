@@ -0,0 +1,12 @@
+-- Chunk embeddings per embedding version (SQLite)
+-- Keep the vectors a re-embedding job writes to a shadow index, so an index
+-- reopened after a restart is refilled from the database instead of empty.
+
+CREATE TABLE IF NOT EXISTS chunk_embeddings (
+    chunk_id TEXT NOT NULL REFERENCES knowledge_chunks(id) ON DELETE CASCADE,
+    embedding_version TEXT NOT NULL,
+    embedding_model TEXT NOT NULL,
+    embedding_vector TEXT NOT NULL,
+    created_at TEXT NOT NULL DEFAULT (datetime('now')),
+    PRIMARY KEY (embedding_version, chunk_id)
+);
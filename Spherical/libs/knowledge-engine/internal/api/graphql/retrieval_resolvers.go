@@ -3,6 +3,8 @@ package graphql
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/monitoring"
@@ -34,9 +36,20 @@ type RetrievalQueryInput struct {
 	CampaignVariantID *string          `json:"campaignVariantId,omitempty"`
 	Question          string           `json:"question"`
 	IntentHint        *string          `json:"intentHint,omitempty"`
+	ConversationContext []*ConversationMessageInput `json:"conversationContext,omitempty"`
 	Filters           *FilterInput     `json:"filters,omitempty"`
 	MaxChunks         *int             `json:"maxChunks,omitempty"`
 	IncludeLineage    *bool            `json:"includeLineage,omitempty"`
+	Explain           *bool            `json:"explain,omitempty"`
+	IncludeBenchmarks *bool            `json:"includeBenchmarks,omitempty"`
+	AsOf              *string          `json:"asOf,omitempty"`
+	CampaignVersion   *int             `json:"campaignVersion,omitempty"`
+}
+
+// ConversationMessageInput represents a conversation turn.
+type ConversationMessageInput struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
 }
 
 // FilterInput represents retrieval filters.
@@ -53,6 +66,112 @@ type RetrievalResult struct {
 	SemanticChunks  []*ChunkResult    `json:"semanticChunks"`
 	Comparisons     []*CompResult     `json:"comparisons,omitempty"`
 	Lineage         []*LineageResult  `json:"lineage,omitempty"`
+	RewrittenQuestion *string         `json:"rewrittenQuestion,omitempty"`
+	Links           []*EntityLinkResult `json:"links"`
+	Availability    []*AvailabilityResult `json:"availability"`
+	Trace           *TraceResult        `json:"trace,omitempty"`
+	Snapshot        *SnapshotResult     `json:"snapshot,omitempty"`
+}
+
+// SnapshotResult represents the campaign versions a point-in-time response used.
+type SnapshotResult struct {
+	AsOf     *string                  `json:"asOf,omitempty"`
+	Versions []*CampaignVersionResult `json:"versions"`
+}
+
+// CampaignVersionResult represents one version of a campaign variant.
+type CampaignVersionResult struct {
+	CampaignID       string  `json:"campaignId"`
+	ProductID        *string `json:"productId,omitempty"`
+	Version          int     `json:"version"`
+	Status           string  `json:"status"`
+	EffectiveFrom    *string `json:"effectiveFrom,omitempty"`
+	EffectiveThrough *string `json:"effectiveThrough,omitempty"`
+}
+
+// TraceResult represents the explain trace of a retrieval query.
+type TraceResult struct {
+	Question          string                  `json:"question"`
+	Intent            string                  `json:"intent"`
+	ClassifiedIntent  string                  `json:"classifiedIntent"`
+	IntentConfidence  float64                 `json:"intentConfidence"`
+	IntentHinted      bool                    `json:"intentHinted"`
+	Keywords          []string                `json:"keywords"`
+	Expansions        []*TraceExpansionResult `json:"expansions"`
+	Path              string                  `json:"path"`
+	KeywordConfidence float64                 `json:"keywordConfidence"`
+	ConfidenceFactors map[string]float64      `json:"confidenceFactors,omitempty"`
+	Cache             string                  `json:"cache"`
+	Candidates        []*TraceCandidateResult `json:"candidates"`
+	Filters           []*TraceFilterResult    `json:"filters"`
+	Stages            []*TraceStageResult     `json:"stages"`
+}
+
+// TraceExpansionResult represents a weighted query expansion term.
+type TraceExpansionResult struct {
+	Term     string  `json:"term"`
+	Original string  `json:"original"`
+	Source   string  `json:"source"`
+	Weight   float64 `json:"weight"`
+}
+
+// TraceCandidateResult represents a scored retrieval candidate.
+type TraceCandidateResult struct {
+	Kind       string             `json:"kind"`
+	ID         string             `json:"id"`
+	Label      *string            `json:"label,omitempty"`
+	Source     string             `json:"source"`
+	Score      float64            `json:"score"`
+	Components map[string]float64 `json:"components,omitempty"`
+}
+
+// TraceFilterResult represents a filter decision.
+type TraceFilterResult struct {
+	Stage   string `json:"stage"`
+	Subject string `json:"subject"`
+	Kept    bool   `json:"kept"`
+	Reason  string `json:"reason"`
+}
+
+// TraceStageResult represents a query stage timing.
+type TraceStageResult struct {
+	Stage      string  `json:"stage"`
+	DurationMs float64 `json:"durationMs"`
+}
+
+// EntityLinkResult represents a question mention linked to a product or trim.
+type EntityLinkResult struct {
+	Kind       string  `json:"kind"`
+	ID         string  `json:"id"`
+	ProductID  string  `json:"productId"`
+	Name       string  `json:"name"`
+	Mention    string  `json:"mention"`
+	ModelYear  *int    `json:"modelYear,omitempty"`
+	Method     string  `json:"method"`
+	Confidence float64 `json:"confidence"`
+	Applied    bool    `json:"applied"`
+}
+
+// AvailabilityResult represents the values of one attribute offered per trim.
+type AvailabilityResult struct {
+	Category string                    `json:"category"`
+	Name     string                    `json:"name"`
+	Trims    []*AvailabilityTrimResult `json:"trims"`
+	Rows     []*AvailabilityRowResult  `json:"rows"`
+}
+
+// AvailabilityTrimResult represents an availability matrix column.
+type AvailabilityTrimResult struct {
+	CampaignVariantID string  `json:"campaignVariantId"`
+	ProductID         string  `json:"productId"`
+	ProductName       *string `json:"productName,omitempty"`
+	Trim              *string `json:"trim,omitempty"`
+}
+
+// AvailabilityRowResult represents an availability matrix row, one cell per trim.
+type AvailabilityRowResult struct {
+	Value     string `json:"value"`
+	Available []bool `json:"available"`
 }
 
 // SpecFactResult represents a structured spec fact.
@@ -65,6 +184,13 @@ type SpecFactResult struct {
 	Confidence        float64       `json:"confidence"`
 	CampaignVariantID string        `json:"campaignVariantId"`
 	Source            *SourceResult `json:"source,omitempty"`
+	Benchmark         *BenchmarkResult `json:"benchmark,omitempty"`
+}
+
+// BenchmarkResult labels a result sourced from another tenant's public benchmark product.
+type BenchmarkResult struct {
+	ProductID   string `json:"productId"`
+	ProductName string `json:"productName"`
 }
 
 // ChunkResult represents a semantic chunk.
@@ -75,6 +201,7 @@ type ChunkResult struct {
 	Distance  float64                `json:"distance"`
 	Metadata  map[string]interface{} `json:"metadata,omitempty"`
 	Source    *SourceResult          `json:"source,omitempty"`
+	Benchmark *BenchmarkResult       `json:"benchmark,omitempty"`
 }
 
 // CompResult represents a comparison result.
@@ -86,6 +213,7 @@ type CompResult struct {
 	SecondaryValue     *string `json:"secondaryValue,omitempty"`
 	Verdict            string  `json:"verdict"`
 	Narrative          *string `json:"narrative,omitempty"`
+	Benchmark          *BenchmarkResult `json:"benchmark,omitempty"`
 }
 
 // LineageResult represents lineage information.
@@ -128,6 +256,16 @@ func (r *RetrievalResolver) Query(ctx context.Context, input RetrievalQueryInput
 		}
 	}
 
+	// Parse the point in time to answer from
+	var asOf *time.Time
+	if input.AsOf != nil {
+		t, err := time.Parse(time.RFC3339, *input.AsOf)
+		if err != nil {
+			return nil, fmt.Errorf("invalid asOf: %w", err)
+		}
+		asOf = &t
+	}
+
 	// Parse intent hint
 	var intentHint *retrieval.Intent
 	if input.IntentHint != nil {
@@ -155,16 +293,31 @@ func (r *RetrievalResolver) Query(ctx context.Context, input RetrievalQueryInput
 		includeLineage = *input.IncludeLineage
 	}
 
+	explain := input.Explain != nil && *input.Explain
+
+	// Parse conversation context
+	var conversation []retrieval.ConversationMessage
+	for _, msg := range input.ConversationContext {
+		if msg != nil {
+			conversation = append(conversation, retrieval.ConversationMessage{Role: msg.Role, Content: msg.Content})
+		}
+	}
+
 	// Build request
 	req := retrieval.RetrievalRequest{
-		TenantID:          tenantID,
-		ProductIDs:        productIDs,
-		CampaignVariantID: campaignVariantID,
-		Question:          input.Question,
-		IntentHint:        intentHint,
-		Filters:           filters,
-		MaxChunks:         maxChunks,
-		IncludeLineage:    includeLineage,
+		TenantID:            tenantID,
+		ProductIDs:          productIDs,
+		CampaignVariantID:   campaignVariantID,
+		Question:            input.Question,
+		IntentHint:          intentHint,
+		ConversationContext: conversation,
+		Filters:             filters,
+		MaxChunks:           maxChunks,
+		IncludeLineage:      includeLineage,
+		Explain:             explain,
+		IncludeBenchmarks:   input.IncludeBenchmarks != nil && *input.IncludeBenchmarks,
+		AsOf:                asOf,
+		CampaignVersion:     input.CampaignVersion,
 	}
 
 	// Execute query
@@ -185,12 +338,111 @@ func (r *RetrievalResolver) Query(ctx context.Context, input RetrievalQueryInput
 	return r.toGraphQLResult(resp), nil
 }
 
+func (r *RetrievalResolver) toTraceResult(trace *retrieval.Trace) *TraceResult {
+	result := &TraceResult{
+		Question:          trace.Question,
+		Intent:            string(trace.Intent),
+		ClassifiedIntent:  string(trace.ClassifiedIntent),
+		IntentConfidence:  trace.IntentConfidence,
+		IntentHinted:      trace.IntentHinted,
+		Keywords:          trace.Keywords,
+		Expansions:        make([]*TraceExpansionResult, 0, len(trace.Expansions)),
+		Path:              string(trace.Path),
+		KeywordConfidence: trace.KeywordConfidence,
+		ConfidenceFactors: trace.ConfidenceFactors,
+		Cache:             trace.Cache,
+		Candidates:        make([]*TraceCandidateResult, 0, len(trace.Candidates)),
+		Filters:           make([]*TraceFilterResult, 0, len(trace.Filters)),
+		Stages:            make([]*TraceStageResult, 0, len(trace.Stages)),
+	}
+	for _, e := range trace.Expansions {
+		result.Expansions = append(result.Expansions, &TraceExpansionResult{Term: e.Term, Original: e.Original, Source: string(e.Source), Weight: e.Weight})
+	}
+	for _, c := range trace.Candidates {
+		result.Candidates = append(result.Candidates, &TraceCandidateResult{
+			Kind:       string(c.Kind),
+			ID:         c.ID.String(),
+			Label:      nilIfEmpty(c.Label),
+			Source:     c.Source,
+			Score:      c.Score,
+			Components: c.Components,
+		})
+	}
+	for _, f := range trace.Filters {
+		result.Filters = append(result.Filters, &TraceFilterResult{Stage: f.Stage, Subject: f.Subject, Kept: f.Kept, Reason: f.Reason})
+	}
+	for _, st := range trace.Stages {
+		result.Stages = append(result.Stages, &TraceStageResult{Stage: st.Stage, DurationMs: st.DurationMs})
+	}
+	return result
+}
+
 func (r *RetrievalResolver) toGraphQLResult(resp *retrieval.RetrievalResponse) *RetrievalResult {
 	result := &RetrievalResult{
 		Intent:          string(resp.Intent),
 		LatencyMs:       int(resp.LatencyMs),
 		StructuredFacts: make([]*SpecFactResult, 0, len(resp.StructuredFacts)),
 		SemanticChunks:  make([]*ChunkResult, 0, len(resp.SemanticChunks)),
+		Links:           make([]*EntityLinkResult, 0, len(resp.Links)),
+		Availability:    make([]*AvailabilityResult, 0, len(resp.Availability)),
+	}
+	if resp.Rewrite != nil && resp.Rewrite.Rewritten {
+		rewritten := resp.Rewrite.Question
+		result.RewrittenQuestion = &rewritten
+	}
+
+	for _, link := range resp.Links {
+		item := &EntityLinkResult{
+			Kind:       string(link.Kind),
+			ID:         link.ID.String(),
+			ProductID:  link.ProductID.String(),
+			Name:       link.Name,
+			Mention:    link.Mention,
+			Method:     string(link.Method),
+			Confidence: link.Confidence,
+			Applied:    link.Applied,
+		}
+		if link.ModelYear != nil {
+			year := int(*link.ModelYear)
+			item.ModelYear = &year
+		}
+		result.Links = append(result.Links, item)
+	}
+
+	for _, matrix := range resp.Availability {
+		item := &AvailabilityResult{
+			Category: matrix.Category,
+			Name:     matrix.Name,
+			Trims:    make([]*AvailabilityTrimResult, 0, len(matrix.Trims)),
+			Rows:     make([]*AvailabilityRowResult, 0, len(matrix.Rows)),
+		}
+		for _, trim := range matrix.Trims {
+			column := &AvailabilityTrimResult{
+				CampaignVariantID: trim.CampaignVariantID.String(),
+				ProductID:         trim.ProductID.String(),
+			}
+			if trim.ProductName != "" {
+				name := trim.ProductName
+				column.ProductName = &name
+			}
+			if trim.Trim != "" {
+				name := trim.Trim
+				column.Trim = &name
+			}
+			item.Trims = append(item.Trims, column)
+		}
+		for _, row := range matrix.Rows {
+			item.Rows = append(item.Rows, &AvailabilityRowResult{Value: row.Value, Available: row.Available})
+		}
+		result.Availability = append(result.Availability, item)
+	}
+
+	if resp.Trace != nil {
+		result.Trace = r.toTraceResult(resp.Trace)
+	}
+
+	if resp.Snapshot != nil {
+		result.Snapshot = toSnapshotResult(resp.Snapshot)
 	}
 
 	for _, fact := range resp.StructuredFacts {
@@ -203,6 +455,7 @@ func (r *RetrievalResolver) toGraphQLResult(resp *retrieval.RetrievalResponse) *
 			Confidence:        fact.Confidence,
 			CampaignVariantID: fact.CampaignVariantID.String(),
 			Source:            r.toSourceResult(fact.Source),
+			Benchmark:         toBenchmarkResult(fact.Benchmark),
 		})
 	}
 
@@ -214,6 +467,7 @@ func (r *RetrievalResolver) toGraphQLResult(resp *retrieval.RetrievalResponse) *
 			Distance:  float64(chunk.Distance),
 			Metadata:  chunk.Metadata,
 			Source:    r.toSourceResult(chunk.Source),
+			Benchmark: toBenchmarkResult(chunk.Benchmark),
 		})
 	}
 
@@ -226,6 +480,7 @@ func (r *RetrievalResolver) toGraphQLResult(resp *retrieval.RetrievalResponse) *
 			SecondaryValue:     nilIfEmpty(comp.SecondaryValue),
 			Verdict:            string(comp.Verdict),
 			Narrative:          nilIfEmpty(comp.Narrative),
+			Benchmark:          toBenchmarkResult(comp.Benchmark),
 		})
 	}
 
@@ -269,3 +524,33 @@ func nilIfEmpty(s string) *string {
 	return &s
 }
 
+// toSnapshotResult converts the campaign versions a point-in-time response used.
+func toSnapshotResult(snapshot *retrieval.Snapshot) *SnapshotResult {
+	result := &SnapshotResult{Versions: make([]*CampaignVersionResult, 0, len(snapshot.Versions))}
+	if snapshot.AsOf != nil {
+		result.AsOf = nilIfEmpty(snapshot.AsOf.Format(time.RFC3339))
+	}
+	for _, v := range snapshot.Versions {
+		item := &CampaignVersionResult{
+			CampaignID: v.CampaignVariantID.String(),
+			ProductID:  nilIfEmpty(v.ProductID.String()),
+			Version:    v.Version,
+			Status:     string(v.Status),
+		}
+		if v.EffectiveFrom != nil {
+			item.EffectiveFrom = nilIfEmpty(v.EffectiveFrom.Format(time.RFC3339))
+		}
+		if v.EffectiveThrough != nil {
+			item.EffectiveThrough = nilIfEmpty(v.EffectiveThrough.Format(time.RFC3339))
+		}
+		result.Versions = append(result.Versions, item)
+	}
+	return result
+}
+
+func toBenchmarkResult(ref *retrieval.BenchmarkRef) *BenchmarkResult {
+	if ref == nil {
+		return nil
+	}
+	return &BenchmarkResult{ProductID: ref.ProductID.String(), ProductName: ref.ProductName}
+}
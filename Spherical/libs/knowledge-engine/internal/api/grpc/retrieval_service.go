@@ -4,6 +4,8 @@ package grpc
 import (
 	"context"
 	"errors"
+	"fmt"
+	"time"
 
 	"connectrpc.com/connect"
 	"github.com/google/uuid"
@@ -40,6 +42,19 @@ type RetrievalRequest struct {
 	ChunkTypes        []string `json:"chunk_types,omitempty"`
 	MaxChunks         int32    `json:"max_chunks,omitempty"`
 	IncludeLineage    bool     `json:"include_lineage,omitempty"`
+	ConversationContext []*ConversationMessage `json:"conversation_context,omitempty"`
+	Explain           bool     `json:"explain,omitempty"`
+	IncludeBenchmarks bool     `json:"include_benchmarks,omitempty"`
+	// AsOf answers from the campaign versions live at this RFC 3339 time.
+	AsOf string `json:"as_of,omitempty"`
+	// CampaignVersion answers from this version of the campaign, taking precedence over AsOf.
+	CampaignVersion int32 `json:"campaign_version,omitempty"`
+}
+
+// ConversationMessage represents a conversation turn in gRPC.
+type ConversationMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
 }
 
 // RetrievalResponse represents the gRPC response message.
@@ -50,6 +65,112 @@ type RetrievalResponse struct {
 	SemanticChunks  []*Chunk      `json:"semantic_chunks"`
 	Comparisons     []*Comparison `json:"comparisons,omitempty"`
 	Lineage         []*Lineage    `json:"lineage,omitempty"`
+	RewrittenQuestion string      `json:"rewritten_question,omitempty"`
+	Links           []*EntityLink `json:"links,omitempty"`
+	Availability    []*AvailabilityMatrix `json:"availability,omitempty"`
+	Trace           *Trace        `json:"trace,omitempty"`
+	Snapshot        *Snapshot     `json:"snapshot,omitempty"`
+}
+
+// Snapshot represents the campaign versions a point-in-time response used in gRPC.
+type Snapshot struct {
+	AsOf     string             `json:"as_of,omitempty"`
+	Versions []*CampaignVersion `json:"versions"`
+}
+
+// CampaignVersion represents one version of a campaign variant in gRPC.
+type CampaignVersion struct {
+	CampaignID       string `json:"campaign_id"`
+	ProductID        string `json:"product_id,omitempty"`
+	Version          int32  `json:"version"`
+	Status           string `json:"status"`
+	EffectiveFrom    string `json:"effective_from,omitempty"`
+	EffectiveThrough string `json:"effective_through,omitempty"`
+}
+
+// Trace represents the explain trace of a retrieval query in gRPC.
+type Trace struct {
+	Question          string             `json:"question"`
+	Intent            string             `json:"intent"`
+	ClassifiedIntent  string             `json:"classified_intent"`
+	IntentConfidence  float64            `json:"intent_confidence"`
+	IntentHinted      bool               `json:"intent_hinted"`
+	Keywords          []string           `json:"keywords"`
+	Expansions        []*TraceExpansion  `json:"expansions"`
+	Path              string             `json:"path"`
+	KeywordConfidence float64            `json:"keyword_confidence"`
+	ConfidenceFactors map[string]float64 `json:"confidence_factors,omitempty"`
+	Cache             string             `json:"cache"`
+	Candidates        []*TraceCandidate  `json:"candidates"`
+	Filters           []*TraceFilter     `json:"filters"`
+	Stages            []*TraceStage      `json:"stages"`
+}
+
+// TraceExpansion represents a weighted query expansion term in gRPC.
+type TraceExpansion struct {
+	Term     string  `json:"term"`
+	Original string  `json:"original"`
+	Source   string  `json:"source"`
+	Weight   float64 `json:"weight"`
+}
+
+// TraceCandidate represents a scored retrieval candidate in gRPC.
+type TraceCandidate struct {
+	Kind       string             `json:"kind"`
+	ID         string             `json:"id"`
+	Label      string             `json:"label,omitempty"`
+	Source     string             `json:"source"`
+	Score      float64            `json:"score"`
+	Components map[string]float64 `json:"components,omitempty"`
+}
+
+// TraceFilter represents a filter decision in gRPC.
+type TraceFilter struct {
+	Stage   string `json:"stage"`
+	Subject string `json:"subject"`
+	Kept    bool   `json:"kept"`
+	Reason  string `json:"reason"`
+}
+
+// TraceStage represents a query stage timing in gRPC.
+type TraceStage struct {
+	Stage      string  `json:"stage"`
+	DurationMs float64 `json:"duration_ms"`
+}
+
+// EntityLink represents a question mention linked to a product or trim in gRPC.
+type EntityLink struct {
+	Kind       string  `json:"kind"`
+	ID         string  `json:"id"`
+	ProductID  string  `json:"product_id"`
+	Name       string  `json:"name"`
+	Mention    string  `json:"mention"`
+	ModelYear  int32   `json:"model_year,omitempty"`
+	Method     string  `json:"method"`
+	Confidence float64 `json:"confidence"`
+	Applied    bool    `json:"applied"`
+}
+
+// AvailabilityMatrix represents the values of one attribute offered per trim in gRPC.
+type AvailabilityMatrix struct {
+	Category string              `json:"category"`
+	Name     string              `json:"name"`
+	Trims    []*AvailabilityTrim `json:"trims"`
+	Rows     []*AvailabilityRow  `json:"rows"`
+}
+
+// AvailabilityTrim represents an availability matrix column in gRPC.
+type AvailabilityTrim struct {
+	CampaignVariantID string `json:"campaign_variant_id"`
+	ProductID         string `json:"product_id"`
+	ProductName       string `json:"product_name,omitempty"`
+	Trim              string `json:"trim,omitempty"`
+}
+
+// AvailabilityRow represents an availability matrix row, one cell per trim, in gRPC.
+type AvailabilityRow struct {
+	Value     string `json:"value"`
+	Available []bool `json:"available"`
 }
 
 // SpecFact represents a structured spec fact in gRPC.
@@ -62,6 +183,13 @@ type SpecFact struct {
 	Confidence        float64 `json:"confidence"`
 	CampaignVariantID string  `json:"campaign_variant_id"`
 	Source            *Source `json:"source,omitempty"`
+	Benchmark         *Benchmark `json:"benchmark,omitempty"`
+}
+
+// Benchmark labels a result sourced from another tenant's public benchmark product.
+type Benchmark struct {
+	ProductID   string `json:"product_id"`
+	ProductName string `json:"product_name"`
 }
 
 // Chunk represents a semantic chunk in gRPC.
@@ -72,6 +200,7 @@ type Chunk struct {
 	Distance  float32           `json:"distance"`
 	Metadata  map[string]string `json:"metadata,omitempty"`
 	Source    *Source           `json:"source,omitempty"`
+	Benchmark *Benchmark        `json:"benchmark,omitempty"`
 }
 
 // Comparison represents a comparison result in gRPC.
@@ -83,6 +212,7 @@ type Comparison struct {
 	SecondaryValue     string `json:"secondary_value,omitempty"`
 	Verdict            string `json:"verdict"`
 	Narrative          string `json:"narrative,omitempty"`
+	Benchmark          *Benchmark `json:"benchmark,omitempty"`
 }
 
 // Lineage represents lineage information in gRPC.
@@ -135,6 +265,12 @@ func (s *RetrievalService) Query(ctx context.Context, req *connect.Request[Retri
 		}
 	}
 
+	// Parse the point in time to answer from
+	asOf, version, err := parsePointInTime(msg.AsOf, msg.CampaignVersion)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+
 	// Parse intent hint
 	var intentHint *retrieval.Intent
 	if msg.IntentHint != "" {
@@ -155,23 +291,36 @@ func (s *RetrievalService) Query(ctx context.Context, req *connect.Request[Retri
 		maxChunks = 6
 	}
 
+	// Parse conversation context
+	var conversation []retrieval.ConversationMessage
+	for _, m := range msg.ConversationContext {
+		if m != nil {
+			conversation = append(conversation, retrieval.ConversationMessage{Role: m.Role, Content: m.Content})
+		}
+	}
+
 	// Build internal request
 	internalReq := retrieval.RetrievalRequest{
-		TenantID:          tenantID,
-		ProductIDs:        productIDs,
-		CampaignVariantID: campaignVariantID,
-		Question:          msg.Question,
-		IntentHint:        intentHint,
-		Filters:           filters,
-		MaxChunks:         maxChunks,
-		IncludeLineage:    msg.IncludeLineage,
+		TenantID:            tenantID,
+		ProductIDs:          productIDs,
+		CampaignVariantID:   campaignVariantID,
+		Question:            msg.Question,
+		IntentHint:          intentHint,
+		ConversationContext: conversation,
+		Filters:             filters,
+		MaxChunks:           maxChunks,
+		IncludeLineage:      msg.IncludeLineage,
+		Explain:             msg.Explain,
+		IncludeBenchmarks:   msg.IncludeBenchmarks,
+		AsOf:                asOf,
+		CampaignVersion:     version,
 	}
 
 	// Execute query
 	resp, err := s.router.Query(ctx, internalReq)
 	if err != nil {
 		s.logger.Error().Err(err).Msg("Query failed")
-		return nil, connect.NewError(connect.CodeInternal, err)
+		return nil, connect.NewError(queryErrorCode(err), err)
 	}
 
 	// Record audit event
@@ -195,6 +344,45 @@ func (s *RetrievalService) toGRPCResponse(resp *retrieval.RetrievalResponse) *Re
 		StructuredFacts: make([]*SpecFact, 0, len(resp.StructuredFacts)),
 		SemanticChunks:  make([]*Chunk, 0, len(resp.SemanticChunks)),
 	}
+	if resp.Rewrite != nil && resp.Rewrite.Rewritten {
+		grpcResp.RewrittenQuestion = resp.Rewrite.Question
+	}
+
+	for _, link := range resp.Links {
+		item := &EntityLink{
+			Kind:       string(link.Kind),
+			ID:         link.ID.String(),
+			ProductID:  link.ProductID.String(),
+			Name:       link.Name,
+			Mention:    link.Mention,
+			Method:     string(link.Method),
+			Confidence: link.Confidence,
+			Applied:    link.Applied,
+		}
+		if link.ModelYear != nil {
+			item.ModelYear = int32(*link.ModelYear)
+		}
+		grpcResp.Links = append(grpcResp.Links, item)
+	}
+
+	for _, matrix := range resp.Availability {
+		item := &AvailabilityMatrix{
+			Category: matrix.Category,
+			Name:     matrix.Name,
+		}
+		for _, trim := range matrix.Trims {
+			item.Trims = append(item.Trims, &AvailabilityTrim{
+				CampaignVariantID: trim.CampaignVariantID.String(),
+				ProductID:         trim.ProductID.String(),
+				ProductName:       trim.ProductName,
+				Trim:              trim.Trim,
+			})
+		}
+		for _, row := range matrix.Rows {
+			item.Rows = append(item.Rows, &AvailabilityRow{Value: row.Value, Available: row.Available})
+		}
+		grpcResp.Availability = append(grpcResp.Availability, item)
+	}
 
 	for _, fact := range resp.StructuredFacts {
 		grpcResp.StructuredFacts = append(grpcResp.StructuredFacts, &SpecFact{
@@ -206,6 +394,7 @@ func (s *RetrievalService) toGRPCResponse(resp *retrieval.RetrievalResponse) *Re
 			Confidence:        fact.Confidence,
 			CampaignVariantID: fact.CampaignVariantID.String(),
 			Source:            s.toGRPCSource(fact.Source),
+			Benchmark:         toGRPCBenchmark(fact.Benchmark),
 		})
 	}
 
@@ -223,9 +412,18 @@ func (s *RetrievalService) toGRPCResponse(resp *retrieval.RetrievalResponse) *Re
 			Distance:  chunk.Distance,
 			Metadata:  metadata,
 			Source:    s.toGRPCSource(chunk.Source),
+			Benchmark: toGRPCBenchmark(chunk.Benchmark),
 		})
 	}
 
+	if resp.Trace != nil {
+		grpcResp.Trace = s.toGRPCTrace(resp.Trace)
+	}
+
+	if resp.Snapshot != nil {
+		grpcResp.Snapshot = toGRPCSnapshot(resp.Snapshot.AsOf, resp.Snapshot.Versions)
+	}
+
 	for _, comp := range resp.Comparisons {
 		grpcResp.Comparisons = append(grpcResp.Comparisons, &Comparison{
 			Dimension:          comp.Dimension,
@@ -235,6 +433,7 @@ func (s *RetrievalService) toGRPCResponse(resp *retrieval.RetrievalResponse) *Re
 			SecondaryValue:     comp.SecondaryValue,
 			Verdict:            string(comp.Verdict),
 			Narrative:          comp.Narrative,
+			Benchmark:          toGRPCBenchmark(comp.Benchmark),
 		})
 	}
 
@@ -255,6 +454,45 @@ func (s *RetrievalService) toGRPCResponse(resp *retrieval.RetrievalResponse) *Re
 	return grpcResp
 }
 
+func (s *RetrievalService) toGRPCTrace(trace *retrieval.Trace) *Trace {
+	grpcTrace := &Trace{
+		Question:          trace.Question,
+		Intent:            string(trace.Intent),
+		ClassifiedIntent:  string(trace.ClassifiedIntent),
+		IntentConfidence:  trace.IntentConfidence,
+		IntentHinted:      trace.IntentHinted,
+		Keywords:          trace.Keywords,
+		Expansions:        make([]*TraceExpansion, 0, len(trace.Expansions)),
+		Path:              string(trace.Path),
+		KeywordConfidence: trace.KeywordConfidence,
+		ConfidenceFactors: trace.ConfidenceFactors,
+		Cache:             trace.Cache,
+		Candidates:        make([]*TraceCandidate, 0, len(trace.Candidates)),
+		Filters:           make([]*TraceFilter, 0, len(trace.Filters)),
+		Stages:            make([]*TraceStage, 0, len(trace.Stages)),
+	}
+	for _, e := range trace.Expansions {
+		grpcTrace.Expansions = append(grpcTrace.Expansions, &TraceExpansion{Term: e.Term, Original: e.Original, Source: string(e.Source), Weight: e.Weight})
+	}
+	for _, c := range trace.Candidates {
+		grpcTrace.Candidates = append(grpcTrace.Candidates, &TraceCandidate{
+			Kind:       string(c.Kind),
+			ID:         c.ID.String(),
+			Label:      c.Label,
+			Source:     c.Source,
+			Score:      c.Score,
+			Components: c.Components,
+		})
+	}
+	for _, f := range trace.Filters {
+		grpcTrace.Filters = append(grpcTrace.Filters, &TraceFilter{Stage: f.Stage, Subject: f.Subject, Kept: f.Kept, Reason: f.Reason})
+	}
+	for _, st := range trace.Stages {
+		grpcTrace.Stages = append(grpcTrace.Stages, &TraceStage{Stage: st.Stage, DurationMs: st.DurationMs})
+	}
+	return grpcTrace
+}
+
 func (s *RetrievalService) toGRPCSource(src retrieval.SourceRef) *Source {
 	grpcSrc := &Source{}
 	if src.DocumentSourceID != nil {
@@ -269,3 +507,66 @@ func (s *RetrievalService) toGRPCSource(src retrieval.SourceRef) *Source {
 	return grpcSrc
 }
 
+func toGRPCBenchmark(ref *retrieval.BenchmarkRef) *Benchmark {
+	if ref == nil {
+		return nil
+	}
+	return &Benchmark{ProductID: ref.ProductID.String(), ProductName: ref.ProductName}
+}
+
+// parsePointInTime parses an optional RFC 3339 time and campaign version, where
+// a zero version is unset.
+func parsePointInTime(asOf string, version int32) (*time.Time, *int, error) {
+	var at *time.Time
+	if asOf != "" {
+		t, err := time.Parse(time.RFC3339, asOf)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid as_of: %w", err)
+		}
+		at = &t
+	}
+	var v *int
+	if version > 0 {
+		n := int(version)
+		v = &n
+	}
+	return at, v, nil
+}
+
+// queryErrorCode maps a retrieval error to a Connect code.
+func queryErrorCode(err error) connect.Code {
+	switch {
+	case errors.Is(err, retrieval.ErrBenchmarksUnavailable):
+		return connect.CodeInvalidArgument
+	case errors.Is(err, storage.ErrNoCampaignVersion), errors.Is(err, storage.ErrNotFound):
+		return connect.CodeNotFound
+	case errors.Is(err, retrieval.ErrSnapshotUnavailable):
+		return connect.CodeUnimplemented
+	default:
+		return connect.CodeInternal
+	}
+}
+
+// toGRPCSnapshot converts the campaign versions a point-in-time response used.
+func toGRPCSnapshot(asOf *time.Time, versions []retrieval.CampaignVersion) *Snapshot {
+	snapshot := &Snapshot{Versions: make([]*CampaignVersion, 0, len(versions))}
+	if asOf != nil {
+		snapshot.AsOf = asOf.Format(time.RFC3339)
+	}
+	for _, v := range versions {
+		item := &CampaignVersion{
+			CampaignID: v.CampaignVariantID.String(),
+			ProductID:  v.ProductID.String(),
+			Version:    int32(v.Version),
+			Status:     string(v.Status),
+		}
+		if v.EffectiveFrom != nil {
+			item.EffectiveFrom = v.EffectiveFrom.Format(time.RFC3339)
+		}
+		if v.EffectiveThrough != nil {
+			item.EffectiveThrough = v.EffectiveThrough.Format(time.RFC3339)
+		}
+		snapshot.Versions = append(snapshot.Versions, item)
+	}
+	return snapshot
+}
@@ -0,0 +1,39 @@
+// Package grpc provides trace context propagation for Connect services.
+package grpc
+
+import (
+	"context"
+
+	"connectrpc.com/connect"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/observability"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracingInterceptor runs each unary call in a span. Handlers continue the
+// caller's trace from the W3C traceparent header; clients send theirs.
+func TracingInterceptor() connect.UnaryInterceptorFunc {
+	return func(next connect.UnaryFunc) connect.UnaryFunc {
+		return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+			procedure := req.Spec().Procedure
+			attrs := []attribute.KeyValue{
+				attribute.String("rpc.system", "connect_rpc"),
+				attribute.String("rpc.method", procedure),
+			}
+
+			var span trace.Span
+			if req.Spec().IsClient {
+				ctx, span = observability.StartClientSpan(ctx, procedure, req.Header(), attrs...)
+			} else {
+				ctx, span = observability.StartServerSpan(ctx, procedure, req.Header(), attrs...)
+			}
+
+			resp, err := next(ctx, req)
+			if err != nil {
+				span.SetAttributes(attribute.String("rpc.connect_rpc.error_code", connect.CodeOf(err).String()))
+			}
+			observability.EndSpan(span, err)
+			return resp, err
+		}
+	}
+}
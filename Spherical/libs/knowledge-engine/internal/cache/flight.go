@@ -0,0 +1,79 @@
+// Package cache provides in-flight request coalescing.
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// FlightGroup coalesces concurrent calls with the same key into one
+// computation whose result every caller shares. The zero value is ready to use.
+type FlightGroup[T any] struct {
+	mu     sync.Mutex
+	calls  map[string]*flight[T]
+	shared atomic.Int64
+}
+
+type flight[T any] struct {
+	done  chan struct{}
+	value T
+	err   error
+}
+
+// Do runs fn once for all concurrent callers with the same key and returns
+// its result, and whether it was shared with a call already in flight.
+//
+// fn runs with a context that is not cancelled with the caller's, so one
+// caller going away does not fail the others; each caller stops waiting
+// when its own context is done.
+func (g *FlightGroup[T]) Do(ctx context.Context, key string, fn func(ctx context.Context) (T, error)) (T, bool, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flight[T])
+	}
+	call, shared := g.calls[key]
+	if shared {
+		g.shared.Add(1)
+	} else {
+		call = &flight[T]{done: make(chan struct{})}
+		g.calls[key] = call
+		go g.run(context.WithoutCancel(ctx), key, call, fn)
+	}
+	g.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.value, shared, call.err
+	case <-ctx.Done():
+		var zero T
+		return zero, shared, ctx.Err()
+	}
+}
+
+func (g *FlightGroup[T]) run(ctx context.Context, key string, call *flight[T], fn func(ctx context.Context) (T, error)) {
+	defer func() {
+		// A panic would otherwise crash the process from this goroutine
+		if recovered := recover(); recovered != nil {
+			call.err = fmt.Errorf("coalesced call panicked: %v", recovered)
+		}
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		close(call.done)
+	}()
+	call.value, call.err = fn(ctx)
+}
+
+// Shared returns how many calls were served by a computation already in flight.
+func (g *FlightGroup[T]) Shared() int64 {
+	return g.shared.Load()
+}
+
+// Pending returns how many computations are in flight.
+func (g *FlightGroup[T]) Pending() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return len(g.calls)
+}
@@ -0,0 +1,129 @@
+// Package cache provides cross-instance cache invalidation messages.
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// InvalidationChannel is the Redis channel invalidation events are published on.
+const InvalidationChannel = "invalidation"
+
+// InvalidationKind identifies the data change behind an invalidation event.
+type InvalidationKind string
+
+// Invalidation kinds.
+const (
+	InvalidationCampaignPublished    InvalidationKind = "campaign_published"
+	InvalidationSpecUpdated          InvalidationKind = "spec_updated"
+	InvalidationComparisonRecomputed InvalidationKind = "comparison_recomputed"
+	InvalidationEmbeddingIndexSwitch InvalidationKind = "embedding_index_switch"
+)
+
+// InvalidationEvent describes a data change that makes cached results stale.
+// A nil ProductID or CampaignID means the change may affect any of the tenant's.
+type InvalidationEvent struct {
+	Kind       InvalidationKind `json:"kind"`
+	TenantID   uuid.UUID        `json:"tenant_id"`
+	ProductID  *uuid.UUID       `json:"product_id,omitempty"`
+	CampaignID *uuid.UUID       `json:"campaign_id,omitempty"`
+	// Origin identifies the instance that published the event, which has
+	// already evicted its own caches.
+	Origin string `json:"origin"`
+}
+
+// InvalidationBus delivers invalidation events to every subscribed instance.
+type InvalidationBus interface {
+	Publish(ctx context.Context, event InvalidationEvent) error
+	// Subscribe calls handler for each event until the returned unsubscribe
+	// function is called.
+	Subscribe(ctx context.Context, handler func(context.Context, InvalidationEvent)) (func(), error)
+}
+
+// RedisInvalidationBus sends invalidation events over Redis pub/sub, so every
+// API replica subscribed to the channel receives them.
+type RedisInvalidationBus struct {
+	client  *RedisClient
+	channel string
+}
+
+// NewRedisInvalidationBus creates an invalidation bus on the client's
+// InvalidationChannel.
+func NewRedisInvalidationBus(client *RedisClient) *RedisInvalidationBus {
+	return &RedisInvalidationBus{client: client, channel: InvalidationChannel}
+}
+
+// Publish sends an event to all subscribers.
+func (b *RedisInvalidationBus) Publish(ctx context.Context, event InvalidationEvent) error {
+	return b.client.Publish(ctx, b.channel, event)
+}
+
+// Subscribe delivers events from the channel to handler on a background
+// goroutine. Messages that do not decode as events are skipped.
+func (b *RedisInvalidationBus) Subscribe(ctx context.Context, handler func(context.Context, InvalidationEvent)) (func(), error) {
+	messages, unsubscribe, err := b.client.Subscribe(ctx, b.channel)
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		for data := range messages {
+			var event InvalidationEvent
+			if err := json.Unmarshal(data, &event); err != nil {
+				continue
+			}
+			handler(ctx, event)
+		}
+	}()
+
+	return unsubscribe, nil
+}
+
+// MemoryInvalidationBus delivers invalidation events synchronously within a
+// process, for single-node deployments and tests.
+type MemoryInvalidationBus struct {
+	mu       sync.RWMutex
+	handlers map[int]func(context.Context, InvalidationEvent)
+	next     int
+}
+
+// NewMemoryInvalidationBus creates a new in-memory invalidation bus.
+func NewMemoryInvalidationBus() *MemoryInvalidationBus {
+	return &MemoryInvalidationBus{
+		handlers: make(map[int]func(context.Context, InvalidationEvent)),
+	}
+}
+
+// Publish calls every subscribed handler before returning.
+func (b *MemoryInvalidationBus) Publish(ctx context.Context, event InvalidationEvent) error {
+	b.mu.RLock()
+	handlers := make([]func(context.Context, InvalidationEvent), 0, len(b.handlers))
+	for _, handler := range b.handlers {
+		handlers = append(handlers, handler)
+	}
+	b.mu.RUnlock()
+
+	for _, handler := range handlers {
+		handler(ctx, event)
+	}
+	return nil
+}
+
+// Subscribe registers handler until the returned function is called.
+func (b *MemoryInvalidationBus) Subscribe(ctx context.Context, handler func(context.Context, InvalidationEvent)) (func(), error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	id := b.next
+	b.next++
+	b.handlers[id] = handler
+
+	return func() {
+		b.mu.Lock()
+		delete(b.handlers, id)
+		b.mu.Unlock()
+	}, nil
+}
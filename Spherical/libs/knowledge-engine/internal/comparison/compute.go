@@ -0,0 +1,407 @@
+// Package comparison provides comparisons computed from products' spec values.
+package comparison
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/storage"
+)
+
+// SpecSource supplies products' current spec values. storage.SpecViewRepository
+// reads them from the spec view.
+type SpecSource interface {
+	Query(ctx context.Context, q storage.SpecViewQuery) (*storage.SpecViewResult, error)
+}
+
+// SetSpecSource enables computing comparisons that were not materialized, from
+// the products' current spec values. Computed rows are saved through the
+// ComparisonStore and cached like materialized ones.
+func (m *Materializer) SetSpecSource(source SpecSource) {
+	m.specs = source
+}
+
+// Direction says which values of a spec item are better.
+type Direction string
+
+const (
+	// DirectionNone leaves differing values without a verdict.
+	DirectionNone   Direction = ""
+	DirectionHigher Direction = "higher"
+	DirectionLower  Direction = "lower"
+)
+
+// specQueryLimit bounds the spec values read per product.
+const specQueryLimit = 1000
+
+// computeComparison builds comparison rows from the products' current spec
+// values. The secondary product's values are read from its owner's tenant when
+// it is a benchmark product.
+func (m *Materializer) computeComparison(ctx context.Context, req ComparisonRequest, benchmark *storage.ProductOwnership) ([]ComparisonRow, error) {
+	primary, err := m.specs.Query(ctx, storage.SpecViewQuery{
+		TenantID:   req.TenantID,
+		ProductIDs: []uuid.UUID{req.PrimaryProductID},
+		Limit:      specQueryLimit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("query primary specs: %w", err)
+	}
+
+	secondaryTenant := req.TenantID
+	shareability := storage.ComparisonTenantOnly
+	if benchmark != nil {
+		secondaryTenant = benchmark.TenantID
+		shareability = storage.ComparisonBenchmarkOnly
+	}
+	secondary, err := m.specs.Query(ctx, storage.SpecViewQuery{
+		TenantID:   secondaryTenant,
+		ProductIDs: []uuid.UUID{req.SecondaryProductID},
+		Limit:      specQueryLimit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("query secondary specs: %w", err)
+	}
+
+	return specRows(req, primary.Specs, secondary.Specs, shareability, m.config.Directions), nil
+}
+
+// specRows aligns the products' spec values on spec items, in the primary
+// product's order followed by items only the secondary product has. Values
+// are normalised to the base unit of their quantity and judged by the item's
+// direction; items missing on either side, with values of different
+// quantities, or without a direction cannot be compared.
+func specRows(req ComparisonRequest, primary, secondary []storage.SpecViewLatest, shareability storage.Shareability, directions map[string]Direction) []ComparisonRow {
+	primaryValues, order := specValues(primary, nil)
+	secondaryValues, order := specValues(secondary, order)
+
+	rows := make([]ComparisonRow, 0, len(order))
+	for _, itemID := range order {
+		p, hasPrimary := primaryValues[itemID]
+		s, hasSecondary := secondaryValues[itemID]
+
+		row := ComparisonRow{
+			ID:                 uuid.New(),
+			TenantID:           req.TenantID,
+			PrimaryProductID:   req.PrimaryProductID,
+			SecondaryProductID: req.SecondaryProductID,
+			Verdict:            storage.VerdictCannotCompare,
+			Shareability:       shareability,
+		}
+		if hasPrimary {
+			row.Dimension = p.SpecName
+			row.PrimaryValue = displayValue(p)
+			row.SourcePrimarySpecID = &p.ID
+		}
+		if hasSecondary {
+			row.Dimension = s.SpecName
+			row.SecondaryValue = displayValue(s)
+			row.SourceSecondarySpecID = &s.ID
+		}
+		if hasPrimary && hasSecondary {
+			row.Verdict = verdict(row.Dimension, p, s, directions)
+			row.Narrative = narrative(row, p.ProductName, s.ProductName)
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// verdict compares two values of a spec item.
+func verdict(name string, primary, secondary storage.SpecViewLatest, directions map[string]Direction) storage.Verdict {
+	p, pok := parseSpecValue(primary)
+	s, sok := parseSpecValue(secondary)
+	if !pok || !sok {
+		if strings.EqualFold(displayValue(primary), displayValue(secondary)) {
+			return storage.VerdictEqual
+		}
+		return storage.VerdictCannotCompare
+	}
+	if p.quantity != s.quantity {
+		return storage.VerdictCannotCompare
+	}
+	if math.Abs(p.value-s.value) <= 1e-3*math.Max(math.Abs(p.value), math.Abs(s.value)) {
+		return storage.VerdictEqual
+	}
+
+	primaryHigher := p.value > s.value
+	switch specDirection(name, p.quantity, directions) {
+	case DirectionHigher:
+		if primaryHigher {
+			return storage.VerdictPrimaryBetter
+		}
+		return storage.VerdictSecondaryBetter
+	case DirectionLower:
+		if primaryHigher {
+			return storage.VerdictSecondaryBetter
+		}
+		return storage.VerdictPrimaryBetter
+	default:
+		return storage.VerdictCannotCompare
+	}
+}
+
+// narrative describes which product leads on a row.
+func narrative(row ComparisonRow, primaryName, secondaryName string) string {
+	var leader string
+	switch row.Verdict {
+	case storage.VerdictPrimaryBetter:
+		leader = primaryName
+		if leader == "" {
+			leader = "The primary product"
+		}
+	case storage.VerdictSecondaryBetter:
+		leader = secondaryName
+		if leader == "" {
+			leader = "The secondary product"
+		}
+	default:
+		return ""
+	}
+	return fmt.Sprintf("%s leads on %s (%s vs %s)", leader, row.Dimension, row.PrimaryValue, row.SecondaryValue)
+}
+
+// quantityDirections are the directions of quantities better one way for
+// most specs. Fuel efficiency is normalised to km/l, so l/100km values follow
+// it. Prices in every currency are lower-is-better.
+var quantityDirections = map[string]Direction{
+	"power":           DirectionHigher,
+	"torque":          DirectionHigher,
+	"fuel_efficiency": DirectionHigher,
+	"speed":           DirectionHigher,
+	"mass":            DirectionLower,
+	"price":           DirectionLower,
+}
+
+// directionRule gives the direction of specs whose lower-case name contains
+// one of its terms.
+type directionRule struct {
+	terms     []string
+	direction Direction
+}
+
+// overridingDirectionRules name specs whose direction differs from their
+// quantity's, such as a towing capacity in kg, checked in order before it.
+var overridingDirectionRules = []directionRule{
+	{[]string{"weight-to-power", "weight to power"}, DirectionLower},
+	{[]string{"power-to-weight", "power to weight"}, DirectionHigher},
+	{[]string{"capacity", "payload", "towing"}, DirectionHigher},
+}
+
+// directionRules name specs whose direction the unit does not tell, checked in
+// order after the quantity's direction.
+var directionRules = []directionRule{
+	{[]string{"price", "cost", "msrp", "ex-showroom", "weight", "acceleration", "0-100", "0-60",
+		"emission", "co2", "consumption", "turning radius", "braking distance"}, DirectionLower},
+	{[]string{"power", "torque", "output", "mileage", "economy", "efficiency", "range", "top speed",
+		"boot", "trunk", "cargo", "luggage", "airbag", "warranty", "ground clearance", "seating"}, DirectionHigher},
+}
+
+// specDirection returns the direction of a spec item: a configured override
+// for its name, then the overriding name rules, its quantity's direction and
+// the other name rules.
+func specDirection(name, quantity string, overrides map[string]Direction) Direction {
+	lower := strings.ToLower(strings.TrimSpace(name))
+	if d, ok := overrides[lower]; ok {
+		return d
+	}
+	if d, ok := matchDirection(lower, overridingDirectionRules); ok {
+		return d
+	}
+	base, _, _ := strings.Cut(quantity, ":")
+	if d, ok := quantityDirections[base]; ok {
+		return d
+	}
+	if d, ok := matchDirection(lower, directionRules); ok {
+		return d
+	}
+	return DirectionNone
+}
+
+// matchDirection returns the direction of the first rule with a term in name.
+func matchDirection(name string, rules []directionRule) (Direction, bool) {
+	for _, rule := range rules {
+		for _, term := range rule.terms {
+			if strings.Contains(name, term) {
+				return rule.direction, true
+			}
+		}
+	}
+	return DirectionNone, false
+}
+
+// specUnit converts a unit to the base unit of its quantity. Inverse units
+// (l/100km) are converted as factor/value.
+type specUnit struct {
+	quantity string
+	factor   float64
+	inverse  bool
+}
+
+// specUnits lists unit spellings found in spec sheets, in lower case.
+var specUnits = map[string]specUnit{
+	"mm": {"length", 1, false}, "cm": {"length", 10, false}, "m": {"length", 1000, false},
+	"in": {"length", 25.4, false}, "inch": {"length", 25.4, false}, "inches": {"length", 25.4, false},
+	"ft": {"length", 304.8, false}, "feet": {"length", 304.8, false},
+
+	"l": {"volume", 1, false}, "ltr": {"volume", 1, false}, "litre": {"volume", 1, false},
+	"litres": {"volume", 1, false}, "liter": {"volume", 1, false}, "liters": {"volume", 1, false},
+	"ml": {"volume", 0.001, false}, "cc": {"volume", 0.001, false},
+	"cu ft": {"volume", 28.3168, false}, "cubic feet": {"volume", 28.3168, false},
+
+	"kg": {"mass", 1, false}, "kgs": {"mass", 1, false}, "lb": {"mass", 0.453592, false},
+	"lbs": {"mass", 0.453592, false}, "tonne": {"mass", 1000, false}, "tonnes": {"mass", 1000, false},
+
+	"hp": {"power", 1, false}, "bhp": {"power", 1, false}, "ps": {"power", 0.98632, false},
+	"kw": {"power", 1.34102, false},
+
+	"nm": {"torque", 1, false}, "lb-ft": {"torque", 1.35582, false}, "lb ft": {"torque", 1.35582, false},
+	"kgm": {"torque", 9.80665, false},
+
+	"km/l": {"fuel_efficiency", 1, false}, "kmpl": {"fuel_efficiency", 1, false},
+	"mpg":     {"fuel_efficiency", 0.425144, false},
+	"l/100km": {"fuel_efficiency", 100, true}, "l/100 km": {"fuel_efficiency", 100, true},
+
+	"km/h": {"speed", 1, false}, "kmph": {"speed", 1, false}, "mph": {"speed", 1.60934, false},
+
+	"s": {"time", 1, false}, "sec": {"time", 1, false}, "seconds": {"time", 1, false},
+
+	"kwh": {"energy", 1, false},
+
+	"inr": {"price:inr", 1, false}, "rs": {"price:inr", 1, false}, "rs.": {"price:inr", 1, false},
+	"₹": {"price:inr", 1, false}, "lakh": {"price:inr", 1e5, false}, "lakhs": {"price:inr", 1e5, false},
+	"crore": {"price:inr", 1e7, false}, "crores": {"price:inr", 1e7, false},
+	"usd": {"price:usd", 1, false}, "$": {"price:usd", 1, false},
+	"eur": {"price:eur", 1, false}, "€": {"price:eur", 1, false},
+}
+
+// specUnitAliases lists unit spellings longest first so "km/l" wins over "km".
+var specUnitAliases = func() []string {
+	aliases := make([]string, 0, len(specUnits))
+	for alias := range specUnits {
+		aliases = append(aliases, alias)
+	}
+	sort.Slice(aliases, func(i, j int) bool {
+		if len(aliases[i]) != len(aliases[j]) {
+			return len(aliases[i]) > len(aliases[j])
+		}
+		return aliases[i] < aliases[j]
+	})
+	return aliases
+}()
+
+var (
+	specNumberRe = regexp.MustCompile(`(?i)^(₹|rs\.?|inr|\$|usd|€|eur)?\s*(-?\d[\d,]*(?:\.\d+)?)\s*(.*)$`)
+	specRangeRe  = regexp.MustCompile(`(?i)^(?:-|–|to)\s*\d`)
+)
+
+// specValue is a spec value in the base unit of its quantity. Units outside
+// specUnits form their own quantity, so "6 airbags" only compares with airbags,
+// and each currency is a quantity of its own, since amounts are not converted.
+type specValue struct {
+	value    float64
+	quantity string
+}
+
+// parseSpecValue reads the number of a spec value and normalises its unit,
+// taken from the spec's unit or the text after the number. Ranges and values
+// without a number cannot be compared.
+func parseSpecValue(sv storage.SpecViewLatest) (specValue, bool) {
+	m := specNumberRe.FindStringSubmatch(strings.TrimSpace(sv.Value))
+	if m == nil || specRangeRe.MatchString(m[3]) {
+		return specValue{}, false
+	}
+	value, err := strconv.ParseFloat(strings.ReplaceAll(m[2], ",", ""), 64)
+	if err != nil {
+		return specValue{}, false
+	}
+	if sv.ValueNumeric != nil {
+		value = *sv.ValueNumeric
+	}
+
+	unit := ""
+	if sv.Unit != nil {
+		unit = strings.ToLower(strings.TrimSpace(*sv.Unit))
+	}
+	if unit == "" {
+		unit = leadingUnit(strings.ToLower(m[3]))
+	}
+	currency := ""
+	if m[1] != "" {
+		currency = strings.TrimSuffix(strings.ToLower(m[1]), ".")
+	}
+	if unit == "" {
+		unit = currency
+	}
+
+	if unit == "" {
+		return specValue{value: value}, true
+	}
+	def, ok := specUnits[unit]
+	if !ok {
+		return specValue{value: value, quantity: "unit:" + unit}, true
+	}
+	switch {
+	case def.inverse && value == 0:
+		return specValue{}, false
+	case def.inverse:
+		value = def.factor / value
+	default:
+		value *= def.factor
+	}
+	// A currency symbol sets the currency of amounts such as "$1.2 lakh"
+	if currency != "" && strings.HasPrefix(def.quantity, "price:") {
+		return specValue{value: value, quantity: specUnits[currency].quantity}, true
+	}
+	return specValue{value: value, quantity: def.quantity}, true
+}
+
+// leadingUnit returns the unit at the start of text: a known unit, or else
+// its first word.
+func leadingUnit(text string) string {
+	text = strings.TrimSpace(text)
+	for _, alias := range specUnitAliases {
+		if !strings.HasPrefix(text, alias) {
+			continue
+		}
+		// Compound units such as hp/tonne are not the unit they start with
+		if rest := text[len(alias):]; rest != "" && (isUnitLetter(rest[0]) || rest[0] == '/') {
+			continue
+		}
+		return alias
+	}
+	if fields := strings.Fields(text); len(fields) > 0 {
+		return fields[0]
+	}
+	return ""
+}
+
+func isUnitLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9')
+}
+
+// orient swaps the sides of rows computed for the reverse pair, since pairs
+// share their cached rows whichever product is primary.
+func orient(rows []ComparisonRow, req ComparisonRequest) []ComparisonRow {
+	oriented := make([]ComparisonRow, len(rows))
+	for i, row := range rows {
+		if row.PrimaryProductID == req.SecondaryProductID && row.SecondaryProductID == req.PrimaryProductID && req.PrimaryProductID != req.SecondaryProductID {
+			row.PrimaryProductID, row.SecondaryProductID = row.SecondaryProductID, row.PrimaryProductID
+			row.PrimaryValue, row.SecondaryValue = row.SecondaryValue, row.PrimaryValue
+			row.SourcePrimarySpecID, row.SourceSecondarySpecID = row.SourceSecondarySpecID, row.SourcePrimarySpecID
+			switch row.Verdict {
+			case storage.VerdictPrimaryBetter:
+				row.Verdict = storage.VerdictSecondaryBetter
+			case storage.VerdictSecondaryBetter:
+				row.Verdict = storage.VerdictPrimaryBetter
+			}
+		}
+		oriented[i] = row
+	}
+	return oriented
+}
@@ -0,0 +1,215 @@
+package comparison
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/cache"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/observability"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubSpecs serves spec values per product and counts the queries it was given.
+type stubSpecs struct {
+	mu      sync.Mutex
+	specs   map[uuid.UUID][]storage.SpecViewLatest
+	queries int
+}
+
+func (s *stubSpecs) Query(ctx context.Context, q storage.SpecViewQuery) (*storage.SpecViewResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queries++
+	var specs []storage.SpecViewLatest
+	for _, sv := range s.specs[q.ProductIDs[0]] {
+		if sv.TenantID == q.TenantID {
+			specs = append(specs, sv)
+		}
+	}
+	return &storage.SpecViewResult{Specs: specs, TotalCount: len(specs)}, nil
+}
+
+// memStore keeps the rows saved through it.
+type memStore struct {
+	mu   sync.Mutex
+	rows []storage.ComparisonRow
+}
+
+func (s *memStore) GetComparison(ctx context.Context, tenantID, primaryID, secondaryID uuid.UUID) ([]storage.ComparisonRow, error) {
+	return nil, nil
+}
+
+func (s *memStore) SaveComparison(ctx context.Context, rows []storage.ComparisonRow) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rows = append(s.rows, rows...)
+	return nil
+}
+
+func (s *memStore) DeleteComparisons(ctx context.Context, tenantID uuid.UUID, productID *uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kept := s.rows[:0]
+	for _, row := range s.rows {
+		if productID != nil && row.PrimaryProductID != *productID && row.SecondaryProductID != *productID {
+			kept = append(kept, row)
+		}
+	}
+	s.rows = kept
+	return nil
+}
+
+func strPtr(s string) *string { return &s }
+
+func TestSpecRows_Verdicts(t *testing.T) {
+	tenant, camry, accord := uuid.New(), uuid.New(), uuid.New()
+	req := ComparisonRequest{TenantID: tenant, PrimaryProductID: camry, SecondaryProductID: accord}
+
+	tests := []struct {
+		name       string
+		spec       string
+		primary    string
+		pUnit      *string
+		secondary  string
+		sUnit      *string
+		directions map[string]Direction
+		want       storage.Verdict
+	}{
+		{"higher power across units", "Max Power", "178", strPtr("hp"), "120", strPtr("kW"), nil, storage.VerdictPrimaryBetter},
+		{"lower price across units", "Ex-showroom Price", "18.5 lakh", nil, "₹ 19,50,000", nil, nil, storage.VerdictPrimaryBetter},
+		{"lower weight across units", "Kerb Weight", "1,550 kg", nil, "3,300 lbs", nil, nil, storage.VerdictSecondaryBetter},
+		{"inverse efficiency units", "Fuel Economy", "20 km/l", nil, "5", strPtr("L/100km"), nil, storage.VerdictEqual},
+		{"higher efficiency", "Mileage", "51 mpg", nil, "19.5", strPtr("km/l"), nil, storage.VerdictPrimaryBetter},
+		{"quicker acceleration", "0-100 km/h", "8.2 s", nil, "7.6 s", nil, nil, storage.VerdictSecondaryBetter},
+		{"more airbags", "Airbags", "6", nil, "8", nil, nil, storage.VerdictSecondaryBetter},
+		{"incomparable quantities", "Torque", "221", strPtr("Nm"), "192", strPtr("hp"), nil, storage.VerdictCannotCompare},
+		{"incomparable units", "Boot Space", "428 L", nil, "15 suitcases", nil, nil, storage.VerdictCannotCompare},
+		{"no direction", "Length", "4,885 mm", nil, "4,971 mm", nil, nil, storage.VerdictCannotCompare},
+		{"configured direction", "Length", "4,885 mm", nil, "4.971 m", nil, map[string]Direction{"length": DirectionLower}, storage.VerdictPrimaryBetter},
+		{"ranges", "Kerb Weight", "1,500-1,600 kg", nil, "1,550 kg", nil, nil, storage.VerdictCannotCompare},
+		{"same currency across units", "Price", "Rs. 12,00,000", nil, "12 lakh", nil, nil, storage.VerdictEqual},
+		{"different currencies", "Price", "$28,400", nil, "€26,000", nil, nil, storage.VerdictCannotCompare},
+		{"currency symbol against code", "Price", "₹ 25,00,000", nil, "30000", strPtr("USD"), nil, storage.VerdictCannotCompare},
+		{"higher towing capacity in kg", "Towing Capacity", "3,500 kg", nil, "2,000 kg", nil, nil, storage.VerdictPrimaryBetter},
+		{"higher payload in lbs", "Payload", "1,500 lbs", nil, "800 kg", nil, nil, storage.VerdictSecondaryBetter},
+		{"higher power-to-weight ratio", "Power-to-weight ratio", "150 hp/tonne", nil, "120 hp/tonne", nil, nil, storage.VerdictPrimaryBetter},
+		{"lower weight-to-power ratio", "Weight to power ratio", "6.5 kg/hp", nil, "8 kg/hp", nil, nil, storage.VerdictPrimaryBetter},
+		{"equal text", "Headlamps", "LED", nil, "led", nil, nil, storage.VerdictEqual},
+		{"differing text", "Headlamps", "LED", nil, "Halogen", nil, nil, storage.VerdictCannotCompare},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item := uuid.New()
+			primary := []storage.SpecViewLatest{{ID: uuid.New(), SpecItemID: item, SpecName: tt.spec, Value: tt.primary, Unit: tt.pUnit, ProductName: "Camry"}}
+			secondary := []storage.SpecViewLatest{{ID: uuid.New(), SpecItemID: item, SpecName: tt.spec, Value: tt.secondary, Unit: tt.sUnit, ProductName: "Accord"}}
+			rows := specRows(req, primary, secondary, storage.ComparisonTenantOnly, tt.directions)
+			require.Len(t, rows, 1)
+			assert.Equal(t, tt.want, rows[0].Verdict)
+			assert.Equal(t, &primary[0].ID, rows[0].SourcePrimarySpecID)
+			assert.Equal(t, &secondary[0].ID, rows[0].SourceSecondarySpecID)
+		})
+	}
+
+	t.Run("missing values", func(t *testing.T) {
+		primary := []storage.SpecViewLatest{{ID: uuid.New(), SpecItemID: uuid.New(), SpecName: "Max Power", Value: "178", Unit: strPtr("hp")}}
+		secondary := []storage.SpecViewLatest{{ID: uuid.New(), SpecItemID: uuid.New(), SpecName: "Sunroof", Value: "Yes"}}
+		rows := specRows(req, primary, secondary, storage.ComparisonTenantOnly, nil)
+		require.Len(t, rows, 2)
+		for _, row := range rows {
+			assert.Equal(t, storage.VerdictCannotCompare, row.Verdict)
+			assert.Empty(t, row.Narrative)
+		}
+		assert.Nil(t, rows[0].SourceSecondarySpecID)
+		assert.Empty(t, rows[1].PrimaryValue)
+	})
+}
+
+func TestMaterializer_ComputesComparison(t *testing.T) {
+	logger := observability.NewLogger(observability.LogConfig{Level: "error"})
+	tenant, rival := uuid.New(), uuid.New()
+	camry, corolla, accord := uuid.New(), uuid.New(), uuid.New()
+	power, price, seats := uuid.New(), uuid.New(), uuid.New()
+
+	spec := func(tenantID, product uuid.UUID, name string, item uuid.UUID, value string, unit *string) storage.SpecViewLatest {
+		return storage.SpecViewLatest{ID: uuid.New(), TenantID: tenantID, ProductID: product, ProductName: name, SpecItemID: item, SpecName: map[uuid.UUID]string{power: "Power", price: "Price", seats: "Seats"}[item], Value: value, Unit: unit, Confidence: 0.9}
+	}
+	specs := &stubSpecs{specs: map[uuid.UUID][]storage.SpecViewLatest{
+		camry:   {spec(tenant, camry, "Camry", power, "225", strPtr("hp")), spec(tenant, camry, "Camry", price, "$28,400", nil), spec(tenant, camry, "Camry", seats, "5", nil)},
+		corolla: {spec(tenant, corolla, "Corolla", power, "169", strPtr("hp")), spec(tenant, corolla, "Corolla", price, "$22,050", nil), spec(tenant, corolla, "Corolla", seats, "5", nil)},
+		accord:  {spec(rival, accord, "Accord", power, "192", strPtr("hp"))},
+	}}
+	ctx := context.Background()
+
+	store := &memStore{}
+	m := NewMaterializer(logger, nil, store, Config{AllowCrossTenant: true})
+	resp, err := m.Compare(ctx, ComparisonRequest{TenantID: tenant, PrimaryProductID: camry, SecondaryProductID: corolla})
+	require.NoError(t, err)
+	assert.Empty(t, resp.Comparisons, "nothing is computed without a spec source")
+
+	m.SetSpecSource(specs)
+	resp, err = m.Compare(ctx, ComparisonRequest{TenantID: tenant, PrimaryProductID: camry, SecondaryProductID: corolla})
+	require.NoError(t, err)
+	require.Len(t, resp.Comparisons, 3)
+	assert.NotEmpty(t, resp.Hash)
+	verdicts := make(map[string]storage.Verdict)
+	for _, row := range resp.Comparisons {
+		verdicts[row.Dimension] = row.Verdict
+	}
+	assert.Equal(t, map[string]storage.Verdict{
+		"Power": storage.VerdictPrimaryBetter,
+		"Price": storage.VerdictSecondaryBetter,
+		"Seats": storage.VerdictEqual,
+	}, verdicts)
+	assert.Equal(t, "Camry leads on Power (225 hp vs 169 hp)", resp.Comparisons[0].Narrative)
+
+	require.Len(t, store.rows, 3, "computed rows are saved")
+	assert.Equal(t, camry, store.rows[0].PrimaryProductID)
+	assert.NotNil(t, store.rows[0].SourcePrimarySpecID)
+	assert.Equal(t, storage.ComparisonTenantOnly, store.rows[0].Shareability)
+
+	t.Run("cached for either order", func(t *testing.T) {
+		queries := specs.queries
+		resp, err := m.Compare(ctx, ComparisonRequest{TenantID: tenant, PrimaryProductID: corolla, SecondaryProductID: camry, Dimensions: []string{"Power"}})
+		require.NoError(t, err)
+		assert.Equal(t, queries, specs.queries)
+		require.Len(t, resp.Comparisons, 1)
+		row := resp.Comparisons[0]
+		assert.Equal(t, corolla, row.PrimaryProductID)
+		assert.Equal(t, "169 hp", row.PrimaryValue)
+		assert.Equal(t, storage.VerdictSecondaryBetter, row.Verdict)
+	})
+
+	t.Run("stored rows are recomputed after a spec update", func(t *testing.T) {
+		require.NoError(t, m.ApplyInvalidation(ctx, cache.InvalidationEvent{Kind: cache.InvalidationComparisonRecomputed, TenantID: tenant}))
+		assert.Len(t, store.rows, 3, "recomputed rows are kept")
+
+		require.NoError(t, m.ApplyInvalidation(ctx, cache.InvalidationEvent{Kind: cache.InvalidationSpecUpdated, TenantID: tenant, ProductID: &corolla}))
+		assert.Empty(t, store.rows)
+		queries := specs.queries
+		resp, err := m.Compare(ctx, ComparisonRequest{TenantID: tenant, PrimaryProductID: camry, SecondaryProductID: corolla})
+		require.NoError(t, err)
+		assert.Len(t, resp.Comparisons, 3)
+		assert.Greater(t, specs.queries, queries)
+		assert.Len(t, store.rows, 3)
+	})
+
+	t.Run("benchmark products are read from their tenant", func(t *testing.T) {
+		m := NewMaterializer(logger, nil, nil, Config{AllowCrossTenant: true})
+		m.SetSpecSource(specs)
+		m.SetProductAccess(stubAccess{
+			camry:  {TenantID: tenant, Name: "Camry"},
+			accord: {TenantID: rival, Name: "Accord", Benchmark: true},
+		})
+		resp, err := m.Compare(ctx, ComparisonRequest{TenantID: tenant, PrimaryProductID: camry, SecondaryProductID: accord, IncludeBenchmarks: true})
+		require.NoError(t, err)
+		require.Len(t, resp.Comparisons, 3)
+		row := resp.Comparisons[0]
+		assert.Equal(t, "Power", row.Dimension)
+		assert.Equal(t, storage.VerdictPrimaryBetter, row.Verdict)
+		assert.Equal(t, "Accord", row.BenchmarkProductName)
+	})
+}
@@ -7,10 +7,13 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/cache"
 	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/observability"
 	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/storage"
 )
@@ -23,24 +26,40 @@ var (
 
 // Materializer handles pre-computed comparisons between products.
 type Materializer struct {
-	logger *observability.Logger
-	cache  ComparisonCache
-	store  ComparisonStore
+	logger    *observability.Logger
+	cache     ComparisonCache
+	store     ComparisonStore
+	access    ProductAccess
+	snapshots SnapshotSource
+	specs     SpecSource
+	metrics   *observability.Metrics
+	config    Config
 
 	mu          sync.RWMutex
 	comparisons map[string]*CachedComparison // key: pairKey
+	inflight    cache.FlightGroup[*ComparisonResponse]
 }
 
 // ComparisonCache provides caching for comparison results.
 type ComparisonCache interface {
 	Get(ctx context.Context, key string) ([]ComparisonRow, bool)
 	Set(ctx context.Context, key string, value []ComparisonRow, ttl time.Duration)
+	Delete(ctx context.Context, key string)
 }
 
-// ComparisonStore persists comparison data.
+// ComparisonStore persists comparison data. storage.ComparisonRepository
+// keeps it in the comparison_rows table.
 type ComparisonStore interface {
 	GetComparison(ctx context.Context, tenantID, primaryID, secondaryID uuid.UUID) ([]storage.ComparisonRow, error)
 	SaveComparison(ctx context.Context, rows []storage.ComparisonRow) error
+	// DeleteComparisons removes the rows of a product's pairs, or of all the
+	// tenant's products when productID is nil.
+	DeleteComparisons(ctx context.Context, tenantID uuid.UUID, productID *uuid.UUID) error
+}
+
+// ProductAccess resolves product ownership for cross-tenant access checks.
+type ProductAccess interface {
+	ProductOwnership(ctx context.Context, productID uuid.UUID) (*storage.ProductOwnership, error)
 }
 
 // CachedComparison holds cached comparison data.
@@ -52,10 +71,15 @@ type CachedComparison struct {
 
 // Config for the materializer.
 type Config struct {
+	// CacheTTL is how long comparisons stay cached (default 1h); the API sets
+	// it to the retrieval cache TTL so both caches expire together.
 	CacheTTL         time.Duration
 	RefreshInterval  time.Duration
 	PolicyFile       string
 	AllowCrossTenant bool
+	// Directions override which values are better for computed comparisons,
+	// keyed by lower-case spec name.
+	Directions map[string]Direction
 }
 
 // NewMaterializer creates a new comparison materializer.
@@ -68,10 +92,29 @@ func NewMaterializer(logger *observability.Logger, cache ComparisonCache, store
 		logger:      logger,
 		cache:       cache,
 		store:       store,
+		config:      cfg,
 		comparisons: make(map[string]*CachedComparison),
 	}
 }
 
+// SetProductAccess enables ownership checks on compared products. Without it
+// products are not checked and comparisons stay within the requested tenant.
+func (m *Materializer) SetProductAccess(access ProductAccess) {
+	m.access = access
+}
+
+// SetMetrics counts comparison cache lookups in metrics and reports the
+// number of in-memory comparisons as a gauge.
+func (m *Materializer) SetMetrics(metrics *observability.Metrics) {
+	m.metrics = metrics
+	metrics.GaugeFunc("knowledge_engine_comparison_cache_entries",
+		"Product pair comparisons held in the in-memory cache.", func() float64 {
+			m.mu.RLock()
+			defer m.mu.RUnlock()
+			return float64(len(m.comparisons))
+		})
+}
+
 // ComparisonRequest represents a request to compare products.
 type ComparisonRequest struct {
 	TenantID           uuid.UUID
@@ -79,6 +122,14 @@ type ComparisonRequest struct {
 	SecondaryProductID uuid.UUID
 	Dimensions         []string
 	MaxRows            int
+	// IncludeBenchmarks allows the secondary product to be another tenant's
+	// public benchmark product when the materializer allows cross-tenant comparisons.
+	IncludeBenchmarks bool
+	// AsOf compares the products as they stood at this moment, enabled with SetSnapshotSource.
+	AsOf *time.Time
+	// CampaignVersion compares this version of the primary product's campaigns,
+	// taking precedence over AsOf.
+	CampaignVersion *int
 }
 
 // ComparisonResponse contains comparison results.
@@ -86,6 +137,8 @@ type ComparisonResponse struct {
 	Comparisons []ComparisonRow
 	ComputedAt  time.Time
 	Hash        string
+	// Versions are the campaign versions compared, set for AsOf and CampaignVersion requests.
+	Versions []*storage.CampaignVariant
 }
 
 // ComparisonRow represents a single comparison dimension.
@@ -100,9 +153,17 @@ type ComparisonRow struct {
 	Verdict            storage.Verdict
 	Narrative          string
 	Shareability       storage.Shareability
+	// BenchmarkProductName is set on rows against another tenant's public
+	// benchmark product, naming that product.
+	BenchmarkProductName string
+	// SourcePrimarySpecID and SourceSecondarySpecID are the spec values a
+	// computed row was built from.
+	SourcePrimarySpecID   *uuid.UUID
+	SourceSecondarySpecID *uuid.UUID
 }
 
 // Compare retrieves or computes a comparison between two products.
+// Concurrent identical requests share one computation.
 func (m *Materializer) Compare(ctx context.Context, req ComparisonRequest) (*ComparisonResponse, error) {
 	m.logger.Info().
 		Str("tenant_id", req.TenantID.String()).
@@ -110,6 +171,103 @@ func (m *Materializer) Compare(ctx context.Context, req ComparisonRequest) (*Com
 		Str("secondary_product", req.SecondaryProductID.String()).
 		Msg("Processing comparison request")
 
+	resp, _, err := m.inflight.Do(ctx, requestKey(req), func(ctx context.Context) (*ComparisonResponse, error) {
+		return m.checkedCompare(ctx, req)
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Callers may modify their response
+	result := *resp
+	result.Comparisons = append([]ComparisonRow(nil), resp.Comparisons...)
+	return &result, nil
+}
+
+// requestKey identifies comparison requests that get the same response.
+func requestKey(req ComparisonRequest) string {
+	dimensions := append([]string(nil), req.Dimensions...)
+	sort.Strings(dimensions)
+	key := fmt.Sprintf("%s:%s:%s:%s:%d:%t", req.TenantID, req.PrimaryProductID, req.SecondaryProductID,
+		strings.Join(dimensions, ","), req.MaxRows, req.IncludeBenchmarks)
+	if req.AsOf != nil {
+		key += ":as_of:" + req.AsOf.UTC().Format(time.RFC3339Nano)
+	}
+	if req.CampaignVersion != nil {
+		key += fmt.Sprintf(":version:%d", *req.CampaignVersion)
+	}
+	return key
+}
+
+// checkedCompare checks access to the products and serves the comparison.
+func (m *Materializer) checkedCompare(ctx context.Context, req ComparisonRequest) (*ComparisonResponse, error) {
+	benchmark, err := m.checkAccess(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	var resp *ComparisonResponse
+	if req.pointInTime() {
+		resp, err = m.compareSnapshot(ctx, req, benchmark)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		resp = m.compare(ctx, req, benchmark)
+	}
+	if benchmark != nil {
+		resp.Comparisons = benchmarkRows(resp.Comparisons, benchmark.Name)
+	}
+	return resp, nil
+}
+
+// checkAccess verifies the caller owns the primary product and may compare
+// against the secondary product. It returns the secondary product's ownership
+// when it is another tenant's benchmark product.
+func (m *Materializer) checkAccess(ctx context.Context, req ComparisonRequest) (*storage.ProductOwnership, error) {
+	if m.access == nil {
+		return nil, nil
+	}
+
+	primary, err := m.access.ProductOwnership(ctx, req.PrimaryProductID)
+	if errors.Is(err, storage.ErrNotFound) || (err == nil && primary.TenantID != req.TenantID) {
+		return nil, ErrProductNotAccessible
+	}
+	if err != nil {
+		return nil, fmt.Errorf("check primary product: %w", err)
+	}
+
+	secondary, err := m.access.ProductOwnership(ctx, req.SecondaryProductID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, ErrProductNotAccessible
+	}
+	if err != nil {
+		return nil, fmt.Errorf("check secondary product: %w", err)
+	}
+	if secondary.TenantID == req.TenantID {
+		return nil, nil
+	}
+	if !secondary.Benchmark || !m.config.AllowCrossTenant || !req.IncludeBenchmarks {
+		return nil, ErrProductNotAccessible
+	}
+	return secondary, nil
+}
+
+// benchmarkRows keeps the rows shared beyond their tenant and labels them with
+// the benchmark product name.
+func benchmarkRows(rows []ComparisonRow, productName string) []ComparisonRow {
+	shared := make([]ComparisonRow, 0, len(rows))
+	for _, row := range rows {
+		if row.Shareability != storage.ComparisonBenchmarkOnly && row.Shareability != storage.ShareabilityPublic {
+			continue
+		}
+		row.BenchmarkProductName = productName
+		shared = append(shared, row)
+	}
+	return shared
+}
+
+// compare serves a comparison from the caches or the store, or computes it
+// from spec values when a spec source is set.
+func (m *Materializer) compare(ctx context.Context, req ComparisonRequest, benchmark *storage.ProductOwnership) *ComparisonResponse {
 	// Generate cache key
 	cacheKey := m.pairKey(req.TenantID, req.PrimaryProductID, req.SecondaryProductID)
 
@@ -118,33 +276,37 @@ func (m *Materializer) Compare(ctx context.Context, req ComparisonRequest) (*Com
 	cached, ok := m.comparisons[cacheKey]
 	m.mu.RUnlock()
 
-	if ok && time.Since(cached.CreatedAt) < time.Hour {
-		rows := m.filterRows(cached.Rows, req.Dimensions, req.MaxRows)
+	if ok && time.Since(cached.CreatedAt) < m.config.CacheTTL {
+		m.metrics.CacheLookup("comparison", true)
+		rows := m.filterRows(orient(cached.Rows, req), req.Dimensions, req.MaxRows)
 		return &ComparisonResponse{
 			Comparisons: rows,
 			ComputedAt:  cached.CreatedAt,
 			Hash:        cached.Hash,
-		}, nil
+		}
 	}
 
 	// Check external cache
 	if m.cache != nil {
 		if rows, found := m.cache.Get(ctx, cacheKey); found {
-			filtered := m.filterRows(rows, req.Dimensions, req.MaxRows)
+			m.metrics.CacheLookup("comparison", true)
+			filtered := m.filterRows(orient(rows, req), req.Dimensions, req.MaxRows)
 			return &ComparisonResponse{
 				Comparisons: filtered,
 				ComputedAt:  time.Now(),
 				Hash:        m.computeHash(rows),
-			}, nil
+			}
 		}
 	}
 
+	m.metrics.CacheLookup("comparison", false)
+
 	// Load from store
 	if m.store != nil {
 		stored, err := m.store.GetComparison(ctx, req.TenantID, req.PrimaryProductID, req.SecondaryProductID)
 		if err == nil && len(stored) > 0 {
 			rows := m.convertFromStorage(stored)
-			filtered := m.filterRows(rows, req.Dimensions, req.MaxRows)
+			filtered := m.filterRows(orient(rows, req), req.Dimensions, req.MaxRows)
 
 			// Update cache
 			m.cacheResult(ctx, cacheKey, rows)
@@ -153,7 +315,24 @@ func (m *Materializer) Compare(ctx context.Context, req ComparisonRequest) (*Com
 				Comparisons: filtered,
 				ComputedAt:  time.Now(),
 				Hash:        m.computeHash(rows),
-			}, nil
+			}
+		}
+	}
+
+	// Compute the comparison from spec values and materialize it for next time
+	if m.specs != nil {
+		rows, err := m.computeComparison(ctx, req, benchmark)
+		if err != nil {
+			m.logger.Warn().Err(err).Msg("Failed to compute comparison")
+		} else if len(rows) > 0 {
+			if err := m.Materialize(ctx, req.TenantID, req.PrimaryProductID, req.SecondaryProductID, rows); err != nil {
+				m.logger.Warn().Err(err).Msg("Failed to materialize computed comparison")
+			}
+			return &ComparisonResponse{
+				Comparisons: m.filterRows(rows, req.Dimensions, req.MaxRows),
+				ComputedAt:  time.Now(),
+				Hash:        m.computeHash(rows),
+			}
 		}
 	}
 
@@ -167,7 +346,7 @@ func (m *Materializer) Compare(ctx context.Context, req ComparisonRequest) (*Com
 		Comparisons: []ComparisonRow{},
 		ComputedAt:  time.Now(),
 		Hash:        "",
-	}, nil
+	}
 }
 
 // Materialize pre-computes comparisons for a product pair.
@@ -192,16 +371,18 @@ func (m *Materializer) Materialize(ctx context.Context, tenantID, primaryID, sec
 		narrative := row.Narrative
 
 		storageRows[i] = storage.ComparisonRow{
-			ID:                 uuid.New(),
-			PrimaryProductID:   primaryID,
-			SecondaryProductID: secondaryID,
-			Dimension:          row.Dimension,
-			PrimaryValue:       &primaryVal,
-			SecondaryValue:     &secondaryVal,
-			Verdict:            row.Verdict,
-			Narrative:          &narrative,
-			Shareability:       row.Shareability,
-			ComputedAt:         now,
+			ID:                    uuid.New(),
+			PrimaryProductID:      primaryID,
+			SecondaryProductID:    secondaryID,
+			Dimension:             row.Dimension,
+			PrimaryValue:          &primaryVal,
+			SecondaryValue:        &secondaryVal,
+			Verdict:               row.Verdict,
+			Narrative:             &narrative,
+			Shareability:          row.Shareability,
+			SourcePrimarySpecID:   row.SourcePrimarySpecID,
+			SourceSecondarySpecID: row.SourceSecondarySpecID,
+			ComputedAt:            now,
 		}
 	}
 
@@ -221,16 +402,50 @@ func (m *Materializer) Materialize(ctx context.Context, tenantID, primaryID, sec
 
 // InvalidatePair removes cached comparisons for a product pair.
 func (m *Materializer) InvalidatePair(tenantID, productID uuid.UUID) {
+	m.invalidate(tenantID, func(key string) bool {
+		return containsProduct(key, productID.String())
+	})
+}
+
+// InvalidateTenant removes all cached comparisons of a tenant.
+func (m *Materializer) InvalidateTenant(tenantID uuid.UUID) {
+	m.invalidate(tenantID, func(string) bool { return true })
+}
+
+// ApplyInvalidation evicts comparisons affected by an invalidation event:
+// those of the event's product, or all of the tenant's when it names none.
+// When comparisons are computed from spec values, rows stored before the
+// specs changed are deleted too, so they are recomputed on the next request.
+func (m *Materializer) ApplyInvalidation(ctx context.Context, event cache.InvalidationEvent) error {
+	if event.ProductID != nil {
+		m.InvalidatePair(event.TenantID, *event.ProductID)
+	} else {
+		m.InvalidateTenant(event.TenantID)
+	}
+
+	switch event.Kind {
+	case cache.InvalidationSpecUpdated, cache.InvalidationCampaignPublished:
+		if m.store != nil && m.specs != nil {
+			if err := m.store.DeleteComparisons(ctx, event.TenantID, event.ProductID); err != nil {
+				return fmt.Errorf("delete stale comparisons: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// invalidate removes the tenant's cached comparisons whose keys match, from
+// both the in-memory map and the external cache.
+func (m *Materializer) invalidate(tenantID uuid.UUID, match func(key string) bool) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	// Remove all comparisons involving this product
 	prefix := tenantID.String() + ":"
 	for key := range m.comparisons {
-		if len(key) > len(prefix) && key[:len(prefix)] == prefix {
-			// Check if either product ID matches
-			if containsProduct(key, productID.String()) {
-				delete(m.comparisons, key)
+		if len(key) > len(prefix) && key[:len(prefix)] == prefix && match(key) {
+			delete(m.comparisons, key)
+			if m.cache != nil {
+				m.cache.Delete(context.Background(), key)
 			}
 		}
 	}
@@ -288,7 +503,7 @@ func (m *Materializer) cacheResult(ctx context.Context, key string, rows []Compa
 	m.mu.Unlock()
 
 	if m.cache != nil {
-		m.cache.Set(ctx, key, rows, time.Hour)
+		m.cache.Set(ctx, key, rows, m.config.CacheTTL)
 	}
 }
 
@@ -319,15 +534,17 @@ func (m *Materializer) convertFromStorage(stored []storage.ComparisonRow) []Comp
 		}
 
 		rows[i] = ComparisonRow{
-			ID:                 s.ID,
-			PrimaryProductID:   s.PrimaryProductID,
-			SecondaryProductID: s.SecondaryProductID,
-			Dimension:          s.Dimension,
-			PrimaryValue:       primaryVal,
-			SecondaryValue:     secondaryVal,
-			Verdict:            s.Verdict,
-			Narrative:          narrative,
-			Shareability:       s.Shareability,
+			ID:                    s.ID,
+			PrimaryProductID:      s.PrimaryProductID,
+			SecondaryProductID:    s.SecondaryProductID,
+			Dimension:             s.Dimension,
+			PrimaryValue:          primaryVal,
+			SecondaryValue:        secondaryVal,
+			Verdict:               s.Verdict,
+			Narrative:             narrative,
+			Shareability:          s.Shareability,
+			SourcePrimarySpecID:   s.SourcePrimarySpecID,
+			SourceSecondarySpecID: s.SourceSecondarySpecID,
 		}
 	}
 	return rows
@@ -389,3 +606,11 @@ func (c *MemoryComparisonCache) Set(ctx context.Context, key string, value []Com
 		expires: time.Now().Add(ttl),
 	}
 }
+
+// Delete removes a cached comparison.
+func (c *MemoryComparisonCache) Delete(ctx context.Context, key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.items, key)
+}
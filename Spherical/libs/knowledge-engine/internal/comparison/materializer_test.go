@@ -0,0 +1,183 @@
+package comparison
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/cache"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/observability"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubAccess map[uuid.UUID]*storage.ProductOwnership
+
+func (s stubAccess) ProductOwnership(ctx context.Context, productID uuid.UUID) (*storage.ProductOwnership, error) {
+	owner, ok := s[productID]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	return owner, nil
+}
+
+func TestMaterializer_CrossTenantAccess(t *testing.T) {
+	logger := observability.NewLogger(observability.LogConfig{Level: "error"})
+	tenant, rival := uuid.New(), uuid.New()
+	camry, corolla, accord, pilot := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	access := stubAccess{
+		camry:   {TenantID: tenant, Name: "Camry"},
+		corolla: {TenantID: tenant, Name: "Corolla"},
+		accord:  {TenantID: rival, Name: "Accord", Benchmark: true},
+		pilot:   {TenantID: rival, Name: "Pilot"},
+	}
+
+	newMaterializer := func(allow bool) *Materializer {
+		m := NewMaterializer(logger, nil, nil, Config{AllowCrossTenant: allow})
+		m.SetProductAccess(access)
+		rows := []ComparisonRow{
+			{Dimension: "Power", Shareability: storage.ComparisonBenchmarkOnly},
+			{Dimension: "Price", Shareability: storage.ComparisonTenantOnly},
+			{Dimension: "Length", Shareability: storage.ShareabilityPublic},
+		}
+		require.NoError(t, m.Materialize(context.Background(), tenant, camry, accord, rows))
+		require.NoError(t, m.Materialize(context.Background(), tenant, camry, corolla, rows))
+		return m
+	}
+	ctx := context.Background()
+
+	t.Run("own products", func(t *testing.T) {
+		resp, err := newMaterializer(false).Compare(ctx, ComparisonRequest{TenantID: tenant, PrimaryProductID: camry, SecondaryProductID: corolla})
+		require.NoError(t, err)
+		assert.Len(t, resp.Comparisons, 3)
+		assert.Empty(t, resp.Comparisons[0].BenchmarkProductName)
+	})
+
+	t.Run("benchmark requires opt-in and permission", func(t *testing.T) {
+		_, err := newMaterializer(false).Compare(ctx, ComparisonRequest{TenantID: tenant, PrimaryProductID: camry, SecondaryProductID: accord, IncludeBenchmarks: true})
+		assert.ErrorIs(t, err, ErrProductNotAccessible)
+
+		_, err = newMaterializer(true).Compare(ctx, ComparisonRequest{TenantID: tenant, PrimaryProductID: camry, SecondaryProductID: accord})
+		assert.ErrorIs(t, err, ErrProductNotAccessible)
+	})
+
+	t.Run("benchmark rows are shared and labelled", func(t *testing.T) {
+		resp, err := newMaterializer(true).Compare(ctx, ComparisonRequest{TenantID: tenant, PrimaryProductID: camry, SecondaryProductID: accord, IncludeBenchmarks: true})
+		require.NoError(t, err)
+		require.Len(t, resp.Comparisons, 2, "tenant-only rows are withheld")
+		for _, row := range resp.Comparisons {
+			assert.NotEqual(t, "Price", row.Dimension)
+			assert.Equal(t, "Accord", row.BenchmarkProductName)
+		}
+	})
+
+	t.Run("private products of other tenants", func(t *testing.T) {
+		m := newMaterializer(true)
+		_, err := m.Compare(ctx, ComparisonRequest{TenantID: tenant, PrimaryProductID: camry, SecondaryProductID: pilot, IncludeBenchmarks: true})
+		assert.ErrorIs(t, err, ErrProductNotAccessible)
+
+		_, err = m.Compare(ctx, ComparisonRequest{TenantID: tenant, PrimaryProductID: accord, SecondaryProductID: camry, IncludeBenchmarks: true})
+		assert.ErrorIs(t, err, ErrProductNotAccessible, "the primary product must be the caller's")
+
+		_, err = m.Compare(ctx, ComparisonRequest{TenantID: tenant, PrimaryProductID: camry, SecondaryProductID: uuid.New(), IncludeBenchmarks: true})
+		assert.ErrorIs(t, err, ErrProductNotAccessible)
+	})
+}
+
+func TestMaterializer_ApplyInvalidation(t *testing.T) {
+	logger := observability.NewLogger(observability.LogConfig{Level: "error"})
+	ctx := context.Background()
+	tenant := uuid.New()
+	camry, corolla, accord := uuid.New(), uuid.New(), uuid.New()
+	rows := []ComparisonRow{{Dimension: "Power", Shareability: storage.ShareabilityPublic}}
+
+	external := NewMemoryComparisonCache()
+	m := NewMaterializer(logger, external, nil, Config{})
+	require.NoError(t, m.Materialize(ctx, tenant, camry, corolla, rows))
+	require.NoError(t, m.Materialize(ctx, tenant, camry, accord, rows))
+	compare := func(secondary uuid.UUID) int {
+		resp, err := m.Compare(ctx, ComparisonRequest{TenantID: tenant, PrimaryProductID: camry, SecondaryProductID: secondary})
+		require.NoError(t, err)
+		return len(resp.Comparisons)
+	}
+
+	require.NoError(t, m.ApplyInvalidation(ctx, cache.InvalidationEvent{
+		Kind:      cache.InvalidationSpecUpdated,
+		TenantID:  tenant,
+		ProductID: &accord,
+	}))
+	assert.Equal(t, 1, compare(corolla))
+	assert.Equal(t, 0, compare(accord), "the external cache is cleared too")
+
+	require.NoError(t, m.ApplyInvalidation(ctx, cache.InvalidationEvent{
+		Kind:     cache.InvalidationComparisonRecomputed,
+		TenantID: tenant,
+	}))
+	assert.Equal(t, 0, compare(corolla))
+}
+
+func TestMaterializer_CacheTTL(t *testing.T) {
+	logger := observability.NewLogger(observability.LogConfig{Level: "error"})
+	ctx := context.Background()
+	tenant, camry, corolla := uuid.New(), uuid.New(), uuid.New()
+	external := NewMemoryComparisonCache()
+	m := NewMaterializer(logger, external, nil, Config{CacheTTL: 20 * time.Millisecond})
+	require.NoError(t, m.Materialize(ctx, tenant, camry, corolla, []ComparisonRow{{Dimension: "Power", PrimaryValue: "225 hp", SecondaryValue: "169 hp"}}))
+	compare := func() int {
+		resp, err := m.Compare(ctx, ComparisonRequest{TenantID: tenant, PrimaryProductID: camry, SecondaryProductID: corolla})
+		require.NoError(t, err)
+		return len(resp.Comparisons)
+	}
+
+	assert.Equal(t, 1, compare())
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, 0, compare(), "both caches expire after the configured TTL")
+}
+
+// blockingAccess holds ownership lookups until release is closed.
+type blockingAccess struct {
+	stubAccess
+	lookups atomic.Int64
+	release chan struct{}
+}
+
+func (b *blockingAccess) ProductOwnership(ctx context.Context, productID uuid.UUID) (*storage.ProductOwnership, error) {
+	b.lookups.Add(1)
+	<-b.release
+	return b.stubAccess.ProductOwnership(ctx, productID)
+}
+
+func TestMaterializer_CoalescesConcurrentCompares(t *testing.T) {
+	logger := observability.NewLogger(observability.LogConfig{Level: "error"})
+	tenant, camry, corolla := uuid.New(), uuid.New(), uuid.New()
+	access := &blockingAccess{
+		stubAccess: stubAccess{
+			camry:   {TenantID: tenant, Name: "Camry"},
+			corolla: {TenantID: tenant, Name: "Corolla"},
+		},
+		release: make(chan struct{}),
+	}
+	m := NewMaterializer(logger, nil, nil, Config{})
+	m.SetProductAccess(access)
+	req := ComparisonRequest{TenantID: tenant, PrimaryProductID: camry, SecondaryProductID: corolla}
+
+	const callers = 4
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := m.Compare(context.Background(), req)
+			assert.NoError(t, err)
+		}()
+	}
+	require.Eventually(t, func() bool { return m.inflight.Shared() == callers-1 }, time.Second, time.Millisecond)
+	close(access.release)
+	wg.Wait()
+
+	assert.Equal(t, int64(2), access.lookups.Load(), "one access check of both products for all callers")
+}
@@ -0,0 +1,129 @@
+// Package comparison provides point-in-time comparisons from campaign version history.
+package comparison
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/storage"
+)
+
+// ErrSnapshotUnavailable is returned for point-in-time requests when the
+// materializer has no snapshot source.
+var ErrSnapshotUnavailable = errors.New("point-in-time comparison is not configured")
+
+// SnapshotSource resolves the campaign versions live at a point in time and the
+// spec values they exposed. storage.SnapshotRepository reads them from version history.
+type SnapshotSource interface {
+	Snapshot(ctx context.Context, q storage.SnapshotQuery) (*storage.Snapshot, error)
+}
+
+// SetSnapshotSource enables requests with AsOf or CampaignVersion. A nil source
+// rejects them with ErrSnapshotUnavailable.
+func (m *Materializer) SetSnapshotSource(source SnapshotSource) {
+	m.snapshots = source
+}
+
+// pointInTime reports whether the request asks for past campaign versions.
+func (req ComparisonRequest) pointInTime() bool {
+	return req.AsOf != nil || req.CampaignVersion != nil
+}
+
+// compareSnapshot compares the spec values the products exposed at the
+// requested point in time. Materialized rows describe the published versions,
+// so rows are built from the snapshot's values instead. A CampaignVersion
+// selects the primary product's version; the secondary product is taken as it
+// stood when that version went live.
+func (m *Materializer) compareSnapshot(ctx context.Context, req ComparisonRequest, benchmark *storage.ProductOwnership) (*ComparisonResponse, error) {
+	if m.snapshots == nil {
+		return nil, ErrSnapshotUnavailable
+	}
+
+	primary, err := m.snapshots.Snapshot(ctx, storage.SnapshotQuery{
+		TenantID:   req.TenantID,
+		ProductIDs: []uuid.UUID{req.PrimaryProductID},
+		AsOf:       req.AsOf,
+		Version:    req.CampaignVersion,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("resolve primary snapshot: %w", err)
+	}
+
+	asOf := req.AsOf
+	if asOf == nil {
+		asOf = wentLive(primary.Campaigns)
+		if asOf == nil {
+			return nil, fmt.Errorf("resolve secondary snapshot: %w", storage.ErrNoCampaignVersion)
+		}
+	}
+	secondaryTenant := req.TenantID
+	if benchmark != nil {
+		secondaryTenant = benchmark.TenantID
+	}
+	secondary, err := m.snapshots.Snapshot(ctx, storage.SnapshotQuery{
+		TenantID:   secondaryTenant,
+		ProductIDs: []uuid.UUID{req.SecondaryProductID},
+		AsOf:       asOf,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("resolve secondary snapshot: %w", err)
+	}
+
+	shareability := storage.ComparisonTenantOnly
+	if benchmark != nil {
+		shareability = storage.ComparisonBenchmarkOnly
+	}
+	rows := specRows(req, primary.Specs, secondary.Specs, shareability, m.config.Directions)
+
+	return &ComparisonResponse{
+		Comparisons: m.filterRows(rows, req.Dimensions, req.MaxRows),
+		ComputedAt:  time.Now(),
+		Hash:        m.computeHash(rows),
+		Versions:    append(append([]*storage.CampaignVariant(nil), primary.Campaigns...), secondary.Campaigns...),
+	}, nil
+}
+
+// wentLive returns the earliest moment one of the campaign versions took effect.
+func wentLive(campaigns []*storage.CampaignVariant) *time.Time {
+	var earliest *time.Time
+	for _, c := range campaigns {
+		if c.EffectiveFrom != nil && (earliest == nil || c.EffectiveFrom.Before(*earliest)) {
+			earliest = c.EffectiveFrom
+		}
+	}
+	return earliest
+}
+
+// specValues keys spec values by spec item, keeping the most confident value
+// when several campaign versions supply one, and appends new items to order.
+func specValues(specs []storage.SpecViewLatest, order []uuid.UUID) (map[uuid.UUID]storage.SpecViewLatest, []uuid.UUID) {
+	seen := make(map[uuid.UUID]bool, len(order))
+	for _, id := range order {
+		seen[id] = true
+	}
+	values := make(map[uuid.UUID]storage.SpecViewLatest, len(specs))
+	for _, sv := range specs {
+		if current, ok := values[sv.SpecItemID]; ok && current.Confidence >= sv.Confidence {
+			continue
+		}
+		values[sv.SpecItemID] = sv
+		if !seen[sv.SpecItemID] {
+			seen[sv.SpecItemID] = true
+			order = append(order, sv.SpecItemID)
+		}
+	}
+	return values, order
+}
+
+// displayValue formats a spec value with its unit.
+func displayValue(sv storage.SpecViewLatest) string {
+	value := strings.TrimSpace(sv.Value)
+	if sv.Unit != nil && *sv.Unit != "" {
+		value += " " + *sv.Unit
+	}
+	return value
+}
@@ -0,0 +1,80 @@
+package comparison
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/observability"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubSnapshots serves a snapshot per product and records the queries it was given.
+type stubSnapshots struct {
+	snapshots map[uuid.UUID]*storage.Snapshot
+	queries   []storage.SnapshotQuery
+}
+
+func (s *stubSnapshots) Snapshot(ctx context.Context, q storage.SnapshotQuery) (*storage.Snapshot, error) {
+	s.queries = append(s.queries, q)
+	snapshot, ok := s.snapshots[q.ProductIDs[0]]
+	if !ok {
+		return nil, storage.ErrNoCampaignVersion
+	}
+	return snapshot, nil
+}
+
+func TestMaterializer_PointInTime(t *testing.T) {
+	logger := observability.NewLogger(observability.LogConfig{Level: "error"})
+	tenant := uuid.New()
+	camry, corolla := uuid.New(), uuid.New()
+	power, seats, wheels := uuid.New(), uuid.New(), uuid.New()
+	jan := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	hp := "hp"
+
+	spec := func(product, item uuid.UUID, name, value string, unit *string) storage.SpecViewLatest {
+		return storage.SpecViewLatest{ID: uuid.New(), TenantID: tenant, ProductID: product, SpecItemID: item, SpecName: name, Value: value, Unit: unit, Confidence: 0.9}
+	}
+	source := &stubSnapshots{snapshots: map[uuid.UUID]*storage.Snapshot{
+		camry: {
+			Campaigns: []*storage.CampaignVariant{{ID: uuid.New(), ProductID: camry, Version: 1, Status: storage.CampaignStatusArchived, EffectiveFrom: &jan}},
+			Specs:     []storage.SpecViewLatest{spec(camry, power, "Power", "178", &hp), spec(camry, seats, "Seats", "5", nil)},
+		},
+		corolla: {
+			Campaigns: []*storage.CampaignVariant{{ID: uuid.New(), ProductID: corolla, Version: 4, Status: storage.CampaignStatusPublished, EffectiveFrom: &jan}},
+			Specs:     []storage.SpecViewLatest{spec(corolla, power, "Power", "169", &hp), spec(corolla, seats, "Seats", "5", nil), spec(corolla, wheels, "Wheels", "16 in", nil)},
+		},
+	}}
+
+	m := NewMaterializer(logger, nil, nil, Config{})
+	ctx := context.Background()
+	version := 1
+	req := ComparisonRequest{TenantID: tenant, PrimaryProductID: camry, SecondaryProductID: corolla, CampaignVersion: &version}
+
+	_, err := m.Compare(ctx, req)
+	assert.ErrorIs(t, err, ErrSnapshotUnavailable)
+
+	m.SetSnapshotSource(source)
+	resp, err := m.Compare(ctx, req)
+	require.NoError(t, err)
+	require.Len(t, resp.Comparisons, 3)
+
+	byDimension := make(map[string]ComparisonRow)
+	for _, row := range resp.Comparisons {
+		byDimension[row.Dimension] = row
+	}
+	assert.Equal(t, "178 hp", byDimension["Power"].PrimaryValue)
+	assert.Equal(t, "169 hp", byDimension["Power"].SecondaryValue)
+	assert.Equal(t, storage.VerdictEqual, byDimension["Seats"].Verdict)
+	assert.Equal(t, storage.VerdictCannotCompare, byDimension["Wheels"].Verdict)
+	assert.Empty(t, byDimension["Wheels"].PrimaryValue)
+
+	require.Len(t, resp.Versions, 2)
+	assert.Equal(t, 1, resp.Versions[0].Version)
+	require.Len(t, source.queries, 2)
+	assert.Equal(t, &version, source.queries[0].Version)
+	assert.Equal(t, &jan, source.queries[1].AsOf, "the secondary product is taken as it stood when the version went live")
+}
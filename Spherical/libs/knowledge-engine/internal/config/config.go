@@ -69,9 +69,19 @@ type VectorConfig struct {
 
 // FAISSConfig holds FAISS-specific settings.
 type FAISSConfig struct {
-	IndexPath string `yaml:"index_path"`
-	Dimension int    `yaml:"dimension"`
-	NList     int    `yaml:"nlist"`
+	IndexPath    string             `yaml:"index_path"`
+	Dimension    int                `yaml:"dimension"`
+	NList        int                `yaml:"nlist"`
+	Quantization QuantizationConfig `yaml:"quantization"`
+}
+
+// QuantizationConfig holds compression settings of in-memory vectors.
+type QuantizationConfig struct {
+	Mode             string  `yaml:"mode"`              // "" (float32), int8 or pq
+	CompressionRatio int     `yaml:"compression_ratio"` // float32 size over PQ code size
+	TrainSize        int     `yaml:"train_size"`        // vectors held before PQ codebooks are trained
+	RerankFactor     int     `yaml:"rerank_factor"`     // candidates re-ranked at full precision per result
+	RetrainGrowth    float64 `yaml:"retrain_growth"`    // index growth that retrains PQ codebooks; negative disables
 }
 
 // PGVectorConfig holds PGVector-specific settings.
@@ -98,9 +108,40 @@ type RedisConfig struct {
 
 // EmbeddingConfig holds embedding model settings.
 type EmbeddingConfig struct {
-	Model     string `yaml:"model"`
+	Model     string `yaml:"model"` // "local" uses the offline embedder
 	Dimension int    `yaml:"dimension"`
 	BatchSize int    `yaml:"batch_size"`
+	Retry     EmbeddingRetryConfig    `yaml:"retry"`
+	Fallback  EmbeddingFallbackConfig `yaml:"fallback"`
+	Cache     EmbeddingCacheConfig    `yaml:"cache"`
+}
+
+// EmbeddingCacheConfig holds settings for reusing vectors of previously embedded text.
+type EmbeddingCacheConfig struct {
+	Backend string        `yaml:"backend"` // empty (disabled), cache (memory or redis per cache.driver) or sqlite
+	Path    string        `yaml:"path"`    // sqlite file; empty uses the main database
+	TTL     time.Duration `yaml:"ttl"`     // entry lifetime in the cache backend
+}
+
+
+// EmbeddingRetryConfig holds retry, rate limiting and circuit breaker settings
+// for embedding providers.
+type EmbeddingRetryConfig struct {
+	MaxRetries        int           `yaml:"max_retries"`
+	InitialBackoff    time.Duration `yaml:"initial_backoff"`
+	MaxBackoff        time.Duration `yaml:"max_backoff"`
+	RequestsPerSecond float64       `yaml:"requests_per_second"` // 0 disables rate limiting
+	Burst             int           `yaml:"burst"`
+	BreakerThreshold  int           `yaml:"breaker_threshold"`
+	BreakerCooldown   time.Duration `yaml:"breaker_cooldown"`
+}
+
+// EmbeddingFallbackConfig holds a secondary provider used when the primary fails.
+// It must serve the same model so vectors stay comparable.
+type EmbeddingFallbackConfig struct {
+	BaseURL string `yaml:"base_url"` // empty disables the fallback
+	Model   string `yaml:"model"`    // defaults to the primary model
+	APIKey  string `yaml:"-"`        // loaded from EMBEDDING_FALLBACK_API_KEY
 }
 
 // RetrievalConfig holds retrieval settings.
@@ -110,6 +151,69 @@ type RetrievalConfig struct {
 	SemanticFallback           bool    `yaml:"semantic_fallback"`
 	IntentConfidenceThreshold  float64 `yaml:"intent_confidence_threshold"`
 	CacheResults               bool    `yaml:"cache_results"`
+	// CacheRevalidateWindow serves responses this close to expiry while refreshing them.
+	CacheRevalidateWindow time.Duration `yaml:"cache_revalidate_window"`
+	Hybrid                     HybridConfig `yaml:"hybrid"`
+	// TenantHybrid overrides Hybrid per tenant ID.
+	TenantHybrid map[string]HybridConfig `yaml:"tenant_hybrid"`
+	Answer       AnswerConfig            `yaml:"answer"`
+	Expansion    ExpansionConfig         `yaml:"expansion"`
+	// IntentModelDir holds trained intent models (default.json, <tenant-id>.json);
+	// empty uses the pattern classifier.
+	IntentModelDir string `yaml:"intent_model_dir"`
+	Benchmarks     BenchmarkConfig `yaml:"benchmarks"`
+	SemanticCache  SemanticCacheConfig `yaml:"semantic_cache"`
+}
+
+// SemanticCacheConfig holds settings for reusing responses to similar questions.
+type SemanticCacheConfig struct {
+	// Enabled caches responses by question embedding; it requires an embedder.
+	Enabled             bool          `yaml:"enabled"`
+	SimilarityThreshold float64       `yaml:"similarity_threshold"`
+	TTL                 time.Duration `yaml:"ttl"`
+	MaxEntries          int           `yaml:"max_entries"`
+	MaxScopes           int           `yaml:"max_scopes"`
+}
+
+// BenchmarkConfig holds cross-tenant benchmark retrieval settings.
+type BenchmarkConfig struct {
+	// Enabled lets requests include other tenants' public benchmark products.
+	Enabled         bool          `yaml:"enabled"`
+	MaxFacts        int           `yaml:"max_facts"`
+	MaxChunks       int           `yaml:"max_chunks"`
+	RefreshInterval time.Duration `yaml:"refresh_interval"`
+}
+
+// ExpansionConfig holds query expansion settings.
+type ExpansionConfig struct {
+	Disabled bool   `yaml:"disabled"`
+	Domain   string `yaml:"domain"` // built-in synonym dictionary, default automotive
+	// Synonyms maps query phrases to extra terms searched at a reduced weight.
+	Synonyms map[string][]string `yaml:"synonyms"`
+	// TenantSynonyms extends Synonyms per tenant ID.
+	TenantSynonyms        map[string]map[string][]string `yaml:"tenant_synonyms"`
+	MinSpellingSimilarity float64                        `yaml:"min_spelling_similarity"`
+}
+
+// AnswerConfig holds answer synthesis settings.
+type AnswerConfig struct {
+	Generator string        `yaml:"generator"` // template or openai
+	BaseURL   string        `yaml:"base_url"`  // OpenAI-compatible API base URL
+	Model     string        `yaml:"model"`
+	APIKey    string        `yaml:"-"` // loaded from ANSWER_API_KEY
+	Timeout   time.Duration `yaml:"timeout"`
+	// Rewriter rewrites follow-up questions: rule (default) or llm, which uses
+	// the openai generator's model and falls back to rules.
+	Rewriter string `yaml:"rewriter"`
+}
+
+// HybridConfig holds hybrid lexical and vector retrieval settings.
+type HybridConfig struct {
+	Fusion        string  `yaml:"fusion"` // empty (disabled), rrf or weighted
+	RRFK          int     `yaml:"rrf_k"`
+	LexicalWeight float64 `yaml:"lexical_weight"`
+	VectorWeight  float64 `yaml:"vector_weight"`
+	MaxEvidence   int     `yaml:"max_evidence"`
 }
 
 // IngestionConfig holds ingestion pipeline settings.
@@ -126,6 +230,8 @@ type ComparisonConfig struct {
 	MaxDimensions   int           `yaml:"max_dimensions"`
 	RefreshInterval time.Duration `yaml:"refresh_interval"`
 	StaleThreshold  time.Duration `yaml:"stale_threshold"`
+	// AllowCrossTenant permits comparisons against other tenants' public benchmark products.
+	AllowCrossTenant bool `yaml:"allow_cross_tenant"`
 }
 
 // DriftConfig holds drift monitoring settings.
@@ -144,9 +250,11 @@ type ObservabilityConfig struct {
 
 // OTELConfig holds OpenTelemetry settings.
 type OTELConfig struct {
-	Enabled     bool   `yaml:"enabled"`
-	Endpoint    string `yaml:"endpoint"`
-	ServiceName string `yaml:"service_name"`
+	Enabled bool `yaml:"enabled"`
+	// Endpoint is the OTLP/HTTP collector base URL.
+	Endpoint    string  `yaml:"endpoint"`
+	ServiceName string  `yaml:"service_name"`
+	SampleRatio float64 `yaml:"sample_ratio"`
 }
 
 // AuthConfig holds authentication settings.
@@ -250,6 +358,14 @@ func DefaultConfig() *Config {
 			Model:     "text-embedding-3-small",
 			Dimension: 768,
 			BatchSize: 100,
+			Retry: EmbeddingRetryConfig{
+				MaxRetries:       4,
+				InitialBackoff:   500 * time.Millisecond,
+				MaxBackoff:       30 * time.Second,
+				Burst:            1,
+				BreakerThreshold: 5,
+				BreakerCooldown:  30 * time.Second,
+			},
 		},
 		Retrieval: RetrievalConfig{
 			MaxChunks:                  8,
@@ -257,6 +373,9 @@ func DefaultConfig() *Config {
 			SemanticFallback:           true,
 			IntentConfidenceThreshold:  0.7,
 			CacheResults:               true,
+			Answer: AnswerConfig{
+				Generator: "template",
+			},
 		},
 		Ingestion: IngestionConfig{
 			PDFExtractorPath:  "../pdf-extractor/cmd/pdf-extractor",
@@ -280,8 +399,9 @@ func DefaultConfig() *Config {
 			LogFormat: "json",
 			OTEL: OTELConfig{
 				Enabled:     false,
-				Endpoint:    "http://localhost:4317",
+				Endpoint:    "http://localhost:4318",
 				ServiceName: "knowledge-engine",
+				SampleRatio: 1,
 			},
 		},
 		Auth: AuthConfig{
@@ -312,6 +432,12 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("invalid vector adapter: %s", c.Vector.Adapter)
 	}
 
+	switch c.Vector.FAISS.Quantization.Mode {
+	case "", "int8", "pq":
+	default:
+		return fmt.Errorf("invalid vector quantization: %s", c.Vector.FAISS.Quantization.Mode)
+	}
+
 	if c.Cache.Driver != "memory" && c.Cache.Driver != "redis" {
 		return fmt.Errorf("invalid cache driver: %s", c.Cache.Driver)
 	}
@@ -320,6 +446,68 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("max_chunks must be between 1 and 20")
 	}
 
+	if err := c.Retrieval.Hybrid.validate(); err != nil {
+		return err
+	}
+	for tenant, hybrid := range c.Retrieval.TenantHybrid {
+		if err := hybrid.validate(); err != nil {
+			return fmt.Errorf("tenant %s: %w", tenant, err)
+		}
+	}
+
+	if err := c.Embedding.Cache.validate(); err != nil {
+		return err
+	}
+
+	if err := c.Retrieval.Answer.validate(); err != nil {
+		return err
+	}
+
+	if s := c.Retrieval.Expansion.MinSpellingSimilarity; s < 0 || s > 1 {
+		return fmt.Errorf("expansion min_spelling_similarity must be between 0 and 1")
+	}
+
+	return nil
+}
+
+// validate checks hybrid retrieval settings.
+func (h HybridConfig) validate() error {
+	switch h.Fusion {
+	case "", "rrf", "weighted":
+	default:
+		return fmt.Errorf("invalid hybrid fusion: %s", h.Fusion)
+	}
+	if h.LexicalWeight < 0 || h.VectorWeight < 0 {
+		return fmt.Errorf("hybrid fusion weights must not be negative")
+	}
+	return nil
+}
+
+// validate checks answer synthesis settings.
+func (a AnswerConfig) validate() error {
+	switch a.Generator {
+	case "", "template", "openai":
+	default:
+		return fmt.Errorf("invalid answer generator: %s", a.Generator)
+	}
+	switch a.Rewriter {
+	case "", "rule":
+	case "llm":
+		if a.Generator != "openai" {
+			return fmt.Errorf("llm query rewriter requires the openai answer generator")
+		}
+	default:
+		return fmt.Errorf("invalid query rewriter: %s", a.Rewriter)
+	}
+	return nil
+}
+
+func (e EmbeddingCacheConfig) validate() error {
+	switch e.Backend {
+	case "", "cache", "sqlite":
+	default:
+		return fmt.Errorf("invalid embedding cache backend: %s", e.Backend)
+	}
 	return nil
 }
 
@@ -382,6 +570,22 @@ func applyEnvOverrides(cfg *Config) {
 		cfg.Embedding.Model = v
 	}
 
+	if v := os.Getenv("EMBEDDING_FALLBACK_API_KEY"); v != "" {
+		cfg.Embedding.Fallback.APIKey = v
+	}
+
+	if v := os.Getenv("ANSWER_GENERATOR"); v != "" {
+		cfg.Retrieval.Answer.Generator = v
+	}
+
+	if v := os.Getenv("ANSWER_BASE_URL"); v != "" {
+		cfg.Retrieval.Answer.BaseURL = v
+	}
+
+	if v := os.Getenv("ANSWER_API_KEY"); v != "" {
+		cfg.Retrieval.Answer.APIKey = v
+	}
+
 	if v := os.Getenv("LOG_LEVEL"); v != "" {
 		cfg.Observability.LogLevel = v
 	}
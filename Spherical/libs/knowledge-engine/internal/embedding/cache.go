@@ -0,0 +1,274 @@
+// Package embedding provides a content-addressed cache for embedding vectors.
+package embedding
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/cache"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/observability"
+)
+
+// CacheBackend stores embedding vectors by content address. Get returns
+// cache.ErrCacheMiss for unknown keys.
+type CacheBackend interface {
+	Get(ctx context.Context, key string) ([]float32, error)
+	Set(ctx context.Context, key string, vector []float32) error
+}
+
+// CacheStats reports how often cached vectors were reused.
+type CacheStats struct {
+	Hits    int64   `json:"hits"`
+	Misses  int64   `json:"misses"`
+	HitRate float64 `json:"hit_rate"`
+}
+
+// CachedEmbedder reuses vectors for texts it has embedded before, keyed by
+// model, dimension and a hash of the normalised text, so re-ingesting or
+// re-querying identical text does not call the provider again.
+type CachedEmbedder struct {
+	logger  *observability.Logger
+	inner   Embedder
+	backend CacheBackend
+	hits    atomic.Int64
+	misses  atomic.Int64
+}
+
+// NewCachedEmbedder wraps inner with a vector cache in backend.
+func NewCachedEmbedder(logger *observability.Logger, inner Embedder, backend CacheBackend) *CachedEmbedder {
+	return &CachedEmbedder{logger: logger, inner: inner, backend: backend}
+}
+
+// CacheKey returns the content address of text's embedding under model and dimension.
+func CacheKey(model string, dimension int, text string) string {
+	sum := sha256.Sum256([]byte(normalizeText(text)))
+	return model + ":" + strconv.Itoa(dimension) + ":" + hex.EncodeToString(sum[:])
+}
+
+// normalizeText collapses whitespace, which does not change what a text says
+// but often differs between extractions of the same brochure.
+func normalizeText(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// Embed returns cached vectors where available and embeds the remaining
+// texts in one call to the wrapped embedder. Cache failures fall back to the
+// wrapped embedder.
+func (e *CachedEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	embeddings, _, err := e.EmbedModels(ctx, texts)
+	return embeddings, err
+}
+
+// EmbedModels embeds texts like Embed and returns the model that produced each
+// vector. Only vectors produced by the wrapped embedder's own model are
+// cached: a vector from a fallback provider is returned but not stored, so
+// the text is embedded with the primary model again once it recovers.
+func (e *CachedEmbedder) EmbedModels(ctx context.Context, texts []string) ([][]float32, []string, error) {
+	if len(texts) == 0 {
+		return nil, nil, nil
+	}
+
+	model, dimension := e.inner.Model(), e.inner.Dimension()
+	embeddings := make([][]float32, len(texts))
+	models := make([]string, len(texts))
+
+	// Identical texts in one call are looked up and embedded once
+	resolved := make(map[string][]float32)
+	pending := make(map[string][]int)
+	var missingKeys []string
+	var missing []string
+	for i, text := range texts {
+		key := CacheKey(model, dimension, text)
+		if vector, ok := resolved[key]; ok {
+			embeddings[i], models[i] = vector, model
+			continue
+		}
+		if indexes, ok := pending[key]; ok {
+			pending[key] = append(indexes, i)
+			continue
+		}
+
+		vector, err := e.backend.Get(ctx, key)
+		if err == nil && len(vector) > 0 {
+			e.hits.Add(1)
+			resolved[key] = vector
+			embeddings[i], models[i] = vector, model
+			continue
+		}
+		if err != nil && !errors.Is(err, cache.ErrCacheMiss) {
+			e.logger.Warn().Err(err).Msg("Embedding cache lookup failed")
+		}
+		e.misses.Add(1)
+		pending[key] = []int{i}
+		missingKeys = append(missingKeys, key)
+		missing = append(missing, text)
+	}
+	if len(missing) == 0 {
+		return embeddings, models, nil
+	}
+
+	vectors, vectorModels, err := EmbedModels(ctx, e.inner, missing)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(vectors) != len(missing) || len(vectorModels) != len(missing) {
+		return nil, nil, fmt.Errorf("embedder returned %d embeddings for %d texts", len(vectors), len(missing))
+	}
+
+	for j, key := range missingKeys {
+		for _, i := range pending[key] {
+			embeddings[i], models[i] = vectors[j], vectorModels[j]
+		}
+		if len(vectors[j]) == 0 || vectorModels[j] != model {
+			continue
+		}
+		// Store under the dimension actually returned, which a provider may only reveal now
+		if len(vectors[j]) != dimension {
+			key = CacheKey(model, len(vectors[j]), missing[j])
+		}
+		if err := e.backend.Set(ctx, key, vectors[j]); err != nil {
+			e.logger.Warn().Err(err).Msg("Embedding cache store failed")
+		}
+	}
+	return embeddings, models, nil
+}
+
+// EmbedSingle generates an embedding for a single text.
+func (e *CachedEmbedder) EmbedSingle(ctx context.Context, text string) ([]float32, error) {
+	embeddings, err := e.Embed(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	if len(embeddings) == 0 || len(embeddings[0]) == 0 {
+		return nil, fmt.Errorf("no embedding returned")
+	}
+	return embeddings[0], nil
+}
+
+// Model returns the wrapped embedder's model.
+func (e *CachedEmbedder) Model() string {
+	return e.inner.Model()
+}
+
+// Dimension returns the wrapped embedder's embedding dimension.
+func (e *CachedEmbedder) Dimension() int {
+	return e.inner.Dimension()
+}
+
+// Stats returns cache hits and misses since the embedder was created.
+func (e *CachedEmbedder) Stats() CacheStats {
+	stats := CacheStats{Hits: e.hits.Load(), Misses: e.misses.Load()}
+	if total := stats.Hits + stats.Misses; total > 0 {
+		stats.HitRate = float64(stats.Hits) / float64(total)
+	}
+	return stats
+}
+
+// encodeVector packs a vector as little-endian float32s.
+func encodeVector(vector []float32) []byte {
+	buf := make([]byte, 4*len(vector))
+	for i, v := range vector {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(v))
+	}
+	return buf
+}
+
+// decodeVector unpacks a vector written by encodeVector.
+func decodeVector(buf []byte) ([]float32, error) {
+	if len(buf)%4 != 0 {
+		return nil, fmt.Errorf("corrupt cached vector of %d bytes", len(buf))
+	}
+	vector := make([]float32, len(buf)/4)
+	for i := range vector {
+		vector[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf[4*i:]))
+	}
+	return vector, nil
+}
+
+// ClientCacheBackend keeps vectors in a cache.Client, such as the in-memory
+// or Redis cache, which may evict them.
+type ClientCacheBackend struct {
+	client cache.Client
+	ttl    time.Duration
+}
+
+// NewClientCacheBackend stores vectors in client for ttl (default 30 days).
+func NewClientCacheBackend(client cache.Client, ttl time.Duration) *ClientCacheBackend {
+	if ttl <= 0 {
+		ttl = 30 * 24 * time.Hour
+	}
+	return &ClientCacheBackend{client: client, ttl: ttl}
+}
+
+// Get retrieves a vector.
+func (b *ClientCacheBackend) Get(ctx context.Context, key string) ([]float32, error) {
+	data, err := b.client.Get(ctx, "embedding:"+key)
+	if err != nil {
+		return nil, err
+	}
+	return decodeVector(data)
+}
+
+// Set stores a vector.
+func (b *ClientCacheBackend) Set(ctx context.Context, key string, vector []float32) error {
+	return b.client.Set(ctx, "embedding:"+key, encodeVector(vector), b.ttl)
+}
+
+// SQLCacheBackend keeps vectors in a SQLite table, so they survive restarts
+// and can live in a local file next to the demo database.
+type SQLCacheBackend struct {
+	db *sql.DB
+}
+
+// NewSQLCacheBackend stores vectors in db, creating the embedding_cache table if needed.
+func NewSQLCacheBackend(ctx context.Context, db *sql.DB) (*SQLCacheBackend, error) {
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS embedding_cache (
+			key TEXT PRIMARY KEY,
+			vector BLOB NOT NULL,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("create embedding_cache table: %w", err)
+	}
+	return &SQLCacheBackend{db: db}, nil
+}
+
+// Get retrieves a vector.
+func (b *SQLCacheBackend) Get(ctx context.Context, key string) ([]float32, error) {
+	var data []byte
+	err := b.db.QueryRowContext(ctx, "SELECT vector FROM embedding_cache WHERE key = ?", key).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, cache.ErrCacheMiss
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decodeVector(data)
+}
+
+// Set stores a vector, replacing any vector under the same key.
+func (b *SQLCacheBackend) Set(ctx context.Context, key string, vector []float32) error {
+	_, err := b.db.ExecContext(ctx,
+		"INSERT OR REPLACE INTO embedding_cache (key, vector) VALUES (?, ?)",
+		key, encodeVector(vector))
+	return err
+}
+
+// Ensure implementations satisfy interfaces.
+var (
+	_ ModelEmbedder = (*CachedEmbedder)(nil)
+	_ CacheBackend  = (*ClientCacheBackend)(nil)
+	_ CacheBackend  = (*SQLCacheBackend)(nil)
+)
@@ -0,0 +1,139 @@
+package embedding
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/cache"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/observability"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingEmbedder wraps the mock client and records the texts it embeds.
+type countingEmbedder struct {
+	*MockClient
+	model string
+	texts []string
+}
+
+func (c *countingEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	c.texts = append(c.texts, texts...)
+	return c.MockClient.Embed(ctx, texts)
+}
+
+func (c *countingEmbedder) EmbedSingle(ctx context.Context, text string) ([]float32, error) {
+	embeddings, err := c.Embed(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return embeddings[0], nil
+}
+
+func (c *countingEmbedder) Model() string {
+	return c.model
+}
+
+func TestCachedEmbedder(t *testing.T) {
+	logger := observability.NewLogger(observability.LogConfig{Level: "error"})
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+	sqlBackend, err := NewSQLCacheBackend(context.Background(), db)
+	require.NoError(t, err)
+
+	backends := map[string]CacheBackend{
+		"cache client": NewClientCacheBackend(cache.NewMemoryClient(100), 0),
+		"sqlite":       sqlBackend,
+	}
+	for name, backend := range backends {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			inner := &countingEmbedder{MockClient: NewMockClient(8), model: "model-a"}
+			e := NewCachedEmbedder(logger, inner, backend)
+
+			first, err := e.Embed(ctx, []string{"Hybrid engine", "Panoramic roof", "Hybrid engine"})
+			require.NoError(t, err)
+			assert.Equal(t, []string{"Hybrid engine", "Panoramic roof"}, inner.texts, "duplicates are embedded once")
+			assert.Equal(t, first[0], first[2])
+
+			second, err := e.Embed(ctx, []string{"  Hybrid\n engine ", "Adaptive cruise"})
+			require.NoError(t, err)
+			assert.Equal(t, first[0], second[0], "whitespace differences share a vector")
+			assert.Equal(t, []string{"Hybrid engine", "Panoramic roof", "Adaptive cruise"}, inner.texts)
+
+			stats := e.Stats()
+			assert.Equal(t, int64(1), stats.Hits)
+			assert.Equal(t, int64(3), stats.Misses)
+			assert.InDelta(t, 0.25, stats.HitRate, 1e-9)
+
+			// Another model does not reuse model-a's vectors
+			other := &countingEmbedder{MockClient: NewMockClient(8), model: "model-b"}
+			_, err = NewCachedEmbedder(logger, other, backend).EmbedSingle(ctx, "Hybrid engine")
+			require.NoError(t, err)
+			assert.Equal(t, []string{"Hybrid engine"}, other.texts)
+		})
+	}
+}
+
+func TestCacheKey(t *testing.T) {
+	key := CacheKey("model-a", 768, "Hybrid engine")
+	assert.Equal(t, key, CacheKey("model-a", 768, "Hybrid\tengine\n"))
+	assert.NotEqual(t, key, CacheKey("model-a", 768, "hybrid engine"), "case is meaningful to embeddings")
+	assert.NotEqual(t, key, CacheKey("model-a", 1536, "Hybrid engine"))
+	assert.NotEqual(t, key, CacheKey("model-b", 768, "Hybrid engine"))
+}
+
+func TestVectorEncoding(t *testing.T) {
+	vector := []float32{0, -1.5, 3.25, 1e-7}
+	decoded, err := decodeVector(encodeVector(vector))
+	require.NoError(t, err)
+	assert.Equal(t, vector, decoded)
+
+	_, err = decodeVector([]byte{1, 2, 3})
+	assert.Error(t, err)
+}
+
+// fallbackEmbedder reports the texts in fallback as embedded by another model.
+type fallbackEmbedder struct {
+	*countingEmbedder
+	fallback map[string]bool
+}
+
+func (f *fallbackEmbedder) EmbedModels(ctx context.Context, texts []string) ([][]float32, []string, error) {
+	vectors, err := f.Embed(ctx, texts)
+	if err != nil {
+		return nil, nil, err
+	}
+	models := make([]string, len(texts))
+	for i, text := range texts {
+		models[i] = f.model
+		if f.fallback[text] {
+			models[i] = "fallback-model"
+		}
+	}
+	return vectors, models, nil
+}
+
+func TestCachedEmbedder_SkipsFallbackVectors(t *testing.T) {
+	logger := observability.NewLogger(observability.LogConfig{Level: "error"})
+	ctx := context.Background()
+	inner := &fallbackEmbedder{
+		countingEmbedder: &countingEmbedder{MockClient: NewMockClient(8), model: "model-a"},
+		fallback:         map[string]bool{"Panoramic roof": true},
+	}
+	e := NewCachedEmbedder(logger, inner, NewClientCacheBackend(cache.NewMemoryClient(100), 0))
+
+	_, models, err := e.EmbedModels(ctx, []string{"Hybrid engine", "Panoramic roof"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"model-a", "fallback-model"}, models)
+
+	// The primary recovers: only the fallback's text is embedded again
+	inner.fallback = nil
+	_, models, err = e.EmbedModels(ctx, []string{"Hybrid engine", "Panoramic roof"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"model-a", "model-a"}, models)
+	assert.Equal(t, []string{"Hybrid engine", "Panoramic roof", "Panoramic roof"}, inner.texts)
+}
@@ -8,7 +8,12 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
+	"sync/atomic"
 	"time"
+
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/observability"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // Client provides embedding generation using OpenRouter API.
@@ -17,7 +22,8 @@ type Client struct {
 	baseURL    string
 	apiKey     string
 	model      string
-	dimension  int
+	// dimension is updated from responses while other goroutines read it
+	dimension atomic.Int64
 }
 
 // Config holds embedding client configuration.
@@ -52,13 +58,14 @@ func NewClient(cfg Config) (*Client, error) {
 		timeout = 30 * time.Second
 	}
 
-	return &Client{
+	c := &Client{
 		httpClient: &http.Client{Timeout: timeout},
 		baseURL:    cfg.BaseURL,
 		apiKey:     cfg.APIKey,
 		model:      cfg.Model,
-		dimension:  cfg.Dimension,
-	}, nil
+	}
+	c.dimension.Store(int64(cfg.Dimension))
+	return c, nil
 }
 
 // EmbeddingRequest represents a request to generate embeddings.
@@ -96,11 +103,50 @@ type EmbeddingError struct {
 	Code    string `json:"code"`
 }
 
+// APIError is returned when the embedding API answers with a non-200 status.
+type APIError struct {
+	StatusCode int
+	Message    string
+	// RetryAfter is the delay the API asked for before retrying; zero when unset.
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API error: status %d: %s", e.StatusCode, e.Message)
+}
+
+// Retryable reports whether the request may succeed if sent again: rate
+// limits, timeouts and server errors.
+func (e *APIError) Retryable() bool {
+	return e.StatusCode == http.StatusTooManyRequests ||
+		e.StatusCode == http.StatusRequestTimeout ||
+		e.StatusCode >= 500
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as an HTTP date.
+func parseRetryAfter(header string, now time.Time) time.Duration {
+	if header == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(header); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(header); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+	return 0
+}
+
 // Embed generates embeddings for the given texts.
-func (c *Client) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+func (c *Client) Embed(ctx context.Context, texts []string) (_ [][]float32, err error) {
 	if len(texts) == 0 {
 		return nil, nil
 	}
+	ctx, span := observability.StartSpan(ctx, "embedding.Embed",
+		attribute.String("model", c.model),
+		attribute.Int("texts", len(texts)),
+	)
+	defer func() { observability.EndSpan(span, err) }()
 
 	reqBody := EmbeddingRequest{
 		Input: texts,
@@ -134,11 +180,16 @@ func (c *Client) Embed(ctx context.Context, texts []string) ([][]float32, error)
 	}
 
 	if resp.StatusCode != http.StatusOK {
+		apiErr := &APIError{
+			StatusCode: resp.StatusCode,
+			Message:    string(body),
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		}
 		var errResp EmbeddingResponse
 		if err := json.Unmarshal(body, &errResp); err == nil && errResp.Error != nil {
-			return nil, fmt.Errorf("API error: %s (type: %s)", errResp.Error.Message, errResp.Error.Type)
+			apiErr.Message = fmt.Sprintf("%s (type: %s)", errResp.Error.Message, errResp.Error.Type)
 		}
-		return nil, fmt.Errorf("API error: status %d, body: %s", resp.StatusCode, string(body))
+		return nil, apiErr
 	}
 
 	var embResp EmbeddingResponse
@@ -152,8 +203,8 @@ func (c *Client) Embed(ctx context.Context, texts []string) ([][]float32, error)
 		if data.Index < len(embeddings) {
 			embeddings[data.Index] = data.Embedding
 			// Update dimension from actual API response
-			if len(data.Embedding) > 0 && c.dimension != len(data.Embedding) {
-				c.dimension = len(data.Embedding)
+			if len(data.Embedding) > 0 {
+				c.dimension.Store(int64(len(data.Embedding)))
 			}
 		}
 	}
@@ -206,7 +257,7 @@ func (c *Client) Model() string {
 
 // Dimension returns the embedding dimension.
 func (c *Client) Dimension() int {
-	return c.dimension
+	return int(c.dimension.Load())
 }
 
 // MockClient provides a mock embedding client for testing.
@@ -293,6 +344,32 @@ type Embedder interface {
 	Dimension() int
 }
 
+// ModelEmbedder is implemented by embedders whose vectors may come from more
+// than one model, such as a ResilientEmbedder with a fallback provider.
+type ModelEmbedder interface {
+	Embedder
+	// EmbedModels embeds texts and returns the model that produced each vector.
+	EmbedModels(ctx context.Context, texts []string) ([][]float32, []string, error)
+}
+
+// EmbedModels embeds texts with e and returns the model that produced each
+// vector: e.Model(), unless e is a ModelEmbedder. Callers storing vectors
+// should label them with these models rather than e.Model().
+func EmbedModels(ctx context.Context, e Embedder, texts []string) ([][]float32, []string, error) {
+	if me, ok := e.(ModelEmbedder); ok {
+		return me.EmbedModels(ctx, texts)
+	}
+	vectors, err := e.Embed(ctx, texts)
+	if err != nil {
+		return nil, nil, err
+	}
+	models := make([]string, len(vectors))
+	for i := range models {
+		models[i] = e.Model()
+	}
+	return vectors, models, nil
+}
+
 // Ensure implementations satisfy interface.
 var (
 	_ Embedder = (*Client)(nil)
@@ -0,0 +1,371 @@
+// Package embedding provides an offline embedder for development, CI and air-gapped deployments.
+package embedding
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+)
+
+// LocalModel selects the local embedder through EmbeddingConfig.Model.
+const LocalModel = "local"
+
+// ErrNoLocalFit is returned when no local embedder fit was saved for a tenant or model.
+var ErrNoLocalFit = errors.New("no local embedder fit")
+
+// IsLocalModel reports whether model names the local embedder, fitted or not.
+func IsLocalModel(model string) bool {
+	return model == LocalModel || strings.HasPrefix(model, LocalModel+"@")
+}
+
+// LocalConfig holds local embedder settings.
+type LocalConfig struct {
+	// Dimension is the number of buckets features are hashed into (default 768).
+	Dimension int
+	// MinN and MaxN bound the length of character n-grams (default 3 to 5).
+	MinN int
+	MaxN int
+}
+
+// localStopwords carry no meaning in product questions. Fitting down-weights
+// common words anyway; the list keeps unfitted vectors useful.
+var localStopwords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true,
+	"by": true, "can": true, "do": true, "does": true, "for": true, "from": true,
+	"has": true, "have": true, "how": true, "in": true, "is": true, "it": true,
+	"its": true, "me": true, "of": true, "on": true, "or": true, "the": true,
+	"this": true, "to": true, "what": true, "which": true, "with": true,
+}
+
+// LocalEmbedder embeds text without network access. Words, word pairs and
+// character n-grams are hashed into a fixed number of signed buckets and
+// weighted by TF-IDF, so texts sharing rare words or word stems ("power",
+// "powerful") are similar. Vectors are deterministic for a given fit.
+//
+// Fit learns document frequencies from a tenant's corpus; until then every
+// feature weighs the same. Vectors from different fits are not comparable, so
+// the fit is part of Model.
+type LocalEmbedder struct {
+	dimension int
+	minN      int
+	maxN      int
+
+	mu          sync.RWMutex
+	docs        int
+	df          map[uint64]int
+	fingerprint string
+}
+
+// NewLocalEmbedder creates an unfitted local embedder.
+func NewLocalEmbedder(cfg LocalConfig) *LocalEmbedder {
+	if cfg.Dimension <= 0 {
+		cfg.Dimension = 768
+	}
+	if cfg.MinN <= 0 {
+		cfg.MinN = 3
+	}
+	if cfg.MaxN < cfg.MinN {
+		cfg.MaxN = cfg.MinN + 2
+	}
+	return &LocalEmbedder{dimension: cfg.Dimension, minN: cfg.MinN, maxN: cfg.MaxN}
+}
+
+// Fit learns feature document frequencies from corpus, replacing any earlier
+// fit. Texts embedded before and after a fit should not share an index; save
+// the fit with a LocalFitStore so every process embeds with it.
+func (e *LocalEmbedder) Fit(corpus []string) {
+	df := make(map[uint64]int)
+	for _, text := range corpus {
+		for h := range e.features(text) {
+			df[h]++
+		}
+	}
+	e.setFit(len(corpus), df)
+}
+
+// setFit installs document frequencies learned from docs texts.
+func (e *LocalEmbedder) setFit(docs int, df map[uint64]int) {
+	// The fingerprint identifies the fit so vectors of different fits never mix
+	keys := make([]uint64, 0, len(df))
+	for h := range df {
+		keys = append(keys, h)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	sum := sha256.New()
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], uint64(docs))
+	sum.Write(buf[:])
+	for _, h := range keys {
+		binary.LittleEndian.PutUint64(buf[:], h)
+		sum.Write(buf[:])
+		binary.LittleEndian.PutUint64(buf[:], uint64(df[h]))
+		sum.Write(buf[:])
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.docs = docs
+	e.df = df
+	e.fingerprint = hex.EncodeToString(sum.Sum(nil))[:12]
+}
+
+// localFitVersion is the encoding version written by MarshalBinary.
+const localFitVersion = 1
+
+// MarshalBinary encodes the embedder's settings and fit.
+func (e *LocalEmbedder) MarshalBinary() ([]byte, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	keys := make([]uint64, 0, len(e.df))
+	for h := range e.df {
+		keys = append(keys, h)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+	var fitted uint64
+	if e.df != nil {
+		fitted = 1
+	}
+	buf := make([]byte, 0, 8*(6+2*len(keys)))
+	for _, v := range []uint64{localFitVersion, uint64(e.dimension), uint64(e.minN), uint64(e.maxN), fitted, uint64(e.docs)} {
+		buf = binary.LittleEndian.AppendUint64(buf, v)
+	}
+	for _, h := range keys {
+		buf = binary.LittleEndian.AppendUint64(buf, h)
+		buf = binary.LittleEndian.AppendUint64(buf, uint64(e.df[h]))
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary restores settings and a fit written by MarshalBinary,
+// replacing the embedder's own, so it embeds exactly as the saved one did.
+func (e *LocalEmbedder) UnmarshalBinary(data []byte) error {
+	if len(data) < 48 || len(data)%16 != 0 {
+		return fmt.Errorf("corrupt local embedder fit of %d bytes", len(data))
+	}
+	if version := binary.LittleEndian.Uint64(data); version != localFitVersion {
+		return fmt.Errorf("unsupported local embedder fit version %d", version)
+	}
+	dimension := int(binary.LittleEndian.Uint64(data[8:]))
+	minN := int(binary.LittleEndian.Uint64(data[16:]))
+	maxN := int(binary.LittleEndian.Uint64(data[24:]))
+	fitted := binary.LittleEndian.Uint64(data[32:]) == 1
+	docs := int(binary.LittleEndian.Uint64(data[40:]))
+	if dimension <= 0 || minN <= 0 || maxN < minN {
+		return fmt.Errorf("corrupt local embedder fit settings")
+	}
+
+	var df map[uint64]int
+	if fitted {
+		df = make(map[uint64]int, (len(data)-48)/16)
+		for i := 48; i < len(data); i += 16 {
+			df[binary.LittleEndian.Uint64(data[i:])] = int(binary.LittleEndian.Uint64(data[i+8:]))
+		}
+	}
+
+	e.mu.Lock()
+	e.dimension, e.minN, e.maxN = dimension, minN, maxN
+	e.docs, e.df, e.fingerprint = 0, nil, ""
+	e.mu.Unlock()
+	if df != nil {
+		e.setFit(docs, df)
+	}
+	return nil
+}
+
+// Embed generates embeddings for the given texts.
+func (e *LocalEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	embeddings := make([][]float32, len(texts))
+	for i, text := range texts {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		vector := make([]float32, e.dimension)
+		for h, feature := range e.features(text) {
+			weight := (1 + math.Log(feature.count)) * feature.weight * e.idf(h)
+			bucket := int(h % uint64(e.dimension))
+			if h>>63 == 1 {
+				weight = -weight
+			}
+			vector[bucket] += float32(weight)
+		}
+		embeddings[i] = normalize(vector)
+	}
+	return embeddings, nil
+}
+
+// EmbedSingle generates an embedding for a single text.
+func (e *LocalEmbedder) EmbedSingle(ctx context.Context, text string) ([]float32, error) {
+	embeddings, err := e.Embed(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return embeddings[0], nil
+}
+
+// Model returns "local", followed by the fit's fingerprint once fitted.
+func (e *LocalEmbedder) Model() string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.fingerprint == "" {
+		return LocalModel
+	}
+	return LocalModel + "@" + e.fingerprint
+}
+
+// Dimension returns the embedding dimension.
+func (e *LocalEmbedder) Dimension() int {
+	return e.dimension
+}
+
+// idf returns a feature's smoothed inverse document frequency; 1 when unfitted.
+func (e *LocalEmbedder) idf(h uint64) float64 {
+	if e.df == nil {
+		return 1
+	}
+	return math.Log(float64(1+e.docs)/float64(1+e.df[h])) + 1
+}
+
+// localFeature is a feature's occurrences in a text and the weight of its kind.
+type localFeature struct {
+	count  float64
+	weight float64
+}
+
+// features extracts a text's hashed features: words and adjacent word pairs,
+// and at half weight the character n-grams of each word, which match
+// inflections and compounds the words alone miss.
+func (e *LocalEmbedder) features(text string) map[uint64]*localFeature {
+	features := make(map[uint64]*localFeature)
+	add := func(kind, value string, weight float64) {
+		h := hashFeature(kind, value)
+		if f, ok := features[h]; ok {
+			f.count++
+			return
+		}
+		features[h] = &localFeature{count: 1, weight: weight}
+	}
+
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	previous := ""
+	for _, word := range words {
+		if localStopwords[word] {
+			previous = ""
+			continue
+		}
+		add("w", word, 1)
+		if previous != "" {
+			add("b", previous+" "+word, 1)
+		}
+		previous = word
+
+		runes := []rune("<" + word + ">")
+		for n := e.minN; n <= e.maxN && n <= len(runes); n++ {
+			for start := 0; start+n <= len(runes); start++ {
+				add("c", string(runes[start:start+n]), 0.5)
+			}
+		}
+	}
+	return features
+}
+
+// hashFeature hashes a feature of a kind; the top bit later signs its bucket,
+// so colliding features tend to cancel rather than accumulate.
+func hashFeature(kind, value string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(kind))
+	h.Write([]byte{0})
+	h.Write([]byte(value))
+	return h.Sum64()
+}
+
+// LocalFitStore keeps local embedder fits in a SQLite table, so ingestion and
+// queries in separate processes embed a tenant's texts with the same fit.
+// Saving a fit makes it the tenant's current one; vectors of earlier fits
+// must be re-embedded, since the fit is part of the model.
+type LocalFitStore struct {
+	db *sql.DB
+}
+
+// NewLocalFitStore stores fits in db, creating the local_embedder_fits table if needed.
+func NewLocalFitStore(ctx context.Context, db *sql.DB) (*LocalFitStore, error) {
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS local_embedder_fits (
+			tenant_id TEXT NOT NULL,
+			model TEXT NOT NULL,
+			fit BLOB NOT NULL,
+			created_at TIMESTAMP NOT NULL,
+			PRIMARY KEY (tenant_id, model)
+		)
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("create local_embedder_fits table: %w", err)
+	}
+	return &LocalFitStore{db: db}, nil
+}
+
+// Save records e's fit as the tenant's current one.
+func (s *LocalFitStore) Save(ctx context.Context, tenantID uuid.UUID, e *LocalEmbedder) error {
+	data, err := e.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx,
+		"INSERT OR REPLACE INTO local_embedder_fits (tenant_id, model, fit, created_at) VALUES (?, ?, ?, ?)",
+		tenantID.String(), e.Model(), data, time.Now())
+	if err != nil {
+		return fmt.Errorf("save local embedder fit: %w", err)
+	}
+	return nil
+}
+
+// Current returns an embedder with the tenant's current fit, or ErrNoLocalFit.
+func (s *LocalFitStore) Current(ctx context.Context, tenantID uuid.UUID) (*LocalEmbedder, error) {
+	return s.load(ctx,
+		"SELECT fit FROM local_embedder_fits WHERE tenant_id = ? ORDER BY created_at DESC LIMIT 1",
+		tenantID.String())
+}
+
+// Model returns an embedder with the fit a model name refers to, such as a
+// re-embedding job's, or ErrNoLocalFit.
+func (s *LocalFitStore) Model(ctx context.Context, model string) (*LocalEmbedder, error) {
+	return s.load(ctx, "SELECT fit FROM local_embedder_fits WHERE model = ? LIMIT 1", model)
+}
+
+func (s *LocalFitStore) load(ctx context.Context, query string, arg string) (*LocalEmbedder, error) {
+	var data []byte
+	err := s.db.QueryRowContext(ctx, query, arg).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNoLocalFit
+	}
+	if err != nil {
+		return nil, fmt.Errorf("load local embedder fit: %w", err)
+	}
+	e := NewLocalEmbedder(LocalConfig{})
+	if err := e.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Ensure the local embedder satisfies the interface.
+var _ Embedder = (*LocalEmbedder)(nil)
@@ -0,0 +1,180 @@
+package embedding
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var localCorpus = []string{
+	"Camry engine: 2.5L four-cylinder hybrid delivering 225 horsepower",
+	"Camry towing capacity is 1,000 lbs when properly equipped",
+	"Camry heated and ventilated front seats with leather trim",
+	"Camry panoramic glass roof with power sunshade",
+	"Camry fuel economy of 51 mpg city and 53 mpg highway",
+}
+
+func dot(a, b []float32) float64 {
+	var sum float64
+	for i := range a {
+		sum += float64(a[i]) * float64(b[i])
+	}
+	return sum
+}
+
+// nearest returns the index of the corpus text most similar to question.
+func nearest(t *testing.T, e Embedder, question string) int {
+	ctx := context.Background()
+	docs, err := e.Embed(ctx, localCorpus)
+	require.NoError(t, err)
+	query, err := e.EmbedSingle(ctx, question)
+	require.NoError(t, err)
+
+	best, bestScore := -1, -2.0
+	for i, doc := range docs {
+		if score := dot(query, doc); score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+	return best
+}
+
+func TestLocalEmbedder_Deterministic(t *testing.T) {
+	ctx := context.Background()
+	a, err := NewLocalEmbedder(LocalConfig{Dimension: 256}).EmbedSingle(ctx, "Heated seats")
+	require.NoError(t, err)
+	b, err := NewLocalEmbedder(LocalConfig{Dimension: 256}).EmbedSingle(ctx, "Heated seats")
+	require.NoError(t, err)
+
+	assert.Len(t, a, 256)
+	assert.Equal(t, a, b)
+	assert.InDelta(t, 1.0, dot(a, a), 1e-5)
+}
+
+func TestLocalEmbedder_Similarity(t *testing.T) {
+	unfitted := NewLocalEmbedder(LocalConfig{Dimension: 512})
+	fitted := NewLocalEmbedder(LocalConfig{Dimension: 512})
+	fitted.Fit(localCorpus)
+
+	questions := map[string]int{
+		"How much horsepower does the engine make?": 0,
+		"What can it tow?":                          1,
+		"Are the seats heated?":                     2,
+		"Does it have a sunroof?":                   3,
+		"What is the fuel economy?":                 4,
+	}
+	for question, want := range questions {
+		assert.Equal(t, want, nearest(t, fitted, question), "fitted: %s", question)
+	}
+	assert.Equal(t, 0, nearest(t, unfitted, "engine horsepower"))
+}
+
+func TestLocalEmbedder_FitChangesModel(t *testing.T) {
+	e := NewLocalEmbedder(LocalConfig{})
+	assert.Equal(t, LocalModel, e.Model())
+	assert.Equal(t, 768, e.Dimension())
+
+	e.Fit(localCorpus)
+	fitted := e.Model()
+	assert.True(t, IsLocalModel(fitted))
+	assert.NotEqual(t, LocalModel, fitted)
+
+	again := NewLocalEmbedder(LocalConfig{})
+	again.Fit(localCorpus)
+	assert.Equal(t, fitted, again.Model(), "the same corpus gives the same fit")
+
+	again.Fit(localCorpus[:2])
+	assert.NotEqual(t, fitted, again.Model())
+
+	assert.False(t, IsLocalModel("google/gemini-embedding-001"))
+	assert.False(t, IsLocalModel("localized"))
+}
+
+func TestLocalEmbedder_MarshalBinary(t *testing.T) {
+	ctx := context.Background()
+	fitted := NewLocalEmbedder(LocalConfig{Dimension: 256, MinN: 2, MaxN: 4})
+	fitted.Fit(localCorpus)
+	data, err := fitted.MarshalBinary()
+	require.NoError(t, err)
+
+	restored := NewLocalEmbedder(LocalConfig{})
+	require.NoError(t, restored.UnmarshalBinary(data))
+	assert.Equal(t, fitted.Model(), restored.Model())
+	assert.Equal(t, 256, restored.Dimension())
+
+	want, err := fitted.EmbedSingle(ctx, "What can it tow?")
+	require.NoError(t, err)
+	got, err := restored.EmbedSingle(ctx, "What can it tow?")
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+
+	t.Run("unfitted", func(t *testing.T) {
+		data, err := NewLocalEmbedder(LocalConfig{Dimension: 128}).MarshalBinary()
+		require.NoError(t, err)
+		require.NoError(t, restored.UnmarshalBinary(data))
+		assert.Equal(t, LocalModel, restored.Model())
+		assert.Equal(t, 128, restored.Dimension())
+	})
+
+	assert.Error(t, restored.UnmarshalBinary(data[:len(data)-3]))
+}
+
+func TestLocalFitStore_IngestThenQuery(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "knowledge.db")
+	tenant, other := uuid.New(), uuid.New()
+
+	// Ingest fits and saves, then embeds the corpus
+	ingestDB, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	defer ingestDB.Close()
+	fits, err := NewLocalFitStore(ctx, ingestDB)
+	require.NoError(t, err)
+	_, err = fits.Current(ctx, tenant)
+	assert.ErrorIs(t, err, ErrNoLocalFit)
+
+	fitted := NewLocalEmbedder(LocalConfig{Dimension: 512})
+	fitted.Fit(localCorpus)
+	require.NoError(t, fits.Save(ctx, tenant, fitted))
+	docs, err := fitted.Embed(ctx, localCorpus)
+	require.NoError(t, err)
+
+	// A separate query process loads the same fit
+	queryDB, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	defer queryDB.Close()
+	queryFits, err := NewLocalFitStore(ctx, queryDB)
+	require.NoError(t, err)
+	query, err := queryFits.Current(ctx, tenant)
+	require.NoError(t, err)
+	assert.Equal(t, fitted.Model(), query.Model(), "queries embed with the ingest fit")
+
+	vector, err := query.EmbedSingle(ctx, "What can it tow?")
+	require.NoError(t, err)
+	best, bestScore := -1, -2.0
+	for i, doc := range docs {
+		if score := dot(vector, doc); score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+	assert.Equal(t, 1, best)
+
+	byModel, err := queryFits.Model(ctx, fitted.Model())
+	require.NoError(t, err)
+	assert.Equal(t, fitted.Model(), byModel.Model())
+
+	_, err = queryFits.Current(ctx, other)
+	assert.ErrorIs(t, err, ErrNoLocalFit, "fits are per tenant")
+
+	refit := NewLocalEmbedder(LocalConfig{Dimension: 512})
+	refit.Fit(localCorpus[:3])
+	require.NoError(t, fits.Save(ctx, tenant, refit))
+	current, err := queryFits.Current(ctx, tenant)
+	require.NoError(t, err)
+	assert.Equal(t, refit.Model(), current.Model(), "the latest fit is current")
+}
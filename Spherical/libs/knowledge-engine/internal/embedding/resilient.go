@@ -0,0 +1,379 @@
+// Package embedding provides a resilient wrapper around embedding providers.
+package embedding
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/observability"
+)
+
+// ErrCircuitOpen is returned while the primary provider's circuit breaker is
+// open and no fallback provider is configured.
+var ErrCircuitOpen = errors.New("embedding circuit breaker is open")
+
+// ResilientConfig holds retry, rate limiting and circuit breaker settings.
+type ResilientConfig struct {
+	// MaxRetries is the number of retries after the first attempt of a batch.
+	MaxRetries int
+	// InitialBackoff is the delay before the first retry; it doubles per retry.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the backoff delay. A longer Retry-After is still honoured.
+	MaxBackoff time.Duration
+	// RequestsPerSecond limits calls to each provider; zero disables the limit.
+	RequestsPerSecond float64
+	// Burst is the number of calls allowed at once before the limit applies.
+	Burst int
+	// MaxBatchSize splits larger inputs into several calls.
+	MaxBatchSize int
+	// BreakerThreshold is the number of consecutive failed batches that opens the breaker.
+	BreakerThreshold int
+	// BreakerCooldown is how long the breaker stays open before a trial call.
+	BreakerCooldown time.Duration
+}
+
+// DefaultResilientConfig returns default resilience settings.
+func DefaultResilientConfig() ResilientConfig {
+	return ResilientConfig{
+		MaxRetries:       4,
+		InitialBackoff:   500 * time.Millisecond,
+		MaxBackoff:       30 * time.Second,
+		Burst:            1,
+		MaxBatchSize:     100,
+		BreakerThreshold: 5,
+		BreakerCooldown:  30 * time.Second,
+	}
+}
+
+// ResilientEmbedder retries transient provider failures with exponential
+// backoff and jitter, honours Retry-After, rate limits calls, splits large
+// inputs into batches and stops calling a failing provider until it recovers.
+// Batches the primary provider cannot embed are sent to the fallback provider,
+// which must produce vectors of the same dimension; EmbedModels reports which
+// model embedded each text.
+type ResilientEmbedder struct {
+	logger   *observability.Logger
+	primary  Embedder
+	fallback Embedder
+	cfg      ResilientConfig
+
+	primaryLimiter  *tokenBucket
+	fallbackLimiter *tokenBucket
+	breaker         *circuitBreaker
+
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+// NewResilientEmbedder wraps primary with retries, rate limiting and a circuit breaker.
+func NewResilientEmbedder(logger *observability.Logger, primary Embedder, cfg ResilientConfig) *ResilientEmbedder {
+	defaults := DefaultResilientConfig()
+	if cfg.MaxRetries < 0 {
+		cfg.MaxRetries = 0
+	}
+	if cfg.InitialBackoff <= 0 {
+		cfg.InitialBackoff = defaults.InitialBackoff
+	}
+	if cfg.MaxBackoff < cfg.InitialBackoff {
+		cfg.MaxBackoff = cfg.InitialBackoff
+	}
+	if cfg.Burst <= 0 {
+		cfg.Burst = defaults.Burst
+	}
+	if cfg.MaxBatchSize <= 0 {
+		cfg.MaxBatchSize = defaults.MaxBatchSize
+	}
+	if cfg.BreakerThreshold <= 0 {
+		cfg.BreakerThreshold = defaults.BreakerThreshold
+	}
+	if cfg.BreakerCooldown <= 0 {
+		cfg.BreakerCooldown = defaults.BreakerCooldown
+	}
+
+	return &ResilientEmbedder{
+		logger:          logger,
+		primary:         primary,
+		cfg:             cfg,
+		primaryLimiter:  newTokenBucket(cfg.RequestsPerSecond, cfg.Burst),
+		fallbackLimiter: newTokenBucket(cfg.RequestsPerSecond, cfg.Burst),
+		breaker:         newCircuitBreaker(cfg.BreakerThreshold, cfg.BreakerCooldown),
+		rng:             rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// SetFallback sets a secondary provider for batches the primary cannot embed.
+func (e *ResilientEmbedder) SetFallback(fallback Embedder) {
+	e.fallback = fallback
+}
+
+// Embed generates embeddings for the given texts, in batches of at most MaxBatchSize.
+func (e *ResilientEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	embeddings, _, err := e.EmbedModels(ctx, texts)
+	return embeddings, err
+}
+
+// EmbedModels generates embeddings like Embed and returns the model of the
+// provider that embedded each text: the primary, or the fallback for batches
+// the primary could not embed.
+func (e *ResilientEmbedder) EmbedModels(ctx context.Context, texts []string) ([][]float32, []string, error) {
+	if len(texts) == 0 {
+		return nil, nil, nil
+	}
+
+	embeddings := make([][]float32, 0, len(texts))
+	models := make([]string, 0, len(texts))
+	for start := 0; start < len(texts); start += e.cfg.MaxBatchSize {
+		end := start + e.cfg.MaxBatchSize
+		if end > len(texts) {
+			end = len(texts)
+		}
+
+		batch, provider, err := e.embedBatch(ctx, texts[start:end])
+		if err != nil {
+			return nil, nil, fmt.Errorf("batch %d-%d: %w", start, end, err)
+		}
+		embeddings = append(embeddings, batch...)
+		for range batch {
+			models = append(models, provider.Model())
+		}
+	}
+	return embeddings, models, nil
+}
+
+// embedBatch embeds one batch with the primary provider, falling back to the
+// secondary provider when the primary fails or its breaker is open. It returns
+// the provider that produced the embeddings.
+func (e *ResilientEmbedder) embedBatch(ctx context.Context, texts []string) ([][]float32, Embedder, error) {
+	var primaryErr error
+	if e.breaker.allow() {
+		embeddings, err := e.withRetries(ctx, e.primary, e.primaryLimiter, texts)
+		if err == nil {
+			e.breaker.record(true)
+			return embeddings, e.primary, nil
+		}
+		// Rejected or cancelled requests say nothing about the provider's health
+		if ctx.Err() == nil && retryable(err) {
+			e.breaker.record(false)
+		} else {
+			e.breaker.release()
+		}
+		primaryErr = err
+	} else {
+		primaryErr = ErrCircuitOpen
+	}
+
+	if e.fallback == nil || ctx.Err() != nil {
+		return nil, nil, primaryErr
+	}
+
+	e.logger.Warn().Err(primaryErr).Int("texts", len(texts)).Str("model", e.fallback.Model()).Msg("Primary embedding provider failed, using fallback")
+	embeddings, err := e.withRetries(ctx, e.fallback, e.fallbackLimiter, texts)
+	if err != nil {
+		return nil, nil, fmt.Errorf("primary: %v; fallback: %w", primaryErr, err)
+	}
+	if dim := e.primary.Dimension(); dim > 0 && len(embeddings) > 0 && len(embeddings[0]) != dim {
+		return nil, nil, fmt.Errorf("fallback returned %d dimensions, primary uses %d", len(embeddings[0]), dim)
+	}
+	return embeddings, e.fallback, nil
+}
+
+// withRetries calls the provider until it succeeds, fails permanently or runs out of retries.
+func (e *ResilientEmbedder) withRetries(ctx context.Context, provider Embedder, limiter *tokenBucket, texts []string) ([][]float32, error) {
+	for attempt := 0; ; attempt++ {
+		if err := limiter.wait(ctx); err != nil {
+			return nil, err
+		}
+
+		embeddings, err := provider.Embed(ctx, texts)
+		if err == nil {
+			if len(embeddings) != len(texts) {
+				return nil, fmt.Errorf("provider returned %d embeddings for %d texts", len(embeddings), len(texts))
+			}
+			return embeddings, nil
+		}
+		if ctx.Err() != nil || !retryable(err) || attempt >= e.cfg.MaxRetries {
+			return nil, err
+		}
+
+		delay := e.backoff(attempt)
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.RetryAfter > delay {
+			delay = apiErr.RetryAfter
+		}
+		e.logger.Debug().Err(err).Int("attempt", attempt+1).Dur("delay", delay).Str("model", provider.Model()).Msg("Retrying embedding request")
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// backoff returns the delay before a retry: exponential growth capped at
+// MaxBackoff, with the upper half jittered so clients do not retry in step.
+func (e *ResilientEmbedder) backoff(attempt int) time.Duration {
+	delay := e.cfg.InitialBackoff
+	for i := 0; i < attempt && delay < e.cfg.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > e.cfg.MaxBackoff {
+		delay = e.cfg.MaxBackoff
+	}
+
+	half := delay / 2
+	e.mu.Lock()
+	jitter := time.Duration(e.rng.Int63n(int64(half) + 1))
+	e.mu.Unlock()
+	return half + jitter
+}
+
+// retryable reports whether an error is transient. API errors say so
+// themselves; other errors come from the transport, including client
+// timeouts, and are retried.
+func retryable(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Retryable()
+	}
+	return true
+}
+
+// EmbedSingle generates an embedding for a single text.
+func (e *ResilientEmbedder) EmbedSingle(ctx context.Context, text string) ([]float32, error) {
+	embeddings, err := e.Embed(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	if len(embeddings) == 0 {
+		return nil, fmt.Errorf("no embedding returned")
+	}
+	return embeddings[0], nil
+}
+
+// Model returns the primary provider's model. Vectors from the fallback
+// provider are labelled with its model by EmbedModels.
+func (e *ResilientEmbedder) Model() string {
+	return e.primary.Model()
+}
+
+// Dimension returns the primary provider's embedding dimension.
+func (e *ResilientEmbedder) Dimension() int {
+	return e.primary.Dimension()
+}
+
+// tokenBucket limits the rate of calls, allowing short bursts.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// newTokenBucket creates a bucket refilled at rate tokens per second; a
+// non-positive rate never blocks.
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	return &tokenBucket{rate: rate, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+// wait blocks until a token is available or the context is done.
+func (b *tokenBucket) wait(ctx context.Context) error {
+	if b.rate <= 0 {
+		return nil
+	}
+	for {
+		b.mu.Lock()
+		now := time.Now()
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+		b.last = now
+		if b.tokens >= 1 {
+			b.tokens--
+			b.mu.Unlock()
+			return nil
+		}
+		delay := time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+		b.mu.Unlock()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// circuitBreaker stops calls after consecutive failures and, once the
+// cooldown has passed, lets a single trial call decide whether to resume.
+type circuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openUntil time.Time
+	trial     bool
+	now       func() time.Time
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{threshold: threshold, cooldown: cooldown, now: time.Now}
+}
+
+// allow reports whether a call may be made.
+func (b *circuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < b.threshold {
+		return true
+	}
+	if b.trial || b.now().Before(b.openUntil) {
+		return false
+	}
+	b.trial = true
+	return true
+}
+
+// record notes the outcome of an allowed call.
+func (b *circuitBreaker) record(success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.trial = false
+	if success {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.failures >= b.threshold {
+		b.openUntil = b.now().Add(b.cooldown)
+	}
+}
+
+// release ends an allowed call whose outcome does not reflect the provider's health.
+func (b *circuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trial = false
+}
+
+// open reports whether calls are currently refused.
+func (b *circuitBreaker) open() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.failures >= b.threshold && (b.trial || b.now().Before(b.openUntil))
+}
+
+// Ensure the wrapper satisfies the interface.
+var _ ModelEmbedder = (*ResilientEmbedder)(nil)
@@ -0,0 +1,209 @@
+package embedding
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/observability"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// provider is an httptest stand-in for an OpenAI-compatible embeddings API.
+// Each response fails with the next queued status, then succeeds with a
+// two-dimensional vector of the text's length and the provider's marker.
+type provider struct {
+	mu         sync.Mutex
+	failures   []int
+	retryAfter string
+	marker     float32
+	batches    [][]string
+}
+
+func (p *provider) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req EmbeddingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	p.mu.Lock()
+	p.batches = append(p.batches, req.Input)
+	status := http.StatusOK
+	if len(p.failures) > 0 {
+		status, p.failures = p.failures[0], p.failures[1:]
+	}
+	p.mu.Unlock()
+
+	if status != http.StatusOK {
+		if p.retryAfter != "" {
+			w.Header().Set("Retry-After", p.retryAfter)
+		}
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(EmbeddingResponse{Error: &EmbeddingError{Message: http.StatusText(status), Type: "test"}})
+		return
+	}
+
+	resp := EmbeddingResponse{Object: "list", Model: req.Model}
+	for i, text := range req.Input {
+		resp.Data = append(resp.Data, EmbeddingData{Index: i, Embedding: []float32{float32(len(text)), p.marker}})
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (p *provider) calls() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.batches)
+}
+
+func newTestEmbedder(t *testing.T, p *provider, cfg ResilientConfig) *ResilientEmbedder {
+	server := httptest.NewServer(p)
+	t.Cleanup(server.Close)
+	client, err := NewClient(Config{APIKey: "test", BaseURL: server.URL, Dimension: 2})
+	require.NoError(t, err)
+	logger := observability.NewLogger(observability.LogConfig{Level: "error"})
+	return NewResilientEmbedder(logger, client, cfg)
+}
+
+func fastConfig() ResilientConfig {
+	return ResilientConfig{
+		MaxRetries:       3,
+		InitialBackoff:   time.Millisecond,
+		MaxBackoff:       5 * time.Millisecond,
+		MaxBatchSize:     100,
+		BreakerThreshold: 2,
+		BreakerCooldown:  time.Hour,
+	}
+}
+
+func TestResilientEmbedder_RetriesTransientErrors(t *testing.T) {
+	p := &provider{failures: []int{http.StatusServiceUnavailable, http.StatusTooManyRequests}}
+	e := newTestEmbedder(t, p, fastConfig())
+
+	embeddings, err := e.Embed(context.Background(), []string{"power", "torque"})
+	require.NoError(t, err)
+	assert.Equal(t, [][]float32{{5, 0}, {6, 0}}, embeddings)
+	assert.Equal(t, 3, p.calls())
+}
+
+func TestResilientEmbedder_DoesNotRetryClientErrors(t *testing.T) {
+	p := &provider{failures: []int{http.StatusBadRequest}}
+	e := newTestEmbedder(t, p, fastConfig())
+
+	_, err := e.Embed(context.Background(), []string{"power"})
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+	assert.Equal(t, 1, p.calls())
+	assert.False(t, e.breaker.open(), "rejected requests do not trip the breaker")
+}
+
+func TestResilientEmbedder_HonoursRetryAfter(t *testing.T) {
+	p := &provider{failures: []int{http.StatusTooManyRequests}, retryAfter: "1"}
+	e := newTestEmbedder(t, p, fastConfig())
+
+	start := time.Now()
+	_, err := e.Embed(context.Background(), []string{"power"})
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), time.Second)
+}
+
+func TestResilientEmbedder_SplitsBatches(t *testing.T) {
+	p := &provider{}
+	cfg := fastConfig()
+	cfg.MaxBatchSize = 2
+	e := newTestEmbedder(t, p, cfg)
+
+	embeddings, err := e.Embed(context.Background(), []string{"a", "bb", "ccc", "dddd", "eeeee"})
+	require.NoError(t, err)
+	require.Len(t, embeddings, 5)
+	for i, v := range embeddings {
+		assert.Equal(t, float32(i+1), v[0], "order is preserved across batches")
+	}
+	assert.Equal(t, [][]string{{"a", "bb"}, {"ccc", "dddd"}, {"eeeee"}}, p.batches)
+}
+
+func TestResilientEmbedder_RateLimits(t *testing.T) {
+	p := &provider{}
+	cfg := fastConfig()
+	cfg.MaxBatchSize = 1
+	cfg.RequestsPerSecond = 20
+	cfg.Burst = 1
+	e := newTestEmbedder(t, p, cfg)
+
+	start := time.Now()
+	_, err := e.Embed(context.Background(), []string{"a", "b", "c"})
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 90*time.Millisecond, "two calls wait for a token at 20/s")
+}
+
+func TestResilientEmbedder_CircuitBreakerAndFallback(t *testing.T) {
+	down := http.StatusBadGateway
+	primary := &provider{failures: []int{down, down, down, down, down, down, down, down}}
+	cfg := fastConfig()
+	cfg.MaxRetries = 1
+	e := newTestEmbedder(t, primary, cfg)
+	ctx := context.Background()
+
+	for i := 0; i < cfg.BreakerThreshold; i++ {
+		_, err := e.Embed(ctx, []string{"power"})
+		require.Error(t, err)
+	}
+	assert.True(t, e.breaker.open())
+	assert.Equal(t, 4, primary.calls())
+
+	_, err := e.Embed(ctx, []string{"power"})
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, 4, primary.calls(), "an open breaker does not call the provider")
+
+	secondary := &provider{marker: 1}
+	server := httptest.NewServer(secondary)
+	defer server.Close()
+	fallback, err := NewClient(Config{APIKey: "test", Model: "fallback-model", BaseURL: server.URL, Dimension: 2})
+	require.NoError(t, err)
+	e.SetFallback(fallback)
+
+	embeddings, models, err := e.EmbedModels(ctx, []string{"power"})
+	require.NoError(t, err)
+	assert.Equal(t, [][]float32{{5, 1}}, embeddings)
+	assert.Equal(t, []string{"fallback-model"}, models, "fallback vectors carry the fallback's model")
+	assert.NotEqual(t, "fallback-model", e.Model())
+
+	// After the cooldown a single trial call closes the breaker again
+	e.breaker.now = func() time.Time { return time.Now().Add(2 * cfg.BreakerCooldown) }
+	primary.mu.Lock()
+	primary.failures = nil
+	primary.mu.Unlock()
+	embeddings, models, err = EmbedModels(ctx, e, []string{"power"})
+	require.NoError(t, err)
+	assert.Equal(t, [][]float32{{5, 0}}, embeddings)
+	assert.Equal(t, []string{e.Model()}, models)
+	assert.False(t, e.breaker.open())
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	assert.Equal(t, 3*time.Second, parseRetryAfter("3", now))
+	assert.Equal(t, 90*time.Second, parseRetryAfter(now.Add(90*time.Second).Format(http.TimeFormat), now))
+	assert.Zero(t, parseRetryAfter("", now))
+	assert.Zero(t, parseRetryAfter("soon", now))
+}
+
+func TestResilientEmbedder_Backoff(t *testing.T) {
+	e := NewResilientEmbedder(observability.NewLogger(observability.LogConfig{Level: "error"}), NewMockClient(4), ResilientConfig{
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     time.Second,
+	})
+	for attempt, ceiling := range []time.Duration{100, 200, 400, 800, 1000, 1000} {
+		ceiling *= time.Millisecond
+		delay := e.backoff(attempt)
+		assert.GreaterOrEqual(t, delay, ceiling/2)
+		assert.LessOrEqual(t, delay, ceiling)
+	}
+}
@@ -0,0 +1,519 @@
+// Package ingest provides campaign cloning and inheritance for the Knowledge Engine.
+package ingest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/observability"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/storage"
+)
+
+var (
+	// ErrNoBaseCampaign indicates an override was requested on a variant without a base.
+	ErrNoBaseCampaign = errors.New("campaign has no base variant")
+	// ErrSpecNotInherited indicates the overridden spec item is not part of the base chain.
+	ErrSpecNotInherited = errors.New("spec item is not inherited from base")
+	// ErrBlockNotInherited indicates the overridden feature block is not part of the base chain.
+	ErrBlockNotInherited = errors.New("feature block is not inherited from base")
+)
+
+// CloneMode selects how a cloned variant relates to its source.
+type CloneMode string
+
+const (
+	// CloneModeCopy copies the source's effective content into an independent variant.
+	CloneModeCopy CloneMode = "copy"
+	// CloneModeInherit creates an empty variant that inherits from the source.
+	CloneModeInherit CloneMode = "inherit"
+)
+
+// Cloner creates campaign variants from existing ones and manages overrides.
+// Each operation, including its lineage events, runs in one transaction.
+type Cloner struct {
+	logger *observability.Logger
+	db     storage.TxDB
+}
+
+// CloneRequest represents a request to clone a campaign into a new variant.
+type CloneRequest struct {
+	TenantID         uuid.UUID
+	SourceCampaignID uuid.UUID
+	Mode             CloneMode
+	// Locale, Trim and Market override the source's values when set.
+	Locale   string
+	Trim     *string
+	Market   *string
+	Operator string
+}
+
+// CloneResult represents the result of a clone operation.
+type CloneResult struct {
+	CampaignID     uuid.UUID
+	SourceID       uuid.UUID
+	Mode           CloneMode
+	SpecsCopied    int
+	FeaturesCopied int
+	Duration       time.Duration
+}
+
+// OverrideSpecRequest replaces an inherited spec value in a derived variant.
+type OverrideSpecRequest struct {
+	TenantID     uuid.UUID
+	CampaignID   uuid.UUID
+	SpecItemID   uuid.UUID
+	ValueNumeric *float64
+	ValueText    *string
+	Unit         *string
+	SourceDocID  *uuid.UUID
+	SourcePage   *int
+	Operator     string
+}
+
+// OverrideFeatureRequest replaces an inherited feature block in a derived variant.
+type OverrideFeatureRequest struct {
+	TenantID    uuid.UUID
+	CampaignID  uuid.UUID
+	BaseBlockID uuid.UUID
+	Body        string
+	Tags        []string
+	SourceDocID *uuid.UUID
+	SourcePage  *int
+	Operator    string
+}
+
+// NewCloner creates a new Cloner.
+func NewCloner(logger *observability.Logger, db storage.TxDB) *Cloner {
+	return &Cloner{
+		logger: logger,
+		db:     db,
+	}
+}
+
+// Clone creates a new draft variant from an existing campaign.
+func (c *Cloner) Clone(ctx context.Context, req CloneRequest) (*CloneResult, error) {
+	start := time.Now()
+	if req.Mode == "" {
+		req.Mode = CloneModeInherit
+	}
+
+	c.logger.Info().
+		Str("tenant_id", req.TenantID.String()).
+		Str("source_campaign_id", req.SourceCampaignID.String()).
+		Str("mode", string(req.Mode)).
+		Msg("Cloning campaign")
+
+	result := &CloneResult{Mode: req.Mode}
+	err := storage.InTx(ctx, c.db, func(repos *storage.Repositories) error {
+		return c.clone(ctx, repos, req, result)
+	})
+	if err != nil {
+		return nil, err
+	}
+	result.Duration = time.Since(start)
+
+	c.logger.Info().
+		Str("campaign_id", result.CampaignID.String()).
+		Int("specs_copied", result.SpecsCopied).
+		Int("features_copied", result.FeaturesCopied).
+		Msg("Campaign cloned")
+
+	return result, nil
+}
+
+// clone creates the variant and, in copy mode, its content through repos.
+func (c *Cloner) clone(ctx context.Context, repos *storage.Repositories, req CloneRequest, result *CloneResult) error {
+	source, err := repos.Campaigns.GetByID(ctx, req.TenantID, req.SourceCampaignID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return ErrCampaignNotFound
+		}
+		return fmt.Errorf("load source campaign: %w", err)
+	}
+
+	clone := &storage.CampaignVariant{
+		ProductID: source.ProductID,
+		TenantID:  source.TenantID,
+		Locale:    source.Locale,
+		Trim:      source.Trim,
+		Market:    source.Market,
+		Status:    storage.CampaignStatusDraft,
+		Version:   1,
+		IsDraft:   true,
+	}
+	if req.Locale != "" {
+		clone.Locale = req.Locale
+	}
+	if req.Trim != nil {
+		clone.Trim = req.Trim
+	}
+	if req.Market != nil {
+		clone.Market = req.Market
+	}
+
+	result.SourceID = source.ID
+
+	switch req.Mode {
+	case CloneModeInherit:
+		if _, err := repos.Campaigns.GetInheritanceChain(ctx, req.TenantID, source.ID); err != nil {
+			return fmt.Errorf("resolve source chain: %w", err)
+		}
+		clone.BaseCampaignVariantID = &source.ID
+		if err := repos.Campaigns.Create(ctx, clone); err != nil {
+			return fmt.Errorf("create campaign: %w", err)
+		}
+
+	case CloneModeCopy:
+		if err := repos.Campaigns.Create(ctx, clone); err != nil {
+			return fmt.Errorf("create campaign: %w", err)
+		}
+		specs, features, err := c.copyContent(ctx, repos, req, source, clone)
+		if err != nil {
+			return err
+		}
+		result.SpecsCopied = specs
+		result.FeaturesCopied = features
+
+	default:
+		return fmt.Errorf("unsupported clone mode: %s", req.Mode)
+	}
+
+	result.CampaignID = clone.ID
+	return c.recordLineage(ctx, repos, clone, "campaign_variant", clone.ID, nil, map[string]interface{}{
+		"action":    "clone",
+		"mode":      string(req.Mode),
+		"source_id": source.ID.String(),
+		"operator":  req.Operator,
+	})
+}
+
+// copyContent materializes the source's effective specs and feature blocks into the clone.
+func (c *Cloner) copyContent(ctx context.Context, repos *storage.Repositories, req CloneRequest, source, clone *storage.CampaignVariant) (int, int, error) {
+	specs, err := repos.Inheritance.EffectiveSpecValues(ctx, req.TenantID, source.ID)
+	if err != nil {
+		return 0, 0, fmt.Errorf("resolve source specs: %w", err)
+	}
+	for _, es := range specs {
+		copied := *es.Value
+		copied.ID = uuid.Nil
+		copied.CampaignVariantID = clone.ID
+		copied.Version = 1
+		if err := repos.SpecValues.Create(ctx, &copied); err != nil {
+			return 0, 0, fmt.Errorf("copy spec value: %w", err)
+		}
+		err := c.recordLineage(ctx, repos, clone, "spec_value", copied.ID, copied.SourceDocID, map[string]interface{}{
+			"action":      "clone",
+			"origin":      string(es.Origin),
+			"copied_from": es.Value.ID.String(),
+		})
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+
+	blocks, err := repos.Inheritance.EffectiveFeatureBlocks(ctx, req.TenantID, source.ID, nil)
+	if err != nil {
+		return 0, 0, fmt.Errorf("resolve source feature blocks: %w", err)
+	}
+	for _, eb := range blocks {
+		copied := *eb.Block
+		copied.ID = uuid.Nil
+		copied.CampaignVariantID = clone.ID
+		copied.OverridesBlockID = nil
+		if err := repos.FeatureBlocks.Create(ctx, &copied); err != nil {
+			return 0, 0, fmt.Errorf("copy feature block: %w", err)
+		}
+		if err := c.storeBlockChunk(ctx, repos, &copied); err != nil {
+			return 0, 0, err
+		}
+		err := c.recordLineage(ctx, repos, clone, "feature_block", copied.ID, copied.SourceDocID, map[string]interface{}{
+			"action":      "clone",
+			"origin":      string(eb.Origin),
+			"copied_from": eb.Block.ID.String(),
+		})
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+
+	return len(specs), len(blocks), nil
+}
+
+// OverrideSpecValue stores a local value that replaces an inherited spec value.
+func (c *Cloner) OverrideSpecValue(ctx context.Context, req OverrideSpecRequest) (*storage.SpecValue, error) {
+	var spec *storage.SpecValue
+	err := storage.InTx(ctx, c.db, func(repos *storage.Repositories) error {
+		var err error
+		spec, err = c.overrideSpecValue(ctx, repos, req)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return spec, nil
+}
+
+func (c *Cloner) overrideSpecValue(ctx context.Context, repos *storage.Repositories, req OverrideSpecRequest) (*storage.SpecValue, error) {
+	campaign, err := c.requireDerived(ctx, repos, req.TenantID, req.CampaignID)
+	if err != nil {
+		return nil, err
+	}
+
+	inherited, err := repos.Inheritance.EffectiveSpecValues(ctx, req.TenantID, *campaign.BaseCampaignVariantID)
+	if err != nil {
+		return nil, fmt.Errorf("resolve base specs: %w", err)
+	}
+	var base *storage.SpecValue
+	for _, es := range inherited {
+		if es.Value.SpecItemID == req.SpecItemID {
+			base = es.Value
+			break
+		}
+	}
+	if base == nil {
+		return nil, fmt.Errorf("%w: %s", ErrSpecNotInherited, req.SpecItemID)
+	}
+
+	// A repeated override supersedes the variant's current value with the next version
+	latest, err := repos.SpecValues.LatestVersion(ctx, req.TenantID, campaign.ID, req.SpecItemID)
+	if err != nil {
+		return nil, fmt.Errorf("load current override: %w", err)
+	}
+
+	spec := &storage.SpecValue{
+		TenantID:          req.TenantID,
+		ProductID:         campaign.ProductID,
+		CampaignVariantID: campaign.ID,
+		SpecItemID:        req.SpecItemID,
+		ValueNumeric:      req.ValueNumeric,
+		ValueText:         req.ValueText,
+		Unit:              req.Unit,
+		Confidence:        1.0,
+		Status:            storage.SpecStatusActive,
+		SourceDocID:       req.SourceDocID,
+		SourcePage:        req.SourcePage,
+		Version:           latest + 1,
+	}
+	if spec.Unit == nil {
+		spec.Unit = base.Unit
+	}
+	if latest > 0 {
+		now := time.Now()
+		if err := repos.SpecValues.Retire(ctx, req.TenantID, campaign.ID, req.SpecItemID, now); err != nil {
+			return nil, fmt.Errorf("retire previous override: %w", err)
+		}
+		spec.EffectiveFrom = &now
+	}
+	if err := repos.SpecValues.Create(ctx, spec); err != nil {
+		return nil, fmt.Errorf("create override: %w", err)
+	}
+
+	err = c.recordLineage(ctx, repos, campaign, "spec_value", spec.ID, spec.SourceDocID, map[string]interface{}{
+		"origin":           string(storage.ValueOriginOverridden),
+		"overrides_id":     base.ID.String(),
+		"base_campaign_id": base.CampaignVariantID.String(),
+		"operator":         req.Operator,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return spec, nil
+}
+
+// OverrideFeatureBlock stores a local block that replaces an inherited feature block.
+func (c *Cloner) OverrideFeatureBlock(ctx context.Context, req OverrideFeatureRequest) (*storage.FeatureBlock, error) {
+	var block *storage.FeatureBlock
+	err := storage.InTx(ctx, c.db, func(repos *storage.Repositories) error {
+		var err error
+		block, err = c.overrideFeatureBlock(ctx, repos, req)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return block, nil
+}
+
+func (c *Cloner) overrideFeatureBlock(ctx context.Context, repos *storage.Repositories, req OverrideFeatureRequest) (*storage.FeatureBlock, error) {
+	campaign, err := c.requireDerived(ctx, repos, req.TenantID, req.CampaignID)
+	if err != nil {
+		return nil, err
+	}
+
+	inherited, err := repos.Inheritance.EffectiveFeatureBlocks(ctx, req.TenantID, *campaign.BaseCampaignVariantID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("resolve base feature blocks: %w", err)
+	}
+	var base *storage.FeatureBlock
+	for _, eb := range inherited {
+		if eb.Block.ID == req.BaseBlockID {
+			base = eb.Block
+			break
+		}
+	}
+	if base == nil {
+		return nil, fmt.Errorf("%w: %s", ErrBlockNotInherited, req.BaseBlockID)
+	}
+
+	// A repeated override replaces the variant's current override in place
+	current, err := repos.FeatureBlocks.GetOverride(ctx, req.TenantID, campaign.ID, base.ID)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return nil, fmt.Errorf("load current override: %w", err)
+	}
+	if current != nil {
+		return c.replaceOverride(ctx, repos, campaign, base, current, req)
+	}
+
+	block := &storage.FeatureBlock{
+		TenantID:          req.TenantID,
+		ProductID:         campaign.ProductID,
+		CampaignVariantID: campaign.ID,
+		BlockType:         base.BlockType,
+		Body:              req.Body,
+		Priority:          base.Priority,
+		Tags:              req.Tags,
+		Shareability:      base.Shareability,
+		SourceDocID:       req.SourceDocID,
+		SourcePage:        req.SourcePage,
+		OverridesBlockID:  &base.ID,
+	}
+	if block.Tags == nil {
+		block.Tags = base.Tags
+	}
+	if err := repos.FeatureBlocks.Create(ctx, block); err != nil {
+		return nil, fmt.Errorf("create override: %w", err)
+	}
+	if err := c.storeBlockChunk(ctx, repos, block); err != nil {
+		return nil, err
+	}
+
+	err = c.recordLineage(ctx, repos, campaign, "feature_block", block.ID, block.SourceDocID, map[string]interface{}{
+		"origin":           string(storage.ValueOriginOverridden),
+		"overrides_id":     base.ID.String(),
+		"base_campaign_id": base.CampaignVariantID.String(),
+		"operator":         req.Operator,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return block, nil
+}
+
+// replaceOverride updates a variant's existing override of base with the
+// request's body, and its chunk with it.
+func (c *Cloner) replaceOverride(ctx context.Context, repos *storage.Repositories, campaign *storage.CampaignVariant, base, block *storage.FeatureBlock, req OverrideFeatureRequest) (*storage.FeatureBlock, error) {
+	block.Body = req.Body
+	block.Tags = req.Tags
+	if block.Tags == nil {
+		block.Tags = base.Tags
+	}
+	block.SourceDocID = req.SourceDocID
+	block.SourcePage = req.SourcePage
+	if err := repos.FeatureBlocks.Update(ctx, block); err != nil {
+		return nil, fmt.Errorf("update override: %w", err)
+	}
+
+	chunks, err := repos.KnowledgeChunks.GetByBlock(ctx, req.TenantID, campaign.ID, block.ID)
+	if err != nil {
+		return nil, fmt.Errorf("load override chunk: %w", err)
+	}
+	if len(chunks) == 0 {
+		if err := c.storeBlockChunk(ctx, repos, block); err != nil {
+			return nil, err
+		}
+	}
+	for _, chunk := range chunks {
+		chunk.Text = block.Body
+		chunk.SourceDocID = block.SourceDocID
+		chunk.SourcePage = block.SourcePage
+		if err := repos.KnowledgeChunks.Update(ctx, chunk); err != nil {
+			return nil, fmt.Errorf("update override chunk: %w", err)
+		}
+	}
+
+	err = c.recordLineage(ctx, repos, campaign, "feature_block", block.ID, block.SourceDocID, map[string]interface{}{
+		"origin":           string(storage.ValueOriginOverridden),
+		"overrides_id":     base.ID.String(),
+		"base_campaign_id": base.CampaignVariantID.String(),
+		"operator":         req.Operator,
+		"replaced":         true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return block, nil
+}
+
+// storeBlockChunk stores the feature_block chunk of a block. Its metadata
+// carries the block type and ID, and the overridden block's ID, so retrieval
+// drops the inherited chunk in favour of the override.
+func (c *Cloner) storeBlockChunk(ctx context.Context, repos *storage.Repositories, block *storage.FeatureBlock) error {
+	metadata := map[string]interface{}{
+		storage.ChunkMetaBlockType: string(block.BlockType),
+		storage.ChunkMetaBlockID:   block.ID.String(),
+	}
+	if block.OverridesBlockID != nil {
+		metadata[storage.ChunkMetaOverridesBlock] = block.OverridesBlockID.String()
+	}
+	encoded, err := json.Marshal(metadata)
+	if err != nil {
+		return fmt.Errorf("encode chunk metadata: %w", err)
+	}
+
+	chunk := &storage.KnowledgeChunk{
+		TenantID:          block.TenantID,
+		ProductID:         block.ProductID,
+		CampaignVariantID: &block.CampaignVariantID,
+		ChunkType:         storage.ChunkTypeFeatureBlock,
+		Text:              block.Body,
+		Metadata:          encoded,
+		SourceDocID:       block.SourceDocID,
+		SourcePage:        block.SourcePage,
+		Visibility:        storage.VisibilityPrivate,
+	}
+	if err := repos.KnowledgeChunks.Create(ctx, chunk); err != nil {
+		return fmt.Errorf("create block chunk: %w", err)
+	}
+	return nil
+}
+
+// requireDerived loads a campaign and checks that it inherits from a base variant.
+func (c *Cloner) requireDerived(ctx context.Context, repos *storage.Repositories, tenantID, campaignID uuid.UUID) (*storage.CampaignVariant, error) {
+	campaign, err := repos.Campaigns.GetByID(ctx, tenantID, campaignID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, ErrCampaignNotFound
+		}
+		return nil, err
+	}
+	if campaign.BaseCampaignVariantID == nil {
+		return nil, ErrNoBaseCampaign
+	}
+	return campaign, nil
+}
+
+// recordLineage writes a creation lineage event in the operation's transaction,
+// so content and its lineage are committed together.
+func (c *Cloner) recordLineage(ctx context.Context, repos *storage.Repositories, campaign *storage.CampaignVariant, resourceType string, resourceID uuid.UUID, docID *uuid.UUID, payload map[string]interface{}) error {
+	payloadJSON, _ := json.Marshal(payload)
+	event := &storage.LineageEvent{
+		ID:                uuid.New(),
+		TenantID:          campaign.TenantID,
+		ProductID:         &campaign.ProductID,
+		CampaignVariantID: &campaign.ID,
+		ResourceType:      resourceType,
+		ResourceID:        resourceID,
+		DocumentSourceID:  docID,
+		Action:            storage.LineageActionCreated,
+		Payload:           payloadJSON,
+		OccurredAt:        time.Now(),
+	}
+	if err := repos.Lineage.Create(ctx, event); err != nil {
+		return fmt.Errorf("record %s lineage: %w", resourceType, err)
+	}
+	return nil
+}
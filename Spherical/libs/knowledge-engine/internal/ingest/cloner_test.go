@@ -0,0 +1,239 @@
+package ingest
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/observability"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// openClonerDB creates a SQLite database with the campaign schema. Timestamp
+// columns are declared DATETIME so the driver scans them into time.Time, and
+// spec value versions are unique per campaign item as in the Postgres schema.
+func openClonerDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "cloner.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	for _, name := range []string{"0001_init_sqlite.sql", "0002_campaign_inheritance_sqlite.sql"} {
+		migration, err := os.ReadFile(filepath.Join("..", "..", "db", "migrations", name))
+		require.NoError(t, err)
+		schema := strings.NewReplacer(
+			"_at TEXT", "_at DATETIME",
+			"effective_from TEXT", "effective_from DATETIME",
+			"effective_through TEXT", "effective_through DATETIME",
+		).Replace(string(migration))
+		_, err = db.Exec(schema)
+		require.NoError(t, err, name)
+	}
+	_, err = db.Exec(`CREATE UNIQUE INDEX idx_sv_version ON spec_values(tenant_id, product_id, campaign_variant_id, spec_item_id, version)`)
+	require.NoError(t, err)
+	return db
+}
+
+// clonerFixture holds a published campaign with two spec values.
+type clonerFixture struct {
+	db                    *sql.DB
+	tenantID, campaignID  uuid.UUID
+	powerItem, torqueItem uuid.UUID
+}
+
+func newClonerFixture(t *testing.T) *clonerFixture {
+	ctx := context.Background()
+	db := openClonerDB(t)
+	f := &clonerFixture{db: db, tenantID: uuid.New(), powerItem: uuid.New(), torqueItem: uuid.New()}
+	productID := uuid.New()
+	_, err := db.Exec("INSERT INTO tenants (id, name) VALUES (?, ?)", f.tenantID, "Toyota")
+	require.NoError(t, err)
+	_, err = db.Exec("INSERT INTO products (id, tenant_id, name) VALUES (?, ?, ?)", productID, f.tenantID, "Camry")
+	require.NoError(t, err)
+
+	repos := storage.NewRepositories(db)
+	campaign := &storage.CampaignVariant{ProductID: productID, TenantID: f.tenantID, Locale: "en-US", Status: storage.CampaignStatusPublished, Version: 1}
+	require.NoError(t, repos.Campaigns.Create(ctx, campaign))
+	f.campaignID = campaign.ID
+
+	for item, value := range map[uuid.UUID]string{f.powerItem: "225 hp", f.torqueItem: "221 Nm"} {
+		value := value
+		require.NoError(t, repos.SpecValues.Create(ctx, &storage.SpecValue{
+			TenantID: f.tenantID, ProductID: productID, CampaignVariantID: campaign.ID, SpecItemID: item,
+			ValueText: &value, Confidence: 1, Status: storage.SpecStatusActive, Version: 1,
+		}))
+	}
+	return f
+}
+
+// addBlock stores a feature block on the fixture's campaign, with its chunk.
+func (f *clonerFixture) addBlock(t *testing.T, body string) *storage.FeatureBlock {
+	ctx := context.Background()
+	repos := storage.NewRepositories(f.db)
+	campaign, err := repos.Campaigns.GetByID(ctx, f.tenantID, f.campaignID)
+	require.NoError(t, err)
+	block := &storage.FeatureBlock{
+		TenantID: f.tenantID, ProductID: campaign.ProductID, CampaignVariantID: f.campaignID,
+		BlockType: storage.BlockTypeFeature, Body: body, Shareability: storage.ShareabilityPrivate,
+	}
+	require.NoError(t, repos.FeatureBlocks.Create(ctx, block))
+	require.NoError(t, NewCloner(observability.NewLogger(observability.LogConfig{Level: "error"}), f.db).storeBlockChunk(ctx, repos, block))
+	return block
+}
+
+func (f *clonerFixture) count(t *testing.T, table string) int {
+	var n int
+	require.NoError(t, f.db.QueryRow("SELECT COUNT(*) FROM "+table).Scan(&n))
+	return n
+}
+
+func TestCloner(t *testing.T) {
+	ctx := context.Background()
+	logger := observability.NewLogger(observability.LogConfig{Level: "error"})
+
+	t.Run("inherit and override", func(t *testing.T) {
+		f := newClonerFixture(t)
+		cloner := NewCloner(logger, f.db)
+
+		result, err := cloner.Clone(ctx, CloneRequest{TenantID: f.tenantID, SourceCampaignID: f.campaignID, Mode: CloneModeInherit})
+		require.NoError(t, err)
+		assert.Zero(t, result.SpecsCopied)
+		assert.Equal(t, 2, f.count(t, "spec_values"), "inherited values are not copied")
+
+		repos := storage.NewRepositories(f.db)
+		clone, err := repos.Campaigns.GetByID(ctx, f.tenantID, result.CampaignID)
+		require.NoError(t, err)
+		require.NotNil(t, clone.BaseCampaignVariantID)
+		assert.Equal(t, f.campaignID, *clone.BaseCampaignVariantID)
+		assert.True(t, clone.IsDraft)
+
+		value := "230 hp"
+		override, err := cloner.OverrideSpecValue(ctx, OverrideSpecRequest{TenantID: f.tenantID, CampaignID: clone.ID, SpecItemID: f.powerItem, ValueText: &value})
+		require.NoError(t, err)
+
+		effective, err := repos.Inheritance.EffectiveSpecValues(ctx, f.tenantID, clone.ID)
+		require.NoError(t, err)
+		origins := make(map[uuid.UUID]storage.ValueOrigin)
+		for _, ev := range effective {
+			origins[ev.Value.SpecItemID] = ev.Origin
+			if ev.Value.SpecItemID == f.powerItem {
+				assert.Equal(t, override.ID, ev.Value.ID)
+			}
+		}
+		assert.Equal(t, map[uuid.UUID]storage.ValueOrigin{
+			f.powerItem:  storage.ValueOriginOverridden,
+			f.torqueItem: storage.ValueOriginInherited,
+		}, origins)
+
+		events, err := repos.Lineage.GetByResource(ctx, f.tenantID, "spec_value", override.ID)
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+		assert.Equal(t, storage.LineageActionCreated, events[0].Action)
+
+		_, err = cloner.OverrideSpecValue(ctx, OverrideSpecRequest{TenantID: f.tenantID, CampaignID: f.campaignID, SpecItemID: f.powerItem, ValueText: &value})
+		assert.ErrorIs(t, err, ErrNoBaseCampaign)
+		_, err = cloner.OverrideSpecValue(ctx, OverrideSpecRequest{TenantID: f.tenantID, CampaignID: clone.ID, SpecItemID: uuid.New(), ValueText: &value})
+		assert.ErrorIs(t, err, ErrSpecNotInherited)
+	})
+
+	t.Run("repeated override supersedes the previous one", func(t *testing.T) {
+		f := newClonerFixture(t)
+		cloner := NewCloner(logger, f.db)
+		result, err := cloner.Clone(ctx, CloneRequest{TenantID: f.tenantID, SourceCampaignID: f.campaignID, Mode: CloneModeInherit})
+		require.NoError(t, err)
+
+		first, second := "230 hp", "235 hp"
+		previous, err := cloner.OverrideSpecValue(ctx, OverrideSpecRequest{TenantID: f.tenantID, CampaignID: result.CampaignID, SpecItemID: f.powerItem, ValueText: &first})
+		require.NoError(t, err)
+		override, err := cloner.OverrideSpecValue(ctx, OverrideSpecRequest{TenantID: f.tenantID, CampaignID: result.CampaignID, SpecItemID: f.powerItem, ValueText: &second})
+		require.NoError(t, err)
+		assert.Equal(t, 1, previous.Version)
+		assert.Equal(t, 2, override.Version)
+
+		repos := storage.NewRepositories(f.db)
+		effective, err := repos.Inheritance.EffectiveSpecValues(ctx, f.tenantID, result.CampaignID)
+		require.NoError(t, err)
+		for _, ev := range effective {
+			if ev.Value.SpecItemID == f.powerItem {
+				assert.Equal(t, override.ID, ev.Value.ID)
+				assert.Equal(t, second, *ev.Value.ValueText)
+			}
+		}
+
+		var status string
+		var through sql.NullTime
+		require.NoError(t, f.db.QueryRow("SELECT status, effective_through FROM spec_values WHERE id = ?", previous.ID).Scan(&status, &through))
+		assert.Equal(t, string(storage.SpecStatusDeprecated), status)
+		require.True(t, through.Valid, "the retired value keeps its effective window")
+		require.NotNil(t, override.EffectiveFrom)
+		assert.True(t, through.Time.Equal(*override.EffectiveFrom))
+	})
+
+	t.Run("repeated block override replaces the previous one", func(t *testing.T) {
+		f := newClonerFixture(t)
+		base := f.addBlock(t, "Panoramic sunroof")
+		cloner := NewCloner(logger, f.db)
+		result, err := cloner.Clone(ctx, CloneRequest{TenantID: f.tenantID, SourceCampaignID: f.campaignID, Mode: CloneModeInherit})
+		require.NoError(t, err)
+
+		first, err := cloner.OverrideFeatureBlock(ctx, OverrideFeatureRequest{TenantID: f.tenantID, CampaignID: result.CampaignID, BaseBlockID: base.ID, Body: "Tilt sunroof"})
+		require.NoError(t, err)
+		second, err := cloner.OverrideFeatureBlock(ctx, OverrideFeatureRequest{TenantID: f.tenantID, CampaignID: result.CampaignID, BaseBlockID: base.ID, Body: "Dual-pane sunroof"})
+		require.NoError(t, err)
+		assert.Equal(t, first.ID, second.ID)
+
+		repos := storage.NewRepositories(f.db)
+		effective, err := repos.Inheritance.EffectiveFeatureBlocks(ctx, f.tenantID, result.CampaignID, nil)
+		require.NoError(t, err)
+		require.Len(t, effective, 1)
+		assert.Equal(t, storage.ValueOriginOverridden, effective[0].Origin)
+		assert.Equal(t, "Dual-pane sunroof", effective[0].Block.Body)
+
+		chunks, err := repos.KnowledgeChunks.GetByCampaign(ctx, f.tenantID, result.CampaignID)
+		require.NoError(t, err)
+		require.Len(t, chunks, 1)
+		assert.Equal(t, "Dual-pane sunroof", chunks[0].Text)
+		assert.JSONEq(t, `{"block_type":"feature","block_id":"`+second.ID.String()+`","overrides_block_id":"`+base.ID.String()+`"}`, string(chunks[0].Metadata))
+	})
+
+	t.Run("copy", func(t *testing.T) {
+		f := newClonerFixture(t)
+		market := "IN"
+		result, err := NewCloner(logger, f.db).Clone(ctx, CloneRequest{TenantID: f.tenantID, SourceCampaignID: f.campaignID, Mode: CloneModeCopy, Market: &market})
+		require.NoError(t, err)
+		assert.Equal(t, 2, result.SpecsCopied)
+		assert.Equal(t, 4, f.count(t, "spec_values"))
+
+		clone, err := storage.NewRepositories(f.db).Campaigns.GetByID(ctx, f.tenantID, result.CampaignID)
+		require.NoError(t, err)
+		assert.Nil(t, clone.BaseCampaignVariantID)
+		require.NotNil(t, clone.Market)
+		assert.Equal(t, "IN", *clone.Market)
+		// One event for the campaign and one per copied value
+		assert.Equal(t, 3, f.count(t, "lineage_events"))
+	})
+
+	t.Run("failure rolls back the clone", func(t *testing.T) {
+		f := newClonerFixture(t)
+		_, err := f.db.Exec("DROP TABLE lineage_events")
+		require.NoError(t, err)
+
+		_, err = NewCloner(logger, f.db).Clone(ctx, CloneRequest{TenantID: f.tenantID, SourceCampaignID: f.campaignID, Mode: CloneModeCopy})
+		require.Error(t, err)
+		assert.Equal(t, 1, f.count(t, "campaign_variants"))
+		assert.Equal(t, 2, f.count(t, "spec_values"))
+	})
+
+	t.Run("unknown source", func(t *testing.T) {
+		f := newClonerFixture(t)
+		_, err := NewCloner(logger, f.db).Clone(ctx, CloneRequest{TenantID: f.tenantID, SourceCampaignID: uuid.New()})
+		assert.ErrorIs(t, err, ErrCampaignNotFound)
+		assert.Equal(t, 1, f.count(t, "campaign_variants"))
+	})
+}
@@ -17,6 +17,7 @@ type ParsedBrochure struct {
 	SpecValues   []ParsedSpec
 	Features     []ParsedFeature
 	USPs         []ParsedUSP
+	Accessories  []ParsedAccessory
 	RawChunks    []ParsedChunk
 	SourcePages  map[int]string // page number -> content
 	Errors       []ParseError
@@ -66,6 +67,15 @@ type ParsedUSP struct {
 	SourceLine int
 }
 
+// ParsedAccessory represents an extracted accessory bullet.
+type ParsedAccessory struct {
+	Body       string
+	Tags       []string
+	Priority   int
+	SourcePage int
+	SourceLine int
+}
+
 // ParsedChunk represents a text chunk for semantic indexing.
 type ParsedChunk struct {
 	Text       string
@@ -151,6 +161,10 @@ func (p *Parser) Parse(content string) (*ParsedBrochure, error) {
 	usps := p.parseUSPs(remaining)
 	result.USPs = usps
 
+	// Parse accessories
+	accessories := p.parseAccessories(remaining)
+	result.Accessories = accessories
+
 	// Generate chunks for semantic search
 	chunks := p.generateChunks(remaining)
 	result.RawChunks = chunks
@@ -475,6 +489,32 @@ func (p *Parser) parseUSPs(content string) []ParsedUSP {
 	return usps
 }
 
+// parseAccessories extracts accessory bullets.
+func (p *Parser) parseAccessories(content string) []ParsedAccessory {
+	var accessories []ParsedAccessory
+
+	matches := accessorySectionRe.FindAllStringSubmatch(content, -1)
+	for _, match := range matches {
+		if len(match) < 2 {
+			continue
+		}
+
+		bullets := p.parseBulletList(match[1])
+		for i, bullet := range bullets {
+			accessories = append(accessories, ParsedAccessory{
+				Body:     bullet,
+				Tags:     p.inferTags(bullet),
+				Priority: i + 1,
+			})
+		}
+	}
+
+	return accessories
+}
+
+// accessorySectionRe matches an accessories heading and its bullet list.
+var accessorySectionRe = regexp.MustCompile(`(?i)##\s*(?:Genuine )?Accessor(?:y|ies)\s*\n((?:[-*]\s*.+\n?)+)`)
+
 // generateChunks creates text chunks for semantic indexing.
 func (p *Parser) generateChunks(content string) []ParsedChunk {
 	var chunks []ParsedChunk
@@ -111,6 +111,23 @@ func TestParser_Parse_USPs(t *testing.T) {
 	require.GreaterOrEqual(t, len(result.USPs), 3)
 }
 
+func TestParser_Parse_Accessories(t *testing.T) {
+	content := `
+## Genuine Accessories
+
+- Roof rack rated for 75 kg
+- All-weather floor mats
+`
+
+	parser := NewParser(ParserConfig{ChunkSize: 512, ChunkOverlap: 64})
+	result, err := parser.Parse(content)
+
+	require.NoError(t, err)
+	require.Len(t, result.Accessories, 2)
+	assert.Equal(t, "Roof rack rated for 75 kg", result.Accessories[0].Body)
+	assert.Empty(t, result.Features)
+}
+
 func TestParser_Parse_GeneratesChunks(t *testing.T) {
 	// Create a longer content to test chunking with multiple paragraphs
 	var builder strings.Builder
@@ -5,6 +5,7 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"os"
 	"os/exec"
@@ -12,14 +13,20 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/embedding"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/lexical"
 	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/observability"
 	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/storage"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // Pipeline orchestrates the brochure ingestion process.
 type Pipeline struct {
 	logger          *observability.Logger
 	parser          *Parser
+	lexicalIndex    *lexical.Index
+	embedder        embedding.Embedder
+	metrics         *observability.Metrics
 	config          PipelineConfig
 }
 
@@ -47,34 +54,49 @@ type IngestionRequest struct {
 
 // IngestionResult represents the result of an ingestion job.
 type IngestionResult struct {
-	JobID            uuid.UUID
-	Status           storage.JobStatus
-	SpecsCreated     int
-	SpecsUpdated     int
-	FeaturesCreated  int
-	USPsCreated      int
-	ChunksCreated    int
-	ConflictingSpecs []uuid.UUID
-	Errors           []string
-	StartedAt        time.Time
-	CompletedAt      time.Time
-	Duration         time.Duration
+	JobID              uuid.UUID
+	Status             storage.JobStatus
+	SpecsCreated       int
+	SpecsUpdated       int
+	FeaturesCreated    int
+	USPsCreated        int
+	AccessoriesCreated int
+	ChunksCreated      int
+	ConflictingSpecs   []uuid.UUID
+	Errors             []string
+	StartedAt          time.Time
+	CompletedAt        time.Time
+	Duration           time.Duration
 }
 
-// NewPipeline creates a new ingestion pipeline.
-func NewPipeline(logger *observability.Logger, cfg PipelineConfig) *Pipeline {
+// NewPipeline creates a new ingestion pipeline. Extracted specs and chunks are
+// staged in lexicalIndex, when set, until the campaign is published.
+func NewPipeline(logger *observability.Logger, lexicalIndex *lexical.Index, cfg PipelineConfig) *Pipeline {
 	return &Pipeline{
 		logger: logger,
 		parser: NewParser(ParserConfig{
 			ChunkSize:    cfg.ChunkSize,
 			ChunkOverlap: cfg.ChunkOverlap,
 		}),
-		config: cfg,
+		lexicalIndex: lexicalIndex,
+		config:       cfg,
 	}
 }
 
+// SetEmbedder embeds knowledge chunks as they are stored. Wrapping the
+// embedder in an embedding.CachedEmbedder avoids re-embedding unchanged text
+// when a brochure is ingested again.
+func (p *Pipeline) SetEmbedder(embedder embedding.Embedder) {
+	p.embedder = embedder
+}
+
+// SetMetrics counts finished ingestion jobs by status in metrics.
+func (p *Pipeline) SetMetrics(metrics *observability.Metrics) {
+	p.metrics = metrics
+}
+
 // Ingest processes a brochure and stores the extracted content.
-func (p *Pipeline) Ingest(ctx context.Context, req IngestionRequest) (*IngestionResult, error) {
+func (p *Pipeline) Ingest(ctx context.Context, req IngestionRequest) (_ *IngestionResult, err error) {
 	jobID := uuid.New()
 	startTime := time.Now()
 
@@ -83,8 +105,20 @@ func (p *Pipeline) Ingest(ctx context.Context, req IngestionRequest) (*Ingestion
 		Status:    storage.JobStatusRunning,
 		StartedAt: startTime,
 	}
+	ctx, span := observability.StartSpan(ctx, "ingest.Ingest",
+		attribute.String("job_id", jobID.String()),
+		attribute.String("tenant_id", req.TenantID.String()),
+		attribute.String("product_id", req.ProductID.String()),
+		attribute.String("campaign_id", req.CampaignID.String()),
+	)
+	defer func() {
+		p.metrics.IngestionJob(string(result.Status))
+		span.SetAttributes(attribute.String("status", string(result.Status)))
+		observability.EndSpan(span, err)
+	}()
 
 	p.logger.Info().
+		Ctx(ctx).
 		Str("job_id", jobID.String()).
 		Str("tenant_id", req.TenantID.String()).
 		Str("product_id", req.ProductID.String()).
@@ -92,7 +126,9 @@ func (p *Pipeline) Ingest(ctx context.Context, req IngestionRequest) (*Ingestion
 		Msg("Starting ingestion job")
 
 	// Step 1: Get Markdown content
-	markdownContent, err := p.getMarkdownContent(ctx, req)
+	stepCtx, step := observability.StartSpan(ctx, "ingest.get_markdown")
+	markdownContent, err := p.getMarkdownContent(stepCtx, req)
+	observability.EndSpan(step, err)
 	if err != nil {
 		result.Status = storage.JobStatusFailed
 		result.Errors = append(result.Errors, fmt.Sprintf("get markdown: %v", err))
@@ -100,7 +136,9 @@ func (p *Pipeline) Ingest(ctx context.Context, req IngestionRequest) (*Ingestion
 	}
 
 	// Step 2: Parse the Markdown
+	_, step = observability.StartSpan(ctx, "ingest.parse")
 	parsed, err := p.parser.Parse(markdownContent)
+	observability.EndSpan(step, err)
 	if err != nil {
 		result.Status = storage.JobStatusFailed
 		result.Errors = append(result.Errors, fmt.Sprintf("parse markdown: %v", err))
@@ -113,13 +151,18 @@ func (p *Pipeline) Ingest(ctx context.Context, req IngestionRequest) (*Ingestion
 	}
 
 	// Step 3: Validate parsed content
+	_, step = observability.StartSpan(ctx, "ingest.validate")
 	validationErrors := ValidateParsedBrochure(parsed)
 	for _, valErr := range validationErrors {
 		result.Errors = append(result.Errors, valErr.Message)
 	}
+	step.SetAttributes(attribute.Int("validation_errors", len(validationErrors)))
+	step.End()
 
 	// Step 4: Create document source record
-	docSource, err := p.createDocumentSource(ctx, req, markdownContent)
+	stepCtx, step = observability.StartSpan(ctx, "ingest.create_document_source")
+	docSource, err := p.createDocumentSource(stepCtx, req, markdownContent)
+	observability.EndSpan(step, err)
 	if err != nil {
 		result.Status = storage.JobStatusFailed
 		result.Errors = append(result.Errors, fmt.Sprintf("create doc source: %v", err))
@@ -127,7 +170,9 @@ func (p *Pipeline) Ingest(ctx context.Context, req IngestionRequest) (*Ingestion
 	}
 
 	// Step 5: Deduplicate and store specs
-	specsResult, err := p.storeSpecs(ctx, req, parsed.SpecValues, docSource.ID)
+	stepCtx, step = observability.StartSpan(ctx, "ingest.store_specs")
+	specsResult, err := p.storeSpecs(stepCtx, req, parsed.SpecValues, docSource.ID)
+	observability.EndSpan(step, err)
 	if err != nil {
 		result.Status = storage.JobStatusFailed
 		result.Errors = append(result.Errors, fmt.Sprintf("store specs: %v", err))
@@ -138,29 +183,52 @@ func (p *Pipeline) Ingest(ctx context.Context, req IngestionRequest) (*Ingestion
 	result.ConflictingSpecs = specsResult.Conflicts
 
 	// Step 6: Store features
-	featuresCreated, err := p.storeFeatures(ctx, req, parsed.Features, docSource.ID)
+	stepCtx, step = observability.StartSpan(ctx, "ingest.store_features")
+	features, err := p.storeFeatures(stepCtx, req, parsed.Features, docSource.ID)
+	observability.EndSpan(step, err)
 	if err != nil {
 		p.logger.Warn().Err(err).Msg("Failed to store some features")
 	}
-	result.FeaturesCreated = featuresCreated
+	result.FeaturesCreated = len(features)
 
 	// Step 7: Store USPs
-	uspsCreated, err := p.storeUSPs(ctx, req, parsed.USPs, docSource.ID)
+	stepCtx, step = observability.StartSpan(ctx, "ingest.store_usps")
+	usps, err := p.storeUSPs(stepCtx, req, parsed.USPs, docSource.ID)
+	observability.EndSpan(step, err)
 	if err != nil {
 		p.logger.Warn().Err(err).Msg("Failed to store some USPs")
 	}
-	result.USPsCreated = uspsCreated
+	result.USPsCreated = len(usps)
 
-	// Step 8: Generate and store chunks
-	chunksCreated, err := p.storeChunks(ctx, req, parsed.RawChunks, docSource.ID)
+	// Step 8: Store accessories
+	stepCtx, step = observability.StartSpan(ctx, "ingest.store_accessories")
+	accessories, err := p.storeAccessories(stepCtx, req, parsed.Accessories, docSource.ID)
+	observability.EndSpan(step, err)
 	if err != nil {
-		p.logger.Warn().Err(err).Msg("Failed to store some chunks")
+		p.logger.Warn().Err(err).Msg("Failed to store some accessories")
 	}
-	result.ChunksCreated = chunksCreated
+	result.AccessoriesCreated = len(accessories)
 
-	// Step 9: Emit lineage events
-	if err := p.emitLineageEvents(ctx, req, result); err != nil {
-		p.logger.Warn().Err(err).Msg("Failed to emit lineage events")
+	// Step 9: Generate and store chunks, one per feature block and the raw text chunks
+	stepCtx, step = observability.StartSpan(ctx, "ingest.store_chunks")
+	blocks := append(append(features, usps...), accessories...)
+	blockChunks, err := p.storeBlockChunks(stepCtx, req, blocks, docSource.ID)
+	if err != nil {
+		p.logger.Warn().Err(err).Msg("Failed to store some feature block chunks")
+	}
+	chunksCreated, err := p.storeChunks(stepCtx, req, parsed.RawChunks, docSource.ID)
+	observability.EndSpan(step, err)
+	if err != nil {
+		p.logger.Warn().Err(err).Msg("Failed to store some chunks")
+	}
+	result.ChunksCreated = blockChunks + chunksCreated
+
+	// Step 10: Emit lineage events
+	stepCtx, step = observability.StartSpan(ctx, "ingest.emit_lineage")
+	lineageErr := p.emitLineageEvents(stepCtx, req, result)
+	observability.EndSpan(step, lineageErr)
+	if lineageErr != nil {
+		p.logger.Warn().Err(lineageErr).Msg("Failed to emit lineage events")
 	}
 
 	// Determine final status
@@ -177,6 +245,7 @@ func (p *Pipeline) Ingest(ctx context.Context, req IngestionRequest) (*Ingestion
 	result.Duration = result.CompletedAt.Sub(result.StartedAt)
 
 	p.logger.Info().
+		Ctx(ctx).
 		Str("job_id", jobID.String()).
 		Str("status", string(result.Status)).
 		Int("specs_created", result.SpecsCreated).
@@ -335,6 +404,21 @@ func (p *Pipeline) storeSpecs(ctx context.Context, req IngestionRequest, specs [
 		}
 
 		// TODO: Persist to database
+		p.stageLexical(lexical.SpecDocument(storage.SpecViewLatest{
+			ID:                specID,
+			TenantID:          req.TenantID,
+			ProductID:         req.ProductID,
+			CampaignVariantID: req.CampaignID,
+			SpecName:          spec.Name,
+			CategoryName:      spec.Category,
+			Value:             spec.Value,
+			Unit:              specValue.Unit,
+			ValueNumeric:      specValue.ValueNumeric,
+			Confidence:        spec.Confidence,
+			SourceDocID:       &docSourceID,
+			SourcePage:        specValue.SourcePage,
+			Version:           specValue.Version,
+		}))
 		p.logger.Debug().
 			Str("spec_id", specID.String()).
 			Str("category", spec.Category).
@@ -347,8 +431,8 @@ func (p *Pipeline) storeSpecs(ctx context.Context, req IngestionRequest, specs [
 }
 
 // storeFeatures persists feature blocks.
-func (p *Pipeline) storeFeatures(ctx context.Context, req IngestionRequest, features []ParsedFeature, docSourceID uuid.UUID) (int, error) {
-	created := 0
+func (p *Pipeline) storeFeatures(ctx context.Context, req IngestionRequest, features []ParsedFeature, docSourceID uuid.UUID) ([]*storage.FeatureBlock, error) {
+	blocks := make([]*storage.FeatureBlock, 0, len(features))
 
 	for _, feature := range features {
 		featureBlock := &storage.FeatureBlock{
@@ -366,16 +450,15 @@ func (p *Pipeline) storeFeatures(ctx context.Context, req IngestionRequest, feat
 		}
 
 		// TODO: Persist to database
-		_ = featureBlock
-		created++
+		blocks = append(blocks, featureBlock)
 	}
 
-	return created, nil
+	return blocks, nil
 }
 
 // storeUSPs persists USP blocks.
-func (p *Pipeline) storeUSPs(ctx context.Context, req IngestionRequest, usps []ParsedUSP, docSourceID uuid.UUID) (int, error) {
-	created := 0
+func (p *Pipeline) storeUSPs(ctx context.Context, req IngestionRequest, usps []ParsedUSP, docSourceID uuid.UUID) ([]*storage.FeatureBlock, error) {
+	blocks := make([]*storage.FeatureBlock, 0, len(usps))
 
 	for _, usp := range usps {
 		uspBlock := &storage.FeatureBlock{
@@ -393,18 +476,74 @@ func (p *Pipeline) storeUSPs(ctx context.Context, req IngestionRequest, usps []P
 		}
 
 		// TODO: Persist to database
-		_ = uspBlock
-		created++
+		blocks = append(blocks, uspBlock)
 	}
 
-	return created, nil
+	return blocks, nil
+}
+
+// storeAccessories persists accessory blocks.
+func (p *Pipeline) storeAccessories(ctx context.Context, req IngestionRequest, accessories []ParsedAccessory, docSourceID uuid.UUID) ([]*storage.FeatureBlock, error) {
+	blocks := make([]*storage.FeatureBlock, 0, len(accessories))
+
+	for _, accessory := range accessories {
+		accessoryBlock := &storage.FeatureBlock{
+			ID:                uuid.New(),
+			TenantID:          req.TenantID,
+			ProductID:         req.ProductID,
+			CampaignVariantID: req.CampaignID,
+			BlockType:         storage.BlockTypeAccessory,
+			Body:              accessory.Body,
+			Priority:          int16(accessory.Priority),
+			Tags:              accessory.Tags,
+			Shareability:      storage.ShareabilityPrivate,
+			SourceDocID:       &docSourceID,
+			SourcePage:        &accessory.SourcePage,
+		}
+
+		// TODO: Persist to database
+		blocks = append(blocks, accessoryBlock)
+	}
+
+	return blocks, nil
+}
+
+// storeBlockChunks stores a feature_block chunk for each block. The chunk
+// metadata carries the block type and ID, which retrieval uses to answer from
+// accessory blocks and to drop blocks overridden by a derived variant.
+func (p *Pipeline) storeBlockChunks(ctx context.Context, req IngestionRequest, blocks []*storage.FeatureBlock, docSourceID uuid.UUID) (int, error) {
+	chunks := make([]ParsedChunk, len(blocks))
+	for i, block := range blocks {
+		metadata := map[string]interface{}{
+			storage.ChunkMetaBlockType: string(block.BlockType),
+			storage.ChunkMetaBlockID:   block.ID.String(),
+		}
+		if block.OverridesBlockID != nil {
+			metadata[storage.ChunkMetaOverridesBlock] = block.OverridesBlockID.String()
+		}
+		chunks[i] = ParsedChunk{
+			Text:      block.Body,
+			ChunkType: storage.ChunkTypeFeatureBlock,
+			Metadata:  metadata,
+		}
+		if block.SourcePage != nil {
+			chunks[i].SourcePage = *block.SourcePage
+		}
+	}
+	return p.storeChunks(ctx, req, chunks, docSourceID)
 }
 
 // storeChunks persists knowledge chunks for semantic search.
 func (p *Pipeline) storeChunks(ctx context.Context, req IngestionRequest, chunks []ParsedChunk, docSourceID uuid.UUID) (int, error) {
 	created := 0
 
-	for _, chunk := range chunks {
+	vectors, models, err := p.embedChunks(ctx, chunks)
+	if err != nil {
+		// Chunks stay searchable lexically without vectors
+		p.logger.Warn().Err(err).Int("chunks", len(chunks)).Msg("Failed to embed chunks")
+	}
+
+	for i, chunk := range chunks {
 		knowledgeChunk := &storage.KnowledgeChunk{
 			ID:                uuid.New(),
 			TenantID:          req.TenantID,
@@ -417,15 +556,48 @@ func (p *Pipeline) storeChunks(ctx context.Context, req IngestionRequest, chunks
 			Visibility:        storage.VisibilityPrivate,
 		}
 
-		// TODO: Generate embedding
+		if len(chunk.Metadata) > 0 {
+			metadata, err := json.Marshal(chunk.Metadata)
+			if err != nil {
+				return created, fmt.Errorf("encode chunk metadata: %w", err)
+			}
+			knowledgeChunk.Metadata = metadata
+		}
+
+		if i < len(vectors) && len(vectors[i]) > 0 {
+			knowledgeChunk.EmbeddingVector = vectors[i]
+			knowledgeChunk.EmbeddingModel = &models[i]
+		}
+
 		// TODO: Persist to database and vector store
-		_ = knowledgeChunk
+		p.stageLexical(lexical.ChunkDocument(*knowledgeChunk))
 		created++
 	}
 
 	return created, nil
 }
 
+// embedChunks embeds the chunks' text in one call, or returns nil without an
+// embedder. It also returns the model that embedded each chunk, which differs
+// from the embedder's model for chunks embedded by a fallback provider.
+func (p *Pipeline) embedChunks(ctx context.Context, chunks []ParsedChunk) ([][]float32, []string, error) {
+	if p.embedder == nil || len(chunks) == 0 {
+		return nil, nil, nil
+	}
+	texts := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		texts[i] = chunk.Text
+	}
+	return embedding.EmbedModels(ctx, p.embedder, texts)
+}
+
+// stageLexical queues a document in the lexical index until the campaign is published.
+func (p *Pipeline) stageLexical(doc lexical.Document) {
+	if p.lexicalIndex != nil {
+		p.lexicalIndex.Stage(doc)
+	}
+}
+
 // emitLineageEvents records audit events for the ingestion.
 func (p *Pipeline) emitLineageEvents(ctx context.Context, req IngestionRequest, result *IngestionResult) error {
 	// TODO: Emit lineage events to the database
@@ -0,0 +1,39 @@
+package ingest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/cache"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/embedding"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/observability"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPipeline_StoreChunksReusesCachedEmbeddings(t *testing.T) {
+	logger := observability.NewLogger(observability.LogConfig{Level: "error"})
+	cached := embedding.NewCachedEmbedder(logger, embedding.NewMockClient(8),
+		embedding.NewClientCacheBackend(cache.NewMemoryClient(100), 0))
+
+	pipeline := NewPipeline(logger, nil, PipelineConfig{})
+	pipeline.SetEmbedder(cached)
+
+	req := IngestionRequest{TenantID: uuid.New(), ProductID: uuid.New(), CampaignID: uuid.New()}
+	chunks := []ParsedChunk{
+		{Text: "The hybrid engine delivers 225 hp.", ChunkType: storage.ChunkTypeGlobal},
+		{Text: "A panoramic roof is standard.", ChunkType: storage.ChunkTypeGlobal},
+	}
+
+	// Ingesting the same brochure again embeds nothing new
+	for run := 0; run < 2; run++ {
+		created, err := pipeline.storeChunks(context.Background(), req, chunks, uuid.New())
+		require.NoError(t, err)
+		assert.Equal(t, 2, created)
+	}
+	stats := cached.Stats()
+	assert.Equal(t, int64(2), stats.Misses)
+	assert.Equal(t, int64(2), stats.Hits)
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/lexical"
 	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/observability"
 	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/storage"
 )
@@ -25,7 +26,14 @@ var (
 
 // Publisher handles campaign publish and rollback operations.
 type Publisher struct {
-	logger *observability.Logger
+	logger       *observability.Logger
+	lexicalIndex *lexical.Index
+	caches       []CacheInvalidator
+}
+
+// CacheInvalidator drops cached retrieval results when a campaign changes.
+type CacheInvalidator interface {
+	InvalidateCampaign(ctx context.Context, tenantID, campaignID uuid.UUID) error
 }
 
 // PublishRequest represents a request to publish a campaign.
@@ -65,10 +73,19 @@ type RollbackResult struct {
 	RolledBackAt    time.Time
 }
 
-// NewPublisher creates a new Publisher.
-func NewPublisher(logger *observability.Logger) *Publisher {
+// NewPublisher creates a new Publisher. Staged lexical documents are promoted in
+// lexicalIndex, when set, as campaigns are published.
+func NewPublisher(logger *observability.Logger, lexicalIndex *lexical.Index) *Publisher {
 	return &Publisher{
-		logger: logger,
+		logger:       logger,
+		lexicalIndex: lexicalIndex,
+	}
+}
+
+// AddCacheInvalidator registers a cache to invalidate on publish and rollback.
+func (p *Publisher) AddCacheInvalidator(cache CacheInvalidator) {
+	if cache != nil {
+		p.caches = append(p.caches, cache)
 	}
 }
 
@@ -124,10 +141,11 @@ func (p *Publisher) Publish(ctx context.Context, req PublishRequest) (*PublishRe
 
 	// TODO: Persist the updated campaign to database
 
-	// Step 5: Refresh materialized views
+	// Step 5: Refresh materialized views and the lexical index
 	if err := p.refreshMaterializedViews(ctx, req.TenantID); err != nil {
 		p.logger.Warn().Err(err).Msg("Failed to refresh materialized views")
 	}
+	p.promoteLexical(req.TenantID, req.CampaignID)
 
 	// Step 6: Emit audit event
 	if err := p.emitPublishEvent(ctx, req, newVersion); err != nil {
@@ -205,6 +223,11 @@ func (p *Publisher) Rollback(ctx context.Context, req RollbackRequest) (*Rollbac
 		p.logger.Warn().Err(err).Msg("Failed to refresh materialized views")
 	}
 
+	// The restored content is only in storage, so reload the tenant's lexical index from it
+	if p.lexicalIndex != nil {
+		p.lexicalIndex.Invalidate(req.TenantID)
+	}
+
 	p.logger.Info().
 		Str("campaign_id", req.CampaignID.String()).
 		Int("from_version", previousVersion).
@@ -260,6 +283,18 @@ func (p *Publisher) refreshMaterializedViews(ctx context.Context, tenantID uuid.
 	return nil
 }
 
+// promoteLexical makes the campaign's staged lexical documents searchable.
+func (p *Publisher) promoteLexical(tenantID, campaignID uuid.UUID) {
+	if p.lexicalIndex == nil {
+		return
+	}
+	promoted := p.lexicalIndex.Promote(tenantID, campaignID)
+	p.logger.Debug().
+		Str("campaign_id", campaignID.String()).
+		Int("documents", promoted).
+		Msg("Promoted lexical index documents")
+}
+
 // emitPublishEvent records a publish audit event.
 func (p *Publisher) emitPublishEvent(ctx context.Context, req PublishRequest, newVersion int) error {
 	// TODO: Insert into lineage_events
@@ -274,8 +309,13 @@ func (p *Publisher) emitRollbackEvent(ctx context.Context, req RollbackRequest,
 
 // invalidateCaches clears cached data for the campaign.
 func (p *Publisher) invalidateCaches(ctx context.Context, tenantID, campaignID uuid.UUID) error {
-	// TODO: Delete cache entries for this campaign
-	return nil
+	var errs []error
+	for _, cache := range p.caches {
+		if err := cache.InvalidateCampaign(ctx, tenantID, campaignID); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
 }
 
 // ResolveConflict marks a spec value conflict as resolved.
@@ -0,0 +1,165 @@
+// Package lexical provides a BM25 lexical index over spec facts and knowledge chunks.
+package lexical
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Token is an analyzed term with its position in the source text.
+type Token struct {
+	Term     string
+	Position int
+}
+
+// Analyzer turns text into normalized, stemmed index terms.
+type Analyzer struct {
+	stopWords map[string]bool
+	spelling  map[string]string
+}
+
+// NewAnalyzer creates a new analyzer with English defaults.
+func NewAnalyzer() *Analyzer {
+	stopWords := []string{
+		"a", "an", "and", "are", "as", "at", "be", "by", "can", "do", "does", "for",
+		"from", "has", "have", "how", "i", "in", "is", "it", "its", "me", "my", "of",
+		"on", "or", "tell", "that", "the", "this", "to", "was", "what", "whats",
+		"which", "with", "you", "your",
+	}
+
+	a := &Analyzer{
+		stopWords: make(map[string]bool, len(stopWords)),
+		// British spellings are folded into US spellings so both match.
+		spelling: map[string]string{
+			"colour":    "color",
+			"colours":   "colors",
+			"tyre":      "tire",
+			"tyres":     "tires",
+			"aluminium": "aluminum",
+			"litre":     "liter",
+			"litres":    "liters",
+			"metre":     "meter",
+			"metres":    "meters",
+			"centre":    "center",
+			"grey":      "gray",
+		},
+	}
+	for _, w := range stopWords {
+		a.stopWords[w] = true
+	}
+	return a
+}
+
+// Analyze splits text into tokens, dropping stop words but keeping positions
+// so that phrase matching sees the original word order.
+func (a *Analyzer) Analyze(text string) []Token {
+	var tokens []Token
+	for pos, word := range splitWords(text) {
+		if a.stopWords[word] {
+			continue
+		}
+		tokens = append(tokens, Token{Term: a.Normalize(word), Position: pos})
+	}
+	return tokens
+}
+
+// Terms returns only the analyzed terms of text.
+func (a *Analyzer) Terms(text string) []string {
+	tokens := a.Analyze(text)
+	terms := make([]string, len(tokens))
+	for i, t := range tokens {
+		terms[i] = t.Term
+	}
+	return terms
+}
+
+// Normalize folds spelling variants and stems a single lowercase word.
+func (a *Analyzer) Normalize(word string) string {
+	if v, ok := a.spelling[word]; ok {
+		word = v
+	}
+	return Stem(word)
+}
+
+// splitWords lowercases text and splits it on anything that is not a letter or
+// digit, keeping decimal points inside numbers.
+func splitWords(text string) []string {
+	runes := []rune(strings.ToLower(text))
+	var words []string
+	var current []rune
+
+	flush := func() {
+		if len(current) > 0 {
+			words = append(words, string(current))
+			current = current[:0]
+		}
+	}
+
+	for i, r := range runes {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			current = append(current, r)
+		case r == '.' && len(current) > 0 && unicode.IsDigit(current[len(current)-1]) &&
+			i+1 < len(runes) && unicode.IsDigit(runes[i+1]):
+			current = append(current, r)
+		case r == '\'':
+			// Drop apostrophes so "what's" becomes "whats"
+		default:
+			flush()
+		}
+	}
+	flush()
+	return words
+}
+
+// Stem applies a light English suffix stemmer. It is deliberately conservative:
+// it only needs to map inflections of the same word onto one term.
+func Stem(word string) string {
+	if len(word) <= 3 || !isAlpha(word) {
+		return word
+	}
+
+	switch {
+	case strings.HasSuffix(word, "ies") && len(word) > 4:
+		word = word[:len(word)-3] + "y"
+	case strings.HasSuffix(word, "sses"):
+		word = word[:len(word)-2]
+	case strings.HasSuffix(word, "ches"), strings.HasSuffix(word, "shes"),
+		strings.HasSuffix(word, "xes"), strings.HasSuffix(word, "zes"):
+		word = word[:len(word)-2]
+	case strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss") &&
+		!strings.HasSuffix(word, "us") && !strings.HasSuffix(word, "is"):
+		word = word[:len(word)-1]
+	}
+
+	switch {
+	case strings.HasSuffix(word, "ing") && len(word) >= 7:
+		word = undouble(word[:len(word)-3])
+	case strings.HasSuffix(word, "ed") && len(word) >= 6:
+		word = undouble(word[:len(word)-2])
+	}
+
+	if strings.HasSuffix(word, "e") && len(word) > 4 {
+		word = word[:len(word)-1]
+	}
+	return word
+}
+
+// undouble removes a doubled final consonant ("fitt" -> "fit").
+func undouble(word string) string {
+	n := len(word)
+	if n >= 2 && word[n-1] == word[n-2] && !strings.ContainsRune("aeiouls", rune(word[n-1])) {
+		return word[:n-1]
+	}
+	return word
+}
+
+// isAlpha reports whether word consists only of ASCII letters.
+func isAlpha(word string) bool {
+	for _, r := range word {
+		if r < 'a' || r > 'z' {
+			return false
+		}
+	}
+	return true
+}
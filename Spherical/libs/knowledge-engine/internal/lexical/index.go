@@ -0,0 +1,710 @@
+// Package lexical provides a BM25 lexical index over spec facts and knowledge chunks.
+package lexical
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// Field identifies a weighted document field.
+type Field string
+
+const (
+	FieldName     Field = "name"
+	FieldCategory Field = "category"
+	FieldValue    Field = "value"
+	FieldText     Field = "text"
+)
+
+// Kind identifies the type of indexed document.
+type Kind string
+
+const (
+	KindSpec  Kind = "spec"
+	KindChunk Kind = "chunk"
+)
+
+// Document is a unit of indexed content.
+type Document struct {
+	ID                uuid.UUID
+	TenantID          uuid.UUID
+	ProductID         uuid.UUID
+	CampaignVariantID uuid.UUID
+	Kind              Kind
+	Fields            map[Field]string
+	// Payload carries caller data returned with search hits.
+	Payload interface{}
+}
+
+// Config holds BM25 and field weighting parameters.
+type Config struct {
+	// K1 controls term frequency saturation.
+	K1 float64
+	// B controls document length normalization.
+	B float64
+	// FieldWeights scales term frequency per field (BM25F).
+	FieldWeights map[Field]float64
+	// PhraseBoost is the share of the remaining score awarded for matching
+	// adjacent query terms as a phrase.
+	PhraseBoost float64
+}
+
+// DefaultConfig returns default index configuration.
+func DefaultConfig() Config {
+	return Config{
+		K1: 1.2,
+		B:  0.75,
+		FieldWeights: map[Field]float64{
+			FieldName:     3.0,
+			FieldCategory: 1.5,
+			FieldValue:    1.0,
+			FieldText:     1.0,
+		},
+		PhraseBoost: 0.3,
+	}
+}
+
+// Query describes a lexical search.
+type Query struct {
+	TenantID uuid.UUID
+	// CampaignVariantIDs restricts the search to these campaigns; empty searches all.
+	CampaignVariantIDs []uuid.UUID
+	ProductIDs         []uuid.UUID
+	Kinds              []Kind
+	// Text is analyzed into terms; "quoted phrases" must match as phrases.
+	Text string
+	// Expansions add alternative terms scored at a reduced weight. They do not
+	// take part in phrase matching or score calibration.
+	Expansions []Expansion
+	Limit      int
+}
+
+// Expansion is alternative query text, such as a synonym or spelling
+// correction, matched with its BM25 contribution scaled by Weight.
+type Expansion struct {
+	Text   string
+	Weight float64
+}
+
+// Hit is a scored search result.
+type Hit struct {
+	Document Document
+	// Score is the raw BM25F score.
+	Score float64
+	// Calibrated is the score relative to a document matching every query term
+	// once in its strongest field, capped to [0, 1], so it is comparable across queries.
+	Calibrated   float64
+	MatchedTerms []string
+}
+
+// Stats summarizes index contents.
+type Stats struct {
+	Tenants    int
+	Partitions int
+	Documents  int
+	Staged     int
+}
+
+// Index is an in-memory BM25F index partitioned by tenant and campaign.
+// Documents are staged on ingest and become searchable when promoted on publish.
+type Index struct {
+	mu       sync.RWMutex
+	config   Config
+	analyzer *Analyzer
+	tenants  map[uuid.UUID]map[uuid.UUID]*partition
+	staged   map[partitionKey][]Document
+	loaded   map[uuid.UUID]bool
+}
+
+type partitionKey struct {
+	tenantID   uuid.UUID
+	campaignID uuid.UUID
+}
+
+type partition struct {
+	docs     map[uuid.UUID]*indexedDoc
+	postings map[string]map[uuid.UUID]*posting
+	fieldLen map[Field]int
+}
+
+type indexedDoc struct {
+	doc     Document
+	lengths map[Field]int
+	terms   []string
+}
+
+type posting struct {
+	positions map[Field][]int
+}
+
+var phraseRe = regexp.MustCompile(`"([^"]+)"`)
+
+// NewIndex creates a new lexical index.
+func NewIndex(cfg Config) *Index {
+	defaults := DefaultConfig()
+	if cfg.K1 <= 0 {
+		cfg.K1 = defaults.K1
+	}
+	if cfg.B < 0 || cfg.B > 1 {
+		cfg.B = defaults.B
+	}
+	if cfg.FieldWeights == nil {
+		cfg.FieldWeights = defaults.FieldWeights
+	}
+	if cfg.PhraseBoost < 0 {
+		cfg.PhraseBoost = 0
+	}
+
+	return &Index{
+		config:   cfg,
+		analyzer: NewAnalyzer(),
+		tenants:  make(map[uuid.UUID]map[uuid.UUID]*partition),
+		staged:   make(map[partitionKey][]Document),
+		loaded:   make(map[uuid.UUID]bool),
+	}
+}
+
+// Analyzer returns the analyzer used by the index.
+func (idx *Index) Analyzer() *Analyzer {
+	return idx.analyzer
+}
+
+// Upsert adds or replaces searchable documents.
+func (idx *Index) Upsert(docs ...Document) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	for _, doc := range docs {
+		idx.upsertLocked(doc)
+	}
+}
+
+// Delete removes a document from a campaign partition.
+func (idx *Index) Delete(tenantID, campaignID, docID uuid.UUID) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if p := idx.partitionLocked(tenantID, campaignID, false); p != nil {
+		p.remove(docID)
+	}
+}
+
+// Stage queues documents for a campaign without making them searchable.
+func (idx *Index) Stage(docs ...Document) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	for _, doc := range docs {
+		key := partitionKey{doc.TenantID, doc.CampaignVariantID}
+		idx.staged[key] = append(idx.staged[key], doc)
+	}
+}
+
+// Promote makes a campaign's staged documents searchable and returns how many were applied.
+func (idx *Index) Promote(tenantID, campaignID uuid.UUID) int {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	key := partitionKey{tenantID, campaignID}
+	docs := idx.staged[key]
+	for _, doc := range docs {
+		idx.upsertLocked(doc)
+	}
+	delete(idx.staged, key)
+	return len(docs)
+}
+
+// ReplaceCampaign atomically swaps the searchable contents of a campaign partition.
+func (idx *Index) ReplaceCampaign(tenantID, campaignID uuid.UUID, docs []Document) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if campaigns, ok := idx.tenants[tenantID]; ok {
+		delete(campaigns, campaignID)
+	}
+	for _, doc := range docs {
+		doc.TenantID = tenantID
+		doc.CampaignVariantID = campaignID
+		idx.upsertLocked(doc)
+	}
+}
+
+// Invalidate drops all searchable documents for a tenant so it is reloaded on next use.
+func (idx *Index) Invalidate(tenantID uuid.UUID) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	delete(idx.tenants, tenantID)
+	delete(idx.loaded, tenantID)
+}
+
+// MarkLoaded records that a tenant's documents have been loaded from storage.
+func (idx *Index) MarkLoaded(tenantID uuid.UUID) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.loaded[tenantID] = true
+}
+
+// Loaded reports whether a tenant's documents were loaded from storage.
+func (idx *Index) Loaded(tenantID uuid.UUID) bool {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	return idx.loaded[tenantID]
+}
+
+// HasDocuments reports whether a tenant has searchable documents.
+func (idx *Index) HasDocuments(tenantID uuid.UUID) bool {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	for _, p := range idx.tenants[tenantID] {
+		if len(p.docs) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Stats returns index statistics.
+func (idx *Index) Stats() Stats {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	stats := Stats{Tenants: len(idx.tenants)}
+	for _, campaigns := range idx.tenants {
+		stats.Partitions += len(campaigns)
+		for _, p := range campaigns {
+			stats.Documents += len(p.docs)
+		}
+	}
+	for _, docs := range idx.staged {
+		stats.Staged += len(docs)
+	}
+	return stats
+}
+
+// Search scores documents against a query using BM25F with phrase support.
+func (idx *Index) Search(q Query) []Hit {
+	required, terms, bigrams := idx.parseQuery(q.Text)
+	if len(terms) == 0 {
+		return nil
+	}
+	original := len(terms)
+	weights := idx.expansionWeights(terms, q.Expansions)
+	for term := range weights {
+		terms = append(terms, term)
+	}
+	// Map iteration order is random; keep matched terms stable
+	sort.Strings(terms[original:])
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	partitions := idx.searchPartitions(q)
+	if len(partitions) == 0 {
+		return nil
+	}
+
+	// Collection statistics across the searched partitions
+	totalDocs := 0
+	fieldLen := make(map[Field]int)
+	for _, p := range partitions {
+		totalDocs += len(p.docs)
+		for f, n := range p.fieldLen {
+			fieldLen[f] += n
+		}
+	}
+	if totalDocs == 0 {
+		return nil
+	}
+	avgLen := make(map[Field]float64, len(fieldLen))
+	for f, n := range fieldLen {
+		avgLen[f] = float64(n) / float64(totalDocs)
+	}
+
+	// Reference score: every term matched once in the highest weighted field
+	refWeight := 0.0
+	for _, w := range idx.config.FieldWeights {
+		refWeight = math.Max(refWeight, w)
+	}
+	refTF := refWeight * (idx.config.K1 + 1) / (idx.config.K1 + refWeight)
+
+	idf := make(map[string]float64, len(terms))
+	dfs := make(map[string]int, len(terms))
+	for _, term := range terms {
+		df := 0
+		for _, p := range partitions {
+			df += len(p.postings[term])
+		}
+		dfs[term] = df
+		idf[term] = math.Log(1 + (float64(totalDocs)-float64(df)+0.5)/(float64(df)+0.5))
+	}
+
+	// Original terms no searched document contains cannot match; when expansions
+	// stand in for them they are left out of the reference score
+	maxScore := 0.0
+	for _, term := range terms[:original] {
+		if len(weights) > 0 && dfs[term] == 0 {
+			continue
+		}
+		maxScore += idf[term] * refTF
+	}
+	if maxScore == 0 {
+		for _, term := range terms[original:] {
+			maxScore += weights[term] * idf[term] * refTF
+		}
+	}
+
+	productFilter := toSet(q.ProductIDs)
+	kindFilter := make(map[Kind]bool, len(q.Kinds))
+	for _, k := range q.Kinds {
+		kindFilter[k] = true
+	}
+
+	type candidate struct {
+		doc     *indexedDoc
+		posting map[string]*posting
+	}
+	candidates := make(map[uuid.UUID]*candidate)
+	for _, p := range partitions {
+		for _, term := range terms {
+			for docID, post := range p.postings[term] {
+				c, ok := candidates[docID]
+				if !ok {
+					d := p.docs[docID]
+					if len(productFilter) > 0 && !productFilter[d.doc.ProductID] {
+						continue
+					}
+					if len(kindFilter) > 0 && !kindFilter[d.doc.Kind] {
+						continue
+					}
+					c = &candidate{doc: d, posting: make(map[string]*posting)}
+					candidates[docID] = c
+				}
+				c.posting[term] = post
+			}
+		}
+	}
+
+	hits := make([]Hit, 0, len(candidates))
+	for _, c := range candidates {
+		if !matchesPhrases(c.posting, required) {
+			continue
+		}
+
+		score := 0.0
+		matched := make([]string, 0, len(c.posting))
+		for _, term := range terms {
+			post, ok := c.posting[term]
+			if !ok {
+				continue
+			}
+			tf := 0.0
+			for f, positions := range post.positions {
+				weight := idx.config.FieldWeights[f]
+				if weight == 0 || avgLen[f] == 0 {
+					continue
+				}
+				norm := 1 - idx.config.B + idx.config.B*float64(c.doc.lengths[f])/avgLen[f]
+				tf += weight * float64(len(positions)) / norm
+			}
+			weight := 1.0
+			if w, ok := weights[term]; ok {
+				weight = w
+			}
+			score += weight * idf[term] * tf * (idx.config.K1 + 1) / (idx.config.K1 + tf)
+			matched = append(matched, term)
+		}
+
+		calibrated := 0.0
+		if maxScore > 0 {
+			calibrated = math.Min(1, score/maxScore)
+		}
+		if len(bigrams) > 0 {
+			phraseMatches := 0
+			for _, bg := range bigrams {
+				if matchesPhrases(c.posting, [][]Token{bg}) {
+					phraseMatches++
+				}
+			}
+			fraction := float64(phraseMatches) / float64(len(bigrams))
+			calibrated += (1 - calibrated) * idx.config.PhraseBoost * fraction
+		}
+
+		hits = append(hits, Hit{
+			Document:     c.doc.doc,
+			Score:        score,
+			Calibrated:   calibrated,
+			MatchedTerms: matched,
+		})
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Calibrated != hits[j].Calibrated {
+			return hits[i].Calibrated > hits[j].Calibrated
+		}
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].Document.ID.String() < hits[j].Document.ID.String()
+	})
+
+	if q.Limit > 0 && len(hits) > q.Limit {
+		hits = hits[:q.Limit]
+	}
+	return hits
+}
+
+// Documents returns the live documents in a query's scope, ignoring its text.
+func (idx *Index) Documents(q Query) []Document {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	productFilter := toSet(q.ProductIDs)
+	kindFilter := make(map[Kind]bool, len(q.Kinds))
+	for _, k := range q.Kinds {
+		kindFilter[k] = true
+	}
+
+	var docs []Document
+	for _, p := range idx.searchPartitions(q) {
+		for _, d := range p.docs {
+			if len(productFilter) > 0 && !productFilter[d.doc.ProductID] {
+				continue
+			}
+			if len(kindFilter) > 0 && !kindFilter[d.doc.Kind] {
+				continue
+			}
+			docs = append(docs, d.doc)
+		}
+	}
+
+	sort.Slice(docs, func(i, j int) bool {
+		return docs[i].ID.String() < docs[j].ID.String()
+	})
+	if q.Limit > 0 && len(docs) > q.Limit {
+		docs = docs[:q.Limit]
+	}
+	return docs
+}
+
+// parseQuery extracts required phrases, unique terms and adjacent term pairs.
+// Phrases keep their token positions, so stop words inside them still count
+// as gaps when matching.
+func (idx *Index) parseQuery(text string) ([][]Token, []string, [][]Token) {
+	var required [][]Token
+	for _, m := range phraseRe.FindAllStringSubmatch(text, -1) {
+		if phrase := idx.analyzer.Analyze(m[1]); len(phrase) > 1 {
+			required = append(required, phrase)
+		}
+	}
+
+	tokens := idx.analyzer.Analyze(strings.ReplaceAll(text, `"`, " "))
+	seen := make(map[string]bool)
+	var terms []string
+	var bigrams [][]Token
+	for i, t := range tokens {
+		if !seen[t.Term] {
+			seen[t.Term] = true
+			terms = append(terms, t.Term)
+		}
+		if i > 0 && tokens[i-1].Position == t.Position-1 {
+			bigrams = append(bigrams, []Token{tokens[i-1], t})
+		}
+	}
+	return required, terms, bigrams
+}
+
+// expansionWeights analyzes expansion text into terms not already in the query,
+// keeping the highest weight seen for each term.
+func (idx *Index) expansionWeights(terms []string, expansions []Expansion) map[string]float64 {
+	if len(expansions) == 0 {
+		return nil
+	}
+	original := make(map[string]bool, len(terms))
+	for _, t := range terms {
+		original[t] = true
+	}
+
+	weights := make(map[string]float64)
+	for _, exp := range expansions {
+		if exp.Weight <= 0 {
+			continue
+		}
+		for _, term := range idx.analyzer.Terms(strings.ReplaceAll(exp.Text, `"`, " ")) {
+			if original[term] {
+				continue
+			}
+			if exp.Weight > weights[term] {
+				weights[term] = math.Min(exp.Weight, 1)
+			}
+		}
+	}
+	return weights
+}
+
+// Vocabulary returns the indexed terms of a tenant with their document frequencies.
+func (idx *Index) Vocabulary(tenantID uuid.UUID) map[string]int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	vocab := make(map[string]int)
+	for _, p := range idx.tenants[tenantID] {
+		for term, postings := range p.postings {
+			vocab[term] += len(postings)
+		}
+	}
+	return vocab
+}
+
+// searchPartitions returns the partitions a query should visit.
+func (idx *Index) searchPartitions(q Query) []*partition {
+	campaigns := idx.tenants[q.TenantID]
+	if len(campaigns) == 0 {
+		return nil
+	}
+
+	if len(q.CampaignVariantIDs) == 0 {
+		partitions := make([]*partition, 0, len(campaigns))
+		for _, p := range campaigns {
+			partitions = append(partitions, p)
+		}
+		return partitions
+	}
+
+	var partitions []*partition
+	for _, id := range q.CampaignVariantIDs {
+		if p, ok := campaigns[id]; ok {
+			partitions = append(partitions, p)
+		}
+	}
+	return partitions
+}
+
+// upsertLocked indexes a document; callers must hold the write lock.
+func (idx *Index) upsertLocked(doc Document) {
+	p := idx.partitionLocked(doc.TenantID, doc.CampaignVariantID, true)
+	p.remove(doc.ID)
+
+	d := &indexedDoc{doc: doc, lengths: make(map[Field]int)}
+	seen := make(map[string]bool)
+	for field, text := range doc.Fields {
+		tokens := idx.analyzer.Analyze(text)
+		d.lengths[field] = len(tokens)
+		p.fieldLen[field] += len(tokens)
+		for _, t := range tokens {
+			docs, ok := p.postings[t.Term]
+			if !ok {
+				docs = make(map[uuid.UUID]*posting)
+				p.postings[t.Term] = docs
+			}
+			post, ok := docs[doc.ID]
+			if !ok {
+				post = &posting{positions: make(map[Field][]int)}
+				docs[doc.ID] = post
+			}
+			post.positions[field] = append(post.positions[field], t.Position)
+			if !seen[t.Term] {
+				seen[t.Term] = true
+				d.terms = append(d.terms, t.Term)
+			}
+		}
+	}
+	p.docs[doc.ID] = d
+}
+
+// partitionLocked returns a campaign partition, optionally creating it.
+func (idx *Index) partitionLocked(tenantID, campaignID uuid.UUID, create bool) *partition {
+	campaigns, ok := idx.tenants[tenantID]
+	if !ok {
+		if !create {
+			return nil
+		}
+		campaigns = make(map[uuid.UUID]*partition)
+		idx.tenants[tenantID] = campaigns
+	}
+	p, ok := campaigns[campaignID]
+	if !ok && create {
+		p = &partition{
+			docs:     make(map[uuid.UUID]*indexedDoc),
+			postings: make(map[string]map[uuid.UUID]*posting),
+			fieldLen: make(map[Field]int),
+		}
+		campaigns[campaignID] = p
+	}
+	return p
+}
+
+// remove deletes a document and its postings from the partition.
+func (p *partition) remove(docID uuid.UUID) {
+	d, ok := p.docs[docID]
+	if !ok {
+		return
+	}
+	for _, term := range d.terms {
+		delete(p.postings[term], docID)
+		if len(p.postings[term]) == 0 {
+			delete(p.postings, term)
+		}
+	}
+	for f, n := range d.lengths {
+		p.fieldLen[f] -= n
+	}
+	delete(p.docs, docID)
+}
+
+// matchesPhrases reports whether every phrase occurs in one field with its
+// terms at the same relative positions as in the query.
+func matchesPhrases(postings map[string]*posting, phrases [][]Token) bool {
+	for _, phrase := range phrases {
+		if !matchesPhrase(postings, phrase) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchesPhrase(postings map[string]*posting, phrase []Token) bool {
+	first, ok := postings[phrase[0].Term]
+	if !ok {
+		return false
+	}
+	for field, starts := range first.positions {
+	next:
+		for _, start := range starts {
+			for i := 1; i < len(phrase); i++ {
+				post, ok := postings[phrase[i].Term]
+				offset := phrase[i].Position - phrase[0].Position
+				if !ok || !containsInt(post.positions[field], start+offset) {
+					continue next
+				}
+			}
+			return true
+		}
+	}
+	return false
+}
+
+func containsInt(values []int, v int) bool {
+	for _, x := range values {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+func toSet(ids []uuid.UUID) map[uuid.UUID]bool {
+	set := make(map[uuid.UUID]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	return set
+}
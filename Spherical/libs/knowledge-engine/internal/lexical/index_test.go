@@ -0,0 +1,157 @@
+package lexical
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func specDoc(tenant, campaign uuid.UUID, category, name, value string) Document {
+	return Document{
+		ID:                uuid.New(),
+		TenantID:          tenant,
+		CampaignVariantID: campaign,
+		Kind:              KindSpec,
+		Fields: map[Field]string{
+			FieldCategory: category,
+			FieldName:     name,
+			FieldValue:    value,
+		},
+	}
+}
+
+func TestStem(t *testing.T) {
+	assert.Equal(t, Stem("seats"), Stem("seat"))
+	assert.Equal(t, Stem("batteries"), Stem("battery"))
+	assert.Equal(t, Stem("charging"), Stem("charge"))
+	assert.Equal(t, "speed", Stem("speed"))
+	assert.Equal(t, "2.5", Stem("2.5"))
+}
+
+func TestAnalyzer_SpellingAndStopWords(t *testing.T) {
+	a := NewAnalyzer()
+	assert.Equal(t, a.Terms("exterior colors"), a.Terms("What are the exterior colours?"))
+}
+
+func TestIndex_SearchFieldWeights(t *testing.T) {
+	tenant, campaign := uuid.New(), uuid.New()
+	idx := NewIndex(DefaultConfig())
+
+	byName := specDoc(tenant, campaign, "Engine", "Fuel Tank Capacity", "45 liters")
+	byValue := specDoc(tenant, campaign, "Engine", "Fuel Type", "Petrol, tank-fed")
+	unrelated := specDoc(tenant, campaign, "Dimensions", "Length", "4315 mm")
+	idx.Upsert(byName, byValue, unrelated)
+
+	hits := idx.Search(Query{TenantID: tenant, Text: "tank"})
+	require.Len(t, hits, 2)
+	assert.Equal(t, byName.ID, hits[0].Document.ID)
+	assert.Greater(t, hits[0].Calibrated, hits[1].Calibrated)
+	assert.LessOrEqual(t, hits[0].Calibrated, 1.0)
+}
+
+func TestIndex_Phrases(t *testing.T) {
+	tenant, campaign := uuid.New(), uuid.New()
+	idx := NewIndex(DefaultConfig())
+
+	adjacent := specDoc(tenant, campaign, "Safety", "Child Seat Anchors", "ISOFIX")
+	apart := specDoc(tenant, campaign, "Interior", "Seat Material", "Child-safe fabric")
+	idx.Upsert(adjacent, apart)
+
+	hits := idx.Search(Query{TenantID: tenant, Text: "child seat"})
+	require.Len(t, hits, 2)
+	assert.Equal(t, adjacent.ID, hits[0].Document.ID)
+
+	hits = idx.Search(Query{TenantID: tenant, Text: `"seat child"`})
+	assert.Empty(t, hits)
+
+	hits = idx.Search(Query{TenantID: tenant, Text: `"child seat"`})
+	require.Len(t, hits, 1)
+	assert.Equal(t, adjacent.ID, hits[0].Document.ID)
+
+	t.Run("stop words inside phrases", func(t *testing.T) {
+		ownership := specDoc(tenant, campaign, "Ownership", "Total Cost of Ownership", "5 years")
+		adjacentTerms := specDoc(tenant, campaign, "Ownership", "Low Cost Ownership Plan", "Yes")
+		idx.Upsert(ownership, adjacentTerms)
+
+		hits := idx.Search(Query{TenantID: tenant, Text: `"cost of ownership"`})
+		require.Len(t, hits, 1)
+		assert.Equal(t, ownership.ID, hits[0].Document.ID)
+	})
+}
+
+func TestIndex_PartitionsAndStaging(t *testing.T) {
+	tenant, other := uuid.New(), uuid.New()
+	published, draft := uuid.New(), uuid.New()
+	idx := NewIndex(DefaultConfig())
+
+	idx.Upsert(specDoc(tenant, published, "Engine", "Power", "150 hp"))
+	idx.Upsert(specDoc(other, published, "Engine", "Power", "200 hp"))
+	idx.Stage(specDoc(tenant, draft, "Engine", "Power", "160 hp"))
+
+	hits := idx.Search(Query{TenantID: tenant, Text: "power"})
+	require.Len(t, hits, 1)
+	assert.Equal(t, "150 hp", hits[0].Document.Fields[FieldValue])
+
+	assert.Equal(t, 1, idx.Promote(tenant, draft))
+	assert.Len(t, idx.Search(Query{TenantID: tenant, Text: "power"}), 2)
+
+	hits = idx.Search(Query{TenantID: tenant, CampaignVariantIDs: []uuid.UUID{draft}, Text: "power"})
+	require.Len(t, hits, 1)
+	assert.Equal(t, "160 hp", hits[0].Document.Fields[FieldValue])
+
+	idx.Invalidate(tenant)
+	assert.False(t, idx.HasDocuments(tenant))
+	assert.True(t, idx.HasDocuments(other))
+}
+
+func TestIndex_UpsertReplaces(t *testing.T) {
+	tenant, campaign := uuid.New(), uuid.New()
+	idx := NewIndex(DefaultConfig())
+
+	doc := specDoc(tenant, campaign, "Wheels", "Wheel Size", "16 inch")
+	idx.Upsert(doc)
+	doc.Fields = map[Field]string{FieldName: "Tyre Size", FieldValue: "205/55 R16"}
+	idx.Upsert(doc)
+
+	assert.Empty(t, idx.Search(Query{TenantID: tenant, Text: "wheel"}))
+	assert.Len(t, idx.Search(Query{TenantID: tenant, Text: "tire"}), 1)
+	assert.Equal(t, 1, idx.Stats().Documents)
+}
+
+func TestIndex_Expansions(t *testing.T) {
+	tenant, campaign := uuid.New(), uuid.New()
+	idx := NewIndex(DefaultConfig())
+
+	efficiency := specDoc(tenant, campaign, "Engine", "Fuel Efficiency", "18 km/l")
+	mileage := specDoc(tenant, campaign, "Engine", "Mileage", "18 km/l")
+	idx.Upsert(efficiency, specDoc(tenant, campaign, "Dimensions", "Length", "4315 mm"))
+
+	assert.Empty(t, idx.Search(Query{TenantID: tenant, Text: "mileage"}))
+
+	hits := idx.Search(Query{
+		TenantID:   tenant,
+		Text:       "mileage",
+		Expansions: []Expansion{{Text: "fuel efficiency", Weight: 0.5}},
+	})
+	require.Len(t, hits, 1)
+	assert.Equal(t, efficiency.ID, hits[0].Document.ID)
+	assert.Equal(t, []string{"efficiency", "fuel"}, hits[0].MatchedTerms)
+
+	// Expansion matches score below matches on the original terms
+	idx.Upsert(mileage)
+	hits = idx.Search(Query{
+		TenantID:   tenant,
+		Text:       "mileage",
+		Expansions: []Expansion{{Text: "fuel efficiency", Weight: 0.5}},
+	})
+	require.Len(t, hits, 2)
+	assert.Equal(t, mileage.ID, hits[0].Document.ID)
+	assert.Greater(t, hits[0].Score, hits[1].Score)
+
+	vocab := idx.Vocabulary(tenant)
+	assert.Equal(t, 2, vocab["engin"])
+	assert.Equal(t, 1, vocab["mileag"])
+	assert.Empty(t, idx.Vocabulary(uuid.New()))
+}
@@ -0,0 +1,115 @@
+// Package lexical provides loading of index documents from storage.
+package lexical
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/storage"
+)
+
+// warmPageSize is the number of spec view rows or chunks loaded per query when warming.
+const warmPageSize = 1000
+
+// SpecSource provides published spec view rows.
+type SpecSource interface {
+	Query(ctx context.Context, q storage.SpecViewQuery) (*storage.SpecViewResult, error)
+}
+
+// ChunkSource provides the knowledge chunks of published campaigns.
+// storage.KnowledgeChunkRepository implements it.
+type ChunkSource interface {
+	ListPublishedAfter(ctx context.Context, tenantID uuid.UUID, after uuid.UUID, limit int) ([]*storage.KnowledgeChunk, error)
+}
+
+// SpecDocument builds an index document for a spec view row. The row is kept as payload.
+func SpecDocument(sv storage.SpecViewLatest) Document {
+	value := sv.Value
+	if sv.Unit != nil && *sv.Unit != "" {
+		value += " " + *sv.Unit
+	}
+	return Document{
+		ID:                sv.ID,
+		TenantID:          sv.TenantID,
+		ProductID:         sv.ProductID,
+		CampaignVariantID: sv.CampaignVariantID,
+		Kind:              KindSpec,
+		Fields: map[Field]string{
+			FieldName:     sv.SpecName,
+			FieldCategory: sv.CategoryName,
+			FieldValue:    value,
+		},
+		Payload: sv,
+	}
+}
+
+// ChunkDocument builds an index document for a knowledge chunk. The chunk is kept as payload.
+func ChunkDocument(chunk storage.KnowledgeChunk) Document {
+	var campaignID uuid.UUID
+	if chunk.CampaignVariantID != nil {
+		campaignID = *chunk.CampaignVariantID
+	}
+	return Document{
+		ID:                chunk.ID,
+		TenantID:          chunk.TenantID,
+		ProductID:         chunk.ProductID,
+		CampaignVariantID: campaignID,
+		Kind:              KindChunk,
+		Fields: map[Field]string{
+			FieldCategory: string(chunk.ChunkType),
+			FieldText:     chunk.Text,
+		},
+		Payload: chunk,
+	}
+}
+
+// Warm loads a tenant's published specs, and chunks when chunks is not nil,
+// into the index, replacing existing partitions. It returns the number of
+// documents loaded.
+func (idx *Index) Warm(ctx context.Context, src SpecSource, chunks ChunkSource, tenantID uuid.UUID) (int, error) {
+	byCampaign := make(map[uuid.UUID][]Document)
+	total := 0
+	for offset := 0; ; offset += warmPageSize {
+		result, err := src.Query(ctx, storage.SpecViewQuery{
+			TenantID: tenantID,
+			Limit:    warmPageSize,
+			Offset:   offset,
+		})
+		if err != nil {
+			return 0, fmt.Errorf("load specs: %w", err)
+		}
+		for _, sv := range result.Specs {
+			byCampaign[sv.CampaignVariantID] = append(byCampaign[sv.CampaignVariantID], SpecDocument(sv))
+		}
+		total += len(result.Specs)
+		if len(result.Specs) < warmPageSize {
+			break
+		}
+	}
+
+	if chunks != nil {
+		after := uuid.Nil
+		for {
+			page, err := chunks.ListPublishedAfter(ctx, tenantID, after, warmPageSize)
+			if err != nil {
+				return 0, fmt.Errorf("load chunks: %w", err)
+			}
+			for _, chunk := range page {
+				doc := ChunkDocument(*chunk)
+				byCampaign[doc.CampaignVariantID] = append(byCampaign[doc.CampaignVariantID], doc)
+			}
+			total += len(page)
+			if len(page) < warmPageSize {
+				break
+			}
+			after = page[len(page)-1].ID
+		}
+	}
+
+	for campaignID, docs := range byCampaign {
+		idx.ReplaceCampaign(tenantID, campaignID, docs)
+	}
+	idx.MarkLoaded(tenantID)
+	return total, nil
+}
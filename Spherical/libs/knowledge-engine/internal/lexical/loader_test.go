@@ -0,0 +1,55 @@
+package lexical
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubSpecs []storage.SpecViewLatest
+
+func (s stubSpecs) Query(ctx context.Context, q storage.SpecViewQuery) (*storage.SpecViewResult, error) {
+	if q.Offset > 0 {
+		return &storage.SpecViewResult{}, nil
+	}
+	return &storage.SpecViewResult{Specs: s, TotalCount: len(s)}, nil
+}
+
+type stubChunks []*storage.KnowledgeChunk
+
+func (s stubChunks) ListPublishedAfter(ctx context.Context, tenantID uuid.UUID, after uuid.UUID, limit int) ([]*storage.KnowledgeChunk, error) {
+	var page []*storage.KnowledgeChunk
+	for _, chunk := range s {
+		if chunk.TenantID == tenantID && chunk.ID.String() > after.String() && len(page) < limit {
+			page = append(page, chunk)
+		}
+	}
+	return page, nil
+}
+
+func TestIndex_WarmLoadsSpecsAndChunks(t *testing.T) {
+	tenant, campaign := uuid.New(), uuid.New()
+	specs := stubSpecs{{ID: uuid.New(), TenantID: tenant, CampaignVariantID: campaign, SpecName: "Fuel Tank Capacity", Value: "45"}}
+	chunks := stubChunks{{ID: uuid.New(), TenantID: tenant, CampaignVariantID: &campaign, ChunkType: storage.ChunkTypeUSP, Text: "Panoramic sunroof with one-touch controls"}}
+
+	idx := NewIndex(DefaultConfig())
+	count, err := idx.Warm(context.Background(), specs, chunks, tenant)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+	assert.True(t, idx.Loaded(tenant))
+
+	hits := idx.Search(Query{TenantID: tenant, Kinds: []Kind{KindChunk}, Text: "sunroof"})
+	require.Len(t, hits, 1)
+	assert.Equal(t, chunks[0].ID, hits[0].Document.ID)
+	assert.Len(t, idx.Search(Query{TenantID: tenant, Kinds: []Kind{KindSpec}, Text: "tank"}), 1)
+
+	// Without a chunk source only specs are loaded
+	idx = NewIndex(DefaultConfig())
+	count, err = idx.Warm(context.Background(), specs, nil, tenant)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/pkgerrors"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Logger wraps zerolog with Knowledge Engine specific functionality.
@@ -102,7 +103,11 @@ func (l *Logger) Fatal() *LogEvent {
 func (l *Logger) WithContext(ctx context.Context) *Logger {
 	// Extract trace ID if present
 	if traceID := TraceIDFromContext(ctx); traceID != "" {
-		return &Logger{zl: l.zl.With().Str("trace_id", traceID).Logger()}
+		zc := l.zl.With().Str("trace_id", traceID)
+		if sc := trace.SpanContextFromContext(ctx); sc.HasSpanID() {
+			zc = zc.Str("span_id", sc.SpanID().String())
+		}
+		return &Logger{zl: zc.Logger()}
 	}
 	return l
 }
@@ -210,6 +215,17 @@ func (e *LogEvent) Interface(key string, val interface{}) *LogEvent {
 	return e
 }
 
+// Ctx adds the trace and span IDs of the span in ctx, if any.
+func (e *LogEvent) Ctx(ctx context.Context) *LogEvent {
+	if traceID := TraceIDFromContext(ctx); traceID != "" {
+		e.evt = e.evt.Str("trace_id", traceID)
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.HasSpanID() {
+		e.evt = e.evt.Str("span_id", sc.SpanID().String())
+	}
+	return e
+}
+
 // Msg sends the log event with a message.
 func (e *LogEvent) Msg(msg string) {
 	e.evt.Msg(msg)
@@ -257,13 +273,17 @@ func ContextWithTraceID(ctx context.Context, traceID string) context.Context {
 	return context.WithValue(ctx, traceIDKey, traceID)
 }
 
-// TraceIDFromContext extracts a trace ID from the context.
+// TraceIDFromContext extracts a trace ID from the context, falling back to
+// the ID of the OpenTelemetry trace the context's span belongs to.
 func TraceIDFromContext(ctx context.Context) string {
 	if v := ctx.Value(traceIDKey); v != nil {
 		if s, ok := v.(string); ok {
 			return s
 		}
 	}
+	if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+		return sc.TraceID().String()
+	}
 	return ""
 }
 
@@ -0,0 +1,189 @@
+// Package observability provides Prometheus metrics for the Knowledge Engine.
+package observability
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// DefaultLatencyBuckets are histogram upper bounds in seconds.
+var DefaultLatencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Metrics is the Knowledge Engine's metric set, shared by the retrieval
+// router, ingestion pipeline, comparison materializer and HTTP middleware.
+// A nil *Metrics records nothing, so components work without it.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	retrievalDuration *prometheus.HistogramVec
+	keywordConfidence prometheus.Histogram
+	cacheRequests     *prometheus.CounterVec
+	zeroResults       *prometheus.CounterVec
+	ingestionJobs     *prometheus.CounterVec
+	embeddingCalls    *prometheus.CounterVec
+	httpRequests      *prometheus.CounterVec
+	httpDuration      *prometheus.HistogramVec
+
+	mu     sync.Mutex
+	gauges map[string]prometheus.Collector
+}
+
+// NewMetrics creates the metric set on a new registry, along with the Go
+// runtime and process collectors.
+func NewMetrics() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		retrievalDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "knowledge_engine_retrieval_duration_seconds",
+			Help:    "Retrieval query latency by retrieval path and intent.",
+			Buckets: DefaultLatencyBuckets,
+		}, []string{"path", "intent"}),
+		keywordConfidence: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "knowledge_engine_keyword_confidence",
+			Help:    "Confidence of structured spec lookups that found facts.",
+			Buckets: []float64{0.1, 0.2, 0.3, 0.4, 0.5, 0.6, 0.7, 0.8, 0.9, 1},
+		}),
+		cacheRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "knowledge_engine_cache_requests_total",
+			Help: "Cache lookups by cache and result (hit or miss).",
+		}, []string{"cache", "result"}),
+		zeroResults: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "knowledge_engine_zero_result_queries_total",
+			Help: "Retrieval queries that returned no facts, chunks or comparisons.",
+		}, []string{"intent"}),
+		ingestionJobs: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "knowledge_engine_ingestion_jobs_total",
+			Help: "Ingestion jobs by final status.",
+		}, []string{"status"}),
+		embeddingCalls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "knowledge_engine_embedding_requests_total",
+			Help: "Embedding provider calls by result (ok or error).",
+		}, []string{"result"}),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "knowledge_engine_http_requests_total",
+			Help: "HTTP requests by method, route pattern and status code.",
+		}, []string{"method", "route", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "knowledge_engine_http_request_duration_seconds",
+			Help:    "HTTP request latency by method and route pattern.",
+			Buckets: DefaultLatencyBuckets,
+		}, []string{"method", "route"}),
+		gauges: make(map[string]prometheus.Collector),
+	}
+	m.registry.MustRegister(
+		m.retrievalDuration,
+		m.keywordConfidence,
+		m.cacheRequests,
+		m.zeroResults,
+		m.ingestionJobs,
+		m.embeddingCalls,
+		m.httpRequests,
+		m.httpDuration,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+// Registry returns the registry the metrics are registered on, so callers can
+// register their own collectors or gather the metrics.
+func (m *Metrics) Registry() *prometheus.Registry {
+	if m == nil {
+		return nil
+	}
+	return m.registry
+}
+
+// Handler serves the metrics in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// GaugeFunc registers a gauge read from fn at scrape time, replacing any gauge
+// previously registered under the name.
+func (m *Metrics) GaugeFunc(name, help string, fn func() float64) {
+	if m == nil {
+		return
+	}
+	gauge := prometheus.NewGaugeFunc(prometheus.GaugeOpts{Name: name, Help: help}, fn)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if previous, ok := m.gauges[name]; ok {
+		m.registry.Unregister(previous)
+	}
+	m.registry.MustRegister(gauge)
+	m.gauges[name] = gauge
+}
+
+// ObserveRetrieval records the latency of a retrieval query.
+func (m *Metrics) ObserveRetrieval(path, intent string, d time.Duration) {
+	if m == nil {
+		return
+	}
+	m.retrievalDuration.WithLabelValues(path, intent).Observe(d.Seconds())
+}
+
+// KeywordConfidence records the confidence of a structured spec lookup.
+func (m *Metrics) KeywordConfidence(confidence float64) {
+	if m == nil {
+		return
+	}
+	m.keywordConfidence.Observe(confidence)
+}
+
+// CacheLookup counts a lookup in the named cache.
+func (m *Metrics) CacheLookup(cache string, hit bool) {
+	if m == nil {
+		return
+	}
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	m.cacheRequests.WithLabelValues(cache, result).Inc()
+}
+
+// ZeroResult counts a retrieval query that found nothing.
+func (m *Metrics) ZeroResult(intent string) {
+	if m == nil {
+		return
+	}
+	m.zeroResults.WithLabelValues(intent).Inc()
+}
+
+// IngestionJob counts an ingestion job that finished with status.
+func (m *Metrics) IngestionJob(status string) {
+	if m == nil {
+		return
+	}
+	m.ingestionJobs.WithLabelValues(status).Inc()
+}
+
+// EmbeddingCall counts a call to the embedding provider.
+func (m *Metrics) EmbeddingCall(err error) {
+	if m == nil {
+		return
+	}
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	m.embeddingCalls.WithLabelValues(result).Inc()
+}
+
+// HTTPRequest records a served HTTP request. route is the matched route
+// pattern rather than the raw path, which would make unbounded series.
+func (m *Metrics) HTTPRequest(method, route string, status int, d time.Duration) {
+	if m == nil {
+		return
+	}
+	m.httpRequests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+	m.httpDuration.WithLabelValues(method, route).Observe(d.Seconds())
+}
@@ -0,0 +1,59 @@
+package observability
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetrics_GaugeFunc(t *testing.T) {
+	metrics := NewMetrics()
+	metrics.GaugeFunc("queue_depth", "Queued jobs.", func() float64 { return 3 })
+	metrics.GaugeFunc("queue_depth", "Queued jobs.", func() float64 { return 5 })
+
+	families, err := metrics.Registry().Gather()
+	require.NoError(t, err)
+	var found bool
+	for _, family := range families {
+		if family.GetName() == "queue_depth" {
+			found = true
+			require.Len(t, family.GetMetric(), 1)
+			assert.Equal(t, 5.0, family.GetMetric()[0].GetGauge().GetValue(), "re-registering replaces the gauge")
+		}
+	}
+	assert.True(t, found)
+}
+
+func TestMetrics(t *testing.T) {
+	t.Run("nil metrics record nothing", func(t *testing.T) {
+		var metrics *Metrics
+		assert.NotPanics(t, func() {
+			metrics.ObserveRetrieval("hybrid", "spec_lookup", time.Millisecond)
+			metrics.CacheLookup("response", true)
+			metrics.EmbeddingCall(nil)
+			metrics.GaugeFunc("gauge", "Gauge.", func() float64 { return 0 })
+		})
+	})
+
+	metrics := NewMetrics()
+	metrics.ObserveRetrieval("hybrid", "spec_lookup", 20*time.Millisecond)
+	metrics.CacheLookup("semantic", false)
+	metrics.EmbeddingCall(errors.New("timeout"))
+	metrics.IngestionJob("failed")
+	metrics.HTTPRequest("GET", "/api/v1/lineage/{resourceType}/{resourceId}", 404, time.Millisecond)
+
+	rec := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Contains(t, rec.Header().Get("Content-Type"), "text/plain")
+	assert.Contains(t, rec.Body.String(), "# TYPE knowledge_engine_retrieval_duration_seconds histogram")
+	body := rec.Body.String()
+	assert.Contains(t, body, `knowledge_engine_retrieval_duration_seconds_count{intent="spec_lookup",path="hybrid"} 1`)
+	assert.Contains(t, body, `knowledge_engine_cache_requests_total{cache="semantic",result="miss"} 1`)
+	assert.Contains(t, body, `knowledge_engine_embedding_requests_total{result="error"} 1`)
+	assert.Contains(t, body, `knowledge_engine_ingestion_jobs_total{status="failed"} 1`)
+	assert.Contains(t, body, `knowledge_engine_http_requests_total{method="GET",route="/api/v1/lineage/{resourceType}/{resourceId}",status="404"} 1`)
+}
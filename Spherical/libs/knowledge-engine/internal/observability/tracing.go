@@ -0,0 +1,115 @@
+// Package observability provides OpenTelemetry tracing for the Knowledge Engine.
+package observability
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracerName is the instrumentation scope of Knowledge Engine spans.
+const TracerName = "github.com/spherical-ai/spherical/libs/knowledge-engine"
+
+// TracingConfig configures span export.
+type TracingConfig struct {
+	Enabled bool
+	// Endpoint is the OTLP/HTTP collector base URL; spans are posted to
+	// Endpoint + "/v1/traces".
+	Endpoint    string
+	ServiceName string
+	// SampleRatio is the fraction of new traces recorded. Default: 1.
+	SampleRatio float64
+}
+
+// InitTracing installs the W3C trace context propagator and, when enabled,
+// a tracer provider exporting spans to the OTLP endpoint. The returned
+// function flushes and stops export.
+func InitTracing(cfg TracingConfig) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{}))
+	if !cfg.Enabled {
+		return func(context.Context) error { return nil }, nil
+	}
+	if cfg.Endpoint == "" {
+		return nil, errors.New("tracing endpoint is required")
+	}
+	if cfg.ServiceName == "" {
+		cfg.ServiceName = "knowledge-engine"
+	}
+	if cfg.SampleRatio <= 0 || cfg.SampleRatio > 1 {
+		cfg.SampleRatio = 1
+	}
+
+	exporter, err := NewOTLPExporter(context.Background(), cfg.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	res := resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(cfg.ServiceName))
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// StartSpan starts a span on the global tracer provider, which records
+// nothing until InitTracing enables export.
+func StartSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(TracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// EndSpan records err, if any, on span and ends it.
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// StartServerSpan starts a server span for an incoming request, continuing
+// the trace in its W3C traceparent header when the caller sent one.
+func StartServerSpan(ctx context.Context, name string, header http.Header, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(header))
+	return otel.Tracer(TracerName).Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(attrs...))
+}
+
+// StartClientSpan starts a client span for an outgoing request and writes
+// its W3C trace context to header, so the server continues the trace.
+func StartClientSpan(ctx context.Context, name string, header http.Header, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	ctx, span := otel.Tracer(TracerName).Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(header))
+	return ctx, span
+}
+
+// NewOTLPExporter creates an OTLP/HTTP exporter posting protobuf-encoded spans
+// to the collector at endpoint + "/v1/traces". Plain http endpoints are sent
+// without TLS.
+func NewOTLPExporter(ctx context.Context, endpoint string) (*otlptrace.Exporter, error) {
+	exporter, err := otlptracehttp.New(ctx,
+		otlptracehttp.WithEndpointURL(strings.TrimSuffix(endpoint, "/")+"/v1/traces"),
+		otlptracehttp.WithTimeout(10*time.Second),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+	}
+	return exporter, nil
+}
@@ -0,0 +1,117 @@
+package observability
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// useRecorder routes spans to an in-memory exporter for the test.
+func useRecorder(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return exporter
+}
+
+func TestStartServerSpan_ContinuesRemoteTrace(t *testing.T) {
+	exporter := useRecorder(t)
+	header := http.Header{}
+	header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	ctx, span := StartServerSpan(context.Background(), "GET /api/v1/query", header)
+	_, child := StartSpan(ctx, "retrieval.Query")
+	child.End()
+	span.End()
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 2)
+	server := spans[1]
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.SpanContext.TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", server.Parent.SpanID().String())
+	assert.True(t, server.Parent.IsRemote())
+	assert.Equal(t, server.SpanContext.SpanID(), spans[0].Parent.SpanID())
+
+	t.Run("client spans send their context", func(t *testing.T) {
+		outgoing := http.Header{}
+		_, client := StartClientSpan(ctx, "retrieval.v1.RetrievalService/Query", outgoing)
+		client.End()
+		assert.Contains(t, outgoing.Get("traceparent"), "4bf92f3577b34da6a3ce929d0e0e4736")
+	})
+}
+
+func TestLogEvent_Ctx(t *testing.T) {
+	useRecorder(t)
+	var out bytes.Buffer
+	logger := NewLogger(LogConfig{Level: "info", Output: &out})
+
+	ctx, span := StartSpan(context.Background(), "ingest.Ingest")
+	defer span.End()
+	logger.Info().Ctx(ctx).Msg("Starting ingestion job")
+
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal(out.Bytes(), &entry))
+	assert.Equal(t, span.SpanContext().TraceID().String(), entry["trace_id"])
+	assert.Equal(t, span.SpanContext().SpanID().String(), entry["span_id"])
+}
+
+func TestOTLPExporter(t *testing.T) {
+	var body []byte
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/traces", r.URL.Path)
+		assert.Equal(t, "application/x-protobuf", r.Header.Get("Content-Type"))
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer collector.Close()
+
+	ctx := context.Background()
+	exporter, err := NewOTLPExporter(ctx, collector.URL)
+	require.NoError(t, err)
+	defer exporter.Shutdown(ctx)
+
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	spanCtx, parent := provider.Tracer(TracerName).Start(ctx, "retrieval.Query")
+	_, child := provider.Tracer(TracerName).Start(spanCtx, "vector.Search")
+	child.End()
+	parent.End()
+
+	var req coltracepb.ExportTraceServiceRequest
+	require.NoError(t, proto.Unmarshal(body, &req))
+	require.Len(t, req.ResourceSpans, 1)
+	require.Len(t, req.ResourceSpans[0].ScopeSpans, 1)
+	assert.Equal(t, TracerName, req.ResourceSpans[0].ScopeSpans[0].Scope.Name)
+	spans := req.ResourceSpans[0].ScopeSpans[0].Spans
+	require.Len(t, spans, 1, "the syncer exports each span as it ends")
+	assert.Equal(t, "retrieval.Query", spans[0].Name)
+	traceID := parent.SpanContext().TraceID()
+	assert.Equal(t, traceID[:], spans[0].TraceId)
+	assert.Empty(t, spans[0].ParentSpanId)
+
+	t.Run("collector errors are returned", func(t *testing.T) {
+		failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+		}))
+		defer failing.Close()
+		exporter, err := NewOTLPExporter(ctx, failing.URL)
+		require.NoError(t, err)
+		stub := tracetest.SpanStub{Name: "db.Query"}
+		assert.Error(t, exporter.ExportSpans(ctx, []sdktrace.ReadOnlySpan{stub.Snapshot()}))
+	})
+}
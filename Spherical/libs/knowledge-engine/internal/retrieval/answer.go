@@ -0,0 +1,489 @@
+// Package retrieval provides grounded answer synthesis over retrieved evidence.
+package retrieval
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AnswerOutcome reports whether an answer could be grounded in evidence.
+type AnswerOutcome string
+
+const (
+	// AnswerGrounded means the answer cites at least one item of evidence.
+	AnswerGrounded AnswerOutcome = "answered"
+	// AnswerInsufficientEvidence means the evidence does not answer the question.
+	AnswerInsufficientEvidence AnswerOutcome = "insufficient_evidence"
+)
+
+// Generator names recorded in Answer.
+const (
+	GeneratorTemplate = "template"
+	GeneratorOpenAI   = "openai"
+)
+
+// InsufficientEvidenceReply is what a generator returns when the evidence
+// does not answer the question.
+const InsufficientEvidenceReply = "INSUFFICIENT_EVIDENCE"
+
+// insufficientEvidenceText is the answer text for the insufficient evidence outcome.
+const insufficientEvidenceText = "There is not enough evidence in the product documentation to answer this question."
+
+// AnswerGenerator composes answer text from numbered evidence. Claims cite
+// evidence inline as [n]; an empty reply or InsufficientEvidenceReply means
+// the evidence does not answer the question.
+type AnswerGenerator interface {
+	Name() string
+	Generate(ctx context.Context, question string, evidence []Citation) (string, error)
+}
+
+// AnswerConfig bounds the evidence offered to a generator.
+type AnswerConfig struct {
+	// MaxEvidence bounds the numbered evidence list (default 8).
+	MaxEvidence int
+	// MinFactConfidence drops facts extracted with lower confidence (default 0.5).
+	MinFactConfidence float64
+	// MinChunkScore drops chunks with lower similarity (default 0.3).
+	MinChunkScore float64
+}
+
+// withDefaults fills unset answer parameters.
+func (c AnswerConfig) withDefaults() AnswerConfig {
+	if c.MaxEvidence <= 0 {
+		c.MaxEvidence = 8
+	}
+	if c.MinFactConfidence <= 0 {
+		c.MinFactConfidence = 0.5
+	}
+	if c.MinChunkScore <= 0 {
+		c.MinChunkScore = 0.3
+	}
+	return c
+}
+
+// Citation is one numbered item of evidence; answers refer to it as [Index].
+type Citation struct {
+	Index  int
+	Kind   EvidenceKind
+	Text   string
+	Fact   *SpecFact
+	Chunk  *SemanticChunk
+	Source SourceRef
+}
+
+// Answer is a composed answer with the citations it uses.
+type Answer struct {
+	Question  string
+	Text      string
+	Outcome   AnswerOutcome
+	Citations []Citation
+	Generator string
+	// Retrieval is the underlying retrieval response the evidence came from.
+	Retrieval *RetrievalResponse
+}
+
+// SetAnswerGenerator replaces the template generator, e.g. with an OpenAIGenerator.
+func (r *Router) SetAnswerGenerator(generator AnswerGenerator) {
+	if generator != nil {
+		r.generator = generator
+	}
+}
+
+// Answer retrieves evidence for the question and composes a cited answer.
+// When no evidence supports an answer the outcome is AnswerInsufficientEvidence.
+func (r *Router) Answer(ctx context.Context, req RetrievalRequest) (*Answer, error) {
+	resp, err := r.Query(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	question := req.Question
+	if resp.Rewrite != nil {
+		question = resp.Rewrite.Question
+	}
+	answer := &Answer{
+		Question:  question,
+		Text:      insufficientEvidenceText,
+		Outcome:   AnswerInsufficientEvidence,
+		Generator: r.generator.Name(),
+		Retrieval: resp,
+	}
+
+	evidence := answerEvidence(resp, r.config.Answer.withDefaults())
+	if len(evidence) == 0 {
+		return answer, nil
+	}
+
+	text, err := r.generator.Generate(ctx, question, evidence)
+	if err != nil {
+		if _, ok := r.generator.(*TemplateGenerator); ok {
+			return nil, fmt.Errorf("generate answer: %w", err)
+		}
+		r.logger.Warn().Err(err).Str("generator", r.generator.Name()).Msg("Answer generation failed, using template")
+		fallback := NewTemplateGenerator()
+		answer.Generator = fallback.Name()
+		if text, err = fallback.Generate(ctx, question, evidence); err != nil {
+			return nil, fmt.Errorf("generate answer: %w", err)
+		}
+	}
+
+	text, cited := resolveCitations(text, evidence)
+	if len(cited) == 0 {
+		return answer, nil
+	}
+	answer.Text = text
+	answer.Outcome = AnswerGrounded
+	answer.Citations = cited
+
+	r.logger.Debug().
+		Str("generator", answer.Generator).
+		Int("evidence", len(evidence)).
+		Int("citations", len(cited)).
+		Msg("Answer composed")
+
+	return answer, nil
+}
+
+// answerEvidence numbers the evidence a generator may cite, following the
+// fused ranking when present and otherwise facts before chunks.
+func answerEvidence(resp *RetrievalResponse, cfg AnswerConfig) []Citation {
+	items := resp.Evidence
+	if len(items) == 0 {
+		items = make([]Evidence, 0, len(resp.StructuredFacts)+len(resp.SemanticChunks))
+		for i := range resp.StructuredFacts {
+			items = append(items, Evidence{Kind: EvidenceSpecFact, Fact: &resp.StructuredFacts[i]})
+		}
+		for i := range resp.SemanticChunks {
+			items = append(items, Evidence{Kind: EvidenceSemanticChunk, Chunk: &resp.SemanticChunks[i]})
+		}
+	}
+
+	evidence := make([]Citation, 0, len(items))
+	for _, item := range items {
+		if len(evidence) >= cfg.MaxEvidence {
+			break
+		}
+		citation := Citation{Index: len(evidence) + 1, Kind: item.Kind}
+		switch {
+		case item.Fact != nil:
+			if item.Fact.Confidence < cfg.MinFactConfidence || strings.TrimSpace(item.Fact.Value) == "" {
+				continue
+			}
+			citation.Fact = item.Fact
+			citation.Text = factStatement(*item.Fact)
+			citation.Source = item.Fact.Source
+		case item.Chunk != nil:
+			if float64(item.Chunk.Score) < cfg.MinChunkScore || strings.TrimSpace(item.Chunk.Text) == "" {
+				continue
+			}
+			citation.Chunk = item.Chunk
+			citation.Text = strings.Join(strings.Fields(item.Chunk.Text), " ")
+			if item.Chunk.Benchmark != nil {
+				citation.Text = "Benchmark (" + item.Chunk.Benchmark.ProductName + "): " + citation.Text
+			}
+			citation.Source = item.Chunk.Source
+		default:
+			continue
+		}
+		evidence = append(evidence, citation)
+	}
+	return evidence
+}
+
+var (
+	citationRe       = regexp.MustCompile(`\[(\d+(?:\s*,\s*\d+)*)\]`)
+	spaceBeforePunct = regexp.MustCompile(`\s+([.,;:!?])`)
+)
+
+// resolveCitations drops markers that do not refer to evidence and returns
+// the cleaned text with the cited evidence in index order. A reply with no
+// valid citation is treated as ungrounded.
+func resolveCitations(text string, evidence []Citation) (string, []Citation) {
+	text = strings.TrimSpace(text)
+	if text == "" || strings.Contains(text, InsufficientEvidenceReply) {
+		return "", nil
+	}
+
+	used := make(map[int]bool)
+	text = citationRe.ReplaceAllStringFunc(text, func(marker string) string {
+		var kept []string
+		for _, part := range strings.Split(strings.Trim(marker, "[]"), ",") {
+			n, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil || n < 1 || n > len(evidence) {
+				continue
+			}
+			used[n] = true
+			kept = append(kept, strconv.Itoa(n))
+		}
+		if len(kept) == 0 {
+			return ""
+		}
+		return "[" + strings.Join(kept, ", ") + "]"
+	})
+
+	indexes := make([]int, 0, len(used))
+	for n := range used {
+		indexes = append(indexes, n)
+	}
+	sort.Ints(indexes)
+
+	cited := make([]Citation, 0, len(indexes))
+	for _, n := range indexes {
+		cited = append(cited, evidence[n-1])
+	}
+	text = strings.Join(strings.Fields(text), " ")
+	return spaceBeforePunct.ReplaceAllString(text, "$1"), cited
+}
+
+// factStatement renders a fact as "Name: value unit".
+func factStatement(fact SpecFact) string {
+	return factSubject(fact) + ": " + factValue(fact)
+}
+
+// factSubject names a fact, labelling benchmark facts with their product.
+func factSubject(fact SpecFact) string {
+	if fact.Benchmark != nil {
+		return fact.Name + " (benchmark: " + fact.Benchmark.ProductName + ")"
+	}
+	return fact.Name
+}
+
+// factValue joins a fact value with its unit unless the value already carries it.
+func factValue(fact SpecFact) string {
+	value := strings.TrimSpace(fact.Value)
+	unit := strings.TrimSpace(fact.Unit)
+	if unit == "" || strings.Contains(strings.ToLower(value), strings.ToLower(unit)) {
+		return value
+	}
+	return value + " " + unit
+}
+
+// TemplateGenerator composes answers offline by restating the top evidence.
+type TemplateGenerator struct {
+	// MaxFacts bounds the facts restated in one answer (default 5).
+	MaxFacts int
+	// MaxSnippets bounds the passages quoted when no fact applies (default 2).
+	MaxSnippets int
+}
+
+// NewTemplateGenerator creates a new template-based generator.
+func NewTemplateGenerator() *TemplateGenerator {
+	return &TemplateGenerator{MaxFacts: 5, MaxSnippets: 2}
+}
+
+// Name returns the generator name.
+func (g *TemplateGenerator) Name() string {
+	return GeneratorTemplate
+}
+
+// Generate restates facts as "Name is value [n]." sentences, or quotes the
+// leading sentence of the top passages when there are no facts.
+func (g *TemplateGenerator) Generate(ctx context.Context, question string, evidence []Citation) (string, error) {
+	var sentences []string
+	for _, e := range evidence {
+		if e.Fact == nil || len(sentences) >= g.MaxFacts {
+			continue
+		}
+		sentences = append(sentences, fmt.Sprintf("%s is %s [%d].", factSubject(*e.Fact), factValue(*e.Fact), e.Index))
+	}
+
+	if len(sentences) == 0 {
+		for _, e := range evidence {
+			if e.Chunk == nil || len(sentences) >= g.MaxSnippets {
+				continue
+			}
+			sentences = append(sentences, fmt.Sprintf("%s [%d].", strings.TrimRight(leadSentence(e.Text, 240), "."), e.Index))
+		}
+	}
+
+	if len(sentences) == 0 {
+		return InsufficientEvidenceReply, nil
+	}
+	return strings.Join(sentences, " "), nil
+}
+
+// leadSentence returns the first sentence of text, cut to at most limit runes.
+func leadSentence(text string, limit int) string {
+	for i := 0; i+1 < len(text); i++ {
+		if (text[i] == '.' || text[i] == '!' || text[i] == '?') && text[i+1] == ' ' {
+			text = text[:i+1]
+			break
+		}
+	}
+	runes := []rune(text)
+	if len(runes) > limit {
+		cut := string(runes[:limit])
+		if space := strings.LastIndex(cut, " "); space > 0 {
+			cut = cut[:space]
+		}
+		return cut + "…"
+	}
+	return text
+}
+
+// OpenAIGeneratorConfig holds settings for an OpenAI-compatible chat completions API.
+type OpenAIGeneratorConfig struct {
+	APIKey      string
+	BaseURL     string // default https://api.openai.com/v1
+	Model       string // default gpt-4o-mini
+	Temperature float64
+	MaxTokens   int           // default 400
+	Timeout     time.Duration // default 30s
+}
+
+// OpenAIGenerator composes answers with an OpenAI-compatible chat completions API.
+// It also implements LLMCompleter, so it can back an LLMRewriter.
+type OpenAIGenerator struct {
+	config     OpenAIGeneratorConfig
+	httpClient *http.Client
+}
+
+// NewOpenAIGenerator creates a new OpenAI-compatible generator.
+func NewOpenAIGenerator(cfg OpenAIGeneratorConfig) *OpenAIGenerator {
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = "https://api.openai.com/v1"
+	}
+	cfg.BaseURL = strings.TrimRight(cfg.BaseURL, "/")
+	if cfg.Model == "" {
+		cfg.Model = "gpt-4o-mini"
+	}
+	if cfg.MaxTokens <= 0 {
+		cfg.MaxTokens = 400
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+
+	return &OpenAIGenerator{
+		config:     cfg,
+		httpClient: &http.Client{Timeout: cfg.Timeout},
+	}
+}
+
+// Name returns the generator name.
+func (g *OpenAIGenerator) Name() string {
+	return GeneratorOpenAI
+}
+
+// Generate asks the model for a concise answer citing the numbered evidence.
+func (g *OpenAIGenerator) Generate(ctx context.Context, question string, evidence []Citation) (string, error) {
+	return g.chat(ctx, answerSystemPrompt, answerPrompt(question, evidence))
+}
+
+// Complete sends a single-turn prompt to the model.
+func (g *OpenAIGenerator) Complete(ctx context.Context, prompt string) (string, error) {
+	return g.chat(ctx, "", prompt)
+}
+
+// answerSystemPrompt instructs the model to stay within the evidence.
+const answerSystemPrompt = "You answer questions about vehicles using only the numbered evidence provided. " +
+	"Be concise. Cite every claim with the number of its evidence in square brackets, e.g. [1]. " +
+	"Do not use outside knowledge. If the evidence does not answer the question, reply exactly " +
+	InsufficientEvidenceReply + "."
+
+// answerPrompt lists the numbered evidence followed by the question.
+func answerPrompt(question string, evidence []Citation) string {
+	var b strings.Builder
+	b.WriteString("Evidence:\n")
+	for _, e := range evidence {
+		fmt.Fprintf(&b, "[%d] %s", e.Index, e.Text)
+		if e.Source.Page != nil {
+			fmt.Fprintf(&b, " (page %d)", *e.Source.Page)
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("\nQuestion: ")
+	b.WriteString(question)
+	return b.String()
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type chatRequest struct {
+	Model       string        `json:"model"`
+	Messages    []chatMessage `json:"messages"`
+	Temperature float64       `json:"temperature"`
+	MaxTokens   int           `json:"max_tokens"`
+}
+
+type chatResponse struct {
+	Choices []struct {
+		Message chatMessage `json:"message"`
+	} `json:"choices"`
+}
+
+type chatErrorResponse struct {
+	Error struct {
+		Message string `json:"message"`
+		Type    string `json:"type"`
+	} `json:"error"`
+}
+
+// chat calls the chat completions endpoint and returns the first choice.
+func (g *OpenAIGenerator) chat(ctx context.Context, system, prompt string) (string, error) {
+	messages := make([]chatMessage, 0, 2)
+	if system != "" {
+		messages = append(messages, chatMessage{Role: "system", Content: system})
+	}
+	messages = append(messages, chatMessage{Role: "user", Content: prompt})
+
+	body, err := json.Marshal(chatRequest{
+		Model:       g.config.Model,
+		Messages:    messages,
+		Temperature: g.config.Temperature,
+		MaxTokens:   g.config.MaxTokens,
+	})
+	if err != nil {
+		return "", fmt.Errorf("marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", g.config.BaseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if g.config.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+g.config.APIKey)
+	}
+
+	resp, err := g.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var errResp chatErrorResponse
+		if err := json.Unmarshal(respBody, &errResp); err == nil && errResp.Error.Message != "" {
+			return "", fmt.Errorf("API error: %s (%s)", errResp.Error.Message, errResp.Error.Type)
+		}
+		return "", fmt.Errorf("API error: status %d, body: %s", resp.StatusCode, string(respBody))
+	}
+
+	var chatResp chatResponse
+	if err := json.Unmarshal(respBody, &chatResp); err != nil {
+		return "", fmt.Errorf("unmarshal response: %w", err)
+	}
+	if len(chatResp.Choices) == 0 {
+		return "", fmt.Errorf("API returned no choices")
+	}
+
+	return strings.TrimSpace(chatResp.Choices[0].Message.Content), nil
+}
@@ -0,0 +1,157 @@
+package retrieval
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/lexical"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/observability"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// answerRouter returns a router whose lexical index holds the given specs for one tenant.
+func answerRouter(t *testing.T, tenant uuid.UUID, specs ...storage.SpecViewLatest) *Router {
+	t.Helper()
+	logger := observability.NewLogger(observability.LogConfig{Level: "error"})
+	router := NewRouter(logger, nil, nil, nil, nil, RouterConfig{StructuredFirst: true})
+	for _, sv := range specs {
+		router.LexicalIndex().Upsert(lexical.SpecDocument(sv))
+	}
+	router.LexicalIndex().MarkLoaded(tenant)
+	return router
+}
+
+func answerSpec(tenant uuid.UUID, name, value, unit string, page int) storage.SpecViewLatest {
+	campaign := uuid.New()
+	doc := uuid.New()
+	return storage.SpecViewLatest{
+		ID:                uuid.New(),
+		TenantID:          tenant,
+		ProductID:         campaign,
+		CampaignVariantID: campaign,
+		CategoryName:      "Engine",
+		SpecName:          name,
+		Value:             value,
+		Unit:              &unit,
+		Confidence:        0.95,
+		SourceDocID:       &doc,
+		SourcePage:        &page,
+	}
+}
+
+func TestTemplateGenerator_Generate(t *testing.T) {
+	gen := NewTemplateGenerator()
+	ctx := context.Background()
+
+	text, err := gen.Generate(ctx, "What is the power?", []Citation{
+		{Index: 1, Kind: EvidenceSpecFact, Fact: &SpecFact{Name: "Power", Value: "150", Unit: "hp"}},
+		{Index: 2, Kind: EvidenceSpecFact, Fact: &SpecFact{Name: "Torque", Value: "250 Nm", Unit: "Nm"}},
+		{Index: 3, Kind: EvidenceSemanticChunk, Text: "A refined engine."},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Power is 150 hp [1]. Torque is 250 Nm [2].", text)
+
+	text, err = gen.Generate(ctx, "Is it comfortable?", []Citation{
+		{Index: 1, Kind: EvidenceSemanticChunk, Chunk: &SemanticChunk{}, Text: "Ventilated seats keep you cool. They come standard."},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Ventilated seats keep you cool [1].", text)
+
+	text, err = gen.Generate(ctx, "Anything?", nil)
+	require.NoError(t, err)
+	assert.Equal(t, InsufficientEvidenceReply, text)
+}
+
+func TestResolveCitations(t *testing.T) {
+	evidence := []Citation{{Index: 1}, {Index: 2}, {Index: 3}}
+
+	text, cited := resolveCitations("Power is 150 hp [2] and torque 250 Nm [3, 9]. Seats [7].", evidence)
+	assert.Equal(t, "Power is 150 hp [2] and torque 250 Nm [3]. Seats.", text)
+	require.Len(t, cited, 2)
+	assert.Equal(t, 2, cited[0].Index)
+	assert.Equal(t, 3, cited[1].Index)
+
+	_, cited = resolveCitations("It has 150 hp.", evidence)
+	assert.Empty(t, cited, "uncited answers are not grounded")
+
+	_, cited = resolveCitations(InsufficientEvidenceReply, evidence)
+	assert.Empty(t, cited)
+}
+
+func TestRouter_Answer(t *testing.T) {
+	tenant := uuid.New()
+	power := answerSpec(tenant, "Max Power", "150", "hp", 4)
+	router := answerRouter(t, tenant, power, answerSpec(tenant, "Fuel Tank Capacity", "50", "L", 7))
+
+	answer, err := router.Answer(context.Background(), RetrievalRequest{TenantID: tenant, Question: "What is the max power?"})
+	require.NoError(t, err)
+	assert.Equal(t, AnswerGrounded, answer.Outcome)
+	assert.Equal(t, GeneratorTemplate, answer.Generator)
+	assert.Contains(t, answer.Text, "Max Power is 150 hp [1]")
+	require.NotEmpty(t, answer.Citations)
+	assert.Equal(t, power.SourceDocID, answer.Citations[0].Source.DocumentSourceID)
+	assert.Equal(t, 4, *answer.Citations[0].Source.Page)
+
+	answer, err = router.Answer(context.Background(), RetrievalRequest{TenantID: tenant, Question: "Does it come with a sunroof?"})
+	require.NoError(t, err)
+	assert.Equal(t, AnswerInsufficientEvidence, answer.Outcome)
+	assert.Empty(t, answer.Citations)
+	assert.NotEmpty(t, answer.Text)
+}
+
+func TestOpenAIGenerator_Answer(t *testing.T) {
+	reply := "The engine makes 150 hp [1] [5]."
+	var received chatRequest
+	stub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/chat/completions", r.URL.Path)
+		assert.Equal(t, "Bearer test-key", r.Header.Get("Authorization"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		if reply == "" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"error": {"message": "overloaded", "type": "server_error"}}`))
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{{"message": map[string]string{"role": "assistant", "content": reply}}},
+		})
+	}))
+	defer stub.Close()
+
+	tenant := uuid.New()
+	router := answerRouter(t, tenant, answerSpec(tenant, "Max Power", "150", "hp", 4))
+	router.SetAnswerGenerator(NewOpenAIGenerator(OpenAIGeneratorConfig{
+		APIKey:  "test-key",
+		BaseURL: stub.URL + "/v1",
+		Model:   "stub-model",
+	}))
+	req := RetrievalRequest{TenantID: tenant, Question: "What is the max power?"}
+
+	answer, err := router.Answer(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, GeneratorOpenAI, answer.Generator)
+	assert.Equal(t, AnswerGrounded, answer.Outcome)
+	assert.Equal(t, "The engine makes 150 hp [1].", answer.Text)
+	require.Len(t, answer.Citations, 1)
+	assert.Equal(t, "stub-model", received.Model)
+	require.Len(t, received.Messages, 2)
+	assert.Contains(t, received.Messages[1].Content, "[1] Max Power: 150 hp (page 4)")
+
+	// The model declines when the evidence does not answer the question
+	reply = InsufficientEvidenceReply
+	answer, err = router.Answer(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, AnswerInsufficientEvidence, answer.Outcome)
+
+	// API failures fall back to the template generator
+	reply = ""
+	answer, err = router.Answer(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, GeneratorTemplate, answer.Generator)
+	assert.Equal(t, AnswerGrounded, answer.Outcome)
+}
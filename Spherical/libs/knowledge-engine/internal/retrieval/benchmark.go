@@ -0,0 +1,427 @@
+// Package retrieval provides cross-tenant retrieval of public benchmark products.
+package retrieval
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/cache"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/lexical"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/storage"
+)
+
+// ErrBenchmarksUnavailable is returned for requests including benchmarks when
+// the router has no benchmark source.
+var ErrBenchmarksUnavailable = errors.New("benchmark retrieval is disabled")
+
+// SourceBenchmark marks trace candidates retrieved from benchmark products.
+const SourceBenchmark = "benchmark"
+
+// BenchmarkSource lists the public benchmark data shared across tenants.
+// Implementations must only return products flagged as public benchmarks,
+// chunks with benchmark visibility and comparison rows shared beyond their
+// tenant; storage.BenchmarkRepository enforces this in SQL.
+type BenchmarkSource interface {
+	Products(ctx context.Context) ([]*storage.Product, error)
+	Specs(ctx context.Context) ([]storage.SpecViewLatest, error)
+	Chunks(ctx context.Context) ([]*storage.KnowledgeChunk, error)
+	Comparisons(ctx context.Context, tenantID, productID uuid.UUID) ([]storage.ComparisonRow, error)
+}
+
+// BenchmarkConfig bounds the benchmark evidence added to a response.
+type BenchmarkConfig struct {
+	// MaxFacts caps benchmark spec facts per response (default 10).
+	MaxFacts int
+	// MaxChunks caps benchmark chunks per response (default 3).
+	MaxChunks int
+	// RefreshInterval bounds how long the benchmark catalogue is cached (default 10 minutes).
+	RefreshInterval time.Duration
+}
+
+func (c BenchmarkConfig) withDefaults() BenchmarkConfig {
+	if c.MaxFacts <= 0 {
+		c.MaxFacts = 10
+	}
+	if c.MaxChunks <= 0 {
+		c.MaxChunks = 3
+	}
+	if c.RefreshInterval <= 0 {
+		c.RefreshInterval = 10 * time.Minute
+	}
+	return c
+}
+
+// BenchmarkRef labels a result as sourced from another tenant's public benchmark product.
+type BenchmarkRef struct {
+	ProductID   uuid.UUID
+	ProductName string
+}
+
+// benchmarkPartition is the lexical index tenant all benchmark documents are
+// indexed under, so one search covers every benchmark product.
+var benchmarkPartition = uuid.Nil
+
+// benchmarkCatalog is the cached set of benchmark products and their indexed documents.
+type benchmarkCatalog struct {
+	index    *lexical.Index
+	products map[uuid.UUID]*storage.Product
+	loadedAt time.Time
+}
+
+// benchmarks holds the router's benchmark source and cached catalogue.
+type benchmarks struct {
+	source BenchmarkSource
+
+	mu      sync.Mutex
+	catalog *benchmarkCatalog
+}
+
+// SetBenchmarkSource enables requests to include public benchmark products of
+// other tenants. A nil source rejects them with ErrBenchmarksUnavailable.
+func (r *Router) SetBenchmarkSource(source BenchmarkSource) {
+	if source == nil {
+		r.benchmarks = nil
+		return
+	}
+	r.benchmarks = &benchmarks{source: source}
+}
+
+// benchmarkCacheNamespace prefixes cached responses that include benchmark
+// products. They are kept apart from each tenant's responses so a benchmark
+// tenant's change can drop them for every tenant.
+const benchmarkCacheNamespace = "benchmarks"
+
+// benchmarkCacheKey creates the key of a tenant's response that includes benchmarks.
+func benchmarkCacheKey(tenantID uuid.UUID, parts ...string) string {
+	return cache.CacheKey(benchmarkCacheNamespace, cache.TenantCacheKey(tenantID.String(), parts...))
+}
+
+// InvalidateBenchmarks drops the cached benchmark catalogue, e.g. after a
+// benchmark product is published or withdrawn.
+func (r *Router) InvalidateBenchmarks() {
+	if r.benchmarks == nil {
+		return
+	}
+	r.benchmarks.mu.Lock()
+	r.benchmarks.catalog = nil
+	r.benchmarks.mu.Unlock()
+}
+
+// invalidateBenchmarks drops the benchmark catalogue after a tenant's
+// catalogue, specs or comparisons change. If the tenant has public benchmark
+// products, before or after the change, every tenant's responses that
+// included benchmarks are dropped too.
+func (r *Router) invalidateBenchmarks(ctx context.Context, tenantID uuid.UUID) error {
+	b := r.benchmarks
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	owner := b.catalog != nil && b.catalog.ownedBy(tenantID)
+	b.catalog = nil
+	b.mu.Unlock()
+
+	if !owner {
+		products, err := b.source.Products(ctx)
+		if err != nil {
+			return fmt.Errorf("list benchmark products: %w", err)
+		}
+		for _, p := range products {
+			if p.IsPublicBenchmark && p.TenantID == tenantID {
+				owner = true
+				break
+			}
+		}
+	}
+	if !owner {
+		return nil
+	}
+
+	if r.semanticCache != nil {
+		r.semanticCache.InvalidateBenchmarks()
+	}
+	if r.cache != nil {
+		if err := r.cache.DeleteByPrefix(ctx, benchmarkCacheNamespace+":"); err != nil {
+			return fmt.Errorf("delete cached benchmark responses: %w", err)
+		}
+	}
+	return nil
+}
+
+// ownedBy reports whether the catalogue has benchmark products of the tenant.
+func (c *benchmarkCatalog) ownedBy(tenantID uuid.UUID) bool {
+	for _, p := range c.products {
+		if p.TenantID == tenantID {
+			return true
+		}
+	}
+	return false
+}
+
+// benchmarkCatalog returns the cached catalogue, reloading it once it is older
+// than the refresh interval.
+func (r *Router) benchmarkCatalog(ctx context.Context) (*benchmarkCatalog, error) {
+	b := r.benchmarks
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.catalog != nil && time.Since(b.catalog.loadedAt) < r.config.Benchmarks.RefreshInterval {
+		return b.catalog, nil
+	}
+
+	products, err := b.source.Products(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list benchmark products: %w", err)
+	}
+	specs, err := b.source.Specs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list benchmark specs: %w", err)
+	}
+	chunks, err := b.source.Chunks(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list benchmark chunks: %w", err)
+	}
+
+	// Re-check visibility so a misbehaving source cannot expose private data
+	catalog := &benchmarkCatalog{
+		index:    lexical.NewIndex(lexical.DefaultConfig()),
+		products: make(map[uuid.UUID]*storage.Product, len(products)),
+		loadedAt: time.Now(),
+	}
+	for _, p := range products {
+		if p.IsPublicBenchmark {
+			catalog.products[p.ID] = p
+		}
+	}
+	var docs []lexical.Document
+	for _, sv := range specs {
+		if p := catalog.products[sv.ProductID]; p != nil && p.TenantID == sv.TenantID {
+			docs = append(docs, benchmarkDocument(lexical.SpecDocument(sv)))
+		}
+	}
+	for _, kc := range chunks {
+		p := catalog.products[kc.ProductID]
+		if p == nil || p.TenantID != kc.TenantID || kc.Visibility != storage.VisibilityBenchmark {
+			continue
+		}
+		docs = append(docs, benchmarkDocument(lexical.ChunkDocument(*kc)))
+	}
+	catalog.index.Upsert(docs...)
+	catalog.index.MarkLoaded(benchmarkPartition)
+
+	r.logger.Info().
+		Int("products", len(catalog.products)).
+		Int("documents", len(docs)).
+		Msg("Benchmark catalogue loaded")
+	b.catalog = catalog
+	return catalog, nil
+}
+
+// benchmarkDocument moves a document into the shared benchmark partition.
+func benchmarkDocument(doc lexical.Document) lexical.Document {
+	doc.TenantID = benchmarkPartition
+	return doc
+}
+
+// queryBenchmarks adds labelled facts, chunks and comparison rows from public
+// benchmark products of other tenants. Products named in the question narrow
+// the search; otherwise every benchmark product is searched.
+func (r *Router) queryBenchmarks(ctx context.Context, req RetrievalRequest, intent Intent, response *RetrievalResponse) {
+	catalog, err := r.benchmarkCatalog(ctx)
+	if err != nil {
+		r.logger.Warn().Err(err).Msg("Benchmark retrieval failed")
+		return
+	}
+
+	trace := traceFrom(ctx)
+	eligible := make(map[uuid.UUID]*storage.Product, len(catalog.products))
+	for id, p := range catalog.products {
+		if p.TenantID == req.TenantID {
+			// The tenant's own products are served by regular retrieval
+			continue
+		}
+		eligible[id] = p
+	}
+	productIDs := mentionedBenchmarks(req.Question, eligible)
+	if len(productIDs) == 0 {
+		for id := range eligible {
+			productIDs = append(productIDs, id)
+		}
+	}
+	if len(productIDs) == 0 {
+		return
+	}
+
+	cfg := r.config.Benchmarks
+	q := lexical.Query{
+		TenantID:   benchmarkPartition,
+		ProductIDs: productIDs,
+		Kinds:      []lexical.Kind{lexical.KindSpec},
+		Text:       lexicalQueryText(req.Question, r.extractKeywords(req.Question)),
+		Expansions: lexicalExpansions(req.expansions),
+		Limit:      lexicalSearchLimit,
+	}
+	hits := catalog.index.Search(q)
+	cutoff := r.config.LexicalMinScore
+	if len(hits) > 0 {
+		cutoff = math.Max(cutoff, hits[0].Calibrated*lexicalRelativeCutoff)
+	}
+
+	var facts int
+	seen := make(map[string]bool)
+	for _, hit := range hits {
+		if hit.Calibrated < cutoff || facts >= cfg.MaxFacts {
+			break
+		}
+		sv, ok := hit.Document.Payload.(storage.SpecViewLatest)
+		if !ok {
+			continue
+		}
+		key := fmt.Sprintf("%s|%s|%s|%s", sv.ProductID, sv.CategoryName, sv.SpecName, sv.Value)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		facts++
+
+		// Variant IDs and source documents belong to the other tenant
+		ref := &BenchmarkRef{ProductID: sv.ProductID, ProductName: eligible[sv.ProductID].Name}
+		fact := specViewFact(sv, "", hit.Calibrated)
+		fact.CampaignVariantID = uuid.Nil
+		fact.Source = SourceRef{}
+		fact.Benchmark = ref
+		response.StructuredFacts = append(response.StructuredFacts, fact)
+		trace.candidate(CandidateScore{
+			Kind:       EvidenceSpecFact,
+			ID:         fact.SpecItemID,
+			Label:      ref.ProductName + ": " + factLabel(fact),
+			Source:     SourceBenchmark,
+			Score:      hit.Calibrated,
+			Components: map[string]float64{"bm25": hit.Score, "calibrated": hit.Calibrated},
+		})
+	}
+
+	q.Kinds = []lexical.Kind{lexical.KindChunk}
+	q.Limit = cfg.MaxChunks
+	var chunks int
+	for _, hit := range catalog.index.Search(q) {
+		if hit.Calibrated < r.config.LexicalMinScore {
+			break
+		}
+		kc, ok := hit.Document.Payload.(storage.KnowledgeChunk)
+		if !ok {
+			continue
+		}
+		chunks++
+		ref := &BenchmarkRef{ProductID: kc.ProductID, ProductName: eligible[kc.ProductID].Name}
+		response.SemanticChunks = append(response.SemanticChunks, SemanticChunk{
+			ChunkID:   kc.ID,
+			ChunkType: kc.ChunkType,
+			Text:      kc.Text,
+			Score:     float32(hit.Calibrated),
+			Benchmark: ref,
+		})
+		trace.candidate(CandidateScore{
+			Kind:       EvidenceSemanticChunk,
+			ID:         kc.ID,
+			Label:      ref.ProductName + ": " + string(kc.ChunkType),
+			Source:     SourceBenchmark,
+			Score:      hit.Calibrated,
+			Components: map[string]float64{"bm25": hit.Score, "calibrated": hit.Calibrated},
+		})
+	}
+
+	if intent == IntentComparison {
+		response.Comparisons = append(response.Comparisons, r.queryBenchmarkComparisons(ctx, req, eligible, productIDs)...)
+	}
+
+	r.logger.Debug().
+		Int("benchmark_products", len(productIDs)).
+		Int("facts", facts).
+		Int("chunks", chunks).
+		Msg("Benchmark retrieval")
+}
+
+// queryBenchmarkComparisons returns shared comparison rows between the
+// requested products and the selected benchmark products.
+func (r *Router) queryBenchmarkComparisons(ctx context.Context, req RetrievalRequest, eligible map[uuid.UUID]*storage.Product, productIDs []uuid.UUID) []ComparisonResult {
+	selected := toIDSet(productIDs)
+	var results []ComparisonResult
+	for _, pid := range req.ProductIDs {
+		rows, err := r.benchmarks.source.Comparisons(ctx, req.TenantID, pid)
+		if err != nil {
+			r.logger.Warn().Err(err).Str("product_id", pid.String()).Msg("Benchmark comparison query failed")
+			continue
+		}
+		for _, row := range rows {
+			p := eligible[row.SecondaryProductID]
+			if p == nil || !selected[row.SecondaryProductID] {
+				continue
+			}
+			if row.Shareability != storage.ComparisonBenchmarkOnly && row.Shareability != storage.ShareabilityPublic {
+				continue
+			}
+			results = append(results, ComparisonResult{
+				Dimension:          row.Dimension,
+				PrimaryProductID:   row.PrimaryProductID,
+				SecondaryProductID: row.SecondaryProductID,
+				PrimaryValue:       optionalString(row.PrimaryValue),
+				SecondaryValue:     optionalString(row.SecondaryValue),
+				Verdict:            row.Verdict,
+				Narrative:          optionalString(row.Narrative),
+				Benchmark:          &BenchmarkRef{ProductID: p.ID, ProductName: p.Name},
+			})
+		}
+	}
+	return results
+}
+
+// mentionedBenchmarks returns the benchmark products whose name appears in the
+// question, matched by any of its words of three or more characters other than numbers.
+func mentionedBenchmarks(question string, products map[uuid.UUID]*storage.Product) []uuid.UUID {
+	words := make(map[string]bool)
+	for _, w := range normalizeTokens(question) {
+		words[w] = true
+	}
+
+	var ids []uuid.UUID
+	for id, p := range products {
+		for _, w := range normalizeTokens(p.Name) {
+			if len(w) >= 3 && !isNumber(w) && words[w] {
+				ids = append(ids, id)
+				break
+			}
+		}
+	}
+	return ids
+}
+
+// optionalString returns the pointed-to string, or "" for nil.
+func optionalString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// isNumber reports whether a word is all digits, such as a model year.
+func isNumber(word string) bool {
+	for _, c := range word {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return word != ""
+}
+
+func toIDSet(ids []uuid.UUID) map[uuid.UUID]bool {
+	set := make(map[uuid.UUID]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	return set
+}
@@ -0,0 +1,237 @@
+package retrieval
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/cache"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/observability"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubBenchmarks returns fixed benchmark data, including rows a correct
+// source would never return, to check the router re-applies visibility.
+type stubBenchmarks struct {
+	products    []*storage.Product
+	specs       []storage.SpecViewLatest
+	chunks      []*storage.KnowledgeChunk
+	comparisons []storage.ComparisonRow
+	loads       int
+}
+
+func (s *stubBenchmarks) Products(ctx context.Context) ([]*storage.Product, error) {
+	s.loads++
+	return s.products, nil
+}
+
+func (s *stubBenchmarks) Specs(ctx context.Context) ([]storage.SpecViewLatest, error) {
+	return s.specs, nil
+}
+
+func (s *stubBenchmarks) Chunks(ctx context.Context) ([]*storage.KnowledgeChunk, error) {
+	return s.chunks, nil
+}
+
+func (s *stubBenchmarks) Comparisons(ctx context.Context, tenantID, productID uuid.UUID) ([]storage.ComparisonRow, error) {
+	return s.comparisons, nil
+}
+
+func benchmarkSpec(tenant, product uuid.UUID, productName, name, value string) storage.SpecViewLatest {
+	doc := uuid.New()
+	return storage.SpecViewLatest{
+		ID:                uuid.New(),
+		SpecItemID:        uuid.New(),
+		TenantID:          tenant,
+		ProductID:         product,
+		CampaignVariantID: uuid.New(),
+		CategoryName:      "Engine",
+		SpecName:          name,
+		Value:             value,
+		Confidence:        0.9,
+		SourceDocID:       &doc,
+		ProductName:       productName,
+	}
+}
+
+func TestRouter_Benchmarks(t *testing.T) {
+	logger := observability.NewLogger(observability.LogConfig{Level: "error"})
+	caller, rival, other := uuid.New(), uuid.New(), uuid.New()
+	camry := uuid.New()
+	accord, civic, ownBenchmark, private := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+
+	source := &stubBenchmarks{
+		products: []*storage.Product{
+			{ID: accord, TenantID: rival, Name: "Accord", IsPublicBenchmark: true},
+			{ID: civic, TenantID: other, Name: "Civic", IsPublicBenchmark: true},
+			{ID: ownBenchmark, TenantID: caller, Name: "Corolla", IsPublicBenchmark: true},
+			{ID: private, TenantID: rival, Name: "Pilot"},
+		},
+		specs: []storage.SpecViewLatest{
+			benchmarkSpec(rival, accord, "Accord", "Power", "192 hp"),
+			benchmarkSpec(other, civic, "Civic", "Power", "158 hp"),
+			benchmarkSpec(caller, ownBenchmark, "Corolla", "Power", "169 hp"),
+			benchmarkSpec(rival, private, "Pilot", "Power", "285 hp"),
+		},
+		chunks: []*storage.KnowledgeChunk{
+			{ID: uuid.New(), TenantID: rival, ProductID: accord, ChunkType: storage.ChunkTypeUSP, Text: "Accord power delivery is smooth.", Visibility: storage.VisibilityBenchmark},
+			{ID: uuid.New(), TenantID: rival, ProductID: accord, ChunkType: storage.ChunkTypeUSP, Text: "Accord power upgrade roadmap.", Visibility: storage.VisibilityShared},
+			{ID: uuid.New(), TenantID: rival, ProductID: accord, ChunkType: storage.ChunkTypeUSP, Text: "Accord power dealer notes.", Visibility: storage.VisibilityPrivate},
+		},
+		comparisons: []storage.ComparisonRow{
+			{PrimaryProductID: camry, SecondaryProductID: accord, Dimension: "Power", Verdict: storage.VerdictPrimaryBetter, Shareability: storage.ComparisonBenchmarkOnly},
+			{PrimaryProductID: camry, SecondaryProductID: accord, Dimension: "Price", Verdict: storage.VerdictSecondaryBetter, Shareability: storage.ComparisonTenantOnly},
+		},
+	}
+
+	router := NewRouter(logger, nil, nil, nil, nil, RouterConfig{StructuredFirst: true})
+	indexSpec(router, caller, camry, uuid.New(), "LE", "Engine", "Power", "203 hp")
+	router.LexicalIndex().MarkLoaded(caller)
+	ctx := context.Background()
+
+	t.Run("rejected without a source, skipped without the request flag", func(t *testing.T) {
+		_, err := router.Query(ctx, RetrievalRequest{TenantID: caller, Question: "What is the power?", IncludeBenchmarks: true})
+		assert.ErrorIs(t, err, ErrBenchmarksUnavailable)
+
+		router.SetBenchmarkSource(source)
+		resp, err := router.Query(ctx, RetrievalRequest{TenantID: caller, Question: "What is the power?"})
+		require.NoError(t, err)
+		for _, fact := range resp.StructuredFacts {
+			assert.Nil(t, fact.Benchmark)
+		}
+	})
+
+	t.Run("labels other tenants' benchmarks only", func(t *testing.T) {
+		resp, err := router.Query(ctx, RetrievalRequest{TenantID: caller, Question: "What is the power?", IncludeBenchmarks: true, Explain: true})
+		require.NoError(t, err)
+
+		benchmarks := make(map[string]SpecFact)
+		for _, fact := range resp.StructuredFacts {
+			if fact.Benchmark == nil {
+				assert.Equal(t, "203 hp", fact.Value)
+				continue
+			}
+			benchmarks[fact.Benchmark.ProductName] = fact
+		}
+		require.Len(t, benchmarks, 2, "own and private products are excluded")
+		assert.Equal(t, "192 hp", benchmarks["Accord"].Value)
+		assert.Equal(t, accord, benchmarks["Accord"].Benchmark.ProductID)
+		assert.Equal(t, "158 hp", benchmarks["Civic"].Value)
+		assert.Nil(t, benchmarks["Accord"].Source.DocumentSourceID, "other tenants' sources are not exposed")
+		assert.Equal(t, uuid.Nil, benchmarks["Accord"].CampaignVariantID)
+
+		require.Len(t, resp.SemanticChunks, 1, "only benchmark-visible chunks are returned")
+		assert.Contains(t, resp.SemanticChunks[0].Text, "smooth")
+		require.NotNil(t, resp.SemanticChunks[0].Benchmark)
+
+		var sources []string
+		for _, c := range resp.Trace.Candidates {
+			sources = append(sources, c.Source)
+		}
+		assert.Contains(t, sources, SourceBenchmark)
+	})
+
+	t.Run("mentioned products narrow the search", func(t *testing.T) {
+		resp, err := router.Query(ctx, RetrievalRequest{TenantID: caller, Question: "What is the Civic power?", IncludeBenchmarks: true})
+		require.NoError(t, err)
+		for _, fact := range resp.StructuredFacts {
+			if fact.Benchmark != nil {
+				assert.Equal(t, civic, fact.Benchmark.ProductID)
+			}
+		}
+	})
+
+	t.Run("comparisons keep shared rows", func(t *testing.T) {
+		resp, err := router.Query(ctx, RetrievalRequest{
+			TenantID:          caller,
+			ProductIDs:        []uuid.UUID{camry},
+			Question:          "Compare the power with the Accord",
+			IncludeBenchmarks: true,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, IntentComparison, resp.Intent)
+		require.Len(t, resp.Comparisons, 1)
+		assert.Equal(t, "Power", resp.Comparisons[0].Dimension)
+		require.NotNil(t, resp.Comparisons[0].Benchmark)
+		assert.Equal(t, "Accord", resp.Comparisons[0].Benchmark.ProductName)
+	})
+
+	t.Run("catalogue is cached until invalidated", func(t *testing.T) {
+		loads := source.loads
+		_, err := router.Query(ctx, RetrievalRequest{TenantID: caller, Question: "torque", IncludeBenchmarks: true})
+		require.NoError(t, err)
+		assert.Equal(t, loads, source.loads)
+
+		router.InvalidateBenchmarks()
+		_, err = router.Query(ctx, RetrievalRequest{TenantID: caller, Question: "torque", IncludeBenchmarks: true})
+		require.NoError(t, err)
+		assert.Equal(t, loads+1, source.loads)
+	})
+}
+
+func TestRouter_ApplyInvalidationDropsBenchmarkResponses(t *testing.T) {
+	logger := observability.NewLogger(observability.LogConfig{Level: "error"})
+	caller, rival := uuid.New(), uuid.New()
+	source := &stubBenchmarks{products: []*storage.Product{
+		{ID: uuid.New(), TenantID: rival, Name: "Accord", IsPublicBenchmark: true},
+	}}
+	router := NewRouter(logger, cache.NewMemoryClient(100), nil, &stubEmbedder{}, nil, RouterConfig{
+		SemanticCache: SemanticCacheConfig{Enabled: true},
+	})
+	router.SetBenchmarkSource(source)
+	ctx := context.Background()
+
+	plain := RetrievalRequest{TenantID: caller, Question: "What is the power?"}
+	withBenchmarks := plain
+	withBenchmarks.IncludeBenchmarks = true
+	store := func() {
+		for _, req := range []RetrievalRequest{plain, withBenchmarks} {
+			require.NoError(t, router.cacheResult(ctx, router.buildCacheKey(req), &RetrievalResponse{Intent: IntentSpecLookup}))
+			router.semanticCache.Store(req, IntentSpecLookup, []float32{1, 0, 0}, &RetrievalResponse{Intent: IntentSpecLookup})
+		}
+	}
+	cached := func(req RetrievalRequest) bool {
+		resp, _, err := router.checkCache(ctx, router.buildCacheKey(req))
+		return err == nil && resp != nil
+	}
+	event := func(tenant uuid.UUID) cache.InvalidationEvent {
+		return cache.InvalidationEvent{Kind: cache.InvalidationSpecUpdated, TenantID: tenant}
+	}
+	store()
+
+	t.Run("other tenants' changes drop the catalogue only", func(t *testing.T) {
+		_, err := router.benchmarkCatalog(ctx)
+		require.NoError(t, err)
+		require.NoError(t, router.ApplyInvalidation(ctx, event(uuid.New())))
+		assert.Nil(t, router.benchmarks.catalog)
+		assert.True(t, cached(plain))
+		assert.True(t, cached(withBenchmarks))
+		assert.Equal(t, int64(2), router.CacheStats().KeyCount)
+	})
+
+	t.Run("a benchmark tenant's change drops every response with benchmarks", func(t *testing.T) {
+		require.NoError(t, router.ApplyInvalidation(ctx, event(rival)))
+		assert.True(t, cached(plain))
+		assert.False(t, cached(withBenchmarks))
+		assert.Equal(t, int64(1), router.CacheStats().KeyCount)
+	})
+
+	t.Run("the caller's change drops both", func(t *testing.T) {
+		store()
+		require.NoError(t, router.ApplyInvalidation(ctx, event(caller)))
+		assert.False(t, cached(plain))
+		assert.False(t, cached(withBenchmarks))
+		assert.Equal(t, int64(0), router.CacheStats().KeyCount)
+	})
+}
+
+func TestAnswerEvidence_LabelsBenchmarks(t *testing.T) {
+	resp := &RetrievalResponse{StructuredFacts: []SpecFact{
+		{Name: "Power", Value: "192 hp", Confidence: 0.9, Benchmark: &BenchmarkRef{ProductName: "Accord"}},
+	}}
+	evidence := answerEvidence(resp, AnswerConfig{MaxEvidence: 5})
+	require.Len(t, evidence, 1)
+	assert.Equal(t, "Power (benchmark: Accord): 192 hp", evidence[0].Text)
+}
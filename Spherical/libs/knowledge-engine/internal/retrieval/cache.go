@@ -8,6 +8,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"sort"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -68,6 +69,13 @@ func NewResponseCache(client *cache.RedisClient, logger *observability.Logger, c
 
 // CacheKey generates a cache key for a retrieval request.
 func (c *ResponseCache) CacheKey(req RetrievalRequest) string {
+	return c.config.KeyPrefix + requestKey(req)
+}
+
+// requestKey hashes the request parameters that shape a response. Requests
+// with the same key get the same response, so it also identifies in-flight
+// duplicates.
+func requestKey(req RetrievalRequest) string {
 	// Create deterministic key from request parameters
 	parts := []string{
 		req.TenantID.String(),
@@ -94,12 +102,40 @@ func (c *ResponseCache) CacheKey(req RetrievalRequest) string {
 		parts = append(parts, string(*req.IntentHint))
 	}
 
+	// Benchmark results differ from tenant-only results
+	if req.IncludeBenchmarks {
+		parts = append(parts, "benchmarks")
+	}
+
+	// Point-in-time requests are answered from past campaign versions
+	if req.AsOf != nil {
+		parts = append(parts, "as_of:"+req.AsOf.UTC().Format(time.RFC3339Nano))
+	}
+	if req.CampaignVersion != nil {
+		parts = append(parts, fmt.Sprintf("version:%d", *req.CampaignVersion))
+	}
+
+	// Follow-up questions are rewritten against the conversation
+	for _, msg := range req.ConversationContext {
+		parts = append(parts, "msg:"+msg.Role+":"+msg.Content)
+	}
+
+	parts = append(parts, fmt.Sprintf("max:%d", req.MaxChunks))
+	if req.IncludeLineage {
+		parts = append(parts, "lineage")
+	}
+
 	// Add filters
-	if len(req.Filters.Categories) > 0 {
-		sort.Strings(req.Filters.Categories)
-		for _, cat := range req.Filters.Categories {
-			parts = append(parts, "cat:"+cat)
-		}
+	categories := append([]string(nil), req.Filters.Categories...)
+	sort.Strings(categories)
+	for _, cat := range categories {
+		parts = append(parts, "cat:"+cat)
+	}
+	for _, ct := range req.Filters.ChunkTypes {
+		parts = append(parts, "chunk:"+string(ct))
+	}
+	for _, bt := range req.Filters.BlockTypes {
+		parts = append(parts, "block:"+string(bt))
 	}
 
 	// Hash the combined parts
@@ -108,9 +144,7 @@ func (c *ResponseCache) CacheKey(req RetrievalRequest) string {
 		combined += p + "|"
 	}
 	hash := sha256.Sum256([]byte(combined))
-	hashStr := hex.EncodeToString(hash[:16]) // Use first 16 bytes
-
-	return c.config.KeyPrefix + hashStr
+	return hex.EncodeToString(hash[:16]) // Use first 16 bytes
 }
 
 // CachedResponse represents a cached retrieval response.
@@ -218,14 +252,14 @@ func (c *ResponseCache) InvalidateForCampaign(ctx context.Context, tenantID, cam
 // getTTLForResponse determines TTL based on response content.
 func (c *ResponseCache) getTTLForResponse(resp *RetrievalResponse) time.Duration {
 	switch resp.Intent {
-	case IntentSpecLookup:
+	case IntentSpecLookup, IntentAvailability, IntentWarranty:
 		// Structured facts are more stable, cache longer
 		return c.config.StructuredFactsTTL
 	case IntentComparison:
 		// Comparisons are pre-computed, cache longest
 		return c.config.ComparisonsTTL
-	case IntentUSPLookup, IntentFAQ:
-		// Semantic content may change more frequently
+	case IntentUSPLookup, IntentFAQ, IntentAccessory, IntentPricing:
+		// Semantic content and offers may change more frequently
 		return c.config.SemanticChunksTTL
 	default:
 		return c.config.DefaultTTL
@@ -255,38 +289,205 @@ type CacheStats struct {
 	MemoryUsed int64   `json:"memory_used_bytes"`
 }
 
-// InvalidationTrigger handles cache invalidation on data changes.
+// ApplyInvalidation evicts cached responses affected by an invalidation event.
+func (c *ResponseCache) ApplyInvalidation(ctx context.Context, event cache.InvalidationEvent) error {
+	switch {
+	case event.Kind == cache.InvalidationCampaignPublished && event.CampaignID != nil:
+		return c.InvalidateForCampaign(ctx, event.TenantID, *event.CampaignID)
+	default:
+		return c.Invalidate(ctx, event.TenantID, event.ProductID)
+	}
+}
+
+// ApplyInvalidation drops a tenant's cached responses, linked catalogue and
+// spec aliases after its campaigns, specs or comparisons change; any of them
+// can appear in a response. The benchmark catalogue is reloaded, and if the
+// tenant has benchmark products, every tenant's responses that included them
+// are dropped. After an embedding index switch it first serves the index now
+// stored for the scope.
+func (r *Router) ApplyInvalidation(ctx context.Context, event cache.InvalidationEvent) error {
+	if event.Kind == cache.InvalidationEmbeddingIndexSwitch {
+		// Another instance switched the index; cached responses are dropped either way
+		if err := r.syncEmbeddingIndexes(ctx, event.TenantID, event.CampaignID); err != nil {
+			r.logger.Error().Err(err).Str("tenant_id", event.TenantID.String()).Msg("Failed to switch embedding index")
+		}
+	}
+	if r.linker != nil {
+		r.linker.Invalidate(event.TenantID)
+	}
+	r.expander.InvalidateSpecAliases(event.TenantID)
+	if r.semanticCache != nil {
+		r.semanticCache.InvalidateTenant(event.TenantID)
+	}
+	if r.cache != nil {
+		prefixes := []string{
+			cache.TenantCacheKey(event.TenantID.String(), "retrieval") + ":",
+			benchmarkCacheKey(event.TenantID, "retrieval") + ":",
+		}
+		for _, prefix := range prefixes {
+			if err := r.cache.DeleteByPrefix(ctx, prefix); err != nil {
+				return fmt.Errorf("delete cached responses: %w", err)
+			}
+		}
+	}
+	if event.Kind != cache.InvalidationEmbeddingIndexSwitch {
+		if err := r.invalidateBenchmarks(ctx, event.TenantID); err != nil {
+			return fmt.Errorf("invalidate benchmarks: %w", err)
+		}
+	}
+	return nil
+}
+
+// LocalInvalidator evicts results cached by this process for an invalidation event.
+type LocalInvalidator interface {
+	ApplyInvalidation(ctx context.Context, event cache.InvalidationEvent) error
+}
+
+// InvalidationTrigger handles cache invalidation on data changes. It evicts
+// this instance's caches immediately and publishes the change on a bus so
+// other instances evict theirs.
 type InvalidationTrigger struct {
-	cache  *ResponseCache
+	bus    cache.InvalidationBus
 	logger *observability.Logger
+	origin string
+
+	mu          sync.RWMutex
+	locals      []LocalInvalidator
+	unsubscribe func()
 }
 
-// NewInvalidationTrigger creates a new invalidation trigger.
-func NewInvalidationTrigger(cache *ResponseCache, logger *observability.Logger) *InvalidationTrigger {
+// NewInvalidationTrigger creates a new invalidation trigger. A nil bus keeps
+// invalidation local to the process.
+func NewInvalidationTrigger(bus cache.InvalidationBus, logger *observability.Logger) *InvalidationTrigger {
 	return &InvalidationTrigger{
-		cache:  cache,
+		bus:    bus,
 		logger: logger,
+		origin: uuid.NewString(),
+	}
+}
+
+// AddInvalidator registers a local cache to evict on every event.
+func (t *InvalidationTrigger) AddInvalidator(local LocalInvalidator) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.locals = append(t.locals, local)
+}
+
+// Start subscribes to events published by other instances.
+func (t *InvalidationTrigger) Start(ctx context.Context) error {
+	if t.bus == nil {
+		return nil
+	}
+	unsubscribe, err := t.bus.Subscribe(ctx, func(ctx context.Context, event cache.InvalidationEvent) {
+		if event.Origin == t.origin {
+			return
+		}
+		t.apply(ctx, event)
+	})
+	if err != nil {
+		return fmt.Errorf("subscribe to invalidations: %w", err)
+	}
+
+	t.mu.Lock()
+	t.unsubscribe = unsubscribe
+	t.mu.Unlock()
+	return nil
+}
+
+// Stop unsubscribes from the bus.
+func (t *InvalidationTrigger) Stop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.unsubscribe != nil {
+		t.unsubscribe()
+		t.unsubscribe = nil
 	}
 }
 
 // OnCampaignPublished invalidates cache when a campaign is published.
 func (t *InvalidationTrigger) OnCampaignPublished(ctx context.Context, tenantID, productID, campaignID uuid.UUID) {
-	if err := t.cache.InvalidateForCampaign(ctx, tenantID, campaignID); err != nil {
+	event := cache.InvalidationEvent{
+		Kind:       cache.InvalidationCampaignPublished,
+		TenantID:   tenantID,
+		ProductID:  &productID,
+		CampaignID: &campaignID,
+	}
+	if err := t.trigger(ctx, event); err != nil {
 		t.logger.Error().Err(err).Msg("Failed to invalidate cache on campaign publish")
 	}
 }
 
 // OnSpecValueUpdated invalidates cache when spec values are updated.
 func (t *InvalidationTrigger) OnSpecValueUpdated(ctx context.Context, tenantID, productID uuid.UUID) {
-	if err := t.cache.Invalidate(ctx, tenantID, &productID); err != nil {
+	event := cache.InvalidationEvent{
+		Kind:      cache.InvalidationSpecUpdated,
+		TenantID:  tenantID,
+		ProductID: &productID,
+	}
+	if err := t.trigger(ctx, event); err != nil {
 		t.logger.Error().Err(err).Msg("Failed to invalidate cache on spec update")
 	}
 }
 
-// OnComparisonRecomputed invalidates cache when comparisons are recomputed.
+// OnComparisonRecomputed invalidates cache when a tenant's stored comparison
+// rows are recomputed.
 func (t *InvalidationTrigger) OnComparisonRecomputed(ctx context.Context, tenantID uuid.UUID) {
-	if err := t.cache.Invalidate(ctx, tenantID, nil); err != nil {
+	event := cache.InvalidationEvent{
+		Kind:     cache.InvalidationComparisonRecomputed,
+		TenantID: tenantID,
+	}
+	if err := t.trigger(ctx, event); err != nil {
 		t.logger.Error().Err(err).Msg("Failed to invalidate cache on comparison recompute")
 	}
 }
 
+// OnEmbeddingIndexSwitched switches every instance to the embedding index
+// now stored for a tenant, or one campaign, and drops their cached responses,
+// satisfying IndexSwitchPublisher.
+func (t *InvalidationTrigger) OnEmbeddingIndexSwitched(ctx context.Context, tenantID uuid.UUID, campaignID *uuid.UUID) error {
+	return t.trigger(ctx, cache.InvalidationEvent{
+		Kind:       cache.InvalidationEmbeddingIndexSwitch,
+		TenantID:   tenantID,
+		CampaignID: campaignID,
+	})
+}
+
+// InvalidateCampaign invalidates cache when a campaign is published or rolled
+// back without a known product, satisfying ingest.CacheInvalidator.
+func (t *InvalidationTrigger) InvalidateCampaign(ctx context.Context, tenantID, campaignID uuid.UUID) error {
+	return t.trigger(ctx, cache.InvalidationEvent{
+		Kind:       cache.InvalidationCampaignPublished,
+		TenantID:   tenantID,
+		CampaignID: &campaignID,
+	})
+}
+
+// trigger evicts local caches and publishes the event to other instances.
+func (t *InvalidationTrigger) trigger(ctx context.Context, event cache.InvalidationEvent) error {
+	event.Origin = t.origin
+	t.apply(ctx, event)
+
+	if t.bus == nil {
+		return nil
+	}
+	if err := t.bus.Publish(ctx, event); err != nil {
+		return fmt.Errorf("publish invalidation: %w", err)
+	}
+	return nil
+}
+
+// apply evicts the event from every registered local cache.
+func (t *InvalidationTrigger) apply(ctx context.Context, event cache.InvalidationEvent) {
+	t.mu.RLock()
+	locals := t.locals
+	t.mu.RUnlock()
+
+	for _, local := range locals {
+		if err := local.ApplyInvalidation(ctx, event); err != nil {
+			t.logger.Error().Err(err).
+				Str("kind", string(event.Kind)).
+				Str("tenant_id", event.TenantID.String()).
+				Msg("Failed to invalidate cache")
+		}
+	}
+}
@@ -0,0 +1,301 @@
+package retrieval
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/cache"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/observability"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingInvalidator records the events applied to it.
+type recordingInvalidator struct {
+	events []cache.InvalidationEvent
+}
+
+func (r *recordingInvalidator) ApplyInvalidation(ctx context.Context, event cache.InvalidationEvent) error {
+	r.events = append(r.events, event)
+	return nil
+}
+
+// failingBus rejects every publish.
+type failingBus struct{ cache.MemoryInvalidationBus }
+
+func (b *failingBus) Publish(ctx context.Context, event cache.InvalidationEvent) error {
+	return errors.New("connection refused")
+}
+
+func TestInvalidationTrigger_Broadcast(t *testing.T) {
+	logger := observability.NewLogger(observability.LogConfig{Level: "error"})
+	ctx := context.Background()
+	bus := cache.NewMemoryInvalidationBus()
+
+	// Two instances sharing a bus
+	local, remote := &recordingInvalidator{}, &recordingInvalidator{}
+	publisher := NewInvalidationTrigger(bus, logger)
+	publisher.AddInvalidator(local)
+	require.NoError(t, publisher.Start(ctx))
+	defer publisher.Stop()
+	replica := NewInvalidationTrigger(bus, logger)
+	replica.AddInvalidator(remote)
+	require.NoError(t, replica.Start(ctx))
+
+	tenant, product, campaign := uuid.New(), uuid.New(), uuid.New()
+	publisher.OnCampaignPublished(ctx, tenant, product, campaign)
+	publisher.OnSpecValueUpdated(ctx, tenant, product)
+	publisher.OnComparisonRecomputed(ctx, tenant)
+
+	kinds := []cache.InvalidationKind{
+		cache.InvalidationCampaignPublished,
+		cache.InvalidationSpecUpdated,
+		cache.InvalidationComparisonRecomputed,
+	}
+	for _, recorder := range []*recordingInvalidator{local, remote} {
+		require.Len(t, recorder.events, 3, "each instance applies every event once")
+		for i, event := range recorder.events {
+			assert.Equal(t, kinds[i], event.Kind)
+			assert.Equal(t, tenant, event.TenantID)
+		}
+	}
+	assert.Equal(t, campaign, *remote.events[0].CampaignID)
+	assert.Equal(t, product, *remote.events[1].ProductID)
+	assert.Nil(t, remote.events[2].ProductID)
+
+	t.Run("stopped instances no longer receive events", func(t *testing.T) {
+		replica.Stop()
+		require.NoError(t, publisher.InvalidateCampaign(ctx, tenant, campaign))
+		assert.Len(t, local.events, 4)
+		assert.Len(t, remote.events, 3)
+	})
+
+	t.Run("publish failures still evict locally", func(t *testing.T) {
+		isolated := &recordingInvalidator{}
+		trigger := NewInvalidationTrigger(&failingBus{}, logger)
+		trigger.AddInvalidator(isolated)
+		assert.Error(t, trigger.InvalidateCampaign(ctx, tenant, campaign))
+		assert.Len(t, isolated.events, 1)
+	})
+}
+
+func TestRouter_ApplyInvalidationClearsSemanticCache(t *testing.T) {
+	logger := observability.NewLogger(observability.LogConfig{Level: "error"})
+	router := NewRouter(logger, nil, nil, &stubEmbedder{}, nil, RouterConfig{SemanticCache: SemanticCacheConfig{Enabled: true}})
+	tenant, other := uuid.New(), uuid.New()
+	for _, id := range []uuid.UUID{tenant, other} {
+		router.semanticCache.Store(RetrievalRequest{TenantID: id}, IntentSpecLookup, []float32{1, 0}, &RetrievalResponse{})
+	}
+
+	trigger := NewInvalidationTrigger(nil, logger)
+	trigger.AddInvalidator(router)
+	trigger.OnSpecValueUpdated(context.Background(), tenant, uuid.New())
+	assert.Equal(t, int64(1), router.CacheStats().KeyCount, "other tenants keep their entries")
+}
+
+// countingEmbedder embeds every question to the same vector, counting calls
+// and optionally holding them until release is closed.
+type countingEmbedder struct {
+	calls   atomic.Int64
+	release chan struct{}
+}
+
+func (e *countingEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	embeddings := make([][]float32, len(texts))
+	for i, text := range texts {
+		embeddings[i], _ = e.EmbedSingle(ctx, text)
+	}
+	return embeddings, nil
+}
+
+func (e *countingEmbedder) EmbedSingle(ctx context.Context, text string) ([]float32, error) {
+	e.calls.Add(1)
+	if e.release != nil {
+		<-e.release
+	}
+	return []float32{1, 0, 0}, nil
+}
+
+func (e *countingEmbedder) Model() string  { return "counting" }
+func (e *countingEmbedder) Dimension() int { return 3 }
+
+// newUSPRouter returns a router with one USP chunk matching every question.
+func newUSPRouter(t *testing.T, client cache.Client, embedder *countingEmbedder, cfg RouterConfig) (*Router, uuid.UUID) {
+	t.Helper()
+	logger := observability.NewLogger(observability.LogConfig{Level: "error"})
+	vectors, err := NewFAISSAdapter(FAISSConfig{Dimension: 3})
+	require.NoError(t, err)
+	tenant := uuid.New()
+	require.NoError(t, vectors.Insert(context.Background(), []VectorEntry{{
+		ID:        uuid.New(),
+		TenantID:  tenant,
+		ProductID: uuid.New(),
+		ChunkType: string(storage.ChunkTypeUSP),
+		Vector:    []float32{1, 0, 0},
+		Metadata:  map[string]interface{}{"chunk_type": string(storage.ChunkTypeUSP), "text": "Best in class safety."},
+	}}))
+	return NewRouter(logger, client, vectors, embedder, nil, cfg), tenant
+}
+
+func TestRouter_CoalescesConcurrentQueries(t *testing.T) {
+	embedder := &countingEmbedder{release: make(chan struct{})}
+	router, tenant := newUSPRouter(t, nil, embedder, RouterConfig{})
+	intent := IntentUSPLookup
+	req := RetrievalRequest{TenantID: tenant, Question: "Why is it safe?", IntentHint: &intent}
+
+	const callers = 5
+	responses := make([]*RetrievalResponse, callers)
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			resp, err := router.Query(context.Background(), req)
+			assert.NoError(t, err)
+			responses[i] = resp
+		}(i)
+	}
+	require.Eventually(t, func() bool { return router.inflight.Shared() == callers-1 }, time.Second, time.Millisecond)
+	close(embedder.release)
+	wg.Wait()
+
+	assert.Equal(t, int64(1), embedder.calls.Load(), "identical requests embed and search once")
+	for _, resp := range responses {
+		require.NotNil(t, resp)
+		assert.Len(t, resp.SemanticChunks, 1)
+	}
+	assert.NotSame(t, responses[0], responses[1], "each caller gets its own response")
+
+	want := responses[1].SemanticChunks[0]
+	responses[0].SemanticChunks[0].ChunkType = storage.ChunkTypeFAQ
+	responses[0].SemanticChunks = append(responses[0].SemanticChunks, SemanticChunk{ChunkType: storage.ChunkTypeUSP})
+	assert.Equal(t, want, responses[1].SemanticChunks[0], "a caller's changes stay in its own response")
+	assert.Len(t, responses[1].SemanticChunks, 1)
+
+	t.Run("explain requests are not shared", func(t *testing.T) {
+		req.Explain = true
+		_, err := router.Query(context.Background(), req)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), embedder.calls.Load())
+	})
+}
+
+func TestRouter_StaleWhileRevalidate(t *testing.T) {
+	embedder := &countingEmbedder{}
+	// A window longer than the TTL makes every cached response due for refresh
+	router, tenant := newUSPRouter(t, cache.NewMemoryClient(100), embedder, RouterConfig{
+		CacheResults:          true,
+		CacheTTL:              time.Hour,
+		CacheRevalidateWindow: 2 * time.Hour,
+	})
+	intent := IntentUSPLookup
+	req := RetrievalRequest{TenantID: tenant, Question: "Why is it safe?", IntentHint: &intent, Explain: true}
+	ctx := context.Background()
+
+	resp, err := router.Query(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, CacheMiss, resp.Trace.Cache)
+	require.Len(t, resp.SemanticChunks, 1)
+
+	resp, err = router.Query(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, CacheHit, resp.Trace.Cache, "the stale response is served")
+	assert.Equal(t, PathCache, resp.Trace.Path)
+	assert.Len(t, resp.SemanticChunks, 1)
+	require.Eventually(t, func() bool { return embedder.calls.Load() == 2 && router.inflight.Pending() == 0 }, time.Second, time.Millisecond,
+		"the response is refreshed in the background")
+
+	t.Run("invalidation clears cached responses", func(t *testing.T) {
+		require.NoError(t, router.ApplyInvalidation(ctx, cache.InvalidationEvent{Kind: cache.InvalidationSpecUpdated, TenantID: tenant}))
+		resp, err := router.Query(ctx, req)
+		require.NoError(t, err)
+		assert.Equal(t, CacheMiss, resp.Trace.Cache)
+	})
+}
+
+func TestRouter_RevalidatesCallerRequest(t *testing.T) {
+	embedder := &countingEmbedder{}
+	router, tenant := newUSPRouter(t, cache.NewMemoryClient(100), embedder, RouterConfig{
+		CacheResults:          true,
+		CacheTTL:              time.Hour,
+		CacheRevalidateWindow: 2 * time.Hour,
+	})
+	camry := testProduct("Toyota Camry", 2024, "")
+	xle := testTrim(camry.ID, "XLE", storage.CampaignStatusPublished, 1)
+	router.SetEntityLinker(NewEntityLinker(&stubCatalog{
+		products:  []*storage.Product{camry},
+		campaigns: []*storage.CampaignVariant{xle},
+	}, DefaultLinkerConfig()))
+	require.NoError(t, router.vectorAdapter.Insert(context.Background(), []VectorEntry{{
+		ID:                uuid.New(),
+		TenantID:          tenant,
+		ProductID:         camry.ID,
+		CampaignVariantID: &xle.ID,
+		ChunkType:         string(storage.ChunkTypeUSP),
+		Vector:            []float32{1, 0, 0},
+		Metadata:          map[string]interface{}{"chunk_type": string(storage.ChunkTypeUSP), "text": "Camry XLE safety."},
+	}}))
+	intent := IntentUSPLookup
+	req := RetrievalRequest{TenantID: tenant, Question: "Why is the Camry XLE safe?", IntentHint: &intent, Explain: true}
+	ctx := context.Background()
+
+	resp, err := router.Query(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, CacheMiss, resp.Trace.Cache)
+
+	resp, err = router.Query(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, CacheHit, resp.Trace.Cache)
+	require.Len(t, resp.Links, 2, "a cache hit reports the request's own links")
+	assert.True(t, resp.Links[1].Applied)
+	require.Eventually(t, func() bool { return embedder.calls.Load() == 2 && router.inflight.Pending() == 0 }, time.Second, time.Millisecond)
+
+	// The refreshed response was computed from the caller's request, so it links the same entities
+	linked := req
+	linked.ProductIDs = []uuid.UUID{camry.ID}
+	linked.CampaignVariantID = &xle.ID
+	linked.MaxChunks = router.config.MaxChunks
+	cached, _, err := router.checkCache(ctx, router.buildCacheKey(linked))
+	require.NoError(t, err)
+	require.Len(t, cached.Links, 2)
+	assert.True(t, cached.Links[1].Applied)
+}
+
+func TestRouter_Metrics(t *testing.T) {
+	embedder := &countingEmbedder{}
+	router, tenant := newUSPRouter(t, cache.NewMemoryClient(100), embedder, RouterConfig{CacheResults: true})
+	metrics := observability.NewMetrics()
+	router.SetMetrics(metrics)
+	ctx := context.Background()
+	intent := IntentUSPLookup
+	req := RetrievalRequest{TenantID: tenant, Question: "Why is it safe?", IntentHint: &intent}
+
+	for i := 0; i < 2; i++ {
+		_, err := router.Query(ctx, req)
+		require.NoError(t, err)
+	}
+	// No tenant data, so nothing is found
+	_, err := router.Query(ctx, RetrievalRequest{TenantID: uuid.New(), Question: "Why is it safe?", IntentHint: &intent})
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	for _, series := range []string{
+		`knowledge_engine_retrieval_duration_seconds_count{intent="usp_lookup",path="vector"} 1`,
+		`knowledge_engine_retrieval_duration_seconds_count{intent="usp_lookup",path="cache"} 1`,
+		`knowledge_engine_retrieval_duration_seconds_count{intent="usp_lookup",path="none"} 1`,
+		`knowledge_engine_cache_requests_total{cache="response",result="hit"} 1`,
+		`knowledge_engine_cache_requests_total{cache="response",result="miss"} 2`,
+		`knowledge_engine_zero_result_queries_total{intent="usp_lookup"} 1`,
+		`knowledge_engine_embedding_requests_total{result="ok"} 2`,
+	} {
+		assert.Contains(t, rec.Body.String(), series)
+	}
+}
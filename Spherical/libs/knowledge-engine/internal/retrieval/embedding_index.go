@@ -0,0 +1,341 @@
+// Package retrieval provides per-tenant embedding indexes for model upgrades.
+package retrieval
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/cache"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/embedding"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/storage"
+)
+
+// ErrNoEmbeddingIndex is returned when rolling back a scope that still uses
+// the router's default embedder and vector index.
+var ErrNoEmbeddingIndex = errors.New("no embedding index to roll back")
+
+// EmbeddingIndex is a vector index built with one embedding model. Queries
+// against it are embedded with Embedder and restricted to Version.
+type EmbeddingIndex struct {
+	Version  string
+	Embedder embedding.Embedder
+	Adapter  VectorAdapter
+}
+
+// EmbeddingIndexStore persists the embedding indexes activated per scope, so
+// a restarted instance, or another replica, serves the same index.
+// storage.EmbeddingIndexRepository implements it.
+type EmbeddingIndexStore interface {
+	Create(ctx context.Context, activation *storage.EmbeddingIndexActivation) error
+	DeleteLatest(ctx context.Context, tenantID uuid.UUID, campaignID *uuid.UUID) error
+	List(ctx context.Context, tenantID uuid.UUID, campaignID *uuid.UUID) ([]*storage.EmbeddingIndexActivation, error)
+	ListAll(ctx context.Context) ([]*storage.EmbeddingIndexActivation, error)
+}
+
+// EmbeddingIndexOpener opens the vector index built with an embedding model
+// and version, to serve an activation read from an EmbeddingIndexStore.
+type EmbeddingIndexOpener func(ctx context.Context, model, version string) (*EmbeddingIndex, error)
+
+// embeddingFillBatch is the number of stored vectors read per query when an
+// index is refilled.
+const embeddingFillBatch = 500
+
+// FillEmbeddingIndex inserts the vectors stored for idx's version into its
+// adapter, so an index reopened after a restart serves queries again. It
+// returns the number of vectors inserted.
+func FillEmbeddingIndex(ctx context.Context, store EmbeddingStore, idx *EmbeddingIndex) (int, error) {
+	filled := 0
+	after := uuid.Nil
+	for {
+		chunks, err := store.ListVersion(ctx, idx.Version, after, embeddingFillBatch)
+		if err != nil {
+			return filled, fmt.Errorf("load embeddings of %s: %w", idx.Version, err)
+		}
+		if len(chunks) == 0 {
+			return filled, nil
+		}
+		entries := make([]VectorEntry, 0, len(chunks))
+		for _, chunk := range chunks {
+			if len(chunk.EmbeddingVector) > 0 {
+				entries = append(entries, chunkVectorEntry(chunk, idx.Version, chunk.EmbeddingVector))
+			}
+		}
+		if err := idx.Adapter.Insert(ctx, entries); err != nil {
+			return filled, fmt.Errorf("fill embedding index %s: %w", idx.Version, err)
+		}
+		filled += len(entries)
+		after = chunks[len(chunks)-1].ID
+	}
+}
+
+// IndexSwitchPublisher announces an embedding index switch to every instance,
+// including this one. InvalidationTrigger implements it.
+type IndexSwitchPublisher interface {
+	OnEmbeddingIndexSwitched(ctx context.Context, tenantID uuid.UUID, campaignID *uuid.UUID) error
+}
+
+// indexScope identifies a tenant, or one of its campaigns, with its own index.
+// A nil campaign stands for the whole tenant.
+type indexScope struct {
+	tenantID   uuid.UUID
+	campaignID uuid.UUID
+}
+
+func newIndexScope(tenantID uuid.UUID, campaignID *uuid.UUID) indexScope {
+	scope := indexScope{tenantID: tenantID}
+	if campaignID != nil {
+		scope.campaignID = *campaignID
+	}
+	return scope
+}
+
+// embeddingIndexes holds the indexes activated per scope. Earlier indexes are
+// kept so a switch can be rolled back.
+type embeddingIndexes struct {
+	mu      sync.RWMutex
+	history map[indexScope][]*EmbeddingIndex
+
+	store     EmbeddingIndexStore
+	open      EmbeddingIndexOpener
+	publisher IndexSwitchPublisher
+}
+
+// SetEmbeddingIndexStore persists index switches in store. Activations made
+// by other instances, or before a restart, are served with indexes from open.
+func (r *Router) SetEmbeddingIndexStore(store EmbeddingIndexStore, open EmbeddingIndexOpener) {
+	r.indexes.mu.Lock()
+	defer r.indexes.mu.Unlock()
+	r.indexes.store = store
+	r.indexes.open = open
+}
+
+// SetIndexSwitchPublisher announces index switches through publisher, so other
+// instances switch too. Without one, switches only evict this instance's caches.
+func (r *Router) SetIndexSwitchPublisher(publisher IndexSwitchPublisher) {
+	r.indexes.mu.Lock()
+	defer r.indexes.mu.Unlock()
+	r.indexes.publisher = publisher
+}
+
+// LoadEmbeddingIndexes restores the indexes activated in the embedding index
+// store, replacing the ones held in memory.
+func (r *Router) LoadEmbeddingIndexes(ctx context.Context) error {
+	r.indexes.mu.RLock()
+	store := r.indexes.store
+	r.indexes.mu.RUnlock()
+	if store == nil {
+		return nil
+	}
+
+	activations, err := store.ListAll(ctx)
+	if err != nil {
+		return fmt.Errorf("load embedding indexes: %w", err)
+	}
+	scopes := make(map[indexScope][]*storage.EmbeddingIndexActivation)
+	for _, activation := range activations {
+		scope := newIndexScope(activation.TenantID, activation.CampaignVariantID)
+		scopes[scope] = append(scopes[scope], activation)
+	}
+	for scope, activations := range scopes {
+		if err := r.restoreEmbeddingIndexes(ctx, scope, activations); err != nil {
+			return err
+		}
+	}
+	r.logger.Info().Int("scopes", len(scopes)).Msg("Embedding indexes loaded")
+	return nil
+}
+
+// syncEmbeddingIndexes reloads a scope's indexes from the store after another
+// instance switched them. It does nothing when they already match.
+func (r *Router) syncEmbeddingIndexes(ctx context.Context, tenantID uuid.UUID, campaignID *uuid.UUID) error {
+	r.indexes.mu.RLock()
+	store := r.indexes.store
+	r.indexes.mu.RUnlock()
+	if store == nil {
+		return nil
+	}
+
+	activations, err := store.List(ctx, tenantID, campaignID)
+	if err != nil {
+		return fmt.Errorf("load embedding indexes: %w", err)
+	}
+	return r.restoreEmbeddingIndexes(ctx, newIndexScope(tenantID, campaignID), activations)
+}
+
+// restoreEmbeddingIndexes replaces a scope's history with the stored
+// activations, keeping the indexes already open for the same versions.
+func (r *Router) restoreEmbeddingIndexes(ctx context.Context, scope indexScope, activations []*storage.EmbeddingIndexActivation) error {
+	r.indexes.mu.RLock()
+	current := r.indexes.history[scope]
+	open := r.indexes.open
+	r.indexes.mu.RUnlock()
+
+	if len(current) == len(activations) {
+		same := true
+		for i, idx := range current {
+			if idx.Version != activations[i].EmbeddingVersion {
+				same = false
+				break
+			}
+		}
+		if same {
+			return nil
+		}
+	}
+
+	opened := make(map[string]*EmbeddingIndex, len(current))
+	for _, idx := range current {
+		opened[idx.Version] = idx
+	}
+	history := make([]*EmbeddingIndex, 0, len(activations))
+	for _, activation := range activations {
+		idx := opened[activation.EmbeddingVersion]
+		if idx == nil {
+			if open == nil {
+				return fmt.Errorf("no opener for embedding index %s", activation.EmbeddingVersion)
+			}
+			var err error
+			idx, err = open(ctx, activation.EmbeddingModel, activation.EmbeddingVersion)
+			if err != nil {
+				return fmt.Errorf("open embedding index %s: %w", activation.EmbeddingVersion, err)
+			}
+			opened[activation.EmbeddingVersion] = idx
+		}
+		history = append(history, idx)
+	}
+
+	r.indexes.mu.Lock()
+	if r.indexes.history == nil {
+		r.indexes.history = make(map[indexScope][]*EmbeddingIndex)
+	}
+	if len(history) > 0 {
+		r.indexes.history[scope] = history
+	} else {
+		delete(r.indexes.history, scope)
+	}
+	r.indexes.mu.Unlock()
+	return nil
+}
+
+// ActivateEmbeddingIndex switches vector search for a tenant, or for one
+// campaign when campaignID is set, to idx. Cached responses of the tenant were
+// retrieved with the previous index and are dropped. The switch is persisted
+// and announced to other instances when a store and publisher are set; it is
+// only made once persisted.
+func (r *Router) ActivateEmbeddingIndex(ctx context.Context, tenantID uuid.UUID, campaignID *uuid.UUID, idx *EmbeddingIndex) error {
+	if idx == nil || idx.Embedder == nil || idx.Adapter == nil {
+		return fmt.Errorf("embedding index needs an embedder and a vector adapter")
+	}
+
+	r.indexes.mu.RLock()
+	store := r.indexes.store
+	r.indexes.mu.RUnlock()
+	if store != nil {
+		err := store.Create(ctx, &storage.EmbeddingIndexActivation{
+			TenantID:          tenantID,
+			CampaignVariantID: campaignID,
+			EmbeddingModel:    idx.Embedder.Model(),
+			EmbeddingVersion:  idx.Version,
+		})
+		if err != nil {
+			return fmt.Errorf("persist embedding index: %w", err)
+		}
+	}
+
+	scope := newIndexScope(tenantID, campaignID)
+	r.indexes.mu.Lock()
+	if r.indexes.history == nil {
+		r.indexes.history = make(map[indexScope][]*EmbeddingIndex)
+	}
+	r.indexes.history[scope] = append(r.indexes.history[scope], idx)
+	r.indexes.mu.Unlock()
+
+	r.logger.Info().
+		Str("tenant_id", tenantID.String()).
+		Str("model", idx.Embedder.Model()).
+		Str("embedding_version", idx.Version).
+		Msg("Embedding index activated")
+	return r.invalidateEmbeddingIndex(ctx, tenantID, campaignID)
+}
+
+// RollbackEmbeddingIndex switches vector search for a tenant, or one campaign,
+// back to the index active before the last ActivateEmbeddingIndex, and returns
+// it. A nil index means the router's default embedder and vector index.
+func (r *Router) RollbackEmbeddingIndex(ctx context.Context, tenantID uuid.UUID, campaignID *uuid.UUID) (*EmbeddingIndex, error) {
+	scope := newIndexScope(tenantID, campaignID)
+	r.indexes.mu.RLock()
+	store := r.indexes.store
+	rollable := len(r.indexes.history[scope]) > 0
+	r.indexes.mu.RUnlock()
+	if !rollable {
+		return nil, ErrNoEmbeddingIndex
+	}
+	if store != nil {
+		if err := store.DeleteLatest(ctx, tenantID, campaignID); err != nil {
+			return nil, fmt.Errorf("persist embedding index rollback: %w", err)
+		}
+	}
+
+	r.indexes.mu.Lock()
+	history := r.indexes.history[scope]
+	if len(history) == 0 {
+		r.indexes.mu.Unlock()
+		return nil, ErrNoEmbeddingIndex
+	}
+	history = history[:len(history)-1]
+	var active *EmbeddingIndex
+	if len(history) > 0 {
+		r.indexes.history[scope] = history
+		active = history[len(history)-1]
+	} else {
+		delete(r.indexes.history, scope)
+	}
+	r.indexes.mu.Unlock()
+
+	r.logger.Info().Str("tenant_id", tenantID.String()).Msg("Embedding index rolled back")
+	return active, r.invalidateEmbeddingIndex(ctx, tenantID, campaignID)
+}
+
+// ActiveEmbeddingIndex returns the index activated for a tenant, or one
+// campaign, or nil when it uses the router's default embedder and vector index.
+func (r *Router) ActiveEmbeddingIndex(tenantID uuid.UUID, campaignID *uuid.UUID) *EmbeddingIndex {
+	r.indexes.mu.RLock()
+	defer r.indexes.mu.RUnlock()
+	history := r.indexes.history[newIndexScope(tenantID, campaignID)]
+	if len(history) == 0 {
+		return nil
+	}
+	return history[len(history)-1]
+}
+
+// vectorIndex returns the index vector search for a request uses: the
+// campaign's own index when the search is confined to that campaign, otherwise
+// the tenant's, or nil for the router's default.
+func (r *Router) vectorIndex(tenantID uuid.UUID, filters VectorFilters) *EmbeddingIndex {
+	if filters.CampaignVariantID != nil && len(filters.CampaignVariantIDs) == 0 {
+		if idx := r.ActiveEmbeddingIndex(tenantID, filters.CampaignVariantID); idx != nil {
+			return idx
+		}
+	}
+	return r.ActiveEmbeddingIndex(tenantID, nil)
+}
+
+// invalidateEmbeddingIndex announces that a tenant's index changed, so every
+// instance switches and drops the responses it cached, or without a publisher
+// drops this instance's.
+func (r *Router) invalidateEmbeddingIndex(ctx context.Context, tenantID uuid.UUID, campaignID *uuid.UUID) error {
+	r.indexes.mu.RLock()
+	publisher := r.indexes.publisher
+	r.indexes.mu.RUnlock()
+	if publisher != nil {
+		return publisher.OnEmbeddingIndexSwitched(ctx, tenantID, campaignID)
+	}
+	return r.ApplyInvalidation(ctx, cache.InvalidationEvent{
+		Kind:       cache.InvalidationEmbeddingIndexSwitch,
+		TenantID:   tenantID,
+		CampaignID: campaignID,
+	})
+}
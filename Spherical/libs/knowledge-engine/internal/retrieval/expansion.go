@@ -0,0 +1,440 @@
+// Package retrieval provides query expansion with synonyms, spec aliases, unit
+// words and spelling correction.
+package retrieval
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/lexical"
+)
+
+// ExpansionSource identifies where a query expansion came from.
+type ExpansionSource string
+
+const (
+	ExpansionSynonym  ExpansionSource = "synonym"
+	ExpansionAlias    ExpansionSource = "alias"
+	ExpansionUnit     ExpansionSource = "unit"
+	ExpansionSpelling ExpansionSource = "spelling"
+)
+
+// DefaultExpansionDomain is the synonym dictionary used when none is configured.
+const DefaultExpansionDomain = "automotive"
+
+// minSpellingWordLen is the shortest word spelling correction is attempted on.
+const minSpellingWordLen = 5
+
+// maxExpansionPhrase is the longest dictionary or alias phrase matched, in words.
+const maxExpansionPhrase = 4
+
+// QueryExpansion is an alternative term searched alongside the question.
+type QueryExpansion struct {
+	// Term is the text added to the query.
+	Term string
+	// Original is the part of the question the term was derived from.
+	Original string
+	Source   ExpansionSource
+	// Weight scales the term's score relative to original query terms.
+	Weight float64
+}
+
+// ExpansionConfig configures query expansion.
+type ExpansionConfig struct {
+	// Disabled turns query expansion off.
+	Disabled bool
+	// Domain selects the built-in synonym dictionary (default "automotive").
+	Domain string
+	// Synonyms extends the domain dictionary; keys are lowercase query phrases.
+	Synonyms map[string][]string
+	// TenantSynonyms extends the dictionary for specific tenants.
+	TenantSynonyms map[uuid.UUID]map[string][]string
+	// Weights scale expansions per source and must be below 1 so original terms
+	// always rank first (defaults: spelling 0.8, alias 0.7, synonym 0.6, unit 0.4).
+	Weights map[ExpansionSource]float64
+	// MinSpellingSimilarity is the edit similarity needed to correct a word (default 0.8).
+	MinSpellingSimilarity float64
+	// MaxExpansions bounds the expansions added to one query (default 12).
+	MaxExpansions int
+}
+
+func (c ExpansionConfig) withDefaults() ExpansionConfig {
+	if c.Domain == "" {
+		c.Domain = DefaultExpansionDomain
+	}
+	weights := map[ExpansionSource]float64{
+		ExpansionSpelling: 0.8,
+		ExpansionAlias:    0.7,
+		ExpansionSynonym:  0.6,
+		ExpansionUnit:     0.4,
+	}
+	for source, w := range c.Weights {
+		if w > 0 && w < 1 {
+			weights[source] = w
+		}
+	}
+	c.Weights = weights
+	if c.MinSpellingSimilarity <= 0 || c.MinSpellingSimilarity > 1 {
+		c.MinSpellingSimilarity = 0.8
+	}
+	if c.MaxExpansions <= 0 {
+		c.MaxExpansions = 12
+	}
+	return c
+}
+
+// domainSynonyms are built-in synonym dictionaries by domain. Keys are lowercase
+// query phrases; values are the spec vocabulary they should also match.
+var domainSynonyms = map[string]map[string][]string{
+	"automotive": {
+		"mileage":          {"fuel efficiency", "fuel economy"},
+		"fuel economy":     {"fuel efficiency", "mileage"},
+		"fuel efficiency":  {"mileage", "fuel economy"},
+		"fuel average":     {"fuel efficiency", "mileage"},
+		"fe":               {"fuel efficiency"},
+		"economy":          {"fuel efficiency"},
+		"horsepower":       {"power"},
+		"pickup":           {"acceleration"},
+		"boot":             {"boot space", "cargo capacity"},
+		"boot space":       {"cargo capacity", "luggage capacity"},
+		"trunk":            {"boot space", "cargo capacity"},
+		"luggage":          {"boot space", "cargo capacity"},
+		"gearbox":          {"transmission"},
+		"transmission":     {"gearbox"},
+		"moonroof":         {"sunroof"},
+		"sunroof":          {"moonroof"},
+		"ac":               {"air conditioning", "climate control"},
+		"air conditioning": {"climate control"},
+		"climate control":  {"air conditioning"},
+		"abs":              {"anti-lock braking"},
+		"ground clearance": {"ride height"},
+		"ride height":      {"ground clearance"},
+		"top speed":        {"maximum speed"},
+		"price":            {"ex-showroom", "cost"},
+		"cost":             {"price", "ex-showroom"},
+		"seats":            {"seating capacity"},
+		"seater":           {"seating capacity"},
+		"engine size":      {"displacement", "engine capacity"},
+		"displacement":     {"engine capacity"},
+		"infotainment":     {"touchscreen", "display"},
+		"warranty":         {"guarantee"},
+		"paint":            {"color"},
+		"shade":            {"color"},
+	},
+}
+
+// unitDimensionTerms names the specs a unit word points at. Length and mass
+// units are left out because they apply to too many specs to help.
+var unitDimensionTerms = map[Dimension][]string{
+	DimensionVolume:     {"capacity", "displacement"},
+	DimensionPower:      {"power"},
+	DimensionTorque:     {"torque"},
+	DimensionEfficiency: {"fuel efficiency", "mileage"},
+	DimensionSpeed:      {"top speed"},
+	DimensionTime:       {"acceleration"},
+	DimensionEnergy:     {"battery capacity"},
+	DimensionPrice:      {"price"},
+}
+
+// QueryExpander adds weighted alternative terms to questions so that specs
+// stored under a different name, alias or spelling are still found.
+type QueryExpander struct {
+	config   ExpansionConfig
+	analyzer *lexical.Analyzer
+
+	mu sync.RWMutex
+	// aliases maps lowercase spec aliases to spec names per tenant.
+	aliases map[uuid.UUID]map[string]string
+}
+
+// NewQueryExpander creates a query expander. The analyzer must be the one used by
+// the lexical index so spelling corrections are compared with indexed terms.
+func NewQueryExpander(cfg ExpansionConfig, analyzer *lexical.Analyzer) *QueryExpander {
+	if analyzer == nil {
+		analyzer = lexical.NewAnalyzer()
+	}
+	return &QueryExpander{
+		config:   cfg.withDefaults(),
+		analyzer: analyzer,
+		aliases:  make(map[uuid.UUID]map[string]string),
+	}
+}
+
+// SetSpecAliases replaces a tenant's spec aliases, keyed by spec name.
+func (e *QueryExpander) SetSpecAliases(tenantID uuid.UUID, aliases map[string][]string) {
+	byAlias := make(map[string]string)
+	for name, list := range aliases {
+		for _, alias := range list {
+			if alias = strings.ToLower(strings.TrimSpace(alias)); alias != "" {
+				byAlias[alias] = name
+			}
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.aliases[tenantID] = byAlias
+}
+
+// InvalidateSpecAliases drops a tenant's spec aliases so they are reloaded on
+// the next query.
+func (e *QueryExpander) InvalidateSpecAliases(tenantID uuid.UUID) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	delete(e.aliases, tenantID)
+}
+
+// HasSpecAliases reports whether aliases were set for a tenant.
+func (e *QueryExpander) HasSpecAliases(tenantID uuid.UUID) bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	_, ok := e.aliases[tenantID]
+	return ok
+}
+
+// Expand returns weighted expansions for a question. Vocabulary holds the
+// tenant's indexed terms with document frequencies and may be nil, in which
+// case only dictionary words are used for spelling correction.
+func (e *QueryExpander) Expand(tenantID uuid.UUID, question string, vocabulary map[string]int) []QueryExpansion {
+	if e.config.Disabled {
+		return nil
+	}
+
+	dictionary := e.dictionary(tenantID)
+	e.mu.RLock()
+	aliases := e.aliases[tenantID]
+	e.mu.RUnlock()
+
+	var expansions []QueryExpansion
+	seen := make(map[string]bool)
+	add := func(term, original string, source ExpansionSource) {
+		key := strings.ToLower(term)
+		if seen[key] || key == strings.ToLower(original) {
+			return
+		}
+		seen[key] = true
+		expansions = append(expansions, QueryExpansion{
+			Term:     term,
+			Original: original,
+			Source:   source,
+			Weight:   e.config.Weights[source],
+		})
+	}
+
+	// Correct misspelled words first so synonyms and aliases see the fixed word
+	words := make([]string, 0)
+	originals := make([]string, 0)
+	for _, tok := range linkTokens(question) {
+		seen[tok.text] = true
+		originals = append(originals, tok.text)
+		if corrected, ok := e.correct(tok.text, vocabulary, dictionary, aliases); ok {
+			add(corrected, tok.text, ExpansionSpelling)
+			words = append(words, corrected)
+			continue
+		}
+		words = append(words, tok.text)
+	}
+
+	// Match dictionary phrases and spec aliases, longest first
+	for i := 0; i < len(words); {
+		matched := 0
+		for n := maxExpansionPhrase; n >= 1 && matched == 0; n-- {
+			if i+n > len(words) {
+				continue
+			}
+			phrase := strings.Join(words[i:i+n], " ")
+			original := strings.Join(originals[i:i+n], " ")
+			if name, ok := aliases[phrase]; ok {
+				add(name, original, ExpansionAlias)
+				matched = n
+			}
+			if terms, ok := dictionary[phrase]; ok {
+				for _, term := range terms {
+					add(term, original, ExpansionSynonym)
+				}
+				matched = n
+			}
+		}
+		if matched == 0 {
+			matched = 1
+		}
+		i += matched
+	}
+
+	for _, unit := range unitWords(question) {
+		for _, term := range unitDimensionTerms[baseDimension(predicateUnits[unit].dimension)] {
+			add(term, unit, ExpansionUnit)
+		}
+	}
+
+	if len(expansions) > e.config.MaxExpansions {
+		expansions = expansions[:e.config.MaxExpansions]
+	}
+	return expansions
+}
+
+// dictionary merges the domain, configured and tenant synonyms.
+func (e *QueryExpander) dictionary(tenantID uuid.UUID) map[string][]string {
+	merged := make(map[string][]string)
+	for _, source := range []map[string][]string{
+		domainSynonyms[e.config.Domain],
+		e.config.Synonyms,
+		e.config.TenantSynonyms[tenantID],
+	} {
+		for phrase, terms := range source {
+			phrase = strings.ToLower(strings.TrimSpace(phrase))
+			merged[phrase] = append(merged[phrase], terms...)
+		}
+	}
+	return merged
+}
+
+// correct returns the closest known word for an unknown word. Known words are
+// indexed terms (compared after stemming) and single-word dictionary phrases
+// and aliases (compared as written). Ties prefer the more frequent term.
+func (e *QueryExpander) correct(word string, vocabulary map[string]int, dictionary map[string][]string, aliases map[string]string) (string, bool) {
+	if len(word) < minSpellingWordLen || !isLetters(word) || len(e.analyzer.Terms(word)) == 0 {
+		return "", false
+	}
+	term := e.analyzer.Normalize(word)
+	if vocabulary[term] > 0 || dictionary[word] != nil || aliases[word] != "" {
+		return "", false
+	}
+
+	best, bestScore, bestFreq := "", 0.0, 0
+	consider := func(candidate, compareTo string, freq int) {
+		if strings.Contains(candidate, " ") || absInt(len(candidate)-len(compareTo)) > 2 {
+			return
+		}
+		score := editSimilarity(compareTo, candidate)
+		if score < e.config.MinSpellingSimilarity {
+			return
+		}
+		if score > bestScore || (score == bestScore && freq > bestFreq) ||
+			(score == bestScore && freq == bestFreq && candidate < best) {
+			best, bestScore, bestFreq = candidate, score, freq
+		}
+	}
+	for candidate, freq := range vocabulary {
+		if isLetters(candidate) {
+			consider(candidate, term, freq)
+		}
+	}
+	for phrase := range dictionary {
+		consider(phrase, word, 0)
+	}
+	for alias := range aliases {
+		consider(alias, word, 0)
+	}
+	return best, best != ""
+}
+
+// unitWords returns the unit spellings written in a question, such as "kmpl" or
+// "bhp". Single-letter units are skipped as they are too ambiguous on their own.
+func unitWords(question string) []string {
+	lower := strings.ToLower(question)
+	var units []string
+	for i := 0; i < len(lower); i++ {
+		if lower[i] == ' ' || (i > 0 && isLetter(lower[i-1])) {
+			continue
+		}
+		unit, _, n := parseUnitPrefix(lower[i:])
+		if n == 0 || len(unit) < 2 {
+			continue
+		}
+		units = append(units, unit)
+		i += n - 1
+	}
+	sort.Strings(units)
+	return units
+}
+
+// lexicalExpansions converts query expansions into weighted lexical query text.
+func lexicalExpansions(expansions []QueryExpansion) []lexical.Expansion {
+	if len(expansions) == 0 {
+		return nil
+	}
+	out := make([]lexical.Expansion, len(expansions))
+	for i, exp := range expansions {
+		out[i] = lexical.Expansion{Text: exp.Term, Weight: exp.Weight}
+	}
+	return out
+}
+
+// searchExpandedKeywordSpecs runs the keyword fallback search for each expansion
+// term and returns facts not already found. The keyword search has no term
+// weights, so expansion matches are ranked after every fact matching the question.
+func (r *Router) searchExpandedKeywordSpecs(ctx context.Context, req RetrievalRequest, found []SpecFact, scope *campaignScope) []SpecFact {
+	if len(req.expansions) == 0 {
+		return nil
+	}
+
+	seen := make(map[string]bool, len(found))
+	for _, f := range found {
+		seen[factLabel(f)] = true
+	}
+	var facts []SpecFact
+	for _, exp := range req.expansions {
+		for _, f := range r.searchKeywordSpecs(ctx, req, []string{strings.ToLower(exp.Term)}, scope) {
+			if seen[factLabel(f)] {
+				continue
+			}
+			seen[factLabel(f)] = true
+			facts = append(facts, f)
+		}
+	}
+	return facts
+}
+
+// expandQuery computes expansions for a request against the tenant's indexed
+// vocabulary and spec aliases.
+func (r *Router) expandQuery(ctx context.Context, req RetrievalRequest) []QueryExpansion {
+	if r.expander == nil || r.expander.config.Disabled {
+		return nil
+	}
+
+	var vocabulary map[string]int
+	if index, ok := r.searchIndex(ctx, req); ok {
+		vocabulary = index.Vocabulary(req.TenantID)
+	}
+	if r.specViewRepo != nil && !r.expander.HasSpecAliases(req.TenantID) {
+		aliases, err := r.specViewRepo.SpecAliases(ctx, req.TenantID)
+		if err != nil {
+			r.logger.Warn().Err(err).Str("tenant_id", req.TenantID.String()).Msg("Failed to load spec aliases")
+		} else {
+			r.expander.SetSpecAliases(req.TenantID, aliases)
+		}
+	}
+
+	expansions := r.expander.Expand(req.TenantID, req.Question, vocabulary)
+	if len(expansions) > 0 {
+		r.logger.Debug().
+			Str("question", req.Question).
+			Int("expansions", len(expansions)).
+			Msg("Expanded query")
+	}
+	return expansions
+}
+
+func isLetters(word string) bool {
+	for i := 0; i < len(word); i++ {
+		if !isLetter(word[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func absInt(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,134 @@
+package retrieval
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/cache"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/lexical"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/observability"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// expansionsBySource groups expansion terms by source for assertions.
+func expansionsBySource(expansions []QueryExpansion) map[ExpansionSource][]string {
+	bySource := make(map[ExpansionSource][]string)
+	for _, exp := range expansions {
+		bySource[exp.Source] = append(bySource[exp.Source], exp.Term)
+	}
+	return bySource
+}
+
+func TestQueryExpander_Expand(t *testing.T) {
+	tenant, other := uuid.New(), uuid.New()
+	expander := NewQueryExpander(ExpansionConfig{
+		TenantSynonyms: map[uuid.UUID]map[string][]string{
+			tenant: {"FE": {"km per litre"}},
+		},
+	}, nil)
+	expander.SetSpecAliases(tenant, map[string][]string{"Fuel Tank Capacity": {"Tank Size"}})
+
+	t.Run("synonyms", func(t *testing.T) {
+		got := expansionsBySource(expander.Expand(other, "What is the fuel economy?", nil))
+		assert.Equal(t, []string{"fuel efficiency", "mileage"}, got[ExpansionSynonym])
+
+		// Tenant synonyms extend the domain dictionary only for that tenant
+		assert.Equal(t, []string{"fuel efficiency", "km per litre"}, expansionsBySource(expander.Expand(tenant, "FE?", nil))[ExpansionSynonym])
+		assert.Equal(t, []string{"fuel efficiency"}, expansionsBySource(expander.Expand(other, "FE?", nil))[ExpansionSynonym])
+	})
+
+	t.Run("aliases", func(t *testing.T) {
+		expansions := expander.Expand(tenant, "How big is the tank size?", nil)
+		require.NotEmpty(t, expansions)
+		assert.Equal(t, QueryExpansion{Term: "Fuel Tank Capacity", Original: "tank size", Source: ExpansionAlias, Weight: 0.7}, expansions[0])
+		assert.Empty(t, expander.Expand(other, "How big is the tank size?", nil))
+	})
+
+	t.Run("units", func(t *testing.T) {
+		got := expansionsBySource(expander.Expand(other, "Does it do 20 kmpl and 150bhp?", nil))
+		assert.Equal(t, []string{"power", "fuel efficiency", "mileage"}, got[ExpansionUnit])
+
+		// Single-letter units are ignored
+		assert.Empty(t, expander.Expand(other, "0-100 in 9 s", nil))
+	})
+
+	t.Run("spelling", func(t *testing.T) {
+		vocabulary := map[string]int{"airbag": 4, "airbus": 1}
+		expansions := expander.Expand(other, "How many airbgs?", vocabulary)
+		require.Len(t, expansions, 1)
+		assert.Equal(t, QueryExpansion{Term: "airbag", Original: "airbgs", Source: ExpansionSpelling, Weight: 0.8}, expansions[0])
+
+		// Corrections to dictionary words pick up their synonyms
+		got := expansionsBySource(expander.Expand(other, "What is the milage?", nil))
+		assert.Equal(t, []string{"mileage"}, got[ExpansionSpelling])
+		assert.Equal(t, []string{"fuel efficiency", "fuel economy"}, got[ExpansionSynonym])
+
+		// Known and short words are left alone
+		assert.Empty(t, expander.Expand(other, "How many airbags in the trim?", vocabulary))
+	})
+
+	t.Run("weights stay below original terms", func(t *testing.T) {
+		e := NewQueryExpander(ExpansionConfig{Weights: map[ExpansionSource]float64{ExpansionSynonym: 1.5}}, nil)
+		for _, exp := range e.Expand(other, "mileage in kmpl", nil) {
+			assert.Less(t, exp.Weight, 1.0)
+		}
+		assert.Empty(t, NewQueryExpander(ExpansionConfig{Disabled: true}, nil).Expand(other, "mileage", nil))
+	})
+}
+
+func TestRouter_QueryExpansion(t *testing.T) {
+	logger := observability.NewLogger(observability.LogConfig{Level: "error"})
+	tenant := uuid.New()
+	router := NewRouter(logger, nil, nil, nil, nil, RouterConfig{StructuredFirst: true})
+	for _, spec := range []struct{ name, value string }{
+		{"Fuel Efficiency", "18.2 km/l"},
+		{"Airbags", "6"},
+		{"Length", "4315 mm"},
+	} {
+		router.LexicalIndex().Upsert(lexical.SpecDocument(storage.SpecViewLatest{
+			ID:           uuid.New(),
+			SpecItemID:   uuid.New(),
+			TenantID:     tenant,
+			CategoryName: "Specifications",
+			SpecName:     spec.name,
+			Value:        spec.value,
+			Confidence:   0.9,
+		}))
+	}
+	router.LexicalIndex().MarkLoaded(tenant)
+	ctx := context.Background()
+
+	for _, question := range []string{"What is the milage?", "What is the fuel economy?", "What is the FE?", "How many kmpl?"} {
+		resp, err := router.Query(ctx, RetrievalRequest{TenantID: tenant, Question: question, Explain: true})
+		require.NoError(t, err, question)
+		require.NotEmpty(t, resp.StructuredFacts, question)
+		assert.Equal(t, "Fuel Efficiency", resp.StructuredFacts[0].Name, question)
+		assert.NotEmpty(t, resp.Trace.Expansions, question)
+	}
+
+	resp, err := router.Query(ctx, RetrievalRequest{TenantID: tenant, Question: "How many airbgs?", Explain: true})
+	require.NoError(t, err)
+	require.NotEmpty(t, resp.StructuredFacts)
+	assert.Equal(t, "Airbags", resp.StructuredFacts[0].Name)
+	require.Len(t, resp.Trace.Expansions, 1)
+	assert.Equal(t, ExpansionSpelling, resp.Trace.Expansions[0].Source)
+
+	stages := make(map[string]bool)
+	for _, st := range resp.Trace.Stages {
+		stages[st.Stage] = true
+	}
+	assert.True(t, stages["expand_query"])
+
+	t.Run("invalidation reloads spec aliases", func(t *testing.T) {
+		router.expander.SetSpecAliases(tenant, map[string][]string{"Fuel Efficiency": {"FE"}})
+		other := uuid.New()
+		router.expander.SetSpecAliases(other, map[string][]string{"Airbags": {"SRS"}})
+
+		require.NoError(t, router.ApplyInvalidation(ctx, cache.InvalidationEvent{Kind: cache.InvalidationSpecUpdated, TenantID: tenant}))
+		assert.False(t, router.expander.HasSpecAliases(tenant))
+		assert.True(t, router.expander.HasSpecAliases(other))
+	})
+}
@@ -0,0 +1,182 @@
+// Package retrieval provides explain traces describing how a query was answered.
+package retrieval
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/observability"
+)
+
+// maxTraceCandidates bounds the candidates and filter decisions recorded per source.
+const maxTraceCandidates = 50
+
+// RetrievalPath names the retrieval path a query took.
+type RetrievalPath string
+
+const (
+	PathKeywordOnly RetrievalPath = "keyword_only"
+	PathHybrid      RetrievalPath = "hybrid"
+	PathVector      RetrievalPath = "vector"
+	PathPredicate   RetrievalPath = "predicate"
+	PathNone        RetrievalPath = "none"
+	// PathCache marks responses served from the exact or semantic cache.
+	PathCache RetrievalPath = "cache"
+)
+
+// Cache outcomes recorded in a trace.
+const (
+	CacheHit      = "hit"
+	CacheMiss     = "miss"
+	CacheDisabled = "disabled"
+)
+
+// Candidate sources recorded in a trace.
+const (
+	SourceLexical   = "lexical"
+	SourceKeyword   = "keyword"
+	SourceVector    = "vector"
+	SourceFusion    = "fusion"
+	SourcePredicate = "predicate"
+)
+
+// Trace explains how a query was answered. It is set on RetrievalResponse
+// when RetrievalRequest.Explain is true.
+type Trace struct {
+	// Question is the question retrieval ran with, after rewriting.
+	Question string
+	Intent   Intent
+	// ClassifiedIntent and IntentConfidence are the classifier output; Intent
+	// falls back to IntentUnknown when the confidence is below the threshold.
+	ClassifiedIntent Intent
+	IntentConfidence float64
+	IntentHinted     bool
+	Keywords         []string
+	// Expansions are the weighted terms searched alongside the keywords.
+	Expansions []QueryExpansion
+	Path       RetrievalPath
+	// KeywordConfidence is the structured search confidence and its components.
+	KeywordConfidence float64
+	ConfidenceFactors map[string]float64
+	Cache             string
+	Candidates        []CandidateScore
+	Filters           []FilterDecision
+	Stages            []StageTiming
+
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+// CandidateScore records how one retrieval candidate was scored.
+type CandidateScore struct {
+	Kind   EvidenceKind
+	ID     uuid.UUID
+	Label  string
+	Source string
+	Score  float64
+	// Components are the named inputs to Score, e.g. bm25 or similarity.
+	Components map[string]float64
+}
+
+// FilterDecision records why a candidate was kept or dropped.
+type FilterDecision struct {
+	Stage   string
+	Subject string
+	Kept    bool
+	Reason  string
+}
+
+// StageTiming records the duration of one query stage.
+type StageTiming struct {
+	Stage      string
+	DurationMs float64
+}
+
+type traceKey struct{}
+
+// withTrace returns a context carrying the trace.
+func withTrace(ctx context.Context, t *Trace) context.Context {
+	return context.WithValue(ctx, traceKey{}, t)
+}
+
+// traceFrom returns the trace carried by ctx, or nil. Trace methods are no-ops on nil.
+func traceFrom(ctx context.Context) *Trace {
+	t, _ := ctx.Value(traceKey{}).(*Trace)
+	return t
+}
+
+// candidate records a scored candidate, up to maxTraceCandidates per source.
+func (t *Trace) candidate(c CandidateScore) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.allow("candidate:" + c.Source) {
+		return
+	}
+	t.Candidates = append(t.Candidates, c)
+}
+
+// filter records a filter decision, up to maxTraceCandidates per stage.
+func (t *Trace) filter(stage, subject string, kept bool, reason string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.allow("filter:" + stage) {
+		return
+	}
+	t.Filters = append(t.Filters, FilterDecision{Stage: stage, Subject: subject, Kept: kept, Reason: reason})
+}
+
+// stage records the time elapsed since start; use as defer t.stage(name, time.Now()).
+func (t *Trace) stage(name string, start time.Time) {
+	if t == nil {
+		return
+	}
+	elapsed := float64(time.Since(start).Microseconds()) / 1000
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.Stages = append(t.Stages, StageTiming{Stage: name, DurationMs: elapsed})
+}
+
+// startStage starts a span for a query stage. The returned function ends it
+// and records the stage's timing in the explain trace, if any.
+func startStage(ctx context.Context, name string) (context.Context, func()) {
+	start := time.Now()
+	ctx, span := observability.StartSpan(ctx, "retrieval."+name)
+	return ctx, func() {
+		span.End()
+		traceFrom(ctx).stage(name, start)
+	}
+}
+
+// keywordConfidence records the structured search confidence and its components.
+func (t *Trace) keywordConfidence(confidence float64, factors map[string]float64) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.KeywordConfidence = confidence
+	t.ConfidenceFactors = factors
+}
+
+// allow counts an entry under key and reports whether it is within the limit.
+// The caller holds t.mu.
+func (t *Trace) allow(key string) bool {
+	if t.counts == nil {
+		t.counts = make(map[string]int)
+	}
+	t.counts[key]++
+	return t.counts[key] <= maxTraceCandidates
+}
+
+// factLabel describes a fact in trace output.
+func factLabel(f SpecFact) string {
+	return f.Category + " > " + f.Name + ": " + f.Value
+}
@@ -0,0 +1,150 @@
+package retrieval
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/lexical"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/observability"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestRouter_Explain(t *testing.T) {
+	logger := observability.NewLogger(observability.LogConfig{Level: "error"})
+	hybridTenant, plainTenant := uuid.New(), uuid.New()
+	router := NewRouter(logger, nil, nil, nil, nil, RouterConfig{
+		StructuredFirst: true,
+		TenantHybrid: map[uuid.UUID]HybridConfig{
+			hybridTenant: {Fusion: FusionRRF},
+		},
+	})
+
+	for _, tenant := range []uuid.UUID{hybridTenant, plainTenant} {
+		for _, spec := range []struct{ name, value string }{
+			{"Fuel Tank Capacity", "45"},
+			{"Airbags", "6"},
+		} {
+			router.LexicalIndex().Upsert(lexical.SpecDocument(storage.SpecViewLatest{
+				ID:           uuid.New(),
+				SpecItemID:   uuid.New(),
+				TenantID:     tenant,
+				CategoryName: "Specifications",
+				SpecName:     spec.name,
+				Value:        spec.value,
+				Confidence:   0.9,
+			}))
+		}
+		router.LexicalIndex().MarkLoaded(tenant)
+	}
+	ctx := context.Background()
+
+	resp, err := router.Query(ctx, RetrievalRequest{TenantID: plainTenant, Question: "What is the fuel tank capacity?"})
+	require.NoError(t, err)
+	assert.Nil(t, resp.Trace, "traces are only collected on request")
+
+	resp, err = router.Query(ctx, RetrievalRequest{TenantID: plainTenant, Question: "What is the fuel tank capacity?", Explain: true})
+	require.NoError(t, err)
+	trace := resp.Trace
+	require.NotNil(t, trace)
+	assert.Equal(t, IntentSpecLookup, trace.Intent)
+	assert.Equal(t, IntentSpecLookup, trace.ClassifiedIntent)
+	assert.Greater(t, trace.IntentConfidence, 0.7)
+	assert.False(t, trace.IntentHinted)
+	assert.Contains(t, trace.Keywords, "fuel")
+	assert.Equal(t, PathKeywordOnly, trace.Path)
+	assert.Equal(t, CacheDisabled, trace.Cache)
+	assert.Greater(t, trace.KeywordConfidence, 0.0)
+	assert.Contains(t, trace.ConfidenceFactors, "mean_top_score")
+
+	require.NotEmpty(t, trace.Candidates)
+	top := trace.Candidates[0]
+	assert.Equal(t, SourceLexical, top.Source)
+	assert.Equal(t, "Specifications > Fuel Tank Capacity: 45", top.Label)
+	assert.Contains(t, top.Components, "bm25")
+	assert.Contains(t, top.Components, "calibrated")
+
+	stages := make(map[string]bool)
+	for _, st := range trace.Stages {
+		stages[st.Stage] = true
+	}
+	for _, name := range []string{"rewrite", "link_entities", "classify_intent", "keyword_search", "retrieve"} {
+		assert.True(t, stages[name], "missing stage %s", name)
+	}
+
+	// Hybrid queries report fused ranks
+	intent := IntentSpecLookup
+	resp, err = router.Query(ctx, RetrievalRequest{TenantID: hybridTenant, Question: "fuel tank capacity", IntentHint: &intent, Explain: true})
+	require.NoError(t, err)
+	require.NotNil(t, resp.Trace)
+	assert.Equal(t, PathHybrid, resp.Trace.Path)
+	assert.True(t, resp.Trace.IntentHinted)
+	var fused *CandidateScore
+	for i := range resp.Trace.Candidates {
+		if resp.Trace.Candidates[i].Source == SourceFusion {
+			fused = &resp.Trace.Candidates[i]
+		}
+	}
+	require.NotNil(t, fused)
+	assert.Equal(t, 1.0, fused.Components["lexical_rank"])
+	assert.Equal(t, 0.0, fused.Components["vector_rank"])
+
+	// Predicate queries record which predicates could be evaluated
+	resp, err = router.Query(ctx, RetrievalRequest{TenantID: plainTenant, Question: "Which trims have more than 4 airbags?", Explain: true})
+	require.NoError(t, err)
+	require.NotNil(t, resp.Trace)
+	assert.Equal(t, PathPredicate, resp.Trace.Path)
+	require.NotEmpty(t, resp.Trace.Filters)
+	assert.Equal(t, "predicate", resp.Trace.Filters[0].Stage)
+	assert.True(t, resp.Trace.Filters[0].Kept)
+}
+
+func TestRouter_KeywordConfidenceFactors(t *testing.T) {
+	logger := observability.NewLogger(observability.LogConfig{Level: "error"})
+	router := NewRouter(logger, nil, nil, nil, nil, RouterConfig{})
+
+	facts := []SpecFact{{Category: "Engine", Name: "Torque", Value: "250 Nm"}}
+	confidence, factors := router.keywordConfidenceFactors(facts, "torque")
+	assert.Equal(t, router.calculateKeywordConfidence(facts, "torque"), confidence)
+	assert.InDelta(t, 0.3, factors["exact_matches"], 1e-9)
+	assert.InDelta(t, 0.1, factors["partial_matches"], 1e-9)
+	assert.InDelta(t, 0.2, factors["complexity_bonus"], 1e-9)
+	assert.InDelta(t, 0.05, factors["count_bonus"], 1e-9)
+}
+
+func TestRouter_Spans(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	defer otel.SetTracerProvider(previous)
+
+	router, tenant := newUSPRouter(t, nil, &countingEmbedder{}, RouterConfig{})
+	intent := IntentUSPLookup
+	_, err := router.Query(context.Background(), RetrievalRequest{TenantID: tenant, Question: "Why is it safe?", IntentHint: &intent})
+	require.NoError(t, err)
+
+	spans := make(map[string]tracetest.SpanStub)
+	for _, span := range exporter.GetSpans() {
+		spans[span.Name] = span
+	}
+	query, ok := spans["retrieval.Query"]
+	require.True(t, ok)
+	assert.Contains(t, query.Attributes, attribute.String("intent", string(IntentUSPLookup)))
+	assert.Contains(t, query.Attributes, attribute.String("path", string(PathVector)))
+
+	for _, stage := range []string{"rewrite", "link_entities", "expand_query", "classify_intent", "retrieve"} {
+		span, ok := spans["retrieval."+stage]
+		require.True(t, ok, stage)
+		assert.Equal(t, query.SpanContext.SpanID(), span.Parent.SpanID(), "%s is a child of the query", stage)
+	}
+	search, ok := spans["vector.Search"]
+	require.True(t, ok)
+	assert.Equal(t, spans["retrieval.retrieve"].SpanContext.SpanID(), search.Parent.SpanID())
+	assert.Contains(t, search.Attributes, attribute.Int("results", 1))
+}
@@ -0,0 +1,276 @@
+// Package retrieval provides hybrid fusion of lexical and vector retrieval.
+package retrieval
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// FusionMode selects how lexical and vector results are combined.
+type FusionMode string
+
+const (
+	// FusionNone disables hybrid retrieval; intents route to a single path.
+	FusionNone FusionMode = ""
+	// FusionRRF combines result lists by reciprocal rank.
+	FusionRRF FusionMode = "rrf"
+	// FusionWeighted combines normalized scores with per-source weights.
+	FusionWeighted FusionMode = "weighted"
+)
+
+// HybridConfig configures concurrent lexical and vector retrieval.
+type HybridConfig struct {
+	// Fusion selects the fusion method; FusionNone disables hybrid retrieval.
+	Fusion FusionMode
+	// RRFK is the reciprocal rank fusion constant (default 60).
+	RRFK int
+	// LexicalWeight and VectorWeight scale scores in weighted fusion (default 0.5 each).
+	LexicalWeight float64
+	VectorWeight  float64
+	// MaxEvidence bounds the fused evidence list (default 20).
+	MaxEvidence int
+}
+
+// EvidenceKind identifies the type of item in a fused evidence list.
+type EvidenceKind string
+
+const (
+	EvidenceSpecFact      EvidenceKind = "spec_fact"
+	EvidenceSemanticChunk EvidenceKind = "semantic_chunk"
+)
+
+// Evidence is one item of a fused, ranked evidence list.
+type Evidence struct {
+	Kind  EvidenceKind
+	Fact  *SpecFact
+	Chunk *SemanticChunk
+	// Score is the fused score; it orders evidence within one response.
+	Score float64
+	// LexicalRank and VectorRank are 1-based ranks in each source list, 0 when absent.
+	LexicalRank int
+	VectorRank  int
+}
+
+// withDefaults fills unset hybrid parameters.
+func (c HybridConfig) withDefaults() HybridConfig {
+	if c.RRFK <= 0 {
+		c.RRFK = 60
+	}
+	if c.LexicalWeight <= 0 && c.VectorWeight <= 0 {
+		c.LexicalWeight = 0.5
+		c.VectorWeight = 0.5
+	}
+	if c.MaxEvidence <= 0 {
+		c.MaxEvidence = 20
+	}
+	return c
+}
+
+// appliesTo reports whether hybrid retrieval handles the intent. USP and
+// comparison lookups keep their dedicated routes.
+func (c HybridConfig) appliesTo(intent Intent) bool {
+	if c.Fusion == FusionNone {
+		return false
+	}
+	return intent == IntentSpecLookup || intent == IntentUnknown
+}
+
+// hybridConfig returns the hybrid configuration for a tenant, honouring overrides.
+func (r *Router) hybridConfig(tenantID uuid.UUID) HybridConfig {
+	if cfg, ok := r.config.TenantHybrid[tenantID]; ok {
+		return cfg.withDefaults()
+	}
+	return r.config.Hybrid.withDefaults()
+}
+
+// queryHybrid runs lexical and vector retrieval concurrently and fuses the results
+// into response.Evidence. It reports whether vector search contributed results.
+func (r *Router) queryHybrid(ctx context.Context, req RetrievalRequest, response *RetrievalResponse, cfg HybridConfig) bool {
+	var (
+		wg          sync.WaitGroup
+		facts       []SpecFact
+		lexChunks   []SemanticChunk
+		chunks      []SemanticChunk
+		lexErr      error
+		semanticErr error
+	)
+
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		facts, _, lexErr = r.queryStructuredSpecs(ctx, req)
+		lexChunks = r.searchLexicalChunks(ctx, req)
+	}()
+	go func() {
+		defer wg.Done()
+		chunks, semanticErr = r.querySemanticChunks(ctx, req)
+	}()
+	wg.Wait()
+
+	if lexErr != nil {
+		r.logger.Warn().Err(lexErr).Msg("Structured query failed")
+	}
+	if semanticErr != nil {
+		r.logger.Warn().Err(semanticErr).Msg("Semantic query failed")
+	}
+
+	evidence := fuseEvidence(lexicalEvidence(facts, lexChunks), vectorEvidence(chunks), cfg)
+	if trace := traceFrom(ctx); trace != nil {
+		for _, e := range evidence {
+			c := CandidateScore{
+				Kind:   e.Kind,
+				Source: SourceFusion,
+				Score:  e.Score,
+				Components: map[string]float64{
+					"fused":        e.Score,
+					"lexical_rank": float64(e.LexicalRank),
+					"vector_rank":  float64(e.VectorRank),
+				},
+			}
+			if e.Fact != nil {
+				c.ID, c.Label = e.Fact.SpecItemID, factLabel(*e.Fact)
+			} else if e.Chunk != nil {
+				c.ID, c.Label = e.Chunk.ChunkID, string(e.Chunk.ChunkType)
+			}
+			trace.candidate(c)
+		}
+	}
+
+	response.Evidence = evidence
+	response.StructuredFacts = nil
+	response.SemanticChunks = nil
+	for _, e := range evidence {
+		switch e.Kind {
+		case EvidenceSpecFact:
+			response.StructuredFacts = append(response.StructuredFacts, *e.Fact)
+		case EvidenceSemanticChunk:
+			response.SemanticChunks = append(response.SemanticChunks, *e.Chunk)
+		}
+	}
+
+	r.logger.Debug().
+		Str("fusion", string(cfg.Fusion)).
+		Int("lexical_facts", len(facts)).
+		Int("lexical_chunks", len(lexChunks)).
+		Int("vector_chunks", len(chunks)).
+		Int("evidence", len(evidence)).
+		Msg("Hybrid retrieval fused")
+
+	return len(chunks) > 0
+}
+
+// rankedEvidence is an evidence candidate with its source score.
+type rankedEvidence struct {
+	key      string
+	evidence Evidence
+	score    float64
+}
+
+// lexicalEvidence merges lexical facts and chunks into one list ordered by calibrated score.
+func lexicalEvidence(facts []SpecFact, chunks []SemanticChunk) []rankedEvidence {
+	list := make([]rankedEvidence, 0, len(facts)+len(chunks))
+	for i := range facts {
+		f := facts[i]
+		list = append(list, rankedEvidence{
+			key:      factKey(f),
+			evidence: Evidence{Kind: EvidenceSpecFact, Fact: &f},
+			score:    f.Score,
+		})
+	}
+	for i := range chunks {
+		c := chunks[i]
+		list = append(list, rankedEvidence{
+			key:      "chunk|" + c.ChunkID.String(),
+			evidence: Evidence{Kind: EvidenceSemanticChunk, Chunk: &c},
+			score:    float64(c.Score),
+		})
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].score > list[j].score
+	})
+	return list
+}
+
+// vectorEvidence converts vector search results, already ordered by similarity.
+func vectorEvidence(chunks []SemanticChunk) []rankedEvidence {
+	list := make([]rankedEvidence, 0, len(chunks))
+	for i := range chunks {
+		c := chunks[i]
+		list = append(list, rankedEvidence{
+			key:      "chunk|" + c.ChunkID.String(),
+			evidence: Evidence{Kind: EvidenceSemanticChunk, Chunk: &c},
+			score:    math.Max(0, math.Min(1, float64(c.Score))),
+		})
+	}
+	return list
+}
+
+// fuseEvidence combines lexical and vector lists. Chunks present in both lists are
+// merged, keeping the vector result but filling in text found lexically.
+func fuseEvidence(lexicalList, vectorList []rankedEvidence, cfg HybridConfig) []Evidence {
+	fused := make(map[string]*Evidence)
+	var order []string
+
+	add := func(item rankedEvidence, rank int, lexical bool) {
+		var contribution float64
+		switch cfg.Fusion {
+		case FusionWeighted:
+			weight := cfg.VectorWeight
+			if lexical {
+				weight = cfg.LexicalWeight
+			}
+			contribution = weight * item.score
+		default:
+			contribution = 1.0 / float64(cfg.RRFK+rank)
+		}
+
+		e, ok := fused[item.key]
+		if !ok {
+			copied := item.evidence
+			e = &copied
+			fused[item.key] = e
+			order = append(order, item.key)
+		} else if !lexical && e.Chunk != nil {
+			text := e.Chunk.Text
+			e.Chunk = item.evidence.Chunk
+			if e.Chunk.Text == "" {
+				e.Chunk.Text = text
+			}
+		}
+		if lexical {
+			e.LexicalRank = rank
+		} else {
+			e.VectorRank = rank
+		}
+		e.Score += contribution
+	}
+
+	for i, item := range lexicalList {
+		add(item, i+1, true)
+	}
+	for i, item := range vectorList {
+		add(item, i+1, false)
+	}
+
+	evidence := make([]Evidence, 0, len(order))
+	for _, key := range order {
+		evidence = append(evidence, *fused[key])
+	}
+	sort.SliceStable(evidence, func(i, j int) bool {
+		return evidence[i].Score > evidence[j].Score
+	})
+	if len(evidence) > cfg.MaxEvidence {
+		evidence = evidence[:cfg.MaxEvidence]
+	}
+	return evidence
+}
+
+// factKey identifies a spec fact independent of how it was retrieved.
+func factKey(f SpecFact) string {
+	return fmt.Sprintf("fact|%s|%s|%s", f.Category, f.Name, f.Value)
+}
@@ -0,0 +1,94 @@
+package retrieval
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/lexical"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/observability"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFuseEvidence_RRF(t *testing.T) {
+	shared := uuid.New()
+	facts := []SpecFact{
+		{Category: "Engine", Name: "Power", Value: "150 hp", Score: 0.9},
+		{Category: "Engine", Name: "Torque", Value: "250 Nm", Score: 0.6},
+	}
+	lexChunks := []SemanticChunk{{ChunkID: shared, Text: "The engine delivers 150 hp", Score: 0.7}}
+	vecChunks := []SemanticChunk{
+		{ChunkID: shared, Score: 0.8, Distance: 0.2},
+		{ChunkID: uuid.New(), Score: 0.75},
+	}
+
+	cfg := HybridConfig{Fusion: FusionRRF}.withDefaults()
+	evidence := fuseEvidence(lexicalEvidence(facts, lexChunks), vectorEvidence(vecChunks), cfg)
+	require.Len(t, evidence, 4)
+
+	// The chunk found by both retrievers ranks first and keeps the lexical text
+	top := evidence[0]
+	assert.Equal(t, EvidenceSemanticChunk, top.Kind)
+	assert.Equal(t, shared, top.Chunk.ChunkID)
+	assert.Equal(t, 2, top.LexicalRank)
+	assert.Equal(t, 1, top.VectorRank)
+	assert.Equal(t, "The engine delivers 150 hp", top.Chunk.Text)
+	assert.InDelta(t, float32(0.2), top.Chunk.Distance, 1e-6)
+
+	assert.Equal(t, EvidenceSpecFact, evidence[1].Kind)
+	assert.Equal(t, "Power", evidence[1].Fact.Name)
+}
+
+func TestFuseEvidence_Weighted(t *testing.T) {
+	facts := []SpecFact{{Category: "Engine", Name: "Power", Value: "150 hp", Score: 0.5}}
+	vecChunks := []SemanticChunk{{ChunkID: uuid.New(), Score: 0.9}}
+
+	cfg := HybridConfig{Fusion: FusionWeighted, LexicalWeight: 0.8, VectorWeight: 0.2, MaxEvidence: 1}.withDefaults()
+	evidence := fuseEvidence(lexicalEvidence(facts, nil), vectorEvidence(vecChunks), cfg)
+	require.Len(t, evidence, 1)
+	assert.Equal(t, EvidenceSpecFact, evidence[0].Kind)
+	assert.InDelta(t, 0.4, evidence[0].Score, 1e-9)
+}
+
+func TestRouter_HybridPerTenant(t *testing.T) {
+	logger := observability.NewLogger(observability.LogConfig{Level: "error"})
+	hybridTenant, plainTenant := uuid.New(), uuid.New()
+	router := NewRouter(logger, nil, nil, nil, nil, RouterConfig{
+		StructuredFirst: true,
+		TenantHybrid: map[uuid.UUID]HybridConfig{
+			hybridTenant: {Fusion: FusionRRF},
+		},
+	})
+
+	for _, tenant := range []uuid.UUID{hybridTenant, plainTenant} {
+		router.LexicalIndex().Upsert(lexical.SpecDocument(storage.SpecViewLatest{
+			ID:           uuid.New(),
+			TenantID:     tenant,
+			CategoryName: "Engine",
+			SpecName:     "Fuel Tank Capacity",
+			Value:        "45",
+		}))
+	}
+
+	intent := IntentSpecLookup
+	resp, err := router.Query(context.Background(), RetrievalRequest{
+		TenantID:   hybridTenant,
+		Question:   "fuel tank capacity",
+		IntentHint: &intent,
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Evidence, 1)
+	assert.Equal(t, EvidenceSpecFact, resp.Evidence[0].Kind)
+	assert.Len(t, resp.StructuredFacts, 1)
+
+	resp, err = router.Query(context.Background(), RetrievalRequest{
+		TenantID:   plainTenant,
+		Question:   "fuel tank capacity",
+		IntentHint: &intent,
+	})
+	require.NoError(t, err)
+	assert.Empty(t, resp.Evidence)
+	assert.Len(t, resp.StructuredFacts, 1)
+}
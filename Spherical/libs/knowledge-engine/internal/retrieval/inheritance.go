@@ -0,0 +1,78 @@
+// Package retrieval provides campaign inheritance scoping for retrieval.
+package retrieval
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/storage"
+)
+
+// campaignScope restricts results to the effective values of a campaign variant.
+type campaignScope struct {
+	campaignID *uuid.UUID
+	chain      []uuid.UUID
+	owners     map[uuid.UUID]storage.EffectiveOwner
+}
+
+// resolveCampaignScope loads the inheritance chain for the requested campaign.
+// When the chain cannot be resolved the scope falls back to an exact campaign match.
+func (r *Router) resolveCampaignScope(ctx context.Context, req RetrievalRequest) *campaignScope {
+	scope := &campaignScope{campaignID: req.CampaignVariantID}
+	if req.CampaignVariantID == nil {
+		return scope
+	}
+	scope.chain = []uuid.UUID{*req.CampaignVariantID}
+
+	// A snapshot already holds the chain and the spec values effective then
+	if req.snapshot != nil {
+		scope.chain = req.snapshot.campaignIDs
+		if len(scope.chain) > 1 {
+			scope.owners = storage.ResolveEffectiveOwners(scope.chain, req.snapshot.specs)
+		}
+		return scope
+	}
+
+	if r.specViewRepo == nil {
+		return scope
+	}
+
+	chain, err := r.specViewRepo.CampaignChain(ctx, req.TenantID, *req.CampaignVariantID)
+	if err != nil {
+		r.logger.Debug().Err(err).Msg("Campaign chain unavailable, using exact campaign match")
+		return scope
+	}
+	scope.chain = chain
+
+	if len(chain) > 1 {
+		owners, err := r.specViewRepo.EffectiveOwners(ctx, req.TenantID, chain)
+		if err != nil {
+			r.logger.Warn().Err(err).Msg("Failed to resolve inherited spec values")
+			scope.chain = chain[:1]
+			return scope
+		}
+		scope.owners = owners
+	}
+
+	return scope
+}
+
+// accept reports whether a spec view row is effective for the scope, and its origin.
+func (s *campaignScope) accept(sv storage.SpecViewLatest) (storage.ValueOrigin, bool) {
+	if s.campaignID == nil {
+		return "", true
+	}
+
+	if s.owners == nil {
+		if sv.CampaignVariantID != *s.campaignID {
+			return "", false
+		}
+		return storage.ValueOriginLocal, true
+	}
+
+	owner, ok := s.owners[sv.SpecItemID]
+	if !ok || owner.CampaignVariantID != sv.CampaignVariantID {
+		return "", false
+	}
+	return owner.Origin, true
+}
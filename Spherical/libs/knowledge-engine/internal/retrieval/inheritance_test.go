@@ -0,0 +1,93 @@
+package retrieval
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/google/uuid"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/ingest"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/observability"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// migratedDB creates a SQLite database with every SQLite migration applied.
+func migratedDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "knowledge.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	migrations, err := filepath.Glob("../../db/migrations/*_sqlite.sql")
+	require.NoError(t, err)
+	sort.Strings(migrations)
+	for _, path := range migrations {
+		script, err := os.ReadFile(path)
+		require.NoError(t, err)
+		_, err = db.Exec(string(script))
+		require.NoError(t, err, path)
+	}
+	return db
+}
+
+func TestRouter_FeatureBlockOverride(t *testing.T) {
+	ctx := context.Background()
+	logger := observability.NewLogger(observability.LogConfig{Level: "error"})
+	db := migratedDB(t)
+	repos := storage.NewRepositories(db)
+
+	tenant, product := uuid.New(), uuid.New()
+	_, err := db.Exec("INSERT INTO tenants (id, name) VALUES ($1, $2)", tenant, "Toyota")
+	require.NoError(t, err)
+	_, err = db.Exec("INSERT INTO products (id, tenant_id, name) VALUES ($1, $2, $3)", product, tenant, "Camry")
+	require.NoError(t, err)
+
+	// The copied clone stores the source's block, and its chunk, as its own
+	source := &storage.CampaignVariant{ProductID: product, TenantID: tenant, Locale: "en-US", Status: storage.CampaignStatusDraft, Version: 1}
+	require.NoError(t, repos.Campaigns.Create(ctx, source))
+	require.NoError(t, repos.FeatureBlocks.Create(ctx, &storage.FeatureBlock{
+		TenantID: tenant, ProductID: product, CampaignVariantID: source.ID,
+		BlockType: storage.BlockTypeFeature, Body: "Panoramic sunroof with one-touch tilt", Shareability: storage.ShareabilityPrivate,
+	}))
+	cloner := ingest.NewCloner(logger, db)
+	copied, err := cloner.Clone(ctx, ingest.CloneRequest{TenantID: tenant, SourceCampaignID: source.ID, Mode: ingest.CloneModeCopy})
+	require.NoError(t, err)
+	assert.Equal(t, 1, copied.FeaturesCopied)
+
+	derived, err := cloner.Clone(ctx, ingest.CloneRequest{TenantID: tenant, SourceCampaignID: copied.CampaignID, Mode: ingest.CloneModeInherit})
+	require.NoError(t, err)
+	blocks, err := repos.FeatureBlocks.GetByCampaign(ctx, tenant, copied.CampaignID, nil)
+	require.NoError(t, err)
+	require.Len(t, blocks, 1)
+	_, err = cloner.OverrideFeatureBlock(ctx, ingest.OverrideFeatureRequest{
+		TenantID: tenant, CampaignID: derived.CampaignID, BaseBlockID: blocks[0].ID, Body: "Fixed glass sunroof with power shade",
+	})
+	require.NoError(t, err)
+	_, err = db.Exec("UPDATE campaign_variants SET status = $1 WHERE id IN ($2, $3)", storage.CampaignStatusPublished, copied.CampaignID, derived.CampaignID)
+	require.NoError(t, err)
+
+	router := NewRouter(logger, nil, nil, nil, storage.NewSpecViewRepository(db), RouterConfig{Hybrid: HybridConfig{Fusion: FusionRRF}})
+	router.SetChunkSource(repos.KnowledgeChunks)
+
+	texts := func(campaignID uuid.UUID) []string {
+		resp, err := router.Query(ctx, RetrievalRequest{
+			TenantID: tenant, CampaignVariantID: &campaignID, Question: "Tell me about the sunroof", MaxChunks: 5,
+			Filters: RetrievalFilters{ChunkTypes: []storage.ChunkType{storage.ChunkTypeFeatureBlock}},
+		})
+		require.NoError(t, err)
+		var out []string
+		for _, chunk := range resp.SemanticChunks {
+			out = append(out, chunk.Text)
+		}
+		return out
+	}
+
+	assert.Equal(t, []string{"Fixed glass sunroof with power shade"}, texts(derived.CampaignID))
+	assert.Equal(t, []string{"Panoramic sunroof with one-touch tilt"}, texts(copied.CampaignID))
+}
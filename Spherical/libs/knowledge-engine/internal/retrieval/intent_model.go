@@ -0,0 +1,516 @@
+// Package retrieval provides a trainable intent classifier over word n-grams.
+package retrieval
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// intentModelVersion is the serialization version written by SaveIntentModel.
+const intentModelVersion = 1
+
+// DefaultIntentExamples names the training file shared by every tenant in an
+// examples directory; tenant files are named <tenant-id>.jsonl.
+const DefaultIntentExamples = "default"
+
+// IntentExample is a labelled question used to train or evaluate a classifier.
+type IntentExample struct {
+	Text   string `json:"text"`
+	Intent Intent `json:"intent"`
+}
+
+// IntentTrainConfig controls intent model training.
+type IntentTrainConfig struct {
+	// Epochs is the number of passes over the examples (default 200).
+	Epochs int
+	// LearningRate is the gradient step size (default 0.5).
+	LearningRate float64
+	// L2 is the weight decay applied each step (default 0.001).
+	L2 float64
+	// MinConfidence is the probability below which the model defers to the
+	// pattern classifier (default 0.7, the router's intent confidence threshold).
+	MinConfidence float64
+	// Seed makes example shuffling deterministic.
+	Seed int64
+}
+
+func (c IntentTrainConfig) withDefaults() IntentTrainConfig {
+	if c.Epochs <= 0 {
+		c.Epochs = 200
+	}
+	if c.LearningRate <= 0 {
+		c.LearningRate = 0.5
+	}
+	if c.L2 < 0 {
+		c.L2 = 0
+	} else if c.L2 == 0 {
+		c.L2 = 0.001
+	}
+	if c.MinConfidence <= 0 || c.MinConfidence >= 1 {
+		c.MinConfidence = 0.7
+	}
+	return c
+}
+
+// IntentModel is a multinomial logistic regression over word unigrams, bigrams
+// and the opening words of a question.
+type IntentModel struct {
+	Version       int            `json:"version"`
+	Intents       []Intent       `json:"intents"`
+	Features      map[string]int `json:"features"`
+	Weights       [][]float64    `json:"weights"` // [intent][feature]
+	Bias          []float64      `json:"bias"`
+	MinConfidence float64        `json:"min_confidence"`
+	Examples      int            `json:"examples"`
+	TrainedAt     time.Time      `json:"trained_at"`
+	// Holdout and Seed record the SplitIntentExamples call whose training share
+	// the model was fitted on; Holdout is zero when it saw every example.
+	Holdout float64 `json:"holdout,omitempty"`
+	Seed    int64   `json:"seed,omitempty"`
+}
+
+// HeldOutExamples returns the examples the model was not fitted on, given the
+// examples it was trained from. It returns nil when the model has no holdout.
+func (m *IntentModel) HeldOutExamples(examples []IntentExample) []IntentExample {
+	if m.Holdout <= 0 {
+		return nil
+	}
+	_, test := SplitIntentExamples(examples, m.Holdout, m.Seed)
+	return test
+}
+
+// TrainIntentModel fits a model to labelled examples. At least two intents are required.
+func TrainIntentModel(examples []IntentExample, cfg IntentTrainConfig) (*IntentModel, error) {
+	cfg = cfg.withDefaults()
+
+	labels := make(map[Intent]int)
+	features := make(map[string]int)
+	type encoded struct {
+		features []int
+		label    int
+	}
+	data := make([]encoded, 0, len(examples))
+	for _, ex := range examples {
+		feats := intentFeatures(ex.Text)
+		if len(feats) == 0 || ex.Intent == "" {
+			continue
+		}
+		if _, ok := labels[ex.Intent]; !ok {
+			labels[ex.Intent] = len(labels)
+		}
+		idx := make([]int, 0, len(feats))
+		for _, f := range feats {
+			if _, ok := features[f]; !ok {
+				features[f] = len(features)
+			}
+			idx = append(idx, features[f])
+		}
+		data = append(data, encoded{features: idx, label: labels[ex.Intent]})
+	}
+	if len(labels) < 2 {
+		return nil, fmt.Errorf("training needs examples of at least two intents, got %d", len(labels))
+	}
+
+	model := &IntentModel{
+		Version:       intentModelVersion,
+		Intents:       make([]Intent, len(labels)),
+		Features:      features,
+		Weights:       make([][]float64, len(labels)),
+		Bias:          make([]float64, len(labels)),
+		MinConfidence: cfg.MinConfidence,
+		Examples:      len(data),
+		TrainedAt:     time.Now().UTC(),
+	}
+	for intent, i := range labels {
+		model.Intents[i] = intent
+		model.Weights[i] = make([]float64, len(features))
+	}
+
+	// Stochastic gradient descent on the cross-entropy loss
+	rng := rand.New(rand.NewSource(cfg.Seed))
+	order := make([]int, len(data))
+	for i := range order {
+		order[i] = i
+	}
+	for epoch := 0; epoch < cfg.Epochs; epoch++ {
+		rng.Shuffle(len(order), func(i, j int) { order[i], order[j] = order[j], order[i] })
+		rate := cfg.LearningRate / (1 + float64(epoch)/float64(cfg.Epochs))
+		for _, n := range order {
+			ex := data[n]
+			probs := model.probabilities(ex.features)
+			for k := range model.Intents {
+				grad := probs[k]
+				if k == ex.label {
+					grad--
+				}
+				for _, f := range ex.features {
+					model.Weights[k][f] -= rate * (grad + cfg.L2*model.Weights[k][f])
+				}
+				model.Bias[k] -= rate * grad
+			}
+		}
+	}
+	return model, nil
+}
+
+// Predict returns the most probable intent and its probability. It returns
+// IntentUnknown with zero confidence when the question shares no features
+// with the training data.
+func (m *IntentModel) Predict(question string) (Intent, float64) {
+	var idx []int
+	for _, f := range intentFeatures(question) {
+		if i, ok := m.Features[f]; ok {
+			idx = append(idx, i)
+		}
+	}
+	if len(idx) == 0 {
+		return IntentUnknown, 0
+	}
+
+	probs := m.probabilities(idx)
+	best := 0
+	for k := range probs {
+		if probs[k] > probs[best] {
+			best = k
+		}
+	}
+	return m.Intents[best], probs[best]
+}
+
+// probabilities returns the softmax distribution over intents for feature indexes.
+func (m *IntentModel) probabilities(features []int) []float64 {
+	scores := make([]float64, len(m.Intents))
+	maxScore := math.Inf(-1)
+	for k := range m.Intents {
+		s := m.Bias[k]
+		for _, f := range features {
+			s += m.Weights[k][f]
+		}
+		scores[k] = s
+		maxScore = math.Max(maxScore, s)
+	}
+	total := 0.0
+	for k := range scores {
+		scores[k] = math.Exp(scores[k] - maxScore)
+		total += scores[k]
+	}
+	for k := range scores {
+		scores[k] /= total
+	}
+	return scores
+}
+
+// intentFeatures extracts unigram, bigram and question-opening features.
+func intentFeatures(text string) []string {
+	tokens := linkTokens(text)
+	if len(tokens) == 0 {
+		return nil
+	}
+	words := make([]string, len(tokens))
+	for i, tok := range tokens {
+		words[i] = tok.text
+	}
+
+	seen := make(map[string]bool)
+	var feats []string
+	add := func(f string) {
+		if !seen[f] {
+			seen[f] = true
+			feats = append(feats, f)
+		}
+	}
+	add("^" + words[0])
+	if len(words) > 1 {
+		add("^" + words[0] + " " + words[1])
+	}
+	for i, w := range words {
+		add(w)
+		if i > 0 {
+			add(words[i-1] + " " + w)
+		}
+	}
+	return feats
+}
+
+// SetModel sets the model used for tenants without their own; nil removes it.
+func (c *IntentClassifier) SetModel(model *IntentModel) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.model = model
+}
+
+// SetTenantModel sets a tenant's model; nil removes it.
+func (c *IntentClassifier) SetTenantModel(tenantID uuid.UUID, model *IntentModel) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if model == nil {
+		delete(c.tenantModels, tenantID)
+		return
+	}
+	if c.tenantModels == nil {
+		c.tenantModels = make(map[uuid.UUID]*IntentModel)
+	}
+	c.tenantModels[tenantID] = model
+}
+
+// ClassifyTenant classifies a question with the tenant's model, else the shared
+// model. The patterns decide when no model is loaded or the model's confidence
+// is below its MinConfidence.
+func (c *IntentClassifier) ClassifyTenant(tenantID uuid.UUID, question string) (Intent, float64) {
+	c.mu.RLock()
+	model, ok := c.tenantModels[tenantID]
+	if !ok {
+		model = c.model
+	}
+	c.mu.RUnlock()
+
+	if model != nil {
+		if intent, confidence := model.Predict(question); confidence >= model.MinConfidence {
+			return intent, confidence
+		}
+	}
+	return c.ClassifyPatterns(question)
+}
+
+// IntentClassifier returns the router's intent classifier so trained models can be loaded.
+func (r *Router) IntentClassifier() *IntentClassifier {
+	return r.intentClassifier
+}
+
+// SaveIntentModel writes a model as JSON.
+func SaveIntentModel(path string, model *IntentModel) error {
+	data, err := json.MarshalIndent(model, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode intent model: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("write intent model: %w", err)
+	}
+	return nil
+}
+
+// LoadIntentModel reads a model written by SaveIntentModel.
+func LoadIntentModel(path string) (*IntentModel, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read intent model: %w", err)
+	}
+	var model IntentModel
+	if err := json.Unmarshal(data, &model); err != nil {
+		return nil, fmt.Errorf("decode intent model: %w", err)
+	}
+	if model.Version != intentModelVersion {
+		return nil, fmt.Errorf("unsupported intent model version %d", model.Version)
+	}
+	if len(model.Intents) < 2 || len(model.Weights) != len(model.Intents) || len(model.Bias) != len(model.Intents) {
+		return nil, fmt.Errorf("intent model %s is malformed", path)
+	}
+	for i, row := range model.Weights {
+		if len(row) != len(model.Features) {
+			return nil, fmt.Errorf("intent model %s is malformed: %s has %d weights for %d features",
+				path, model.Intents[i], len(row), len(model.Features))
+		}
+	}
+	for feature, idx := range model.Features {
+		if idx < 0 || idx >= len(model.Features) {
+			return nil, fmt.Errorf("intent model %s is malformed: feature %q has index %d", path, feature, idx)
+		}
+	}
+	if model.MinConfidence < 0 || model.MinConfidence >= 1 || model.Holdout < 0 || model.Holdout >= 1 {
+		return nil, fmt.Errorf("intent model %s is malformed: thresholds out of range", path)
+	}
+	return &model, nil
+}
+
+// LoadIntentModels reads every model in a directory. default.json becomes the
+// shared model; <tenant-id>.json files become tenant models.
+func LoadIntentModels(dir string) (*IntentModel, map[uuid.UUID]*IntentModel, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("list intent models: %w", err)
+	}
+
+	var shared *IntentModel
+	tenants := make(map[uuid.UUID]*IntentModel)
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), ".json")
+		var tenantID uuid.UUID
+		if name != DefaultIntentExamples {
+			if tenantID, err = uuid.Parse(name); err != nil {
+				continue
+			}
+		}
+		model, err := LoadIntentModel(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		if name == DefaultIntentExamples {
+			shared = model
+		} else {
+			tenants[tenantID] = model
+		}
+	}
+	return shared, tenants, nil
+}
+
+// LoadIntentExamples reads labelled examples from a JSON lines file with one
+// {"text": ..., "intent": ...} object per line. Blank lines and lines starting
+// with # are skipped.
+func LoadIntentExamples(path string) ([]IntentExample, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open intent examples: %w", err)
+	}
+	defer f.Close()
+
+	var examples []IntentExample
+	scanner := bufio.NewScanner(f)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		var ex IntentExample
+		if err := json.Unmarshal([]byte(text), &ex); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		if ex.Text == "" || ex.Intent == "" {
+			return nil, fmt.Errorf("%s:%d: text and intent are required", path, line)
+		}
+		examples = append(examples, ex)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read intent examples: %w", err)
+	}
+	return examples, nil
+}
+
+// LoadTenantIntentExamples reads the shared examples in dir/default.jsonl and,
+// when tenantID is set, the tenant's own dir/<tenant-id>.jsonl. Either file may
+// be missing, but not both.
+func LoadTenantIntentExamples(dir string, tenantID uuid.UUID) ([]IntentExample, error) {
+	names := []string{DefaultIntentExamples}
+	if tenantID != uuid.Nil {
+		names = append(names, tenantID.String())
+	}
+
+	var examples []IntentExample
+	found := false
+	for _, name := range names {
+		path := filepath.Join(dir, name+".jsonl")
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			continue
+		}
+		loaded, err := LoadIntentExamples(path)
+		if err != nil {
+			return nil, err
+		}
+		examples = append(examples, loaded...)
+		found = true
+	}
+	if !found {
+		return nil, fmt.Errorf("no intent examples in %s", dir)
+	}
+	return examples, nil
+}
+
+// SplitIntentExamples holds out a share of each intent's examples for evaluation.
+// The split is deterministic for a seed.
+func SplitIntentExamples(examples []IntentExample, holdout float64, seed int64) ([]IntentExample, []IntentExample) {
+	byIntent := make(map[Intent][]IntentExample)
+	var intents []Intent
+	for _, ex := range examples {
+		if _, ok := byIntent[ex.Intent]; !ok {
+			intents = append(intents, ex.Intent)
+		}
+		byIntent[ex.Intent] = append(byIntent[ex.Intent], ex)
+	}
+
+	rng := rand.New(rand.NewSource(seed))
+	var train, test []IntentExample
+	for _, intent := range intents {
+		group := byIntent[intent]
+		rng.Shuffle(len(group), func(i, j int) { group[i], group[j] = group[j], group[i] })
+		n := int(math.Round(float64(len(group)) * holdout))
+		if n >= len(group) {
+			n = len(group) - 1
+		}
+		test = append(test, group[:n]...)
+		train = append(train, group[n:]...)
+	}
+	return train, test
+}
+
+// IntentEvaluation summarizes classifier accuracy over labelled examples.
+type IntentEvaluation struct {
+	Total    int
+	Correct  int
+	Accuracy float64
+	// Labels lists the expected and predicted intents in sorted order.
+	Labels []Intent
+	// Confusion counts predictions as Confusion[expected][predicted].
+	Confusion map[Intent]map[Intent]int
+}
+
+// EvaluateIntents classifies every example and tallies a confusion matrix.
+func EvaluateIntents(classify func(question string) (Intent, float64), examples []IntentExample) IntentEvaluation {
+	eval := IntentEvaluation{Confusion: make(map[Intent]map[Intent]int)}
+	labels := make(map[Intent]bool)
+	for _, ex := range examples {
+		predicted, _ := classify(ex.Text)
+		if eval.Confusion[ex.Intent] == nil {
+			eval.Confusion[ex.Intent] = make(map[Intent]int)
+		}
+		eval.Confusion[ex.Intent][predicted]++
+		labels[ex.Intent] = true
+		labels[predicted] = true
+		eval.Total++
+		if predicted == ex.Intent {
+			eval.Correct++
+		}
+	}
+	if eval.Total > 0 {
+		eval.Accuracy = float64(eval.Correct) / float64(eval.Total)
+	}
+	for intent := range labels {
+		eval.Labels = append(eval.Labels, intent)
+	}
+	sort.Slice(eval.Labels, func(i, j int) bool { return eval.Labels[i] < eval.Labels[j] })
+	return eval
+}
+
+// Precision returns the share of predictions of an intent that were correct.
+func (e IntentEvaluation) Precision(intent Intent) float64 {
+	predicted := 0
+	for _, row := range e.Confusion {
+		predicted += row[intent]
+	}
+	if predicted == 0 {
+		return 0
+	}
+	return float64(e.Confusion[intent][intent]) / float64(predicted)
+}
+
+// Recall returns the share of examples of an intent that were predicted correctly.
+func (e IntentEvaluation) Recall(intent Intent) float64 {
+	expected := 0
+	for _, n := range e.Confusion[intent] {
+		expected += n
+	}
+	if expected == 0 {
+		return 0
+	}
+	return float64(e.Confusion[intent][intent]) / float64(expected)
+}
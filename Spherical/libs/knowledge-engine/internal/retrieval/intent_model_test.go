@@ -0,0 +1,152 @@
+package retrieval
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrainIntentModel(t *testing.T) {
+	examples, err := LoadTenantIntentExamples("../../configs/intents", uuid.Nil)
+	require.NoError(t, err)
+
+	train, test := SplitIntentExamples(examples, 0.2, 7)
+	assert.Equal(t, len(examples), len(train)+len(test))
+	require.NotEmpty(t, test)
+
+	model, err := TrainIntentModel(train, IntentTrainConfig{Seed: 7})
+	require.NoError(t, err)
+	eval := EvaluateIntents(model.Predict, test)
+	assert.GreaterOrEqual(t, eval.Accuracy, 0.7, "held-out accuracy")
+
+	intent, confidence := model.Predict("Why should I pick this over others?")
+	assert.Equal(t, IntentUSPLookup, intent)
+	assert.Greater(t, confidence, 0.5)
+
+	intent, _ = model.Predict("Hello there")
+	assert.Equal(t, IntentUnknown, intent)
+
+	intent, confidence = model.Predict("zzz qqq")
+	assert.Equal(t, IntentUnknown, intent)
+	assert.Zero(t, confidence, "questions without known features defer to the patterns")
+
+	_, err = TrainIntentModel([]IntentExample{{Text: "mileage", Intent: IntentSpecLookup}}, IntentTrainConfig{})
+	assert.Error(t, err)
+}
+
+func TestIntentModel_SaveLoad(t *testing.T) {
+	dir := t.TempDir()
+	tenant := uuid.New()
+	model, err := TrainIntentModel([]IntentExample{
+		{Text: "what is the mileage", Intent: IntentSpecLookup},
+		{Text: "compare with the accord", Intent: IntentComparison},
+	}, IntentTrainConfig{Epochs: 20})
+	require.NoError(t, err)
+	require.NoError(t, SaveIntentModel(filepath.Join(dir, DefaultIntentExamples+".json"), model))
+	require.NoError(t, SaveIntentModel(filepath.Join(dir, tenant.String()+".json"), model))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.json"), []byte("{}"), 0o644))
+
+	shared, tenants, err := LoadIntentModels(dir)
+	require.NoError(t, err)
+	require.NotNil(t, shared)
+	require.Contains(t, tenants, tenant)
+	assert.Len(t, tenants, 1, "files not named by tenant ID are skipped")
+
+	want, _ := model.Predict("compare mileage with the accord")
+	got, _ := shared.Predict("compare mileage with the accord")
+	assert.Equal(t, want, got)
+	assert.Equal(t, 0.7, shared.MinConfidence, "defaults to the router's intent threshold")
+
+	t.Run("malformed weights are rejected", func(t *testing.T) {
+		bad := *model
+		bad.Weights = [][]float64{model.Weights[0], model.Weights[1][:1]}
+		path := filepath.Join(t.TempDir(), "bad.json")
+		require.NoError(t, SaveIntentModel(path, &bad))
+		_, err := LoadIntentModel(path)
+		assert.ErrorContains(t, err, "malformed")
+
+		bad = *model
+		bad.Features = map[string]int{"w:mileage": len(model.Features)}
+		for f, i := range model.Features {
+			if i != 0 {
+				bad.Features[f] = i
+			}
+		}
+		require.NoError(t, SaveIntentModel(path, &bad))
+		_, err = LoadIntentModel(path)
+		assert.ErrorContains(t, err, "malformed")
+	})
+}
+
+func TestIntentModel_HeldOutExamples(t *testing.T) {
+	examples, err := LoadTenantIntentExamples("../../configs/intents", uuid.Nil)
+	require.NoError(t, err)
+	train, test := SplitIntentExamples(examples, 0.2, 3)
+
+	model, err := TrainIntentModel(train, IntentTrainConfig{Epochs: 5})
+	require.NoError(t, err)
+	assert.Nil(t, model.HeldOutExamples(examples), "no holdout recorded")
+
+	model.Holdout, model.Seed = 0.2, 3
+	assert.Equal(t, test, model.HeldOutExamples(examples))
+}
+
+func TestIntentClassifier_Models(t *testing.T) {
+	tenant := uuid.New()
+	c := NewIntentClassifier()
+
+	// Without a model the patterns decide
+	intent, _ := c.ClassifyTenant(tenant, "Hello there")
+	assert.Equal(t, IntentSpecLookup, intent)
+
+	model, err := TrainIntentModel([]IntentExample{
+		{Text: "hello there", Intent: IntentUnknown},
+		{Text: "hi", Intent: IntentUnknown},
+		{Text: "what is the mileage", Intent: IntentSpecLookup},
+		{Text: "what is the torque", Intent: IntentSpecLookup},
+	}, IntentTrainConfig{})
+	require.NoError(t, err)
+	c.SetTenantModel(tenant, model)
+
+	intent, confidence := c.ClassifyTenant(tenant, "Hello there")
+	assert.Equal(t, IntentUnknown, intent)
+	assert.Greater(t, confidence, 0.5)
+
+	// Other tenants and questions the model knows nothing about use the patterns
+	intent, _ = c.ClassifyTenant(uuid.New(), "Hello there")
+	assert.Equal(t, IntentSpecLookup, intent)
+	intent, _ = c.ClassifyTenant(tenant, "Compare versus Accord")
+	assert.Equal(t, IntentComparison, intent)
+
+	c.SetModel(model)
+	intent, _ = c.Classify("Hello there")
+	assert.Equal(t, IntentUnknown, intent)
+	c.SetTenantModel(tenant, nil)
+	intent, _ = c.ClassifyTenant(tenant, "Hello there")
+	assert.Equal(t, IntentUnknown, intent, "tenants without a model use the shared model")
+}
+
+func TestEvaluateIntents(t *testing.T) {
+	classify := func(q string) (Intent, float64) {
+		if q == "compare" {
+			return IntentComparison, 1
+		}
+		return IntentSpecLookup, 1
+	}
+	eval := EvaluateIntents(classify, []IntentExample{
+		{Text: "compare", Intent: IntentComparison},
+		{Text: "mileage", Intent: IntentSpecLookup},
+		{Text: "why buy", Intent: IntentUSPLookup},
+	})
+	assert.Equal(t, 3, eval.Total)
+	assert.Equal(t, 2, eval.Correct)
+	assert.InDelta(t, 2.0/3, eval.Accuracy, 1e-9)
+	assert.Equal(t, []Intent{IntentComparison, IntentSpecLookup, IntentUSPLookup}, eval.Labels)
+	assert.Equal(t, 1, eval.Confusion[IntentUSPLookup][IntentSpecLookup])
+	assert.InDelta(t, 0.5, eval.Precision(IntentSpecLookup), 1e-9)
+	assert.InDelta(t, 0.0, eval.Recall(IntentUSPLookup), 1e-9)
+}
@@ -0,0 +1,360 @@
+// Package retrieval provides retrieval strategies for pricing, availability,
+// warranty and accessory questions.
+package retrieval
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/lexical"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/storage"
+)
+
+// maxAvailabilityMatrices bounds the attributes tabulated for one question.
+const maxAvailabilityMatrices = 3
+
+// intentStrategy narrows retrieval to the specs and chunks an intent is about.
+type intentStrategy struct {
+	// label names the intent in trace filter reasons.
+	label string
+	// specTerms select spec facts whose category or name contains one of them.
+	specTerms []string
+	// specDimension also selects spec values measured in this dimension.
+	specDimension Dimension
+	// chunkTypes and blockTypes restrict the chunks searched for context.
+	chunkTypes []storage.ChunkType
+	blockTypes []storage.BlockType
+	// chunksOnly answers from chunks alone, skipping spec facts.
+	chunksOnly bool
+}
+
+// intentStrategies are the dedicated strategies for intents answered from a
+// subset of specs and chunks. Availability builds per-trim matrices instead.
+var intentStrategies = map[Intent]intentStrategy{
+	IntentPricing: {
+		label:         "pricing",
+		specTerms:     []string{"price", "ex-showroom", "on-road", "msrp", "cost", "emi", "offer", "discount"},
+		specDimension: DimensionPrice,
+	},
+	IntentWarranty: {
+		label:      "warranty",
+		specTerms:  []string{"warranty", "guarantee", "service", "maintenance", "roadside"},
+		chunkTypes: []storage.ChunkType{storage.ChunkTypeFAQ, storage.ChunkTypeGlobal},
+	},
+	IntentAccessory: {
+		label:      "accessory",
+		chunkTypes: []storage.ChunkType{storage.ChunkTypeFeatureBlock},
+		blockTypes: []storage.BlockType{storage.BlockTypeAccessory},
+		chunksOnly: true,
+	},
+}
+
+// AvailabilityMatrix lists which values of one attribute, such as body colour,
+// each trim offers.
+type AvailabilityMatrix struct {
+	Category string
+	Name     string
+	// Trims are the matrix columns.
+	Trims []AvailabilityTrim
+	// Rows are the distinct values, each with one cell per trim.
+	Rows []AvailabilityRow
+}
+
+// AvailabilityTrim is a matrix column: a campaign variant with stored values.
+type AvailabilityTrim struct {
+	CampaignVariantID uuid.UUID
+	ProductID         uuid.UUID
+	ProductName       string
+	Trim              string
+}
+
+// AvailabilityRow is a matrix row. Available is aligned with the matrix trims.
+type AvailabilityRow struct {
+	Value     string
+	Available []bool
+}
+
+// queryIntentStrategy answers an intent from the specs and chunks its strategy
+// selects. It reports whether vector search was used.
+func (r *Router) queryIntentStrategy(ctx context.Context, req RetrievalRequest, response *RetrievalResponse, s intentStrategy) bool {
+	if !s.chunksOnly {
+		facts, confidence, err := r.queryStructuredSpecs(ctx, req)
+		if err != nil {
+			r.logger.Warn().Err(err).Msg("Structured query failed")
+		}
+		facts = r.selectIntentFacts(ctx, facts, s)
+
+		// The question may not name the spec, e.g. "How much is the Camry?";
+		// search for the strategy's own terms instead
+		if len(facts) == 0 && len(s.specTerms) > 0 {
+			termReq := req
+			termReq.Question = strings.Join(s.specTerms, " ")
+			termReq.expansions = nil
+			facts, confidence, err = r.queryStructuredSpecs(ctx, termReq)
+			if err != nil {
+				r.logger.Warn().Err(err).Msg("Structured query failed")
+			}
+			facts = r.selectIntentFacts(ctx, facts, s)
+		}
+
+		response.StructuredFacts = facts
+		if len(facts) > 0 && (confidence >= r.config.KeywordConfidenceThreshold || !r.config.SemanticFallback) {
+			return false
+		}
+	}
+
+	chunkReq := req
+	chunkReq.Filters.ChunkTypes = s.chunkTypes
+	chunkReq.Filters.BlockTypes = s.blockTypes
+	chunks, err := r.querySemanticChunks(ctx, chunkReq)
+	if err == nil && len(chunks) > 0 {
+		response.SemanticChunks = chunks
+		return true
+	}
+
+	// Fall back to BM25 over chunks when vector search is unavailable or empty
+	if chunks := r.searchLexicalChunks(ctx, chunkReq); len(chunks) > 0 {
+		response.SemanticChunks = chunks
+	}
+	return false
+}
+
+// selectIntentFacts keeps the facts a strategy's spec terms or dimension select.
+func (r *Router) selectIntentFacts(ctx context.Context, facts []SpecFact, s intentStrategy) []SpecFact {
+	trace := traceFrom(ctx)
+	selected := facts[:0:0]
+	for _, f := range facts {
+		if s.selects(f) {
+			selected = append(selected, f)
+			continue
+		}
+		trace.filter("intent", factLabel(f), false, "not a "+s.label+" spec")
+	}
+	return selected
+}
+
+// selects reports whether a fact is one of the strategy's specs.
+func (s intentStrategy) selects(f SpecFact) bool {
+	subject := strings.ToLower(f.Category + " " + f.Name)
+	for _, term := range s.specTerms {
+		if strings.Contains(subject, term) {
+			return true
+		}
+	}
+	if s.specDimension != "" {
+		_, dimension, ok := specQuantity(storage.SpecViewLatest{Value: f.Value, Unit: &f.Unit, SpecName: f.Name})
+		return ok && baseDimension(dimension) == s.specDimension
+	}
+	return false
+}
+
+// queryAvailability answers availability questions with a matrix of values per
+// trim for each attribute the question matches. Every trim of the requested
+// products is tabulated, not only the requested variant. It reports false when
+// no attribute matched, so routing falls back to a spec lookup.
+func (r *Router) queryAvailability(ctx context.Context, req RetrievalRequest, response *RetrievalResponse) bool {
+	index, ok := r.searchIndex(ctx, req)
+	if !ok {
+		return false
+	}
+
+	hits := index.Search(lexical.Query{
+		TenantID:   req.TenantID,
+		ProductIDs: req.ProductIDs,
+		Kinds:      []lexical.Kind{lexical.KindSpec},
+		Text:       lexicalQueryText(req.Question, r.extractKeywords(req.Question)),
+		Expansions: lexicalExpansions(req.expansions),
+		Limit:      lexicalSearchLimit,
+	})
+	cutoff := r.config.LexicalMinScore
+	if len(hits) > 0 {
+		cutoff = math.Max(cutoff, hits[0].Calibrated*lexicalRelativeCutoff)
+	}
+
+	// Attributes in order of their best hit
+	trace := traceFrom(ctx)
+	var attributes []string
+	scores := make(map[string]float64)
+	for _, hit := range hits {
+		if hit.Calibrated < cutoff {
+			break
+		}
+		sv, ok := hit.Document.Payload.(storage.SpecViewLatest)
+		if !ok {
+			continue
+		}
+		key := availabilityKey(sv)
+		if _, ok := scores[key]; ok {
+			continue
+		}
+		if len(attributes) == maxAvailabilityMatrices {
+			trace.filter("availability", sv.CategoryName+" > "+sv.SpecName, false, fmt.Sprintf("beyond the top %d attributes", maxAvailabilityMatrices))
+			continue
+		}
+		attributes = append(attributes, key)
+		scores[key] = hit.Calibrated
+	}
+	if len(attributes) == 0 {
+		return false
+	}
+
+	// Tabulate every stored value of the matched attributes, across trims
+	values := make(map[string][]storage.SpecViewLatest, len(attributes))
+	for _, doc := range index.Documents(lexical.Query{
+		TenantID:   req.TenantID,
+		ProductIDs: req.ProductIDs,
+		Kinds:      []lexical.Kind{lexical.KindSpec},
+	}) {
+		sv, ok := doc.Payload.(storage.SpecViewLatest)
+		if !ok {
+			continue
+		}
+		if key := availabilityKey(sv); scores[key] > 0 {
+			values[key] = append(values[key], sv)
+		}
+	}
+
+	var facts []SpecFact
+	for _, key := range attributes {
+		matrix := buildAvailabilityMatrix(values[key])
+		response.Availability = append(response.Availability, matrix)
+		for _, sv := range values[key] {
+			facts = append(facts, specViewFact(sv, storage.ValueOriginLocal, scores[key]))
+			trace.candidate(CandidateScore{
+				Kind:   EvidenceSpecFact,
+				ID:     sv.SpecItemID,
+				Label:  sv.CategoryName + " > " + sv.SpecName + ": " + sv.Value,
+				Source: SourceLexical,
+				Score:  scores[key],
+			})
+		}
+		trace.filter("availability", matrix.Category+" > "+matrix.Name, true,
+			fmt.Sprintf("%d values across %d trims", len(matrix.Rows), len(matrix.Trims)))
+	}
+	response.StructuredFacts = facts
+
+	r.logger.Debug().
+		Int("attributes", len(attributes)).
+		Int("facts", len(facts)).
+		Msg("Built availability matrices")
+	return true
+}
+
+// availabilityKey groups spec values of the same attribute across trims.
+func availabilityKey(sv storage.SpecViewLatest) string {
+	return strings.ToLower(sv.CategoryName) + "|" + strings.ToLower(sv.SpecName)
+}
+
+// buildAvailabilityMatrix tabulates an attribute's values per campaign variant.
+// Rows offered on more trims come first.
+func buildAvailabilityMatrix(specs []storage.SpecViewLatest) AvailabilityMatrix {
+	matrix := AvailabilityMatrix{}
+	if len(specs) > 0 {
+		matrix.Category, matrix.Name = specs[0].CategoryName, specs[0].SpecName
+	}
+
+	seenTrims := make(map[uuid.UUID]bool)
+	for _, sv := range specs {
+		if seenTrims[sv.CampaignVariantID] {
+			continue
+		}
+		seenTrims[sv.CampaignVariantID] = true
+		trim := AvailabilityTrim{
+			CampaignVariantID: sv.CampaignVariantID,
+			ProductID:         sv.ProductID,
+			ProductName:       sv.ProductName,
+		}
+		if sv.Trim != nil {
+			trim.Trim = *sv.Trim
+		}
+		matrix.Trims = append(matrix.Trims, trim)
+	}
+	sort.Slice(matrix.Trims, func(i, j int) bool {
+		a, b := matrix.Trims[i], matrix.Trims[j]
+		if a.ProductName != b.ProductName {
+			return a.ProductName < b.ProductName
+		}
+		if a.Trim != b.Trim {
+			return a.Trim < b.Trim
+		}
+		return a.CampaignVariantID.String() < b.CampaignVariantID.String()
+	})
+	column := make(map[uuid.UUID]int, len(matrix.Trims))
+	for i, trim := range matrix.Trims {
+		column[trim.CampaignVariantID] = i
+	}
+
+	rows := make(map[string]int)
+	for _, sv := range specs {
+		for _, value := range splitAvailabilityValues(sv.Value) {
+			key := strings.ToLower(value)
+			i, ok := rows[key]
+			if !ok {
+				i = len(matrix.Rows)
+				rows[key] = i
+				matrix.Rows = append(matrix.Rows, AvailabilityRow{Value: value, Available: make([]bool, len(matrix.Trims))})
+			}
+			matrix.Rows[i].Available[column[sv.CampaignVariantID]] = true
+		}
+	}
+	sort.SliceStable(matrix.Rows, func(i, j int) bool {
+		a, b := availableCount(matrix.Rows[i]), availableCount(matrix.Rows[j])
+		if a != b {
+			return a > b
+		}
+		return matrix.Rows[i].Value < matrix.Rows[j].Value
+	})
+	return matrix
+}
+
+// splitAvailabilityValues splits a listed value such as "Red, Blue; White".
+func splitAvailabilityValues(value string) []string {
+	var values []string
+	for _, part := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ';' || r == '|' }) {
+		if part = strings.TrimSpace(part); part != "" {
+			values = append(values, part)
+		}
+	}
+	return values
+}
+
+// availableCount counts the trims offering a row's value.
+func availableCount(row AvailabilityRow) int {
+	n := 0
+	for _, ok := range row.Available {
+		if ok {
+			n++
+		}
+	}
+	return n
+}
+
+// chunkBlockType reads the feature block type a chunk was built from, if any.
+func chunkBlockType(metadata map[string]interface{}) storage.BlockType {
+	if bt, ok := metadata[storage.ChunkMetaBlockType].(string); ok {
+		return storage.BlockType(bt)
+	}
+	return ""
+}
+
+// knowledgeChunkBlockType reads the feature block type from stored chunk metadata.
+func knowledgeChunkBlockType(kc storage.KnowledgeChunk) storage.BlockType {
+	return chunkBlockType(knowledgeChunkMetadata(kc))
+}
+
+// knowledgeChunkMetadata decodes stored chunk metadata, or returns nil.
+func knowledgeChunkMetadata(kc storage.KnowledgeChunk) map[string]interface{} {
+	if len(kc.Metadata) == 0 {
+		return nil
+	}
+	var metadata map[string]interface{}
+	if err := json.Unmarshal(kc.Metadata, &metadata); err != nil {
+		return nil
+	}
+	return metadata
+}
@@ -0,0 +1,179 @@
+package retrieval
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/ingest"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/lexical"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/observability"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntentClassifier_StrategyIntents(t *testing.T) {
+	classifier := NewIntentClassifier()
+
+	tests := []struct {
+		question string
+		expected Intent
+	}{
+		{"What is the ex-showroom price?", IntentPricing},
+		{"Are there any discounts this month?", IntentPricing},
+		{"What colours does it come in?", IntentAvailability},
+		{"Is the sunroof available on the base trim?", IntentAvailability},
+		{"How long is the warranty?", IntentWarranty},
+		{"What are the service intervals?", IntentWarranty},
+		{"What accessories are available?", IntentAccessory},
+		{"How much do the floor mats cost?", IntentAccessory},
+		{"Compare the price with the Accord", IntentComparison},
+		{"How much horsepower does it have?", IntentSpecLookup},
+		{"Does it have premium audio?", IntentSpecLookup},
+	}
+	for _, tc := range tests {
+		t.Run(tc.question, func(t *testing.T) {
+			intent, _ := classifier.ClassifyPatterns(tc.question)
+			assert.Equal(t, tc.expected, intent)
+		})
+	}
+}
+
+// indexSpec adds a spec value for a trim to the router's lexical index.
+func indexSpec(router *Router, tenant, product, campaign uuid.UUID, trim, category, name, value string) {
+	router.LexicalIndex().Upsert(lexical.SpecDocument(storage.SpecViewLatest{
+		ID:                uuid.New(),
+		SpecItemID:        uuid.New(),
+		TenantID:          tenant,
+		ProductID:         product,
+		CampaignVariantID: campaign,
+		CategoryName:      category,
+		SpecName:          name,
+		Value:             value,
+		Confidence:        0.9,
+		Trim:              &trim,
+		ProductName:       "Camry",
+	}))
+}
+
+func TestRouter_IntentStrategies(t *testing.T) {
+	logger := observability.NewLogger(observability.LogConfig{Level: "error"})
+	tenant, product := uuid.New(), uuid.New()
+	le, xle := uuid.New(), uuid.New()
+	router := NewRouter(logger, nil, nil, nil, nil, RouterConfig{StructuredFirst: true})
+
+	indexSpec(router, tenant, product, le, "LE", "Colors", "Body Color", "Red, White")
+	indexSpec(router, tenant, product, xle, "XLE", "Colors", "Body Color", "White; Blue")
+	indexSpec(router, tenant, product, le, "LE", "Pricing", "Ex-Showroom Price", "$28,000")
+	indexSpec(router, tenant, product, le, "LE", "Warranty", "Warranty Period", "3 years / 100,000 km")
+	indexSpec(router, tenant, product, le, "LE", "Engine", "Power", "203 hp")
+	for _, chunk := range []struct {
+		text, blockType string
+	}{
+		{"Genuine roof rack rated for 75 kg, available as an accessory.", "accessory"},
+		{"Roof rails come standard on every trim.", "feature"},
+	} {
+		metadata, _ := json.Marshal(map[string]string{"block_type": chunk.blockType})
+		router.LexicalIndex().Upsert(lexical.ChunkDocument(storage.KnowledgeChunk{
+			ID:        uuid.New(),
+			TenantID:  tenant,
+			ProductID: product,
+			ChunkType: storage.ChunkTypeFeatureBlock,
+			Text:      chunk.text,
+			Metadata:  metadata,
+		}))
+	}
+	router.LexicalIndex().MarkLoaded(tenant)
+	ctx := context.Background()
+
+	t.Run("availability matrix per trim", func(t *testing.T) {
+		resp, err := router.Query(ctx, RetrievalRequest{TenantID: tenant, Question: "Which colours does it come in?"})
+		require.NoError(t, err)
+		assert.Equal(t, IntentAvailability, resp.Intent)
+		require.Len(t, resp.Availability, 1)
+
+		matrix := resp.Availability[0]
+		assert.Equal(t, "Body Color", matrix.Name)
+		require.Len(t, matrix.Trims, 2)
+		assert.Equal(t, "LE", matrix.Trims[0].Trim)
+		assert.Equal(t, "XLE", matrix.Trims[1].Trim)
+		assert.Equal(t, []AvailabilityRow{
+			{Value: "White", Available: []bool{true, true}},
+			{Value: "Blue", Available: []bool{false, true}},
+			{Value: "Red", Available: []bool{true, false}},
+		}, matrix.Rows)
+		assert.Len(t, resp.StructuredFacts, 2)
+	})
+
+	t.Run("pricing keeps price specs", func(t *testing.T) {
+		resp, err := router.Query(ctx, RetrievalRequest{TenantID: tenant, Question: "Are there any discounts?", Explain: true})
+		require.NoError(t, err)
+		assert.Equal(t, IntentPricing, resp.Intent)
+		require.Len(t, resp.StructuredFacts, 1)
+		assert.Equal(t, "Ex-Showroom Price", resp.StructuredFacts[0].Name)
+	})
+
+	t.Run("warranty keeps warranty specs", func(t *testing.T) {
+		resp, err := router.Query(ctx, RetrievalRequest{TenantID: tenant, Question: "How long is the warranty?"})
+		require.NoError(t, err)
+		assert.Equal(t, IntentWarranty, resp.Intent)
+		require.NotEmpty(t, resp.StructuredFacts)
+		assert.Equal(t, "Warranty Period", resp.StructuredFacts[0].Name)
+	})
+
+	t.Run("accessories use accessory blocks only", func(t *testing.T) {
+		resp, err := router.Query(ctx, RetrievalRequest{TenantID: tenant, Question: "Is a roof rack accessory available?"})
+		require.NoError(t, err)
+		assert.Equal(t, IntentAccessory, resp.Intent)
+		assert.Empty(t, resp.StructuredFacts)
+		require.Len(t, resp.SemanticChunks, 1)
+		assert.Contains(t, resp.SemanticChunks[0].Text, "Genuine roof rack")
+	})
+}
+
+func TestRouter_AccessoryBlocksFromIngest(t *testing.T) {
+	logger := observability.NewLogger(observability.LogConfig{Level: "error"})
+	router := NewRouter(logger, nil, nil, nil, nil, RouterConfig{StructuredFirst: true})
+
+	brochure := filepath.Join(t.TempDir(), "brochure.md")
+	require.NoError(t, os.WriteFile(brochure, []byte(`# Camry
+
+## Features
+
+- Roof rails come standard on every trim
+
+## Accessories
+
+- Genuine roof rack rated for 75 kg
+- All-weather floor mats
+`), 0o644))
+
+	req := ingest.IngestionRequest{TenantID: uuid.New(), ProductID: uuid.New(), CampaignID: uuid.New(), MarkdownPath: brochure}
+	pipeline := ingest.NewPipeline(logger, router.LexicalIndex(), ingest.PipelineConfig{ChunkSize: 512})
+	result, err := pipeline.Ingest(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.AccessoriesCreated)
+	router.LexicalIndex().Promote(req.TenantID, req.CampaignID)
+	router.LexicalIndex().MarkLoaded(req.TenantID)
+
+	resp, err := router.Query(context.Background(), RetrievalRequest{TenantID: req.TenantID, Question: "Is a roof rack accessory available?"})
+	require.NoError(t, err)
+	assert.Equal(t, IntentAccessory, resp.Intent)
+	require.NotEmpty(t, resp.SemanticChunks)
+	for _, chunk := range resp.SemanticChunks {
+		assert.Equal(t, storage.ChunkTypeFeatureBlock, chunk.ChunkType)
+		assert.NotContains(t, chunk.Text, "Roof rails", "feature blocks and raw text are not accessories")
+	}
+	assert.Contains(t, resp.SemanticChunks[0].Text, "Genuine roof rack")
+}
+
+func TestMatchesFilters_BlockTypes(t *testing.T) {
+	entry := VectorEntry{ChunkType: string(storage.ChunkTypeFeatureBlock), Metadata: map[string]interface{}{"block_type": "accessory"}}
+	assert.True(t, matchesFilters(entry, VectorFilters{BlockTypes: []string{"accessory"}}))
+	assert.False(t, matchesFilters(entry, VectorFilters{BlockTypes: []string{"usp"}}))
+	assert.False(t, matchesFilters(VectorEntry{}, VectorFilters{BlockTypes: []string{"accessory"}}))
+}
@@ -0,0 +1,312 @@
+// Package retrieval provides BM25 lexical retrieval of spec facts.
+package retrieval
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/lexical"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/storage"
+)
+
+const (
+	// lexicalSearchLimit bounds the hits considered per query.
+	lexicalSearchLimit = 200
+	// lexicalRelativeCutoff drops hits scoring below this fraction of the best hit.
+	lexicalRelativeCutoff = 0.5
+	// lexicalConfidenceDepth is the number of top facts averaged into confidence.
+	lexicalConfidenceDepth = 3
+)
+
+var quotedPhraseRe = regexp.MustCompile(`"[^"]+"`)
+
+// LexicalIndex returns the router's lexical index so ingest and publish can update it.
+func (r *Router) LexicalIndex() *lexical.Index {
+	return r.lexicalIndex
+}
+
+// SetChunkSource loads published knowledge chunks into the lexical index
+// alongside specs when a tenant's index is warmed.
+func (r *Router) SetChunkSource(source lexical.ChunkSource) {
+	r.chunkSource = source
+}
+
+// ensureLexicalIndex loads a tenant's published specs and chunks into the
+// lexical index on first use. It reports whether the index can serve the tenant.
+func (r *Router) ensureLexicalIndex(ctx context.Context, tenantID uuid.UUID) bool {
+	if r.lexicalIndex == nil {
+		return false
+	}
+	if r.lexicalIndex.Loaded(tenantID) {
+		return true
+	}
+	if r.specViewRepo == nil {
+		return r.lexicalIndex.HasDocuments(tenantID)
+	}
+
+	count, err := r.lexicalIndex.Warm(ctx, r.specViewRepo, r.chunkSource, tenantID)
+	if err != nil {
+		r.logger.Warn().Err(err).Str("tenant_id", tenantID.String()).Msg("Failed to warm lexical index, using keyword search")
+		return false
+	}
+	r.logger.Info().
+		Str("tenant_id", tenantID.String()).
+		Int("documents", count).
+		Msg("Lexical index warmed")
+	return true
+}
+
+// searchLexicalSpecs retrieves spec facts from the BM25 index, ordered by calibrated
+// score. The second return value is false when the index cannot serve the tenant.
+func (r *Router) searchLexicalSpecs(ctx context.Context, req RetrievalRequest, keywords []string, scope *campaignScope) ([]SpecFact, bool) {
+	index, ok := r.searchIndex(ctx, req)
+	if !ok {
+		return nil, false
+	}
+
+	q := lexical.Query{
+		TenantID:   req.TenantID,
+		ProductIDs: req.ProductIDs,
+		Kinds:      []lexical.Kind{lexical.KindSpec},
+		Text:       lexicalQueryText(req.Question, keywords),
+		Expansions: lexicalExpansions(req.expansions),
+		Limit:      lexicalSearchLimit,
+	}
+	if scope.campaignID != nil {
+		q.CampaignVariantIDs = scope.chain
+	}
+	hits := index.Search(q)
+
+	cutoff := r.config.LexicalMinScore
+	if len(hits) > 0 {
+		cutoff = math.Max(cutoff, hits[0].Calibrated*lexicalRelativeCutoff)
+	}
+
+	trace := traceFrom(ctx)
+	facts := make([]SpecFact, 0, len(hits))
+	seen := make(map[string]bool, len(hits))
+	for i, hit := range hits {
+		if hit.Calibrated < cutoff {
+			if trace != nil {
+				for _, rest := range hits[i:] {
+					subject := rest.Document.ID.String()
+					if sv, ok := rest.Document.Payload.(storage.SpecViewLatest); ok {
+						subject = sv.CategoryName + " > " + sv.SpecName + ": " + sv.Value
+					}
+					trace.filter("lexical_cutoff", subject, false, fmt.Sprintf("score %.3f below cutoff %.3f", rest.Calibrated, cutoff))
+				}
+			}
+			break
+		}
+		sv, ok := hit.Document.Payload.(storage.SpecViewLatest)
+		if !ok {
+			continue
+		}
+		label := sv.CategoryName + " > " + sv.SpecName + ": " + sv.Value
+		trace.candidate(CandidateScore{
+			Kind:   EvidenceSpecFact,
+			ID:     sv.SpecItemID,
+			Label:  label,
+			Source: SourceLexical,
+			Score:  hit.Calibrated,
+			Components: map[string]float64{
+				"bm25":          hit.Score,
+				"calibrated":    hit.Calibrated,
+				"matched_terms": float64(len(hit.MatchedTerms)),
+				"cutoff":        cutoff,
+			},
+		})
+
+		// Filter by campaign variant if specified, keeping only effective values
+		origin, ok := scope.accept(sv)
+		if !ok {
+			trace.filter("campaign_scope", label, false, "not effective for the requested variant")
+			continue
+		}
+
+		key := fmt.Sprintf("%s|%s|%s", sv.CategoryName, sv.SpecName, sv.Value)
+		if seen[key] {
+			trace.filter("dedupe", label, false, "duplicate of a higher-scoring fact")
+			continue
+		}
+		seen[key] = true
+
+		facts = append(facts, specViewFact(sv, origin, hit.Calibrated))
+	}
+
+	r.logger.Debug().
+		Int("hits", len(hits)).
+		Int("facts", len(facts)).
+		Float64("cutoff", cutoff).
+		Msg("Lexical spec search")
+	return facts, true
+}
+
+// specViewFact converts a spec view row into a fact with the given relevance score.
+func specViewFact(sv storage.SpecViewLatest, origin storage.ValueOrigin, score float64) SpecFact {
+	unit := ""
+	if sv.Unit != nil {
+		unit = *sv.Unit
+	}
+	return SpecFact{
+		SpecValueID:       sv.ID,
+		SpecItemID:        sv.SpecItemID,
+		Category:          sv.CategoryName,
+		Name:              sv.SpecName,
+		Value:             sv.Value,
+		Unit:              unit,
+		Confidence:        sv.Confidence,
+		CampaignVariantID: sv.CampaignVariantID,
+		Origin:            origin,
+		Score:             score,
+		Source: SourceRef{
+			DocumentSourceID: sv.SourceDocID,
+			Page:             sv.SourcePage,
+		},
+	}
+}
+
+// lexicalQueryText builds the index query from extracted keywords, keeping any
+// quoted phrases from the question so they are matched as phrases.
+func lexicalQueryText(question string, keywords []string) string {
+	parts := append([]string{}, keywords...)
+	parts = append(parts, quotedPhraseRe.FindAllString(question, -1)...)
+	return strings.Join(parts, " ")
+}
+
+// lexicalConfidence averages the calibrated scores of the top facts.
+func lexicalConfidence(facts []SpecFact) float64 {
+	if len(facts) == 0 {
+		return 0.0
+	}
+	n := len(facts)
+	if n > lexicalConfidenceDepth {
+		n = lexicalConfidenceDepth
+	}
+	total := 0.0
+	for _, f := range facts[:n] {
+		total += f.Score
+	}
+	return total / float64(n)
+}
+
+// searchLexicalChunks retrieves knowledge chunks from the BM25 index, ordered by
+// calibrated score.
+func (r *Router) searchLexicalChunks(ctx context.Context, req RetrievalRequest) []SemanticChunk {
+	index, ok := r.searchIndex(ctx, req)
+	if !ok {
+		return nil
+	}
+
+	q := lexical.Query{
+		TenantID:   req.TenantID,
+		ProductIDs: req.ProductIDs,
+		Kinds:      []lexical.Kind{lexical.KindChunk},
+		Text:       lexicalQueryText(req.Question, r.extractKeywords(req.Question)),
+		Expansions: lexicalExpansions(req.expansions),
+		Limit:      req.MaxChunks,
+	}
+	if req.CampaignVariantID != nil {
+		q.CampaignVariantIDs = r.resolveCampaignScope(ctx, req).chain
+	}
+	overrides := lexicalOverrides(index, q)
+
+	allowedTypes := make(map[storage.ChunkType]bool, len(req.Filters.ChunkTypes))
+	for _, ct := range req.Filters.ChunkTypes {
+		allowedTypes[ct] = true
+	}
+	allowedBlocks := make(map[storage.BlockType]bool, len(req.Filters.BlockTypes))
+	for _, bt := range req.Filters.BlockTypes {
+		allowedBlocks[bt] = true
+	}
+
+	trace := traceFrom(ctx)
+	var chunks []SemanticChunk
+	for _, hit := range index.Search(q) {
+		if hit.Calibrated < r.config.LexicalMinScore {
+			break
+		}
+		kc, ok := hit.Document.Payload.(storage.KnowledgeChunk)
+		if !ok {
+			continue
+		}
+		trace.candidate(CandidateScore{
+			Kind:   EvidenceSemanticChunk,
+			ID:     kc.ID,
+			Label:  string(kc.ChunkType),
+			Source: SourceLexical,
+			Score:  hit.Calibrated,
+			Components: map[string]float64{
+				"bm25":       hit.Score,
+				"calibrated": hit.Calibrated,
+			},
+		})
+		if overriddenChunk(kc, overrides, q.CampaignVariantIDs) {
+			trace.filter("campaign_scope", kc.ID.String(), false, "feature block overridden by the requested variant")
+			continue
+		}
+		if len(allowedTypes) > 0 && !allowedTypes[kc.ChunkType] {
+			trace.filter("chunk_type", kc.ID.String(), false, "chunk type "+string(kc.ChunkType)+" not requested")
+			continue
+		}
+		if len(allowedBlocks) > 0 && !allowedBlocks[knowledgeChunkBlockType(kc)] {
+			trace.filter("block_type", kc.ID.String(), false, "not built from a requested feature block type")
+			continue
+		}
+		chunks = append(chunks, SemanticChunk{
+			ChunkID:   kc.ID,
+			ChunkType: kc.ChunkType,
+			Text:      kc.Text,
+			Score:     float32(hit.Calibrated),
+			Source: SourceRef{
+				DocumentSourceID: kc.SourceDocID,
+				Page:             kc.SourcePage,
+			},
+		})
+	}
+	return chunks
+}
+
+// lexicalOverrides maps each feature block overridden within a campaign chain
+// to the nearest position in the chain of a variant overriding it.
+func lexicalOverrides(index *lexical.Index, q lexical.Query) map[string]int {
+	if len(q.CampaignVariantIDs) < 2 {
+		return nil
+	}
+	overrides := make(map[string]int)
+	for _, doc := range index.Documents(lexical.Query{
+		TenantID:           q.TenantID,
+		ProductIDs:         q.ProductIDs,
+		CampaignVariantIDs: q.CampaignVariantIDs,
+		Kinds:              []lexical.Kind{lexical.KindChunk},
+	}) {
+		kc, ok := doc.Payload.(storage.KnowledgeChunk)
+		if !ok || kc.CampaignVariantID == nil {
+			continue
+		}
+		blockID, _ := knowledgeChunkMetadata(kc)[storage.ChunkMetaOverridesBlock].(string)
+		if blockID == "" {
+			continue
+		}
+		rank := chainIndex(q.CampaignVariantIDs, *kc.CampaignVariantID)
+		if prev, ok := overrides[blockID]; rank >= 0 && (!ok || rank < prev) {
+			overrides[blockID] = rank
+		}
+	}
+	return overrides
+}
+
+// overriddenChunk reports whether a chunk's feature block is overridden by a
+// variant nearer the head of chain.
+func overriddenChunk(kc storage.KnowledgeChunk, overrides map[string]int, chain []uuid.UUID) bool {
+	if len(overrides) == 0 || kc.CampaignVariantID == nil {
+		return false
+	}
+	blockID, _ := knowledgeChunkMetadata(kc)[storage.ChunkMetaBlockID].(string)
+	rank, ok := overrides[blockID]
+	return ok && rank < chainIndex(chain, *kc.CampaignVariantID)
+}
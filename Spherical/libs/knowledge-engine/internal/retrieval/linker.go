@@ -0,0 +1,614 @@
+// Package retrieval provides entity linking from question text to the product catalogue.
+package retrieval
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/storage"
+)
+
+// EntityKind identifies what a linked mention resolved to.
+type EntityKind string
+
+const (
+	EntityProduct         EntityKind = "product"
+	EntityCampaignVariant EntityKind = "campaign_variant"
+)
+
+// LinkMethod records how a mention was matched.
+type LinkMethod string
+
+const (
+	LinkExact LinkMethod = "exact"
+	LinkAlias LinkMethod = "alias"
+	LinkFuzzy LinkMethod = "fuzzy"
+)
+
+// Base confidences per kind of catalogue surface form.
+const (
+	linkNameWeight      = 1.0
+	linkAliasWeight     = 0.95
+	linkShortNameWeight = 0.9
+	// linkYearMismatch scales confidence when the mentioned model year matches no candidate.
+	linkYearMismatch = 0.8
+	// linkAmbiguous scales confidence when several products share the mention.
+	linkAmbiguous = 0.9
+	// linkFuzzyMinLength is the shortest surface form eligible for fuzzy matching.
+	linkFuzzyMinLength = 4
+)
+
+// CatalogSource lists a tenant's products and campaign variants.
+type CatalogSource interface {
+	ListProducts(ctx context.Context, tenantID uuid.UUID) ([]*storage.Product, error)
+	ListCampaigns(ctx context.Context, tenantID uuid.UUID) ([]*storage.CampaignVariant, error)
+}
+
+// repositoryCatalog adapts the storage repositories to CatalogSource.
+type repositoryCatalog struct {
+	products  *storage.ProductRepository
+	campaigns *storage.CampaignRepository
+}
+
+// NewRepositoryCatalog creates a catalogue source backed by the product and campaign repositories.
+func NewRepositoryCatalog(products *storage.ProductRepository, campaigns *storage.CampaignRepository) CatalogSource {
+	return &repositoryCatalog{products: products, campaigns: campaigns}
+}
+
+func (c *repositoryCatalog) ListProducts(ctx context.Context, tenantID uuid.UUID) ([]*storage.Product, error) {
+	return c.products.ListByTenant(ctx, tenantID)
+}
+
+func (c *repositoryCatalog) ListCampaigns(ctx context.Context, tenantID uuid.UUID) ([]*storage.CampaignVariant, error) {
+	return c.campaigns.ListByTenant(ctx, tenantID)
+}
+
+// EntityLink is a product or trim mention resolved against the catalogue.
+type EntityLink struct {
+	Kind EntityKind
+	// ID is the product ID or campaign variant ID, depending on Kind.
+	ID        uuid.UUID
+	ProductID uuid.UUID
+	// Name is the catalogue name: the product name or the trim.
+	Name string
+	// Mention is the question text that was matched.
+	Mention    string
+	ModelYear  *int16
+	Method     LinkMethod
+	Confidence float64
+	// Applied reports whether the link filled ProductIDs or CampaignVariantID on the request.
+	Applied bool
+}
+
+// LinkerConfig configures entity linking.
+type LinkerConfig struct {
+	// MinConfidence drops links below this confidence (default 0.6).
+	MinConfidence float64
+	// FuzzyThreshold is the minimum edit similarity for a fuzzy match (default 0.8).
+	FuzzyThreshold float64
+	// CatalogTTL bounds how long a tenant catalogue is cached (default 5 minutes).
+	CatalogTTL time.Duration
+}
+
+// DefaultLinkerConfig returns the default entity linker configuration.
+func DefaultLinkerConfig() LinkerConfig {
+	return LinkerConfig{
+		MinConfidence:  0.6,
+		FuzzyThreshold: 0.8,
+		CatalogTTL:     5 * time.Minute,
+	}
+}
+
+// EntityLinker matches product names, model years, trims and aliases in questions.
+type EntityLinker struct {
+	source CatalogSource
+	config LinkerConfig
+
+	mu       sync.RWMutex
+	catalogs map[uuid.UUID]*linkCatalog
+}
+
+// NewEntityLinker creates a new entity linker.
+func NewEntityLinker(source CatalogSource, cfg LinkerConfig) *EntityLinker {
+	defaults := DefaultLinkerConfig()
+	if cfg.MinConfidence <= 0 {
+		cfg.MinConfidence = defaults.MinConfidence
+	}
+	if cfg.FuzzyThreshold <= 0 {
+		cfg.FuzzyThreshold = defaults.FuzzyThreshold
+	}
+	if cfg.CatalogTTL <= 0 {
+		cfg.CatalogTTL = defaults.CatalogTTL
+	}
+	return &EntityLinker{
+		source:   source,
+		config:   cfg,
+		catalogs: make(map[uuid.UUID]*linkCatalog),
+	}
+}
+
+// Invalidate drops the cached catalogue for a tenant.
+func (l *EntityLinker) Invalidate(tenantID uuid.UUID) {
+	l.mu.Lock()
+	delete(l.catalogs, tenantID)
+	l.mu.Unlock()
+}
+
+// ProductNames returns the names, short names and aliases of a tenant's products.
+func (l *EntityLinker) ProductNames(ctx context.Context, tenantID uuid.UUID) ([]string, error) {
+	catalog, err := l.catalog(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(catalog.surfaces))
+	for i, surface := range catalog.surfaces {
+		names[i] = surface.text
+	}
+	return names, nil
+}
+
+// linkSurface is one way a product can be written in a question.
+type linkSurface struct {
+	text    string
+	tokens  []string
+	compact string
+	method  LinkMethod
+	weight  float64
+	product *storage.Product
+}
+
+// linkTrim is the preferred campaign variant for a product trim.
+type linkTrim struct {
+	tokens   []string
+	compact  string
+	campaign *storage.CampaignVariant
+}
+
+// linkCatalog is a tenant's catalogue prepared for matching.
+type linkCatalog struct {
+	surfaces  []linkSurface
+	trims     map[uuid.UUID][]linkTrim
+	maxTokens int
+	loadedAt  time.Time
+}
+
+// linkToken is a normalized question word with its position in the original text.
+type linkToken struct {
+	text       string
+	start, end int
+}
+
+var (
+	linkWordRe = regexp.MustCompile(`[A-Za-z0-9]+`)
+	linkYearRe = regexp.MustCompile(`^(19|20)\d{2}$`)
+)
+
+// Link resolves product and trim mentions in the question.
+func (l *EntityLinker) Link(ctx context.Context, tenantID uuid.UUID, question string) ([]EntityLink, error) {
+	catalog, err := l.catalog(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	tokens := linkTokens(question)
+	if len(tokens) == 0 || len(catalog.surfaces) == 0 {
+		return nil, nil
+	}
+
+	used := make([]bool, len(tokens))
+	var links []EntityLink
+	var positions []int
+
+	// Products: longest mentions first so "Camry Hybrid" wins over "Camry"
+	for n := catalog.maxTokens; n >= 1; n-- {
+		for i := 0; i+n <= len(tokens); i++ {
+			if anyUsed(used[i : i+n]) {
+				continue
+			}
+			window := tokens[i : i+n]
+			candidates, score, method := l.matchProducts(catalog, window)
+			if len(candidates) == 0 {
+				continue
+			}
+
+			year, yearIdx := adjacentYear(tokens, used, i, n)
+			product, confidence := pickProduct(candidates, year)
+			confidence *= score
+			if confidence < l.config.MinConfidence {
+				continue
+			}
+
+			markUsed(used, i, n)
+			if yearIdx >= 0 {
+				used[yearIdx] = true
+			}
+			links = append(links, EntityLink{
+				Kind:       EntityProduct,
+				ID:         product.ID,
+				ProductID:  product.ID,
+				Name:       product.Name,
+				Mention:    question[window[0].start:window[n-1].end],
+				ModelYear:  product.ModelYear,
+				Method:     method,
+				Confidence: confidence,
+			})
+			positions = append(positions, i)
+		}
+	}
+	if len(links) == 0 {
+		return nil, nil
+	}
+	productLinks := len(links)
+
+	// Trims are matched only against the products already linked
+	for n := catalog.maxTokens; n >= 1; n-- {
+		for i := 0; i+n <= len(tokens); i++ {
+			if anyUsed(used[i : i+n]) {
+				continue
+			}
+			window := tokens[i : i+n]
+
+			best, bestScore, bestDistance := -1, 0.0, 0
+			var bestTrim linkTrim
+			var bestMethod LinkMethod
+			for p := 0; p < productLinks; p++ {
+				for _, trim := range catalog.trims[links[p].ProductID] {
+					score, method := l.matchTokens(window, trim.tokens, trim.compact)
+					if score == 0 {
+						continue
+					}
+					distance := trimDistance(positions[p], i)
+					if score > bestScore || (score == bestScore && distance < bestDistance) {
+						best, bestScore, bestDistance = p, score, distance
+						bestTrim, bestMethod = trim, method
+					}
+				}
+			}
+			if best < 0 {
+				continue
+			}
+
+			confidence := bestScore * links[best].Confidence
+			if confidence < l.config.MinConfidence {
+				continue
+			}
+			markUsed(used, i, n)
+			links = append(links, EntityLink{
+				Kind:       EntityCampaignVariant,
+				ID:         bestTrim.campaign.ID,
+				ProductID:  bestTrim.campaign.ProductID,
+				Name:       *bestTrim.campaign.Trim,
+				Mention:    question[window[0].start:window[n-1].end],
+				Method:     bestMethod,
+				Confidence: confidence,
+			})
+			positions = append(positions, i)
+		}
+	}
+
+	order := make([]int, len(links))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return positions[order[a]] < positions[order[b]]
+	})
+	sorted := make([]EntityLink, len(links))
+	for i, idx := range order {
+		sorted[i] = links[idx]
+	}
+	return sorted, nil
+}
+
+// matchProducts returns the products whose best surface form matches the window.
+func (l *EntityLinker) matchProducts(catalog *linkCatalog, window []linkToken) ([]*storage.Product, float64, LinkMethod) {
+	var (
+		candidates []*storage.Product
+		best       float64
+		method     LinkMethod
+		seen       = make(map[uuid.UUID]bool)
+	)
+	for _, s := range catalog.surfaces {
+		score, m := l.matchTokens(window, s.tokens, s.compact)
+		if score == 0 {
+			continue
+		}
+		if m != LinkFuzzy {
+			m = s.method
+		}
+		score *= s.weight
+		switch {
+		case score > best:
+			best, method = score, m
+			candidates = []*storage.Product{s.product}
+			seen = map[uuid.UUID]bool{s.product.ID: true}
+		case score == best && !seen[s.product.ID]:
+			candidates = append(candidates, s.product)
+			seen[s.product.ID] = true
+		}
+	}
+	return candidates, best, method
+}
+
+// matchTokens compares a question window with a surface form. It returns a
+// similarity in (0, 1], or 0 when the window does not match.
+func (l *EntityLinker) matchTokens(window []linkToken, tokens []string, compact string) (float64, LinkMethod) {
+	var b strings.Builder
+	for _, t := range window {
+		b.WriteString(t.text)
+	}
+	// Compact comparison treats "RAV 4", "RAV-4" and "RAV4" alike
+	if b.String() == compact {
+		return 1.0, LinkExact
+	}
+	if len(window) != len(tokens) || len(compact) < linkFuzzyMinLength {
+		return 0, ""
+	}
+	if rewriteStopWords[window[0].text] || rewriteStopWords[window[len(window)-1].text] ||
+		linkYearRe.MatchString(window[0].text) {
+		return 0, ""
+	}
+	similarity := editSimilarity(b.String(), compact)
+	if similarity < l.config.FuzzyThreshold {
+		return 0, ""
+	}
+	return similarity, LinkFuzzy
+}
+
+// pickProduct chooses among products sharing a mention, preferring the
+// mentioned model year, then the most recent one.
+func pickProduct(candidates []*storage.Product, year int) (*storage.Product, float64) {
+	confidence := 1.0
+	if year > 0 {
+		var matching []*storage.Product
+		for _, p := range candidates {
+			if p.ModelYear != nil && int(*p.ModelYear) == year {
+				matching = append(matching, p)
+			}
+		}
+		if len(matching) > 0 {
+			candidates = matching
+		} else {
+			confidence *= linkYearMismatch
+		}
+	}
+	if len(candidates) > 1 {
+		confidence *= linkAmbiguous
+	}
+
+	best := candidates[0]
+	for _, p := range candidates[1:] {
+		if modelYear(p) > modelYear(best) {
+			best = p
+		}
+	}
+	return best, confidence
+}
+
+func modelYear(p *storage.Product) int {
+	if p.ModelYear == nil {
+		return 0
+	}
+	return int(*p.ModelYear)
+}
+
+// adjacentYear finds a model year immediately before or after a mention.
+func adjacentYear(tokens []linkToken, used []bool, start, n int) (int, int) {
+	for _, idx := range []int{start - 1, start + n} {
+		if idx < 0 || idx >= len(tokens) || used[idx] || !linkYearRe.MatchString(tokens[idx].text) {
+			continue
+		}
+		year, err := strconv.Atoi(tokens[idx].text)
+		if err == nil {
+			return year, idx
+		}
+	}
+	return 0, -1
+}
+
+// trimDistance prefers products mentioned before the trim ("Camry XLE") over after.
+func trimDistance(productPos, trimPos int) int {
+	if productPos <= trimPos {
+		return trimPos - productPos
+	}
+	return (productPos - trimPos) * 2
+}
+
+// catalog returns the tenant's prepared catalogue, loading it when missing or stale.
+func (l *EntityLinker) catalog(ctx context.Context, tenantID uuid.UUID) (*linkCatalog, error) {
+	l.mu.RLock()
+	catalog, ok := l.catalogs[tenantID]
+	l.mu.RUnlock()
+	if ok && time.Since(catalog.loadedAt) < l.config.CatalogTTL {
+		return catalog, nil
+	}
+
+	products, err := l.source.ListProducts(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("list products: %w", err)
+	}
+	campaigns, err := l.source.ListCampaigns(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("list campaigns: %w", err)
+	}
+
+	catalog = buildLinkCatalog(products, campaigns)
+	l.mu.Lock()
+	l.catalogs[tenantID] = catalog
+	l.mu.Unlock()
+	return catalog, nil
+}
+
+// buildLinkCatalog derives surface forms from product names and metadata
+// aliases, and picks one campaign variant per product trim.
+func buildLinkCatalog(products []*storage.Product, campaigns []*storage.CampaignVariant) *linkCatalog {
+	catalog := &linkCatalog{
+		trims:    make(map[uuid.UUID][]linkTrim),
+		loadedAt: time.Now(),
+	}
+
+	add := func(text string, method LinkMethod, weight float64, p *storage.Product) {
+		tokens := normalizeTokens(text)
+		if len(tokens) == 0 {
+			return
+		}
+		catalog.surfaces = append(catalog.surfaces, linkSurface{
+			text:    text,
+			tokens:  tokens,
+			compact: strings.Join(tokens, ""),
+			method:  method,
+			weight:  weight,
+			product: p,
+		})
+		if len(tokens) > catalog.maxTokens {
+			catalog.maxTokens = len(tokens)
+		}
+	}
+
+	for _, p := range products {
+		add(p.Name, LinkExact, linkNameWeight, p)
+
+		// "Toyota Camry" is usually asked about as "Camry"
+		if words := strings.Fields(p.Name); len(words) > 1 {
+			rest := strings.Join(words[1:], " ")
+			if lower := strings.ToLower(rest); !trimWords[lower] && !rewriteStopWords[lower] {
+				add(rest, LinkAlias, linkShortNameWeight, p)
+			}
+		}
+
+		for _, alias := range productAliases(p.Metadata) {
+			add(alias, LinkAlias, linkAliasWeight, p)
+		}
+	}
+
+	best := make(map[string]*storage.CampaignVariant)
+	for _, c := range campaigns {
+		if c.Trim == nil || strings.TrimSpace(*c.Trim) == "" {
+			continue
+		}
+		key := c.ProductID.String() + "|" + strings.Join(normalizeTokens(*c.Trim), " ")
+		if current, ok := best[key]; !ok || preferCampaign(c, current) {
+			best[key] = c
+		}
+	}
+	for _, c := range best {
+		tokens := normalizeTokens(*c.Trim)
+		catalog.trims[c.ProductID] = append(catalog.trims[c.ProductID], linkTrim{
+			tokens:   tokens,
+			compact:  strings.Join(tokens, ""),
+			campaign: c,
+		})
+		if len(tokens) > catalog.maxTokens {
+			catalog.maxTokens = len(tokens)
+		}
+	}
+
+	return catalog
+}
+
+// preferCampaign reports whether a should represent its trim over b: published
+// variants first, then the highest version.
+func preferCampaign(a, b *storage.CampaignVariant) bool {
+	aPublished := a.Status == storage.CampaignStatusPublished && !a.IsDraft
+	bPublished := b.Status == storage.CampaignStatusPublished && !b.IsDraft
+	if aPublished != bPublished {
+		return aPublished
+	}
+	return a.Version > b.Version
+}
+
+// productAliases reads aliases and nicknames from product metadata.
+func productAliases(metadata json.RawMessage) []string {
+	if len(metadata) == 0 {
+		return nil
+	}
+	var meta struct {
+		Aliases   []string `json:"aliases"`
+		Nicknames []string `json:"nicknames"`
+	}
+	if err := json.Unmarshal(metadata, &meta); err != nil {
+		return nil
+	}
+	return append(meta.Aliases, meta.Nicknames...)
+}
+
+// linkTokens splits a question into lowercased words, dropping possessive "s".
+func linkTokens(text string) []linkToken {
+	var tokens []linkToken
+	for _, loc := range linkWordRe.FindAllStringIndex(text, -1) {
+		word := strings.ToLower(text[loc[0]:loc[1]])
+		if word == "s" && loc[0] > 0 && (text[loc[0]-1] == '\'' || strings.HasSuffix(text[:loc[0]], "’")) {
+			continue
+		}
+		tokens = append(tokens, linkToken{text: word, start: loc[0], end: loc[1]})
+	}
+	return tokens
+}
+
+// normalizeTokens lowercases and splits a catalogue name into words.
+func normalizeTokens(text string) []string {
+	tokens := linkTokens(text)
+	words := make([]string, len(tokens))
+	for i, t := range tokens {
+		words[i] = t.text
+	}
+	return words
+}
+
+func anyUsed(used []bool) bool {
+	for _, u := range used {
+		if u {
+			return true
+		}
+	}
+	return false
+}
+
+func markUsed(used []bool, start, n int) {
+	for i := start; i < start+n; i++ {
+		used[i] = true
+	}
+}
+
+// editSimilarity is 1 minus the Levenshtein distance over the longer length.
+func editSimilarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longest := len(ra)
+	if len(rb) > longest {
+		longest = len(rb)
+	}
+	if longest == 0 {
+		return 1.0
+	}
+
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = prev[j] + 1
+			if curr[j-1]+1 < curr[j] {
+				curr[j] = curr[j-1] + 1
+			}
+			if prev[j-1]+cost < curr[j] {
+				curr[j] = prev[j-1] + cost
+			}
+		}
+		prev, curr = curr, prev
+	}
+	return 1.0 - float64(prev[len(rb)])/float64(longest)
+}
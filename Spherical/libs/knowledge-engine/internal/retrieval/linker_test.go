@@ -0,0 +1,171 @@
+package retrieval
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/cache"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/observability"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubCatalog struct {
+	products  []*storage.Product
+	campaigns []*storage.CampaignVariant
+	loads     int
+}
+
+func (s *stubCatalog) ListProducts(ctx context.Context, tenantID uuid.UUID) ([]*storage.Product, error) {
+	s.loads++
+	return s.products, nil
+}
+
+func (s *stubCatalog) ListCampaigns(ctx context.Context, tenantID uuid.UUID) ([]*storage.CampaignVariant, error) {
+	return s.campaigns, nil
+}
+
+func testProduct(name string, year int16, metadata string) *storage.Product {
+	p := &storage.Product{ID: uuid.New(), Name: name, ModelYear: &year}
+	if metadata != "" {
+		p.Metadata = json.RawMessage(metadata)
+	}
+	return p
+}
+
+func testTrim(productID uuid.UUID, trim string, status storage.CampaignStatus, version int) *storage.CampaignVariant {
+	return &storage.CampaignVariant{ID: uuid.New(), ProductID: productID, Trim: &trim, Status: status, Version: version}
+}
+
+func TestEntityLinker_ProductsAndTrims(t *testing.T) {
+	camry := testProduct("Toyota Camry", 2024, "")
+	camryHybrid := testProduct("Toyota Camry Hybrid", 2024, "")
+	accord := testProduct("Honda Accord", 2024, "")
+	rav4 := testProduct("Toyota RAV4", 2024, `{"aliases": ["Rav 4"], "nicknames": ["Ravvy"]}`)
+	xleDraft := testTrim(camry.ID, "XLE", storage.CampaignStatusDraft, 3)
+	xle := testTrim(camry.ID, "XLE", storage.CampaignStatusPublished, 2)
+
+	linker := NewEntityLinker(&stubCatalog{
+		products:  []*storage.Product{camry, camryHybrid, accord, rav4},
+		campaigns: []*storage.CampaignVariant{xleDraft, xle},
+	}, DefaultLinkerConfig())
+	ctx := context.Background()
+	tenant := uuid.New()
+
+	links, err := linker.Link(ctx, tenant, "Compare the Camry Hybrid with the Accord")
+	require.NoError(t, err)
+	require.Len(t, links, 2)
+	assert.Equal(t, camryHybrid.ID, links[0].ID)
+	assert.Equal(t, "Camry Hybrid", links[0].Mention)
+	assert.Equal(t, accord.ID, links[1].ID)
+	assert.Equal(t, LinkAlias, links[1].Method)
+
+	links, err = linker.Link(ctx, tenant, "What is the towing capacity of the Camry XLE?")
+	require.NoError(t, err)
+	require.Len(t, links, 2)
+	assert.Equal(t, EntityProduct, links[0].Kind)
+	assert.Equal(t, EntityCampaignVariant, links[1].Kind)
+	assert.Equal(t, xle.ID, links[1].ID, "published variant preferred over newer draft")
+	assert.Equal(t, camry.ID, links[1].ProductID)
+
+	links, err = linker.Link(ctx, tenant, "Does the ravvy have AWD? Is the RAV-4 quieter than the Camri?")
+	require.NoError(t, err)
+	require.Len(t, links, 3)
+	assert.Equal(t, rav4.ID, links[0].ID)
+	assert.Equal(t, rav4.ID, links[1].ID)
+	assert.Equal(t, camry.ID, links[2].ID)
+	assert.Equal(t, LinkFuzzy, links[2].Method)
+	assert.Less(t, links[2].Confidence, links[1].Confidence)
+
+	links, err = linker.Link(ctx, tenant, "What is the warranty?")
+	require.NoError(t, err)
+	assert.Empty(t, links)
+}
+
+func TestEntityLinker_ModelYear(t *testing.T) {
+	older := testProduct("Camry", 2023, "")
+	newer := testProduct("Camry", 2024, "")
+	source := &stubCatalog{products: []*storage.Product{older, newer}}
+	linker := NewEntityLinker(source, DefaultLinkerConfig())
+	ctx := context.Background()
+	tenant := uuid.New()
+
+	links, err := linker.Link(ctx, tenant, "Fuel economy of the 2023 Camry")
+	require.NoError(t, err)
+	require.Len(t, links, 1)
+	assert.Equal(t, older.ID, links[0].ID)
+	assert.Equal(t, 1.0, links[0].Confidence)
+
+	links, err = linker.Link(ctx, tenant, "Fuel economy of the Camry")
+	require.NoError(t, err)
+	require.Len(t, links, 1)
+	assert.Equal(t, newer.ID, links[0].ID)
+	assert.Less(t, links[0].Confidence, 1.0)
+
+	// The catalogue is cached until invalidated
+	assert.Equal(t, 1, source.loads)
+	linker.Invalidate(tenant)
+	_, err = linker.Link(ctx, tenant, "Camry")
+	require.NoError(t, err)
+	assert.Equal(t, 2, source.loads)
+}
+
+func TestEditSimilarity(t *testing.T) {
+	assert.Equal(t, 1.0, editSimilarity("camry", "camry"))
+	assert.InDelta(t, 0.8, editSimilarity("camri", "camry"), 1e-9)
+	assert.Less(t, editSimilarity("according", "accord"), 0.8)
+}
+
+func TestRouter_LinksEntities(t *testing.T) {
+	logger := observability.NewLogger(observability.LogConfig{Level: "error"})
+	camry := testProduct("Toyota Camry", 2024, "")
+	xle := testTrim(camry.ID, "XLE", storage.CampaignStatusPublished, 1)
+
+	router := NewRouter(logger, nil, nil, nil, nil, RouterConfig{})
+	router.SetEntityLinker(NewEntityLinker(&stubCatalog{
+		products:  []*storage.Product{camry},
+		campaigns: []*storage.CampaignVariant{xle},
+	}, DefaultLinkerConfig()))
+
+	resp, err := router.Query(context.Background(), RetrievalRequest{
+		TenantID: uuid.New(),
+		Question: "Is the Camry XLE available in blue?",
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Links, 2)
+	assert.True(t, resp.Links[0].Applied)
+	assert.True(t, resp.Links[1].Applied)
+
+	// Caller-supplied IDs are kept
+	supplied := uuid.New()
+	resp, err = router.Query(context.Background(), RetrievalRequest{
+		TenantID:   uuid.New(),
+		ProductIDs: []uuid.UUID{supplied},
+		Question:   "Is the Camry XLE available in blue?",
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Links, 2)
+	assert.False(t, resp.Links[0].Applied)
+	assert.False(t, resp.Links[1].Applied)
+
+	t.Run("invalidation reloads the catalogue", func(t *testing.T) {
+		source := &stubCatalog{products: []*storage.Product{camry}}
+		router := NewRouter(logger, nil, nil, nil, nil, RouterConfig{})
+		router.SetEntityLinker(NewEntityLinker(source, DefaultLinkerConfig()))
+		tenant := uuid.New()
+
+		ask := func() {
+			_, err := router.Query(context.Background(), RetrievalRequest{TenantID: tenant, Question: "Is the Camry available in blue?"})
+			require.NoError(t, err)
+		}
+		ask()
+		ask()
+		assert.Equal(t, 1, source.loads)
+		require.NoError(t, router.ApplyInvalidation(context.Background(), cache.InvalidationEvent{Kind: cache.InvalidationCampaignPublished, TenantID: tenant}))
+		ask()
+		assert.Equal(t, 2, source.loads)
+	})
+}
@@ -0,0 +1,901 @@
+// Package retrieval provides numeric and comparative predicates over spec values.
+package retrieval
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/lexical"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/storage"
+)
+
+// PredicateOp is a numeric comparison parsed from a question.
+type PredicateOp string
+
+const (
+	PredicateGreater   PredicateOp = "gt"
+	PredicateGreaterEq PredicateOp = "gte"
+	PredicateLess      PredicateOp = "lt"
+	PredicateLessEq    PredicateOp = "lte"
+	PredicateBetween   PredicateOp = "between"
+	PredicateMax       PredicateOp = "max"
+	PredicateMin       PredicateOp = "min"
+)
+
+// Dimension is the physical quantity a unit measures. Values are compared in
+// the base unit of their dimension.
+type Dimension string
+
+const (
+	// DimensionCount covers plain numbers such as airbags or seats.
+	DimensionCount      Dimension = ""
+	DimensionLength     Dimension = "length"          // mm
+	DimensionVolume     Dimension = "volume"          // L
+	DimensionMass       Dimension = "mass"            // kg
+	DimensionPower      Dimension = "power"           // hp
+	DimensionTorque     Dimension = "torque"          // Nm
+	DimensionEfficiency Dimension = "fuel_efficiency" // km/l
+	DimensionSpeed      Dimension = "speed"           // km/h
+	DimensionTime       Dimension = "time"            // s
+	DimensionEnergy     Dimension = "energy"          // kWh
+	// DimensionPrice is an amount in an unstated currency, such as "30k".
+	// Amounts in a stated currency carry it in their dimension, since they are
+	// never converted or compared across currencies.
+	DimensionPrice    Dimension = "price"
+	DimensionPriceINR Dimension = "price:inr"
+	DimensionPriceUSD Dimension = "price:usd"
+	DimensionPriceEUR Dimension = "price:eur"
+)
+
+// predicateResultLimit bounds the facts returned for a predicate query.
+const predicateResultLimit = 30
+
+// NumericPredicate is a constraint or ranking over spec values, with Value and
+// Upper expressed in the base unit of Dimension.
+type NumericPredicate struct {
+	Op        PredicateOp
+	Value     float64
+	Upper     float64
+	Unit      string
+	Dimension Dimension
+	// Attribute names the spec the predicate applies to, e.g. "airbags" or "boot".
+	Attribute string
+	// Text is the part of the question the predicate was parsed from.
+	Text string
+}
+
+// unitDef converts a unit to the base unit of its dimension. Inverse units
+// (l/100km) are converted as factor/value.
+type unitDef struct {
+	dimension Dimension
+	factor    float64
+	inverse   bool
+}
+
+var predicateUnits = map[string]unitDef{
+	"mm": {DimensionLength, 1, false}, "millimeter": {DimensionLength, 1, false}, "millimeters": {DimensionLength, 1, false},
+	"millimetre": {DimensionLength, 1, false}, "millimetres": {DimensionLength, 1, false},
+	"cm": {DimensionLength, 10, false}, "m": {DimensionLength, 1000, false}, "meter": {DimensionLength, 1000, false},
+	"meters": {DimensionLength, 1000, false}, "metre": {DimensionLength, 1000, false}, "metres": {DimensionLength, 1000, false},
+	"km": {DimensionLength, 1e6, false}, "kms": {DimensionLength, 1e6, false}, "kilometers": {DimensionLength, 1e6, false},
+	"kilometres": {DimensionLength, 1e6, false}, "inch": {DimensionLength, 25.4, false}, "inches": {DimensionLength, 25.4, false},
+	"ft": {DimensionLength, 304.8, false}, "feet": {DimensionLength, 304.8, false},
+
+	"l": {DimensionVolume, 1, false}, "ltr": {DimensionVolume, 1, false}, "litre": {DimensionVolume, 1, false},
+	"litres": {DimensionVolume, 1, false}, "liter": {DimensionVolume, 1, false}, "liters": {DimensionVolume, 1, false},
+	"ml": {DimensionVolume, 0.001, false}, "cc": {DimensionVolume, 0.001, false},
+	"cu ft": {DimensionVolume, 28.3168, false}, "cubic feet": {DimensionVolume, 28.3168, false},
+
+	"kg": {DimensionMass, 1, false}, "kgs": {DimensionMass, 1, false}, "kilograms": {DimensionMass, 1, false},
+	"lb": {DimensionMass, 0.453592, false}, "lbs": {DimensionMass, 0.453592, false},
+	"tonne": {DimensionMass, 1000, false}, "tonnes": {DimensionMass, 1000, false},
+
+	"hp": {DimensionPower, 1, false}, "bhp": {DimensionPower, 1, false}, "horsepower": {DimensionPower, 1, false},
+	"ps": {DimensionPower, 0.98632, false}, "kw": {DimensionPower, 1.34102, false}, "kilowatts": {DimensionPower, 1.34102, false},
+
+	"nm": {DimensionTorque, 1, false}, "newton meters": {DimensionTorque, 1, false}, "newton metres": {DimensionTorque, 1, false},
+	"lb-ft": {DimensionTorque, 1.35582, false}, "lb ft": {DimensionTorque, 1.35582, false}, "kgm": {DimensionTorque, 9.80665, false},
+
+	"km/l": {DimensionEfficiency, 1, false}, "kmpl": {DimensionEfficiency, 1, false}, "km/litre": {DimensionEfficiency, 1, false},
+	"km/liter": {DimensionEfficiency, 1, false}, "mpg": {DimensionEfficiency, 0.425144, false},
+	"l/100km": {DimensionEfficiency, 100, true}, "l/100 km": {DimensionEfficiency, 100, true},
+
+	"km/h": {DimensionSpeed, 1, false}, "kmph": {DimensionSpeed, 1, false}, "kph": {DimensionSpeed, 1, false},
+	"mph": {DimensionSpeed, 1.60934, false},
+
+	"s": {DimensionTime, 1, false}, "sec": {DimensionTime, 1, false}, "secs": {DimensionTime, 1, false},
+	"seconds": {DimensionTime, 1, false},
+
+	"kwh": {DimensionEnergy, 1, false},
+
+	"lakh": {DimensionPriceINR, 1e5, false}, "lakhs": {DimensionPriceINR, 1e5, false}, "lac": {DimensionPriceINR, 1e5, false},
+	"crore": {DimensionPriceINR, 1e7, false}, "crores": {DimensionPriceINR, 1e7, false}, "cr": {DimensionPriceINR, 1e7, false},
+	"k": {DimensionPrice, 1e3, false}, "thousand": {DimensionPrice, 1e3, false}, "million": {DimensionPrice, 1e6, false},
+	"inr": {DimensionPriceINR, 1, false}, "rs": {DimensionPriceINR, 1, false}, "₹": {DimensionPriceINR, 1, false},
+	"usd": {DimensionPriceUSD, 1, false}, "$": {DimensionPriceUSD, 1, false},
+	"eur": {DimensionPriceEUR, 1, false}, "€": {DimensionPriceEUR, 1, false},
+}
+
+// predicateUnitAliases lists unit spellings longest first so "km/l" wins over "km".
+var predicateUnitAliases = func() []string {
+	aliases := make([]string, 0, len(predicateUnits))
+	for alias := range predicateUnits {
+		aliases = append(aliases, alias)
+	}
+	sort.Slice(aliases, func(i, j int) bool {
+		if len(aliases[i]) != len(aliases[j]) {
+			return len(aliases[i]) > len(aliases[j])
+		}
+		return aliases[i] < aliases[j]
+	})
+	return aliases
+}()
+
+const (
+	predicateNumber   = `(\d[\d,]*(?:\.\d+)?)`
+	predicateCurrency = `(?:(₹|rs\.?|inr|\$|usd|€|eur)\s*)?`
+)
+
+var (
+	betweenRe = regexp.MustCompile(`(?i)\bbetween\s+` + predicateCurrency + predicateNumber +
+		`\s*([^\d]{0,20}?)\s*(?:and|to|-)\s*` + predicateCurrency + predicateNumber)
+	comparatorRe = regexp.MustCompile(`(?i)(>=|<=|>|<|\b(?:more than|greater than|higher than|bigger than|larger than|` +
+		`longer than|over|above|exceeding|in excess of|at least|minimum of|no less than|less than|fewer than|lower than|` +
+		`smaller than|shorter than|cheaper than|under|below|at most|up to|upto|maximum of|no more than|within)\b)\s*` +
+		predicateCurrency + predicateNumber)
+	postfixRe = regexp.MustCompile(`(?i)` + predicateCurrency + predicateNumber +
+		`\s*(?:([a-z]+(?:/[a-z0-9]+)?)\s*)?(\+|\bor (?:more|above|higher|greater|over)\b|\bor (?:less|fewer|below|under|lower)\b)`)
+	predicateWordRe = regexp.MustCompile(`[A-Za-z][A-Za-z'-]*|\d[\d,]*(?:\.\d+)?`)
+)
+
+// comparatorOps maps comparator phrases to operators.
+var comparatorOps = map[string]PredicateOp{
+	">": PredicateGreater, ">=": PredicateGreaterEq, "<": PredicateLess, "<=": PredicateLessEq,
+	"more than": PredicateGreater, "greater than": PredicateGreater, "higher than": PredicateGreater,
+	"bigger than": PredicateGreater, "larger than": PredicateGreater, "longer than": PredicateGreater,
+	"over": PredicateGreater, "above": PredicateGreater, "exceeding": PredicateGreater, "in excess of": PredicateGreater,
+	"at least": PredicateGreaterEq, "minimum of": PredicateGreaterEq, "no less than": PredicateGreaterEq,
+	"less than": PredicateLess, "fewer than": PredicateLess, "lower than": PredicateLess, "smaller than": PredicateLess,
+	"shorter than": PredicateLess, "cheaper than": PredicateLess, "under": PredicateLess, "below": PredicateLess,
+	"at most": PredicateLessEq, "up to": PredicateLessEq, "upto": PredicateLessEq, "maximum of": PredicateLessEq,
+	"no more than": PredicateLessEq, "within": PredicateLessEq,
+}
+
+// superlative describes a superlative word and the attribute it implies, if any.
+type superlative struct {
+	op        PredicateOp
+	attribute string
+}
+
+var superlativeWords = map[string]superlative{
+	"biggest": {PredicateMax, ""}, "largest": {PredicateMax, ""}, "highest": {PredicateMax, ""},
+	"longest": {PredicateMax, ""}, "widest": {PredicateMax, ""}, "tallest": {PredicateMax, ""},
+	"heaviest": {PredicateMax, ""}, "greatest": {PredicateMax, ""}, "maximum": {PredicateMax, ""},
+	"smallest": {PredicateMin, ""}, "lowest": {PredicateMin, ""}, "shortest": {PredicateMin, ""},
+	"lightest": {PredicateMin, ""}, "narrowest": {PredicateMin, ""}, "minimum": {PredicateMin, ""},
+	"cheapest": {PredicateMin, "price"}, "priciest": {PredicateMax, "price"}, "costliest": {PredicateMax, "price"},
+	"fastest": {PredicateMax, "top speed"}, "quickest": {PredicateMin, "acceleration"},
+}
+
+// superlativeAdjectives resolve "most powerful" style phrases.
+var superlativeAdjectives = map[string]string{
+	"powerful": "power", "efficient": "mileage", "economical": "mileage", "expensive": "price",
+	"affordable": "price", "spacious": "boot",
+}
+
+// attributeSynonyms lists other spec names an attribute can appear under.
+var attributeSynonyms = map[string][]string{
+	"boot":      {"trunk", "cargo", "luggage"},
+	"trunk":     {"boot", "cargo", "luggage"},
+	"mileage":   {"fuel efficiency", "fuel economy"},
+	"price":     {"ex-showroom", "cost", "msrp"},
+	"power":     {"horsepower", "output"},
+	"airbags":   {"airbag"},
+	"range":     {"driving range"},
+	"top speed": {"maximum speed"},
+}
+
+// dimensionTerms name specs that carry a dimension when their unit is missing.
+var dimensionTerms = map[Dimension][]string{
+	DimensionPrice:      {"price", "cost", "ex-showroom", "msrp"},
+	DimensionEfficiency: {"mileage", "fuel efficiency", "fuel economy"},
+	DimensionPower:      {"power", "horsepower"},
+	DimensionTorque:     {"torque"},
+	DimensionSpeed:      {"speed"},
+	DimensionVolume:     {"capacity", "boot", "displacement"},
+	DimensionLength:     {"length", "width", "height", "clearance", "wheelbase", "range"},
+	DimensionMass:       {"weight"},
+	DimensionTime:       {"acceleration"},
+	DimensionEnergy:     {"battery"},
+}
+
+// predicateSkipWords are read past when looking for the attribute of a predicate.
+var predicateSkipWords = map[string]bool{"of": true, "is": true, "are": true, "at": true, "a": true, "an": true, "the": true}
+
+// predicateBoundaryWords end the attribute phrase of a predicate.
+var predicateBoundaryWords = map[string]bool{
+	"with": true, "have": true, "has": true, "having": true, "offer": true, "offers": true, "offering": true,
+	"and": true, "or": true, "but": true, "which": true, "that": true, "where": true, "whose": true, "in": true,
+	"for": true, "on": true, "cars": true, "car": true, "trims": true, "trim": true, "models": true,
+	"variants": true, "variant": true, "vehicles": true, "vehicle": true, "options": true, "suvs": true,
+	"suv": true, "sedans": true, "sedan": true, "hatchbacks": true, "ones": true, "one": true, "any": true,
+	"all": true, "than": true, "more": true, "less": true, "most": true, "least": true, "costing": true,
+	"priced": true, "anything": true, "something": true, "everything": true, "under": true, "over": true, "below": true, "above": true, "within": true, "between": true,
+}
+
+// predicateWord is a question word with its byte offsets.
+type predicateWord struct {
+	text       string
+	start, end int
+}
+
+// ParsePredicates extracts numeric comparisons, ranges and superlatives from a
+// question. Words of mentions, the product and trim names linked in the
+// question, are never taken as the attribute of a predicate.
+func ParsePredicates(question string, mentions ...string) []NumericPredicate {
+	words := predicateWords(question)
+	linked := make(map[string]bool)
+	for _, mention := range mentions {
+		for _, w := range predicateWords(mention) {
+			linked[strings.ToLower(w.text)] = true
+		}
+	}
+	var spans [][2]int
+	overlaps := func(start, end int) bool {
+		for _, s := range spans {
+			if start < s[1] && end > s[0] {
+				return true
+			}
+		}
+		return false
+	}
+
+	type parsed struct {
+		pred       NumericPredicate
+		start, end int
+	}
+	var found []parsed
+	add := func(p NumericPredicate, start, end int) {
+		spans = append(spans, [2]int{start, end})
+		found = append(found, parsed{pred: p, start: start, end: end})
+	}
+
+	for _, m := range betweenRe.FindAllStringSubmatchIndex(question, -1) {
+		if overlaps(m[0], m[1]) {
+			continue
+		}
+		lo, okLo := parseNumber(question[m[4]:m[5]])
+		hi, okHi := parseNumber(question[m[10]:m[11]])
+		if !okLo || !okHi {
+			continue
+		}
+		unit, def, consumed := parseUnitPrefix(question[m[1]:])
+		if unit == "" {
+			unit, def, _ = parseUnitPrefix(question[m[6]:m[7]])
+		}
+		end := m[1] + consumed
+		currency := submatch(question, m, 1)
+		if currency == "" {
+			currency = submatch(question, m, 4)
+		}
+		def = withCurrency(def, currency)
+		lo, hi = convertUnit(lo, def), convertUnit(hi, def)
+		if lo > hi {
+			lo, hi = hi, lo
+		}
+		add(NumericPredicate{
+			Op: PredicateBetween, Value: lo, Upper: hi, Unit: unit, Dimension: def.dimension,
+			Text: strings.TrimSpace(question[m[0]:end]),
+		}, m[0], end)
+	}
+
+	for _, m := range comparatorRe.FindAllStringSubmatchIndex(question, -1) {
+		if overlaps(m[0], m[1]) {
+			continue
+		}
+		value, ok := parseNumber(question[m[6]:m[7]])
+		if !ok {
+			continue
+		}
+		op := comparatorOps[strings.ToLower(question[m[2]:m[3]])]
+		unit, def, consumed := parseUnitPrefix(question[m[1]:])
+		end := m[1] + consumed
+		add(newComparison(op, value, unit, withCurrency(def, submatch(question, m, 2)), strings.TrimSpace(question[m[0]:end])), m[0], end)
+	}
+
+	for _, m := range postfixRe.FindAllStringSubmatchIndex(question, -1) {
+		if overlaps(m[0], m[1]) {
+			continue
+		}
+		value, ok := parseNumber(question[m[4]:m[5]])
+		if !ok {
+			continue
+		}
+		unit, def, attribute := "", unitDef{}, ""
+		if m[6] >= 0 {
+			// "6 airbags or more" names the attribute where a unit would be
+			if unit, def, _ = parseUnitPrefix(question[m[6]:m[7]]); unit == "" && isAttributeWord(question[m[6]:m[7]]) {
+				attribute = strings.ToLower(question[m[6]:m[7]])
+			}
+		}
+		op := PredicateGreaterEq
+		if suffix := strings.ToLower(question[m[8]:m[9]]); strings.Contains(suffix, "less") || strings.Contains(suffix, "fewer") ||
+			strings.Contains(suffix, "below") || strings.Contains(suffix, "under") || strings.Contains(suffix, "lower") {
+			op = PredicateLessEq
+		}
+		p := newComparison(op, value, unit, withCurrency(def, submatch(question, m, 1)), strings.TrimSpace(question[m[0]:m[1]]))
+		p.Attribute = attribute
+		add(p, m[0], m[1])
+	}
+
+	for i := 0; i < len(words); i++ {
+		w := words[i]
+		if overlaps(w.start, w.end) {
+			continue
+		}
+		lower := strings.ToLower(w.text)
+
+		var sup superlative
+		next := i + 1
+		switch {
+		case lower == "most" || lower == "least":
+			if next >= len(words) || overlaps(words[next].start, words[next].end) {
+				continue
+			}
+			// "most fuel efficient" reads past the noun to its adjective
+			adjIdx := next
+			if strings.EqualFold(words[next].text, "fuel") && next+1 < len(words) {
+				adjIdx = next + 1
+			}
+			adjective := strings.ToLower(words[adjIdx].text)
+			sup = superlative{op: PredicateMax}
+			if lower == "least" {
+				sup.op = PredicateMin
+			}
+			if attribute, ok := superlativeAdjectives[adjective]; ok {
+				sup.attribute = attribute
+				if adjective == "affordable" {
+					sup.op = PredicateMin
+					if lower == "least" {
+						sup.op = PredicateMax
+					}
+				}
+				next = adjIdx + 1
+			}
+		default:
+			var ok bool
+			if sup, ok = superlativeWords[lower]; !ok {
+				continue
+			}
+		}
+
+		attribute, end := sup.attribute, words[next-1].end
+		if attribute == "" {
+			var last int
+			attribute, last = attributeAfter(question, words, next, linked)
+			if attribute == "" {
+				continue
+			}
+			end = words[last].end
+		}
+		add(NumericPredicate{Op: sup.op, Attribute: attribute, Text: question[w.start:end]}, w.start, end)
+	}
+
+	sort.SliceStable(found, func(i, j int) bool {
+		return found[i].start < found[j].start
+	})
+
+	// Comparisons take their attribute from the words after them, or failing
+	// that the words before them ("boot space over 400 litres")
+	predicates := make([]NumericPredicate, 0, len(found))
+	for i, f := range found {
+		p := f.pred
+		if p.Attribute == "" && p.Op != PredicateMax && p.Op != PredicateMin {
+			next := sort.Search(len(words), func(k int) bool { return words[k].start >= f.end })
+			attribute, _ := attributeAfter(question, words, next, linked)
+			if attribute == "" {
+				lowerBound := 0
+				if i > 0 {
+					lowerBound = found[i-1].end
+				}
+				attribute = attributeBefore(question, words, f.start, lowerBound, linked)
+			}
+			p.Attribute = attribute
+		}
+		if p.Attribute == "" && p.Dimension == DimensionCount {
+			// A bare number with no unit or attribute cannot be evaluated
+			continue
+		}
+		predicates = append(predicates, p)
+	}
+	return predicates
+}
+
+// newComparison builds a comparison, flipping it for inverse units where a
+// lower l/100km means a higher km/l.
+func newComparison(op PredicateOp, value float64, unit string, def unitDef, text string) NumericPredicate {
+	if def.inverse {
+		switch op {
+		case PredicateGreater:
+			op = PredicateLess
+		case PredicateGreaterEq:
+			op = PredicateLessEq
+		case PredicateLess:
+			op = PredicateGreater
+		case PredicateLessEq:
+			op = PredicateGreaterEq
+		}
+	}
+	return NumericPredicate{
+		Op:        op,
+		Value:     convertUnit(value, def),
+		Unit:      unit,
+		Dimension: def.dimension,
+		Text:      text,
+	}
+}
+
+// withCurrency treats a currency-prefixed number as a price in that currency,
+// so "$30k" is 30,000 USD.
+func withCurrency(def unitDef, currency string) unitDef {
+	if currency == "" {
+		return def
+	}
+	dimension := predicateUnits[strings.TrimSuffix(strings.ToLower(currency), ".")].dimension
+	if !isPriceDimension(def.dimension) {
+		return unitDef{dimension: dimension, factor: 1}
+	}
+	def.dimension = dimension
+	return def
+}
+
+// isPriceDimension reports whether a dimension is an amount of money.
+func isPriceDimension(d Dimension) bool {
+	return d == DimensionPrice || strings.HasPrefix(string(d), string(DimensionPrice)+":")
+}
+
+// baseDimension drops the currency of a price dimension.
+func baseDimension(d Dimension) Dimension {
+	if isPriceDimension(d) {
+		return DimensionPrice
+	}
+	return d
+}
+
+// sameDimension reports whether values of two dimensions can be compared. A
+// price in an unstated currency compares with any price, but prices in two
+// stated currencies never compare.
+func sameDimension(a, b Dimension) bool {
+	if a == b {
+		return true
+	}
+	return (a == DimensionPrice && isPriceDimension(b)) || (b == DimensionPrice && isPriceDimension(a))
+}
+
+// submatch returns the text of a regexp group, or "" if it did not match.
+func submatch(text string, m []int, group int) string {
+	if m[2*group] < 0 {
+		return ""
+	}
+	return text[m[2*group]:m[2*group+1]]
+}
+
+// convertUnit converts a value to the base unit of its dimension.
+func convertUnit(value float64, def unitDef) float64 {
+	switch {
+	case def.factor == 0:
+		return value
+	case def.inverse:
+		if value == 0 {
+			return 0
+		}
+		return def.factor / value
+	default:
+		return value * def.factor
+	}
+}
+
+// parseUnitPrefix reads a unit at the start of text. It returns the unit as
+// written, its definition and the number of bytes consumed.
+func parseUnitPrefix(text string) (string, unitDef, int) {
+	trimmed := strings.TrimLeft(text, " ")
+	offset := len(text) - len(trimmed)
+	lower := strings.ToLower(trimmed)
+	for _, alias := range predicateUnitAliases {
+		if !strings.HasPrefix(lower, alias) {
+			continue
+		}
+		if len(lower) > len(alias) && isAlphaNumeric(lower[len(alias)]) {
+			continue
+		}
+		return trimmed[:len(alias)], predicateUnits[alias], offset + len(alias)
+	}
+	return "", unitDef{}, 0
+}
+
+func isAlphaNumeric(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+func parseNumber(text string) (float64, bool) {
+	value, err := strconv.ParseFloat(strings.ReplaceAll(text, ",", ""), 64)
+	return value, err == nil
+}
+
+func predicateWords(text string) []predicateWord {
+	var words []predicateWord
+	for _, loc := range predicateWordRe.FindAllStringIndex(text, -1) {
+		words = append(words, predicateWord{text: text[loc[0]:loc[1]], start: loc[0], end: loc[1]})
+	}
+	return words
+}
+
+// isAttributeWord reports whether a word can be part of an attribute phrase.
+func isAttributeWord(word string) bool {
+	lower := strings.ToLower(word)
+	if predicateBoundaryWords[lower] || predicateSkipWords[lower] || rewriteStopWords[lower] {
+		return false
+	}
+	if _, ok := predicateUnits[lower]; ok {
+		return false
+	}
+	_, isNumber := parseNumber(lower)
+	return !isNumber
+}
+
+// attributeAfter collects up to three attribute words starting at words[i],
+// skipping leading filler and stopping at linked names. It returns the phrase
+// and the index of its last word.
+func attributeAfter(text string, words []predicateWord, i int, linked map[string]bool) (string, int) {
+	for i < len(words) && predicateSkipWords[strings.ToLower(words[i].text)] {
+		i++
+	}
+	var phrase []string
+	last := -1
+	for j := i; j < len(words) && len(phrase) < 3; j++ {
+		if j > i && strings.TrimSpace(text[words[j-1].end:words[j].start]) != "" {
+			break
+		}
+		if !isAttributeWord(words[j].text) || linked[strings.ToLower(words[j].text)] {
+			break
+		}
+		phrase = append(phrase, strings.ToLower(words[j].text))
+		last = j
+	}
+	return strings.Join(phrase, " "), last
+}
+
+// attributeBefore collects up to three attribute words ending before offset
+// start and after offset lowerBound, reading past linked names. A phrase that
+// is the object of "of" gives way to the spec noun before it, so "range of
+// the EV" is about range.
+func attributeBefore(text string, words []predicateWord, start, lowerBound int, linked map[string]bool) string {
+	var phrase []string
+	end, first := start, -1
+	for j := len(words) - 1; j >= 0 && len(phrase) < 3; j-- {
+		w := words[j]
+		if w.end > start {
+			continue
+		}
+		if w.start < lowerBound {
+			break
+		}
+		if strings.TrimSpace(text[w.end:end]) != "" && len(phrase) > 0 {
+			break
+		}
+		lower := strings.ToLower(w.text)
+		if (predicateSkipWords[lower] || linked[lower]) && len(phrase) == 0 {
+			end = w.start
+			continue
+		}
+		if !isAttributeWord(w.text) || linked[lower] {
+			break
+		}
+		phrase = append([]string{lower}, phrase...)
+		end, first = w.start, j
+	}
+
+	for k := first - 1; first >= 0 && k >= 0 && words[k].start >= lowerBound; k-- {
+		lower := strings.ToLower(words[k].text)
+		if lower == "of" {
+			if head := attributeBefore(text, words, words[k].start, lowerBound, linked); head != "" {
+				return head
+			}
+			break
+		}
+		if !predicateSkipWords[lower] {
+			break
+		}
+	}
+	return strings.Join(phrase, " ")
+}
+
+// satisfiedBy reports whether a value in base units meets the predicate.
+func (p NumericPredicate) satisfiedBy(value float64) bool {
+	switch p.Op {
+	case PredicateGreater:
+		return value > p.Value
+	case PredicateGreaterEq:
+		return value >= p.Value
+	case PredicateLess:
+		return value < p.Value
+	case PredicateLessEq:
+		return value <= p.Value
+	case PredicateBetween:
+		return value >= p.Value && value <= p.Upper
+	default:
+		return true
+	}
+}
+
+// descending reports whether larger values rank first.
+func (p NumericPredicate) descending() bool {
+	return p.Op == PredicateMax || p.Op == PredicateGreater || p.Op == PredicateGreaterEq
+}
+
+// specQuantity returns a spec value in base units, preferring ValueNumeric
+// over parsing the text value.
+func specQuantity(sv storage.SpecViewLatest) (float64, Dimension, bool) {
+	unit := ""
+	if sv.Unit != nil {
+		unit = strings.TrimSpace(*sv.Unit)
+	}
+
+	var (
+		value    float64
+		def      unitDef
+		currency string
+	)
+	if sv.ValueNumeric != nil {
+		value = *sv.ValueNumeric
+	} else {
+		m := quantityRe.FindStringSubmatchIndex(sv.Value)
+		if m == nil {
+			return 0, DimensionCount, false
+		}
+		parsed, ok := parseNumber(sv.Value[m[4]:m[5]])
+		if !ok {
+			return 0, DimensionCount, false
+		}
+		value, currency = parsed, submatch(sv.Value, m, 1)
+		if unit == "" {
+			_, def, _ = parseUnitPrefix(sv.Value[m[1]:])
+		}
+	}
+	if unit != "" {
+		if d, ok := predicateUnits[strings.ToLower(unit)]; ok {
+			def = d
+		}
+	}
+	def = withCurrency(def, currency)
+	return convertUnit(value, def), def.dimension, true
+}
+
+var quantityRe = regexp.MustCompile(`(?i)` + predicateCurrency + predicateNumber)
+
+// predicateMatch is a spec value evaluated against one predicate.
+type predicateMatch struct {
+	spec   storage.SpecViewLatest
+	origin storage.ValueOrigin
+	value  float64
+}
+
+// queryPredicates answers questions with numeric predicates from spec values in
+// scope. It reports false when no predicate could be evaluated, so routing
+// continues with the other retrieval paths.
+func (r *Router) queryPredicates(ctx context.Context, req RetrievalRequest, response *RetrievalResponse, predicates []NumericPredicate) bool {
+	index, ok := r.searchIndex(ctx, req)
+	if !ok {
+		return false
+	}
+
+	scope := r.resolveCampaignScope(ctx, req)
+	q := lexical.Query{
+		TenantID:   req.TenantID,
+		ProductIDs: req.ProductIDs,
+		Kinds:      []lexical.Kind{lexical.KindSpec},
+	}
+	if scope.campaignID != nil {
+		q.CampaignVariantIDs = scope.chain
+	}
+
+	analyzer := index.Analyzer()
+	docs := index.Documents(q)
+
+	// Matches per predicate, keyed by campaign variant
+	matches := make([]map[uuid.UUID][]predicateMatch, len(predicates))
+	for i, p := range predicates {
+		matches[i] = make(map[uuid.UUID][]predicateMatch)
+		applicable := 0
+		currencies := make(map[Dimension]bool)
+		for _, doc := range docs {
+			sv, ok := doc.Payload.(storage.SpecViewLatest)
+			if !ok {
+				continue
+			}
+			origin, ok := scope.accept(sv)
+			if !ok {
+				continue
+			}
+			value, dimension, ok := specQuantity(sv)
+			if !ok || !predicateApplies(analyzer, p, sv, dimension) {
+				continue
+			}
+			applicable++
+			if dimension != DimensionPrice && isPriceDimension(dimension) {
+				currencies[dimension] = true
+			}
+			if p.satisfiedBy(value) {
+				matches[i][sv.CampaignVariantID] = append(matches[i][sv.CampaignVariantID], predicateMatch{spec: sv, origin: origin, value: value})
+			}
+		}
+		if applicable == 0 {
+			r.logger.Debug().Str("predicate", p.Text).Msg("No spec values for predicate")
+			traceFrom(ctx).filter("predicate", p.Text, false, "no spec values the predicate applies to")
+			return false
+		}
+		if len(currencies) > 1 {
+			// Amounts in different currencies cannot be compared or ranked together
+			r.logger.Debug().Str("predicate", p.Text).Int("currencies", len(currencies)).Msg("Predicate spans currencies")
+			traceFrom(ctx).filter("predicate", p.Text, false, "spec values in more than one currency")
+			return false
+		}
+		traceFrom(ctx).filter("predicate", p.Text, true, fmt.Sprintf("%d of %d applicable values satisfy it", countMatches(matches[i]), applicable))
+	}
+
+	// Variants must satisfy every predicate; rank them by the first superlative,
+	// or else the first predicate
+	primary := 0
+	for i, p := range predicates {
+		if p.Op == PredicateMax || p.Op == PredicateMin {
+			primary = i
+			break
+		}
+	}
+
+	type rankedVariant struct {
+		id    uuid.UUID
+		value float64
+	}
+	var variants []rankedVariant
+	for id, primaryMatches := range matches[primary] {
+		qualifies := true
+		for i := range predicates {
+			if len(matches[i][id]) == 0 {
+				qualifies = false
+				break
+			}
+		}
+		if !qualifies {
+			continue
+		}
+		best := primaryMatches[0].value
+		for _, m := range primaryMatches[1:] {
+			if predicates[primary].descending() == (m.value > best) {
+				best = m.value
+			}
+		}
+		variants = append(variants, rankedVariant{id: id, value: best})
+	}
+	sort.Slice(variants, func(i, j int) bool {
+		if variants[i].value != variants[j].value {
+			return predicates[primary].descending() == (variants[i].value > variants[j].value)
+		}
+		return variants[i].id.String() < variants[j].id.String()
+	})
+
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, v := range variants {
+		lo, hi = math.Min(lo, v.value), math.Max(hi, v.value)
+	}
+
+	var facts []SpecFact
+	seen := make(map[uuid.UUID]bool)
+	order := append([]int{primary}, indexesExcept(len(predicates), primary)...)
+	for _, v := range variants {
+		score := 1.0
+		if hi > lo {
+			score = (v.value - lo) / (hi - lo)
+			if !predicates[primary].descending() {
+				score = 1 - score
+			}
+		}
+		for _, i := range order {
+			for _, m := range matches[i][v.id] {
+				if seen[m.spec.ID] {
+					continue
+				}
+				seen[m.spec.ID] = true
+				facts = append(facts, specViewFact(m.spec, m.origin, score))
+				traceFrom(ctx).candidate(CandidateScore{
+					Kind:       EvidenceSpecFact,
+					ID:         m.spec.SpecItemID,
+					Label:      m.spec.CategoryName + " > " + m.spec.SpecName + ": " + m.spec.Value,
+					Source:     SourcePredicate,
+					Score:      score,
+					Components: map[string]float64{"value": m.value, "normalized": score},
+				})
+			}
+		}
+	}
+	if len(facts) > predicateResultLimit {
+		facts = facts[:predicateResultLimit]
+	}
+
+	response.StructuredFacts = facts
+	response.Predicates = predicates
+
+	r.logger.Debug().
+		Int("predicates", len(predicates)).
+		Int("variants", len(variants)).
+		Int("facts", len(facts)).
+		Msg("Evaluated numeric predicates")
+	return true
+}
+
+// countMatches counts the matched values across variants.
+func countMatches(byVariant map[uuid.UUID][]predicateMatch) int {
+	n := 0
+	for _, m := range byVariant {
+		n += len(m)
+	}
+	return n
+}
+
+// predicateApplies reports whether a spec value is what the predicate constrains:
+// its name must match the attribute, and its unit must measure the same dimension.
+func predicateApplies(analyzer *lexical.Analyzer, p NumericPredicate, sv storage.SpecViewLatest, dimension Dimension) bool {
+	terms := make(map[string]bool)
+	for _, t := range analyzer.Terms(sv.CategoryName + " " + sv.SpecName) {
+		terms[t] = true
+	}
+	containsAll := func(phrase string) bool {
+		phraseTerms := analyzer.Terms(phrase)
+		if len(phraseTerms) == 0 {
+			return false
+		}
+		for _, t := range phraseTerms {
+			if !terms[t] {
+				return false
+			}
+		}
+		return true
+	}
+	containsAny := func(phrases []string) bool {
+		for _, phrase := range phrases {
+			if containsAll(phrase) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if p.Attribute != "" {
+		if !containsAll(p.Attribute) && !containsAny(attributeSynonyms[p.Attribute]) {
+			return false
+		}
+		// A unitless spec is assumed to be in the base unit
+		return p.Dimension == DimensionCount || dimension == DimensionCount || sameDimension(dimension, p.Dimension)
+	}
+
+	if sameDimension(dimension, p.Dimension) {
+		return true
+	}
+	return dimension == DimensionCount && containsAny(dimensionTerms[baseDimension(p.Dimension)])
+}
+
+func indexesExcept(n, skip int) []int {
+	indexes := make([]int, 0, n)
+	for i := 0; i < n; i++ {
+		if i != skip {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
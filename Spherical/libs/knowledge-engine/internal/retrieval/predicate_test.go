@@ -0,0 +1,245 @@
+package retrieval
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/lexical"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/observability"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePredicates(t *testing.T) {
+	tests := []struct {
+		question string
+		mentions []string
+		expected []NumericPredicate
+	}{
+		{
+			question: "Which trims have more than 6 airbags?",
+			expected: []NumericPredicate{{Op: PredicateGreater, Value: 6, Attribute: "airbags"}},
+		},
+		{
+			question: "cars under 15 lakh with over 20 km/l",
+			expected: []NumericPredicate{
+				{Op: PredicateLess, Value: 1.5e6, Unit: "lakh", Dimension: DimensionPriceINR},
+				{Op: PredicateGreater, Value: 20, Unit: "km/l", Dimension: DimensionEfficiency},
+			},
+		},
+		{
+			question: "What's the biggest boot?",
+			expected: []NumericPredicate{{Op: PredicateMax, Attribute: "boot"}},
+		},
+		{
+			question: "boot space of at least 400 litres",
+			expected: []NumericPredicate{{Op: PredicateGreaterEq, Value: 400, Unit: "litres", Dimension: DimensionVolume, Attribute: "boot space"}},
+		},
+		{
+			question: "Ground clearance between 17 and 20 cm",
+			expected: []NumericPredicate{{Op: PredicateBetween, Value: 170, Upper: 200, Unit: "cm", Dimension: DimensionLength, Attribute: "ground clearance"}},
+		},
+		{
+			question: "Anything under 5 l/100km?",
+			expected: []NumericPredicate{{Op: PredicateGreater, Value: 20, Unit: "l/100km", Dimension: DimensionEfficiency}},
+		},
+		{
+			question: "Which has 6 airbags or more?",
+			expected: []NumericPredicate{{Op: PredicateGreaterEq, Value: 6, Attribute: "airbags"}},
+		},
+		{
+			question: "Which is the most fuel efficient?",
+			expected: []NumericPredicate{{Op: PredicateMax, Attribute: "mileage"}},
+		},
+		{
+			question: "What is the cheapest trim?",
+			expected: []NumericPredicate{{Op: PredicateMin, Attribute: "price"}},
+		},
+		{question: "What is the fuel tank capacity?"},
+		{
+			question: "Is it available in at least 3 colours",
+			expected: []NumericPredicate{{Op: PredicateGreaterEq, Value: 3, Attribute: "colours"}},
+		},
+		{
+			question: "price of the XLE under 30000",
+			mentions: []string{"XLE"},
+			expected: []NumericPredicate{{Op: PredicateLess, Value: 30000, Attribute: "price"}},
+		},
+		{
+			question: "Camry XLE price under $30k",
+			mentions: []string{"Camry XLE"},
+			expected: []NumericPredicate{{Op: PredicateLess, Value: 30000, Unit: "k", Dimension: DimensionPriceUSD, Attribute: "price"}},
+		},
+		{
+			question: "range of the EV over 400 km",
+			expected: []NumericPredicate{{Op: PredicateGreater, Value: 4e8, Unit: "km", Dimension: DimensionLength, Attribute: "range"}},
+		},
+		{
+			question: "priced between €25,000 and 30,000",
+			expected: []NumericPredicate{{Op: PredicateBetween, Value: 25000, Upper: 30000, Dimension: DimensionPriceEUR}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.question, func(t *testing.T) {
+			got := ParsePredicates(tt.question, tt.mentions...)
+			require.Len(t, got, len(tt.expected))
+			for i, want := range tt.expected {
+				assert.Equal(t, want.Op, got[i].Op)
+				assert.InDelta(t, want.Value, got[i].Value, 1e-9)
+				assert.InDelta(t, want.Upper, got[i].Upper, 1e-9)
+				assert.Equal(t, want.Unit, got[i].Unit)
+				assert.Equal(t, want.Dimension, got[i].Dimension)
+				assert.Equal(t, want.Attribute, got[i].Attribute)
+			}
+		})
+	}
+}
+
+func TestSpecQuantity(t *testing.T) {
+	numeric := 21.5
+	unit := "kmpl"
+	value, dimension, ok := specQuantity(storage.SpecViewLatest{Value: "21.5", ValueNumeric: &numeric, Unit: &unit})
+	require.True(t, ok)
+	assert.Equal(t, DimensionEfficiency, dimension)
+	assert.InDelta(t, 21.5, value, 1e-9)
+
+	value, dimension, ok = specQuantity(storage.SpecViewLatest{Value: "₹ 12.5 lakh"})
+	require.True(t, ok)
+	assert.Equal(t, DimensionPriceINR, dimension)
+	assert.InDelta(t, 1.25e6, value, 1e-6)
+
+	value, dimension, ok = specQuantity(storage.SpecViewLatest{Value: "$28,500"})
+	require.True(t, ok)
+	assert.Equal(t, DimensionPriceUSD, dimension)
+	assert.InDelta(t, 28500, value, 1e-6)
+
+	_, _, ok = specQuantity(storage.SpecViewLatest{Value: "Yes"})
+	assert.False(t, ok)
+}
+
+func TestRouter_NumericPredicates(t *testing.T) {
+	logger := observability.NewLogger(observability.LogConfig{Level: "error"})
+	router := NewRouter(logger, nil, nil, nil, nil, RouterConfig{StructuredFirst: true})
+	tenant := uuid.New()
+	base, top, rival := uuid.New(), uuid.New(), uuid.New()
+
+	spec := func(campaign uuid.UUID, name, value string, numeric *float64, unit string) {
+		sv := storage.SpecViewLatest{
+			ID:                uuid.New(),
+			TenantID:          tenant,
+			ProductID:         campaign,
+			CampaignVariantID: campaign,
+			CategoryName:      "Specifications",
+			SpecName:          name,
+			Value:             value,
+			ValueNumeric:      numeric,
+		}
+		if unit != "" {
+			sv.Unit = &unit
+		}
+		router.LexicalIndex().Upsert(lexical.SpecDocument(sv))
+	}
+	num := func(v float64) *float64 { return &v }
+
+	spec(base, "Airbags", "6", num(6), "")
+	spec(base, "Ex-showroom Price", "1250000", num(1250000), "INR")
+	spec(base, "Mileage", "22 km/l", nil, "")
+	spec(base, "Boot Space", "420", num(420), "L")
+	spec(top, "Airbags", "7", num(7), "")
+	spec(top, "Ex-showroom Price", "₹ 16 lakh", nil, "")
+	spec(top, "Mileage", "24.1", num(24.1), "km/l")
+	spec(top, "Boot Space", "15.1 cu ft", nil, "")
+	spec(rival, "Airbags", "8", num(8), "")
+	spec(rival, "Ex-showroom Price", "14.2", num(14.2), "lakh")
+	spec(rival, "Mileage", "18", num(18), "kmpl")
+	spec(rival, "Boot Space", "380", num(380), "litres")
+	router.LexicalIndex().MarkLoaded(tenant)
+
+	query := func(question string) *RetrievalResponse {
+		resp, err := router.Query(context.Background(), RetrievalRequest{TenantID: tenant, Question: question})
+		require.NoError(t, err)
+		return resp
+	}
+
+	resp := query("Which trims have more than 6 airbags?")
+	require.Len(t, resp.Predicates, 1)
+	require.Len(t, resp.StructuredFacts, 2)
+	assert.Equal(t, rival, resp.StructuredFacts[0].CampaignVariantID)
+	assert.Equal(t, top, resp.StructuredFacts[1].CampaignVariantID)
+	assert.Equal(t, 1.0, resp.StructuredFacts[0].Score)
+
+	resp = query("cars under 15 lakh with over 20 km/l")
+	require.Len(t, resp.Predicates, 2)
+	require.Len(t, resp.StructuredFacts, 2)
+	assert.Equal(t, base, resp.StructuredFacts[0].CampaignVariantID)
+	assert.Equal(t, "Ex-showroom Price", resp.StructuredFacts[0].Name)
+	assert.Equal(t, "Mileage", resp.StructuredFacts[1].Name)
+
+	// 15.1 cu ft is about 428 L, so the top trim has the biggest boot
+	resp = query("What's the biggest boot?")
+	require.Len(t, resp.StructuredFacts, 3)
+	assert.Equal(t, top, resp.StructuredFacts[0].CampaignVariantID)
+	assert.Equal(t, base, resp.StructuredFacts[1].CampaignVariantID)
+	assert.Equal(t, rival, resp.StructuredFacts[2].CampaignVariantID)
+
+	// Predicates on specs the tenant does not have fall through to normal routing
+	resp = query("Which trims have more than 4 speakers?")
+	assert.Empty(t, resp.Predicates)
+}
+
+func TestRouter_PricePredicatesByCurrency(t *testing.T) {
+	logger := observability.NewLogger(observability.LogConfig{Level: "error"})
+	router := NewRouter(logger, nil, nil, nil, nil, RouterConfig{StructuredFirst: true})
+	tenant := uuid.New()
+	camry := testProduct("Toyota Camry", 2024, "")
+	xle := testTrim(camry.ID, "XLE", storage.CampaignStatusPublished, 1)
+	le := testTrim(camry.ID, "LE", storage.CampaignStatusPublished, 1)
+	rival := uuid.New()
+	router.SetEntityLinker(NewEntityLinker(&stubCatalog{
+		products:  []*storage.Product{camry},
+		campaigns: []*storage.CampaignVariant{xle, le},
+	}, DefaultLinkerConfig()))
+
+	price := func(productID, campaign uuid.UUID, value string) {
+		router.LexicalIndex().Upsert(lexical.SpecDocument(storage.SpecViewLatest{
+			ID:                uuid.New(),
+			TenantID:          tenant,
+			ProductID:         productID,
+			CampaignVariantID: campaign,
+			CategoryName:      "Pricing",
+			SpecName:          "Price",
+			Value:             value,
+		}))
+	}
+	price(camry.ID, xle.ID, "$28,500")
+	price(camry.ID, le.ID, "$26,000")
+	price(rival, rival, "₹ 16 lakh")
+	router.LexicalIndex().MarkLoaded(tenant)
+
+	query := func(question string) *RetrievalResponse {
+		resp, err := router.Query(context.Background(), RetrievalRequest{TenantID: tenant, Question: question})
+		require.NoError(t, err)
+		return resp
+	}
+
+	// 16 lakh rupees is below two million, but is not an amount in dollars
+	resp := query("Which trims are under $2,000,000?")
+	require.Len(t, resp.Predicates, 1)
+	require.Len(t, resp.StructuredFacts, 2)
+	assert.Equal(t, le.ID, resp.StructuredFacts[0].CampaignVariantID)
+	assert.Equal(t, xle.ID, resp.StructuredFacts[1].CampaignVariantID)
+
+	// Prices in several currencies are not ranked against each other
+	resp = query("What is the cheapest trim?")
+	assert.Empty(t, resp.Predicates)
+
+	// The linked product and trim are not taken for the attribute
+	resp = query("Is the Camry XLE price under 30000?")
+	require.Len(t, resp.Predicates, 1)
+	assert.Equal(t, "price", resp.Predicates[0].Attribute)
+	require.Len(t, resp.StructuredFacts, 1)
+	assert.Equal(t, "$28,500", resp.StructuredFacts[0].Value)
+}
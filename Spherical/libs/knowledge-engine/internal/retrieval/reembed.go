@@ -0,0 +1,575 @@
+// Package retrieval provides background re-embedding of chunks for embedding model upgrades.
+package retrieval
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/embedding"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/observability"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/storage"
+)
+
+// ErrReembedJobRunning is returned when resuming or rolling back a job that is still running.
+var ErrReembedJobRunning = errors.New("re-embedding job is running")
+
+// errReembedCancelRequested stops a job whose cancellation was requested
+// through the store, possibly by another process.
+var errReembedCancelRequested = errors.New("re-embedding job cancellation requested")
+
+// ChunkSource pages through the chunks a re-embedding job covers and records
+// the embedding version they are indexed with. ListUpdatedSince finds the
+// chunks created or updated while the job ran.
+// storage.KnowledgeChunkRepository implements it.
+type ChunkSource interface {
+	ListAfter(ctx context.Context, tenantID uuid.UUID, campaignID *uuid.UUID, after uuid.UUID, limit int) ([]*storage.KnowledgeChunk, error)
+	ListUpdatedSince(ctx context.Context, tenantID uuid.UUID, campaignID *uuid.UUID, since time.Time, after uuid.UUID, limit int) ([]*storage.KnowledgeChunk, error)
+	Count(ctx context.Context, tenantID uuid.UUID, campaignID *uuid.UUID) (int64, error)
+	SetEmbeddingVersion(ctx context.Context, tenantID uuid.UUID, campaignID *uuid.UUID, model, version *string) error
+}
+
+// ReembedStore persists re-embedding jobs, their checkpoints and requests to
+// cancel them. storage.ReembedJobRepository implements it.
+type ReembedStore interface {
+	Save(ctx context.Context, job *storage.ReembedJob) error
+	Get(ctx context.Context, id uuid.UUID) (*storage.ReembedJob, error)
+	SetCancelRequested(ctx context.Context, id uuid.UUID, requested bool) error
+}
+
+// EmbeddingStore persists the vectors re-embedding jobs write to a shadow
+// index, per embedding version, so the index can be refilled when it is
+// reopened after a restart. storage.ChunkEmbeddingRepository implements it.
+type EmbeddingStore interface {
+	Save(ctx context.Context, model, version string, vectors map[uuid.UUID][]float32) error
+	ListVersion(ctx context.Context, version string, after uuid.UUID, limit int) ([]*storage.KnowledgeChunk, error)
+}
+
+// ReembedConfig bounds how fast a re-embedding job calls the embedding provider.
+type ReembedConfig struct {
+	// BatchSize is the number of chunks embedded per call (default 64).
+	BatchSize int
+	// BatchesPerSecond paces calls to the provider; zero disables pacing.
+	BatchesPerSecond float64
+}
+
+// ReembedRequest describes a re-embedding job. Chunks are embedded with
+// Embedder into Index, a shadow index that serves no queries until the job
+// completes and retrieval switches to it.
+type ReembedRequest struct {
+	TenantID uuid.UUID
+	// CampaignID restricts the job to one campaign's chunks; nil re-embeds the whole tenant.
+	CampaignID *uuid.UUID
+	// Version labels the new vectors, e.g. "text-embedding-3-large@2".
+	Version  string
+	Embedder embedding.Embedder
+	Index    VectorAdapter
+}
+
+// Reembedder re-embeds a tenant's or campaign's chunks with a new embedding
+// model in the background, then switches the router's vector search over to
+// the new index. Progress is checkpointed after every batch, so an interrupted
+// job resumes where it stopped, and a completed job can be rolled back.
+// Chunks are paged in ID order; chunks created or updated meanwhile may sort
+// before the checkpoint, so once paging ends they are swept up by update time
+// until a sweep finds none.
+type Reembedder struct {
+	logger *observability.Logger
+	router *Router
+	chunks ChunkSource
+	jobs   ReembedStore
+	stored EmbeddingStore
+	cfg    ReembedConfig
+
+	mu      sync.Mutex
+	running map[uuid.UUID]*reembedRun
+}
+
+// reembedRun is a job running in the background.
+type reembedRun struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewReembedder creates a re-embedder that switches router's indexes.
+func NewReembedder(logger *observability.Logger, router *Router, chunks ChunkSource, jobs ReembedStore, cfg ReembedConfig) *Reembedder {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 64
+	}
+	return &Reembedder{
+		logger:  logger,
+		router:  router,
+		chunks:  chunks,
+		jobs:    jobs,
+		cfg:     cfg,
+		running: make(map[uuid.UUID]*reembedRun),
+	}
+}
+
+// SetEmbeddingStore persists the vectors of every batch in store before the
+// batch is checkpointed.
+func (m *Reembedder) SetEmbeddingStore(store EmbeddingStore) {
+	m.stored = store
+}
+
+// Start records a new job and re-embeds its chunks in the background. The
+// job keeps running after ctx is cancelled; use Cancel to pause it.
+func (m *Reembedder) Start(ctx context.Context, req ReembedRequest) (*storage.ReembedJob, error) {
+	if req.Embedder == nil || req.Index == nil {
+		return nil, fmt.Errorf("re-embedding needs an embedder and a shadow index")
+	}
+	if req.Version == "" {
+		return nil, fmt.Errorf("re-embedding needs an embedding version")
+	}
+
+	job := &storage.ReembedJob{
+		TenantID:          req.TenantID,
+		CampaignVariantID: req.CampaignID,
+		EmbeddingModel:    req.Embedder.Model(),
+		EmbeddingVersion:  req.Version,
+		Status:            storage.ReembedStatusPending,
+		StartedAt:         time.Now(),
+	}
+	if previous := m.router.ActiveEmbeddingIndex(req.TenantID, req.CampaignID); previous != nil {
+		model := previous.Embedder.Model()
+		job.PreviousEmbeddingModel = &model
+		job.PreviousEmbeddingVersion = &previous.Version
+	} else if m.router.embedder != nil {
+		model := m.router.embedder.Model()
+		job.PreviousEmbeddingModel = &model
+	}
+	if err := m.jobs.Save(ctx, job); err != nil {
+		return nil, err
+	}
+
+	if err := m.launch(ctx, job, req); err != nil {
+		return nil, err
+	}
+	snapshot := *job
+	return &snapshot, nil
+}
+
+// Resume continues a paused, failed or interrupted job from its last
+// checkpoint. embedder must use the job's model, and index should be the
+// job's shadow index; if it holds fewer vectors than the job processed, for
+// example because it lived in memory, the job starts over.
+func (m *Reembedder) Resume(ctx context.Context, jobID uuid.UUID, embedder embedding.Embedder, index VectorAdapter) (*storage.ReembedJob, error) {
+	if embedder == nil || index == nil {
+		return nil, fmt.Errorf("re-embedding needs an embedder and a shadow index")
+	}
+	job, err := m.jobs.Get(ctx, jobID)
+	if err != nil {
+		return nil, fmt.Errorf("load reembed job: %w", err)
+	}
+	switch job.Status {
+	case storage.ReembedStatusCompleted, storage.ReembedStatusRolledBack:
+		return nil, fmt.Errorf("re-embedding job %s is %s", jobID, job.Status)
+	}
+	if embedder.Model() != job.EmbeddingModel {
+		return nil, fmt.Errorf("re-embedding job %s uses model %s, not %s", jobID, job.EmbeddingModel, embedder.Model())
+	}
+	if job.CancelRequested {
+		if err := m.jobs.SetCancelRequested(ctx, jobID, false); err != nil {
+			return nil, err
+		}
+		job.CancelRequested = false
+	}
+
+	err = m.launch(ctx, job, ReembedRequest{
+		TenantID:   job.TenantID,
+		CampaignID: job.CampaignVariantID,
+		Version:    job.EmbeddingVersion,
+		Embedder:   embedder,
+		Index:      index,
+	})
+	if err != nil {
+		return nil, err
+	}
+	snapshot := *job
+	return &snapshot, nil
+}
+
+// Cancel pauses a running job at its next batch. It reports whether the job was running.
+func (m *Reembedder) Cancel(jobID uuid.UUID) bool {
+	m.mu.Lock()
+	run, ok := m.running[jobID]
+	m.mu.Unlock()
+	if ok {
+		run.cancel()
+	}
+	return ok
+}
+
+// RequestCancel pauses a job at its next batch, whether this re-embedder or
+// another process runs it. The job can be resumed later.
+func (m *Reembedder) RequestCancel(ctx context.Context, jobID uuid.UUID) error {
+	if m.Cancel(jobID) {
+		return nil
+	}
+	job, err := m.jobs.Get(ctx, jobID)
+	if err != nil {
+		return fmt.Errorf("load reembed job: %w", err)
+	}
+	switch job.Status {
+	case storage.ReembedStatusPending, storage.ReembedStatusRunning:
+	default:
+		return fmt.Errorf("re-embedding job %s is %s, not running", jobID, job.Status)
+	}
+	return m.jobs.SetCancelRequested(ctx, jobID, true)
+}
+
+// Wait blocks until a job started by this re-embedder stops running, then
+// returns its final state.
+func (m *Reembedder) Wait(ctx context.Context, jobID uuid.UUID) (*storage.ReembedJob, error) {
+	m.mu.Lock()
+	run, ok := m.running[jobID]
+	m.mu.Unlock()
+	if ok {
+		select {
+		case <-run.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return m.jobs.Get(ctx, jobID)
+}
+
+// Job returns a job's status and progress.
+func (m *Reembedder) Job(ctx context.Context, jobID uuid.UUID) (*storage.ReembedJob, error) {
+	return m.jobs.Get(ctx, jobID)
+}
+
+// Rollback switches retrieval back to the index used before a completed job
+// and restores the embedding version recorded on its chunks.
+func (m *Reembedder) Rollback(ctx context.Context, jobID uuid.UUID) (*storage.ReembedJob, error) {
+	if m.isRunning(jobID) {
+		return nil, ErrReembedJobRunning
+	}
+	job, err := m.jobs.Get(ctx, jobID)
+	if err != nil {
+		return nil, fmt.Errorf("load reembed job: %w", err)
+	}
+	if job.Status != storage.ReembedStatusCompleted {
+		return nil, fmt.Errorf("re-embedding job %s is %s, only completed jobs can be rolled back", jobID, job.Status)
+	}
+	active := m.router.ActiveEmbeddingIndex(job.TenantID, job.CampaignVariantID)
+	if active == nil || active.Version != job.EmbeddingVersion {
+		return nil, fmt.Errorf("re-embedding job %s is no longer the active index", jobID)
+	}
+
+	if _, err := m.router.RollbackEmbeddingIndex(ctx, job.TenantID, job.CampaignVariantID); err != nil {
+		return nil, err
+	}
+	if err := m.chunks.SetEmbeddingVersion(ctx, job.TenantID, job.CampaignVariantID,
+		job.PreviousEmbeddingModel, job.PreviousEmbeddingVersion); err != nil {
+		m.logger.Warn().Err(err).Str("job_id", jobID.String()).Msg("Failed to restore chunk embedding version")
+	}
+
+	job.Status = storage.ReembedStatusRolledBack
+	if err := m.jobs.Save(ctx, job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+func (m *Reembedder) isRunning(jobID uuid.UUID) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.running[jobID]
+	return ok
+}
+
+// launch runs a job in the background, detached from the caller's cancellation.
+func (m *Reembedder) launch(ctx context.Context, job *storage.ReembedJob, req ReembedRequest) error {
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	run := &reembedRun{cancel: cancel, done: make(chan struct{})}
+	m.mu.Lock()
+	if _, ok := m.running[job.ID]; ok {
+		m.mu.Unlock()
+		cancel()
+		return ErrReembedJobRunning
+	}
+	m.running[job.ID] = run
+	m.mu.Unlock()
+
+	// The goroutine owns its own copy, so callers never see a job mid-update
+	owned := *job
+	job = &owned
+	go func() {
+		defer close(run.done)
+		defer cancel()
+		defer func() {
+			m.mu.Lock()
+			delete(m.running, job.ID)
+			m.mu.Unlock()
+		}()
+		m.run(ctx, job, req)
+	}()
+	return nil
+}
+
+// run re-embeds the job's remaining chunks and records how it ended.
+func (m *Reembedder) run(ctx context.Context, job *storage.ReembedJob, req ReembedRequest) {
+	logger := m.logger.With().Str("job_id", job.ID.String()).Str("tenant_id", job.TenantID.String()).Logger()
+
+	err := m.reembed(ctx, job, req)
+	// Checkpoints and the final status are saved even when the job was paused
+	saveCtx := context.WithoutCancel(ctx)
+	switch {
+	case err == nil:
+		now := time.Now()
+		job.Status = storage.ReembedStatusCompleted
+		job.CompletedAt = &now
+		job.Error = nil
+		logger.Info().Int64("chunks", job.ProcessedChunks).Msg("Re-embedding completed")
+	case ctx.Err() != nil, errors.Is(err, errReembedCancelRequested):
+		job.Status = storage.ReembedStatusPaused
+		logger.Info().Int64("processed", job.ProcessedChunks).Int64("total", job.TotalChunks).Msg("Re-embedding paused")
+	default:
+		msg := err.Error()
+		job.Status = storage.ReembedStatusFailed
+		job.Error = &msg
+		logger.Error().Err(err).Int64("processed", job.ProcessedChunks).Msg("Re-embedding failed")
+	}
+	if err := m.jobs.Save(saveCtx, job); err != nil {
+		logger.Error().Err(err).Msg("Failed to save re-embedding job")
+	}
+}
+
+// reembed embeds the chunks after the job's checkpoint into the shadow index,
+// then activates the index and records the new version on the chunks.
+func (m *Reembedder) reembed(ctx context.Context, job *storage.ReembedJob, req ReembedRequest) error {
+	if job.ProcessedChunks > 0 {
+		indexed, err := req.Index.Count(ctx, VectorFilters{
+			TenantID:          &req.TenantID,
+			CampaignVariantID: req.CampaignID,
+			EmbeddingVersion:  &req.Version,
+		})
+		if err != nil {
+			return fmt.Errorf("count shadow index: %w", err)
+		}
+		if indexed < job.ProcessedChunks {
+			m.logger.Warn().
+				Str("job_id", job.ID.String()).
+				Int64("indexed", indexed).
+				Int64("processed", job.ProcessedChunks).
+				Msg("Shadow index is missing checkpointed vectors, re-embedding from the start")
+			job.ProcessedChunks = 0
+			job.LastChunkID = nil
+		}
+	}
+
+	total, err := m.chunks.Count(ctx, job.TenantID, job.CampaignVariantID)
+	if err != nil {
+		return fmt.Errorf("count chunks: %w", err)
+	}
+	job.TotalChunks = total
+	job.Status = storage.ReembedStatusRunning
+	job.Error = nil
+	if err := m.jobs.Save(ctx, job); err != nil {
+		return err
+	}
+
+	pacer := m.newPacer()
+	for {
+		after := uuid.Nil
+		if job.LastChunkID != nil {
+			after = *job.LastChunkID
+		}
+		chunks, err := m.chunks.ListAfter(ctx, job.TenantID, job.CampaignVariantID, after, m.cfg.BatchSize)
+		if err != nil {
+			return fmt.Errorf("list chunks: %w", err)
+		}
+		if len(chunks) == 0 {
+			break
+		}
+		if err := m.checkCancelRequested(ctx, job.ID); err != nil {
+			return err
+		}
+		if err := pacer.wait(ctx); err != nil {
+			return err
+		}
+		if err := m.embedBatch(ctx, chunks, req); err != nil {
+			return err
+		}
+
+		lastID := chunks[len(chunks)-1].ID
+		job.LastChunkID = &lastID
+		job.ProcessedChunks += int64(len(chunks))
+		if err := m.jobs.Save(ctx, job); err != nil {
+			return err
+		}
+	}
+
+	// Sweep up chunks created or updated since the job started until a sweep
+	// finds none; each sweep looks back to when the previous one began
+	since := job.StartedAt
+	for {
+		sweepStart := time.Now()
+		swept, err := m.sweep(ctx, job, req, pacer, since)
+		if err != nil {
+			return err
+		}
+		if swept == 0 {
+			break
+		}
+		since = sweepStart
+	}
+
+	err = m.router.ActivateEmbeddingIndex(ctx, job.TenantID, job.CampaignVariantID, &EmbeddingIndex{
+		Version:  req.Version,
+		Embedder: req.Embedder,
+		Adapter:  req.Index,
+	})
+	if err != nil {
+		active := m.router.ActiveEmbeddingIndex(job.TenantID, job.CampaignVariantID)
+		if active == nil || active.Version != req.Version {
+			return fmt.Errorf("activate embedding index: %w", err)
+		}
+		// The switch was made; only evicting stale responses failed
+		m.logger.Warn().Err(err).Str("job_id", job.ID.String()).Msg("Failed to invalidate cached responses")
+	}
+	if err := m.chunks.SetEmbeddingVersion(ctx, job.TenantID, job.CampaignVariantID,
+		&job.EmbeddingModel, &job.EmbeddingVersion); err != nil {
+		m.logger.Warn().Err(err).Str("job_id", job.ID.String()).Msg("Failed to record chunk embedding version")
+	}
+	return nil
+}
+
+// sweep re-embeds the chunks created or updated at or after since and
+// returns how many it found. Their vectors replace any already in the shadow
+// index, so they do not count as processed again.
+func (m *Reembedder) sweep(ctx context.Context, job *storage.ReembedJob, req ReembedRequest, pacer *reembedPacer, since time.Time) (int, error) {
+	swept := 0
+	after := uuid.Nil
+	for {
+		chunks, err := m.chunks.ListUpdatedSince(ctx, job.TenantID, job.CampaignVariantID, since, after, m.cfg.BatchSize)
+		if err != nil {
+			return swept, fmt.Errorf("list updated chunks: %w", err)
+		}
+		if len(chunks) == 0 {
+			return swept, nil
+		}
+		if err := m.checkCancelRequested(ctx, job.ID); err != nil {
+			return swept, err
+		}
+		if err := pacer.wait(ctx); err != nil {
+			return swept, err
+		}
+		if err := m.embedBatch(ctx, chunks, req); err != nil {
+			return swept, err
+		}
+		swept += len(chunks)
+		after = chunks[len(chunks)-1].ID
+	}
+}
+
+// checkCancelRequested returns errReembedCancelRequested once the job's
+// cancellation was requested through the store.
+func (m *Reembedder) checkCancelRequested(ctx context.Context, jobID uuid.UUID) error {
+	stored, err := m.jobs.Get(ctx, jobID)
+	if err != nil {
+		return fmt.Errorf("load reembed job: %w", err)
+	}
+	if stored.CancelRequested {
+		return errReembedCancelRequested
+	}
+	return nil
+}
+
+// reembedPacer spaces calls to the embedding provider by the configured rate.
+type reembedPacer struct {
+	interval time.Duration
+	last     time.Time
+}
+
+func (m *Reembedder) newPacer() *reembedPacer {
+	pacer := &reembedPacer{}
+	if m.cfg.BatchesPerSecond > 0 {
+		pacer.interval = time.Duration(float64(time.Second) / m.cfg.BatchesPerSecond)
+	}
+	return pacer
+}
+
+// wait blocks until the next batch may be embedded.
+func (p *reembedPacer) wait(ctx context.Context) error {
+	if wait := p.interval - time.Since(p.last); p.interval > 0 && wait > 0 {
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+	p.last = time.Now()
+	return nil
+}
+
+// embedBatch embeds a batch of chunks and inserts them into the shadow index.
+func (m *Reembedder) embedBatch(ctx context.Context, chunks []*storage.KnowledgeChunk, req ReembedRequest) error {
+	texts := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		texts[i] = chunk.Text
+	}
+	vectors, models, err := embedding.EmbedModels(ctx, req.Embedder, texts)
+	if err != nil {
+		return fmt.Errorf("embed chunks: %w", err)
+	}
+	if len(vectors) != len(chunks) {
+		return fmt.Errorf("embedder returned %d embeddings for %d chunks", len(vectors), len(chunks))
+	}
+	// A fallback provider's vectors are not in the new index's space; failing
+	// the batch leaves it for a resumed job
+	for i, model := range models {
+		if model != req.Embedder.Model() {
+			return fmt.Errorf("chunk %s was embedded by %s, not %s", chunks[i].ID, model, req.Embedder.Model())
+		}
+	}
+
+	entries := make([]VectorEntry, 0, len(chunks))
+	for i, chunk := range chunks {
+		entries = append(entries, chunkVectorEntry(chunk, req.Version, vectors[i]))
+	}
+	if err := req.Index.Insert(ctx, entries); err != nil {
+		return fmt.Errorf("insert into shadow index: %w", err)
+	}
+	if m.stored != nil {
+		saved := make(map[uuid.UUID][]float32, len(chunks))
+		for i, chunk := range chunks {
+			saved[chunk.ID] = vectors[i]
+		}
+		if err := m.stored.Save(ctx, req.Embedder.Model(), req.Version, saved); err != nil {
+			return fmt.Errorf("store embeddings: %w", err)
+		}
+	}
+	return nil
+}
+
+// chunkVectorEntry builds the vector index entry of a chunk embedded for version.
+func chunkVectorEntry(chunk *storage.KnowledgeChunk, version string, vector []float32) VectorEntry {
+	metadata := make(map[string]interface{})
+	if len(chunk.Metadata) > 0 {
+		if err := json.Unmarshal(chunk.Metadata, &metadata); err != nil {
+			metadata = make(map[string]interface{})
+		}
+	}
+	metadata["chunk_type"] = string(chunk.ChunkType)
+	metadata["text"] = chunk.Text
+
+	return VectorEntry{
+		ID:                chunk.ID,
+		TenantID:          chunk.TenantID,
+		ProductID:         chunk.ProductID,
+		CampaignVariantID: chunk.CampaignVariantID,
+		ChunkType:         string(chunk.ChunkType),
+		Visibility:        string(chunk.Visibility),
+		EmbeddingVersion:  version,
+		Vector:            vector,
+		Metadata:          metadata,
+	}
+}
@@ -0,0 +1,648 @@
+package retrieval
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/cache"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/observability"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memChunks is an in-memory ChunkSource.
+type memChunks struct {
+	mu       sync.Mutex
+	chunks   []*storage.KnowledgeChunk
+	versions map[uuid.UUID]*string
+}
+
+func newMemChunks(chunks ...*storage.KnowledgeChunk) *memChunks {
+	sort.Slice(chunks, func(i, j int) bool { return bytes.Compare(chunks[i].ID[:], chunks[j].ID[:]) < 0 })
+	return &memChunks{chunks: chunks, versions: make(map[uuid.UUID]*string)}
+}
+
+func (s *memChunks) ListAfter(ctx context.Context, tenantID uuid.UUID, campaignID *uuid.UUID, after uuid.UUID, limit int) ([]*storage.KnowledgeChunk, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var page []*storage.KnowledgeChunk
+	for _, chunk := range s.chunks {
+		if chunk.TenantID == tenantID && bytes.Compare(chunk.ID[:], after[:]) > 0 && len(page) < limit {
+			page = append(page, chunk)
+		}
+	}
+	return page, nil
+}
+
+func (s *memChunks) ListUpdatedSince(ctx context.Context, tenantID uuid.UUID, campaignID *uuid.UUID, since time.Time, after uuid.UUID, limit int) ([]*storage.KnowledgeChunk, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var page []*storage.KnowledgeChunk
+	for _, chunk := range s.chunks {
+		if chunk.TenantID == tenantID && !chunk.UpdatedAt.Before(since) &&
+			bytes.Compare(chunk.ID[:], after[:]) > 0 && len(page) < limit {
+			page = append(page, chunk)
+		}
+	}
+	return page, nil
+}
+
+// add inserts a chunk in ID order, as if ingested while a job runs.
+func (s *memChunks) add(chunk *storage.KnowledgeChunk) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.chunks = append(s.chunks, chunk)
+	sort.Slice(s.chunks, func(i, j int) bool { return bytes.Compare(s.chunks[i].ID[:], s.chunks[j].ID[:]) < 0 })
+}
+
+func (s *memChunks) Count(ctx context.Context, tenantID uuid.UUID, campaignID *uuid.UUID) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return int64(len(s.chunks)), nil
+}
+
+func (s *memChunks) SetEmbeddingVersion(ctx context.Context, tenantID uuid.UUID, campaignID *uuid.UUID, model, version *string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, chunk := range s.chunks {
+		s.versions[chunk.ID] = version
+	}
+	return nil
+}
+
+func (s *memChunks) version(id uuid.UUID) *string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.versions[id]
+}
+
+// memJobs is an in-memory ReembedStore.
+type memJobs struct {
+	mu   sync.Mutex
+	jobs map[uuid.UUID]storage.ReembedJob
+}
+
+func (s *memJobs) Save(ctx context.Context, job *storage.ReembedJob) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if job.ID == uuid.Nil {
+		job.ID = uuid.New()
+	}
+	if s.jobs == nil {
+		s.jobs = make(map[uuid.UUID]storage.ReembedJob)
+	}
+	// Like the repository, saving leaves the cancel request untouched
+	saved := *job
+	saved.CancelRequested = s.jobs[job.ID].CancelRequested
+	s.jobs[job.ID] = saved
+	return nil
+}
+
+func (s *memJobs) SetCancelRequested(ctx context.Context, id uuid.UUID, requested bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[id]
+	if !ok {
+		return storage.ErrNotFound
+	}
+	job.CancelRequested = requested
+	s.jobs[id] = job
+	return nil
+}
+
+func (s *memJobs) Get(ctx context.Context, id uuid.UUID) (*storage.ReembedJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[id]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	return &job, nil
+}
+
+// modelEmbedder embeds known texts with fixed vectors and counts the texts it
+// embedded, failing once failAfter texts have been embedded. onEmbed, when
+// set, runs before each call.
+type modelEmbedder struct {
+	model     string
+	vectors   map[string][]float32
+	failAfter int
+	onEmbed   func()
+
+	mu       sync.Mutex
+	embedded int
+}
+
+func (e *modelEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if e.onEmbed != nil {
+		e.onEmbed()
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.failAfter > 0 && e.embedded >= e.failAfter {
+		return nil, errors.New("provider unavailable")
+	}
+	e.embedded += len(texts)
+	embeddings := make([][]float32, len(texts))
+	for i, text := range texts {
+		embeddings[i] = e.vectors[text]
+	}
+	return embeddings, nil
+}
+
+func (e *modelEmbedder) EmbedSingle(ctx context.Context, text string) ([]float32, error) {
+	embeddings, err := e.Embed(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return embeddings[0], nil
+}
+
+func (e *modelEmbedder) Model() string  { return e.model }
+func (e *modelEmbedder) Dimension() int { return 4 }
+
+func (e *modelEmbedder) count() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.embedded
+}
+
+type reembedFixture struct {
+	tenant   uuid.UUID
+	chunks   *memChunks
+	jobs     *memJobs
+	router   *Router
+	embedder *modelEmbedder
+	ids      []uuid.UUID
+}
+
+func newReembedFixture(t *testing.T) *reembedFixture {
+	logger := observability.NewLogger(observability.LogConfig{Level: "error"})
+	tenant, product := uuid.New(), uuid.New()
+	texts := []string{"towing capacity 3500 kg", "heated seats", "panoramic roof", "adaptive cruise control", "wireless charging"}
+
+	f := &reembedFixture{
+		tenant:   tenant,
+		jobs:     &memJobs{},
+		embedder: &modelEmbedder{model: "large-v2", vectors: make(map[string][]float32)},
+	}
+	var chunks []*storage.KnowledgeChunk
+	for i, text := range texts {
+		chunks = append(chunks, &storage.KnowledgeChunk{
+			ID:         uuid.New(),
+			TenantID:   tenant,
+			ProductID:  product,
+			ChunkType:  storage.ChunkTypeGlobal,
+			Text:       text,
+			Visibility: storage.VisibilityPrivate,
+		})
+		vector := make([]float32, 4)
+		vector[i%4] = 1
+		vector[(i+1)%4] = float32(i) / 10
+		f.embedder.vectors[text] = vector
+	}
+	f.chunks = newMemChunks(chunks...)
+	for _, chunk := range f.chunks.chunks {
+		f.ids = append(f.ids, chunk.ID)
+	}
+
+	// The default index holds the same chunks embedded with the old 3-dimensional model
+	defaultIndex, err := NewFAISSAdapter(FAISSConfig{Dimension: 3})
+	require.NoError(t, err)
+	old := &stubEmbedder{vectors: map[string][]float32{}}
+	require.NoError(t, defaultIndex.Insert(context.Background(), []VectorEntry{{
+		ID: uuid.New(), TenantID: tenant, ProductID: product, ChunkType: "global",
+		EmbeddingVersion: "v1", Vector: []float32{0, 0, 1},
+	}}))
+	f.router = NewRouter(logger, nil, defaultIndex, old, nil, RouterConfig{})
+	return f
+}
+
+func (f *reembedFixture) reembedder(cfg ReembedConfig) *Reembedder {
+	logger := observability.NewLogger(observability.LogConfig{Level: "error"})
+	return NewReembedder(logger, f.router, f.chunks, f.jobs, cfg)
+}
+
+func TestReembedder_SwitchesAndRollsBack(t *testing.T) {
+	ctx := context.Background()
+	f := newReembedFixture(t)
+	m := f.reembedder(ReembedConfig{BatchSize: 2})
+
+	shadow, err := NewFAISSAdapter(FAISSConfig{Dimension: 4})
+	require.NoError(t, err)
+	started, err := m.Start(ctx, ReembedRequest{TenantID: f.tenant, Version: "v2", Embedder: f.embedder, Index: shadow})
+	require.NoError(t, err)
+	assert.Equal(t, "large-v2", started.EmbeddingModel)
+	require.NotNil(t, started.PreviousEmbeddingModel)
+	assert.Equal(t, "stub", *started.PreviousEmbeddingModel)
+
+	job, err := m.Wait(ctx, started.ID)
+	require.NoError(t, err)
+	require.Equal(t, storage.ReembedStatusCompleted, job.Status, "error: %v", job.Error)
+	assert.EqualValues(t, 5, job.TotalChunks)
+	assert.EqualValues(t, 5, job.ProcessedChunks)
+	assert.Equal(t, 1.0, job.Progress())
+	assert.Equal(t, f.ids[4], *job.LastChunkID)
+
+	indexed, err := shadow.Count(ctx, VectorFilters{})
+	require.NoError(t, err)
+	assert.EqualValues(t, 5, indexed)
+	require.NotNil(t, f.chunks.version(f.ids[0]))
+	assert.Equal(t, "v2", *f.chunks.version(f.ids[0]))
+
+	t.Run("vector search uses the new index and model", func(t *testing.T) {
+		active := f.router.ActiveEmbeddingIndex(f.tenant, nil)
+		require.NotNil(t, active)
+		assert.Equal(t, "v2", active.Version)
+
+		chunks, err := f.router.querySemanticChunks(ctx, RetrievalRequest{
+			TenantID:  f.tenant,
+			Question:  "heated seats",
+			MaxChunks: 3,
+		})
+		require.NoError(t, err)
+		require.NotEmpty(t, chunks)
+		assert.Contains(t, f.ids, chunks[0].ChunkID)
+		assert.Equal(t, "heated seats", chunks[0].Metadata["text"])
+	})
+
+	t.Run("rollback restores the previous index", func(t *testing.T) {
+		rolledBack, err := m.Rollback(ctx, job.ID)
+		require.NoError(t, err)
+		assert.Equal(t, storage.ReembedStatusRolledBack, rolledBack.Status)
+		assert.Nil(t, f.router.ActiveEmbeddingIndex(f.tenant, nil))
+		assert.Nil(t, f.chunks.version(f.ids[0]))
+
+		_, err = m.Rollback(ctx, job.ID)
+		assert.Error(t, err)
+	})
+}
+
+func TestReembedder_ResumesFromCheckpoint(t *testing.T) {
+	ctx := context.Background()
+	f := newReembedFixture(t)
+	m := f.reembedder(ReembedConfig{BatchSize: 2})
+
+	shadow, err := NewFAISSAdapter(FAISSConfig{Dimension: 4})
+	require.NoError(t, err)
+	failing := &modelEmbedder{model: "large-v2", vectors: f.embedder.vectors, failAfter: 2}
+	started, err := m.Start(ctx, ReembedRequest{TenantID: f.tenant, Version: "v2", Embedder: failing, Index: shadow})
+	require.NoError(t, err)
+
+	job, err := m.Wait(ctx, started.ID)
+	require.NoError(t, err)
+	assert.Equal(t, storage.ReembedStatusFailed, job.Status)
+	require.NotNil(t, job.Error)
+	assert.EqualValues(t, 2, job.ProcessedChunks)
+	assert.InDelta(t, 0.4, job.Progress(), 0.001)
+	assert.Nil(t, f.router.ActiveEmbeddingIndex(f.tenant, nil), "a failed job must not switch retrieval")
+
+	t.Run("resume rejects another model", func(t *testing.T) {
+		_, err := m.Resume(ctx, job.ID, &stubEmbedder{}, shadow)
+		assert.Error(t, err)
+	})
+
+	_, err = m.Resume(ctx, job.ID, f.embedder, shadow)
+	require.NoError(t, err)
+	job, err = m.Wait(ctx, job.ID)
+	require.NoError(t, err)
+	require.Equal(t, storage.ReembedStatusCompleted, job.Status)
+	assert.Nil(t, job.Error)
+	assert.EqualValues(t, 5, job.ProcessedChunks)
+	assert.Equal(t, 3, f.embedder.count(), "checkpointed chunks are not embedded again")
+	assert.NotNil(t, f.router.ActiveEmbeddingIndex(f.tenant, nil))
+}
+
+func TestReembedder_RestartsWhenShadowIndexIsLost(t *testing.T) {
+	ctx := context.Background()
+	f := newReembedFixture(t)
+	m := f.reembedder(ReembedConfig{BatchSize: 2})
+
+	lost, err := NewFAISSAdapter(FAISSConfig{Dimension: 4})
+	require.NoError(t, err)
+	failing := &modelEmbedder{model: "large-v2", vectors: f.embedder.vectors, failAfter: 4}
+	started, err := m.Start(ctx, ReembedRequest{TenantID: f.tenant, Version: "v2", Embedder: failing, Index: lost})
+	require.NoError(t, err)
+	job, err := m.Wait(ctx, started.ID)
+	require.NoError(t, err)
+	require.Equal(t, storage.ReembedStatusFailed, job.Status)
+	assert.EqualValues(t, 4, job.ProcessedChunks)
+
+	fresh, err := NewFAISSAdapter(FAISSConfig{Dimension: 4})
+	require.NoError(t, err)
+	_, err = m.Resume(ctx, job.ID, f.embedder, fresh)
+	require.NoError(t, err)
+	job, err = m.Wait(ctx, job.ID)
+	require.NoError(t, err)
+	require.Equal(t, storage.ReembedStatusCompleted, job.Status)
+	assert.Equal(t, 5, f.embedder.count())
+
+	indexed, err := fresh.Count(ctx, VectorFilters{})
+	require.NoError(t, err)
+	assert.EqualValues(t, 5, indexed)
+}
+
+func TestReembedder_IndexSurvivesRestart(t *testing.T) {
+	ctx := context.Background()
+	logger := observability.NewLogger(observability.LogConfig{Level: "error"})
+	db := migratedDB(t)
+	tenant, product := uuid.New(), uuid.New()
+	_, err := db.Exec("INSERT INTO tenants (id, name) VALUES ($1, $2)", tenant, "Toyota")
+	require.NoError(t, err)
+	_, err = db.Exec("INSERT INTO products (id, tenant_id, name) VALUES ($1, $2, $3)", product, tenant, "Camry")
+	require.NoError(t, err)
+
+	chunks := storage.NewKnowledgeChunkRepository(db)
+	embedder := &modelEmbedder{model: "large-v2", vectors: make(map[string][]float32)}
+	for i, text := range []string{"towing capacity 3500 kg", "heated seats", "panoramic roof"} {
+		require.NoError(t, chunks.Create(ctx, &storage.KnowledgeChunk{
+			TenantID: tenant, ProductID: product, ChunkType: storage.ChunkTypeGlobal, Text: text,
+			Metadata: json.RawMessage(`{}`), Visibility: storage.VisibilityPrivate,
+		}))
+		vector := make([]float32, 4)
+		vector[i] = 1
+		embedder.vectors[text] = vector
+	}
+
+	// Each instance opens the version's index empty and refills it from the stored vectors
+	embeddings := storage.NewChunkEmbeddingRepository(db)
+	open := func(ctx context.Context, model, version string) (*EmbeddingIndex, error) {
+		adapter, err := NewFAISSAdapter(FAISSConfig{Dimension: 4})
+		if err != nil {
+			return nil, err
+		}
+		idx := &EmbeddingIndex{Version: version, Embedder: embedder, Adapter: adapter}
+		if _, err := FillEmbeddingIndex(ctx, embeddings, idx); err != nil {
+			return nil, err
+		}
+		return idx, nil
+	}
+	newInstance := func() *Router {
+		router := NewRouter(logger, nil, nil, nil, nil, RouterConfig{})
+		router.SetEmbeddingIndexStore(storage.NewEmbeddingIndexRepository(db), open)
+		require.NoError(t, router.LoadEmbeddingIndexes(ctx))
+		return router
+	}
+
+	router := newInstance()
+	m := NewReembedder(logger, router, chunks, &memJobs{}, ReembedConfig{BatchSize: 2})
+	m.SetEmbeddingStore(embeddings)
+	idx, err := open(ctx, embedder.Model(), "v2")
+	require.NoError(t, err)
+	started, err := m.Start(ctx, ReembedRequest{TenantID: tenant, Version: "v2", Embedder: embedder, Index: idx.Adapter})
+	require.NoError(t, err)
+	job, err := m.Wait(ctx, started.ID)
+	require.NoError(t, err)
+	require.Equal(t, storage.ReembedStatusCompleted, job.Status, "error: %v", job.Error)
+
+	restarted := newInstance()
+	active := restarted.ActiveEmbeddingIndex(tenant, nil)
+	require.NotNil(t, active)
+	assert.Equal(t, "v2", active.Version)
+	indexed, err := active.Adapter.Count(ctx, VectorFilters{})
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, indexed)
+
+	results, err := restarted.querySemanticChunks(ctx, RetrievalRequest{TenantID: tenant, Question: "heated seats", MaxChunks: 1})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "heated seats", results[0].Metadata["text"])
+}
+
+func TestRouter_EmbeddingIndexScopes(t *testing.T) {
+	ctx := context.Background()
+	logger := observability.NewLogger(observability.LogConfig{Level: "error"})
+	router := NewRouter(logger, nil, nil, nil, nil, RouterConfig{})
+	tenant, campaign, other := uuid.New(), uuid.New(), uuid.New()
+
+	_, err := router.RollbackEmbeddingIndex(ctx, tenant, nil)
+	assert.ErrorIs(t, err, ErrNoEmbeddingIndex)
+
+	adapter, err := NewFAISSAdapter(FAISSConfig{})
+	require.NoError(t, err)
+	tenantIndex := &EmbeddingIndex{Version: "v2", Embedder: &stubEmbedder{}, Adapter: adapter}
+	campaignIndex := &EmbeddingIndex{Version: "v3", Embedder: &stubEmbedder{}, Adapter: adapter}
+	require.NoError(t, router.ActivateEmbeddingIndex(ctx, tenant, nil, tenantIndex))
+	require.NoError(t, router.ActivateEmbeddingIndex(ctx, tenant, &campaign, campaignIndex))
+
+	assert.Same(t, campaignIndex, router.vectorIndex(tenant, VectorFilters{CampaignVariantID: &campaign}))
+	assert.Same(t, tenantIndex, router.vectorIndex(tenant, VectorFilters{CampaignVariantID: &other}))
+	assert.Same(t, tenantIndex, router.vectorIndex(tenant, VectorFilters{
+		CampaignVariantID:  &campaign,
+		CampaignVariantIDs: []uuid.UUID{campaign, other},
+	}), "an inheritance chain spans chunks outside the campaign's index")
+	assert.Nil(t, router.vectorIndex(uuid.New(), VectorFilters{}))
+
+	previous, err := router.RollbackEmbeddingIndex(ctx, tenant, &campaign)
+	require.NoError(t, err)
+	assert.Nil(t, previous)
+	assert.Same(t, tenantIndex, router.vectorIndex(tenant, VectorFilters{CampaignVariantID: &campaign}))
+}
+
+func TestReembedder_SweepsChunksAddedDuringJob(t *testing.T) {
+	ctx := context.Background()
+	f := newReembedFixture(t)
+	m := f.reembedder(ReembedConfig{BatchSize: 2})
+
+	// Ingested after the first batch, with an ID sorting before the checkpoint
+	late := &storage.KnowledgeChunk{
+		ID:         uuid.MustParse("00000000-0000-4000-8000-000000000001"),
+		TenantID:   f.tenant,
+		ProductID:  uuid.New(),
+		ChunkType:  storage.ChunkTypeGlobal,
+		Text:       "ventilated seats",
+		Visibility: storage.VisibilityPrivate,
+	}
+	f.embedder.vectors[late.Text] = []float32{0, 1, 1, 0}
+	var once sync.Once
+	f.embedder.onEmbed = func() {
+		once.Do(func() {
+			late.CreatedAt = time.Now()
+			late.UpdatedAt = late.CreatedAt
+			f.chunks.add(late)
+		})
+	}
+
+	shadow, err := NewFAISSAdapter(FAISSConfig{Dimension: 4})
+	require.NoError(t, err)
+	started, err := m.Start(ctx, ReembedRequest{TenantID: f.tenant, Version: "v2", Embedder: f.embedder, Index: shadow})
+	require.NoError(t, err)
+	job, err := m.Wait(ctx, started.ID)
+	require.NoError(t, err)
+	require.Equal(t, storage.ReembedStatusCompleted, job.Status, "error: %v", job.Error)
+
+	indexed, err := shadow.Count(ctx, VectorFilters{})
+	require.NoError(t, err)
+	assert.EqualValues(t, 6, indexed, "the chunk added behind the checkpoint is swept up")
+	assert.Equal(t, 6, f.embedder.count(), "only the late chunk is embedded again by the sweep")
+}
+
+func TestReembedder_CancelRequestedByAnotherProcess(t *testing.T) {
+	ctx := context.Background()
+	f := newReembedFixture(t)
+	m := f.reembedder(ReembedConfig{BatchSize: 2})
+	// Another process sharing the job store
+	other := f.reembedder(ReembedConfig{BatchSize: 2})
+
+	embedding := make(chan struct{})
+	release := make(chan struct{})
+	var once sync.Once
+	f.embedder.onEmbed = func() {
+		once.Do(func() {
+			close(embedding)
+			<-release
+		})
+	}
+
+	shadow, err := NewFAISSAdapter(FAISSConfig{Dimension: 4})
+	require.NoError(t, err)
+	started, err := m.Start(ctx, ReembedRequest{TenantID: f.tenant, Version: "v2", Embedder: f.embedder, Index: shadow})
+	require.NoError(t, err)
+
+	<-embedding
+	require.NoError(t, other.RequestCancel(ctx, started.ID))
+	close(release)
+
+	job, err := m.Wait(ctx, started.ID)
+	require.NoError(t, err)
+	assert.Equal(t, storage.ReembedStatusPaused, job.Status)
+	assert.EqualValues(t, 2, job.ProcessedChunks, "the batch in flight is checkpointed")
+	assert.Nil(t, f.router.ActiveEmbeddingIndex(f.tenant, nil))
+
+	err = other.RequestCancel(ctx, started.ID)
+	assert.Error(t, err, "a paused job cannot be cancelled")
+
+	_, err = m.Resume(ctx, started.ID, f.embedder, shadow)
+	require.NoError(t, err)
+	job, err = m.Wait(ctx, started.ID)
+	require.NoError(t, err)
+	assert.Equal(t, storage.ReembedStatusCompleted, job.Status)
+	assert.False(t, job.CancelRequested, "resuming clears the request")
+}
+
+// memIndexStore is an in-memory EmbeddingIndexStore.
+type memIndexStore struct {
+	mu          sync.Mutex
+	activations []*storage.EmbeddingIndexActivation
+}
+
+func (s *memIndexStore) Create(ctx context.Context, activation *storage.EmbeddingIndexActivation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	saved := *activation
+	s.activations = append(s.activations, &saved)
+	return nil
+}
+
+func (s *memIndexStore) DeleteLatest(ctx context.Context, tenantID uuid.UUID, campaignID *uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := len(s.activations) - 1; i >= 0; i-- {
+		if s.inScope(s.activations[i], tenantID, campaignID) {
+			s.activations = append(s.activations[:i], s.activations[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+func (s *memIndexStore) List(ctx context.Context, tenantID uuid.UUID, campaignID *uuid.UUID) ([]*storage.EmbeddingIndexActivation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var activations []*storage.EmbeddingIndexActivation
+	for _, activation := range s.activations {
+		if s.inScope(activation, tenantID, campaignID) {
+			activations = append(activations, activation)
+		}
+	}
+	return activations, nil
+}
+
+func (s *memIndexStore) ListAll(ctx context.Context) ([]*storage.EmbeddingIndexActivation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*storage.EmbeddingIndexActivation(nil), s.activations...), nil
+}
+
+func (s *memIndexStore) inScope(activation *storage.EmbeddingIndexActivation, tenantID uuid.UUID, campaignID *uuid.UUID) bool {
+	if activation.TenantID != tenantID {
+		return false
+	}
+	if campaignID == nil {
+		return activation.CampaignVariantID == nil
+	}
+	return activation.CampaignVariantID != nil && *activation.CampaignVariantID == *campaignID
+}
+
+func TestRouter_EmbeddingIndexesPersistAndPropagate(t *testing.T) {
+	ctx := context.Background()
+	logger := observability.NewLogger(observability.LogConfig{Level: "error"})
+	store := &memIndexStore{}
+	bus := cache.NewMemoryInvalidationBus()
+	tenant := uuid.New()
+
+	adapter, err := NewFAISSAdapter(FAISSConfig{})
+	require.NoError(t, err)
+	var opened []string
+	open := func(ctx context.Context, model, version string) (*EmbeddingIndex, error) {
+		opened = append(opened, version)
+		return &EmbeddingIndex{Version: version, Embedder: &modelEmbedder{model: model}, Adapter: adapter}, nil
+	}
+	newInstance := func() *Router {
+		router := NewRouter(logger, nil, nil, nil, nil, RouterConfig{})
+		router.SetEmbeddingIndexStore(store, open)
+		trigger := NewInvalidationTrigger(bus, logger)
+		trigger.AddInvalidator(router)
+		require.NoError(t, trigger.Start(ctx))
+		t.Cleanup(trigger.Stop)
+		router.SetIndexSwitchPublisher(trigger)
+		return router
+	}
+	primary, replica := newInstance(), newInstance()
+
+	v2 := &EmbeddingIndex{Version: "v2", Embedder: &modelEmbedder{model: "large-v2"}, Adapter: adapter}
+	require.NoError(t, primary.ActivateEmbeddingIndex(ctx, tenant, nil, v2))
+	assert.Same(t, v2, primary.ActiveEmbeddingIndex(tenant, nil), "the switching instance keeps its own index")
+	require.NotNil(t, replica.ActiveEmbeddingIndex(tenant, nil), "the replica switches on the published event")
+	assert.Equal(t, "v2", replica.ActiveEmbeddingIndex(tenant, nil).Version)
+	assert.Equal(t, "large-v2", replica.ActiveEmbeddingIndex(tenant, nil).Embedder.Model())
+	assert.Equal(t, []string{"v2"}, opened)
+
+	v3 := &EmbeddingIndex{Version: "v3", Embedder: &modelEmbedder{model: "large-v3"}, Adapter: adapter}
+	require.NoError(t, primary.ActivateEmbeddingIndex(ctx, tenant, nil, v3))
+	assert.Equal(t, "v3", replica.ActiveEmbeddingIndex(tenant, nil).Version)
+
+	t.Run("a restarted instance loads the active index", func(t *testing.T) {
+		restarted := NewRouter(logger, nil, nil, nil, nil, RouterConfig{})
+		restarted.SetEmbeddingIndexStore(store, open)
+		require.NoError(t, restarted.LoadEmbeddingIndexes(ctx))
+		require.NotNil(t, restarted.ActiveEmbeddingIndex(tenant, nil))
+		assert.Equal(t, "v3", restarted.ActiveEmbeddingIndex(tenant, nil).Version)
+
+		previous, err := restarted.RollbackEmbeddingIndex(ctx, tenant, nil)
+		require.NoError(t, err)
+		require.NotNil(t, previous)
+		assert.Equal(t, "v2", previous.Version)
+		require.NoError(t, restarted.ActivateEmbeddingIndex(ctx, tenant, nil, v3))
+	})
+
+	t.Run("rollback propagates", func(t *testing.T) {
+		_, err := primary.RollbackEmbeddingIndex(ctx, tenant, nil)
+		require.NoError(t, err)
+		assert.Same(t, v2, primary.ActiveEmbeddingIndex(tenant, nil))
+		assert.Equal(t, "v2", replica.ActiveEmbeddingIndex(tenant, nil).Version)
+
+		activations, err := store.List(ctx, tenant, nil)
+		require.NoError(t, err)
+		require.Len(t, activations, 1)
+		assert.Equal(t, "v2", activations[0].EmbeddingVersion)
+	})
+}
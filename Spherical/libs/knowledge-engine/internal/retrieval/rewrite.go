@@ -0,0 +1,359 @@
+// Package retrieval provides conversation-aware query rewriting.
+package retrieval
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// MaxRewriteTurns bounds how many previous turns are considered when rewriting.
+const MaxRewriteTurns = 6
+
+// Rewrite methods recorded in QueryRewrite.
+const (
+	RewriteMethodRule = "rule"
+	RewriteMethodLLM  = "llm"
+)
+
+// QueryRewriter turns a follow-up question into a standalone question.
+// products are the tenant's product names and aliases; only these are
+// recognized as product mentions.
+type QueryRewriter interface {
+	Rewrite(ctx context.Context, question string, history []ConversationMessage, products []string) (*QueryRewrite, error)
+}
+
+// LLMCompleter generates a completion for a prompt.
+type LLMCompleter interface {
+	Complete(ctx context.Context, prompt string) (string, error)
+}
+
+// ConversationFocus is the product, trim and topic carried across turns.
+type ConversationFocus struct {
+	Product  string
+	Trim     string
+	Category string
+}
+
+// QueryRewrite records how a question was rewritten.
+type QueryRewrite struct {
+	Original  string
+	Question  string
+	Rewritten bool
+	Method    string
+	Focus     ConversationFocus
+}
+
+var (
+	pronounRe     = regexp.MustCompile(`(?i)\b(its|it's|it|this one|that one|this car|that car|this model|that model|they|them|their)\b`)
+	connectorRe   = regexp.MustCompile(`(?i)^\s*(and what about|and how about|what about|how about|and also|and|also|plus)\b\s*`)
+	comparisonRe  = regexp.MustCompile(`(?i)\b(compare|compares|compared|comparison|vs|versus|difference|differ)\b`)
+	compareToRe   = regexp.MustCompile(`(?i)\b(to|with|vs\.?|versus|against|and)\s+\S+`)
+	rewriteWordRe = regexp.MustCompile(`[A-Za-z0-9][A-Za-z0-9'-]*`)
+)
+
+// trimWords are trim and powertrain designations recognized in questions.
+var trimWords = map[string]bool{
+	"hybrid": true, "phev": true, "plug-in": true, "electric": true, "ev": true,
+	"le": true, "xle": true, "se": true, "xse": true, "limited": true, "platinum": true,
+	"sport": true, "touring": true, "base": true, "premium": true, "gt": true,
+	"awd": true, "4wd": true, "fwd": true, "trd": true, "nightshade": true,
+}
+
+// rewriteStopWords are words that never carry topic focus.
+var rewriteStopWords = map[string]bool{
+	"what": true, "what's": true, "whats": true, "how": true, "does": true, "do": true,
+	"is": true, "are": true, "the": true, "a": true, "an": true, "of": true, "for": true,
+	"on": true, "in": true, "and": true, "also": true, "about": true, "tell": true,
+	"me": true, "much": true, "many": true, "which": true, "it": true, "its": true,
+	"it's": true, "this": true, "that": true, "one": true, "they": true, "them": true,
+	"their": true, "car": true, "model": true, "to": true, "with": true, "can": true,
+	"you": true, "has": true, "have": true, "get": true, "plus": true, "there": true,
+	"compare": true, "compares": true, "compared": true, "comparison": true, "vs": true,
+	"versus": true, "difference": true, "differ": true, "between": true, "than": true,
+	"i": true, "show": true, "give": true, "list": true, "please": true, "could": true,
+	"would": true, "should": true, "will": true, "when": true, "where": true, "why": true,
+	"who": true, "hi": true, "hello": true, "thanks": true,
+}
+
+// RuleRewriter rewrites follow-up questions offline using conversation focus.
+type RuleRewriter struct{}
+
+// NewRuleRewriter creates a new rule-based rewriter.
+func NewRuleRewriter() *RuleRewriter {
+	return &RuleRewriter{}
+}
+
+// Rewrite resolves pronouns, ellipsis and open comparisons against previous turns.
+func (rw *RuleRewriter) Rewrite(ctx context.Context, question string, history []ConversationMessage, products []string) (*QueryRewrite, error) {
+	focus := conversationFocus(history, products)
+	result := &QueryRewrite{
+		Original: question,
+		Question: question,
+		Method:   RewriteMethodRule,
+		Focus:    focus,
+	}
+	if focus.Product == "" && focus.Trim == "" {
+		return result, nil
+	}
+
+	product := extractProduct(question, products)
+	trim := extractTrim(question)
+	rewritten := question
+
+	switch {
+	case product == "" && focus.Product != "" && pronounRe.MatchString(question):
+		// "What colours does it come in?" -> "What colours does the Camry come in?"
+		rewritten = pronounRe.ReplaceAllStringFunc(question, func(p string) string {
+			switch strings.ToLower(p) {
+			case "its", "their":
+				return "the " + focus.Product + "'s"
+			case "it's":
+				return "the " + focus.Product + " is"
+			}
+			return "the " + focus.Product
+		})
+
+	case connectorRe.MatchString(question) || isFragment(question):
+		// "And the torque?" -> "What is the torque of the Camry?"
+		rewritten = rewriteFragment(question, product, trim, focus, products)
+
+	case comparisonRe.MatchString(question) && !compareToRe.MatchString(question):
+		// "How does the hybrid compare?" -> "How does the Camry hybrid compare to the Camry?"
+		subject := strings.TrimSpace(joinNonEmpty(firstNonEmpty(product, focus.Product), trim))
+		target := strings.TrimSpace(joinNonEmpty(focus.Product, focus.Trim))
+		if target != "" && !strings.EqualFold(subject, target) {
+			rewritten = strings.TrimRight(injectProduct(question, product, trim, focus.Product), "?. ") + " to the " + target + "?"
+		}
+
+	case product == "" && trim != "" && focus.Product != "":
+		// "Is the hybrid available in blue?" -> "Is the Camry hybrid available in blue?"
+		rewritten = injectProduct(question, product, trim, focus.Product)
+	}
+
+	result.Question = rewritten
+	result.Rewritten = rewritten != question
+	return result, nil
+}
+
+// rewriteFragment expands an elliptical follow-up into a full question.
+func rewriteFragment(question, product, trim string, focus ConversationFocus, products []string) string {
+	rest := connectorRe.ReplaceAllString(question, "")
+	rest = strings.TrimRight(strings.TrimSpace(rest), "?. ")
+
+	subject := strings.TrimSpace(joinNonEmpty(firstNonEmpty(product, focus.Product), firstNonEmpty(trim, focusTrim(product, focus))))
+	topic := topicWords(rest, products)
+	if len(topic) == 0 {
+		// Only a product or trim changed: keep asking about the previous topic
+		if focus.Category == "" || subject == "" {
+			return question
+		}
+		return fmt.Sprintf("What is the %s of the %s?", focus.Category, subject)
+	}
+	if subject == "" {
+		return question
+	}
+	return fmt.Sprintf("What is the %s of the %s?", strings.Join(topic, " "), subject)
+}
+
+// focusTrim keeps the previous trim only when the product did not change.
+func focusTrim(product string, focus ConversationFocus) string {
+	if product == "" || strings.EqualFold(product, focus.Product) {
+		return focus.Trim
+	}
+	return ""
+}
+
+// injectProduct inserts the focus product before the first trim mention.
+func injectProduct(question, product, trim, focusProduct string) string {
+	if product != "" || trim == "" || focusProduct == "" {
+		return question
+	}
+	for _, loc := range rewriteWordRe.FindAllStringIndex(question, -1) {
+		if strings.EqualFold(question[loc[0]:loc[1]], trim) {
+			return question[:loc[0]] + focusProduct + " " + question[loc[0]:]
+		}
+	}
+	return question
+}
+
+// conversationFocus derives focus from the most recent user turns.
+func conversationFocus(history []ConversationMessage, products []string) ConversationFocus {
+	if len(history) > MaxRewriteTurns {
+		history = history[len(history)-MaxRewriteTurns:]
+	}
+
+	var focus ConversationFocus
+	for _, msg := range history {
+		if msg.Role != "" && !strings.EqualFold(msg.Role, "user") {
+			continue
+		}
+		product := extractProduct(msg.Content, products)
+		trim := extractTrim(msg.Content)
+		switch {
+		case product != "":
+			focus.Product = product
+			focus.Trim = trim
+		case trim != "":
+			focus.Trim = trim
+		}
+		if topic := topicWords(msg.Content, products); len(topic) > 0 {
+			focus.Category = strings.Join(topic, " ")
+		}
+	}
+	return focus
+}
+
+// extractProduct returns the known product name or alias mentioned first in
+// text, preferring the longest when several start at the same word.
+func extractProduct(text string, products []string) string {
+	words := rewriteWords(text)
+	best, bestStart, bestLen := "", len(words), 0
+	for _, product := range products {
+		name := rewriteWords(product)
+		if len(name) == 0 {
+			continue
+		}
+		for start := 0; start+len(name) <= len(words) && start <= bestStart; start++ {
+			if !equalWords(words[start:start+len(name)], name) {
+				continue
+			}
+			if start < bestStart || len(name) > bestLen {
+				best, bestStart, bestLen = product, start, len(name)
+			}
+			break
+		}
+	}
+	return best
+}
+
+// rewriteWords returns the lowercased words of text without possessive "'s".
+func rewriteWords(text string) []string {
+	words := rewriteWordRe.FindAllString(text, -1)
+	for i, w := range words {
+		words[i] = strings.TrimSuffix(strings.ToLower(w), "'s")
+	}
+	return words
+}
+
+func equalWords(a, b []string) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// extractTrim returns the first trim designation mentioned in text.
+func extractTrim(text string) string {
+	for _, w := range rewriteWordRe.FindAllString(text, -1) {
+		if trimWords[strings.ToLower(w)] {
+			return strings.ToLower(w)
+		}
+	}
+	return ""
+}
+
+// topicWords returns the content words of text, excluding products and trims.
+func topicWords(text string, products []string) []string {
+	productWords := make(map[string]bool)
+	for _, w := range rewriteWords(extractProduct(text, products)) {
+		productWords[w] = true
+	}
+
+	var topic []string
+	for _, w := range rewriteWordRe.FindAllString(text, -1) {
+		lower := strings.ToLower(w)
+		if rewriteStopWords[lower] || trimWords[lower] || productWords[strings.TrimSuffix(lower, "'s")] {
+			continue
+		}
+		topic = append(topic, lower)
+	}
+	return topic
+}
+
+// isFragment reports whether a question is too short to stand alone.
+func isFragment(question string) bool {
+	words := rewriteWordRe.FindAllString(question, -1)
+	return len(words) > 0 && len(words) <= 3 && !strings.HasPrefix(strings.ToLower(words[0]), "what") &&
+		!strings.HasPrefix(strings.ToLower(words[0]), "how")
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func joinNonEmpty(a, b string) string {
+	if a == "" || b == "" {
+		return a + b
+	}
+	return a + " " + b
+}
+
+// LLMRewriter rewrites questions with a language model, falling back to rules
+// when the model is unavailable or returns nothing.
+type LLMRewriter struct {
+	client   LLMCompleter
+	fallback QueryRewriter
+}
+
+// NewLLMRewriter creates a new LLM-backed rewriter.
+func NewLLMRewriter(client LLMCompleter) *LLMRewriter {
+	return &LLMRewriter{
+		client:   client,
+		fallback: NewRuleRewriter(),
+	}
+}
+
+// Rewrite asks the model for a standalone version of the question.
+func (rw *LLMRewriter) Rewrite(ctx context.Context, question string, history []ConversationMessage, products []string) (*QueryRewrite, error) {
+	fallback, err := rw.fallback.Rewrite(ctx, question, history, products)
+	if err != nil || len(history) == 0 || rw.client == nil {
+		return fallback, err
+	}
+
+	completion, err := rw.client.Complete(ctx, rewritePrompt(question, history))
+	if err != nil {
+		return fallback, nil
+	}
+	rewritten := strings.Trim(strings.TrimSpace(completion), `"`)
+	if rewritten == "" || strings.Contains(rewritten, "\n") {
+		return fallback, nil
+	}
+
+	return &QueryRewrite{
+		Original:  question,
+		Question:  rewritten,
+		Rewritten: rewritten != question,
+		Method:    RewriteMethodLLM,
+		Focus:     fallback.Focus,
+	}, nil
+}
+
+// rewritePrompt builds the instruction prompt for LLM rewriting.
+func rewritePrompt(question string, history []ConversationMessage) string {
+	if len(history) > MaxRewriteTurns {
+		history = history[len(history)-MaxRewriteTurns:]
+	}
+
+	var b strings.Builder
+	b.WriteString("Rewrite the follow-up question as a standalone question about a vehicle. ")
+	b.WriteString("Resolve pronouns and omitted products, trims and topics from the conversation. ")
+	b.WriteString("Reply with the question only.\n\nConversation:\n")
+	for _, msg := range history {
+		role := msg.Role
+		if role == "" {
+			role = "user"
+		}
+		fmt.Fprintf(&b, "%s: %s\n", role, msg.Content)
+	}
+	fmt.Fprintf(&b, "\nFollow-up: %s\nStandalone question:", question)
+	return b.String()
+}
@@ -0,0 +1,128 @@
+package retrieval
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/observability"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testProducts are the product names and aliases known to the rewriter tests.
+var testProducts = []string{"Toyota Camry", "Camry", "Toyota RAV4", "RAV4"}
+
+func TestRuleRewriter_SalesConversation(t *testing.T) {
+	rw := NewRuleRewriter()
+	ctx := context.Background()
+
+	history := []ConversationMessage{
+		{Role: "user", Content: "What's the mileage of the Camry?"},
+		{Role: "assistant", Content: "The Camry gets 32 mpg combined."},
+	}
+
+	tests := []struct {
+		question string
+		history  []ConversationMessage
+		expected string
+	}{
+		{"And the torque?", history, "What is the torque of the Camry?"},
+		{"What colours does it come in?", history, "What colours does the Camry come in?"},
+		{"How does the hybrid compare?", history, "How does the Camry hybrid compare to the Camry?"},
+		{"Is the hybrid available in blue?", history, "Is the Camry hybrid available in blue?"},
+		{"What about the hybrid?", history, "What is the mileage of the Camry hybrid?"},
+		{"What is the towing capacity of the RAV4?", history, "What is the towing capacity of the RAV4?"},
+		{"Mileage?", []ConversationMessage{{Role: "user", Content: "Tell me about the Camry"}}, "What is the mileage of the Camry?"},
+		{"Does it have ISOFIX?", history, "Does the Camry have ISOFIX?"},
+		{"It's available in blue?", history, "the Camry is available in blue?"},
+		{"And the torque?", nil, "And the torque?"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.question, func(t *testing.T) {
+			result, err := rw.Rewrite(ctx, tt.question, tt.history, testProducts)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result.Question)
+			assert.Equal(t, tt.expected != tt.question, result.Rewritten)
+		})
+	}
+}
+
+func TestConversationFocus(t *testing.T) {
+	focus := conversationFocus([]ConversationMessage{
+		{Role: "user", Content: "What's the mileage of the Camry?"},
+		{Role: "user", Content: "And the torque?"},
+		{Role: "user", Content: "How does the hybrid compare?"},
+	}, testProducts)
+	assert.Equal(t, "Camry", focus.Product)
+	assert.Equal(t, "hybrid", focus.Trim)
+	assert.Equal(t, "torque", focus.Category)
+}
+
+func TestExtractProduct_KnownNamesOnly(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected string
+	}{
+		{"Mileage?", ""},
+		{"Does it have ISOFIX?", ""},
+		{"What's the Camry's mileage?", "Camry"},
+		{"Is the Toyota Camry hybrid quieter?", "Toyota Camry"},
+		{"Compare the rav4 to the Camry", "RAV4"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			assert.Equal(t, tt.expected, extractProduct(tt.text, testProducts))
+		})
+	}
+	assert.Empty(t, extractProduct("What's the mileage of the Camry?", nil), "nothing is a product without known names")
+}
+
+type stubCompleter struct {
+	completion string
+	err        error
+}
+
+func (s stubCompleter) Complete(ctx context.Context, prompt string) (string, error) {
+	return s.completion, s.err
+}
+
+func TestLLMRewriter_FallsBackToRules(t *testing.T) {
+	history := []ConversationMessage{{Role: "user", Content: "What's the mileage of the Camry?"}}
+
+	rw := NewLLMRewriter(stubCompleter{completion: "What is the torque of the Toyota Camry?"})
+	result, err := rw.Rewrite(context.Background(), "And the torque?", history, testProducts)
+	require.NoError(t, err)
+	assert.Equal(t, RewriteMethodLLM, result.Method)
+	assert.Equal(t, "What is the torque of the Toyota Camry?", result.Question)
+
+	rw = NewLLMRewriter(stubCompleter{err: errors.New("unavailable")})
+	result, err = rw.Rewrite(context.Background(), "And the torque?", history, testProducts)
+	require.NoError(t, err)
+	assert.Equal(t, RewriteMethodRule, result.Method)
+	assert.Equal(t, "What is the torque of the Camry?", result.Question)
+}
+
+func TestRouter_RecordsRewrite(t *testing.T) {
+	logger := observability.NewLogger(observability.LogConfig{Level: "error"})
+	router := NewRouter(logger, nil, nil, nil, nil, RouterConfig{})
+	router.SetEntityLinker(NewEntityLinker(&stubCatalog{
+		products: []*storage.Product{testProduct("Toyota Camry", 2024, "")},
+	}, DefaultLinkerConfig()))
+
+	resp, err := router.Query(context.Background(), RetrievalRequest{
+		TenantID: uuid.New(),
+		Question: "And the torque?",
+		ConversationContext: []ConversationMessage{
+			{Role: "user", Content: "What's the mileage of the Camry?"},
+		},
+	})
+	require.NoError(t, err)
+	require.NotNil(t, resp.Rewrite)
+	assert.True(t, resp.Rewrite.Rewritten)
+	assert.Equal(t, "What is the torque of the Camry?", resp.Rewrite.Question)
+	assert.Equal(t, "And the torque?", resp.Rewrite.Original)
+}
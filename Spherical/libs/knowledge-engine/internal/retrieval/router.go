@@ -3,28 +3,38 @@ package retrieval
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"math"
 	"regexp"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/cache"
 	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/embedding"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/lexical"
 	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/observability"
 	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/storage"
+	"go.opentelemetry.io/otel/attribute"
+	oteltrace "go.opentelemetry.io/otel/trace"
 )
 
 // Intent represents the classified intent of a query.
 type Intent string
 
 const (
-	IntentSpecLookup  Intent = "spec_lookup"
-	IntentUSPLookup   Intent = "usp_lookup"
-	IntentComparison  Intent = "comparison"
-	IntentFAQ         Intent = "faq"
-	IntentUnknown     Intent = "unknown"
+	IntentSpecLookup   Intent = "spec_lookup"
+	IntentUSPLookup    Intent = "usp_lookup"
+	IntentComparison   Intent = "comparison"
+	IntentFAQ          Intent = "faq"
+	IntentPricing      Intent = "pricing"
+	IntentAvailability Intent = "availability"
+	IntentWarranty     Intent = "warranty"
+	IntentAccessory    Intent = "accessory"
+	IntentUnknown      Intent = "unknown"
 )
 
 // RetrievalRequest represents a knowledge retrieval query.
@@ -38,12 +48,35 @@ type RetrievalRequest struct {
 	Filters             RetrievalFilters
 	MaxChunks           int
 	IncludeLineage      bool
+	// Explain returns a Trace of intent, keywords, path, scores, filters and timings.
+	Explain bool
+	// IncludeBenchmarks adds public benchmark products of other tenants, labelled as such.
+	IncludeBenchmarks bool
+	// AsOf answers from the campaign versions live at this moment and the spec
+	// values effective then, instead of the published ones.
+	AsOf *time.Time
+	// CampaignVersion answers from this version of the campaign, taking precedence over AsOf.
+	CampaignVersion *int
+
+	// snapshot is the resolved point in time of an AsOf or CampaignVersion request.
+	snapshot *snapshot
+	// expansions are computed by the router and searched alongside the question.
+	expansions []QueryExpansion
+	// embedding is the question embedding computed for the semantic cache, reused by vector search.
+	embedding *questionEmbedding
+	// revalidate recomputes a response in the background, bypassing the caches.
+	revalidate bool
+	// mentions are the product and trim names linked in the question, which
+	// are never taken as the attribute of a numeric predicate.
+	mentions []string
 }
 
 // RetrievalFilters holds filtering options.
 type RetrievalFilters struct {
 	Categories []string
 	ChunkTypes []storage.ChunkType
+	// BlockTypes restricts chunks to those built from feature blocks of these types.
+	BlockTypes []storage.BlockType
 }
 
 // ConversationMessage represents a conversation turn.
@@ -60,10 +93,26 @@ type RetrievalResponse struct {
 	SemanticChunks  []SemanticChunk
 	Comparisons     []ComparisonResult
 	Lineage         []LineageInfo
+	// Evidence is the fused ranking of facts and chunks, set in hybrid mode.
+	Evidence []Evidence
+	// Rewrite records how a follow-up question was resolved against the conversation.
+	Rewrite *QueryRewrite
+	// Links are the products and trims recognized in the question.
+	Links []EntityLink
+	// Predicates are the numeric constraints and superlatives evaluated against spec values.
+	Predicates []NumericPredicate
+	// Availability tabulates attribute values per trim for availability questions.
+	Availability []AvailabilityMatrix
+	// Trace explains how the query was answered, set when the request asked to explain.
+	Trace *Trace
+	// Snapshot names the campaign versions used, set for AsOf and CampaignVersion requests.
+	Snapshot *Snapshot
 }
 
 // SpecFact represents a structured specification fact.
 type SpecFact struct {
+	// SpecValueID identifies the stored value the fact was read from.
+	SpecValueID       uuid.UUID
 	SpecItemID        uuid.UUID
 	Category          string
 	Name              string
@@ -71,7 +120,13 @@ type SpecFact struct {
 	Unit              string
 	Confidence        float64
 	CampaignVariantID uuid.UUID
-	Source            SourceRef
+	// Origin reports whether the value is local to, overridden in, or inherited by the requested variant.
+	Origin storage.ValueOrigin
+	// Score is the calibrated lexical relevance in [0, 1], when the lexical index was used.
+	Score  float64
+	Source SourceRef
+	// Benchmark is set when the fact comes from another tenant's benchmark product.
+	Benchmark *BenchmarkRef
 }
 
 // SemanticChunk represents a retrieved semantic chunk.
@@ -83,6 +138,8 @@ type SemanticChunk struct {
 	Score     float32
 	Metadata  map[string]interface{}
 	Source    SourceRef
+	// Benchmark is set when the chunk comes from another tenant's benchmark product.
+	Benchmark *BenchmarkRef
 }
 
 // SourceRef contains source document reference.
@@ -101,6 +158,14 @@ type ComparisonResult struct {
 	SecondaryValue     string
 	Verdict            storage.Verdict
 	Narrative          string
+	// Benchmark is set when the secondary product is another tenant's benchmark product.
+	Benchmark *BenchmarkRef
+}
+
+// LineageSource supplies the recorded lineage events of a resource, newest
+// first. storage.LineageRepository reads them from lineage_events.
+type LineageSource interface {
+	GetByResource(ctx context.Context, tenantID uuid.UUID, resourceType string, resourceID uuid.UUID) ([]*storage.LineageEvent, error)
 }
 
 // LineageInfo contains lineage metadata.
@@ -110,6 +175,9 @@ type LineageInfo struct {
 	Action           storage.LineageAction
 	DocumentSourceID *uuid.UUID
 	OccurredAt       time.Time
+	// CampaignVariantID and Origin describe where an inherited value was resolved from.
+	CampaignVariantID *uuid.UUID
+	Origin            storage.ValueOrigin
 }
 
 // Router orchestrates hybrid retrieval combining structured and semantic search.
@@ -120,8 +188,20 @@ type Router struct {
 	embedder         embedding.Embedder // For generating query embeddings
 	intentClassifier *IntentClassifier
 	specViewRepo     *storage.SpecViewRepository
+	lexicalIndex     *lexical.Index
+	chunkSource      lexical.ChunkSource
+	rewriter         QueryRewriter
+	linker           *EntityLinker
+	expander         *QueryExpander
+	generator        AnswerGenerator
+	benchmarks       *benchmarks
+	snapshots        SnapshotSource
+	lineage          LineageSource
+	semanticCache    *SemanticCache
+	indexes          embeddingIndexes
+	inflight         cache.FlightGroup[*RetrievalResponse]
 	config           RouterConfig
-	metrics          *RouterMetrics
+	metrics          *observability.Metrics
 }
 
 
@@ -134,27 +214,23 @@ type RouterConfig struct {
 	KeywordConfidenceThreshold float64 // Threshold for keyword-only path (default 0.8)
 	CacheResults              bool
 	CacheTTL                  time.Duration
-}
-
-// RouterMetrics tracks router performance metrics.
-type RouterMetrics struct {
-	KeywordOnlyCount int64
-	HybridCount      int64
-	VectorOnlyCount  int64
-	KeywordLatencyMs []int64
-	HybridLatencyMs  []int64
-	VectorLatencyMs  []int64
-	ConfidenceScores []float64
-}
-
-// NewRouterMetrics creates a new metrics tracker.
-func NewRouterMetrics() *RouterMetrics {
-	return &RouterMetrics{
-		KeywordLatencyMs: make([]int64, 0),
-		HybridLatencyMs:  make([]int64, 0),
-		VectorLatencyMs:  make([]int64, 0),
-		ConfidenceScores: make([]float64, 0),
-	}
+	// CacheRevalidateWindow serves cached responses this close to expiry while
+	// refreshing them in the background (default a fifth of CacheTTL).
+	CacheRevalidateWindow time.Duration
+	// LexicalMinScore is the minimum calibrated BM25 score for a spec fact (default 0.2).
+	LexicalMinScore float64
+	// Hybrid enables concurrent lexical and vector retrieval with result fusion.
+	Hybrid HybridConfig
+	// TenantHybrid overrides Hybrid for specific tenants.
+	TenantHybrid map[uuid.UUID]HybridConfig
+	// Answer bounds the evidence used for answer synthesis.
+	Answer AnswerConfig
+	// Expansion configures synonym, alias, unit and spelling query expansion.
+	Expansion ExpansionConfig
+	// Benchmarks bounds cross-tenant benchmark retrieval, enabled with SetBenchmarkSource.
+	Benchmarks BenchmarkConfig
+	// SemanticCache reuses responses to questions with similar embeddings.
+	SemanticCache SemanticCacheConfig
 }
 
 // NewRouter creates a new retrieval router.
@@ -178,7 +254,24 @@ func NewRouter(
 	if cfg.CacheTTL <= 0 {
 		cfg.CacheTTL = 5 * time.Minute
 	}
+	if cfg.CacheRevalidateWindow <= 0 {
+		cfg.CacheRevalidateWindow = cfg.CacheTTL / 5
+	}
+	if cfg.LexicalMinScore <= 0 {
+		cfg.LexicalMinScore = 0.2
+	}
+	cfg.Benchmarks = cfg.Benchmarks.withDefaults()
+
+	// The semantic cache matches question embeddings, so it needs an embedder
+	var semanticCache *SemanticCache
+	switch {
+	case cfg.SemanticCache.Enabled && embedder == nil:
+		logger.Warn().Msg("Semantic cache is enabled but there is no embedder, serving without it")
+	case cfg.SemanticCache.Enabled:
+		semanticCache = NewSemanticCache(cfg.SemanticCache)
+	}
 
+	lexicalIndex := lexical.NewIndex(lexical.DefaultConfig())
 	return &Router{
 		logger:           logger,
 		cache:            cache,
@@ -186,22 +279,137 @@ func NewRouter(
 		embedder:         embedder,
 		intentClassifier: NewIntentClassifier(),
 		specViewRepo:     specViewRepo,
+		lexicalIndex:     lexicalIndex,
+		rewriter:         NewRuleRewriter(),
+		expander:         NewQueryExpander(cfg.Expansion, lexicalIndex.Analyzer()),
+		generator:        NewTemplateGenerator(),
+		semanticCache:    semanticCache,
 		config:           cfg,
-		metrics:          NewRouterMetrics(),
 	}
 }
 
-// Query executes a hybrid retrieval query.
+// Query executes a hybrid retrieval query. Concurrent identical requests
+// share one computation, so a burst after a cache flush embeds and searches
+// once; explain requests run alone so each gets its own trace.
 func (r *Router) Query(ctx context.Context, req RetrievalRequest) (*RetrievalResponse, error) {
-	start := time.Now()
+	if req.IncludeBenchmarks && r.benchmarks == nil {
+		return nil, ErrBenchmarksUnavailable
+	}
 
 	// Apply defaults
 	if req.MaxChunks <= 0 {
 		req.MaxChunks = r.config.MaxChunks
 	}
 
+	ctx, span := observability.StartSpan(ctx, "retrieval.Query",
+		attribute.String("tenant_id", req.TenantID.String()),
+		attribute.Bool("explain", req.Explain),
+	)
+	defer span.End()
+
+	if req.Explain {
+		return r.query(ctx, req)
+	}
+	resp, shared, err := r.inflight.Do(ctx, requestKey(req), func(ctx context.Context) (*RetrievalResponse, error) {
+		return r.query(ctx, req)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if shared {
+		r.logger.Debug().Str("tenant_id", req.TenantID.String()).Msg("Shared in-flight retrieval")
+	}
+	return copyResponse(resp), nil
+}
+
+// copyResponse copies a response shared by coalesced callers and the cache,
+// so a caller modifying its response or the slices in it affects no other.
+// Explain traces are not copied; explain requests are never shared.
+func copyResponse(resp *RetrievalResponse) *RetrievalResponse {
+	result := *resp
+	result.StructuredFacts = slices.Clone(resp.StructuredFacts)
+	result.SemanticChunks = slices.Clone(resp.SemanticChunks)
+	result.Comparisons = slices.Clone(resp.Comparisons)
+	result.Lineage = slices.Clone(resp.Lineage)
+	result.Evidence = slices.Clone(resp.Evidence)
+	result.Links = slices.Clone(resp.Links)
+	result.Predicates = slices.Clone(resp.Predicates)
+	result.Availability = slices.Clone(resp.Availability)
+	if resp.Rewrite != nil {
+		rewrite := *resp.Rewrite
+		result.Rewrite = &rewrite
+	}
+	if resp.Snapshot != nil {
+		snapshot := *resp.Snapshot
+		snapshot.Versions = slices.Clone(resp.Snapshot.Versions)
+		result.Snapshot = &snapshot
+	}
+	return &result
+}
+
+// query executes a retrieval query without coalescing.
+func (r *Router) query(ctx context.Context, req RetrievalRequest) (*RetrievalResponse, error) {
+	start := time.Now()
+	span := oteltrace.SpanFromContext(ctx)
+
+	// Collect an explain trace through the context when requested
+	var trace *Trace
+	if req.Explain {
+		trace = &Trace{}
+		ctx = withTrace(ctx, trace)
+	}
+
+	// Revalidation reruns the request as the caller sent it, before rewriting and linking
+	original := req
+
+	// Resolve follow-up questions against previous turns
+	stageCtx, endStage := startStage(ctx, "rewrite")
+	rewrite := r.rewriteQuestion(stageCtx, req)
+	if rewrite != nil {
+		req.Question = rewrite.Question
+	}
+	endStage()
+
+	// Resolve product and trim mentions the caller did not supply as IDs
+	stageCtx, endStage = startStage(ctx, "link_entities")
+	links := r.linkEntities(stageCtx, &req)
+	for _, link := range links {
+		req.mentions = append(req.mentions, link.Mention)
+	}
+	endStage()
+
+	// Answer from the campaign versions live at the requested time
+	if req.pointInTime() && req.snapshot == nil {
+		stageCtx, endStage = startStage(ctx, "resolve_snapshot")
+		err := r.resolveSnapshot(stageCtx, &req)
+		endStage()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Add synonyms, spec aliases, unit words and spelling corrections as weighted terms
+	stageCtx, endStage = startStage(ctx, "expand_query")
+	req.expansions = r.expandQuery(stageCtx, req)
+	endStage()
+
 	// Classify intent
-	intent := r.classifyIntent(req)
+	_, endStage = startStage(ctx, "classify_intent")
+	intent, intentConfidence := r.classifyIntent(req)
+	endStage()
+	span.SetAttributes(attribute.String("intent", string(intent)))
+	if trace != nil {
+		trace.Question = req.Question
+		trace.Intent = intent
+		trace.IntentConfidence = intentConfidence
+		trace.IntentHinted = req.IntentHint != nil
+		trace.ClassifiedIntent = intent
+		if !trace.IntentHinted {
+			trace.ClassifiedIntent, _ = r.intentClassifier.ClassifyTenant(req.TenantID, req.Question)
+		}
+		trace.Keywords = r.extractKeywords(req.Question)
+		trace.Expansions = req.expansions
+	}
 
 	r.logger.Debug().
 		Str("tenant_id", req.TenantID.String()).
@@ -210,25 +418,95 @@ func (r *Router) Query(ctx context.Context, req RetrievalRequest) (*RetrievalRes
 		Msg("Processing retrieval query")
 
 	response := &RetrievalResponse{
-		Intent: intent,
+		Intent:  intent,
+		Rewrite: rewrite,
+		Links:   links,
+		Trace:   trace,
+	}
+	if req.snapshot != nil {
+		response.Snapshot = req.snapshot.info
+	}
+
+	// Check cache; strategies below may narrow req's filters, so the key is kept
+	if trace != nil {
+		trace.Cache = CacheDisabled
+	}
+	cacheKey := r.buildCacheKey(req)
+	if r.config.CacheResults && !req.revalidate {
+		stageCtx, endStage = startStage(ctx, "cache_lookup")
+		cached, stale, err := r.checkCache(stageCtx, cacheKey)
+		endStage()
+		r.metrics.CacheLookup("response", err == nil && cached != nil)
+		if err == nil && cached != nil {
+			r.logger.Debug().Bool("stale", stale).Msg("Cache hit")
+			if stale {
+				r.revalidateCache(ctx, original)
+			}
+			hit := *cached
+			hit.Rewrite = rewrite
+			hit.Links = links
+			hit.LatencyMs = time.Since(start).Milliseconds()
+			r.metrics.ObserveRetrieval(string(PathCache), string(intent), time.Since(start))
+			span.SetAttributes(attribute.String("cache", CacheHit))
+			if trace != nil {
+				trace.Cache = CacheHit
+				trace.Path = PathCache
+				hit.Trace = trace
+			}
+			return &hit, nil
+		}
+		if trace != nil {
+			trace.Cache = CacheMiss
+		}
 	}
 
-	// Check cache
-	if r.config.CacheResults {
-		cacheKey := r.buildCacheKey(req)
-		if cached, err := r.checkCache(ctx, cacheKey); err == nil && cached != nil {
-			r.logger.Debug().Msg("Cache hit")
+	// Reuse the response to an earlier question with a similar embedding; the
+	// semantic cache holds answers from published versions only
+	if r.semanticCache != nil && !req.revalidate && req.snapshot == nil {
+		stageCtx, endStage = startStage(ctx, "semantic_cache_lookup")
+		cached, ok := r.lookupSemanticCache(stageCtx, &req, intent)
+		endStage()
+		r.metrics.CacheLookup("semantic", ok)
+		if ok {
+			cached.Rewrite = rewrite
+			cached.Links = links
 			cached.LatencyMs = time.Since(start).Milliseconds()
+			r.metrics.ObserveRetrieval(string(PathCache), string(intent), time.Since(start))
+			span.SetAttributes(attribute.String("cache", CacheSemanticHit))
+			if trace != nil {
+				trace.Cache = CacheSemanticHit
+				trace.Path = PathCache
+				cached.Trace = trace
+			}
 			return cached, nil
 		}
+		if trace != nil {
+			trace.Cache = CacheMiss
+		}
 	}
+	// Strategies below may narrow req's filters, so store under the request as looked up
+	semanticReq := req
 
-	// Track which path was used for metrics
+	// Track which path was used for the trace and metrics
 	usedVectorSearch := false
-
-	// Route based on intent
-	switch intent {
-	case IntentSpecLookup:
+	path := RetrievalPath("")
+
+	// Route based on intent, or fuse lexical and vector results in hybrid mode;
+	// strategies below run under the retrieve stage's span
+	queryCtx := ctx
+	ctx, endStage = startStage(queryCtx, "retrieve")
+	hybrid := r.hybridConfig(req.TenantID)
+	predicates := ParsePredicates(req.Question, req.mentions...)
+	switch {
+	case len(predicates) > 0 && r.queryPredicates(ctx, req, response, predicates):
+		// Numeric constraints were evaluated against spec values
+		path = PathPredicate
+
+	case hybrid.appliesTo(intent):
+		usedVectorSearch = r.queryHybrid(ctx, req, response, hybrid)
+		path = PathHybrid
+
+	case intent == IntentSpecLookup:
 		if r.config.StructuredFirst {
 			facts, confidence, err := r.queryStructuredSpecs(ctx, req)
 			if err != nil {
@@ -240,7 +518,7 @@ func (r *Router) Query(ctx context.Context, req RetrievalRequest) (*RetrievalRes
 			
 			response.StructuredFacts = facts
 			if confidence > 0 {
-				r.metrics.ConfidenceScores = append(r.metrics.ConfidenceScores, confidence)
+				r.metrics.KeywordConfidence(confidence)
 			}
 
 			// Only fallback to vector if NO results found (don't fallback if we have results, even with low confidence)
@@ -257,16 +535,13 @@ func (r *Router) Query(ctx context.Context, req RetrievalRequest) (*RetrievalRes
 					response.SemanticChunks = chunks
 					if len(chunks) > 0 {
 						usedVectorSearch = true
-						r.metrics.HybridCount++
 						r.logger.Debug().Int("chunks_found", len(chunks)).Msg("Vector search found chunks")
 					} else {
 						r.logger.Debug().Msg("Vector search returned no chunks")
 					}
 				}
-			} else if len(facts) > 0 {
-				r.metrics.KeywordOnlyCount++
-				// Don't run vector search if keyword search found results - it's fast and sufficient
 			}
+			// Don't run vector search if keyword search found results - it's fast and sufficient
 		} else {
 			// StructuredFirst is false, go straight to vector
 			chunks, err := r.querySemanticChunks(ctx, req)
@@ -275,11 +550,10 @@ func (r *Router) Query(ctx context.Context, req RetrievalRequest) (*RetrievalRes
 			} else {
 				response.SemanticChunks = chunks
 				usedVectorSearch = true
-				r.metrics.VectorOnlyCount++
 			}
 		}
 
-	case IntentUSPLookup:
+	case intent == IntentUSPLookup:
 		// USPs are stored as semantic chunks with chunk_type='usp'
 		// Filter vector search to only return USP chunks for better accuracy
 		req.Filters.ChunkTypes = []storage.ChunkType{storage.ChunkTypeUSP}
@@ -290,14 +564,19 @@ func (r *Router) Query(ctx context.Context, req RetrievalRequest) (*RetrievalRes
 			response.SemanticChunks = chunks
 			if len(chunks) > 0 {
 				usedVectorSearch = true
-				r.metrics.VectorOnlyCount++
 				r.logger.Debug().Int("chunks_found", len(chunks)).Msg("Found USP chunks")
 			} else {
 				r.logger.Debug().Msg("No USP chunks found in vector database")
 			}
 		}
 
-	case IntentComparison:
+	case intent == IntentAvailability && r.queryAvailability(ctx, req, response):
+		// Values were tabulated per trim
+
+	case intent == IntentPricing || intent == IntentWarranty || intent == IntentAccessory:
+		usedVectorSearch = r.queryIntentStrategy(ctx, req, response, intentStrategies[intent])
+
+	case intent == IntentComparison:
 		// Query comparison rows if available
 		comparisons, err := r.queryComparisons(ctx, req)
 		if err != nil {
@@ -311,7 +590,6 @@ func (r *Router) Query(ctx context.Context, req RetrievalRequest) (*RetrievalRes
 		if err == nil {
 			response.SemanticChunks = chunks
 			usedVectorSearch = true
-			r.metrics.HybridCount++
 		}
 
 	default:
@@ -327,7 +605,6 @@ func (r *Router) Query(ctx context.Context, req RetrievalRequest) (*RetrievalRes
 			if len(chunks) > 0 {
 				response.SemanticChunks = chunks
 				usedVectorSearch = true
-				r.metrics.HybridCount++
 			}
 		} else if confidence < r.config.KeywordConfidenceThreshold && len(facts) > 0 && r.config.SemanticFallback {
 			// We have facts but low confidence - add semantic chunks for context, but be selective
@@ -340,42 +617,63 @@ func (r *Router) Query(ctx context.Context, req RetrievalRequest) (*RetrievalRes
 			if len(chunks) > 0 {
 				response.SemanticChunks = chunks
 				usedVectorSearch = true
-				r.metrics.HybridCount++
 			}
-		} else if len(facts) > 0 {
-			// We have facts with good confidence, don't add semantic chunks to avoid noise
-			r.metrics.KeywordOnlyCount++
 		}
+		// With facts of good confidence, semantic chunks are skipped to avoid noise
+	}
+
+	endStage()
+	ctx = queryCtx
+
+	// Add labelled results from other tenants' public benchmark products
+	if req.IncludeBenchmarks && r.benchmarks != nil {
+		stageCtx, endStage = startStage(ctx, "benchmarks")
+		r.queryBenchmarks(stageCtx, req, intent, response)
+		endStage()
+	}
+
+	if path == "" {
+		path = retrievalPath(usedVectorSearch, response)
+	}
+	if trace != nil {
+		trace.Path = path
 	}
 
 	// Add lineage if requested
 	if req.IncludeLineage {
-		lineage, err := r.queryLineage(ctx, req, response)
+		stageCtx, endStage = startStage(ctx, "lineage")
+		lineage, err := r.queryLineage(stageCtx, req, response)
 		if err == nil {
 			response.Lineage = lineage
 		}
+		endStage()
 	}
 
 	response.LatencyMs = time.Since(start).Milliseconds()
 
-	// Track latency metrics
-	if usedVectorSearch {
-		if len(response.StructuredFacts) > 0 {
-			r.metrics.HybridLatencyMs = append(r.metrics.HybridLatencyMs, response.LatencyMs)
-		} else {
-			r.metrics.VectorLatencyMs = append(r.metrics.VectorLatencyMs, response.LatencyMs)
-		}
-	} else {
-		r.metrics.KeywordLatencyMs = append(r.metrics.KeywordLatencyMs, response.LatencyMs)
+	r.metrics.ObserveRetrieval(string(path), string(intent), time.Since(start))
+	if response.empty() {
+		r.metrics.ZeroResult(string(intent))
 	}
 
 	// Only cache if vector search was used (not keyword-only results)
 	if r.config.CacheResults && r.cache != nil && usedVectorSearch {
-		cacheKey := r.buildCacheKey(req)
-		_ = r.cacheResult(ctx, cacheKey, response)
+		cached := *response
+		cached.Trace = nil
+		_ = r.cacheResult(ctx, cacheKey, &cached)
 	}
+	if r.semanticCache != nil && req.embedding != nil && req.snapshot == nil && !response.empty() {
+		r.semanticCache.Store(semanticReq, intent, req.embedding.vector, response)
+	}
+
+	span.SetAttributes(
+		attribute.String("path", string(path)),
+		attribute.Int("structured_facts", len(response.StructuredFacts)),
+		attribute.Int("semantic_chunks", len(response.SemanticChunks)),
+	)
 
 	r.logger.Info().
+		Ctx(ctx).
 		Int64("latency_ms", response.LatencyMs).
 		Int("structured_facts", len(response.StructuredFacts)).
 		Int("semantic_chunks", len(response.SemanticChunks)).
@@ -384,28 +682,175 @@ func (r *Router) Query(ctx context.Context, req RetrievalRequest) (*RetrievalRes
 	return response, nil
 }
 
-// classifyIntent determines the query intent.
-func (r *Router) classifyIntent(req RetrievalRequest) Intent {
+// SetQueryRewriter replaces the rule-based rewriter, e.g. with an LLMRewriter.
+func (r *Router) SetQueryRewriter(rewriter QueryRewriter) {
+	if rewriter != nil {
+		r.rewriter = rewriter
+	}
+}
+
+// SetEntityLinker enables linking product and trim mentions in questions to
+// catalogue IDs. Its product names and aliases are also the products the query
+// rewriter recognizes.
+func (r *Router) SetEntityLinker(linker *EntityLinker) {
+	r.linker = linker
+}
+
+// SetLineageSource reports the latest recorded lineage event of each returned
+// fact and chunk when a request includes lineage.
+func (r *Router) SetLineageSource(source LineageSource) {
+	r.lineage = source
+}
+
+// SetMetrics records retrieval latency, cache lookups, zero-result queries
+// and embedding calls in metrics.
+func (r *Router) SetMetrics(metrics *observability.Metrics) {
+	r.metrics = metrics
+}
+
+// embed embeds the question, counting the call.
+func (r *Router) embed(ctx context.Context, question string) ([]float32, error) {
+	vector, err := r.embedder.EmbedSingle(ctx, question)
+	r.metrics.EmbeddingCall(err)
+	return vector, err
+}
+
+// linkEntities links mentions in the question and fills ProductIDs and
+// CampaignVariantID when the caller left them empty.
+func (r *Router) linkEntities(ctx context.Context, req *RetrievalRequest) []EntityLink {
+	if r.linker == nil || (len(req.ProductIDs) > 0 && req.CampaignVariantID != nil) {
+		return nil
+	}
+
+	links, err := r.linker.Link(ctx, req.TenantID, req.Question)
+	if err != nil {
+		r.logger.Warn().Err(err).Msg("Entity linking failed")
+		return nil
+	}
+	if len(links) == 0 {
+		return nil
+	}
+
+	fillProducts := len(req.ProductIDs) == 0
+	if fillProducts {
+		seen := make(map[uuid.UUID]bool)
+		for i := range links {
+			if !seen[links[i].ProductID] {
+				seen[links[i].ProductID] = true
+				req.ProductIDs = append(req.ProductIDs, links[i].ProductID)
+			}
+			if links[i].Kind == EntityProduct {
+				links[i].Applied = true
+			}
+		}
+	}
+
+	// A variant is only implied when exactly one trim of a requested product was named
+	if req.CampaignVariantID == nil {
+		variant := -1
+		for i, link := range links {
+			if link.Kind != EntityCampaignVariant || !containsUUID(req.ProductIDs, link.ProductID) {
+				continue
+			}
+			if variant >= 0 && links[variant].ID != link.ID {
+				variant = -1
+				break
+			}
+			variant = i
+		}
+		if variant >= 0 {
+			id := links[variant].ID
+			req.CampaignVariantID = &id
+			links[variant].Applied = true
+		}
+	}
+
+	r.logger.Debug().
+		Int("links", len(links)).
+		Int("product_ids", len(req.ProductIDs)).
+		Bool("campaign_linked", req.CampaignVariantID != nil).
+		Msg("Linked entities in question")
+
+	return links
+}
+
+func containsUUID(ids []uuid.UUID, id uuid.UUID) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+// rewriteQuestion rewrites the question using the conversation context, if any.
+func (r *Router) rewriteQuestion(ctx context.Context, req RetrievalRequest) *QueryRewrite {
+	if len(req.ConversationContext) == 0 || r.rewriter == nil {
+		return nil
+	}
+
+	var products []string
+	if r.linker != nil {
+		names, err := r.linker.ProductNames(ctx, req.TenantID)
+		if err != nil {
+			r.logger.Warn().Err(err).Msg("Failed to load product names for query rewrite")
+		}
+		products = names
+	}
+
+	rewrite, err := r.rewriter.Rewrite(ctx, req.Question, req.ConversationContext, products)
+	if err != nil {
+		r.logger.Warn().Err(err).Msg("Query rewrite failed, using original question")
+		return nil
+	}
+	if rewrite.Rewritten {
+		r.logger.Debug().
+			Str("original", rewrite.Original).
+			Str("rewritten", rewrite.Question).
+			Str("method", rewrite.Method).
+			Msg("Rewrote follow-up question")
+	}
+	return rewrite
+}
+
+// classifyIntent determines the query intent and the classifier confidence.
+func (r *Router) classifyIntent(req RetrievalRequest) (Intent, float64) {
 	// Use hint if provided and confident
 	if req.IntentHint != nil {
-		return *req.IntentHint
+		return *req.IntentHint, 1.0
 	}
 
 	// Use classifier
-	intent, confidence := r.intentClassifier.Classify(req.Question)
+	intent, confidence := r.intentClassifier.ClassifyTenant(req.TenantID, req.Question)
 	if confidence >= r.config.IntentConfidenceThreshold {
-		return intent
+		return intent, confidence
 	}
 
-	return IntentUnknown
+	return IntentUnknown, confidence
+}
+
+// retrievalPath names the path taken by intent routing from the results it produced.
+func retrievalPath(usedVectorSearch bool, response *RetrievalResponse) RetrievalPath {
+	switch {
+	case usedVectorSearch && len(response.StructuredFacts) > 0:
+		return PathHybrid
+	case usedVectorSearch:
+		return PathVector
+	case len(response.StructuredFacts) > 0:
+		return PathKeywordOnly
+	default:
+		return PathNone
+	}
 }
 
 // queryStructuredSpecs retrieves structured specification facts via keyword search.
 // Returns facts, confidence score, and error.
 func (r *Router) queryStructuredSpecs(ctx context.Context, req RetrievalRequest) ([]SpecFact, float64, error) {
 	r.logger.Debug().Msg("Querying structured specs")
+	trace := traceFrom(ctx)
+	defer trace.stage("keyword_search", time.Now())
 
-	if r.specViewRepo == nil {
+	if r.specViewRepo == nil && req.snapshot == nil && !r.lexicalIndex.HasDocuments(req.TenantID) {
 		// If spec view repo is not configured, return empty results with low confidence
 		// This allows the router to fall back to vector search
 		return nil, 0.0, nil
@@ -421,12 +866,104 @@ func (r *Router) queryStructuredSpecs(ctx context.Context, req RetrievalRequest)
 		return nil, 0.0, nil
 	}
 
+	// Resolve the campaign inheritance chain so base values are visible to derived variants
+	scope := r.resolveCampaignScope(ctx, req)
+
+	// Prefer the BM25 lexical index; fall back to ILIKE keyword search when it is unavailable
+	facts, lexicalScored := r.searchLexicalSpecs(ctx, req, keywords, scope)
+	if !lexicalScored {
+		facts = r.searchKeywordSpecs(ctx, req, keywords, scope)
+		facts = append(facts, r.searchExpandedKeywordSpecs(ctx, req, facts, scope)...)
+	}
+
+	// Limit to top results to avoid noise, but keep more for color searches or multi-keyword queries
+	maxResults := 30 // Increased default to handle multi-keyword queries better
+	queryLower := strings.ToLower(req.Question)
+	
+	// Check for color-related queries (handle both singular/plural and US/UK spelling)
+	// Also check for queries that might be asking about colors (body colors, exterior colors, etc.)
+	hasColorKeyword := false
+	for _, kw := range keywords {
+		kwLower := strings.ToLower(kw)
+		if kwLower == "color" || kwLower == "colors" || kwLower == "colour" || kwLower == "colours" {
+			hasColorKeyword = true
+			break
+		}
+	}
+	isColorQuery := hasColorKeyword || strings.Contains(queryLower, "color") || strings.Contains(queryLower, "colour") || 
+	   strings.Contains(queryLower, "colors") || strings.Contains(queryLower, "colours") ||
+	   strings.Contains(queryLower, "body color") || strings.Contains(queryLower, "body colour") ||
+	   strings.Contains(queryLower, "exterior color") || strings.Contains(queryLower, "exterior colour")
+	
+	if isColorQuery {
+		maxResults = 100 // Allow many results for color queries to get all color options
+	}
+	
+	// Multi-keyword queries - limit results more aggressively for focused queries
+	// BUT: Don't limit color queries - they need to return all available colors
+	if len(keywords) >= 2 && !isColorQuery {
+		// For focused 2-keyword queries (like "child seat"), limit to top 5
+		if len(keywords) == 2 {
+			maxResults = 5 // Focused queries - only top 5 most relevant
+		} else {
+			maxResults = 60 // Multi-keyword queries like "weight wheels colors length" need more results
+		}
+	}
+	r.logger.Debug().
+		Int("facts_before_limit", len(facts)).
+		Int("max_results", maxResults).
+		Bool("is_color_query", isColorQuery).
+		Int("keyword_count", len(keywords)).
+		Msg("Applying maxResults limit")
+	if len(facts) > maxResults {
+		for _, f := range facts[maxResults:] {
+			trace.filter("max_results", factLabel(f), false, fmt.Sprintf("beyond the top %d results", maxResults))
+		}
+		facts = facts[:maxResults]
+	}
+
+	// Calculate confidence from calibrated lexical scores when available
+	var confidence float64
+	var factors map[string]float64
+	if lexicalScored {
+		confidence = lexicalConfidence(facts)
+		factors = map[string]float64{"mean_top_score": confidence}
+	} else {
+		confidence, factors = r.keywordConfidenceFactors(facts, req.Question)
+	}
+	trace.keywordConfidence(confidence, factors)
+
+	// If high confidence, log and return early
+	if confidence >= r.config.KeywordConfidenceThreshold {
+		r.logger.Debug().
+			Float64("confidence", confidence).
+			Int("results", len(facts)).
+			Msg("Keyword search sufficient, skipping vector search")
+		return facts, confidence, nil
+	}
+
+	// Low confidence - will trigger vector fallback
+	r.logger.Debug().
+		Float64("confidence", confidence).
+		Msg("Keyword search low confidence, will use vector fallback")
+	return facts, confidence, nil
+}
+
+// searchKeywordSpecs retrieves spec facts with per-keyword ILIKE searches and
+// heuristic ranking. It is used when the lexical index is unavailable.
+func (r *Router) searchKeywordSpecs(ctx context.Context, req RetrievalRequest, keywords []string, scope *campaignScope) []SpecFact {
+	if r.specViewRepo == nil {
+		return nil
+	}
+
 	// Determine search limit based on query type
 	searchLimit := 50
 	if len(keywords) > 1 {
 		searchLimit = 100 // Get more results per keyword when querying multiple keywords
 	}
-	
+
+	trace := traceFrom(ctx)
+
 	// Perform keyword search for each keyword
 	factMap := make(map[string]*SpecFact)
 	for _, keyword := range keywords {
@@ -500,8 +1037,10 @@ func (r *Router) queryStructuredSpecs(ctx context.Context, req RetrievalRequest)
 				}
 			}
 
-			// Filter by campaign variant if specified
-			if req.CampaignVariantID != nil && sv.CampaignVariantID != *req.CampaignVariantID {
+			// Filter by campaign variant if specified, keeping only effective values
+			origin, ok := scope.accept(sv)
+			if !ok {
+				trace.filter("campaign_scope", sv.CategoryName+" > "+sv.SpecName, false, "not effective for the requested variant")
 				continue
 			}
 
@@ -517,6 +1056,7 @@ func (r *Router) queryStructuredSpecs(ctx context.Context, req RetrievalRequest)
 					unit = *sv.Unit
 				}
 				factMap[key] = &SpecFact{
+					SpecValueID:        sv.ID,
 					SpecItemID:        sv.SpecItemID,
 					Category:           sv.CategoryName,
 					Name:               sv.SpecName,
@@ -524,6 +1064,7 @@ func (r *Router) queryStructuredSpecs(ctx context.Context, req RetrievalRequest)
 					Unit:               unit,
 					Confidence:         sv.Confidence,
 					CampaignVariantID:  sv.CampaignVariantID,
+					Origin:             origin,
 					Source: SourceRef{
 						DocumentSourceID: sv.SourceDocID,
 						Page:             sv.SourcePage,
@@ -540,92 +1081,48 @@ func (r *Router) queryStructuredSpecs(ctx context.Context, req RetrievalRequest)
 	}
 
 	// Rank facts by relevance to query keywords
-	facts = r.rankFactsByRelevance(facts, keywords, req.Question)
+	facts, relevance := r.rankFactsByRelevance(facts, keywords, req.Question)
 	r.logger.Debug().Int("facts_after_ranking", len(facts)).Msg("Facts after ranking")
 
 	// Filter out low-relevance facts
+	ranked := facts
 	facts = r.filterLowRelevanceFacts(facts, keywords)
 	r.logger.Debug().Int("facts_after_filtering", len(facts)).Msg("Facts after filtering")
 
-	// Limit to top results to avoid noise, but keep more for color searches or multi-keyword queries
-	maxResults := 30 // Increased default to handle multi-keyword queries better
-	queryLower := strings.ToLower(req.Question)
-	
-	// Check for color-related queries (handle both singular/plural and US/UK spelling)
-	// Also check for queries that might be asking about colors (body colors, exterior colors, etc.)
-	hasColorKeyword := false
-	for _, kw := range keywords {
-		kwLower := strings.ToLower(kw)
-		if kwLower == "color" || kwLower == "colors" || kwLower == "colour" || kwLower == "colours" {
-			hasColorKeyword = true
-			break
+	if trace != nil {
+		kept := make(map[string]bool, len(facts))
+		for _, f := range facts {
+			kept[factLabel(f)] = true
 		}
-	}
-	isColorQuery := hasColorKeyword || strings.Contains(queryLower, "color") || strings.Contains(queryLower, "colour") || 
-	   strings.Contains(queryLower, "colors") || strings.Contains(queryLower, "colours") ||
-	   strings.Contains(queryLower, "body color") || strings.Contains(queryLower, "body colour") ||
-	   strings.Contains(queryLower, "exterior color") || strings.Contains(queryLower, "exterior colour")
-	
-	if isColorQuery {
-		maxResults = 100 // Allow many results for color queries to get all color options
-	}
-	
-	// Multi-keyword queries - limit results more aggressively for focused queries
-	// BUT: Don't limit color queries - they need to return all available colors
-	if len(keywords) >= 2 && !isColorQuery {
-		// For focused 2-keyword queries (like "child seat"), limit to top 5
-		if len(keywords) == 2 {
-			maxResults = 5 // Focused queries - only top 5 most relevant
-		} else {
-			maxResults = 60 // Multi-keyword queries like "weight wheels colors length" need more results
+		for i, f := range ranked {
+			score := f.Confidence
+			if i < len(relevance) {
+				score = relevance[i]
+			}
+			trace.candidate(CandidateScore{
+				Kind:       EvidenceSpecFact,
+				ID:         f.SpecItemID,
+				Label:      factLabel(f),
+				Source:     SourceKeyword,
+				Score:      score,
+				Components: map[string]float64{"relevance": score, "extraction_confidence": f.Confidence},
+			})
+			reason := "matches query keywords"
+			if !kept[factLabel(f)] {
+				reason = "below relevance threshold"
+			}
+			trace.filter("relevance", factLabel(f), kept[factLabel(f)], reason)
 		}
 	}
-	r.logger.Debug().
-		Int("facts_before_limit", len(facts)).
-		Int("max_results", maxResults).
-		Bool("is_color_query", isColorQuery).
-		Int("keyword_count", len(keywords)).
-		Msg("Applying maxResults limit")
-	if len(facts) > maxResults {
-		facts = facts[:maxResults]
-	}
-
-	// Calculate confidence
-	confidence := r.calculateKeywordConfidence(facts, req.Question)
 
-	// If high confidence, log and return early
-	if confidence >= r.config.KeywordConfidenceThreshold {
-		r.logger.Debug().
-			Float64("confidence", confidence).
-			Int("results", len(facts)).
-			Msg("Keyword search sufficient, skipping vector search")
-		return facts, confidence, nil
-	}
-
-	// Low confidence - will trigger vector fallback
-	r.logger.Debug().
-		Float64("confidence", confidence).
-		Msg("Keyword search low confidence, will use vector fallback")
-	return facts, confidence, nil
+	return facts
 }
 
 // querySemanticChunks retrieves semantic chunks via vector search.
 func (r *Router) querySemanticChunks(ctx context.Context, req RetrievalRequest) ([]SemanticChunk, error) {
 	r.logger.Debug().Msg("Querying semantic chunks")
-
-	// Generate embedding for the question
-	var queryVector []float32
-	var err error
-	if r.embedder != nil {
-		queryVector, err = r.embedder.EmbedSingle(ctx, req.Question)
-		if err != nil {
-			r.logger.Warn().Err(err).Msg("Failed to generate query embedding, skipping vector search")
-			return nil, err
-		}
-	} else {
-		r.logger.Warn().Msg("No embedder configured, cannot perform vector search")
-		return nil, fmt.Errorf("embedder not configured")
-	}
+	trace := traceFrom(ctx)
+	defer trace.stage("vector_search", time.Now())
 
 	// Build filters
 	filters := VectorFilters{
@@ -633,16 +1130,52 @@ func (r *Router) querySemanticChunks(ctx context.Context, req RetrievalRequest)
 		ProductIDs: req.ProductIDs,
 	}
 	if req.CampaignVariantID != nil {
-		filters.CampaignVariantID = req.CampaignVariantID
+		if scope := r.resolveCampaignScope(ctx, req); len(scope.chain) > 1 {
+			filters.CampaignVariantIDs = scope.chain
+			filters.ExcludeOverridden = true
+		} else {
+			filters.CampaignVariantID = req.CampaignVariantID
+		}
+	} else if req.snapshot != nil {
+		filters.CampaignVariantIDs = req.snapshot.campaignIDs
 	}
 	if len(req.Filters.ChunkTypes) > 0 {
 		for _, ct := range req.Filters.ChunkTypes {
 			filters.ChunkTypes = append(filters.ChunkTypes, string(ct))
 		}
 	}
+	for _, bt := range req.Filters.BlockTypes {
+		filters.BlockTypes = append(filters.BlockTypes, string(bt))
+	}
+
+	// A re-embedded tenant or campaign is searched in its own index, with
+	// questions embedded by the model that built it
+	embedder, adapter := r.embedder, r.vectorAdapter
+	idx := r.vectorIndex(req.TenantID, filters)
+	if idx != nil {
+		embedder, adapter = idx.Embedder, idx.Adapter
+		filters.EmbeddingVersion = &idx.Version
+	}
+
+	// Generate embedding for the question
+	var queryVector []float32
+	var err error
+	if idx == nil && req.embedding != nil && req.embedding.question == req.Question {
+		queryVector = req.embedding.vector
+	} else if embedder != nil {
+		queryVector, err = embedder.EmbedSingle(ctx, req.Question)
+		r.metrics.EmbeddingCall(err)
+		if err != nil {
+			r.logger.Warn().Err(err).Msg("Failed to generate query embedding, skipping vector search")
+			return nil, err
+		}
+	} else {
+		r.logger.Warn().Msg("No embedder configured, cannot perform vector search")
+		return nil, fmt.Errorf("embedder not configured")
+	}
 
 	// Execute vector search
-	results, err := r.vectorAdapter.Search(ctx, queryVector, req.MaxChunks, filters)
+	results, err := adapter.Search(ctx, queryVector, req.MaxChunks, filters)
 	if err != nil {
 		r.logger.Warn().Err(err).Msg("Vector search failed")
 		return nil, fmt.Errorf("vector search: %w", err)
@@ -684,6 +1217,21 @@ func (r *Router) querySemanticChunks(ctx context.Context, req RetrievalRequest)
 	}
 	
 	for _, result := range results {
+		trace.candidate(CandidateScore{
+			Kind:   EvidenceSemanticChunk,
+			ID:     result.ID,
+			Source: SourceVector,
+			Score:  float64(result.Score),
+			Components: map[string]float64{
+				"similarity": float64(result.Score),
+				"distance":   float64(result.Distance),
+				"min_score":  float64(minScore),
+			},
+		})
+		if result.Score < minScore {
+			trace.filter("similarity", result.ID.String(), false, fmt.Sprintf("similarity %.3f below %.3f", result.Score, minScore))
+		}
+
 		// Filter by minimum score threshold
 		if result.Score >= minScore {
 			chunk := SemanticChunk{
@@ -711,11 +1259,13 @@ func (r *Router) querySemanticChunks(ctx context.Context, req RetrievalRequest)
 					}
 					// If no keyword match and score is mediocre, skip it
 					if !hasKeywordMatch && result.Score < 0.5 {
+						trace.filter("keyword_overlap", result.ID.String(), false, "no query keyword in chunk text and similarity below 0.5")
 						continue
 					}
 				}
 			}
 			filteredChunks = append(filteredChunks, chunk)
+			trace.filter("similarity", result.ID.String(), true, fmt.Sprintf("similarity %.3f", result.Score))
 			
 			// Limit total results
 			if len(filteredChunks) >= maxChunksToReturn {
@@ -745,42 +1295,94 @@ func (r *Router) queryComparisons(ctx context.Context, req RetrievalRequest) ([]
 	return nil, nil
 }
 
-// queryLineage retrieves lineage information for results.
+// queryLineage retrieves lineage information for the returned facts and chunks.
+// Action and OccurredAt come from the latest lineage event recorded for each
+// resource, and are left empty without a lineage source or recorded events.
 func (r *Router) queryLineage(ctx context.Context, req RetrievalRequest, resp *RetrievalResponse) ([]LineageInfo, error) {
 	r.logger.Debug().Msg("Querying lineage")
 
-	// TODO: Implement lineage query based on returned resource IDs
+	lineage := make([]LineageInfo, 0, len(resp.StructuredFacts)+len(resp.SemanticChunks))
+	for _, fact := range resp.StructuredFacts {
+		campaignID := fact.CampaignVariantID
+		info := LineageInfo{
+			ResourceType:      "spec_value",
+			ResourceID:        fact.SpecValueID,
+			DocumentSourceID:  fact.Source.DocumentSourceID,
+			CampaignVariantID: &campaignID,
+			// Origin tells where the value was resolved from in the inheritance chain
+			Origin: fact.Origin,
+		}
+		lineage = append(lineage, r.latestLineage(ctx, req.TenantID, info))
+	}
+	for _, chunk := range resp.SemanticChunks {
+		info := LineageInfo{
+			ResourceType:     "knowledge_chunk",
+			ResourceID:       chunk.ChunkID,
+			DocumentSourceID: chunk.Source.DocumentSourceID,
+		}
+		lineage = append(lineage, r.latestLineage(ctx, req.TenantID, info))
+	}
 
-	return nil, nil
+	return lineage, nil
 }
 
-// buildCacheKey creates a cache key for the request.
-func (r *Router) buildCacheKey(req RetrievalRequest) string {
-	parts := []string{
-		"retrieval",
-		req.TenantID.String(),
-		req.Question,
+// latestLineage fills info with the action and time of its resource's latest
+// lineage event. Lookup failures are logged and leave info unchanged.
+func (r *Router) latestLineage(ctx context.Context, tenantID uuid.UUID, info LineageInfo) LineageInfo {
+	if r.lineage == nil || info.ResourceID == uuid.Nil {
+		return info
 	}
-
-	for _, pid := range req.ProductIDs {
-		parts = append(parts, pid.String())
+	events, err := r.lineage.GetByResource(ctx, tenantID, info.ResourceType, info.ResourceID)
+	if err != nil {
+		r.logger.Warn().Err(err).
+			Str("resource_type", info.ResourceType).
+			Str("resource_id", info.ResourceID.String()).
+			Msg("Failed to load lineage events")
+		return info
 	}
-
-	if req.CampaignVariantID != nil {
-		parts = append(parts, req.CampaignVariantID.String())
+	if len(events) == 0 {
+		return info
 	}
+	latest := events[0]
+	info.Action = latest.Action
+	info.OccurredAt = latest.OccurredAt
+	if info.DocumentSourceID == nil {
+		info.DocumentSourceID = latest.DocumentSourceID
+	}
+	if info.CampaignVariantID == nil {
+		info.CampaignVariantID = latest.CampaignVariantID
+	}
+	return info
+}
 
-	return cache.CacheKey(parts...)
+// buildCacheKey creates a cache key for the request.
+func (r *Router) buildCacheKey(req RetrievalRequest) string {
+	if req.IncludeBenchmarks {
+		return benchmarkCacheKey(req.TenantID, "retrieval", requestKey(req))
+	}
+	return cache.TenantCacheKey(req.TenantID.String(), "retrieval", requestKey(req))
 }
 
-// checkCache attempts to retrieve a cached response.
-func (r *Router) checkCache(ctx context.Context, key string) (*RetrievalResponse, error) {
+// checkCache attempts to retrieve a cached response, reporting whether it is
+// within the revalidate window of its expiry.
+func (r *Router) checkCache(ctx context.Context, key string) (*RetrievalResponse, bool, error) {
 	if r.cache == nil {
-		return nil, nil
+		return nil, false, nil
 	}
 
-	// TODO: Implement cache deserialization
-	return nil, cache.ErrCacheMiss
+	data, err := r.cache.Get(ctx, key)
+	if err != nil {
+		return nil, false, err
+	}
+	var cached CachedResponse
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return nil, false, fmt.Errorf("unmarshal cached response: %w", err)
+	}
+	if cached.Response == nil || time.Now().After(cached.ExpiresAt) {
+		return nil, false, cache.ErrCacheMiss
+	}
+	stale := time.Until(cached.ExpiresAt) < r.config.CacheRevalidateWindow
+	return cached.Response, stale, nil
 }
 
 // cacheResult stores the response in cache.
@@ -789,21 +1391,66 @@ func (r *Router) cacheResult(ctx context.Context, key string, resp *RetrievalRes
 		return nil
 	}
 
-	// TODO: Implement cache serialization
-	return nil
+	now := time.Now()
+	data, err := json.Marshal(CachedResponse{
+		Response:  resp,
+		CachedAt:  now,
+		ExpiresAt: now.Add(r.config.CacheTTL),
+		Version:   now.UnixNano(),
+	})
+	if err != nil {
+		return fmt.Errorf("marshal response: %w", err)
+	}
+	return r.cache.Set(ctx, key, data, r.config.CacheTTL)
+}
+
+// revalidateCache recomputes a cached response close to expiry in the
+// background. Refreshes of the same request are coalesced, so only one runs.
+func (r *Router) revalidateCache(ctx context.Context, req RetrievalRequest) {
+	req.revalidate = true
+	req.Explain = false
+	req.embedding = nil
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		_, _, err := r.inflight.Do(ctx, "revalidate:"+requestKey(req), func(ctx context.Context) (*RetrievalResponse, error) {
+			return r.query(ctx, req)
+		})
+		if err != nil {
+			r.logger.Warn().Err(err).Msg("Failed to revalidate cached response")
+		}
+	}()
 }
 
-// IntentClassifier classifies query intent using rules and patterns.
+// IntentClassifier classifies query intent with a trained model when one is
+// loaded, falling back to rules and patterns.
 type IntentClassifier struct {
 	specPatterns       []string
 	uspPatterns        []string
 	comparisonPatterns []string
 	faqPatterns        []string
+	// Patterns for intents with dedicated strategies, matched as whole words
+	accessoryPatterns    []string
+	warrantyPatterns     []string
+	pricingPatterns      []string
+	availabilityPatterns []string
+	// Whole-word matchers compiled once from the pattern lists, in match order
+	wordMatchers []intentMatcher
+	uspRe        *regexp.Regexp
+
+	mu           sync.RWMutex
+	model        *IntentModel
+	tenantModels map[uuid.UUID]*IntentModel
+}
+
+// intentMatcher matches any of an intent's patterns as whole words.
+type intentMatcher struct {
+	re     *regexp.Regexp
+	intent Intent
 }
 
 // NewIntentClassifier creates a new intent classifier.
 func NewIntentClassifier() *IntentClassifier {
-	return &IntentClassifier{
+	c := &IntentClassifier{
 		specPatterns: []string{
 			"what is the",
 			"what's the",
@@ -822,15 +1469,7 @@ func NewIntentClassifier() *IntentClassifier {
 			"range",
 			"tell me about the",
 			"displacement",
-			"color",
-			"colour",
 			"paint",
-			"exterior color",
-			"interior color",
-			"available in",
-			"come in",
-			"what colors",
-			"what colours",
 			"speaker",
 			"speakers",
 			"audio",
@@ -893,7 +1532,87 @@ func NewIntentClassifier() *IntentClassifier {
 			"what if",
 			"help me",
 		},
+		accessoryPatterns: []string{
+			"accessory",
+			"accessories",
+			"add-on",
+			"add-ons",
+			"roof rack",
+			"floor mats",
+			"mud flaps",
+			"seat covers",
+			"tow bar",
+			"dash cam",
+		},
+		warrantyPatterns: []string{
+			"warranty",
+			"warranties",
+			"guarantee",
+			"service interval",
+			"service intervals",
+			"servicing",
+			"service cost",
+			"maintenance",
+			"roadside assistance",
+		},
+		pricingPatterns: []string{
+			"price",
+			"prices",
+			"pricing",
+			"cost",
+			"costs",
+			"how much does it cost",
+			"ex-showroom",
+			"on-road",
+			"msrp",
+			"emi",
+			"discount",
+			"discounts",
+			"offers",
+			"finance",
+			"down payment",
+			"lease",
+		},
+		availabilityPatterns: []string{
+			"color",
+			"colors",
+			"colour",
+			"colours",
+			"exterior color",
+			"interior color",
+			"available in",
+			"available on",
+			"availability",
+			"come in",
+			"comes in",
+			"which trims",
+			"which variants",
+			"which trim",
+			"which variant",
+			"what colors",
+			"what colours",
+		},
+	}
+	// Accessories and warranty come first so "How much do the floor mats
+	// cost?" targets accessory blocks
+	c.wordMatchers = []intentMatcher{
+		{wordPattern(c.accessoryPatterns), IntentAccessory},
+		{wordPattern(c.warrantyPatterns), IntentWarranty},
+		{wordPattern(c.pricingPatterns), IntentPricing},
+		{wordPattern(c.availabilityPatterns), IntentAvailability},
+	}
+	c.uspRe = wordPattern(c.uspPatterns)
+	return c
+}
+
+// wordPattern compiles patterns into one expression that matches any of them
+// in a lowercase query as whole words, so "emi" does not match "premium".
+func wordPattern(patterns []string) *regexp.Regexp {
+	quoted := make([]string, len(patterns))
+	for i, p := range patterns {
+		quoted[i] = regexp.QuoteMeta(strings.ToLower(p))
 	}
+	return regexp.MustCompile(`\b(?:` + strings.Join(quoted, "|") + `)\b`)
 }
 
 // isSingleWordQuery checks if the query is essentially a single word/keyword.
@@ -929,8 +1648,15 @@ func (c *IntentClassifier) isStopWord(word string) bool {
 	return stopWords[word]
 }
 
-// Classify determines the intent and confidence for a question.
+// Classify determines the intent and confidence for a question using the shared
+// model, or the patterns when no model is loaded.
 func (c *IntentClassifier) Classify(question string) (Intent, float64) {
+	return c.ClassifyTenant(uuid.Nil, question)
+}
+
+// ClassifyPatterns determines the intent and confidence for a question from
+// the built-in patterns alone.
+func (c *IntentClassifier) ClassifyPatterns(question string) (Intent, float64) {
 	q := strings.ToLower(question)
 
 	// Check comparison patterns first (highest priority)
@@ -940,19 +1666,19 @@ func (c *IntentClassifier) Classify(question string) (Intent, float64) {
 		}
 	}
 
-	// Check USP patterns next (before spec to catch "special", "unique", etc.)
-	// Use word boundaries to avoid false matches (e.g., "suspension" matching "usp")
-	for _, pattern := range c.uspPatterns {
-		// Match whole words only to avoid substring matches
-		patternLower := strings.ToLower(pattern)
-		// Check if pattern appears as a whole word (with word boundaries)
-		wordBoundaryPattern := "\\b" + regexp.QuoteMeta(patternLower) + "\\b"
-		matched, _ := regexp.MatchString(wordBoundaryPattern, q)
-		if matched {
-			return IntentUSPLookup, 0.85
+	// Check the dedicated-strategy intents next
+	for _, m := range c.wordMatchers {
+		if m.re.MatchString(q) {
+			return m.intent, 0.85
 		}
 	}
 
+	// Check USP patterns next (before spec to catch "special", "unique", etc.)
+	// Whole words only, so "suspension" does not match "usp"
+	if c.uspRe.MatchString(q) {
+		return IntentUSPLookup, 0.85
+	}
+
 	// Check FAQ patterns
 	for _, pattern := range c.faqPatterns {
 		if strings.Contains(q, pattern) {
@@ -1005,8 +1731,15 @@ func (c *IntentClassifier) Classify(question string) (Intent, float64) {
 
 // calculateKeywordConfidence computes confidence for keyword search results.
 func (r *Router) calculateKeywordConfidence(results []SpecFact, query string) float64 {
+	confidence, _ := r.keywordConfidenceFactors(results, query)
+	return confidence
+}
+
+// keywordConfidenceFactors computes keyword search confidence along with the
+// match counts and bonuses it was built from.
+func (r *Router) keywordConfidenceFactors(results []SpecFact, query string) (float64, map[string]float64) {
 	if len(results) == 0 {
-		return 0.0
+		return 0.0, nil
 	}
 
 	queryLower := strings.ToLower(query)
@@ -1061,13 +1794,20 @@ func (r *Router) calculateKeywordConfidence(results []SpecFact, query string) fl
 	if len(results) > 10 && exactMatches == 0 && partialMatches < 0.8 {
 		confidence *= 0.8 // Penalty for too many low-relevance results
 	}
-	return math.Min(1.0, math.Max(0.0, confidence))
+	factors := map[string]float64{
+		"exact_matches":    exactMatches,
+		"partial_matches":  partialMatches,
+		"complexity_bonus": complexityBonus,
+		"count_bonus":      countBonus,
+	}
+	return math.Min(1.0, math.Max(0.0, confidence)), factors
 }
 
 // rankFactsByRelevance ranks facts by how relevant they are to the query keywords.
-func (r *Router) rankFactsByRelevance(facts []SpecFact, keywords []string, query string) []SpecFact {
+// It also returns the relevance score of each ranked fact.
+func (r *Router) rankFactsByRelevance(facts []SpecFact, keywords []string, query string) ([]SpecFact, []float64) {
 	if len(keywords) == 0 {
-		return facts
+		return facts, nil
 	}
 
 	// Create a map to store relevance scores
@@ -1270,11 +2010,13 @@ func (r *Router) rankFactsByRelevance(facts []SpecFact, keywords []string, query
 
 	// Extract sorted facts
 	result := make([]SpecFact, len(facts))
+	scores := make([]float64, len(facts))
 	for i, s := range scored {
 		result[i] = s.fact
+		scores[i] = s.score
 	}
 
-	return result
+	return result, scores
 }
 
 // filterLowRelevanceFacts filters out facts with low relevance scores.
@@ -1,9 +1,15 @@
 package retrieval
 
 import (
+	"context"
 	"testing"
+	"time"
 
+	"github.com/google/uuid"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/observability"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/storage"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestIntentClassifier_Classify(t *testing.T) {
@@ -60,3 +66,59 @@ func TestVectorFilters_Matching(t *testing.T) {
 	})
 }
 
+
+// stubLineage serves lineage events by resource ID, newest first.
+type stubLineage map[uuid.UUID][]*storage.LineageEvent
+
+func (s stubLineage) GetByResource(ctx context.Context, tenantID uuid.UUID, resourceType string, resourceID uuid.UUID) ([]*storage.LineageEvent, error) {
+	return s[resourceID], nil
+}
+
+func TestRouter_QueryLineage(t *testing.T) {
+	logger := observability.NewLogger(observability.LogConfig{Level: "error"})
+	router := NewRouter(logger, nil, nil, nil, nil, RouterConfig{})
+
+	tenantID, campaignID, docID := uuid.New(), uuid.New(), uuid.New()
+	valueID, chunkID, unknownID := uuid.New(), uuid.New(), uuid.New()
+	created := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	updated := created.Add(48 * time.Hour)
+	resp := &RetrievalResponse{
+		StructuredFacts: []SpecFact{
+			{SpecValueID: valueID, CampaignVariantID: campaignID, Origin: storage.ValueOriginInherited},
+			{SpecValueID: unknownID, CampaignVariantID: campaignID},
+		},
+		SemanticChunks: []SemanticChunk{{ChunkID: chunkID}},
+	}
+	req := RetrievalRequest{TenantID: tenantID, IncludeLineage: true}
+
+	lineage, err := router.queryLineage(context.Background(), req, resp)
+	require.NoError(t, err)
+	require.Len(t, lineage, 3)
+	assert.Empty(t, lineage[0].Action, "nothing is recorded without a lineage source")
+
+	router.SetLineageSource(stubLineage{
+		valueID: {
+			{Action: storage.LineageActionUpdated, OccurredAt: updated},
+			{Action: storage.LineageActionCreated, OccurredAt: created},
+		},
+		chunkID: {{Action: storage.LineageActionCreated, OccurredAt: created, DocumentSourceID: &docID}},
+	})
+	lineage, err = router.queryLineage(context.Background(), req, resp)
+	require.NoError(t, err)
+	require.Len(t, lineage, 3)
+
+	assert.Equal(t, "spec_value", lineage[0].ResourceType)
+	assert.Equal(t, valueID, lineage[0].ResourceID)
+	assert.Equal(t, storage.LineageActionUpdated, lineage[0].Action)
+	assert.Equal(t, updated, lineage[0].OccurredAt)
+	assert.Equal(t, storage.ValueOriginInherited, lineage[0].Origin)
+	assert.Equal(t, &campaignID, lineage[0].CampaignVariantID)
+
+	assert.Empty(t, lineage[1].Action)
+	assert.True(t, lineage[1].OccurredAt.IsZero())
+
+	assert.Equal(t, "knowledge_chunk", lineage[2].ResourceType)
+	assert.Equal(t, storage.LineageActionCreated, lineage[2].Action)
+	assert.Equal(t, created, lineage[2].OccurredAt)
+	assert.Equal(t, &docID, lineage[2].DocumentSourceID)
+}
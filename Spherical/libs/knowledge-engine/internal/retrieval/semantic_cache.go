@@ -0,0 +1,356 @@
+// Package retrieval provides a semantic response cache keyed on question embeddings.
+package retrieval
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/embedding"
+)
+
+// CacheSemanticHit records a trace whose response was reused from a similar question.
+const CacheSemanticHit = "semantic_hit"
+
+// SemanticCacheConfig configures the semantic response cache.
+type SemanticCacheConfig struct {
+	Enabled bool
+	// SimilarityThreshold is the minimum cosine similarity between question
+	// embeddings for a cached response to be reused (default 0.95).
+	SimilarityThreshold float64
+	// TTL bounds how long a response is reused (default 10m).
+	TTL time.Duration
+	// MaxEntries bounds the responses kept per scope; the oldest are evicted first (default 256).
+	MaxEntries int
+	// MaxScopes bounds the scopes kept across tenants; expired scopes, then the
+	// least recently stored, are evicted first (default 4096).
+	MaxScopes int
+}
+
+func (c SemanticCacheConfig) withDefaults() SemanticCacheConfig {
+	if c.SimilarityThreshold <= 0 {
+		c.SimilarityThreshold = 0.95
+	}
+	if c.TTL <= 0 {
+		c.TTL = 10 * time.Minute
+	}
+	if c.MaxEntries <= 0 {
+		c.MaxEntries = 256
+	}
+	if c.MaxScopes <= 0 {
+		c.MaxScopes = 4096
+	}
+	return c
+}
+
+// SemanticCache reuses responses to earlier questions whose embeddings are
+// close to a new question's. Entries are partitioned by everything else that
+// shapes a response (tenant, products, campaign, intent, filters), so only the
+// wording of the question is matched approximately.
+//
+// Each tenant has a version that InvalidateCampaign advances; entries stored
+// under an older version are never returned, so a publish or rollback takes
+// effect on the next query.
+type SemanticCache struct {
+	config SemanticCacheConfig
+
+	mu       sync.Mutex
+	scopes   map[string]*semanticScope
+	versions map[uuid.UUID]int64
+	entries  int
+	hits     int64
+	misses   int64
+}
+
+type semanticScope struct {
+	tenantID uuid.UUID
+	entries  []*semanticEntry
+	storedAt time.Time
+	// benchmarks marks responses that include other tenants' benchmark products.
+	benchmarks bool
+}
+
+type semanticEntry struct {
+	vector    []float32
+	response  *RetrievalResponse
+	version   int64
+	expiresAt time.Time
+}
+
+// NewSemanticCache creates a new semantic response cache.
+func NewSemanticCache(cfg SemanticCacheConfig) *SemanticCache {
+	return &SemanticCache{
+		config:   cfg.withDefaults(),
+		scopes:   make(map[string]*semanticScope),
+		versions: make(map[uuid.UUID]int64),
+	}
+}
+
+// Lookup returns the cached response whose question embedding is most similar
+// to vector, if it meets the similarity threshold, along with the similarity.
+func (c *SemanticCache) Lookup(req RetrievalRequest, intent Intent, vector []float32) (*RetrievalResponse, float64, bool) {
+	query := normalizeVector(vector)
+	now := time.Now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var best *semanticEntry
+	bestSimilarity := c.config.SimilarityThreshold
+	key := semanticScopeKey(req, intent)
+	if scope, ok := c.scopes[key]; ok {
+		c.expireLocked(key, scope, now)
+		for _, entry := range scope.entries {
+			if similarity := 1 - float64(cosineDistance(query, entry.vector)); similarity >= bestSimilarity {
+				best, bestSimilarity = entry, similarity
+			}
+		}
+	}
+
+	if best == nil {
+		c.misses++
+		return nil, 0, false
+	}
+	c.hits++
+	hit := *best.response
+	return &hit, bestSimilarity, true
+}
+
+// Store caches a response under the question embedding it answered.
+func (c *SemanticCache) Store(req RetrievalRequest, intent Intent, vector []float32, resp *RetrievalResponse) {
+	cached := *resp
+	cached.Trace = nil
+	now := time.Now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := semanticScopeKey(req, intent)
+	scope, ok := c.scopes[key]
+	if !ok {
+		if len(c.scopes) >= c.config.MaxScopes {
+			c.evictScopeLocked(now)
+		}
+		scope = &semanticScope{tenantID: req.TenantID, benchmarks: req.IncludeBenchmarks}
+		c.scopes[key] = scope
+	}
+	scope.storedAt = now
+	if len(scope.entries) >= c.config.MaxEntries {
+		evicted := len(scope.entries) - c.config.MaxEntries + 1
+		scope.entries = append(scope.entries[:0], scope.entries[evicted:]...)
+		c.entries -= evicted
+	}
+	scope.entries = append(scope.entries, &semanticEntry{
+		vector:    normalizeVector(vector),
+		response:  &cached,
+		version:   c.versions[req.TenantID],
+		expiresAt: now.Add(c.config.TTL),
+	})
+	c.entries++
+}
+
+// expireLocked drops a scope's expired entries and those stored before its
+// tenant was invalidated, and the scope itself once it is empty.
+func (c *SemanticCache) expireLocked(key string, scope *semanticScope, now time.Time) {
+	version := c.versions[scope.tenantID]
+	live := scope.entries[:0]
+	for _, entry := range scope.entries {
+		if entry.version != version || now.After(entry.expiresAt) {
+			c.entries--
+			continue
+		}
+		live = append(live, entry)
+	}
+	scope.entries = live
+	if len(live) == 0 {
+		delete(c.scopes, key)
+	}
+}
+
+// evictScopeLocked makes room for a new scope, expiring every scope and, if
+// none was emptied, evicting the least recently stored one.
+func (c *SemanticCache) evictScopeLocked(now time.Time) {
+	for key, scope := range c.scopes {
+		c.expireLocked(key, scope, now)
+	}
+	if len(c.scopes) < c.config.MaxScopes {
+		return
+	}
+	oldest := ""
+	for key, scope := range c.scopes {
+		if oldest == "" || scope.storedAt.Before(c.scopes[oldest].storedAt) {
+			oldest = key
+		}
+	}
+	c.entries -= len(c.scopes[oldest].entries)
+	delete(c.scopes, oldest)
+}
+
+// InvalidateCampaign advances the tenant's version after a campaign is
+// published or rolled back. Responses without a campaign resolve to default
+// campaigns, so all of the tenant's entries are dropped, not only the campaign's.
+func (c *SemanticCache) InvalidateCampaign(ctx context.Context, tenantID, campaignID uuid.UUID) error {
+	c.InvalidateTenant(tenantID)
+	return nil
+}
+
+// InvalidateTenant advances the tenant's version and drops its entries.
+func (c *SemanticCache) InvalidateTenant(tenantID uuid.UUID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.versions[tenantID]++
+	for key, scope := range c.scopes {
+		if scope.tenantID == tenantID {
+			c.entries -= len(scope.entries)
+			delete(c.scopes, key)
+		}
+	}
+}
+
+// InvalidateBenchmarks drops every tenant's entries that include benchmark
+// products, after a benchmark tenant's catalogue changes.
+func (c *SemanticCache) InvalidateBenchmarks() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, scope := range c.scopes {
+		if scope.benchmarks {
+			c.entries -= len(scope.entries)
+			delete(c.scopes, key)
+		}
+	}
+}
+
+// Stats returns hit and miss counts and the number of cached responses.
+func (c *SemanticCache) Stats() *CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := &CacheStats{
+		Enabled:  true,
+		Hits:     c.hits,
+		Misses:   c.misses,
+		KeyCount: int64(c.entries),
+	}
+	if total := c.hits + c.misses; total > 0 {
+		stats.HitRate = float64(c.hits) / float64(total)
+	}
+	return stats
+}
+
+// questionEmbedding carries the embedding of a question between query stages.
+type questionEmbedding struct {
+	question string
+	vector   []float32
+}
+
+// CacheStats returns the semantic cache's statistics, or disabled stats when
+// the router has no semantic cache.
+func (r *Router) CacheStats() *CacheStats {
+	if r.semanticCache == nil {
+		return &CacheStats{}
+	}
+	return r.semanticCache.Stats()
+}
+
+// EmbeddingCacheStats returns the embedding cache's statistics, or nil when the
+// router's embedder does not cache vectors.
+func (r *Router) EmbeddingCacheStats() *embedding.CacheStats {
+	cached, ok := r.embedder.(*embedding.CachedEmbedder)
+	if !ok {
+		return nil
+	}
+	stats := cached.Stats()
+	return &stats
+}
+
+// lookupSemanticCache embeds the question, keeping the embedding on the
+// request for vector search, and returns a cached response to a similar one.
+func (r *Router) lookupSemanticCache(ctx context.Context, req *RetrievalRequest, intent Intent) (*RetrievalResponse, bool) {
+	if r.embedder == nil {
+		return nil, false
+	}
+	vector, err := r.embed(ctx, req.Question)
+	if err != nil {
+		r.logger.Warn().Err(err).Msg("Failed to embed question for semantic cache")
+		return nil, false
+	}
+	req.embedding = &questionEmbedding{question: req.Question, vector: vector}
+
+	cached, similarity, ok := r.semanticCache.Lookup(*req, intent, vector)
+	if ok {
+		r.logger.Debug().Float64("similarity", similarity).Msg("Semantic cache hit")
+	}
+	return cached, ok
+}
+
+// empty reports whether a response carries no results worth caching.
+func (resp *RetrievalResponse) empty() bool {
+	return len(resp.StructuredFacts) == 0 && len(resp.SemanticChunks) == 0 &&
+		len(resp.Comparisons) == 0 && len(resp.Evidence) == 0 && len(resp.Availability) == 0
+}
+
+// semanticScopeKey identifies the request parameters other than the question
+// that a cached response must share. Numeric predicates are part of the key:
+// "more than 6 airbags" and "more than 8 airbags" embed almost identically but
+// select different products.
+func semanticScopeKey(req RetrievalRequest, intent Intent) string {
+	parts := []string{req.TenantID.String(), string(intent), strconv.Itoa(req.MaxChunks)}
+
+	productIDs := make([]string, len(req.ProductIDs))
+	for i, pid := range req.ProductIDs {
+		productIDs[i] = pid.String()
+	}
+	sort.Strings(productIDs)
+	parts = append(parts, strings.Join(productIDs, ","))
+
+	if req.CampaignVariantID != nil {
+		parts = append(parts, "campaign:"+req.CampaignVariantID.String())
+	}
+	if req.IncludeBenchmarks {
+		parts = append(parts, "benchmarks")
+	}
+	if req.IncludeLineage {
+		parts = append(parts, "lineage")
+	}
+
+	categories := append([]string(nil), req.Filters.Categories...)
+	sort.Strings(categories)
+	for _, category := range categories {
+		parts = append(parts, "cat:"+category)
+	}
+	chunkTypes := make([]string, len(req.Filters.ChunkTypes))
+	for i, chunkType := range req.Filters.ChunkTypes {
+		chunkTypes[i] = string(chunkType)
+	}
+	sort.Strings(chunkTypes)
+	for _, chunkType := range chunkTypes {
+		parts = append(parts, "chunk:"+chunkType)
+	}
+	blockTypes := make([]string, len(req.Filters.BlockTypes))
+	for i, blockType := range req.Filters.BlockTypes {
+		blockTypes[i] = string(blockType)
+	}
+	sort.Strings(blockTypes)
+	for _, blockType := range blockTypes {
+		parts = append(parts, "block:"+blockType)
+	}
+
+	predicates := ParsePredicates(req.Question, req.mentions...)
+	keys := make([]string, len(predicates))
+	for i, p := range predicates {
+		keys[i] = strings.Join([]string{
+			"pred", p.Attribute, string(p.Op), string(p.Dimension), p.Unit,
+			strconv.FormatFloat(p.Value, 'g', -1, 64), strconv.FormatFloat(p.Upper, 'g', -1, 64),
+		}, ":")
+	}
+	sort.Strings(keys)
+	parts = append(parts, keys...)
+
+	return strings.Join(parts, "|")
+}
@@ -0,0 +1,184 @@
+package retrieval
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/cache"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/observability"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubEmbedder returns fixed embeddings per question so tests control similarity.
+type stubEmbedder struct {
+	vectors map[string][]float32
+}
+
+func (e *stubEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	embeddings := make([][]float32, len(texts))
+	for i, text := range texts {
+		embeddings[i], _ = e.EmbedSingle(ctx, text)
+	}
+	return embeddings, nil
+}
+
+func (e *stubEmbedder) EmbedSingle(ctx context.Context, text string) ([]float32, error) {
+	if vector, ok := e.vectors[text]; ok {
+		return vector, nil
+	}
+	return []float32{0, 0, 1}, nil
+}
+
+func (e *stubEmbedder) Model() string  { return "stub" }
+func (e *stubEmbedder) Dimension() int { return 3 }
+
+func TestRouter_SemanticCache(t *testing.T) {
+	logger := observability.NewLogger(observability.LogConfig{Level: "error"})
+	tenant, product, campaign := uuid.New(), uuid.New(), uuid.New()
+	embedder := &stubEmbedder{vectors: map[string][]float32{
+		"What is the power?":          {1, 0, 0},
+		"How powerful is the engine?": {0.98, 0.1, 0},
+		"What is the warranty?":       {0, 1, 0},
+	}}
+	router := NewRouter(logger, nil, nil, embedder, nil, RouterConfig{
+		StructuredFirst: true,
+		SemanticCache:   SemanticCacheConfig{Enabled: true, SimilarityThreshold: 0.95},
+	})
+	indexSpec(router, tenant, product, campaign, "LE", "Engine", "Power", "203 hp")
+	indexSpec(router, tenant, product, campaign, "LE", "Engine", "Torque", "184 lb-ft")
+	router.LexicalIndex().MarkLoaded(tenant)
+	ctx := context.Background()
+	intent := IntentSpecLookup
+
+	query := func(question string, productIDs ...uuid.UUID) *RetrievalResponse {
+		resp, err := router.Query(ctx, RetrievalRequest{
+			TenantID:   tenant,
+			ProductIDs: productIDs,
+			Question:   question,
+			IntentHint: &intent,
+			Explain:    true,
+		})
+		require.NoError(t, err)
+		return resp
+	}
+
+	first := query("What is the power?")
+	assert.Equal(t, CacheMiss, first.Trace.Cache)
+	require.NotEmpty(t, first.StructuredFacts)
+
+	t.Run("similar question reuses the response", func(t *testing.T) {
+		resp := query("How powerful is the engine?")
+		assert.Equal(t, CacheSemanticHit, resp.Trace.Cache)
+		assert.Equal(t, PathCache, resp.Trace.Path)
+		assert.Equal(t, first.StructuredFacts, resp.StructuredFacts)
+	})
+
+	t.Run("dissimilar question misses", func(t *testing.T) {
+		resp := query("What is the warranty?")
+		assert.Equal(t, CacheMiss, resp.Trace.Cache)
+	})
+
+	t.Run("scope must match", func(t *testing.T) {
+		resp := query("How powerful is the engine?", product)
+		assert.Equal(t, CacheMiss, resp.Trace.Cache)
+	})
+
+	t.Run("numeric predicates must match", func(t *testing.T) {
+		key := func(question string) string {
+			return semanticScopeKey(RetrievalRequest{TenantID: tenant, Question: question}, intent)
+		}
+		assert.NotEqual(t, key("Which trims have more than 6 airbags?"), key("Which trims have more than 8 airbags?"))
+		assert.Equal(t, key("What is the power?"), key("How powerful is the engine?"))
+
+		cache := NewSemanticCache(SemanticCacheConfig{Enabled: true, SimilarityThreshold: 0.95})
+		req := RetrievalRequest{TenantID: tenant, Question: "Which trims have more than 6 airbags?"}
+		cache.Store(req, intent, []float32{0.6, 0.8, 0}, first)
+		_, _, ok := cache.Lookup(req, intent, []float32{0.6, 0.8, 0})
+		assert.True(t, ok)
+		req.Question = "Which trims have more than 8 airbags?"
+		_, _, ok = cache.Lookup(req, intent, []float32{0.6, 0.8, 0})
+		assert.False(t, ok, "same embedding, different threshold")
+	})
+
+	t.Run("publishing invalidates the tenant", func(t *testing.T) {
+		require.NoError(t, router.ApplyInvalidation(ctx, cache.InvalidationEvent{
+			Kind:       cache.InvalidationCampaignPublished,
+			TenantID:   tenant,
+			CampaignID: &campaign,
+		}))
+		resp := query("What is the power?")
+		assert.Equal(t, CacheMiss, resp.Trace.Cache)
+
+		resp = query("How powerful is the engine?")
+		assert.Equal(t, CacheSemanticHit, resp.Trace.Cache, "entries stored after the publish are reused")
+	})
+
+	t.Run("stats report the hit rate", func(t *testing.T) {
+		stats := router.CacheStats()
+		assert.True(t, stats.Enabled)
+		assert.Equal(t, int64(2), stats.Hits)
+		assert.Equal(t, int64(4), stats.Misses)
+		assert.InDelta(t, 2.0/6.0, stats.HitRate, 1e-9)
+		assert.Equal(t, int64(1), stats.KeyCount)
+	})
+}
+
+func TestSemanticCache_EvictsOldest(t *testing.T) {
+	semantic := NewSemanticCache(SemanticCacheConfig{MaxEntries: 2})
+	req := RetrievalRequest{TenantID: uuid.New()}
+	for i := 0; i < 3; i++ {
+		vector := []float32{0, 0, 0}
+		vector[i] = 1
+		semantic.Store(req, IntentSpecLookup, vector, &RetrievalResponse{Intent: IntentSpecLookup, LatencyMs: int64(i)})
+	}
+
+	_, _, ok := semantic.Lookup(req, IntentSpecLookup, []float32{1, 0, 0})
+	assert.False(t, ok, "the oldest entry was evicted")
+	hit, similarity, ok := semantic.Lookup(req, IntentSpecLookup, []float32{0, 0, 1})
+	require.True(t, ok)
+	assert.Equal(t, int64(2), hit.LatencyMs)
+	assert.InDelta(t, 1.0, similarity, 1e-6)
+	assert.Equal(t, int64(2), semantic.Stats().KeyCount)
+}
+
+func TestSemanticCache_PrunesScopes(t *testing.T) {
+	vector := []float32{1, 0, 0}
+	resp := &RetrievalResponse{Intent: IntentSpecLookup}
+
+	t.Run("expired scopes are dropped", func(t *testing.T) {
+		semantic := NewSemanticCache(SemanticCacheConfig{TTL: time.Millisecond})
+		req := RetrievalRequest{TenantID: uuid.New()}
+		semantic.Store(req, IntentSpecLookup, vector, resp)
+		time.Sleep(5 * time.Millisecond)
+
+		_, _, ok := semantic.Lookup(req, IntentSpecLookup, vector)
+		assert.False(t, ok)
+		assert.Empty(t, semantic.scopes)
+		assert.Equal(t, int64(0), semantic.Stats().KeyCount)
+	})
+
+	t.Run("the least recently stored scope is evicted", func(t *testing.T) {
+		semantic := NewSemanticCache(SemanticCacheConfig{MaxScopes: 2})
+		tenants := []uuid.UUID{uuid.New(), uuid.New(), uuid.New()}
+		for _, tenant := range tenants {
+			semantic.Store(RetrievalRequest{TenantID: tenant}, IntentSpecLookup, vector, resp)
+		}
+
+		assert.Len(t, semantic.scopes, 2)
+		assert.Equal(t, int64(2), semantic.Stats().KeyCount)
+		_, _, ok := semantic.Lookup(RetrievalRequest{TenantID: tenants[0]}, IntentSpecLookup, vector)
+		assert.False(t, ok)
+		_, _, ok = semantic.Lookup(RetrievalRequest{TenantID: tenants[2]}, IntentSpecLookup, vector)
+		assert.True(t, ok)
+	})
+}
+
+func TestRouter_SemanticCacheNeedsEmbedder(t *testing.T) {
+	logger := observability.NewLogger(observability.LogConfig{Level: "error"})
+	router := NewRouter(logger, nil, nil, nil, nil, RouterConfig{SemanticCache: SemanticCacheConfig{Enabled: true}})
+	assert.Nil(t, router.semanticCache)
+	assert.False(t, router.CacheStats().Enabled)
+}
@@ -0,0 +1,125 @@
+// Package retrieval provides point-in-time retrieval from campaign version history.
+package retrieval
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/lexical"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/storage"
+)
+
+// ErrSnapshotUnavailable is returned for point-in-time requests when the router
+// has no snapshot source.
+var ErrSnapshotUnavailable = errors.New("point-in-time retrieval is not configured")
+
+// SnapshotSource resolves the campaign versions live at a point in time and the
+// spec values they exposed. storage.SnapshotRepository reads them from version history.
+type SnapshotSource interface {
+	Snapshot(ctx context.Context, q storage.SnapshotQuery) (*storage.Snapshot, error)
+}
+
+// Snapshot records the campaign versions a point-in-time response was answered from.
+type Snapshot struct {
+	// AsOf is the requested moment, when the request gave one.
+	AsOf *time.Time
+	// Versions are the campaign versions live at AsOf, or numbered as requested.
+	Versions []CampaignVersion
+}
+
+// CampaignVersion identifies one version of a campaign variant.
+type CampaignVersion struct {
+	CampaignVariantID uuid.UUID
+	ProductID         uuid.UUID
+	Version           int
+	Status            storage.CampaignStatus
+	EffectiveFrom     *time.Time
+	EffectiveThrough  *time.Time
+}
+
+// snapshot is a resolved point in time: the versions used and an index over
+// their spec values, searched instead of the router's index of published specs.
+type snapshot struct {
+	info        *Snapshot
+	campaignIDs []uuid.UUID
+	specs       []storage.SpecViewLatest
+	index       *lexical.Index
+}
+
+// SetSnapshotSource enables requests with AsOf or CampaignVersion. A nil source
+// rejects them with ErrSnapshotUnavailable.
+func (r *Router) SetSnapshotSource(source SnapshotSource) {
+	r.snapshots = source
+}
+
+// pointInTime reports whether the request asks for a past campaign version.
+func (req RetrievalRequest) pointInTime() bool {
+	return req.AsOf != nil || req.CampaignVersion != nil
+}
+
+// resolveSnapshot selects the campaign versions a point-in-time request is
+// answered from and indexes their spec values. A requested campaign variant is
+// replaced by its version live at the time, so scoping and vector search target it.
+func (r *Router) resolveSnapshot(ctx context.Context, req *RetrievalRequest) error {
+	if r.snapshots == nil {
+		return ErrSnapshotUnavailable
+	}
+
+	resolved, err := r.snapshots.Snapshot(ctx, storage.SnapshotQuery{
+		TenantID:          req.TenantID,
+		ProductIDs:        req.ProductIDs,
+		CampaignVariantID: req.CampaignVariantID,
+		AsOf:              req.AsOf,
+		Version:           req.CampaignVersion,
+	})
+	if err != nil {
+		return fmt.Errorf("resolve campaign snapshot: %w", err)
+	}
+
+	snap := &snapshot{
+		info:        &Snapshot{AsOf: req.AsOf},
+		campaignIDs: resolved.CampaignIDs,
+		specs:       resolved.Specs,
+		index:       lexical.NewIndex(lexical.DefaultConfig()),
+	}
+	for _, c := range resolved.Campaigns {
+		snap.info.Versions = append(snap.info.Versions, CampaignVersion{
+			CampaignVariantID: c.ID,
+			ProductID:         c.ProductID,
+			Version:           c.Version,
+			Status:            c.Status,
+			EffectiveFrom:     c.EffectiveFrom,
+			EffectiveThrough:  c.EffectiveThrough,
+		})
+	}
+	for _, sv := range resolved.Specs {
+		snap.index.Upsert(lexical.SpecDocument(sv))
+	}
+	snap.index.MarkLoaded(req.TenantID)
+
+	if req.CampaignVariantID != nil && len(resolved.Campaigns) > 0 {
+		id := resolved.Campaigns[0].ID
+		req.CampaignVariantID = &id
+	}
+	req.snapshot = snap
+
+	r.logger.Debug().
+		Str("tenant_id", req.TenantID.String()).
+		Int("versions", len(snap.info.Versions)).
+		Int("specs", len(snap.specs)).
+		Msg("Resolved campaign snapshot")
+	return nil
+}
+
+// searchIndex returns the lexical index serving a request: the snapshot's index
+// for point-in-time requests, otherwise the router's index of published specs.
+// The second return value is false when no index can serve the request.
+func (r *Router) searchIndex(ctx context.Context, req RetrievalRequest) (*lexical.Index, bool) {
+	if req.snapshot != nil {
+		return req.snapshot.index, true
+	}
+	return r.lexicalIndex, r.ensureLexicalIndex(ctx, req.TenantID)
+}
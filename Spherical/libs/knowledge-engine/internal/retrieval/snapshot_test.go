@@ -0,0 +1,115 @@
+package retrieval
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/observability"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubSnapshots returns a fixed snapshot and records the query it was given.
+type stubSnapshots struct {
+	snapshot *storage.Snapshot
+	query    storage.SnapshotQuery
+}
+
+func (s *stubSnapshots) Snapshot(ctx context.Context, q storage.SnapshotQuery) (*storage.Snapshot, error) {
+	s.query = q
+	if s.snapshot == nil {
+		return nil, storage.ErrNoCampaignVersion
+	}
+	return s.snapshot, nil
+}
+
+func TestRouter_PointInTime(t *testing.T) {
+	logger := observability.NewLogger(observability.LogConfig{Level: "error"})
+	tenant, product := uuid.New(), uuid.New()
+	current, archived := uuid.New(), uuid.New()
+	jan := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	apr := time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)
+	feb := jan.AddDate(0, 1, 0)
+
+	router := NewRouter(logger, nil, nil, nil, nil, RouterConfig{StructuredFirst: true, CacheResults: true})
+	indexSpec(router, tenant, product, current, "LE", "Engine", "Power", "203 hp")
+	router.LexicalIndex().MarkLoaded(tenant)
+
+	trim := "LE"
+	source := &stubSnapshots{snapshot: &storage.Snapshot{
+		Campaigns: []*storage.CampaignVariant{
+			{ID: archived, ProductID: product, Version: 1, Status: storage.CampaignStatusArchived, EffectiveFrom: &jan, EffectiveThrough: &apr},
+		},
+		CampaignIDs: []uuid.UUID{archived},
+		Specs: []storage.SpecViewLatest{{
+			ID:                uuid.New(),
+			SpecItemID:        uuid.New(),
+			TenantID:          tenant,
+			ProductID:         product,
+			CampaignVariantID: archived,
+			CategoryName:      "Engine",
+			SpecName:          "Power",
+			Value:             "178 hp",
+			Confidence:        0.9,
+			Trim:              &trim,
+			ProductName:       "Camry",
+		}},
+	}}
+	ctx := context.Background()
+
+	t.Run("unavailable without a source", func(t *testing.T) {
+		_, err := router.Query(ctx, RetrievalRequest{TenantID: tenant, Question: "What is the power?", AsOf: &feb})
+		assert.ErrorIs(t, err, ErrSnapshotUnavailable)
+	})
+
+	router.SetSnapshotSource(source)
+
+	t.Run("answers from the archived version", func(t *testing.T) {
+		resp, err := router.Query(ctx, RetrievalRequest{TenantID: tenant, CampaignVariantID: &current, Question: "What is the power?", AsOf: &feb})
+		require.NoError(t, err)
+		require.NotEmpty(t, resp.StructuredFacts)
+		for _, fact := range resp.StructuredFacts {
+			assert.Equal(t, "178 hp", fact.Value)
+			assert.Equal(t, archived, fact.CampaignVariantID)
+		}
+
+		require.NotNil(t, resp.Snapshot)
+		assert.Equal(t, &feb, resp.Snapshot.AsOf)
+		require.Len(t, resp.Snapshot.Versions, 1)
+		assert.Equal(t, archived, resp.Snapshot.Versions[0].CampaignVariantID)
+		assert.Equal(t, 1, resp.Snapshot.Versions[0].Version)
+		assert.Equal(t, current, *source.query.CampaignVariantID)
+	})
+
+	t.Run("current answers are unaffected", func(t *testing.T) {
+		resp, err := router.Query(ctx, RetrievalRequest{TenantID: tenant, Question: "What is the power?"})
+		require.NoError(t, err)
+		require.NotEmpty(t, resp.StructuredFacts)
+		assert.Equal(t, "203 hp", resp.StructuredFacts[0].Value)
+		assert.Nil(t, resp.Snapshot)
+	})
+
+	t.Run("missing versions are reported", func(t *testing.T) {
+		version := 7
+		router.SetSnapshotSource(&stubSnapshots{})
+		_, err := router.Query(ctx, RetrievalRequest{TenantID: tenant, Question: "What is the power?", CampaignVersion: &version})
+		assert.ErrorIs(t, err, storage.ErrNoCampaignVersion)
+	})
+}
+
+func TestRequestKey_PointInTime(t *testing.T) {
+	tenant := uuid.New()
+	feb := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+	version := 2
+
+	current := requestKey(RetrievalRequest{TenantID: tenant, Question: "power"})
+	past := requestKey(RetrievalRequest{TenantID: tenant, Question: "power", AsOf: &feb})
+	numbered := requestKey(RetrievalRequest{TenantID: tenant, Question: "power", CampaignVersion: &version})
+
+	assert.NotEqual(t, current, past)
+	assert.NotEqual(t, current, numbered)
+	assert.NotEqual(t, past, numbered)
+}
@@ -2,6 +2,7 @@
 package retrieval
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -10,6 +11,10 @@ import (
 	"sync"
 
 	"github.com/google/uuid"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/observability"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/storage"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // VectorAdapter defines the interface for vector similarity search.
@@ -23,21 +28,51 @@ type VectorAdapter interface {
 	// Delete removes vectors from the index.
 	Delete(ctx context.Context, ids []uuid.UUID) error
 	
-	// Count returns the number of vectors in the index.
-	Count(ctx context.Context) (int64, error)
+	// Count returns the number of vectors in the index matching filters;
+	// empty filters count every vector.
+	Count(ctx context.Context, filters VectorFilters) (int64, error)
 	
 	// Close releases resources.
 	Close() error
 }
 
+// startSearchSpan starts the span around a VectorAdapter.Search call.
+func startSearchSpan(ctx context.Context, adapter string, k int) (context.Context, trace.Span) {
+	return observability.StartSpan(ctx, "vector.Search",
+		attribute.String("adapter", adapter),
+		attribute.Int("k", k),
+	)
+}
+
+// endSearchSpan records the number of results and any error, and ends span.
+func endSearchSpan(span trace.Span, results []VectorResult, err error) {
+	span.SetAttributes(attribute.Int("results", len(results)))
+	observability.EndSpan(span, err)
+}
+
 // VectorFilters defines filtering options for vector search.
 type VectorFilters struct {
 	TenantID          *uuid.UUID
 	ProductIDs        []uuid.UUID
 	CampaignVariantID *uuid.UUID
-	ChunkTypes        []string
-	Visibility        []string
-	EmbeddingVersion  *string
+	// CampaignVariantIDs matches any of the listed variants (e.g. an inheritance chain).
+	CampaignVariantIDs []uuid.UUID
+	// ExcludeOverridden treats CampaignVariantIDs as an inheritance chain, nearest
+	// first, and drops chunks of feature blocks overridden by a nearer variant.
+	ExcludeOverridden bool
+	ChunkTypes         []string
+	// BlockTypes matches the "block_type" metadata of chunks built from feature blocks.
+	BlockTypes         []string
+	Visibility         []string
+	EmbeddingVersion   *string
+}
+
+// isEmpty reports whether the filters match every vector.
+func (f VectorFilters) isEmpty() bool {
+	return f.TenantID == nil && len(f.ProductIDs) == 0 &&
+		f.CampaignVariantID == nil && len(f.CampaignVariantIDs) == 0 &&
+		len(f.ChunkTypes) == 0 && len(f.BlockTypes) == 0 &&
+		len(f.Visibility) == 0 && f.EmbeddingVersion == nil
 }
 
 // VectorEntry represents a vector to be indexed.
@@ -67,22 +102,47 @@ var ErrVectorDimensionMismatch = errors.New("vector dimension mismatch")
 // FAISSAdapter implements VectorAdapter using an in-memory FAISS-like index.
 // For production, this would use actual FAISS C bindings.
 // This is a simplified pure-Go implementation for development.
+//
+// Vectors are partitioned by tenant, with secondary indexes on campaign
+// variant, chunk type and visibility, so a filtered search only visits the
+// vectors it can return.
+//
+// Vectors can be stored quantized to int8 or product quantization codes; the
+// top candidates are then re-ranked at full precision when a RerankSource is set.
 type FAISSAdapter struct {
-	mu        sync.RWMutex
-	dimension int
-	vectors   map[uuid.UUID]indexedVector
+	mu           sync.RWMutex
+	dimension    int
+	vectors      map[uuid.UUID]indexedVector
+	partitions   map[uuid.UUID]*vectorPartition
+	quantization QuantizationConfig
+	pq           *productQuantizer
+	rerank       RerankSource
+	// training is set while PQ codebooks are trained outside mu; trainedOn is
+	// the number of vectors the current codebooks were trained on.
+	training  bool
+	trainedOn int
+	// seq numbers stored vectors so a training snapshot can tell which
+	// vectors were replaced while it ran.
+	seq uint64
 }
 
+// indexedVector is a stored vector: normalized at full precision, or as int8
+// or PQ codes. The entry's own Vector is dropped to avoid keeping two copies.
 type indexedVector struct {
 	entry  VectorEntry
+	dim    int
 	vector []float32
+	codes  []byte
+	scale  float32
+	seq    uint64
 }
 
 // FAISSConfig holds FAISS adapter configuration.
 type FAISSConfig struct {
-	Dimension int
-	IndexPath string
-	NList     int
+	Dimension    int
+	IndexPath    string
+	NList        int
+	Quantization QuantizationConfig
 }
 
 // NewFAISSAdapter creates a new FAISS adapter.
@@ -90,15 +150,37 @@ func NewFAISSAdapter(cfg FAISSConfig) (*FAISSAdapter, error) {
 	if cfg.Dimension <= 0 {
 		cfg.Dimension = 768
 	}
+	switch cfg.Quantization.Mode {
+	case QuantizationNone, QuantizationInt8, QuantizationPQ:
+	default:
+		return nil, fmt.Errorf("unknown vector quantization: %s", cfg.Quantization.Mode)
+	}
 	
 	return &FAISSAdapter{
-		dimension: cfg.Dimension,
-		vectors:   make(map[uuid.UUID]indexedVector),
+		dimension:    cfg.Dimension,
+		vectors:      make(map[uuid.UUID]indexedVector),
+		partitions:   make(map[uuid.UUID]*vectorPartition),
+		quantization: cfg.Quantization.withDefaults(),
 	}, nil
 }
 
+// SetRerankSource sets where the full-precision vectors of a quantized index
+// are read from to re-rank its top candidates.
+func (a *FAISSAdapter) SetRerankSource(source RerankSource) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.rerank = source
+}
+
 // Search finds the k nearest neighbors using cosine similarity.
 func (a *FAISSAdapter) Search(ctx context.Context, query []float32, k int, filters VectorFilters) ([]VectorResult, error) {
+	ctx, span := startSearchSpan(ctx, "faiss", k)
+	results, err := a.search(ctx, query, k, filters)
+	endSearchSpan(span, results, err)
+	return results, err
+}
+
+func (a *FAISSAdapter) search(ctx context.Context, query []float32, k int, filters VectorFilters) ([]VectorResult, error) {
 	a.mu.RLock()
 	hasVectors := len(a.vectors) > 0
 	a.mu.RUnlock()
@@ -110,12 +192,13 @@ func (a *FAISSAdapter) Search(ctx context.Context, query []float32, k int, filte
 			a.mu.RLock()
 			// Get dimension from first stored vector that matches filters
 			var storedDimension int
-			for _, iv := range a.vectors {
-				if len(iv.vector) > 0 && matchesFilters(iv.entry, filters) {
-					storedDimension = len(iv.vector)
-					break
+			a.scan(filters, func(iv indexedVector) bool {
+				if iv.dim > 0 {
+					storedDimension = iv.dim
+					return false
 				}
-			}
+				return true
+			})
 			a.mu.RUnlock()
 			
 			if storedDimension > 0 {
@@ -144,56 +227,39 @@ func (a *FAISSAdapter) Search(ctx context.Context, query []float32, k int, filte
 	}
 	
 	a.mu.RLock()
-	defer a.mu.RUnlock()
-	
-	// Collect all vectors that match filters
-	var candidates []struct {
-		id       uuid.UUID
-		vector   []float32
-		metadata map[string]interface{}
+	// PQ codes are scored against a table of the query's centroid products
+	var table []float32
+	if a.pq != nil && len(query) == a.pq.dim {
+		table = a.pq.table(query)
 	}
 	
-	for id, iv := range a.vectors {
-		if !matchesFilters(iv.entry, filters) {
-			continue
-		}
+	// Score all vectors that match filters
+	var results []scoredVector
+	a.scan(filters, func(iv indexedVector) bool {
 		// Skip vectors with dimension mismatch
-		if len(iv.vector) != len(query) {
-			continue
+		if iv.dim != len(query) {
+			return true
 		}
-		candidates = append(candidates, struct {
-			id       uuid.UUID
-			vector   []float32
-			metadata map[string]interface{}
-		}{
-			id:       id,
-			vector:   iv.vector,
-			metadata: iv.entry.Metadata,
+		results = append(results, scoredVector{
+			id:        iv.entry.ID,
+			distance:  a.distance(query, iv, table),
+			metadata:  iv.entry.Metadata,
+			quantized: iv.vector == nil,
 		})
-	}
+		return true
+	})
+	rerank, factor := a.rerank, a.quantization.RerankFactor
+	a.mu.RUnlock()
 	
-	// Compute distances
-	type scored struct {
-		id       uuid.UUID
-		distance float32
-		metadata map[string]interface{}
-	}
+	// Sort by distance (ascending)
+	sortScored(results)
 	
-	results := make([]scored, len(candidates))
-	for i, c := range candidates {
-		dist := cosineDistance(query, c.vector)
-		results[i] = scored{
-			id:       c.id,
-			distance: dist,
-			metadata: c.metadata,
+	if rerank != nil {
+		if err := rerankScored(ctx, rerank, query, results, k*factor); err != nil {
+			return nil, err
 		}
 	}
 	
-	// Sort by distance (ascending)
-	sort.Slice(results, func(i, j int) bool {
-		return results[i].distance < results[j].distance
-	})
-	
 	// Return top k
 	if k > len(results) {
 		k = len(results)
@@ -212,11 +278,80 @@ func (a *FAISSAdapter) Search(ctx context.Context, query []float32, k int, filte
 	return output, nil
 }
 
-// Insert adds vectors to the index.
+// scoredVector is a search candidate and its cosine distance to the query.
+type scoredVector struct {
+	id        uuid.UUID
+	distance  float32
+	metadata  map[string]interface{}
+	quantized bool
+}
+
+func sortScored(results []scoredVector) {
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].distance < results[j].distance
+	})
+}
+
+// distance returns the cosine distance between the query and a stored vector,
+// approximated from its codes when quantized. The caller must hold a.mu.
+func (a *FAISSAdapter) distance(query []float32, iv indexedVector, table []float32) float32 {
+	switch {
+	case iv.vector != nil:
+		return cosineDistance(query, iv.vector)
+	case a.quantization.Mode == QuantizationInt8:
+		return dotDistance(int8Dot(query, iv.codes, iv.scale))
+	default:
+		return dotDistance(a.pq.score(table, iv.codes))
+	}
+}
+
+// rerankScored recomputes the distances of the top n quantized candidates from
+// their full-precision vectors and re-sorts them.
+func rerankScored(ctx context.Context, source RerankSource, query []float32, results []scoredVector, n int) error {
+	if n > len(results) {
+		n = len(results)
+	}
+	top := results[:n]
+	ids := make([]uuid.UUID, 0, n)
+	for _, r := range top {
+		if r.quantized {
+			ids = append(ids, r.id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	
+	vectors, err := source.Vectors(ctx, ids)
+	if err != nil {
+		return fmt.Errorf("load vectors for re-ranking: %w", err)
+	}
+	for i, r := range top {
+		if v, ok := vectors[r.id]; ok && len(v) == len(query) {
+			top[i].distance = cosineDistance(query, normalizeVector(v))
+		}
+	}
+	sortScored(top)
+	return nil
+}
+
+// Insert adds vectors to the index. Once enough vectors are held, the PQ
+// codebooks are trained, or retrained, on a snapshot without blocking searches
+// and inserts, then swapped in along with the re-encoded vectors.
 func (a *FAISSAdapter) Insert(ctx context.Context, vectors []VectorEntry) error {
 	a.mu.Lock()
-	defer a.mu.Unlock()
+	err := a.insert(vectors)
+	snapshot := a.pqSnapshot(false)
+	a.mu.Unlock()
 	
+	if snapshot != nil {
+		a.trainPQ(ctx, snapshot)
+	}
+	return err
+}
+
+// insert stores vectors. The caller must hold a.mu.
+func (a *FAISSAdapter) insert(vectors []VectorEntry) error {
 	// If no vectors stored yet and we have vectors to insert, detect dimension from first vector
 	if len(a.vectors) == 0 && len(vectors) > 0 {
 		// Find first vector with non-zero length
@@ -243,8 +378,8 @@ func (a *FAISSAdapter) Insert(ctx context.Context, vectors []VectorEntry) error
 				// Check if all existing vectors have the same dimension as this one
 				var existingDim int
 				for _, iv := range a.vectors {
-					if len(iv.vector) > 0 {
-						existingDim = len(iv.vector)
+					if iv.dim > 0 {
+						existingDim = iv.dim
 						break
 					}
 				}
@@ -261,32 +396,281 @@ func (a *FAISSAdapter) Insert(ctx context.Context, vectors []VectorEntry) error
 		// Normalize vector for cosine similarity
 		normalized := normalizeVector(v.Vector)
 		
-		a.vectors[v.ID] = indexedVector{
-			entry:  v,
-			vector: normalized,
+		if existing, ok := a.vectors[v.ID]; ok {
+			a.unindex(existing.entry)
+		}
+		stored := v
+		stored.Vector = nil
+		a.vectors[v.ID] = a.encode(stored, normalized)
+		partition, ok := a.partitions[v.TenantID]
+		if !ok {
+			partition = newVectorPartition()
+			a.partitions[v.TenantID] = partition
 		}
+		partition.add(v)
 	}
-	
 	return nil
 }
 
+// encode stores a normalized vector in the index's quantization. Until the
+// PQ codebooks are trained, vectors are kept at full precision.
+func (a *FAISSAdapter) encode(entry VectorEntry, normalized []float32) indexedVector {
+	a.seq++
+	iv := indexedVector{entry: entry, dim: len(normalized), seq: a.seq}
+	switch {
+	case a.quantization.Mode == QuantizationInt8:
+		iv.codes, iv.scale = quantizeInt8(normalized)
+	case a.pq != nil && len(normalized) == a.pq.dim:
+		iv.codes = a.pq.encode(normalized)
+	default:
+		iv.vector = normalized
+	}
+	return iv
+}
+
+// pqSnapshot holds the vectors PQ codebooks are trained on outside a.mu.
+type pqSnapshot struct {
+	dim      int
+	ids      []uuid.UUID
+	seqs     []uint64
+	vectors  [][]float32
+	previous *productQuantizer
+}
+
+// pqSnapshot returns the training vectors once the index holds TrainSize
+// vectors, or has grown by RetrainGrowth since the codebooks were trained,
+// and marks training as started. Vectors already encoded are reconstructed
+// from their codes. Training vectors are taken in ID order so the codebooks
+// are deterministic. force snapshots any non-empty index. It returns nil when
+// no training is due or one is running. The caller must hold a.mu.
+func (a *FAISSAdapter) pqSnapshot(force bool) *pqSnapshot {
+	if a.quantization.Mode != QuantizationPQ || a.training {
+		return nil
+	}
+	dim := a.dimension
+	if a.pq != nil {
+		dim = a.pq.dim
+	}
+	ids := make([]uuid.UUID, 0, len(a.vectors))
+	for id, iv := range a.vectors {
+		if iv.dim == dim && (iv.vector != nil || a.pq != nil) {
+			ids = append(ids, id)
+		}
+	}
+	switch {
+	case len(ids) == 0:
+		return nil
+	case force:
+	case a.pq == nil:
+		if len(ids) < a.quantization.TrainSize {
+			return nil
+		}
+	default:
+		growth := a.quantization.RetrainGrowth
+		if growth < 0 || float64(len(ids)) < growth*float64(a.trainedOn) {
+			return nil
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return bytes.Compare(ids[i][:], ids[j][:]) < 0
+	})
+
+	snapshot := &pqSnapshot{
+		dim:      dim,
+		ids:      ids,
+		seqs:     make([]uint64, len(ids)),
+		vectors:  make([][]float32, len(ids)),
+		previous: a.pq,
+	}
+	for i, id := range ids {
+		iv := a.vectors[id]
+		snapshot.seqs[i] = iv.seq
+		if iv.vector != nil {
+			snapshot.vectors[i] = iv.vector
+		} else {
+			snapshot.vectors[i] = a.pq.decode(iv.codes)
+		}
+	}
+	a.training = true
+	return snapshot
+}
+
+// trainPQ trains codebooks on a snapshot and encodes it without holding a.mu,
+// then swaps in the codebooks and codes at once. Retraining reads the
+// full-precision vectors from the RerankSource when set, rather than training
+// on reconstructed ones. Vectors stored during training are encoded at the swap.
+func (a *FAISSAdapter) trainPQ(ctx context.Context, snapshot *pqSnapshot) {
+	defer func() {
+		a.mu.Lock()
+		a.training = false
+		a.mu.Unlock()
+	}()
+
+	a.mu.RLock()
+	source := a.rerank
+	a.mu.RUnlock()
+	if snapshot.previous != nil && source != nil {
+		// A failed read leaves the reconstructed vectors to train on
+		if full, err := source.Vectors(ctx, snapshot.ids); err == nil {
+			for i, id := range snapshot.ids {
+				if v, ok := full[id]; ok && len(v) == snapshot.dim {
+					snapshot.vectors[i] = normalizeVector(v)
+				}
+			}
+		}
+	}
+
+	pq := trainProductQuantizer(snapshot.vectors, pqSubvectors(snapshot.dim, a.quantization.CompressionRatio))
+	encoded := make(map[uuid.UUID]indexedVector, len(snapshot.ids))
+	for i, id := range snapshot.ids {
+		encoded[id] = indexedVector{seq: snapshot.seqs[i], codes: pq.encode(snapshot.vectors[i])}
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for id, iv := range a.vectors {
+		if iv.dim != pq.dim {
+			continue
+		}
+		if e, ok := encoded[id]; ok && e.seq == iv.seq {
+			iv.codes = e.codes
+		} else if iv.vector != nil {
+			iv.codes = pq.encode(iv.vector)
+		} else {
+			iv.codes = pq.encode(a.pq.decode(iv.codes))
+		}
+		iv.vector = nil
+		a.vectors[id] = iv
+	}
+	a.pq = pq
+	a.trainedOn = len(snapshot.ids)
+}
+
+// RetrainPQ retrains the PQ codebooks on every vector held, e.g. after the
+// content of the index has shifted, and returns once they are in use. It does
+// nothing without PQ quantization or while training runs.
+func (a *FAISSAdapter) RetrainPQ(ctx context.Context) {
+	a.mu.Lock()
+	snapshot := a.pqSnapshot(true)
+	a.mu.Unlock()
+
+	if snapshot != nil {
+		a.trainPQ(ctx, snapshot)
+	}
+}
+
 // Delete removes vectors from the index.
 func (a *FAISSAdapter) Delete(ctx context.Context, ids []uuid.UUID) error {
 	a.mu.Lock()
 	defer a.mu.Unlock()
 	
 	for _, id := range ids {
-		delete(a.vectors, id)
+		if existing, ok := a.vectors[id]; ok {
+			a.unindex(existing.entry)
+			delete(a.vectors, id)
+		}
 	}
 	
 	return nil
 }
 
-// Count returns the number of vectors in the index.
-func (a *FAISSAdapter) Count(ctx context.Context) (int64, error) {
+// Count returns the number of vectors in the index matching filters, visiting
+// only the partitions the filters select.
+func (a *FAISSAdapter) Count(ctx context.Context, filters VectorFilters) (int64, error) {
 	a.mu.RLock()
 	defer a.mu.RUnlock()
-	return int64(len(a.vectors)), nil
+
+	if filters.isEmpty() {
+		return int64(len(a.vectors)), nil
+	}
+	var count int64
+	a.scan(filters, func(indexedVector) bool {
+		count++
+		return true
+	})
+	return count, nil
+}
+
+// scan calls fn with each vector matching filters until fn returns false.
+// Only the filtered tenant's partition is visited, and within a partition the
+// narrowest secondary index. The caller must hold a.mu.
+func (a *FAISSAdapter) scan(filters VectorFilters, fn func(indexedVector) bool) {
+	var partitions []*vectorPartition
+	if filters.TenantID != nil {
+		partition, ok := a.partitions[*filters.TenantID]
+		if !ok {
+			return
+		}
+		partitions = []*vectorPartition{partition}
+	} else {
+		partitions = make([]*vectorPartition, 0, len(a.partitions))
+		for _, partition := range a.partitions {
+			partitions = append(partitions, partition)
+		}
+	}
+
+	for _, partition := range partitions {
+		for _, ids := range partition.candidates(filters) {
+			for id := range ids {
+				iv := a.vectors[id]
+				if !matchesFilters(iv.entry, filters) {
+					continue
+				}
+				if filters.ExcludeOverridden && a.overridden(partition, iv.entry, filters.CampaignVariantIDs) {
+					continue
+				}
+				if !fn(iv) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// overridden reports whether a chunk's feature block is overridden by a block of
+// a variant nearer the head of chain. The caller must hold a.mu.
+func (a *FAISSAdapter) overridden(partition *vectorPartition, entry VectorEntry, chain []uuid.UUID) bool {
+	blockID, ok := entry.Metadata[storage.ChunkMetaBlockID].(string)
+	if !ok || entry.CampaignVariantID == nil {
+		return false
+	}
+	overriders := partition.overrides[blockID]
+	if len(overriders) == 0 {
+		return false
+	}
+	rank := chainIndex(chain, *entry.CampaignVariantID)
+	for id := range overriders {
+		campaign := a.vectors[id].entry.CampaignVariantID
+		if campaign == nil {
+			continue
+		}
+		if r := chainIndex(chain, *campaign); r >= 0 && r < rank {
+			return true
+		}
+	}
+	return false
+}
+
+// chainIndex returns the position of id in chain, or -1.
+func chainIndex(chain []uuid.UUID, id uuid.UUID) int {
+	for i, c := range chain {
+		if c == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// unindex removes an entry from its tenant's partition. The caller must hold a.mu.
+func (a *FAISSAdapter) unindex(entry VectorEntry) {
+	partition, ok := a.partitions[entry.TenantID]
+	if !ok {
+		return
+	}
+	partition.remove(entry)
+	if len(partition.ids) == 0 {
+		delete(a.partitions, entry.TenantID)
+	}
 }
 
 // Close releases resources.
@@ -319,6 +703,22 @@ func matchesFilters(entry VectorEntry, filters VectorFilters) bool {
 			return false
 		}
 	}
+
+	if len(filters.CampaignVariantIDs) > 0 {
+		if entry.CampaignVariantID == nil {
+			return false
+		}
+		found := false
+		for _, cid := range filters.CampaignVariantIDs {
+			if *entry.CampaignVariantID == cid {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
 	
 	if len(filters.ChunkTypes) > 0 {
 		found := false
@@ -333,6 +733,19 @@ func matchesFilters(entry VectorEntry, filters VectorFilters) bool {
 		}
 	}
 	
+	if len(filters.BlockTypes) > 0 {
+		found := false
+		for _, bt := range filters.BlockTypes {
+			if string(chunkBlockType(entry.Metadata)) == bt {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	
 	if len(filters.Visibility) > 0 {
 		found := false
 		for _, v := range filters.Visibility {
@@ -375,6 +788,17 @@ func cosineDistance(a, b []float32) float32 {
 	return 1 - dot
 }
 
+// dotDistance converts the dot product of unit vectors to a cosine distance.
+func dotDistance(dot float32) float32 {
+	// Quantization error can push the product slightly out of range
+	if dot > 1 {
+		dot = 1
+	} else if dot < -1 {
+		dot = -1
+	}
+	return 1 - dot
+}
+
 // normalizeVector returns a unit vector.
 func normalizeVector(v []float32) []float32 {
 	var norm float64
@@ -425,6 +849,10 @@ func NewPGVectorAdapter(cfg PGVectorConfig) (*PGVectorAdapter, error) {
 
 // Search finds the k nearest neighbors using PGVector.
 func (a *PGVectorAdapter) Search(ctx context.Context, query []float32, k int, filters VectorFilters) ([]VectorResult, error) {
+	_, span := startSearchSpan(ctx, "pgvector", k)
+	err := errors.New("pgvector adapter not yet implemented")
+	endSearchSpan(span, nil, err)
+
 	// TODO: Implement PGVector search
 	// SELECT id, embedding_vector <-> $1 AS distance
 	// FROM knowledge_chunks
@@ -432,7 +860,7 @@ func (a *PGVectorAdapter) Search(ctx context.Context, query []float32, k int, fi
 	// ORDER BY distance
 	// LIMIT $3
 	
-	return nil, errors.New("pgvector adapter not yet implemented")
+	return nil, err
 }
 
 // Insert adds vectors using PGVector.
@@ -447,8 +875,8 @@ func (a *PGVectorAdapter) Delete(ctx context.Context, ids []uuid.UUID) error {
 	return errors.New("pgvector adapter not yet implemented")
 }
 
-// Count returns the number of vectors.
-func (a *PGVectorAdapter) Count(ctx context.Context) (int64, error) {
+// Count returns the number of vectors matching filters.
+func (a *PGVectorAdapter) Count(ctx context.Context, filters VectorFilters) (int64, error) {
 	// TODO: Implement count query
 	return 0, errors.New("pgvector adapter not yet implemented")
 }
@@ -0,0 +1,136 @@
+package retrieval
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func resultIDs(results []VectorResult) []uuid.UUID {
+	ids := make([]uuid.UUID, len(results))
+	for i, r := range results {
+		ids[i] = r.ID
+	}
+	return ids
+}
+
+func TestFAISSAdapter_PartitionedFilters(t *testing.T) {
+	ctx := context.Background()
+	adapter, err := NewFAISSAdapter(FAISSConfig{Dimension: 3})
+	require.NoError(t, err)
+
+	tenantA, tenantB := uuid.New(), uuid.New()
+	spring, summer := uuid.New(), uuid.New()
+	entry := func(tenant uuid.UUID, campaign *uuid.UUID, chunkType, visibility string) VectorEntry {
+		return VectorEntry{
+			ID:                uuid.New(),
+			TenantID:          tenant,
+			ProductID:         uuid.New(),
+			CampaignVariantID: campaign,
+			ChunkType:         chunkType,
+			Visibility:        visibility,
+			EmbeddingVersion:  "v1",
+			Vector:            []float32{1, 0, 0},
+		}
+	}
+	springUSP := entry(tenantA, &spring, "usp", "private")
+	springFAQ := entry(tenantA, &spring, "faq", "public")
+	summerUSP := entry(tenantA, &summer, "usp", "public")
+	globalUSP := entry(tenantA, nil, "usp", "private")
+	otherUSP := entry(tenantB, &spring, "usp", "private")
+	require.NoError(t, adapter.Insert(ctx, []VectorEntry{springUSP, springFAQ, summerUSP, globalUSP, otherUSP}))
+
+	query := []float32{1, 0, 0}
+	tests := []struct {
+		name    string
+		filters VectorFilters
+		want    []uuid.UUID
+	}{
+		{"tenant", VectorFilters{TenantID: &tenantA}, []uuid.UUID{springUSP.ID, springFAQ.ID, summerUSP.ID, globalUSP.ID}},
+		{"campaign", VectorFilters{TenantID: &tenantA, CampaignVariantID: &spring}, []uuid.UUID{springUSP.ID, springFAQ.ID}},
+		{"campaign chain", VectorFilters{TenantID: &tenantA, CampaignVariantIDs: []uuid.UUID{spring, summer, spring}}, []uuid.UUID{springUSP.ID, springFAQ.ID, summerUSP.ID}},
+		{"chunk type and visibility", VectorFilters{TenantID: &tenantA, ChunkTypes: []string{"usp"}, Visibility: []string{"private"}}, []uuid.UUID{springUSP.ID, globalUSP.ID}},
+		{"campaign across tenants", VectorFilters{CampaignVariantID: &spring, ChunkTypes: []string{"usp"}}, []uuid.UUID{springUSP.ID, otherUSP.ID}},
+		{"unknown tenant", VectorFilters{TenantID: func() *uuid.UUID { id := uuid.New(); return &id }()}, nil},
+		{"unknown chunk type", VectorFilters{TenantID: &tenantA, ChunkTypes: []string{"spec_row"}}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := adapter.Search(ctx, query, 10, tt.filters)
+			require.NoError(t, err)
+			assert.ElementsMatch(t, tt.want, resultIDs(results))
+
+			count, err := adapter.Count(ctx, tt.filters)
+			require.NoError(t, err)
+			assert.EqualValues(t, len(tt.want), count)
+		})
+	}
+
+	total, err := adapter.Count(ctx, VectorFilters{})
+	require.NoError(t, err)
+	assert.EqualValues(t, 5, total)
+
+	t.Run("reinsert moves the vector between indexes", func(t *testing.T) {
+		moved := springUSP
+		moved.CampaignVariantID = &summer
+		moved.ChunkType = "faq"
+		require.NoError(t, adapter.Insert(ctx, []VectorEntry{moved}))
+
+		count, err := adapter.Count(ctx, VectorFilters{TenantID: &tenantA, CampaignVariantID: &spring})
+		require.NoError(t, err)
+		assert.EqualValues(t, 1, count)
+		count, err = adapter.Count(ctx, VectorFilters{TenantID: &tenantA, CampaignVariantID: &summer, ChunkTypes: []string{"faq"}})
+		require.NoError(t, err)
+		assert.EqualValues(t, 1, count)
+		total, err := adapter.Count(ctx, VectorFilters{})
+		require.NoError(t, err)
+		assert.EqualValues(t, 5, total)
+	})
+
+	t.Run("delete drops empty partitions", func(t *testing.T) {
+		require.NoError(t, adapter.Delete(ctx, []uuid.UUID{otherUSP.ID, uuid.New()}))
+		count, err := adapter.Count(ctx, VectorFilters{TenantID: &tenantB})
+		require.NoError(t, err)
+		assert.Zero(t, count)
+		assert.NotContains(t, adapter.partitions, tenantB)
+
+		results, err := adapter.Search(ctx, query, 10, VectorFilters{CampaignVariantID: &spring})
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []uuid.UUID{springFAQ.ID}, resultIDs(results))
+	})
+}
+
+func TestFAISSAdapter_ExcludeOverridden(t *testing.T) {
+	ctx := context.Background()
+	adapter, err := NewFAISSAdapter(FAISSConfig{Dimension: 3})
+	require.NoError(t, err)
+
+	tenant, base, derived := uuid.New(), uuid.New(), uuid.New()
+	baseBlock, keptBlock := uuid.New().String(), uuid.New().String()
+	entry := func(campaign uuid.UUID, metadata map[string]interface{}) VectorEntry {
+		return VectorEntry{ID: uuid.New(), TenantID: tenant, ProductID: uuid.New(), CampaignVariantID: &campaign, ChunkType: "feature", Vector: []float32{1, 0, 0}, Metadata: metadata}
+	}
+	overridden := entry(base, map[string]interface{}{"block_id": baseBlock})
+	kept := entry(base, map[string]interface{}{"block_id": keptBlock})
+	override := entry(derived, map[string]interface{}{"block_id": uuid.New().String(), "overrides_block_id": baseBlock})
+	require.NoError(t, adapter.Insert(ctx, []VectorEntry{overridden, kept, override}))
+
+	query := []float32{1, 0, 0}
+	chain := []uuid.UUID{derived, base}
+	results, err := adapter.Search(ctx, query, 10, VectorFilters{TenantID: &tenant, CampaignVariantIDs: chain, ExcludeOverridden: true})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []uuid.UUID{kept.ID, override.ID}, resultIDs(results))
+
+	// Searching the base variant alone still finds its own block
+	results, err = adapter.Search(ctx, query, 10, VectorFilters{TenantID: &tenant, CampaignVariantIDs: []uuid.UUID{base}, ExcludeOverridden: true})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []uuid.UUID{overridden.ID, kept.ID}, resultIDs(results))
+
+	require.NoError(t, adapter.Delete(ctx, []uuid.UUID{override.ID}))
+	results, err = adapter.Search(ctx, query, 10, VectorFilters{TenantID: &tenant, CampaignVariantIDs: chain, ExcludeOverridden: true})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []uuid.UUID{overridden.ID, kept.ID}, resultIDs(results))
+}
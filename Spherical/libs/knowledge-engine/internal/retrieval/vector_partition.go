@@ -0,0 +1,128 @@
+// Package retrieval provides the tenant partitions of the in-memory vector index.
+package retrieval
+
+import (
+	"github.com/google/uuid"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/storage"
+)
+
+// idSet is a set of vector IDs.
+type idSet map[uuid.UUID]struct{}
+
+// vectorPartition holds the IDs of one tenant's vectors, with secondary
+// indexes on the filters most searches apply. Campaign variants are keyed by
+// their string form.
+type vectorPartition struct {
+	ids        idSet
+	campaigns  map[string]idSet
+	chunkTypes map[string]idSet
+	visibility map[string]idSet
+	// overrides maps a feature block ID to the chunks of blocks overriding it.
+	overrides map[string]idSet
+}
+
+func newVectorPartition() *vectorPartition {
+	return &vectorPartition{
+		ids:        make(idSet),
+		campaigns:  make(map[string]idSet),
+		chunkTypes: make(map[string]idSet),
+		visibility: make(map[string]idSet),
+		overrides:  make(map[string]idSet),
+	}
+}
+
+func (p *vectorPartition) add(entry VectorEntry) {
+	p.ids[entry.ID] = struct{}{}
+	if entry.CampaignVariantID != nil {
+		addPosting(p.campaigns, entry.CampaignVariantID.String(), entry.ID)
+	}
+	addPosting(p.chunkTypes, entry.ChunkType, entry.ID)
+	addPosting(p.visibility, entry.Visibility, entry.ID)
+	if blockID, ok := entry.Metadata[storage.ChunkMetaOverridesBlock].(string); ok {
+		addPosting(p.overrides, blockID, entry.ID)
+	}
+}
+
+func (p *vectorPartition) remove(entry VectorEntry) {
+	delete(p.ids, entry.ID)
+	if entry.CampaignVariantID != nil {
+		removePosting(p.campaigns, entry.CampaignVariantID.String(), entry.ID)
+	}
+	removePosting(p.chunkTypes, entry.ChunkType, entry.ID)
+	removePosting(p.visibility, entry.Visibility, entry.ID)
+	if blockID, ok := entry.Metadata[storage.ChunkMetaOverridesBlock].(string); ok {
+		removePosting(p.overrides, blockID, entry.ID)
+	}
+}
+
+func addPosting(postings map[string]idSet, key string, id uuid.UUID) {
+	ids, ok := postings[key]
+	if !ok {
+		ids = make(idSet)
+		postings[key] = ids
+	}
+	ids[id] = struct{}{}
+}
+
+func removePosting(postings map[string]idSet, key string, id uuid.UUID) {
+	ids, ok := postings[key]
+	if !ok {
+		return
+	}
+	delete(ids, id)
+	if len(ids) == 0 {
+		delete(postings, key)
+	}
+}
+
+// candidates returns the narrowest of the partition's postings that can hold
+// vectors matching filters, as disjoint sets. Callers still check each
+// candidate with matchesFilters, since only one posting is used.
+func (p *vectorPartition) candidates(filters VectorFilters) []idSet {
+	best := []idSet{p.ids}
+	bestSize := len(p.ids)
+	consider := func(sets []idSet) {
+		size := 0
+		for _, ids := range sets {
+			size += len(ids)
+		}
+		if size < bestSize {
+			best, bestSize = sets, size
+		}
+	}
+
+	// A vector has one campaign, chunk type and visibility, so the postings
+	// selected by one filter never overlap
+	if filters.CampaignVariantID != nil {
+		consider(postingsFor(p.campaigns, []string{filters.CampaignVariantID.String()}))
+	} else if len(filters.CampaignVariantIDs) > 0 {
+		keys := make([]string, len(filters.CampaignVariantIDs))
+		for i, id := range filters.CampaignVariantIDs {
+			keys[i] = id.String()
+		}
+		consider(postingsFor(p.campaigns, keys))
+	}
+	if len(filters.ChunkTypes) > 0 {
+		consider(postingsFor(p.chunkTypes, filters.ChunkTypes))
+	}
+	if len(filters.Visibility) > 0 {
+		consider(postingsFor(p.visibility, filters.Visibility))
+	}
+	return best
+}
+
+// postingsFor returns the postings of the distinct keys present.
+func postingsFor(postings map[string]idSet, keys []string) []idSet {
+	sets := make([]idSet, 0, len(keys))
+	seen := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if ids, ok := postings[key]; ok {
+			sets = append(sets, ids)
+		}
+	}
+	return sets
+}
@@ -0,0 +1,255 @@
+// Package retrieval provides quantization of the in-memory vector index.
+package retrieval
+
+import (
+	"context"
+	"math"
+
+	"github.com/google/uuid"
+)
+
+// Quantization modes of the in-memory vector index.
+const (
+	// QuantizationNone stores float32 vectors (4 bytes per dimension).
+	QuantizationNone = ""
+	// QuantizationInt8 stores one signed byte per dimension and a scale (about 4x smaller).
+	QuantizationInt8 = "int8"
+	// QuantizationPQ stores product quantization codes, one byte per subvector.
+	QuantizationPQ = "pq"
+)
+
+// QuantizationConfig holds vector quantization settings of the FAISS adapter.
+type QuantizationConfig struct {
+	// Mode is QuantizationNone, QuantizationInt8 or QuantizationPQ.
+	Mode string
+	// CompressionRatio is the size of a float32 vector over its PQ code (default 16).
+	// The number of subvectors is rounded down to a divisor of the dimension.
+	CompressionRatio int
+	// TrainSize is the number of vectors held at full precision before the PQ
+	// codebooks are trained on them and every vector is encoded (default 1024).
+	TrainSize int
+	// RerankFactor is the number of candidates per requested result re-ranked
+	// at full precision when a RerankSource is set (default 10).
+	RerankFactor int
+	// RetrainGrowth retrains the PQ codebooks once the index has grown by this
+	// factor since they were last trained (default 2; negative disables).
+	RetrainGrowth float64
+}
+
+func (c QuantizationConfig) withDefaults() QuantizationConfig {
+	if c.CompressionRatio <= 0 {
+		c.CompressionRatio = 16
+	}
+	if c.TrainSize <= 0 {
+		c.TrainSize = 1024
+	}
+	if c.RerankFactor <= 0 {
+		c.RerankFactor = 10
+	}
+	if c.RetrainGrowth == 0 {
+		c.RetrainGrowth = 2
+	}
+	return c
+}
+
+// RerankSource supplies the full-precision vectors of a quantized index, for
+// example from the chunk store, so its top candidates can be re-ranked.
+// Vectors missing from the result keep their quantized score.
+type RerankSource interface {
+	Vectors(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID][]float32, error)
+}
+
+// quantizeInt8 encodes a vector as signed bytes scaled by its largest component.
+func quantizeInt8(v []float32) ([]byte, float32) {
+	var maxAbs float32
+	for _, x := range v {
+		if x < 0 {
+			x = -x
+		}
+		if x > maxAbs {
+			maxAbs = x
+		}
+	}
+	codes := make([]byte, len(v))
+	if maxAbs == 0 {
+		return codes, 0
+	}
+	scale := maxAbs / 127
+	for i, x := range v {
+		codes[i] = byte(int8(math.Round(float64(x / scale))))
+	}
+	return codes, scale
+}
+
+// int8Dot returns the dot product of a full-precision query and an int8 code.
+func int8Dot(query []float32, codes []byte, scale float32) float32 {
+	var dot float32
+	for i, c := range codes {
+		dot += query[i] * float32(int8(c))
+	}
+	return dot * scale
+}
+
+// productQuantizer splits vectors into subvectors and encodes each as the
+// nearest of up to 256 centroids learned by k-means.
+type productQuantizer struct {
+	dim        int
+	subvectors int
+	subDim     int
+	centroids  int
+	// codebooks holds, per subvector, centroids*subDim values.
+	codebooks [][]float32
+}
+
+// pqSubvectors returns the number of subvectors that compress a float32
+// vector of dim dimensions by about ratio.
+func pqSubvectors(dim, ratio int) int {
+	target := 4 * dim / ratio
+	if target < 1 {
+		target = 1
+	}
+	if target > dim {
+		target = dim
+	}
+	for m := target; m > 1; m-- {
+		if dim%m == 0 {
+			return m
+		}
+	}
+	return 1
+}
+
+// trainProductQuantizer learns codebooks from vectors of equal dimension.
+func trainProductQuantizer(vectors [][]float32, subvectors int) *productQuantizer {
+	dim := len(vectors[0])
+	pq := &productQuantizer{
+		dim:        dim,
+		subvectors: subvectors,
+		subDim:     dim / subvectors,
+		centroids:  256,
+		codebooks:  make([][]float32, subvectors),
+	}
+	if len(vectors) < pq.centroids {
+		pq.centroids = len(vectors)
+	}
+	for m := range pq.codebooks {
+		pq.codebooks[m] = pq.trainSubspace(vectors, m)
+	}
+	return pq
+}
+
+// trainSubspace runs k-means over one subvector of every training vector,
+// seeded with evenly spaced samples so training is deterministic.
+func (pq *productQuantizer) trainSubspace(vectors [][]float32, m int) []float32 {
+	const iterations = 12
+	offset := m * pq.subDim
+	codebook := make([]float32, pq.centroids*pq.subDim)
+	for c := 0; c < pq.centroids; c++ {
+		sample := vectors[c*len(vectors)/pq.centroids]
+		copy(codebook[c*pq.subDim:], sample[offset:offset+pq.subDim])
+	}
+
+	assignments := make([]int, len(vectors))
+	sums := make([]float64, len(codebook))
+	counts := make([]int, pq.centroids)
+	for iter := 0; iter < iterations; iter++ {
+		changed := false
+		for i, v := range vectors {
+			c := pq.nearest(codebook, v[offset:offset+pq.subDim])
+			if iter == 0 || c != assignments[i] {
+				assignments[i] = c
+				changed = true
+			}
+		}
+		if !changed {
+			break
+		}
+
+		for i := range sums {
+			sums[i] = 0
+		}
+		for i := range counts {
+			counts[i] = 0
+		}
+		for i, v := range vectors {
+			c := assignments[i]
+			counts[c]++
+			for d := 0; d < pq.subDim; d++ {
+				sums[c*pq.subDim+d] += float64(v[offset+d])
+			}
+		}
+		// Empty clusters keep their centroid
+		for c := 0; c < pq.centroids; c++ {
+			if counts[c] == 0 {
+				continue
+			}
+			for d := 0; d < pq.subDim; d++ {
+				codebook[c*pq.subDim+d] = float32(sums[c*pq.subDim+d] / float64(counts[c]))
+			}
+		}
+	}
+	return codebook
+}
+
+// nearest returns the centroid of codebook closest to sub in squared distance.
+func (pq *productQuantizer) nearest(codebook, sub []float32) int {
+	best, bestDist := 0, float32(math.MaxFloat32)
+	for c := 0; c < pq.centroids; c++ {
+		centroid := codebook[c*pq.subDim : (c+1)*pq.subDim]
+		var dist float32
+		for d, x := range sub {
+			diff := x - centroid[d]
+			dist += diff * diff
+		}
+		if dist < bestDist {
+			best, bestDist = c, dist
+		}
+	}
+	return best
+}
+
+// encode returns the code of a vector: the nearest centroid of each subvector.
+func (pq *productQuantizer) encode(v []float32) []byte {
+	codes := make([]byte, pq.subvectors)
+	for m := range codes {
+		offset := m * pq.subDim
+		codes[m] = byte(pq.nearest(pq.codebooks[m], v[offset:offset+pq.subDim]))
+	}
+	return codes
+}
+
+// decode reconstructs a vector from its code as the concatenated centroids.
+func (pq *productQuantizer) decode(codes []byte) []float32 {
+	v := make([]float32, pq.dim)
+	for m, c := range codes {
+		copy(v[m*pq.subDim:], pq.codebooks[m][int(c)*pq.subDim:(int(c)+1)*pq.subDim])
+	}
+	return v
+}
+
+// table precomputes the dot product of each query subvector with every
+// centroid, so scoring a code costs one lookup per subvector.
+func (pq *productQuantizer) table(query []float32) []float32 {
+	table := make([]float32, pq.subvectors*pq.centroids)
+	for m := 0; m < pq.subvectors; m++ {
+		sub := query[m*pq.subDim : (m+1)*pq.subDim]
+		for c := 0; c < pq.centroids; c++ {
+			centroid := pq.codebooks[m][c*pq.subDim : (c+1)*pq.subDim]
+			var dot float32
+			for d, x := range sub {
+				dot += x * centroid[d]
+			}
+			table[m*pq.centroids+c] = dot
+		}
+	}
+	return table
+}
+
+// score returns the approximate dot product of the query behind table and a code.
+func (pq *productQuantizer) score(table []float32, codes []byte) float32 {
+	var dot float32
+	for m, c := range codes {
+		dot += table[m*pq.centroids+int(c)]
+	}
+	return dot
+}
@@ -0,0 +1,294 @@
+package retrieval
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mapRerankSource serves full-precision vectors from memory.
+type mapRerankSource map[uuid.UUID][]float32
+
+func (s mapRerankSource) Vectors(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID][]float32, error) {
+	vectors := make(map[uuid.UUID][]float32, len(ids))
+	for _, id := range ids {
+		if v, ok := s[id]; ok {
+			vectors[id] = v
+		}
+	}
+	return vectors, nil
+}
+
+// clusteredVectors generates n vectors scattered around a few centers, the
+// way embeddings of related chunks cluster.
+func clusteredVectors(rng *rand.Rand, centers [][]float32, n int) [][]float32 {
+	vectors := make([][]float32, n)
+	for i := range vectors {
+		center := centers[rng.Intn(len(centers))]
+		v := make([]float32, len(center))
+		for d := range v {
+			v[d] = center[d] + float32(rng.NormFloat64())*0.4
+		}
+		vectors[i] = v
+	}
+	return vectors
+}
+
+// quantizationFixture indexes the same vectors at full precision and with the
+// given quantization, and holds queries drawn from the same distribution.
+type quantizationFixture struct {
+	baseline  *FAISSAdapter
+	quantized *FAISSAdapter
+	source    mapRerankSource
+	queries   [][]float32
+}
+
+func newQuantizationFixture(t testing.TB, cfg QuantizationConfig, dim, n, queries int) *quantizationFixture {
+	ctx := context.Background()
+	rng := rand.New(rand.NewSource(1))
+	centers := make([][]float32, 32)
+	for i := range centers {
+		centers[i] = make([]float32, dim)
+		for d := range centers[i] {
+			centers[i][d] = float32(rng.NormFloat64())
+		}
+	}
+
+	f := &quantizationFixture{source: make(mapRerankSource)}
+	var err error
+	f.baseline, err = NewFAISSAdapter(FAISSConfig{Dimension: dim})
+	require.NoError(t, err)
+	f.quantized, err = NewFAISSAdapter(FAISSConfig{Dimension: dim, Quantization: cfg})
+	require.NoError(t, err)
+
+	tenantID := uuid.New()
+	entries := make([]VectorEntry, 0, n)
+	for _, v := range clusteredVectors(rng, centers, n) {
+		entry := VectorEntry{ID: uuid.New(), TenantID: tenantID, ChunkType: "usp", Vector: v}
+		entries = append(entries, entry)
+		f.source[entry.ID] = v
+	}
+	require.NoError(t, f.baseline.Insert(ctx, entries))
+	require.NoError(t, f.quantized.Insert(ctx, entries))
+
+	for _, q := range clusteredVectors(rng, centers, queries) {
+		f.queries = append(f.queries, normalizeVector(q))
+	}
+	return f
+}
+
+// recall returns the share of the baseline's top k the quantized index finds.
+func (f *quantizationFixture) recall(t testing.TB, k int) float64 {
+	ctx := context.Background()
+	found := 0
+	for _, q := range f.queries {
+		want, err := f.baseline.Search(ctx, q, k, VectorFilters{})
+		require.NoError(t, err)
+		got, err := f.quantized.Search(ctx, q, k, VectorFilters{})
+		require.NoError(t, err)
+
+		wantIDs := make(map[uuid.UUID]bool, len(want))
+		for _, r := range want {
+			wantIDs[r.ID] = true
+		}
+		for _, r := range got {
+			if wantIDs[r.ID] {
+				found++
+			}
+		}
+	}
+	return float64(found) / float64(k*len(f.queries))
+}
+
+// bytesPerVector returns the average size of the stored vectors and codes.
+func (a *FAISSAdapter) bytesPerVector() float64 {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	total := 0
+	for _, iv := range a.vectors {
+		total += 4*len(iv.vector) + len(iv.codes)
+		if iv.entry.Vector != nil {
+			total += 4 * len(iv.entry.Vector)
+		}
+	}
+	return float64(total) / float64(len(a.vectors))
+}
+
+func TestFAISSAdapter_Quantization(t *testing.T) {
+	const dim, n, k = 64, 1000, 10
+
+	t.Run("int8", func(t *testing.T) {
+		f := newQuantizationFixture(t, QuantizationConfig{Mode: QuantizationInt8}, dim, n, 20)
+		assert.Equal(t, float64(dim), f.quantized.bytesPerVector())
+		assert.Equal(t, float64(4*dim), f.baseline.bytesPerVector(), "entries do not keep a second copy")
+		assert.GreaterOrEqual(t, f.recall(t, k), 0.9)
+	})
+
+	t.Run("pq with re-ranking", func(t *testing.T) {
+		cfg := QuantizationConfig{Mode: QuantizationPQ, CompressionRatio: 8, TrainSize: 500}
+		f := newQuantizationFixture(t, cfg, dim, n, 20)
+		require.NotNil(t, f.quantized.pq)
+		assert.Equal(t, float64(4*dim/8), f.quantized.bytesPerVector())
+
+		approximate := f.recall(t, k)
+		f.quantized.SetRerankSource(f.source)
+		reranked := f.recall(t, k)
+		assert.GreaterOrEqual(t, reranked, 0.9)
+		assert.GreaterOrEqual(t, reranked, approximate)
+
+		// Re-ranked distances are exact
+		results, err := f.quantized.Search(context.Background(), f.queries[0], k, VectorFilters{})
+		require.NoError(t, err)
+		all, err := f.baseline.Search(context.Background(), f.queries[0], n, VectorFilters{})
+		require.NoError(t, err)
+		exact := make(map[uuid.UUID]float32, len(all))
+		for _, r := range all {
+			exact[r.ID] = r.Distance
+		}
+		for _, r := range results {
+			assert.InDelta(t, exact[r.ID], r.Distance, 1e-5)
+		}
+	})
+
+	t.Run("pq holds full precision until trained", func(t *testing.T) {
+		cfg := QuantizationConfig{Mode: QuantizationPQ, TrainSize: 2 * n}
+		f := newQuantizationFixture(t, cfg, dim, n, 5)
+		assert.Nil(t, f.quantized.pq)
+		assert.Equal(t, 1.0, f.recall(t, k))
+	})
+
+	_, err := NewFAISSAdapter(FAISSConfig{Quantization: QuantizationConfig{Mode: "int4"}})
+	assert.Error(t, err)
+}
+
+// lockProbeSource serves vectors after checking that the adapter's lock is
+// free, which it is not if training holds it.
+type lockProbeSource struct {
+	mapRerankSource
+	adapter *FAISSAdapter
+	calls   int
+}
+
+func (s *lockProbeSource) Vectors(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID][]float32, error) {
+	s.calls++
+	if !s.adapter.mu.TryLock() {
+		return nil, fmt.Errorf("adapter lock held while reading vectors")
+	}
+	s.adapter.mu.Unlock()
+	return s.mapRerankSource.Vectors(ctx, ids)
+}
+
+func TestFAISSAdapter_RetrainPQ(t *testing.T) {
+	const dim, n, k = 64, 1000, 10
+	ctx := context.Background()
+
+	t.Run("retrains once the index has grown", func(t *testing.T) {
+		cfg := QuantizationConfig{Mode: QuantizationPQ, CompressionRatio: 8, TrainSize: 200}
+		f := newQuantizationFixture(t, cfg, dim, n, 5)
+		a := f.quantized
+		require.NotNil(t, a.pq)
+		assert.Equal(t, n, a.trainedOn)
+		first := a.pq
+
+		// Below the growth factor the codebooks are kept
+		rng := rand.New(rand.NewSource(2))
+		more := clusteredVectors(rng, [][]float32{f.queries[0]}, n/2)
+		entries := make([]VectorEntry, len(more))
+		for i, v := range more {
+			entries[i] = VectorEntry{ID: uuid.New(), TenantID: uuid.New(), Vector: v}
+		}
+		require.NoError(t, a.Insert(ctx, entries))
+		assert.Same(t, first, a.pq)
+		assert.Equal(t, float64(4*dim/8), a.bytesPerVector(), "new vectors are encoded with the current codebooks")
+
+		more = clusteredVectors(rng, [][]float32{f.queries[1]}, n/2)
+		entries = make([]VectorEntry, len(more))
+		for i, v := range more {
+			entries[i] = VectorEntry{ID: uuid.New(), TenantID: uuid.New(), Vector: v}
+		}
+		require.NoError(t, a.Insert(ctx, entries))
+		assert.NotSame(t, first, a.pq)
+		assert.Equal(t, 2*n, a.trainedOn)
+		assert.Equal(t, float64(4*dim/8), a.bytesPerVector())
+		assert.False(t, a.training)
+	})
+
+	t.Run("retraining reads full precision outside the lock", func(t *testing.T) {
+		cfg := QuantizationConfig{Mode: QuantizationPQ, CompressionRatio: 8, TrainSize: 500, RetrainGrowth: -1}
+		f := newQuantizationFixture(t, cfg, dim, n, 20)
+		a := f.quantized
+		source := &lockProbeSource{mapRerankSource: f.source, adapter: a}
+		a.SetRerankSource(source)
+		before := f.recall(t, k)
+		first := a.pq
+
+		source.calls = 0
+		a.RetrainPQ(ctx)
+		assert.Equal(t, 1, source.calls)
+		assert.NotSame(t, first, a.pq)
+		assert.Equal(t, float64(4*dim/8), a.bytesPerVector())
+		assert.GreaterOrEqual(t, f.recall(t, k), before-0.05)
+	})
+
+	t.Run("retraining without a source uses reconstructed vectors", func(t *testing.T) {
+		cfg := QuantizationConfig{Mode: QuantizationPQ, CompressionRatio: 8, TrainSize: 500}
+		f := newQuantizationFixture(t, cfg, dim, n, 20)
+		first := f.quantized.pq
+		f.quantized.RetrainPQ(ctx)
+		assert.NotSame(t, first, f.quantized.pq)
+		assert.GreaterOrEqual(t, f.recall(t, k), 0.5)
+	})
+
+	t.Run("does nothing without pq", func(t *testing.T) {
+		f := newQuantizationFixture(t, QuantizationConfig{Mode: QuantizationInt8}, dim, 100, 1)
+		f.quantized.RetrainPQ(ctx)
+		assert.Nil(t, f.quantized.pq)
+	})
+}
+
+func TestPQSubvectors(t *testing.T) {
+	assert.Equal(t, 192, pqSubvectors(768, 16))
+	assert.Equal(t, 96, pqSubvectors(768, 32))
+	assert.Equal(t, 25, pqSubvectors(100, 16))
+	assert.Equal(t, 1, pqSubvectors(10, 64))
+	assert.Equal(t, 10, pqSubvectors(10, 1))
+}
+
+func BenchmarkFAISSAdapter_Quantization(b *testing.B) {
+	const dim, n, k = 256, 4000, 10
+	modes := []struct {
+		name   string
+		cfg    QuantizationConfig
+		rerank bool
+	}{
+		{"float32", QuantizationConfig{}, false},
+		{"int8", QuantizationConfig{Mode: QuantizationInt8}, false},
+		{"pq16", QuantizationConfig{Mode: QuantizationPQ, TrainSize: n}, false},
+		{"pq16+rerank", QuantizationConfig{Mode: QuantizationPQ, TrainSize: n}, true},
+		{"pq32+rerank", QuantizationConfig{Mode: QuantizationPQ, CompressionRatio: 32, TrainSize: n}, true},
+	}
+	for _, mode := range modes {
+		b.Run(mode.name, func(b *testing.B) {
+			f := newQuantizationFixture(b, mode.cfg, dim, n, 50)
+			if mode.rerank {
+				f.quantized.SetRerankSource(f.source)
+			}
+			recall := f.recall(b, k)
+
+			ctx := context.Background()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := f.quantized.Search(ctx, f.queries[i%len(f.queries)], k, VectorFilters{}); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(recall, fmt.Sprintf("recall@%d", k))
+			b.ReportMetric(f.quantized.bytesPerVector(), "bytes/vector")
+		})
+	}
+}
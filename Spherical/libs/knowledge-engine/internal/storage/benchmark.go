@@ -0,0 +1,188 @@
+// Package storage provides read access to public benchmark data shared across tenants.
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+// Comparison row shareability values, matching the comparison_shareability
+// enum. ShareabilityPublic also applies to comparison rows.
+const (
+	ComparisonTenantOnly    Shareability = "tenant_only"
+	ComparisonBenchmarkOnly Shareability = "benchmark_only"
+)
+
+// BenchmarkRepository reads the curated benchmark data other tenants may
+// retrieve. Every query enforces the visibility rules: only products flagged
+// is_public_benchmark, only published spec values, only chunks with benchmark
+// visibility, and only comparison rows shared beyond their tenant.
+type BenchmarkRepository struct {
+	db DB
+}
+
+// NewBenchmarkRepository creates a new benchmark repository.
+func NewBenchmarkRepository(db DB) *BenchmarkRepository {
+	return &BenchmarkRepository{db: db}
+}
+
+// Products lists the public benchmark products of all tenants.
+func (r *BenchmarkRepository) Products(ctx context.Context) ([]*Product, error) {
+	query := `
+		SELECT id, tenant_id, name, segment, body_type, model_year,
+			is_public_benchmark, default_campaign_variant_id, metadata, created_at, updated_at
+		FROM products
+		WHERE is_public_benchmark = TRUE
+		ORDER BY name
+	`
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var products []*Product
+	for rows.Next() {
+		product := &Product{}
+		if err := rows.Scan(
+			&product.ID, &product.TenantID, &product.Name, &product.Segment, &product.BodyType,
+			&product.ModelYear, &product.IsPublicBenchmark, &product.DefaultCampaignVariantID,
+			&product.Metadata, &product.CreatedAt, &product.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		products = append(products, product)
+	}
+	return products, rows.Err()
+}
+
+// Specs lists the published spec values of public benchmark products.
+func (r *BenchmarkRepository) Specs(ctx context.Context) ([]SpecViewLatest, error) {
+	query := `
+		SELECT
+			sv.id, sv.tenant_id, sv.product_id, sv.campaign_variant_id, sv.spec_item_id,
+			sv.spec_name, sv.category_name, sv.value, sv.unit, sv.confidence,
+			sv.source_doc_id, sv.source_page, sv.version, sv.locale, sv.trim,
+			sv.market, sv.product_name, sv.value_numeric
+		FROM spec_view_latest sv
+		JOIN products p ON p.id = sv.product_id AND p.tenant_id = sv.tenant_id
+		WHERE p.is_public_benchmark = TRUE
+		ORDER BY sv.product_name, sv.category_name, sv.spec_name
+	`
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var specs []SpecViewLatest
+	for rows.Next() {
+		var sv SpecViewLatest
+		if err := rows.Scan(
+			&sv.ID, &sv.TenantID, &sv.ProductID, &sv.CampaignVariantID, &sv.SpecItemID,
+			&sv.SpecName, &sv.CategoryName, &sv.Value, &sv.Unit, &sv.Confidence,
+			&sv.SourceDocID, &sv.SourcePage, &sv.Version, &sv.Locale, &sv.Trim,
+			&sv.Market, &sv.ProductName, &sv.ValueNumeric,
+		); err != nil {
+			return nil, err
+		}
+		specs = append(specs, sv)
+	}
+	return specs, rows.Err()
+}
+
+// Chunks lists the benchmark-visible knowledge chunks of public benchmark
+// products. Chunks shared with visibility 'shared' stay within partner scope
+// and are not returned.
+func (r *BenchmarkRepository) Chunks(ctx context.Context) ([]*KnowledgeChunk, error) {
+	query := `
+		SELECT kc.id, kc.tenant_id, kc.product_id, kc.campaign_variant_id, kc.chunk_type,
+			kc.text, kc.metadata, kc.embedding_model, kc.embedding_version, kc.source_doc_id, kc.source_page,
+			kc.visibility, kc.created_at, kc.updated_at
+		FROM knowledge_chunks_shared kc
+		JOIN products p ON p.id = kc.product_id AND p.tenant_id = kc.tenant_id
+		WHERE kc.visibility = 'benchmark' AND p.is_public_benchmark = TRUE
+	`
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var chunks []*KnowledgeChunk
+	for rows.Next() {
+		chunk := &KnowledgeChunk{}
+		if err := rows.Scan(
+			&chunk.ID, &chunk.TenantID, &chunk.ProductID, &chunk.CampaignVariantID, &chunk.ChunkType,
+			&chunk.Text, &chunk.Metadata, &chunk.EmbeddingModel, &chunk.EmbeddingVersion,
+			&chunk.SourceDocID, &chunk.SourcePage, &chunk.Visibility, &chunk.CreatedAt, &chunk.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, chunk)
+	}
+	return chunks, rows.Err()
+}
+
+// Comparisons lists comparison rows between a tenant's product and public
+// benchmark products of other tenants, limited to rows shared beyond the tenant.
+func (r *BenchmarkRepository) Comparisons(ctx context.Context, tenantID, productID uuid.UUID) ([]ComparisonRow, error) {
+	query := `
+		SELECT cr.id, cr.primary_product_id, cr.secondary_product_id, cr.dimension,
+			cr.primary_value, cr.secondary_value, cr.verdict, cr.narrative, cr.shareability,
+			cr.source_primary_spec_id, cr.source_secondary_spec_id, cr.computed_at
+		FROM comparison_rows cr
+		JOIN products primary_p ON primary_p.id = cr.primary_product_id
+		JOIN products secondary_p ON secondary_p.id = cr.secondary_product_id
+		WHERE cr.primary_product_id = $1
+			AND primary_p.tenant_id = $2
+			AND secondary_p.tenant_id <> $2
+			AND secondary_p.is_public_benchmark = TRUE
+			AND cr.shareability IN ('benchmark_only', 'public')
+		ORDER BY cr.dimension
+	`
+	rows, err := r.db.QueryContext(ctx, query, productID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var comparisons []ComparisonRow
+	for rows.Next() {
+		var cr ComparisonRow
+		if err := rows.Scan(
+			&cr.ID, &cr.PrimaryProductID, &cr.SecondaryProductID, &cr.Dimension,
+			&cr.PrimaryValue, &cr.SecondaryValue, &cr.Verdict, &cr.Narrative, &cr.Shareability,
+			&cr.SourcePrimarySpecID, &cr.SourceSecondarySpecID, &cr.ComputedAt,
+		); err != nil {
+			return nil, err
+		}
+		comparisons = append(comparisons, cr)
+	}
+	return comparisons, rows.Err()
+}
+
+// ProductOwnership describes a product's owner for cross-tenant access checks.
+type ProductOwnership struct {
+	TenantID  uuid.UUID
+	Name      string
+	Benchmark bool
+}
+
+// ProductOwnership returns a product's owning tenant, name and whether it is a
+// public benchmark.
+func (r *BenchmarkRepository) ProductOwnership(ctx context.Context, productID uuid.UUID) (*ProductOwnership, error) {
+	query := `SELECT tenant_id, name, is_public_benchmark FROM products WHERE id = $1`
+	owner := &ProductOwnership{}
+	err := r.db.QueryRowContext(ctx, query, productID).Scan(&owner.TenantID, &owner.Name, &owner.Benchmark)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return owner, nil
+}
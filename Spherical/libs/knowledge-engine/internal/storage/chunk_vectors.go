@@ -0,0 +1,196 @@
+// Package storage provides access to the stored embeddings of knowledge chunks.
+package storage
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// chunkVectorBatch bounds the IDs bound to one query.
+const chunkVectorBatch = 500
+
+// encodeEmbeddingVector formats a vector for the embedding_vector column in
+// the pgvector text format, [x,y,...], which SQLite stores as is. An empty
+// vector is stored as NULL.
+func encodeEmbeddingVector(vector []float32) interface{} {
+	if len(vector) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(vector)
+	if err != nil {
+		// Only NaN and infinite components fail, which embedders never produce
+		return nil
+	}
+	return string(data)
+}
+
+// decodeEmbeddingVector parses an embedding_vector value: the pgvector text
+// format, or little-endian float32s written by older loaders.
+func decodeEmbeddingVector(data []byte) ([]float32, error) {
+	text := strings.TrimSpace(string(data))
+	if text == "" {
+		return nil, nil
+	}
+	if strings.HasPrefix(text, "[") {
+		var vector []float32
+		if err := json.Unmarshal([]byte(text), &vector); err != nil {
+			return nil, fmt.Errorf("decode embedding vector: %w", err)
+		}
+		return vector, nil
+	}
+	if len(data)%4 != 0 {
+		return nil, fmt.Errorf("decode embedding vector: %d bytes", len(data))
+	}
+	vector := make([]float32, len(data)/4)
+	for i := range vector {
+		vector[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[4*i:]))
+	}
+	return vector, nil
+}
+
+// ChunkVectorSource reads the stored full-precision embeddings of chunks, so
+// a quantized vector index can re-rank its top candidates. It implements
+// retrieval.RerankSource.
+type ChunkVectorSource struct {
+	db    DB
+	model string
+}
+
+// NewChunkVectorSource creates a source of the chunk embeddings produced by
+// model, so vectors of another model are never compared with the query. An
+// empty model reads every stored embedding.
+func NewChunkVectorSource(db DB, model string) *ChunkVectorSource {
+	return &ChunkVectorSource{db: db, model: model}
+}
+
+// Vectors returns the stored embeddings of the chunks with the given IDs.
+// Chunks without an embedding of the source's model are left out.
+func (s *ChunkVectorSource) Vectors(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID][]float32, error) {
+	vectors := make(map[uuid.UUID][]float32, len(ids))
+	for start := 0; start < len(ids); start += chunkVectorBatch {
+		end := start + chunkVectorBatch
+		if end > len(ids) {
+			end = len(ids)
+		}
+		if err := s.load(ctx, ids[start:end], vectors); err != nil {
+			return nil, err
+		}
+	}
+	return vectors, nil
+}
+
+func (s *ChunkVectorSource) load(ctx context.Context, ids []uuid.UUID, vectors map[uuid.UUID][]float32) error {
+	args := make([]interface{}, 0, len(ids)+1)
+	placeholders := make([]string, len(ids))
+	for i, id := range ids {
+		args = append(args, id)
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+	}
+	query := `
+		SELECT id, embedding_vector FROM knowledge_chunks
+		WHERE id IN (` + strings.Join(placeholders, ", ") + `) AND embedding_vector IS NOT NULL`
+	if s.model != "" {
+		query += fmt.Sprintf(" AND embedding_model = $%d", len(args)+1)
+		args = append(args, s.model)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("load chunk vectors: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id uuid.UUID
+		var data []byte
+		if err := rows.Scan(&id, &data); err != nil {
+			return fmt.Errorf("load chunk vectors: %w", err)
+		}
+		vector, err := decodeEmbeddingVector(data)
+		if err != nil {
+			return fmt.Errorf("chunk %s: %w", id, err)
+		}
+		if len(vector) > 0 {
+			vectors[id] = vector
+		}
+	}
+	return rows.Err()
+}
+
+// ChunkEmbeddingRepository stores chunk embeddings per embedding version, as
+// written by re-embedding jobs, so a version's vector index can be refilled
+// after a restart. It implements retrieval.EmbeddingStore.
+type ChunkEmbeddingRepository struct {
+	db DB
+}
+
+// NewChunkEmbeddingRepository creates a new chunk embedding repository.
+func NewChunkEmbeddingRepository(db DB) *ChunkEmbeddingRepository {
+	return &ChunkEmbeddingRepository{db: db}
+}
+
+// Save stores the embeddings of chunks for a version, replacing any the
+// version already holds for them.
+func (r *ChunkEmbeddingRepository) Save(ctx context.Context, model, version string, vectors map[uuid.UUID][]float32) error {
+	query := `
+		INSERT INTO chunk_embeddings (chunk_id, embedding_version, embedding_model, embedding_vector)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (embedding_version, chunk_id)
+		DO UPDATE SET embedding_model = excluded.embedding_model, embedding_vector = excluded.embedding_vector
+	`
+	for id, vector := range vectors {
+		encoded := encodeEmbeddingVector(vector)
+		if encoded == nil {
+			continue
+		}
+		if _, err := r.db.ExecContext(ctx, query, id, version, model, encoded); err != nil {
+			return fmt.Errorf("save chunk embedding %s: %w", id, err)
+		}
+	}
+	return nil
+}
+
+// ListVersion retrieves up to limit chunks with an embedding of the version,
+// with IDs after the given one in ID order. Each chunk carries the version's
+// vector, model and label.
+func (r *ChunkEmbeddingRepository) ListVersion(ctx context.Context, version string, after uuid.UUID, limit int) ([]*KnowledgeChunk, error) {
+	query := `
+		SELECT kc.id, kc.tenant_id, kc.product_id, kc.campaign_variant_id, kc.chunk_type,
+			kc.text, kc.metadata, ce.embedding_vector, ce.embedding_model, kc.source_doc_id,
+			kc.source_page, kc.visibility, kc.created_at, kc.updated_at
+		FROM chunk_embeddings ce
+		JOIN knowledge_chunks kc ON kc.id = ce.chunk_id
+		WHERE ce.embedding_version = $1 AND kc.id > $2
+		ORDER BY kc.id
+		LIMIT $3
+	`
+	rows, err := r.db.QueryContext(ctx, query, version, after, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list chunk embeddings: %w", err)
+	}
+	defer rows.Close()
+
+	var chunks []*KnowledgeChunk
+	for rows.Next() {
+		chunk := &KnowledgeChunk{EmbeddingVersion: &version}
+		var data []byte
+		if err := rows.Scan(
+			&chunk.ID, &chunk.TenantID, &chunk.ProductID, &chunk.CampaignVariantID, &chunk.ChunkType,
+			&chunk.Text, &chunk.Metadata, &data, &chunk.EmbeddingModel, &chunk.SourceDocID,
+			&chunk.SourcePage, &chunk.Visibility, textTime{&chunk.CreatedAt}, textTime{&chunk.UpdatedAt},
+		); err != nil {
+			return nil, fmt.Errorf("list chunk embeddings: %w", err)
+		}
+		if chunk.EmbeddingVector, err = decodeEmbeddingVector(data); err != nil {
+			return nil, fmt.Errorf("chunk %s: %w", chunk.ID, err)
+		}
+		chunks = append(chunks, chunk)
+	}
+	return chunks, rows.Err()
+}
@@ -0,0 +1,114 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChunkVectorSource_Vectors(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+	_, err = db.Exec(`CREATE TABLE knowledge_chunks (
+		id TEXT PRIMARY KEY, tenant_id TEXT, product_id TEXT, campaign_variant_id TEXT,
+		chunk_type TEXT, text TEXT, metadata TEXT, embedding_vector BLOB, embedding_model TEXT,
+		embedding_version TEXT, source_doc_id TEXT, source_page INTEGER, visibility TEXT,
+		created_at TIMESTAMP, updated_at TIMESTAMP)`)
+	require.NoError(t, err)
+
+	chunks := NewKnowledgeChunkRepository(db)
+	model, other := "text-embedding-3-small", "local"
+	stored := &KnowledgeChunk{TenantID: uuid.New(), ChunkType: "usp", Text: "heated seats",
+		EmbeddingVector: []float32{0.25, -0.5, 1}, EmbeddingModel: &model}
+	require.NoError(t, chunks.Create(ctx, stored))
+	otherModel := &KnowledgeChunk{TenantID: stored.TenantID, ChunkType: "usp", Text: "sunroof",
+		EmbeddingVector: []float32{1, 0, 0}, EmbeddingModel: &other}
+	require.NoError(t, chunks.Create(ctx, otherModel))
+	unembedded := &KnowledgeChunk{TenantID: stored.TenantID, ChunkType: "usp", Text: "warranty"}
+	require.NoError(t, chunks.Create(ctx, unembedded))
+
+	// Older loaders wrote JSON bytes or little-endian float32s
+	jsonID, binaryID := uuid.New(), uuid.New()
+	_, err = db.Exec(`INSERT INTO knowledge_chunks (id, embedding_vector, embedding_model) VALUES ($1, $2, $3)`,
+		jsonID, []byte("[0.5,0.5]"), model)
+	require.NoError(t, err)
+	packed := make([]byte, 8)
+	binary.LittleEndian.PutUint32(packed, math.Float32bits(2))
+	binary.LittleEndian.PutUint32(packed[4:], math.Float32bits(-3))
+	_, err = db.Exec(`INSERT INTO knowledge_chunks (id, embedding_vector, embedding_model) VALUES ($1, $2, $3)`,
+		binaryID, packed, model)
+	require.NoError(t, err)
+
+	ids := []uuid.UUID{stored.ID, otherModel.ID, unembedded.ID, jsonID, binaryID, uuid.New()}
+	vectors, err := NewChunkVectorSource(db, model).Vectors(ctx, ids)
+	require.NoError(t, err)
+	assert.Equal(t, map[uuid.UUID][]float32{
+		stored.ID: {0.25, -0.5, 1},
+		jsonID:    {0.5, 0.5},
+		binaryID:  {2, -3},
+	}, vectors, "embeddings of another model are left out")
+
+	all, err := NewChunkVectorSource(db, "").Vectors(ctx, ids)
+	require.NoError(t, err)
+	assert.Len(t, all, 4)
+	assert.Equal(t, []float32{1, 0, 0}, all[otherModel.ID])
+}
+
+func TestChunkEmbeddingRepository(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+	_, err = db.Exec(`CREATE TABLE knowledge_chunks (
+		id TEXT PRIMARY KEY, tenant_id TEXT, product_id TEXT, campaign_variant_id TEXT,
+		chunk_type TEXT, text TEXT, metadata TEXT, embedding_vector BLOB, embedding_model TEXT,
+		embedding_version TEXT, source_doc_id TEXT, source_page INTEGER, visibility TEXT,
+		created_at TIMESTAMP, updated_at TIMESTAMP)`)
+	require.NoError(t, err)
+	migration, err := os.ReadFile(filepath.Join("..", "..", "db", "migrations", "0007_chunk_embeddings_sqlite.sql"))
+	require.NoError(t, err)
+	_, err = db.Exec(string(migration))
+	require.NoError(t, err)
+
+	chunks := NewKnowledgeChunkRepository(db)
+	first := &KnowledgeChunk{TenantID: uuid.New(), ChunkType: "usp", Text: "heated seats", Metadata: []byte(`{}`)}
+	second := &KnowledgeChunk{TenantID: first.TenantID, ChunkType: "usp", Text: "sunroof", Metadata: []byte(`{}`)}
+	require.NoError(t, chunks.Create(ctx, first))
+	require.NoError(t, chunks.Create(ctx, second))
+
+	repo := NewChunkEmbeddingRepository(db)
+	require.NoError(t, repo.Save(ctx, "large-v2", "v2", map[uuid.UUID][]float32{first.ID: {1, 0}, second.ID: {0, 1}}))
+	require.NoError(t, repo.Save(ctx, "large-v2", "v2", map[uuid.UUID][]float32{first.ID: {0.5, 0.5}}))
+	require.NoError(t, repo.Save(ctx, "large-v3", "v3", map[uuid.UUID][]float32{first.ID: {0, 0, 1}}))
+
+	var listed []*KnowledgeChunk
+	after := uuid.Nil
+	for {
+		page, err := repo.ListVersion(ctx, "v2", after, 1)
+		require.NoError(t, err)
+		if len(page) == 0 {
+			break
+		}
+		listed = append(listed, page...)
+		after = page[len(page)-1].ID
+	}
+	require.Len(t, listed, 2, "each chunk is listed once for the version")
+	vectors := make(map[uuid.UUID][]float32)
+	for _, chunk := range listed {
+		vectors[chunk.ID] = chunk.EmbeddingVector
+		assert.Equal(t, "large-v2", *chunk.EmbeddingModel)
+		assert.Equal(t, "v2", *chunk.EmbeddingVersion)
+	}
+	assert.Equal(t, map[uuid.UUID][]float32{first.ID: {0.5, 0.5}, second.ID: {0, 1}}, vectors, "a saved vector replaces the version's previous one")
+}
@@ -0,0 +1,174 @@
+// Package storage provides persistence for materialized product comparisons.
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ComparisonRepository persists materialized comparison rows. It implements
+// comparison.ComparisonStore.
+type ComparisonRepository struct {
+	db DB
+}
+
+// NewComparisonRepository creates a new comparison repository.
+func NewComparisonRepository(db DB) *ComparisonRepository {
+	return &ComparisonRepository{db: db}
+}
+
+// pairCondition selects the rows of a product pair in either orientation, with
+// its arguments numbered from $first.
+func pairCondition(first int, a, b uuid.UUID) (string, []interface{}) {
+	return fmt.Sprintf(`((primary_product_id = $%d AND secondary_product_id = $%d)
+			OR (primary_product_id = $%d AND secondary_product_id = $%d))`, first, first+1, first+2, first+3),
+		[]interface{}{a, b, b, a}
+}
+
+// GetComparison returns the rows materialized for a product pair in either
+// orientation, provided one of the products belongs to the tenant.
+func (r *ComparisonRepository) GetComparison(ctx context.Context, tenantID, primaryID, secondaryID uuid.UUID) ([]ComparisonRow, error) {
+	pair, args := pairCondition(1, primaryID, secondaryID)
+	query := fmt.Sprintf(`
+		SELECT id, primary_product_id, secondary_product_id, dimension,
+			primary_value, secondary_value, verdict, narrative, shareability,
+			source_primary_spec_id, source_secondary_spec_id, computed_at
+		FROM comparison_rows cr
+		WHERE %s
+			AND EXISTS (
+				SELECT 1 FROM products p
+				WHERE p.id IN (cr.primary_product_id, cr.secondary_product_id) AND p.tenant_id = $%d
+			)
+		ORDER BY dimension
+	`, pair, len(args)+1)
+	rows, err := r.db.QueryContext(ctx, query, append(args, tenantID)...)
+	if err != nil {
+		return nil, fmt.Errorf("get comparison: %w", err)
+	}
+	defer rows.Close()
+
+	var comparisons []ComparisonRow
+	for rows.Next() {
+		var cr ComparisonRow
+		if err := rows.Scan(
+			&cr.ID, &cr.PrimaryProductID, &cr.SecondaryProductID, &cr.Dimension,
+			&cr.PrimaryValue, &cr.SecondaryValue, &cr.Verdict, &cr.Narrative, &cr.Shareability,
+			&cr.SourcePrimarySpecID, &cr.SourceSecondarySpecID, textTime{&cr.ComputedAt},
+		); err != nil {
+			return nil, fmt.Errorf("get comparison: %w", err)
+		}
+		comparisons = append(comparisons, cr)
+	}
+	return comparisons, rows.Err()
+}
+
+// SaveComparison replaces the rows of a product pair, in either orientation,
+// with rows, which must all be of the same pair.
+func (r *ComparisonRepository) SaveComparison(ctx context.Context, rows []ComparisonRow) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	pair, args := pairCondition(1, rows[0].PrimaryProductID, rows[0].SecondaryProductID)
+	if _, err := r.db.ExecContext(ctx, "DELETE FROM comparison_rows WHERE "+pair, args...); err != nil {
+		return fmt.Errorf("replace comparison: %w", err)
+	}
+
+	query := `
+		INSERT INTO comparison_rows (id, primary_product_id, secondary_product_id, dimension,
+			primary_value, secondary_value, verdict, narrative, shareability,
+			source_primary_spec_id, source_secondary_spec_id, computed_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+	`
+	for i := range rows {
+		row := &rows[i]
+		if row.ID == uuid.Nil {
+			row.ID = uuid.New()
+		}
+		_, err := r.db.ExecContext(ctx, query,
+			row.ID, row.PrimaryProductID, row.SecondaryProductID, row.Dimension,
+			row.PrimaryValue, row.SecondaryValue, row.Verdict, row.Narrative, row.Shareability,
+			row.SourcePrimarySpecID, row.SourceSecondarySpecID, row.ComputedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("save comparison row %s: %w", row.Dimension, err)
+		}
+	}
+	return nil
+}
+
+// DeleteComparisons removes the rows of every pair including a product, or
+// when productID is nil, any of the tenant's products.
+func (r *ComparisonRepository) DeleteComparisons(ctx context.Context, tenantID uuid.UUID, productID *uuid.UUID) error {
+	query := `
+		DELETE FROM comparison_rows
+		WHERE primary_product_id IN (SELECT id FROM products WHERE tenant_id = $1)
+			OR secondary_product_id IN (SELECT id FROM products WHERE tenant_id = $2)
+	`
+	args := []interface{}{tenantID, tenantID}
+	if productID != nil {
+		query = `DELETE FROM comparison_rows WHERE primary_product_id = $1 OR secondary_product_id = $2`
+		args = []interface{}{*productID, *productID}
+	}
+	if _, err := r.db.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("delete comparisons: %w", err)
+	}
+	return nil
+}
+
+// textTimeLayouts are the layouts of timestamps SQLite keeps in TEXT columns:
+// those written by the sqlite3 driver and by datetime('now').
+var textTimeLayouts = []string{
+	"2006-01-02 15:04:05.999999999-07:00",
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05",
+}
+
+// textTime scans a timestamp column that SQLite returns as text, such as
+// comparison_rows.computed_at, as well as a native timestamp.
+type textTime struct {
+	t *time.Time
+}
+
+func (s textTime) Scan(src interface{}) error {
+	var text string
+	switch v := src.(type) {
+	case time.Time:
+		*s.t = v
+		return nil
+	case string:
+		text = v
+	case []byte:
+		text = string(v)
+	default:
+		return fmt.Errorf("scan timestamp: unsupported type %T", src)
+	}
+	for _, layout := range textTimeLayouts {
+		if t, err := time.Parse(layout, text); err == nil {
+			*s.t = t
+			return nil
+		}
+	}
+	return fmt.Errorf("scan timestamp: unknown format %q", text)
+}
+
+// nullTextTime scans a nullable timestamp column like textTime, leaving the
+// target nil for NULL, such as campaign_variants.effective_through.
+type nullTextTime struct {
+	t **time.Time
+}
+
+func (s nullTextTime) Scan(src interface{}) error {
+	if src == nil {
+		*s.t = nil
+		return nil
+	}
+	var t time.Time
+	if err := (textTime{&t}).Scan(src); err != nil {
+		return err
+	}
+	*s.t = &t
+	return nil
+}
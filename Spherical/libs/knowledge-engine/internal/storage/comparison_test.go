@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComparisonRepository(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+	_, err = db.Exec(`
+		CREATE TABLE products (id TEXT PRIMARY KEY, tenant_id TEXT NOT NULL);
+		CREATE TABLE comparison_rows (
+			id TEXT PRIMARY KEY, primary_product_id TEXT NOT NULL, secondary_product_id TEXT NOT NULL,
+			dimension TEXT NOT NULL, primary_value TEXT, secondary_value TEXT, verdict TEXT NOT NULL,
+			narrative TEXT, shareability TEXT NOT NULL, source_primary_spec_id TEXT,
+			source_secondary_spec_id TEXT, computed_at TEXT NOT NULL)`)
+	require.NoError(t, err)
+
+	tenant, other := uuid.New(), uuid.New()
+	camry, corolla, accord := uuid.New(), uuid.New(), uuid.New()
+	for product, owner := range map[uuid.UUID]uuid.UUID{camry: tenant, corolla: tenant, accord: other} {
+		_, err := db.Exec(`INSERT INTO products (id, tenant_id) VALUES ($1, $2)`, product, owner)
+		require.NoError(t, err)
+	}
+
+	value := func(s string) *string { return &s }
+	row := func(primary, secondary uuid.UUID, dimension string, verdict Verdict) ComparisonRow {
+		return ComparisonRow{PrimaryProductID: primary, SecondaryProductID: secondary, Dimension: dimension,
+			PrimaryValue: value("225 hp"), SecondaryValue: value("169 hp"), Verdict: verdict,
+			Shareability: ComparisonTenantOnly, ComputedAt: time.Now()}
+	}
+	repo := NewComparisonRepository(db)
+	require.NoError(t, repo.SaveComparison(ctx, []ComparisonRow{
+		row(camry, corolla, "Power", VerdictPrimaryBetter),
+		row(camry, corolla, "Price", VerdictSecondaryBetter),
+	}))
+	require.NoError(t, repo.SaveComparison(ctx, []ComparisonRow{row(camry, accord, "Power", VerdictPrimaryBetter)}))
+
+	rows, err := repo.GetComparison(ctx, tenant, corolla, camry)
+	require.NoError(t, err)
+	require.Len(t, rows, 2, "pairs are found in either orientation")
+	assert.Equal(t, "Power", rows[0].Dimension)
+	assert.Equal(t, camry, rows[0].PrimaryProductID)
+	assert.Equal(t, "225 hp", *rows[0].PrimaryValue)
+
+	assert.WithinDuration(t, time.Now(), rows[0].ComputedAt, time.Minute)
+
+	_, err = db.Exec(`UPDATE comparison_rows SET computed_at = datetime('now') WHERE dimension = 'Price'`)
+	require.NoError(t, err)
+	rows, err = repo.GetComparison(ctx, tenant, camry, corolla)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), rows[1].ComputedAt, time.Minute, "timestamps written by SQLite are read")
+
+	rows, err = repo.GetComparison(ctx, uuid.New(), camry, corolla)
+	require.NoError(t, err)
+	assert.Empty(t, rows, "other tenants do not see the pair")
+
+	// Saving the reverse pair replaces its rows
+	require.NoError(t, repo.SaveComparison(ctx, []ComparisonRow{row(corolla, camry, "Power", VerdictSecondaryBetter)}))
+	rows, err = repo.GetComparison(ctx, tenant, camry, corolla)
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	assert.Equal(t, corolla, rows[0].PrimaryProductID)
+
+	require.NoError(t, repo.DeleteComparisons(ctx, tenant, &corolla))
+	rows, err = repo.GetComparison(ctx, tenant, camry, corolla)
+	require.NoError(t, err)
+	assert.Empty(t, rows)
+	rows, err = repo.GetComparison(ctx, tenant, camry, accord)
+	require.NoError(t, err)
+	assert.Len(t, rows, 1, "pairs without the product are kept")
+
+	require.NoError(t, repo.DeleteComparisons(ctx, tenant, nil))
+	rows, err = repo.GetComparison(ctx, other, accord, camry)
+	require.NoError(t, err)
+	assert.Empty(t, rows)
+}
@@ -0,0 +1,123 @@
+// Package storage provides persistence for activated embedding indexes.
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EmbeddingIndexActivation records that vector search for a tenant, or one
+// campaign, was switched to the index built with an embedding model and
+// version. The latest activation of a scope is its active index; earlier ones
+// are kept so a switch can be rolled back.
+type EmbeddingIndexActivation struct {
+	ID                uuid.UUID  `json:"id" db:"id"`
+	TenantID          uuid.UUID  `json:"tenant_id" db:"tenant_id"`
+	CampaignVariantID *uuid.UUID `json:"campaign_variant_id,omitempty" db:"campaign_variant_id"`
+	EmbeddingModel    string     `json:"embedding_model" db:"embedding_model"`
+	EmbeddingVersion  string     `json:"embedding_version" db:"embedding_version"`
+	ActivatedAt       time.Time  `json:"activated_at" db:"activated_at"`
+}
+
+// EmbeddingIndexRepository persists embedding index activations.
+type EmbeddingIndexRepository struct {
+	db DB
+}
+
+// NewEmbeddingIndexRepository creates a new embedding index repository.
+func NewEmbeddingIndexRepository(db DB) *EmbeddingIndexRepository {
+	return &EmbeddingIndexRepository{db: db}
+}
+
+// activationScope returns the condition selecting the activations of a
+// tenant, or of one campaign, with its arguments numbered from $first. Unlike
+// chunkScope, a nil campaign selects only the tenant-wide activations.
+func activationScope(first int, tenantID uuid.UUID, campaignID *uuid.UUID) (string, []interface{}) {
+	if campaignID == nil {
+		return fmt.Sprintf("tenant_id = $%d AND campaign_variant_id IS NULL", first), []interface{}{tenantID}
+	}
+	return fmt.Sprintf("tenant_id = $%d AND campaign_variant_id = $%d", first, first+1), []interface{}{tenantID, *campaignID}
+}
+
+// Create records an activation, making it the scope's active index.
+func (r *EmbeddingIndexRepository) Create(ctx context.Context, activation *EmbeddingIndexActivation) error {
+	if activation.ID == uuid.Nil {
+		activation.ID = uuid.New()
+	}
+	if activation.ActivatedAt.IsZero() {
+		activation.ActivatedAt = time.Now()
+	}
+
+	query := `
+		INSERT INTO embedding_index_activations (id, tenant_id, campaign_variant_id,
+			embedding_model, embedding_version, activated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		activation.ID, activation.TenantID, activation.CampaignVariantID,
+		activation.EmbeddingModel, activation.EmbeddingVersion, activation.ActivatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("create embedding index activation: %w", err)
+	}
+	return nil
+}
+
+// DeleteLatest removes a scope's latest activation, so the one before it is
+// active again.
+func (r *EmbeddingIndexRepository) DeleteLatest(ctx context.Context, tenantID uuid.UUID, campaignID *uuid.UUID) error {
+	scope, args := activationScope(1, tenantID, campaignID)
+	query := `
+		DELETE FROM embedding_index_activations WHERE id = (
+			SELECT id FROM embedding_index_activations
+			WHERE ` + scope + `
+			ORDER BY activated_at DESC
+			LIMIT 1
+		)
+	`
+	if _, err := r.db.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("delete embedding index activation: %w", err)
+	}
+	return nil
+}
+
+// List returns a scope's activations, oldest first.
+func (r *EmbeddingIndexRepository) List(ctx context.Context, tenantID uuid.UUID, campaignID *uuid.UUID) ([]*EmbeddingIndexActivation, error) {
+	scope, args := activationScope(1, tenantID, campaignID)
+	return r.list(ctx, "WHERE "+scope, args...)
+}
+
+// ListAll returns the activations of every scope, oldest first.
+func (r *EmbeddingIndexRepository) ListAll(ctx context.Context) ([]*EmbeddingIndexActivation, error) {
+	return r.list(ctx, "")
+}
+
+func (r *EmbeddingIndexRepository) list(ctx context.Context, where string, args ...interface{}) ([]*EmbeddingIndexActivation, error) {
+	query := `
+		SELECT id, tenant_id, campaign_variant_id, embedding_model, embedding_version, activated_at
+		FROM embedding_index_activations
+		` + where + `
+		ORDER BY activated_at
+	`
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list embedding index activations: %w", err)
+	}
+	defer rows.Close()
+
+	var activations []*EmbeddingIndexActivation
+	for rows.Next() {
+		activation := &EmbeddingIndexActivation{}
+		if err := rows.Scan(
+			&activation.ID, &activation.TenantID, &activation.CampaignVariantID,
+			&activation.EmbeddingModel, &activation.EmbeddingVersion, &activation.ActivatedAt,
+		); err != nil {
+			return nil, err
+		}
+		activations = append(activations, activation)
+	}
+	return activations, rows.Err()
+}
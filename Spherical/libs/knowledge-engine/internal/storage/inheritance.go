@@ -0,0 +1,326 @@
+// Package storage provides campaign inheritance resolution.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// MaxInheritanceDepth bounds the number of variants in an inheritance chain.
+const MaxInheritanceDepth = 8
+
+// Inheritance errors.
+var (
+	ErrInheritanceCycle    = errors.New("campaign inheritance cycle")
+	ErrInheritanceTooDeep  = errors.New("campaign inheritance chain too deep")
+	ErrInheritanceMismatch = errors.New("base campaign belongs to a different product")
+)
+
+// GetInheritanceChain returns the campaign followed by its ancestors, nearest first.
+func (r *CampaignRepository) GetInheritanceChain(ctx context.Context, tenantID, campaignID uuid.UUID) ([]*CampaignVariant, error) {
+	var chain []*CampaignVariant
+	seen := make(map[uuid.UUID]bool)
+
+	id := campaignID
+	for {
+		if seen[id] {
+			return nil, fmt.Errorf("%w: %s", ErrInheritanceCycle, id)
+		}
+		if len(chain) >= MaxInheritanceDepth {
+			return nil, ErrInheritanceTooDeep
+		}
+		seen[id] = true
+
+		campaign, err := r.GetByID(ctx, tenantID, id)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, campaign)
+
+		if campaign.BaseCampaignVariantID == nil {
+			return chain, nil
+		}
+		id = *campaign.BaseCampaignVariantID
+	}
+}
+
+// SetBase points a campaign at a base variant, or detaches it when baseID is nil.
+func (r *CampaignRepository) SetBase(ctx context.Context, tenantID, campaignID uuid.UUID, baseID *uuid.UUID) error {
+	campaign, err := r.GetByID(ctx, tenantID, campaignID)
+	if err != nil {
+		return err
+	}
+
+	if baseID != nil {
+		baseChain, err := r.GetInheritanceChain(ctx, tenantID, *baseID)
+		if err != nil {
+			return fmt.Errorf("resolve base chain: %w", err)
+		}
+		if baseChain[0].ProductID != campaign.ProductID {
+			return ErrInheritanceMismatch
+		}
+		for _, ancestor := range baseChain {
+			if ancestor.ID == campaignID {
+				return fmt.Errorf("%w: %s", ErrInheritanceCycle, campaignID)
+			}
+		}
+		if len(baseChain)+1 > MaxInheritanceDepth {
+			return ErrInheritanceTooDeep
+		}
+	}
+
+	campaign.BaseCampaignVariantID = baseID
+	return r.Update(ctx, campaign)
+}
+
+// EffectiveSpecValue is a spec value resolved through an inheritance chain.
+type EffectiveSpecValue struct {
+	Value  *SpecValue
+	Origin ValueOrigin
+	// OverriddenValueID is the ancestor value replaced by a local override.
+	OverriddenValueID *uuid.UUID
+}
+
+// EffectiveFeatureBlock is a feature block resolved through an inheritance chain.
+type EffectiveFeatureBlock struct {
+	Block  *FeatureBlock
+	Origin ValueOrigin
+	// OverriddenBlockID is the ancestor block replaced by a local override.
+	OverriddenBlockID *uuid.UUID
+}
+
+// ResolveSpecValues merges spec values along a chain (nearest first), keeping the
+// nearest value for each spec item.
+func ResolveSpecValues(chain []uuid.UUID, values []*SpecValue) []EffectiveSpecValue {
+	rank := chainRank(chain)
+
+	byItem := make(map[uuid.UUID][]*SpecValue)
+	var order []uuid.UUID
+	for _, v := range values {
+		if _, ok := rank[v.CampaignVariantID]; !ok {
+			continue
+		}
+		if _, ok := byItem[v.SpecItemID]; !ok {
+			order = append(order, v.SpecItemID)
+		}
+		byItem[v.SpecItemID] = append(byItem[v.SpecItemID], v)
+	}
+
+	effective := make([]EffectiveSpecValue, 0, len(order))
+	for _, itemID := range order {
+		candidates := byItem[itemID]
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return rank[candidates[i].CampaignVariantID] < rank[candidates[j].CampaignVariantID]
+		})
+
+		winner := candidates[0]
+		ev := EffectiveSpecValue{Value: winner, Origin: ValueOriginLocal}
+		if rank[winner.CampaignVariantID] > 0 {
+			ev.Origin = ValueOriginInherited
+		} else if len(candidates) > 1 {
+			ev.Origin = ValueOriginOverridden
+			overridden := candidates[1].ID
+			ev.OverriddenValueID = &overridden
+		}
+		effective = append(effective, ev)
+	}
+	return effective
+}
+
+// ResolveFeatureBlocks merges feature blocks along a chain (nearest first). Blocks
+// replaced via OverridesBlockID are dropped; all others are kept.
+func ResolveFeatureBlocks(chain []uuid.UUID, blocks []*FeatureBlock) []EffectiveFeatureBlock {
+	rank := chainRank(chain)
+
+	replaced := make(map[uuid.UUID]bool)
+	for _, b := range blocks {
+		if b.OverridesBlockID != nil {
+			replaced[*b.OverridesBlockID] = true
+		}
+	}
+
+	var effective []EffectiveFeatureBlock
+	for _, b := range blocks {
+		r, ok := rank[b.CampaignVariantID]
+		if !ok || replaced[b.ID] {
+			continue
+		}
+		eb := EffectiveFeatureBlock{Block: b, Origin: ValueOriginLocal}
+		switch {
+		case r > 0:
+			eb.Origin = ValueOriginInherited
+		case b.OverridesBlockID != nil:
+			eb.Origin = ValueOriginOverridden
+			eb.OverriddenBlockID = b.OverridesBlockID
+		}
+		effective = append(effective, eb)
+	}
+
+	sort.SliceStable(effective, func(i, j int) bool {
+		return effective[i].Block.Priority < effective[j].Block.Priority
+	})
+	return effective
+}
+
+// chainRank maps campaign IDs to their distance from the head of the chain.
+func chainRank(chain []uuid.UUID) map[uuid.UUID]int {
+	rank := make(map[uuid.UUID]int, len(chain))
+	for i, id := range chain {
+		if _, ok := rank[id]; !ok {
+			rank[id] = i
+		}
+	}
+	return rank
+}
+
+// InheritanceResolver resolves effective campaign content through base variants.
+type InheritanceResolver struct {
+	campaigns *CampaignRepository
+	specs     *SpecValueRepository
+	blocks    *FeatureBlockRepository
+}
+
+// NewInheritanceResolver creates a new inheritance resolver.
+func NewInheritanceResolver(db DB) *InheritanceResolver {
+	return &InheritanceResolver{
+		campaigns: NewCampaignRepository(db),
+		specs:     NewSpecValueRepository(db),
+		blocks:    NewFeatureBlockRepository(db),
+	}
+}
+
+// Chain returns the IDs of a campaign and its ancestors, nearest first.
+func (r *InheritanceResolver) Chain(ctx context.Context, tenantID, campaignID uuid.UUID) ([]uuid.UUID, error) {
+	chain, err := r.campaigns.GetInheritanceChain(ctx, tenantID, campaignID)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uuid.UUID, len(chain))
+	for i, c := range chain {
+		ids[i] = c.ID
+	}
+	return ids, nil
+}
+
+// EffectiveSpecValues returns the spec values a campaign exposes after inheritance.
+func (r *InheritanceResolver) EffectiveSpecValues(ctx context.Context, tenantID, campaignID uuid.UUID) ([]EffectiveSpecValue, error) {
+	chain, err := r.Chain(ctx, tenantID, campaignID)
+	if err != nil {
+		return nil, err
+	}
+
+	var values []*SpecValue
+	for _, id := range chain {
+		specs, err := r.specs.GetByCampaign(ctx, tenantID, id)
+		if err != nil {
+			return nil, fmt.Errorf("load spec values for %s: %w", id, err)
+		}
+		values = append(values, specs...)
+	}
+	return ResolveSpecValues(chain, values), nil
+}
+
+// EffectiveFeatureBlocks returns the feature blocks a campaign exposes after inheritance.
+func (r *InheritanceResolver) EffectiveFeatureBlocks(ctx context.Context, tenantID, campaignID uuid.UUID, blockType *BlockType) ([]EffectiveFeatureBlock, error) {
+	chain, err := r.Chain(ctx, tenantID, campaignID)
+	if err != nil {
+		return nil, err
+	}
+
+	var blocks []*FeatureBlock
+	for _, id := range chain {
+		fbs, err := r.blocks.GetByCampaign(ctx, tenantID, id, blockType)
+		if err != nil {
+			return nil, fmt.Errorf("load feature blocks for %s: %w", id, err)
+		}
+		blocks = append(blocks, fbs...)
+	}
+	return ResolveFeatureBlocks(chain, blocks), nil
+}
+
+// CampaignChain returns the IDs of a campaign and its ancestors, nearest first.
+func (r *SpecViewRepository) CampaignChain(ctx context.Context, tenantID, campaignID uuid.UUID) ([]uuid.UUID, error) {
+	return NewInheritanceResolver(r.db).Chain(ctx, tenantID, campaignID)
+}
+
+// EffectiveOwner identifies the campaign supplying a spec item's effective value.
+type EffectiveOwner struct {
+	CampaignVariantID uuid.UUID
+	Origin            ValueOrigin
+}
+
+// EffectiveOwners maps each spec item visible through the chain to the campaign
+// that supplies its effective value.
+func (r *SpecViewRepository) EffectiveOwners(ctx context.Context, tenantID uuid.UUID, chain []uuid.UUID) (map[uuid.UUID]EffectiveOwner, error) {
+	if len(chain) == 0 {
+		return make(map[uuid.UUID]EffectiveOwner), nil
+	}
+
+	placeholders := make([]string, len(chain))
+	args := []interface{}{tenantID}
+	for i, id := range chain {
+		placeholders[i] = "$" + strconv.Itoa(i+2)
+		args = append(args, id)
+	}
+
+	query := `
+		SELECT sv.spec_item_id, sv.campaign_variant_id
+		FROM spec_view_latest sv
+		WHERE sv.tenant_id = $1 AND sv.campaign_variant_id IN (` + strings.Join(placeholders, ", ") + `)
+	`
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var specs []SpecViewLatest
+	for rows.Next() {
+		var sv SpecViewLatest
+		if err := rows.Scan(&sv.SpecItemID, &sv.CampaignVariantID); err != nil {
+			return nil, err
+		}
+		specs = append(specs, sv)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return ResolveEffectiveOwners(chain, specs), nil
+}
+
+// ResolveEffectiveOwners maps each spec item of the rows to the campaign nearest
+// the head of the chain (nearest first) that has a value for it.
+func ResolveEffectiveOwners(chain []uuid.UUID, specs []SpecViewLatest) map[uuid.UUID]EffectiveOwner {
+	owners := make(map[uuid.UUID]EffectiveOwner)
+	rank := chainRank(chain)
+	seen := make(map[uuid.UUID]int)
+	for _, sv := range specs {
+		itemID, campaignID := sv.SpecItemID, sv.CampaignVariantID
+		if _, ok := rank[campaignID]; !ok {
+			continue
+		}
+		seen[itemID]++
+		if current, ok := owners[itemID]; !ok || rank[campaignID] < rank[current.CampaignVariantID] {
+			owners[itemID] = EffectiveOwner{CampaignVariantID: campaignID}
+		}
+	}
+
+	for itemID, owner := range owners {
+		switch {
+		case rank[owner.CampaignVariantID] > 0:
+			owner.Origin = ValueOriginInherited
+		case seen[itemID] > 1:
+			owner.Origin = ValueOriginOverridden
+		default:
+			owner.Origin = ValueOriginLocal
+		}
+		owners[itemID] = owner
+	}
+	return owners
+}
@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveSpecValues(t *testing.T) {
+	child, base, root := uuid.New(), uuid.New(), uuid.New()
+	chain := []uuid.UUID{child, base, root}
+
+	power, torque, colour, wheels := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	rootPower := &SpecValue{ID: uuid.New(), CampaignVariantID: root, SpecItemID: power}
+	basePower := &SpecValue{ID: uuid.New(), CampaignVariantID: base, SpecItemID: power}
+	childPower := &SpecValue{ID: uuid.New(), CampaignVariantID: child, SpecItemID: power}
+	rootTorque := &SpecValue{ID: uuid.New(), CampaignVariantID: root, SpecItemID: torque}
+	childColour := &SpecValue{ID: uuid.New(), CampaignVariantID: child, SpecItemID: colour}
+	otherWheels := &SpecValue{ID: uuid.New(), CampaignVariantID: uuid.New(), SpecItemID: wheels}
+
+	effective := ResolveSpecValues(chain, []*SpecValue{rootPower, basePower, childPower, rootTorque, childColour, otherWheels})
+	require.Len(t, effective, 3)
+
+	byItem := make(map[uuid.UUID]EffectiveSpecValue)
+	for _, ev := range effective {
+		byItem[ev.Value.SpecItemID] = ev
+	}
+
+	assert.Equal(t, childPower.ID, byItem[power].Value.ID)
+	assert.Equal(t, ValueOriginOverridden, byItem[power].Origin)
+	require.NotNil(t, byItem[power].OverriddenValueID)
+	assert.Equal(t, basePower.ID, *byItem[power].OverriddenValueID)
+
+	assert.Equal(t, rootTorque.ID, byItem[torque].Value.ID)
+	assert.Equal(t, ValueOriginInherited, byItem[torque].Origin)
+
+	assert.Equal(t, ValueOriginLocal, byItem[colour].Origin)
+	assert.NotContains(t, byItem, wheels)
+}
+
+func TestResolveFeatureBlocks(t *testing.T) {
+	child, base := uuid.New(), uuid.New()
+	chain := []uuid.UUID{child, base}
+
+	kept := &FeatureBlock{ID: uuid.New(), CampaignVariantID: base, Priority: 1}
+	replaced := &FeatureBlock{ID: uuid.New(), CampaignVariantID: base, Priority: 2}
+	override := &FeatureBlock{ID: uuid.New(), CampaignVariantID: child, Priority: 2, OverridesBlockID: &replaced.ID}
+	added := &FeatureBlock{ID: uuid.New(), CampaignVariantID: child, Priority: 3}
+
+	effective := ResolveFeatureBlocks(chain, []*FeatureBlock{override, added, kept, replaced})
+	require.Len(t, effective, 3)
+
+	assert.Equal(t, kept.ID, effective[0].Block.ID)
+	assert.Equal(t, ValueOriginInherited, effective[0].Origin)
+	assert.Equal(t, override.ID, effective[1].Block.ID)
+	assert.Equal(t, ValueOriginOverridden, effective[1].Origin)
+	assert.Equal(t, replaced.ID, *effective[1].OverriddenBlockID)
+	assert.Equal(t, added.ID, effective[2].Block.ID)
+	assert.Equal(t, ValueOriginLocal, effective[2].Origin)
+}
@@ -104,6 +104,15 @@ const (
 	LineageActionReconciled LineageAction = "reconciled"
 )
 
+// ValueOrigin describes where an effective campaign value comes from.
+type ValueOrigin string
+
+const (
+	ValueOriginLocal      ValueOrigin = "local"
+	ValueOriginInherited  ValueOrigin = "inherited"
+	ValueOriginOverridden ValueOrigin = "overridden"
+)
+
 // AlertType represents drift alert types.
 type AlertType string
 
@@ -162,8 +171,10 @@ type CampaignVariant struct {
 	EffectiveThrough *time.Time     `json:"effective_through,omitempty" db:"effective_through"`
 	IsDraft          bool           `json:"is_draft" db:"is_draft"`
 	LastPublishedBy  *string        `json:"last_published_by,omitempty" db:"last_published_by"`
-	CreatedAt        time.Time      `json:"created_at" db:"created_at"`
-	UpdatedAt        time.Time      `json:"updated_at" db:"updated_at"`
+	// BaseCampaignVariantID is the variant this one inherits spec values and feature blocks from.
+	BaseCampaignVariantID *uuid.UUID `json:"base_campaign_variant_id,omitempty" db:"base_campaign_variant_id"`
+	CreatedAt             time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt             time.Time  `json:"updated_at" db:"updated_at"`
 }
 
 // DocumentSource represents an ingested brochure or document.
@@ -236,8 +247,10 @@ type FeatureBlock struct {
 	SourcePage        *int         `json:"source_page,omitempty" db:"source_page"`
 	EmbeddingVector   []float32    `json:"embedding_vector,omitempty" db:"embedding_vector"`
 	EmbeddingVersion  *string      `json:"embedding_version,omitempty" db:"embedding_version"`
-	CreatedAt         time.Time    `json:"created_at" db:"created_at"`
-	UpdatedAt         time.Time    `json:"updated_at" db:"updated_at"`
+	// OverridesBlockID is the inherited base block this block replaces.
+	OverridesBlockID *uuid.UUID `json:"overrides_block_id,omitempty" db:"overrides_block_id"`
+	CreatedAt        time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at" db:"updated_at"`
 }
 
 // KnowledgeChunk represents a vectorized text chunk for semantic retrieval.
@@ -259,6 +272,13 @@ type KnowledgeChunk struct {
 	UpdatedAt         time.Time       `json:"updated_at" db:"updated_at"`
 }
 
+// Metadata keys of chunks built from feature blocks.
+const (
+	ChunkMetaBlockType      = "block_type"
+	ChunkMetaBlockID        = "block_id"
+	ChunkMetaOverridesBlock = "overrides_block_id"
+)
+
 // ComparisonRow represents a pre-computed comparison between products.
 type ComparisonRow struct {
 	ID                    uuid.UUID    `json:"id" db:"id"`
@@ -337,5 +357,6 @@ type SpecViewLatest struct {
 	Trim              *string   `json:"trim,omitempty" db:"trim"`
 	Market            *string   `json:"market,omitempty" db:"market"`
 	ProductName       string    `json:"product_name" db:"product_name"`
+	ValueNumeric      *float64  `json:"value_numeric,omitempty" db:"value_numeric"`
 }
 
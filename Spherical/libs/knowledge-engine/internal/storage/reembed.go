@@ -0,0 +1,144 @@
+// Package storage provides persistence for re-embedding jobs.
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ReembedStatus represents the state of a re-embedding job.
+type ReembedStatus string
+
+const (
+	ReembedStatusPending    ReembedStatus = "pending"
+	ReembedStatusRunning    ReembedStatus = "running"
+	ReembedStatusPaused     ReembedStatus = "paused"
+	ReembedStatusFailed     ReembedStatus = "failed"
+	ReembedStatusCompleted  ReembedStatus = "completed"
+	ReembedStatusRolledBack ReembedStatus = "rolled_back"
+)
+
+// ReembedJob tracks the migration of a tenant's chunks, or one campaign's, to
+// a new embedding model. Chunks are re-embedded in ID order, so LastChunkID
+// is where an interrupted job resumes. CancelRequested asks the process
+// running the job to pause it.
+type ReembedJob struct {
+	ID                       uuid.UUID     `json:"id" db:"id"`
+	TenantID                 uuid.UUID     `json:"tenant_id" db:"tenant_id"`
+	CampaignVariantID        *uuid.UUID    `json:"campaign_variant_id,omitempty" db:"campaign_variant_id"`
+	EmbeddingModel           string        `json:"embedding_model" db:"embedding_model"`
+	EmbeddingVersion         string        `json:"embedding_version" db:"embedding_version"`
+	PreviousEmbeddingModel   *string       `json:"previous_embedding_model,omitempty" db:"previous_embedding_model"`
+	PreviousEmbeddingVersion *string       `json:"previous_embedding_version,omitempty" db:"previous_embedding_version"`
+	Status                   ReembedStatus `json:"status" db:"status"`
+	TotalChunks              int64         `json:"total_chunks" db:"total_chunks"`
+	ProcessedChunks          int64         `json:"processed_chunks" db:"processed_chunks"`
+	LastChunkID              *uuid.UUID    `json:"last_chunk_id,omitempty" db:"last_chunk_id"`
+	Error                    *string       `json:"error,omitempty" db:"error"`
+	StartedAt                time.Time     `json:"started_at" db:"started_at"`
+	UpdatedAt                time.Time     `json:"updated_at" db:"updated_at"`
+	CompletedAt              *time.Time    `json:"completed_at,omitempty" db:"completed_at"`
+	CancelRequested          bool          `json:"cancel_requested" db:"cancel_requested"`
+}
+
+// Progress returns the fraction of chunks re-embedded, between 0 and 1.
+func (j *ReembedJob) Progress() float64 {
+	if j.TotalChunks <= 0 {
+		if j.Status == ReembedStatusCompleted {
+			return 1
+		}
+		return 0
+	}
+	progress := float64(j.ProcessedChunks) / float64(j.TotalChunks)
+	if progress > 1 {
+		progress = 1
+	}
+	return progress
+}
+
+// ReembedJobRepository persists re-embedding jobs and their checkpoints.
+type ReembedJobRepository struct {
+	db DB
+}
+
+// NewReembedJobRepository creates a new re-embedding job repository.
+func NewReembedJobRepository(db DB) *ReembedJobRepository {
+	return &ReembedJobRepository{db: db}
+}
+
+// Save creates the job or updates its status and checkpoint.
+func (r *ReembedJobRepository) Save(ctx context.Context, job *ReembedJob) error {
+	if job.ID == uuid.Nil {
+		job.ID = uuid.New()
+	}
+	if job.StartedAt.IsZero() {
+		job.StartedAt = time.Now()
+	}
+	job.UpdatedAt = time.Now()
+
+	query := `
+		INSERT INTO reembed_jobs (id, tenant_id, campaign_variant_id, embedding_model, embedding_version,
+			previous_embedding_model, previous_embedding_version, status, total_chunks, processed_chunks,
+			last_chunk_id, error, started_at, updated_at, completed_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+		ON CONFLICT (id) DO UPDATE SET
+			previous_embedding_model = excluded.previous_embedding_model,
+			previous_embedding_version = excluded.previous_embedding_version,
+			status = excluded.status, total_chunks = excluded.total_chunks,
+			processed_chunks = excluded.processed_chunks, last_chunk_id = excluded.last_chunk_id,
+			error = excluded.error, updated_at = excluded.updated_at, completed_at = excluded.completed_at
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		job.ID, job.TenantID, job.CampaignVariantID, job.EmbeddingModel, job.EmbeddingVersion,
+		job.PreviousEmbeddingModel, job.PreviousEmbeddingVersion, job.Status, job.TotalChunks, job.ProcessedChunks,
+		job.LastChunkID, job.Error, job.StartedAt, job.UpdatedAt, job.CompletedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("save reembed job: %w", err)
+	}
+	return nil
+}
+
+// SetCancelRequested asks the process running a job to pause it, or clears
+// the request. Save leaves it untouched, so the running job's checkpoints do
+// not overwrite it.
+func (r *ReembedJobRepository) SetCancelRequested(ctx context.Context, id uuid.UUID, requested bool) error {
+	result, err := r.db.ExecContext(ctx,
+		"UPDATE reembed_jobs SET cancel_requested = $1, updated_at = $2 WHERE id = $3",
+		requested, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("set reembed job cancel request: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Get retrieves a job by ID.
+func (r *ReembedJobRepository) Get(ctx context.Context, id uuid.UUID) (*ReembedJob, error) {
+	query := `
+		SELECT id, tenant_id, campaign_variant_id, embedding_model, embedding_version,
+			previous_embedding_model, previous_embedding_version, status, total_chunks, processed_chunks,
+			last_chunk_id, error, started_at, updated_at, completed_at, cancel_requested
+		FROM reembed_jobs WHERE id = $1
+	`
+	job := &ReembedJob{}
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&job.ID, &job.TenantID, &job.CampaignVariantID, &job.EmbeddingModel, &job.EmbeddingVersion,
+		&job.PreviousEmbeddingModel, &job.PreviousEmbeddingVersion, &job.Status, &job.TotalChunks, &job.ProcessedChunks,
+		&job.LastChunkID, &job.Error, &job.StartedAt, &job.UpdatedAt, &job.CompletedAt, &job.CancelRequested,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return job, nil
+}
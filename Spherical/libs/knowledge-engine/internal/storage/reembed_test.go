@@ -0,0 +1,15 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReembedJob_Progress(t *testing.T) {
+	assert.Equal(t, 0.0, (&ReembedJob{Status: ReembedStatusPending}).Progress())
+	assert.Equal(t, 1.0, (&ReembedJob{Status: ReembedStatusCompleted}).Progress(), "a job over no chunks is done when it completes")
+	assert.InDelta(t, 0.25, (&ReembedJob{TotalChunks: 8, ProcessedChunks: 2}).Progress(), 0.001)
+	// Chunks ingested while the job ran are re-embedded but were not counted
+	assert.Equal(t, 1.0, (&ReembedJob{TotalChunks: 8, ProcessedChunks: 9}).Progress())
+}
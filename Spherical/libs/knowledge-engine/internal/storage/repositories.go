@@ -4,11 +4,17 @@ package storage
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/observability"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Common errors
@@ -25,6 +31,48 @@ type DB interface {
 	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
 }
 
+// TracedDB wraps a DB so every query runs in a span carrying its statement.
+type TracedDB struct {
+	db     DB
+	system string
+}
+
+// NewTracedDB wraps db; system names the database, e.g. "postgresql".
+func NewTracedDB(db DB, system string) *TracedDB {
+	return &TracedDB{db: db, system: system}
+}
+
+// QueryContext runs a query returning rows.
+func (t *TracedDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	ctx, span := t.start(ctx, "db.Query", query)
+	rows, err := t.db.QueryContext(ctx, query, args...)
+	observability.EndSpan(span, err)
+	return rows, err
+}
+
+// QueryRowContext runs a query returning at most one row. Its error is
+// deferred to Scan, so the span records only the round trip.
+func (t *TracedDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	ctx, span := t.start(ctx, "db.QueryRow", query)
+	defer span.End()
+	return t.db.QueryRowContext(ctx, query, args...)
+}
+
+// ExecContext runs a statement without returning rows.
+func (t *TracedDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, span := t.start(ctx, "db.Exec", query)
+	result, err := t.db.ExecContext(ctx, query, args...)
+	observability.EndSpan(span, err)
+	return result, err
+}
+
+func (t *TracedDB) start(ctx context.Context, name, query string) (context.Context, trace.Span) {
+	return observability.StartSpan(ctx, name,
+		attribute.String("db.system", t.system),
+		attribute.String("db.statement", strings.Join(strings.Fields(query), " ")),
+	)
+}
+
 // TenantRepository handles tenant CRUD operations.
 type TenantRepository struct {
 	db DB
@@ -189,14 +237,15 @@ func (r *CampaignRepository) Create(ctx context.Context, campaign *CampaignVaria
 
 	query := `
 		INSERT INTO campaign_variants (id, product_id, tenant_id, locale, trim, market, 
-			status, version, effective_from, effective_through, is_draft, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+			status, version, effective_from, effective_through, is_draft, base_campaign_variant_id,
+			created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
 	`
 	_, err := r.db.ExecContext(ctx, query,
 		campaign.ID, campaign.ProductID, campaign.TenantID, campaign.Locale,
 		campaign.Trim, campaign.Market, campaign.Status, campaign.Version,
 		campaign.EffectiveFrom, campaign.EffectiveThrough, campaign.IsDraft,
-		campaign.CreatedAt, campaign.UpdatedAt,
+		campaign.BaseCampaignVariantID, campaign.CreatedAt, campaign.UpdatedAt,
 	)
 	return err
 }
@@ -205,7 +254,8 @@ func (r *CampaignRepository) Create(ctx context.Context, campaign *CampaignVaria
 func (r *CampaignRepository) GetByID(ctx context.Context, tenantID, campaignID uuid.UUID) (*CampaignVariant, error) {
 	query := `
 		SELECT id, product_id, tenant_id, locale, trim, market, status, version,
-			effective_from, effective_through, is_draft, last_published_by, created_at, updated_at
+			effective_from, effective_through, is_draft, last_published_by,
+			base_campaign_variant_id, created_at, updated_at
 		FROM campaign_variants
 		WHERE id = $1 AND tenant_id = $2
 	`
@@ -213,8 +263,9 @@ func (r *CampaignRepository) GetByID(ctx context.Context, tenantID, campaignID u
 	err := r.db.QueryRowContext(ctx, query, campaignID, tenantID).Scan(
 		&campaign.ID, &campaign.ProductID, &campaign.TenantID, &campaign.Locale,
 		&campaign.Trim, &campaign.Market, &campaign.Status, &campaign.Version,
-		&campaign.EffectiveFrom, &campaign.EffectiveThrough, &campaign.IsDraft,
-		&campaign.LastPublishedBy, &campaign.CreatedAt, &campaign.UpdatedAt,
+		nullTextTime{&campaign.EffectiveFrom}, nullTextTime{&campaign.EffectiveThrough}, &campaign.IsDraft,
+		&campaign.LastPublishedBy, &campaign.BaseCampaignVariantID,
+		textTime{&campaign.CreatedAt}, textTime{&campaign.UpdatedAt},
 	)
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, ErrNotFound
@@ -222,6 +273,39 @@ func (r *CampaignRepository) GetByID(ctx context.Context, tenantID, campaignID u
 	return campaign, err
 }
 
+// ListByTenant lists all campaign variants for a tenant.
+func (r *CampaignRepository) ListByTenant(ctx context.Context, tenantID uuid.UUID) ([]*CampaignVariant, error) {
+	query := `
+		SELECT id, product_id, tenant_id, locale, trim, market, status, version,
+			effective_from, effective_through, is_draft, last_published_by,
+			base_campaign_variant_id, created_at, updated_at
+		FROM campaign_variants
+		WHERE tenant_id = $1
+		ORDER BY product_id, trim, version DESC
+	`
+	rows, err := r.db.QueryContext(ctx, query, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var campaigns []*CampaignVariant
+	for rows.Next() {
+		campaign := &CampaignVariant{}
+		if err := rows.Scan(
+			&campaign.ID, &campaign.ProductID, &campaign.TenantID, &campaign.Locale,
+			&campaign.Trim, &campaign.Market, &campaign.Status, &campaign.Version,
+			nullTextTime{&campaign.EffectiveFrom}, nullTextTime{&campaign.EffectiveThrough}, &campaign.IsDraft,
+			&campaign.LastPublishedBy, &campaign.BaseCampaignVariantID,
+			textTime{&campaign.CreatedAt}, textTime{&campaign.UpdatedAt},
+		); err != nil {
+			return nil, err
+		}
+		campaigns = append(campaigns, campaign)
+	}
+	return campaigns, rows.Err()
+}
+
 // Update updates a campaign variant.
 func (r *CampaignRepository) Update(ctx context.Context, campaign *CampaignVariant) error {
 	campaign.UpdatedAt = time.Now()
@@ -229,12 +313,12 @@ func (r *CampaignRepository) Update(ctx context.Context, campaign *CampaignVaria
 	query := `
 		UPDATE campaign_variants SET
 			status = $1, version = $2, effective_from = $3, effective_through = $4,
-			is_draft = $5, last_published_by = $6, updated_at = $7
-		WHERE id = $8 AND tenant_id = $9
+			is_draft = $5, last_published_by = $6, base_campaign_variant_id = $7, updated_at = $8
+		WHERE id = $9 AND tenant_id = $10
 	`
 	result, err := r.db.ExecContext(ctx, query,
 		campaign.Status, campaign.Version, campaign.EffectiveFrom, campaign.EffectiveThrough,
-		campaign.IsDraft, campaign.LastPublishedBy, campaign.UpdatedAt,
+		campaign.IsDraft, campaign.LastPublishedBy, campaign.BaseCampaignVariantID, campaign.UpdatedAt,
 		campaign.ID, campaign.TenantID,
 	)
 	if err != nil {
@@ -284,6 +368,31 @@ func (r *SpecValueRepository) Create(ctx context.Context, spec *SpecValue) error
 	return err
 }
 
+// LatestVersion returns the highest version of a spec item's values in a
+// campaign, whatever their status, or 0 when the campaign has none.
+func (r *SpecValueRepository) LatestVersion(ctx context.Context, tenantID, campaignID, specItemID uuid.UUID) (int, error) {
+	query := `
+		SELECT COALESCE(MAX(version), 0)
+		FROM spec_values
+		WHERE tenant_id = $1 AND campaign_variant_id = $2 AND spec_item_id = $3
+	`
+	var version int
+	err := r.db.QueryRowContext(ctx, query, tenantID, campaignID, specItemID).Scan(&version)
+	return version, err
+}
+
+// Retire deprecates a spec item's active values in a campaign, ending their
+// effective window at the given time so point-in-time reads still find them.
+func (r *SpecValueRepository) Retire(ctx context.Context, tenantID, campaignID, specItemID uuid.UUID, at time.Time) error {
+	query := `
+		UPDATE spec_values
+		SET status = 'deprecated', effective_through = $1, updated_at = $2
+		WHERE tenant_id = $3 AND campaign_variant_id = $4 AND spec_item_id = $5 AND status = 'active'
+	`
+	_, err := r.db.ExecContext(ctx, query, at, time.Now(), tenantID, campaignID, specItemID)
+	return err
+}
+
 // GetByCampaign retrieves all spec values for a campaign.
 func (r *SpecValueRepository) GetByCampaign(ctx context.Context, tenantID, campaignID uuid.UUID) ([]*SpecValue, error) {
 	query := `
@@ -347,6 +456,46 @@ func (r *SpecValueRepository) GetConflicts(ctx context.Context, tenantID, campai
 	return specs, rows.Err()
 }
 
+// encodeBlockTags formats feature block tags as a Postgres array literal,
+// which SQLite stores as is.
+func encodeBlockTags(tags []string) (interface{}, error) {
+	if tags == nil {
+		tags = []string{}
+	}
+	return pq.StringArray(tags).Value()
+}
+
+// blockTags scans the tags column: a Postgres array, or the JSON array
+// SQLite defaults it to.
+type blockTags struct {
+	tags *[]string
+}
+
+func (s blockTags) Scan(src interface{}) error {
+	var text string
+	switch v := src.(type) {
+	case nil:
+		*s.tags = nil
+		return nil
+	case string:
+		text = v
+	case []byte:
+		text = string(v)
+	default:
+		return fmt.Errorf("scan tags: unsupported type %T", src)
+	}
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(text, "[") {
+		return json.Unmarshal([]byte(text), s.tags)
+	}
+	var array pq.StringArray
+	if err := array.Scan(text); err != nil {
+		return fmt.Errorf("scan tags: %w", err)
+	}
+	*s.tags = []string(array)
+	return nil
+}
+
 // FeatureBlockRepository handles feature block CRUD operations.
 type FeatureBlockRepository struct {
 	db DB
@@ -367,13 +516,18 @@ func (r *FeatureBlockRepository) Create(ctx context.Context, block *FeatureBlock
 
 	query := `
 		INSERT INTO feature_blocks (id, tenant_id, product_id, campaign_variant_id, block_type,
-			body, priority, tags, shareability, source_doc_id, source_page, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+			body, priority, tags, shareability, source_doc_id, source_page, overrides_block_id,
+			created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
 	`
-	_, err := r.db.ExecContext(ctx, query,
+	tags, err := encodeBlockTags(block.Tags)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.ExecContext(ctx, query,
 		block.ID, block.TenantID, block.ProductID, block.CampaignVariantID, block.BlockType,
-		block.Body, block.Priority, block.Tags, block.Shareability,
-		block.SourceDocID, block.SourcePage, block.CreatedAt, block.UpdatedAt,
+		block.Body, block.Priority, tags, block.Shareability,
+		block.SourceDocID, block.SourcePage, block.OverridesBlockID, block.CreatedAt, block.UpdatedAt,
 	)
 	return err
 }
@@ -382,7 +536,8 @@ func (r *FeatureBlockRepository) Create(ctx context.Context, block *FeatureBlock
 func (r *FeatureBlockRepository) GetByCampaign(ctx context.Context, tenantID, campaignID uuid.UUID, blockType *BlockType) ([]*FeatureBlock, error) {
 	query := `
 		SELECT id, tenant_id, product_id, campaign_variant_id, block_type,
-			body, priority, tags, shareability, source_doc_id, source_page, created_at, updated_at
+			body, priority, tags, shareability, source_doc_id, source_page, overrides_block_id,
+			created_at, updated_at
 		FROM feature_blocks
 		WHERE tenant_id = $1 AND campaign_variant_id = $2
 	`
@@ -406,8 +561,9 @@ func (r *FeatureBlockRepository) GetByCampaign(ctx context.Context, tenantID, ca
 		block := &FeatureBlock{}
 		if err := rows.Scan(
 			&block.ID, &block.TenantID, &block.ProductID, &block.CampaignVariantID, &block.BlockType,
-			&block.Body, &block.Priority, &block.Tags, &block.Shareability,
-			&block.SourceDocID, &block.SourcePage, &block.CreatedAt, &block.UpdatedAt,
+			&block.Body, &block.Priority, blockTags{&block.Tags}, &block.Shareability,
+			&block.SourceDocID, &block.SourcePage, &block.OverridesBlockID,
+			textTime{&block.CreatedAt}, textTime{&block.UpdatedAt},
 		); err != nil {
 			return nil, err
 		}
@@ -416,6 +572,53 @@ func (r *FeatureBlockRepository) GetByCampaign(ctx context.Context, tenantID, ca
 	return blocks, rows.Err()
 }
 
+// GetOverride retrieves a campaign's block overriding the given base block.
+func (r *FeatureBlockRepository) GetOverride(ctx context.Context, tenantID, campaignID, baseBlockID uuid.UUID) (*FeatureBlock, error) {
+	query := `
+		SELECT id, tenant_id, product_id, campaign_variant_id, block_type,
+			body, priority, tags, shareability, source_doc_id, source_page, overrides_block_id,
+			created_at, updated_at
+		FROM feature_blocks
+		WHERE tenant_id = $1 AND campaign_variant_id = $2 AND overrides_block_id = $3
+		ORDER BY created_at DESC
+		LIMIT 1
+	`
+	block := &FeatureBlock{}
+	err := r.db.QueryRowContext(ctx, query, tenantID, campaignID, baseBlockID).Scan(
+		&block.ID, &block.TenantID, &block.ProductID, &block.CampaignVariantID, &block.BlockType,
+		&block.Body, &block.Priority, blockTags{&block.Tags}, &block.Shareability,
+		&block.SourceDocID, &block.SourcePage, &block.OverridesBlockID,
+		textTime{&block.CreatedAt}, textTime{&block.UpdatedAt},
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return block, nil
+}
+
+// Update replaces a feature block's body, tags and source.
+func (r *FeatureBlockRepository) Update(ctx context.Context, block *FeatureBlock) error {
+	block.UpdatedAt = time.Now()
+
+	query := `
+		UPDATE feature_blocks
+		SET body = $1, tags = $2, source_doc_id = $3, source_page = $4, updated_at = $5
+		WHERE tenant_id = $6 AND id = $7
+	`
+	tags, err := encodeBlockTags(block.Tags)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.ExecContext(ctx, query,
+		block.Body, tags, block.SourceDocID, block.SourcePage, block.UpdatedAt,
+		block.TenantID, block.ID,
+	)
+	return err
+}
+
 // KnowledgeChunkRepository handles knowledge chunk CRUD operations.
 type KnowledgeChunkRepository struct {
 	db DB
@@ -436,14 +639,15 @@ func (r *KnowledgeChunkRepository) Create(ctx context.Context, chunk *KnowledgeC
 
 	query := `
 		INSERT INTO knowledge_chunks (id, tenant_id, product_id, campaign_variant_id, chunk_type,
-			text, metadata, embedding_model, embedding_version, source_doc_id, source_page,
-			visibility, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+			text, metadata, embedding_vector, embedding_model, embedding_version, source_doc_id,
+			source_page, visibility, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
 	`
 	_, err := r.db.ExecContext(ctx, query,
 		chunk.ID, chunk.TenantID, chunk.ProductID, chunk.CampaignVariantID, chunk.ChunkType,
-		chunk.Text, chunk.Metadata, chunk.EmbeddingModel, chunk.EmbeddingVersion,
-		chunk.SourceDocID, chunk.SourcePage, chunk.Visibility, chunk.CreatedAt, chunk.UpdatedAt,
+		chunk.Text, chunk.Metadata, encodeEmbeddingVector(chunk.EmbeddingVector), chunk.EmbeddingModel,
+		chunk.EmbeddingVersion, chunk.SourceDocID, chunk.SourcePage, chunk.Visibility,
+		chunk.CreatedAt, chunk.UpdatedAt,
 	)
 	return err
 }
@@ -469,7 +673,7 @@ func (r *KnowledgeChunkRepository) GetByCampaign(ctx context.Context, tenantID,
 		if err := rows.Scan(
 			&chunk.ID, &chunk.TenantID, &chunk.ProductID, &chunk.CampaignVariantID, &chunk.ChunkType,
 			&chunk.Text, &chunk.Metadata, &chunk.EmbeddingModel, &chunk.EmbeddingVersion,
-			&chunk.SourceDocID, &chunk.SourcePage, &chunk.Visibility, &chunk.CreatedAt, &chunk.UpdatedAt,
+			&chunk.SourceDocID, &chunk.SourcePage, &chunk.Visibility, textTime{&chunk.CreatedAt}, textTime{&chunk.UpdatedAt},
 		); err != nil {
 			return nil, err
 		}
@@ -478,6 +682,203 @@ func (r *KnowledgeChunkRepository) GetByCampaign(ctx context.Context, tenantID,
 	return chunks, rows.Err()
 }
 
+// GetByBlock retrieves the chunks of a campaign built from the given feature block.
+func (r *KnowledgeChunkRepository) GetByBlock(ctx context.Context, tenantID, campaignID, blockID uuid.UUID) ([]*KnowledgeChunk, error) {
+	chunks, err := r.GetByCampaign(ctx, tenantID, campaignID)
+	if err != nil {
+		return nil, err
+	}
+	var matched []*KnowledgeChunk
+	for _, chunk := range chunks {
+		if chunk.ChunkType != ChunkTypeFeatureBlock || len(chunk.Metadata) == 0 {
+			continue
+		}
+		var metadata map[string]interface{}
+		if err := json.Unmarshal(chunk.Metadata, &metadata); err != nil {
+			continue
+		}
+		if id, _ := metadata[ChunkMetaBlockID].(string); id == blockID.String() {
+			matched = append(matched, chunk)
+		}
+	}
+	return matched, nil
+}
+
+// Update replaces a chunk's text, metadata and source. Its embedding is
+// cleared, so the chunk is re-embedded with its new text.
+func (r *KnowledgeChunkRepository) Update(ctx context.Context, chunk *KnowledgeChunk) error {
+	chunk.EmbeddingVector = nil
+	chunk.EmbeddingModel = nil
+	chunk.EmbeddingVersion = nil
+	chunk.UpdatedAt = time.Now()
+
+	query := `
+		UPDATE knowledge_chunks
+		SET text = $1, metadata = $2, source_doc_id = $3, source_page = $4,
+			embedding_vector = NULL, embedding_model = NULL, embedding_version = NULL, updated_at = $5
+		WHERE tenant_id = $6 AND id = $7
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		chunk.Text, chunk.Metadata, chunk.SourceDocID, chunk.SourcePage, chunk.UpdatedAt,
+		chunk.TenantID, chunk.ID,
+	)
+	return err
+}
+
+// ListTexts returns the text of every chunk of a tenant, e.g. to fit a local embedder.
+func (r *KnowledgeChunkRepository) ListTexts(ctx context.Context, tenantID uuid.UUID) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT text FROM knowledge_chunks WHERE tenant_id = $1", tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var texts []string
+	for rows.Next() {
+		var text string
+		if err := rows.Scan(&text); err != nil {
+			return nil, err
+		}
+		texts = append(texts, text)
+	}
+	return texts, rows.Err()
+}
+
+// chunkScope returns the condition selecting a tenant's chunks, or one
+// campaign's, with its arguments numbered from $first. SQLite binds numbered
+// parameters in order of appearance, so they must be numbered that way.
+func chunkScope(first int, tenantID uuid.UUID, campaignID *uuid.UUID) (string, []interface{}) {
+	if campaignID == nil {
+		return fmt.Sprintf("tenant_id = $%d", first), []interface{}{tenantID}
+	}
+	return fmt.Sprintf("tenant_id = $%d AND campaign_variant_id = $%d", first, first+1), []interface{}{tenantID, *campaignID}
+}
+
+// ListAfter retrieves up to limit chunks of a tenant, or of one campaign when
+// campaignID is set, with IDs after the given one in ID order. Passing the
+// last ID returned pages through all chunks.
+func (r *KnowledgeChunkRepository) ListAfter(ctx context.Context, tenantID uuid.UUID, campaignID *uuid.UUID, after uuid.UUID, limit int) ([]*KnowledgeChunk, error) {
+	scope, args := chunkScope(1, tenantID, campaignID)
+	query := fmt.Sprintf(`
+		SELECT id, tenant_id, product_id, campaign_variant_id, chunk_type,
+			text, metadata, embedding_model, embedding_version, source_doc_id, source_page,
+			visibility, created_at, updated_at
+		FROM knowledge_chunks
+		WHERE %s AND id > $%d
+		ORDER BY id
+		LIMIT $%d
+	`, scope, len(args)+1, len(args)+2)
+	rows, err := r.db.QueryContext(ctx, query, append(args, after, limit)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var chunks []*KnowledgeChunk
+	for rows.Next() {
+		chunk := &KnowledgeChunk{}
+		if err := rows.Scan(
+			&chunk.ID, &chunk.TenantID, &chunk.ProductID, &chunk.CampaignVariantID, &chunk.ChunkType,
+			&chunk.Text, &chunk.Metadata, &chunk.EmbeddingModel, &chunk.EmbeddingVersion,
+			&chunk.SourceDocID, &chunk.SourcePage, &chunk.Visibility, textTime{&chunk.CreatedAt}, textTime{&chunk.UpdatedAt},
+		); err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, chunk)
+	}
+	return chunks, rows.Err()
+}
+
+// ListUpdatedSince retrieves up to limit chunks of a tenant, or of one
+// campaign, created or updated at or after since, with IDs after the given
+// one in ID order.
+func (r *KnowledgeChunkRepository) ListUpdatedSince(ctx context.Context, tenantID uuid.UUID, campaignID *uuid.UUID, since time.Time, after uuid.UUID, limit int) ([]*KnowledgeChunk, error) {
+	scope, args := chunkScope(1, tenantID, campaignID)
+	query := fmt.Sprintf(`
+		SELECT id, tenant_id, product_id, campaign_variant_id, chunk_type,
+			text, metadata, embedding_model, embedding_version, source_doc_id, source_page,
+			visibility, created_at, updated_at
+		FROM knowledge_chunks
+		WHERE %s AND updated_at >= $%d AND id > $%d
+		ORDER BY id
+		LIMIT $%d
+	`, scope, len(args)+1, len(args)+2, len(args)+3)
+	rows, err := r.db.QueryContext(ctx, query, append(args, since, after, limit)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var chunks []*KnowledgeChunk
+	for rows.Next() {
+		chunk := &KnowledgeChunk{}
+		if err := rows.Scan(
+			&chunk.ID, &chunk.TenantID, &chunk.ProductID, &chunk.CampaignVariantID, &chunk.ChunkType,
+			&chunk.Text, &chunk.Metadata, &chunk.EmbeddingModel, &chunk.EmbeddingVersion,
+			&chunk.SourceDocID, &chunk.SourcePage, &chunk.Visibility, textTime{&chunk.CreatedAt}, textTime{&chunk.UpdatedAt},
+		); err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, chunk)
+	}
+	return chunks, rows.Err()
+}
+
+// ListPublishedAfter retrieves up to limit chunks of a tenant's published
+// campaigns, and chunks without a campaign, with IDs after the given one in ID order.
+func (r *KnowledgeChunkRepository) ListPublishedAfter(ctx context.Context, tenantID uuid.UUID, after uuid.UUID, limit int) ([]*KnowledgeChunk, error) {
+	query := `
+		SELECT kc.id, kc.tenant_id, kc.product_id, kc.campaign_variant_id, kc.chunk_type,
+			kc.text, kc.metadata, kc.embedding_model, kc.embedding_version, kc.source_doc_id, kc.source_page,
+			kc.visibility, kc.created_at, kc.updated_at
+		FROM knowledge_chunks kc
+		LEFT JOIN campaign_variants cv ON cv.id = kc.campaign_variant_id
+		WHERE kc.tenant_id = $1 AND kc.id > $2
+			AND (kc.campaign_variant_id IS NULL OR cv.status = $3)
+		ORDER BY kc.id
+		LIMIT $4
+	`
+	rows, err := r.db.QueryContext(ctx, query, tenantID, after, CampaignStatusPublished, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var chunks []*KnowledgeChunk
+	for rows.Next() {
+		chunk := &KnowledgeChunk{}
+		if err := rows.Scan(
+			&chunk.ID, &chunk.TenantID, &chunk.ProductID, &chunk.CampaignVariantID, &chunk.ChunkType,
+			&chunk.Text, &chunk.Metadata, &chunk.EmbeddingModel, &chunk.EmbeddingVersion,
+			&chunk.SourceDocID, &chunk.SourcePage, &chunk.Visibility, textTime{&chunk.CreatedAt}, textTime{&chunk.UpdatedAt},
+		); err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, chunk)
+	}
+	return chunks, rows.Err()
+}
+
+// Count returns the number of chunks of a tenant, or of one campaign when campaignID is set.
+func (r *KnowledgeChunkRepository) Count(ctx context.Context, tenantID uuid.UUID, campaignID *uuid.UUID) (int64, error) {
+	scope, args := chunkScope(1, tenantID, campaignID)
+	var count int64
+	err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM knowledge_chunks WHERE "+scope, args...).Scan(&count)
+	return count, err
+}
+
+// SetEmbeddingVersion records the embedding model and version of a tenant's
+// chunks, or one campaign's, after they were re-embedded or rolled back.
+func (r *KnowledgeChunkRepository) SetEmbeddingVersion(ctx context.Context, tenantID uuid.UUID, campaignID *uuid.UUID, model, version *string) error {
+	scope, args := chunkScope(4, tenantID, campaignID)
+	query := `
+		UPDATE knowledge_chunks
+		SET embedding_model = $1, embedding_version = $2, updated_at = $3
+		WHERE ` + scope
+	_, err := r.db.ExecContext(ctx, query, append([]interface{}{model, version, time.Now()}, args...)...)
+	return err
+}
+
 // LineageRepository handles lineage event operations.
 type LineageRepository struct {
 	db DB
@@ -632,6 +1033,8 @@ type Repositories struct {
 	KnowledgeChunks *KnowledgeChunkRepository
 	Lineage        *LineageRepository
 	DriftAlerts    *DriftAlertRepository
+	ReembedJobs    *ReembedJobRepository
+	Inheritance    *InheritanceResolver
 }
 
 // NewRepositories creates all repositories with the given database connection.
@@ -645,7 +1048,34 @@ func NewRepositories(db DB) *Repositories {
 		KnowledgeChunks: NewKnowledgeChunkRepository(db),
 		Lineage:        NewLineageRepository(db),
 		DriftAlerts:    NewDriftAlertRepository(db),
+		ReembedJobs:    NewReembedJobRepository(db),
+		Inheritance:    NewInheritanceResolver(db),
+	}
+}
+
+// TxDB is a database connection that can start transactions, such as *sql.DB.
+type TxDB interface {
+	DB
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+// InTx runs fn with repositories bound to a single transaction, committing
+// when fn succeeds and rolling back when it fails.
+func InTx(ctx context.Context, db TxDB, fn func(repos *Repositories) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	if err := fn(NewRepositories(tx)); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return fmt.Errorf("%w (rollback: %v)", err, rbErr)
+		}
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
 	}
+	return nil
 }
 
 // WithTenantScope returns a query helper that enforces tenant scoping.
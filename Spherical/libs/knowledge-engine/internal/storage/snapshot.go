@@ -0,0 +1,254 @@
+// Package storage provides point-in-time snapshots of campaign version history.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrNoCampaignVersion is returned when no campaign version matches a point in time.
+var ErrNoCampaignVersion = errors.New("no campaign version at the requested point in time")
+
+// SnapshotQuery selects campaign versions by the moment they were live or by
+// version number. Versions of a campaign variant are the variants sharing its
+// product, locale, trim and market.
+type SnapshotQuery struct {
+	TenantID uuid.UUID
+	// ProductIDs restricts the snapshot to these products when CampaignVariantID is unset.
+	ProductIDs []uuid.UUID
+	// CampaignVariantID selects the versions of this variant's lineage.
+	CampaignVariantID *uuid.UUID
+	// AsOf selects the versions live at this moment, and the spec values effective then.
+	AsOf *time.Time
+	// Version selects versions by number and takes precedence over AsOf.
+	Version *int
+}
+
+// Snapshot is the campaign versions selected for a point in time and the spec
+// values they exposed.
+type Snapshot struct {
+	// Campaigns are the selected versions.
+	Campaigns []*CampaignVariant
+	// CampaignIDs are the selected versions followed by the base variants they inherit from.
+	CampaignIDs []uuid.UUID
+	// Specs are the spec values of CampaignIDs, shaped like spec view rows.
+	Specs []SpecViewLatest
+}
+
+// SnapshotRepository reads campaign version history, including archived
+// versions the spec view no longer exposes.
+type SnapshotRepository struct {
+	db DB
+}
+
+// NewSnapshotRepository creates a new snapshot repository.
+func NewSnapshotRepository(db DB) *SnapshotRepository {
+	return &SnapshotRepository{db: db}
+}
+
+// LiveAt reports whether the campaign version was published and in effect at t.
+// Archived versions were live during their effective window.
+func (c *CampaignVariant) LiveAt(t time.Time) bool {
+	if c.Status != CampaignStatusPublished && c.Status != CampaignStatusArchived {
+		return false
+	}
+	if c.EffectiveFrom == nil || t.Before(*c.EffectiveFrom) {
+		return false
+	}
+	return c.EffectiveThrough == nil || t.Before(*c.EffectiveThrough)
+}
+
+// sameLineage reports whether two variants are versions of the same campaign.
+func (c *CampaignVariant) sameLineage(other *CampaignVariant) bool {
+	return c.ProductID == other.ProductID && c.Locale == other.Locale &&
+		stringValue(c.Trim) == stringValue(other.Trim) && stringValue(c.Market) == stringValue(other.Market)
+}
+
+// SelectVersions keeps the versions matching q's Version, or those live at q's AsOf.
+// Drafts are never selected.
+func SelectVersions(versions []*CampaignVariant, q SnapshotQuery) []*CampaignVariant {
+	var selected []*CampaignVariant
+	for _, c := range versions {
+		switch {
+		case q.Version != nil:
+			if c.Version == *q.Version && !c.IsDraft && c.Status != CampaignStatusDraft {
+				selected = append(selected, c)
+			}
+		case q.AsOf != nil:
+			if c.LiveAt(*q.AsOf) {
+				selected = append(selected, c)
+			}
+		}
+	}
+	return selected
+}
+
+// Snapshot resolves the campaign versions selected by q and loads their spec values.
+func (r *SnapshotRepository) Snapshot(ctx context.Context, q SnapshotQuery) (*Snapshot, error) {
+	if q.Version == nil && q.AsOf == nil {
+		return nil, fmt.Errorf("%w: neither a time nor a version was given", ErrNoCampaignVersion)
+	}
+
+	campaigns := NewCampaignRepository(r.db)
+	all, err := campaigns.ListByTenant(ctx, q.TenantID)
+	if err != nil {
+		return nil, fmt.Errorf("list campaign versions: %w", err)
+	}
+
+	var candidates []*CampaignVariant
+	if q.CampaignVariantID != nil {
+		anchor, err := campaigns.GetByID(ctx, q.TenantID, *q.CampaignVariantID)
+		if err != nil {
+			return nil, fmt.Errorf("load campaign: %w", err)
+		}
+		for _, c := range all {
+			if c.sameLineage(anchor) {
+				candidates = append(candidates, c)
+			}
+		}
+	} else {
+		products := toUUIDSet(q.ProductIDs)
+		for _, c := range all {
+			if len(products) == 0 || products[c.ProductID] {
+				candidates = append(candidates, c)
+			}
+		}
+	}
+
+	selected := SelectVersions(candidates, q)
+	if len(selected) == 0 {
+		return nil, ErrNoCampaignVersion
+	}
+	// A lineage has one live version; prefer the newest if windows overlap
+	if q.CampaignVariantID != nil {
+		newest := selected[0]
+		for _, c := range selected[1:] {
+			if c.Version > newest.Version {
+				newest = c
+			}
+		}
+		selected = []*CampaignVariant{newest}
+	}
+
+	snapshot := &Snapshot{Campaigns: selected}
+	seen := make(map[uuid.UUID]bool)
+	for _, c := range selected {
+		chain, err := campaigns.GetInheritanceChain(ctx, q.TenantID, c.ID)
+		if err != nil {
+			return nil, fmt.Errorf("resolve inheritance chain: %w", err)
+		}
+		for _, ancestor := range chain {
+			if !seen[ancestor.ID] {
+				seen[ancestor.ID] = true
+				snapshot.CampaignIDs = append(snapshot.CampaignIDs, ancestor.ID)
+			}
+		}
+	}
+
+	snapshot.Specs, err = r.Specs(ctx, q.TenantID, snapshot.CampaignIDs, q.AsOf)
+	if err != nil {
+		return nil, fmt.Errorf("load spec values: %w", err)
+	}
+	return snapshot, nil
+}
+
+// Specs returns the spec values of campaigns as they stood at asOf, regardless
+// of campaign status. Values with an effective window outside asOf are skipped;
+// deprecated values are kept only when their window shows they were in effect.
+// Without asOf the active values are returned.
+func (r *SnapshotRepository) Specs(ctx context.Context, tenantID uuid.UUID, campaignIDs []uuid.UUID, asOf *time.Time) ([]SpecViewLatest, error) {
+	if len(campaignIDs) == 0 {
+		return nil, nil
+	}
+
+	placeholders := make([]string, len(campaignIDs))
+	args := []interface{}{tenantID}
+	for i, id := range campaignIDs {
+		placeholders[i] = "$" + strconv.Itoa(i+2)
+		args = append(args, id)
+	}
+
+	query := `
+		SELECT
+			sv.id, sv.tenant_id, sv.product_id, sv.campaign_variant_id, sv.spec_item_id,
+			si.display_name, sc.name, COALESCE(sv.value_text, CAST(sv.value_numeric AS TEXT)),
+			sv.unit, sv.confidence, sv.source_doc_id, sv.source_page, sv.version,
+			cv.locale, cv.trim, cv.market, p.name, sv.value_numeric,
+			sv.status, sv.effective_from, sv.effective_through
+		FROM spec_values sv
+		JOIN spec_items si ON sv.spec_item_id = si.id
+		JOIN spec_categories sc ON si.category_id = sc.id
+		JOIN campaign_variants cv ON sv.campaign_variant_id = cv.id
+		JOIN products p ON sv.product_id = p.id
+		WHERE sv.tenant_id = $1 AND sv.campaign_variant_id IN (` + strings.Join(placeholders, ", ") + `)
+		ORDER BY sc.name, si.display_name
+	`
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var specs []SpecViewLatest
+	for rows.Next() {
+		var sv SpecViewLatest
+		var status SpecStatus
+		var from, through *time.Time
+		if err := rows.Scan(
+			&sv.ID, &sv.TenantID, &sv.ProductID, &sv.CampaignVariantID, &sv.SpecItemID,
+			&sv.SpecName, &sv.CategoryName, &sv.Value,
+			&sv.Unit, &sv.Confidence, &sv.SourceDocID, &sv.SourcePage, &sv.Version,
+			&sv.Locale, &sv.Trim, &sv.Market, &sv.ProductName, &sv.ValueNumeric,
+			&status, nullTextTime{&from}, nullTextTime{&through},
+		); err != nil {
+			return nil, err
+		}
+		if specEffective(status, from, through, asOf) {
+			specs = append(specs, sv)
+		}
+	}
+	return specs, rows.Err()
+}
+
+// specEffective reports whether a spec value was in effect at asOf.
+func specEffective(status SpecStatus, from, through *time.Time, asOf *time.Time) bool {
+	switch status {
+	case SpecStatusActive:
+	case SpecStatusDeprecated:
+		if asOf == nil || through == nil {
+			return false
+		}
+	default:
+		return false
+	}
+	if asOf == nil {
+		return true
+	}
+	if from != nil && asOf.Before(*from) {
+		return false
+	}
+	return through == nil || asOf.Before(*through)
+}
+
+// stringValue dereferences an optional string.
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// toUUIDSet builds a membership set of IDs.
+func toUUIDSet(ids []uuid.UUID) map[uuid.UUID]bool {
+	set := make(map[uuid.UUID]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	return set
+}
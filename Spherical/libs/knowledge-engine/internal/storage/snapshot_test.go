@@ -0,0 +1,47 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelectVersions(t *testing.T) {
+	product := uuid.New()
+	jan := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	apr := time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	v1 := &CampaignVariant{ID: uuid.New(), ProductID: product, Version: 1, Status: CampaignStatusArchived, EffectiveFrom: &jan, EffectiveThrough: &apr}
+	v2 := &CampaignVariant{ID: uuid.New(), ProductID: product, Version: 2, Status: CampaignStatusPublished, EffectiveFrom: &apr}
+	v3 := &CampaignVariant{ID: uuid.New(), ProductID: product, Version: 3, Status: CampaignStatusDraft, IsDraft: true}
+	versions := []*CampaignVariant{v1, v2, v3}
+
+	at := func(t time.Time) *time.Time { return &t }
+	number := func(n int) *int { return &n }
+
+	assert.Equal(t, []*CampaignVariant{v1}, SelectVersions(versions, SnapshotQuery{AsOf: at(jan.AddDate(0, 1, 0))}))
+	assert.Equal(t, []*CampaignVariant{v2}, SelectVersions(versions, SnapshotQuery{AsOf: at(apr)}), "effective windows are half-open")
+	assert.Empty(t, SelectVersions(versions, SnapshotQuery{AsOf: at(jan.AddDate(-1, 0, 0))}))
+
+	assert.Equal(t, []*CampaignVariant{v1}, SelectVersions(versions, SnapshotQuery{Version: number(1), AsOf: at(apr)}), "version takes precedence")
+	assert.Empty(t, SelectVersions(versions, SnapshotQuery{Version: number(3)}), "drafts are never selected")
+	assert.Empty(t, SelectVersions(versions, SnapshotQuery{}))
+}
+
+func TestSpecEffective(t *testing.T) {
+	jan := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	apr := time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)
+	feb := jan.AddDate(0, 1, 0)
+	may := apr.AddDate(0, 1, 0)
+
+	assert.True(t, specEffective(SpecStatusActive, nil, nil, nil))
+	assert.True(t, specEffective(SpecStatusActive, &jan, nil, &feb))
+	assert.False(t, specEffective(SpecStatusActive, &apr, nil, &feb), "not yet in effect")
+
+	assert.True(t, specEffective(SpecStatusDeprecated, &jan, &apr, &feb), "deprecated values were in effect during their window")
+	assert.False(t, specEffective(SpecStatusDeprecated, &jan, &apr, &may))
+	assert.False(t, specEffective(SpecStatusDeprecated, &jan, nil, &feb), "no window, no evidence it was in effect")
+	assert.False(t, specEffective(SpecStatusDeprecated, &jan, &apr, nil))
+}
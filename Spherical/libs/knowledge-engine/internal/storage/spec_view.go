@@ -4,7 +4,10 @@ package storage
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -62,7 +65,7 @@ func (r *SpecViewRepository) Query(ctx context.Context, q SpecViewQuery) (*SpecV
 			sv.id, sv.tenant_id, sv.product_id, sv.campaign_variant_id, sv.spec_item_id,
 			sv.spec_name, sv.category_name, sv.value, sv.unit, sv.confidence,
 			sv.source_doc_id, sv.source_page, sv.version, sv.locale, sv.trim,
-			sv.market, sv.product_name
+			sv.market, sv.product_name, sv.value_numeric
 		FROM spec_view_latest sv
 		WHERE sv.tenant_id = $1
 	`
@@ -71,49 +74,55 @@ func (r *SpecViewRepository) Query(ctx context.Context, q SpecViewQuery) (*SpecV
 
 	// Filter by product IDs
 	if len(q.ProductIDs) > 0 {
-		query += " AND sv.product_id = ANY($" + string('0'+byte(argIdx)) + ")"
-		args = append(args, q.ProductIDs)
-		argIdx++
+		query += " AND sv.product_id IN (" + placeholders(argIdx, len(q.ProductIDs)) + ")"
+		for _, id := range q.ProductIDs {
+			args = append(args, id)
+		}
+		argIdx += len(q.ProductIDs)
 	}
 
 	// Filter by campaign variant
 	if q.CampaignVariantID != nil {
-		query += " AND sv.campaign_variant_id = $" + string('0'+byte(argIdx))
+		query += " AND sv.campaign_variant_id = $" + strconv.Itoa(argIdx)
 		args = append(args, *q.CampaignVariantID)
 		argIdx++
 	}
 
 	// Filter by categories
 	if len(q.Categories) > 0 {
-		query += " AND sv.category_name = ANY($" + string('0'+byte(argIdx)) + ")"
-		args = append(args, q.Categories)
-		argIdx++
+		query += " AND sv.category_name IN (" + placeholders(argIdx, len(q.Categories)) + ")"
+		for _, category := range q.Categories {
+			args = append(args, category)
+		}
+		argIdx += len(q.Categories)
 	}
 
 	// Filter by spec names
 	if len(q.SpecNames) > 0 {
-		query += " AND sv.spec_name = ANY($" + string('0'+byte(argIdx)) + ")"
-		args = append(args, q.SpecNames)
-		argIdx++
+		query += " AND sv.spec_name IN (" + placeholders(argIdx, len(q.SpecNames)) + ")"
+		for _, name := range q.SpecNames {
+			args = append(args, name)
+		}
+		argIdx += len(q.SpecNames)
 	}
 
 	// Filter by locale
 	if q.Locale != nil {
-		query += " AND sv.locale = $" + string('0'+byte(argIdx))
+		query += " AND sv.locale = $" + strconv.Itoa(argIdx)
 		args = append(args, *q.Locale)
 		argIdx++
 	}
 
 	// Filter by trim
 	if q.Trim != nil {
-		query += " AND sv.trim = $" + string('0'+byte(argIdx))
+		query += " AND sv.trim = $" + strconv.Itoa(argIdx)
 		args = append(args, *q.Trim)
 		argIdx++
 	}
 
 	// Filter by market
 	if q.Market != nil {
-		query += " AND sv.market = $" + string('0'+byte(argIdx))
+		query += " AND sv.market = $" + strconv.Itoa(argIdx)
 		args = append(args, *q.Market)
 		argIdx++
 	}
@@ -126,13 +135,13 @@ func (r *SpecViewRepository) Query(ctx context.Context, q SpecViewQuery) (*SpecV
 	if limit <= 0 {
 		limit = 100
 	}
-	query += " LIMIT $" + string('0'+byte(argIdx))
+	query += " LIMIT $" + strconv.Itoa(argIdx)
 	args = append(args, limit)
 	argIdx++
 
 	// Add offset
 	if q.Offset > 0 {
-		query += " OFFSET $" + string('0'+byte(argIdx))
+		query += " OFFSET $" + strconv.Itoa(argIdx)
 		args = append(args, q.Offset)
 	}
 
@@ -151,7 +160,7 @@ func (r *SpecViewRepository) Query(ctx context.Context, q SpecViewQuery) (*SpecV
 			&sv.ID, &sv.TenantID, &sv.ProductID, &sv.CampaignVariantID, &sv.SpecItemID,
 			&sv.SpecName, &sv.CategoryName, &sv.Value, &sv.Unit, &sv.Confidence,
 			&sv.SourceDocID, &sv.SourcePage, &sv.Version, &sv.Locale, &sv.Trim,
-			&sv.Market, &sv.ProductName,
+			&sv.Market, &sv.ProductName, &sv.ValueNumeric,
 		); err != nil {
 			return nil, err
 		}
@@ -176,6 +185,16 @@ func (r *SpecViewRepository) Query(ctx context.Context, q SpecViewQuery) (*SpecV
 	}, nil
 }
 
+// placeholders returns n comma-separated parameters numbered from $first, so
+// list filters bind one value each in both SQLite and Postgres.
+func placeholders(first, n int) string {
+	params := make([]string, n)
+	for i := range params {
+		params[i] = "$" + strconv.Itoa(first+i)
+	}
+	return strings.Join(params, ", ")
+}
+
 // GetBySpecItem retrieves a specific spec value by item ID.
 func (r *SpecViewRepository) GetBySpecItem(ctx context.Context, tenantID, productID, campaignVariantID, specItemID uuid.UUID) (*SpecViewLatest, error) {
 	query := `
@@ -183,7 +202,7 @@ func (r *SpecViewRepository) GetBySpecItem(ctx context.Context, tenantID, produc
 			sv.id, sv.tenant_id, sv.product_id, sv.campaign_variant_id, sv.spec_item_id,
 			sv.spec_name, sv.category_name, sv.value, sv.unit, sv.confidence,
 			sv.source_doc_id, sv.source_page, sv.version, sv.locale, sv.trim,
-			sv.market, sv.product_name
+			sv.market, sv.product_name, sv.value_numeric
 		FROM spec_view_latest sv
 		WHERE sv.tenant_id = $1 AND sv.product_id = $2 
 			AND sv.campaign_variant_id = $3 AND sv.spec_item_id = $4
@@ -193,7 +212,7 @@ func (r *SpecViewRepository) GetBySpecItem(ctx context.Context, tenantID, produc
 		&sv.ID, &sv.TenantID, &sv.ProductID, &sv.CampaignVariantID, &sv.SpecItemID,
 		&sv.SpecName, &sv.CategoryName, &sv.Value, &sv.Unit, &sv.Confidence,
 		&sv.SourceDocID, &sv.SourcePage, &sv.Version, &sv.Locale, &sv.Trim,
-		&sv.Market, &sv.ProductName,
+		&sv.Market, &sv.ProductName, &sv.ValueNumeric,
 	)
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, ErrNotFound
@@ -211,7 +230,7 @@ func (r *SpecViewRepository) SearchByKeyword(ctx context.Context, tenantID uuid.
 			sv.id, sv.tenant_id, sv.product_id, sv.campaign_variant_id, sv.spec_item_id,
 			sv.spec_name, sv.category_name, sv.value, sv.unit, sv.confidence,
 			sv.source_doc_id, sv.source_page, sv.version, sv.locale, sv.trim,
-			sv.market, sv.product_name
+			sv.market, sv.product_name, sv.value_numeric
 		FROM spec_view_latest sv
 		WHERE sv.tenant_id = $1 
 			AND (UPPER(sv.spec_name) LIKE '%' || UPPER($2) || '%' 
@@ -237,7 +256,7 @@ func (r *SpecViewRepository) SearchByKeyword(ctx context.Context, tenantID uuid.
 			&sv.ID, &sv.TenantID, &sv.ProductID, &sv.CampaignVariantID, &sv.SpecItemID,
 			&sv.SpecName, &sv.CategoryName, &sv.Value, &sv.Unit, &sv.Confidence,
 			&sv.SourceDocID, &sv.SourcePage, &sv.Version, &sv.Locale, &sv.Trim,
-			&sv.Market, &sv.ProductName,
+			&sv.Market, &sv.ProductName, &sv.ValueNumeric,
 		); err != nil {
 			return nil, err
 		}
@@ -271,6 +290,75 @@ func (r *SpecViewRepository) GetCategories(ctx context.Context, tenantID uuid.UU
 	return categories, rows.Err()
 }
 
+// SpecAliases returns the aliases of spec items a tenant has published values for,
+// keyed by spec display name.
+func (r *SpecViewRepository) SpecAliases(ctx context.Context, tenantID uuid.UUID) (map[string][]string, error) {
+	query := `
+		SELECT DISTINCT si.display_name, si.aliases
+		FROM spec_items si
+		JOIN spec_view_latest sv ON sv.spec_item_id = si.id
+		WHERE sv.tenant_id = $1
+	`
+	rows, err := r.db.QueryContext(ctx, query, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	aliases := make(map[string][]string)
+	for rows.Next() {
+		var name string
+		var raw sql.NullString
+		if err := rows.Scan(&name, &raw); err != nil {
+			return nil, err
+		}
+		if list := parseTextArray(raw.String); len(list) > 0 {
+			aliases[name] = append(aliases[name], list...)
+		}
+	}
+	return aliases, rows.Err()
+}
+
+// parseTextArray reads a text list stored as a Postgres array literal ({a,"b c"})
+// or, on SQLite, as a JSON array.
+func parseTextArray(raw string) []string {
+	raw = strings.TrimSpace(raw)
+	if strings.HasPrefix(raw, "[") {
+		var list []string
+		if err := json.Unmarshal([]byte(raw), &list); err != nil {
+			return nil
+		}
+		return list
+	}
+	if !strings.HasPrefix(raw, "{") || !strings.HasSuffix(raw, "}") {
+		return nil
+	}
+
+	var list []string
+	var current strings.Builder
+	quoted, escaped := false, false
+	for _, r := range raw[1 : len(raw)-1] {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '"':
+			quoted = !quoted
+		case r == ',' && !quoted:
+			list = append(list, current.String())
+			current.Reset()
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if current.Len() > 0 || len(list) > 0 {
+		list = append(list, current.String())
+	}
+	return list
+}
+
 // computeCacheHint determines caching recommendations for a query.
 func (r *SpecViewRepository) computeCacheHint(q SpecViewQuery, maxVersion int) CacheHint {
 	// Generate cache key based on query parameters
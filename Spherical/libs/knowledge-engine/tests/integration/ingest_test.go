@@ -86,7 +86,7 @@ func TestIngestionPipeline_FullIngestion(t *testing.T) {
 	}
 
 	logger := observability.DefaultLogger()
-	pipeline := ingest.NewPipeline(logger, ingest.PipelineConfig{
+	pipeline := ingest.NewPipeline(logger, nil, ingest.PipelineConfig{
 		ChunkSize:         512,
 		ChunkOverlap:      64,
 		MaxConcurrentJobs: 2,
@@ -122,7 +122,7 @@ func TestIngestionPipeline_DuplicateDetection(t *testing.T) {
 	}
 
 	logger := observability.DefaultLogger()
-	pipeline := ingest.NewPipeline(logger, ingest.PipelineConfig{
+	pipeline := ingest.NewPipeline(logger, nil, ingest.PipelineConfig{
 		DedupeThreshold: 0.95,
 	})
 
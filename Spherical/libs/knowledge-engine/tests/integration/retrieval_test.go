@@ -11,6 +11,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/cache"
+	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/embedding"
 	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/observability"
 	"github.com/spherical-ai/spherical/libs/knowledge-engine/internal/retrieval"
 )
@@ -58,7 +59,7 @@ func TestRetrievalRouter_HybridQuery(t *testing.T) {
 
 	// Create router
 	// TODO: Create specViewRepo from test database when available
-	router := retrieval.NewRouter(logger, memCache, vectorAdapter, nil, retrieval.RouterConfig{
+	router := retrieval.NewRouter(logger, memCache, vectorAdapter, nil, nil, retrieval.RouterConfig{
 		MaxChunks:                 8,
 		StructuredFirst:           true,
 		SemanticFallback:          true,
@@ -87,7 +88,7 @@ func TestRetrievalRouter_IntentClassification(t *testing.T) {
 	memCache := cache.NewMemoryClient(100)
 	vectorAdapter, _ := retrieval.NewFAISSAdapter(retrieval.FAISSConfig{Dimension: 768})
 
-	router := retrieval.NewRouter(logger, memCache, vectorAdapter, nil, retrieval.RouterConfig{
+	router := retrieval.NewRouter(logger, memCache, vectorAdapter, nil, nil, retrieval.RouterConfig{
 		IntentConfidenceThreshold: 0.5,
 		KeywordConfidenceThreshold: 0.8,
 	})
@@ -152,7 +153,7 @@ func TestRetrievalRouter_TenantIsolation(t *testing.T) {
 	err := vectorAdapter.Insert(context.Background(), chunks)
 	require.NoError(t, err)
 
-	router := retrieval.NewRouter(logger, memCache, vectorAdapter, nil, retrieval.RouterConfig{
+	router := retrieval.NewRouter(logger, memCache, vectorAdapter, nil, nil, retrieval.RouterConfig{
 		SemanticFallback: true,
 	})
 
@@ -185,7 +186,7 @@ func TestRetrievalRouter_Caching(t *testing.T) {
 	memCache := cache.NewMemoryClient(100)
 	vectorAdapter, _ := retrieval.NewFAISSAdapter(retrieval.FAISSConfig{Dimension: 768})
 
-	router := retrieval.NewRouter(logger, memCache, vectorAdapter, nil, retrieval.RouterConfig{
+	router := retrieval.NewRouter(logger, memCache, vectorAdapter, nil, nil, retrieval.RouterConfig{
 		CacheResults: true,
 		CacheTTL:     1 * time.Minute,
 	})
@@ -210,6 +211,61 @@ func TestRetrievalRouter_Caching(t *testing.T) {
 	assert.LessOrEqual(t, latency2, latency1+10) // Allow small variance
 }
 
+func TestRetrievalRouter_LocalEmbeddings(t *testing.T) {
+	logger := observability.NewLogger(observability.LogConfig{Level: "error"})
+	ctx := context.Background()
+	tenantID, productID := uuid.New(), uuid.New()
+
+	usps := []string{
+		"Toyota Safety Sense 3.0 with pre-collision system and lane tracing assist",
+		"Best-in-class hybrid fuel economy of 51 mpg combined",
+		"Wireless Apple CarPlay and Android Auto on a 12.3-inch touchscreen",
+		"JBL premium audio with nine speakers",
+	}
+	embedder := embedding.NewLocalEmbedder(embedding.LocalConfig{Dimension: 512})
+	embedder.Fit(usps)
+	vectors, err := embedder.Embed(ctx, usps)
+	require.NoError(t, err)
+
+	vectorAdapter, err := retrieval.NewFAISSAdapter(retrieval.FAISSConfig{Dimension: 512})
+	require.NoError(t, err)
+	ids := make([]uuid.UUID, len(usps))
+	for i, text := range usps {
+		ids[i] = uuid.New()
+		require.NoError(t, vectorAdapter.Insert(ctx, []retrieval.VectorEntry{{
+			ID:               ids[i],
+			TenantID:         tenantID,
+			ProductID:        productID,
+			ChunkType:        "usp",
+			Visibility:       "private",
+			EmbeddingVersion: embedder.Model(),
+			Vector:           vectors[i],
+			Metadata:         map[string]interface{}{"chunk_type": "usp", "text": text},
+		}}))
+	}
+
+	router := retrieval.NewRouter(logger, nil, vectorAdapter, embedder, nil, retrieval.RouterConfig{MaxChunks: 4})
+	intent := retrieval.IntentUSPLookup
+	questions := map[string]uuid.UUID{
+		"Is wireless CarPlay supported?":              ids[2],
+		"Does it have a pre-collision safety system?": ids[0],
+		"How good is the hybrid fuel economy?":        ids[1],
+		"What premium audio speakers does it have?":   ids[3],
+	}
+	for question, want := range questions {
+		resp, err := router.Query(ctx, retrieval.RetrievalRequest{
+			TenantID:   tenantID,
+			ProductIDs: []uuid.UUID{productID},
+			Question:   question,
+			IntentHint: &intent,
+			MaxChunks:  4,
+		})
+		require.NoError(t, err)
+		require.NotEmpty(t, resp.SemanticChunks, question)
+		assert.Equal(t, want, resp.SemanticChunks[0].ChunkID, question)
+	}
+}
+
 // generateTestVector creates a test vector with a seed for reproducibility.
 func generateTestVector(dim int, seed int) []float32 {
 	vec := make([]float32, dim)
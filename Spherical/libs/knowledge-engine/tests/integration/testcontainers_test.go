@@ -6,6 +6,9 @@ import (
 	"database/sql"
 	"fmt"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 
@@ -137,13 +140,21 @@ func (s *TestContainerSetup) RunMigrations(t *testing.T) {
 	_, err = db.ExecContext(ctx, "CREATE EXTENSION IF NOT EXISTS vector")
 	require.NoError(t, err)
 
-	// Read and execute migration file
-	migrationPath := "../../db/migrations/0001_init.sql"
-	migration, err := os.ReadFile(migrationPath)
+	// Read and execute the Postgres migration files in order
+	migrationPaths, err := filepath.Glob("../../db/migrations/*.sql")
 	require.NoError(t, err)
+	sort.Strings(migrationPaths)
 
-	_, err = db.ExecContext(ctx, string(migration))
-	require.NoError(t, err)
+	for _, migrationPath := range migrationPaths {
+		if strings.HasSuffix(migrationPath, "_sqlite.sql") {
+			continue
+		}
+		migration, err := os.ReadFile(migrationPath)
+		require.NoError(t, err)
+
+		_, err = db.ExecContext(ctx, string(migration))
+		require.NoError(t, err, migrationPath)
+	}
 
 	t.Log("Migrations applied successfully")
 }